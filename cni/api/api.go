@@ -23,10 +23,38 @@ type AzureCNIState struct {
 	ContainerInterfaces map[string]PodNetworkInterfaceInfo
 }
 
+// ReconciledEndpoint is a single entry of the reconcile result.
+type ReconciledEndpoint struct {
+	NetworkID     string
+	PodEndpointId string //nolint:revive,stylecheck // matches PodNetworkInterfaceInfo
+	ContainerID   string
+	NetNsPath     string
+	IPAddresses   []net.IPNet
+	Reason        string
+}
+
+// AzureCNIReconcileResult is the result of the RECONCILE_ENDPOINTS command.
+// The Reason of Removed endpoints tells whether the release of their IP addresses was queued to CNS.
+type AzureCNIReconcileResult struct {
+	Checked   int
+	Repaired  []ReconciledEndpoint
+	Reapplied []ReconciledEndpoint
+	Removed   []ReconciledEndpoint
+	Failed    []ReconciledEndpoint
+}
+
+func (a *AzureCNIReconcileResult) PrintResult() error {
+	return printJSON(a)
+}
+
 func (a *AzureCNIState) PrintResult() error {
+	return printJSON(a)
+}
+
+func printJSON(a any) error {
 	b, err := json.MarshalIndent(a, "", "    ")
 	if err != nil {
-		logger.Error("Failed to unmarshall Azure CNI state", zap.Error(err))
+		logger.Error("Failed to marshal Azure CNI result", zap.Error(err))
 	}
 
 	// write result to stdout to be captured by caller
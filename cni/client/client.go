@@ -50,6 +50,30 @@ func (c *client) GetEndpointState() (*api.AzureCNIState, error) {
 	return state, nil
 }
 
+// ReconcileEndpoints runs the endpoint reconciliation of Azure CNI, which repairs the host side objects of the
+// endpoints and removes the state of the endpoints whose netns no longer exists.
+func (c *client) ReconcileEndpoints() (*api.AzureCNIReconcileResult, error) {
+	cmd := c.exec.Command(platform.CNIBinaryPath)
+	cmd.SetDir(CNIExecDir)
+	envs := os.Environ()
+	cmdenv := fmt.Sprintf("%s=%s", cni.Cmd, cni.CmdReconcileEndpoints)
+	logger.Info("Setting cmd to", zap.String("cmdenv", cmdenv))
+	envs = append(envs, cmdenv)
+	cmd.SetEnv(envs)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Azure CNI bin with err: [%w], output: [%s]", err, string(output))
+	}
+
+	result := &api.AzureCNIReconcileResult{}
+	if err := json.Unmarshal(output, result); err != nil {
+		return nil, fmt.Errorf("failed to decode response from Azure CNI when reconciling endpoints: [%w], response from CNI: [%s]", err, string(output))
+	}
+
+	return result, nil
+}
+
 func (c *client) GetVersion() (*semver.Version, error) {
 	cmd := c.exec.Command(platform.CNIBinaryPath, "-v")
 	cmd.SetDir(CNIExecDir)
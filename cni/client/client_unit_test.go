@@ -33,6 +33,35 @@ func TestGetState(t *testing.T) {
 	require.Equal(t, res, state)
 }
 
+func TestReconcileEndpoints(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"/opt/cni/bin/azure-vnet"}, Stdout: `{"Checked":2,"Repaired":null,"Reapplied":null,"Removed":[{"NetworkID":"azure","PodEndpointId":"3f813b02-eth0","ContainerID":"3f813b029429b4e41a09ab33b6f6d365d2ed704017524c78d1d0dece33cdaf46","NetNsPath":"/var/run/netns/cni-1","IPAddresses":[{"IP":"10.241.0.17","Mask":"//8AAA=="}],"Reason":"netns no longer exists, ip addresses queued for release"}],"Failed":null}`},
+	}
+
+	fakeexec := testutils.GetFakeExecWithScripts(calls)
+
+	c := New(fakeexec)
+	result, err := c.ReconcileEndpoints()
+	require.NoError(t, err)
+
+	removed := testGetPodNetworkInterfaceInfo("3f813b02-eth0", "", "", "3f813b029429b4e41a09ab33b6f6d365d2ed704017524c78d1d0dece33cdaf46", "10.241.0.17/16")
+	res := &api.AzureCNIReconcileResult{
+		Checked: 2,
+		Removed: []api.ReconciledEndpoint{
+			{
+				NetworkID:     "azure",
+				PodEndpointId: removed.PodEndpointId,
+				ContainerID:   removed.ContainerID,
+				NetNsPath:     "/var/run/netns/cni-1",
+				IPAddresses:   removed.IPAddresses,
+				Reason:        "netns no longer exists, ip addresses queued for release",
+			},
+		},
+	}
+
+	require.Equal(t, res, result)
+}
+
 func TestGetVersion(t *testing.T) {
 	calls := []testutils.TestCmd{
 		{Cmd: []string{"/opt/cni/bin/azure-vnet", "-v"}, Stdout: `Azure CNI Version v1.4.0-2-g984c5a5e-dirty`},
@@ -23,6 +23,8 @@ const (
 
 	// nonstandard CNI spec command, used to dump CNI state to stdout
 	CmdGetEndpointsState = "GET_ENDPOINT_STATE"
+	// nonstandard CNI spec command, used to reconcile the host dataplane with the CNI state and print the report to stdout
+	CmdReconcileEndpoints = "RECONCILE_ENDPOINTS"
 
 	// CNI errors.
 	ErrRuntime = 100
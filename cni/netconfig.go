@@ -63,6 +63,7 @@ type NetworkConfig struct {
 	LogTarget                     string          `json:"logTarget,omitempty"`
 	InfraVnetAddressSpace         string          `json:"infraVnetAddressSpace,omitempty"`
 	IPV6Mode                      string          `json:"ipv6Mode,omitempty"`
+	VlanIsolationMode             string          `json:"vlanIsolationMode,omitempty"`
 	ServiceCidrs                  string          `json:"serviceCidrs,omitempty"`
 	VnetCidrs                     string          `json:"vnetCidrs,omitempty"`
 	PodNamespaceForDualNetwork    []string        `json:"podNamespaceForDualNetwork,omitempty"`
@@ -56,7 +56,9 @@ type IPResultInfo struct {
 	skipDefaultRoutes  bool
 	routes             []cns.Route
 	pnpID              string
+	virtualFunction    *cns.VirtualFunction
 	endpointPolicies   []policy.Policy
+	egressIPAddress    string
 }
 
 func (i IPResultInfo) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
@@ -71,6 +73,10 @@ func (i IPResultInfo) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
 	encoder.AddString("macAddress", i.macAddress)
 	encoder.AddBool("skipDefaultRoutes", i.skipDefaultRoutes)
 	encoder.AddString("routes", fmt.Sprintf("%+v", i.routes))
+	encoder.AddString("egressIPAddress", i.egressIPAddress)
+	if i.virtualFunction != nil {
+		encoder.AddInt("virtualFunction", i.virtualFunction.Index)
+	}
 	return nil
 }
 
@@ -161,7 +167,9 @@ func (invoker *CNSIPAMInvoker) Add(addConfig IPAMAddConfig) (IPAMAddResult, erro
 			skipDefaultRoutes:  response.PodIPInfo[i].SkipDefaultRoutes,
 			routes:             response.PodIPInfo[i].Routes,
 			pnpID:              response.PodIPInfo[i].PnPID,
+			virtualFunction:    response.PodIPInfo[i].VirtualFunction,
 			endpointPolicies:   response.PodIPInfo[i].EndpointPolicies,
+			egressIPAddress:    response.PodIPInfo[i].EgressIPAddress,
 		}
 
 		logger.Info("Received info for pod",
@@ -448,6 +456,15 @@ func configureDefaultAddResult(info *IPResultInfo, addConfig *IPAMAddConfig, add
 			})
 		}
 
+		// the static egress ip of the pod namespace is ipv4 only and egresses via the host gateway
+		egressIP, egressGateway := addResult.interfaceInfo[key].EgressIP, addResult.interfaceInfo[key].EgressGateway
+		if info.egressIPAddress != "" && ip.To4() != nil {
+			egressIP, egressGateway = net.ParseIP(info.egressIPAddress), net.ParseIP(info.hostGateway)
+			if egressIP == nil || egressGateway == nil {
+				return errors.Wrap(errInvalidArgs, "egress ip "+info.egressIPAddress+" or host gateway "+info.hostGateway+" from response is invalid")
+			}
+		}
+
 		// if we have multiple infra ip result infos, we effectively append routes and ip configs to that same interface info each time
 		// the host subnet prefix (in ipv4 or ipv6) will always refer to the same interface regardless of which ip result info we look at
 		addResult.interfaceInfo[key] = network.InterfaceInfo{
@@ -457,6 +474,8 @@ func configureDefaultAddResult(info *IPResultInfo, addConfig *IPAMAddConfig, add
 			Routes:            resRoute,
 			HostSubnetPrefix:  *hostIPNet,
 			EndpointPolicies:  info.endpointPolicies,
+			EgressIP:          egressIP,
+			EgressGateway:     egressGateway,
 		}
 	}
 
@@ -526,6 +545,7 @@ func addBackendNICToResult(info *IPResultInfo, addResult *IPAMAddResult, key str
 		MacAddress:        macAddress,
 		SkipDefaultRoutes: info.skipDefaultRoutes,
 		PnPID:             info.pnpID,
+		VirtualFunction:   info.virtualFunction,
 	}
 
 	return nil
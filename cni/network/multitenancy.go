@@ -228,6 +228,10 @@ func (m *Multitenancy) GetAllNetworkContainers(
 
 		ipconfig, routes := convertToIPConfigAndRouteInfo(ifInfo.NCResponse)
 		ifInfo.IPConfigs = append(ifInfo.IPConfigs, ipconfig)
+		if ipv6Config := convertToIPv6Config(ifInfo.NCResponse); ipv6Config != nil {
+			ifInfo.IPConfigs = append(ifInfo.IPConfigs, ipv6Config)
+			ipamResult.ipv6Enabled = true
+		}
 		ifInfo.Routes = routes
 		ifInfo.NICType = cns.InfraNIC
 		ifInfo.SkipDefaultRoutes = ncResponses[i].SkipDefaultRoutes
@@ -299,6 +303,10 @@ func convertToCniResult(networkConfig *cns.GetNetworkContainerResponse, ifName s
 	resultIpconfig.Gateway = net.ParseIP(ipconfig.GatewayIPAddress)
 	result.IPs = append(result.IPs, resultIpconfig)
 
+	if ipv6Config := convertToIPv6Config(networkConfig); ipv6Config != nil {
+		result.IPs = append(result.IPs, &cniTypesCurr.IPConfig{Address: ipv6Config.Address, Gateway: ipv6Config.Gateway})
+	}
+
 	if networkConfig.Routes != nil && len(networkConfig.Routes) > 0 {
 		for _, route := range networkConfig.Routes {
 			_, routeIPnet, _ := net.ParseCIDR(route.IPAddress)
@@ -349,6 +357,19 @@ func convertToIPConfigAndRouteInfo(networkConfig *cns.GetNetworkContainerRespons
 	return ipconfig, routes
 }
 
+// convertToIPv6Config returns the ipv6 address of a dual-stack network container, nil if the nc has no ipv6 address
+func convertToIPv6Config(networkConfig *cns.GetNetworkContainerResponse) *network.IPConfig {
+	cnsIPConfig := networkConfig.IPv6Configuration
+	if cnsIPConfig.IPSubnet.IPAddress == "" {
+		return nil
+	}
+
+	return &network.IPConfig{
+		Address: net.IPNet{IP: net.ParseIP(cnsIPConfig.IPSubnet.IPAddress), Mask: net.CIDRMask(int(cnsIPConfig.IPSubnet.PrefixLength), ipv6FullMask)},
+		Gateway: net.ParseIP(cnsIPConfig.GatewayIPv6Address),
+	}
+}
+
 func checkIfSubnetOverlaps(enableInfraVnet bool, nwCfg *cni.NetworkConfig, cnsNetworkConfig *cns.GetNetworkContainerResponse) bool {
 	if enableInfraVnet {
 		if cnsNetworkConfig != nil {
@@ -95,6 +95,10 @@ func (m *MockMultitenancy) GetAllNetworkContainers(
 
 		ipconfig, routes := convertToIPConfigAndRouteInfo(ifInfo.NCResponse)
 		ifInfo.IPConfigs = append(ifInfo.IPConfigs, ipconfig)
+		if ipv6Config := convertToIPv6Config(ifInfo.NCResponse); ipv6Config != nil {
+			ifInfo.IPConfigs = append(ifInfo.IPConfigs, ipv6Config)
+			ipamResult.ipv6Enabled = true
+		}
 		ifInfo.Routes = routes
 		ifInfo.NICType = cns.InfraNIC
 
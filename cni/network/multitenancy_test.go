@@ -862,3 +862,31 @@ func TestGetMultiTenancyCNIResultNotFound(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertToIPv6Config(t *testing.T) {
+	ncResponse := &cns.GetNetworkContainerResponse{
+		IPConfiguration: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: "20.0.0.10", PrefixLength: 24},
+			GatewayIPAddress: "20.0.0.1",
+		},
+	}
+	require.Nil(t, convertToIPv6Config(ncResponse))
+
+	result := convertToCniResult(ncResponse, "eth1")
+	require.Len(t, result.IPs, 1)
+
+	ncResponse.IPv6Configuration = cns.IPConfiguration{
+		IPSubnet:           cns.IPSubnet{IPAddress: "fd00::10", PrefixLength: 64},
+		GatewayIPv6Address: "fd00::1",
+	}
+	ipconfig := convertToIPv6Config(ncResponse)
+	require.NotNil(t, ipconfig)
+	require.Equal(t, "fd00::10/64", ipconfig.Address.String())
+	require.Equal(t, "fd00::1", ipconfig.Gateway.String())
+
+	result = convertToCniResult(ncResponse, "eth1")
+	require.Len(t, result.IPs, 2)
+	require.Equal(t, "20.0.0.10/24", result.IPs[0].Address.String())
+	require.Equal(t, "fd00::10/64", result.IPs[1].Address.String())
+	require.Equal(t, "fd00::1", result.IPs[1].Gateway.String())
+}
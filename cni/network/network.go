@@ -201,6 +201,63 @@ func (plugin *NetPlugin) GetAllEndpointState(networkid string) (*api.AzureCNISta
 	return &st, nil
 }
 
+// ReconcileEndpoints re-creates missing host side objects for persisted endpoints and removes the state of
+// endpoints whose netns no longer exists. The network config is not available outside of a CNI ADD/DEL, so the
+// IP addresses of removed endpoints are released through the async delete path of CNS, like a DEL which can't
+// reach CNS.
+func (plugin *NetPlugin) ReconcileEndpoints() (*api.AzureCNIReconcileResult, error) {
+	report, err := plugin.nm.ReconcileEndpoints()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reconcile endpoints")
+	}
+
+	res := &api.AzureCNIReconcileResult{Checked: report.Checked}
+	for _, a := range report.Repaired {
+		res.Repaired = append(res.Repaired, toReconciledEndpoint(a))
+	}
+	for _, a := range report.Reapplied {
+		res.Reapplied = append(res.Reapplied, toReconciledEndpoint(a))
+	}
+	for _, a := range report.Removed {
+		a.Reason += ", " + releaseReconciledIPs(a)
+		res.Removed = append(res.Removed, toReconciledEndpoint(a))
+	}
+	for _, a := range report.Failed {
+		res.Failed = append(res.Failed, toReconciledEndpoint(a))
+	}
+
+	return res, nil
+}
+
+// releaseReconciledIPs queues the release of the IP addresses of a removed endpoint to CNS, and returns what
+// happened to them for the reason of the removal.
+func releaseReconciledIPs(a network.ReconcileAction) string {
+	if len(a.IPAddresses) == 0 || a.ContainerID == "" {
+		return "no ip addresses to release"
+	}
+
+	if err := fsnotify.AddFile(a.EndpointID, a.ContainerID, watcherPath); err != nil {
+		logger.Error("Failed to queue release of ip addresses of removed endpoint",
+			zap.String("endpointID", a.EndpointID), zap.String("containerID", a.ContainerID), zap.Error(err))
+		return fmt.Sprintf("ip addresses were not released: %v", err)
+	}
+
+	logger.Info("Queued release of ip addresses of removed endpoint",
+		zap.String("endpointID", a.EndpointID), zap.String("containerID", a.ContainerID))
+	return "ip addresses queued for release"
+}
+
+func toReconciledEndpoint(a network.ReconcileAction) api.ReconciledEndpoint {
+	return api.ReconciledEndpoint{
+		NetworkID:     a.NetworkID,
+		PodEndpointId: a.EndpointID,
+		ContainerID:   a.ContainerID,
+		NetNsPath:     a.NetNsPath,
+		IPAddresses:   a.IPAddresses,
+		Reason:        a.Reason,
+	}
+}
+
 // Stops the plugin.
 func (plugin *NetPlugin) Stop() {
 	plugin.nm.Uninitialize()
@@ -747,6 +804,9 @@ func (plugin *NetPlugin) createEpInfo(opt *createEpInfoOpt) (*network.EndpointIn
 		PODNameSpace:       opt.k8sNamespace,
 		SkipHotAttachEp:    false, // Hot attach at the time of endpoint creation
 		IPV6Mode:           opt.nwCfg.IPV6Mode,
+		VlanIsolationMode:  opt.nwCfg.VlanIsolationMode,
+		EgressIP:           opt.ifInfo.EgressIP,
+		EgressGateway:      opt.ifInfo.EgressGateway,
 		VnetCidrs:          opt.nwCfg.VnetCidrs,
 		ServiceCidrs:       opt.nwCfg.ServiceCidrs,
 		NATInfo:            opt.natInfo,
@@ -759,6 +819,7 @@ func (plugin *NetPlugin) createEpInfo(opt *createEpInfoOpt) (*network.EndpointIn
 		// the following is used for creating an external interface if we can't find an existing network
 		HostSubnetPrefix: opt.ifInfo.HostSubnetPrefix.String(),
 		PnPID:            opt.ifInfo.PnPID,
+		VirtualFunction:  opt.ifInfo.VirtualFunction,
 	}
 
 	if err = addSubnetToEndpointInfo(*opt.ifInfo, &endpointInfo); err != nil {
@@ -1018,7 +1079,7 @@ func (plugin *NetPlugin) Delete(args *cniSkel.CmdArgs) error {
 	if plugin.ipamInvoker == nil {
 		switch nwCfg.IPAM.Type {
 		case network.AzureCNS:
-			cnsClient, cnsErr := cnscli.New("", defaultRequestTimeout)
+			cnsClient, cnsErr := cnscli.New(nwCfg.CNSUrl, defaultRequestTimeout)
 			if cnsErr != nil {
 				logger.Error("failed to create cns client", zap.Error(cnsErr))
 				return errors.Wrap(cnsErr, "failed to create cns client")
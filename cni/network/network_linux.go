@@ -53,6 +53,10 @@ func setEndpointOptions(cnsNwConfig *cns.GetNetworkContainerResponse, epInfo *ne
 		epInfo.Data[network.VlanIDKey] = cnsNwConfig.MultiTenancyInfo.ID
 		epInfo.Data[network.LocalIPKey] = cnsNwConfig.LocalIPConfiguration.IPSubnet.IPAddress + "/" + strconv.Itoa(int(cnsNwConfig.LocalIPConfiguration.IPSubnet.PrefixLength))
 		epInfo.Data[network.SnatBridgeIPKey] = cnsNwConfig.LocalIPConfiguration.GatewayIPAddress + "/" + strconv.Itoa(int(cnsNwConfig.LocalIPConfiguration.IPSubnet.PrefixLength))
+		if localIPv6 := cnsNwConfig.LocalIPv6Configuration; localIPv6.IPSubnet.IPAddress != "" {
+			epInfo.Data[network.LocalIPv6Key] = localIPv6.IPSubnet.IPAddress + "/" + strconv.Itoa(int(localIPv6.IPSubnet.PrefixLength))
+			epInfo.Data[network.SnatBridgeIPv6Key] = localIPv6.GatewayIPv6Address + "/" + strconv.Itoa(int(localIPv6.IPSubnet.PrefixLength))
+		}
 		epInfo.AllowInboundFromHostToNC = cnsNwConfig.AllowHostToNCCommunication
 		epInfo.AllowInboundFromNCToHost = cnsNwConfig.AllowNCToHostCommunication
 		epInfo.NetworkContainerID = cnsNwConfig.NetworkContainerID
@@ -1849,3 +1849,22 @@ func TestValidateArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestReleaseReconciledIPs(t *testing.T) {
+	defer func(path string) { watcherPath = path }(watcherPath)
+	watcherPath = t.TempDir()
+
+	podIP := net.IPNet{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(16, 32)}
+	removed := acnnetwork.ReconcileAction{EndpointID: "12345678-eth0", ContainerID: "12345678abcd", IPAddresses: []net.IPNet{podIP}}
+
+	require.Equal(t, "ip addresses queued for release", releaseReconciledIPs(removed))
+	// the file is picked up by the async delete watcher of cns, which releases the ips of the pod interface
+	podInterfaceID, err := os.ReadFile(watcherPath + "/" + removed.ContainerID)
+	require.NoError(t, err)
+	require.Equal(t, removed.EndpointID, string(podInterfaceID))
+
+	require.Equal(t, "no ip addresses to release", releaseReconciledIPs(acnnetwork.ReconcileAction{EndpointID: "delegated", ContainerID: "abcd"}))
+
+	watcherPath += "/missing"
+	require.Contains(t, releaseReconciledIPs(removed), "ip addresses were not released")
+}
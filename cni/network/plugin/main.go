@@ -150,6 +150,24 @@ func rootExecute() error {
 
 			return errors.Wrap(err, "Get cni state printresult error")
 		}
+
+		// used to repair the host dataplane, e.g. after a node reboot or a manual cleanup
+		if cniCmd == cni.CmdReconcileEndpoints {
+			logger.Info("Reconciling endpoints")
+			var result *api.AzureCNIReconcileResult
+			result, err = netPlugin.ReconcileEndpoints()
+			if err != nil {
+				logger.Error("Failed to reconcile endpoints", zap.Error(err))
+				return errors.Wrap(err, "Reconcile endpoints error")
+			}
+
+			err = result.PrintResult()
+			if err != nil {
+				logger.Error("Failed to print reconcile result to stdout", zap.Error(err))
+			}
+
+			return errors.Wrap(err, "Reconcile endpoints printresult error")
+		}
 	}
 
 	handled, _ := network.HandleIfCniUpdate(netPlugin.Update)
@@ -6,6 +6,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
@@ -38,6 +39,7 @@ const (
 	PathDebugIPAddresses                     = "/debug/ipaddresses"
 	PathDebugPodContext                      = "/debug/podcontext"
 	PathDebugRestData                        = "/debug/restdata"
+	PathDebugEgressIPs                       = "/debug/egressips"
 	NumberOfCPUCores                         = NumberOfCPUCoresPath
 	NMAgentSupportedAPIs                     = NmAgentSupportedApisPath
 	EndpointAPI                              = EndpointPath
@@ -120,6 +122,8 @@ type CreateNetworkContainerRequest struct {
 	LocalIPConfiguration       IPConfiguration
 	OrchestratorContext        json.RawMessage
 	IPConfiguration            IPConfiguration
+	IPv6Configuration          IPConfiguration              // Optional, the IPv6 subnet and gateway (GatewayIPv6Address) of a dual-stack NC.
+	LocalIPv6Configuration     IPConfiguration              // Optional, the IPv6 SNAT bridge address (GatewayIPv6Address) of a dual-stack NC.
 	SecondaryIPConfigs         map[string]SecondaryIPConfig // uuid is key
 	MultiTenancyInfo           MultiTenancyInfo
 	CnetAddressSpace           []IPSubnet // To setup SNAT (should include service endpoint vips).
@@ -145,9 +149,31 @@ func (req *CreateNetworkContainerRequest) Validate() error {
 	if req.IPConfiguration.GatewayIPAddress != "" && !isValidIP(req.IPConfiguration.GatewayIPAddress) {
 		return errors.Wrapf(ErrInvalidIP, "GatewayIPAddress %s is not a valid ip address", req.IPConfiguration.GatewayIPAddress)
 	}
+	if err := validateIPv6Configuration(req.IPv6Configuration); err != nil {
+		return errors.Wrap(err, "IPv6Configuration is invalid")
+	}
+	if err := validateIPv6Configuration(req.LocalIPv6Configuration); err != nil {
+		return errors.Wrap(err, "LocalIPv6Configuration is invalid")
+	}
+	return nil
+}
+
+// validateIPv6Configuration checks that the addresses of an optional IPv6 configuration are IPv6 addresses.
+func validateIPv6Configuration(ipConfig IPConfiguration) error {
+	if ipConfig.IPSubnet.IPAddress != "" && !isValidIPv6(ipConfig.IPSubnet.IPAddress) {
+		return errors.Wrapf(ErrInvalidIP, "IPAddress %s is not a valid ipv6 address", ipConfig.IPSubnet.IPAddress)
+	}
+	if ipConfig.GatewayIPv6Address != "" && !isValidIPv6(ipConfig.GatewayIPv6Address) {
+		return errors.Wrapf(ErrInvalidIP, "GatewayIPv6Address %s is not a valid ipv6 address", ipConfig.GatewayIPv6Address)
+	}
 	return nil
 }
 
+func isValidIPv6(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	return ip != nil && ip.To4() == nil
+}
+
 func isValidIP(ipStr string) bool {
 	// if can parse (i.e. not nil), then valid ip
 	if ip, _, err := net.ParseCIDR(ipStr); err == nil {
@@ -161,10 +187,10 @@ func isValidIP(ipStr string) bool {
 func (req *CreateNetworkContainerRequest) String() string {
 	return fmt.Sprintf("CreateNetworkContainerRequest"+
 		"{Version: %s, NetworkContainerType: %s, NetworkContainerid: %s, PrimaryInterfaceIdentifier: %s, "+
-		"LocalIPConfiguration: %+v, IPConfiguration: %+v, SecondaryIPConfigs: %+v, MultitenancyInfo: %+v, "+
+		"LocalIPConfiguration: %+v, IPConfiguration: %+v, IPv6Configuration: %+v, LocalIPv6Configuration: %+v, SecondaryIPConfigs: %+v, MultitenancyInfo: %+v, "+
 		"AllowHostToNCCommunication: %t, AllowNCToHostCommunication: %t, SkipDefaultRoutes: %t, NCStatus: %s, NetworkInterfaceInfo: %+v}",
 		req.Version, req.NetworkContainerType, req.NetworkContainerid, req.PrimaryInterfaceIdentifier, req.LocalIPConfiguration,
-		req.IPConfiguration, req.SecondaryIPConfigs, req.MultiTenancyInfo, req.AllowHostToNCCommunication, req.AllowNCToHostCommunication,
+		req.IPConfiguration, req.IPv6Configuration, req.LocalIPv6Configuration, req.SecondaryIPConfigs, req.MultiTenancyInfo, req.AllowHostToNCCommunication, req.AllowNCToHostCommunication,
 		req.SkipDefaultRoutes, string(req.NCStatus), req.NetworkInterfaceInfo)
 }
 
@@ -496,6 +522,8 @@ type GetNetworkContainerResponse struct {
 	MultiTenancyInfo           MultiTenancyInfo
 	PrimaryInterfaceIdentifier string
 	LocalIPConfiguration       IPConfiguration
+	IPv6Configuration          IPConfiguration
+	LocalIPv6Configuration     IPConfiguration
 	Response                   Response
 	AllowHostToNCCommunication bool
 	AllowNCToHostCommunication bool
@@ -519,6 +547,23 @@ type PodIpInfo struct {
 	PnPID string
 	// Default Deny ACL's to configure on HNS endpoints for Swiftv2 window nodes
 	EndpointPolicies []policy.Policy
+	// EgressIPAddress is the static egress IP of the pod namespace, empty if the namespace has none
+	EgressIPAddress string `json:",omitempty"`
+	// VirtualFunction is the SR-IOV virtual function of the backend interface allocated to the pod, nil if the
+	// physical function identified by PnPID has no virtual functions
+	VirtualFunction *VirtualFunction `json:",omitempty"`
+}
+
+// VirtualFunction is an SR-IOV virtual function of a physical function, allocated to a pod by CNS.
+type VirtualFunction struct {
+	// Index of the virtual function on the physical function
+	Index int
+	// MacAddress to program on the virtual function
+	MacAddress string
+	// VlanID to tag the virtual function traffic with, 0 for untagged
+	VlanID int `json:",omitempty"`
+	// Trust allows the pod to change the mac address and enable promiscuous mode of the virtual function
+	Trust bool `json:",omitempty"`
 }
 
 type HostIPInfo struct {
@@ -537,14 +582,15 @@ type IPConfigRequest struct {
 
 // Same as IPConfigRequest except that DesiredIPAddresses is passed in as a slice
 type IPConfigsRequest struct {
-	DesiredIPAddresses           []string        `json:"desiredIPAddresses"`
-	PodInterfaceID               string          `json:"podInterfaceID"`
-	InfraContainerID             string          `json:"infraContainerID"`
-	OrchestratorContext          json.RawMessage `json:"orchestratorContext"`
-	Ifname                       string          `json:"ifname"`                   // Used by delegated IPAM
-	SecondaryInterfacesExist     bool            `json:"secondaryInterfacesExist"` // will be set by SWIFT v2 validator func
-	BackendInterfaceExist        bool            `json:"BackendInterfaceExist"`    // will be set by SWIFT v2 validator func
-	BackendInterfaceMacAddresses []string        `json:"BacknendInterfaceMacAddress"`
+	DesiredIPAddresses           []string                   `json:"desiredIPAddresses"`
+	PodInterfaceID               string                     `json:"podInterfaceID"`
+	InfraContainerID             string                     `json:"infraContainerID"`
+	OrchestratorContext          json.RawMessage            `json:"orchestratorContext"`
+	Ifname                       string                     `json:"ifname"`                   // Used by delegated IPAM
+	SecondaryInterfacesExist     bool                       `json:"secondaryInterfacesExist"` // will be set by SWIFT v2 validator func
+	BackendInterfaceExist        bool                       `json:"BackendInterfaceExist"`    // will be set by SWIFT v2 validator func
+	BackendInterfaceMacAddresses []string                   `json:"BacknendInterfaceMacAddress"`
+	BackendInterfaceVFs          map[string]VirtualFunction `json:"backendInterfaceVFs,omitempty"` // keyed by mac address, will be set by SWIFT v2 validator func
 }
 
 // IPConfigResponse is used in CNS IPAM mode as a response to CNI ADD
@@ -583,6 +629,42 @@ type GetPodContextResponse struct {
 	Response   Response
 }
 
+// EgressIPAssignment is the static egress IP reserved from a network container of the node for the pods of a
+// namespace on the node. Every node reserves its own egress IP for the namespace when the first pod of the
+// namespace is scheduled on it, and releases it when the last one is gone.
+type EgressIPAssignment struct {
+	Namespace string
+	// NetworkContainerID is the network container the egress IP was last reserved from
+	NetworkContainerID string
+	// IPConfigID and IPAddress are the current egress IP, empty when no pod of the namespace is on the node
+	IPConfigID string `json:",omitempty"`
+	IPAddress  string `json:",omitempty"`
+	// Pods are the keys of the pods which were given the current egress IP
+	Pods []string `json:",omitempty"`
+	// RetiredIPs are the previous egress IPs which pods still SNAT to
+	RetiredIPs []RetiredEgressIP `json:",omitempty"`
+	// Disabled is set once the namespace opted out, the assignment is removed when its retired IPs are gone
+	Disabled bool `json:",omitempty"`
+	// PreviousIPAddress is the egress IP of the namespace before the last failover
+	PreviousIPAddress string `json:",omitempty"`
+	FailoverCount     int
+	LastFailoverTime  time.Time `json:",omitempty"`
+}
+
+// RetiredEgressIP is an egress IP which was lost or which the namespace opted out of while pods were using it.
+// It is kept out of the pool of available IPs until these pods are gone, since their endpoints still SNAT to it.
+type RetiredEgressIP struct {
+	IPConfigID string
+	IPAddress  string
+	Pods       []string
+}
+
+// GetEgressIPsResponse is used in CNS Client debug mode to get the egress IPs of the namespaces
+type GetEgressIPsResponse struct {
+	EgressIPs map[string]EgressIPAssignment // Namespace is the key
+	Response  Response
+}
+
 // IPAddressState Only used in the GetIPConfig API to return IPs that match a filter
 type IPAddressState struct {
 	IPAddress string
@@ -135,6 +135,41 @@ func TestCreateNetworkContainerRequestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid dual-stack",
+			req: CreateNetworkContainerRequest{
+				NetworkContainerid: "f47ac10b-58cc-0372-8567-0e02b2c3d479",
+				IPv6Configuration: IPConfiguration{
+					IPSubnet:           IPSubnet{IPAddress: "fd00::4", PrefixLength: 64},
+					GatewayIPv6Address: "fd00::1",
+				},
+				LocalIPv6Configuration: IPConfiguration{
+					IPSubnet:           IPSubnet{IPAddress: "fd00:a9fe:8000::4", PrefixLength: 64},
+					GatewayIPv6Address: "fd00:a9fe:8000::1",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ipv4 address in ipv6 configuration",
+			req: CreateNetworkContainerRequest{
+				NetworkContainerid: "f47ac10b-58cc-0372-8567-0e02b2c3d479",
+				IPv6Configuration: IPConfiguration{
+					IPSubnet: IPSubnet{IPAddress: "10.0.0.4", PrefixLength: 24},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid ipv6 snat gateway",
+			req: CreateNetworkContainerRequest{
+				NetworkContainerid: "f47ac10b-58cc-0372-8567-0e02b2c3d479",
+				LocalIPv6Configuration: IPConfiguration{
+					GatewayIPv6Address: "not-an-ip",
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
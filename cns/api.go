@@ -305,10 +305,36 @@ type IpamPoolMonitorStateSnapshot struct {
 	CachedNNC                v1alpha.NodeNetworkConfig
 }
 
-// Response describes generic response from CNS.
+// Response describes generic response from CNS. Failed responses carry an Error envelope in addition
+// to the legacy ReturnCode and Message, which old clients still read.
 type Response struct {
 	ReturnCode types.ResponseCode `json:"ReturnCode"`
 	Message    string             `json:"Message"`
+	Error      *types.Error       `json:"Error,omitempty"`
+}
+
+// MarshalJSON fills in the Error envelope of failed responses from the ReturnCode and Message, so that
+// every handler returns it.
+func (r Response) MarshalJSON() ([]byte, error) {
+	type response Response
+	if r.Error == nil && r.ReturnCode != types.Success {
+		r.Error = types.NewError(r.ReturnCode, r.Message)
+	}
+	b, err := json.Marshal(response(r))
+	return b, errors.Wrap(err, "failed to marshal response")
+}
+
+// Err returns the error of a failed response, or nil if it succeeded. Responses from CNS versions which
+// don't return the Error envelope get one from their ReturnCode and Message.
+func (r *Response) Err() error {
+	switch {
+	case r.ReturnCode == types.Success:
+		return nil
+	case r.Error != nil:
+		return r.Error
+	default:
+		return types.NewError(r.ReturnCode, r.Message)
+	}
 }
 
 // NumOfCPUCoresResponse describes num of cpu cores present on host.
@@ -0,0 +1,62 @@
+package cns
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseErrorEnvelope(t *testing.T) {
+	t.Run("failed responses carry the envelope and the legacy fields", func(t *testing.T) {
+		b, err := json.Marshal(IPConfigsResponse{
+			Response: Response{ReturnCode: types.FailedToAllocateIPConfig, Message: "no IPs available"},
+		})
+		require.NoError(t, err)
+
+		var legacy struct {
+			Response struct {
+				ReturnCode types.ResponseCode
+				Message    string
+			}
+		}
+		require.NoError(t, json.Unmarshal(b, &legacy))
+		assert.Equal(t, types.FailedToAllocateIPConfig, legacy.Response.ReturnCode)
+		assert.Equal(t, "no IPs available", legacy.Response.Message)
+
+		var resp IPConfigsResponse
+		require.NoError(t, json.Unmarshal(b, &resp))
+		require.NotNil(t, resp.Response.Error)
+		assert.Equal(t, types.CategoryResourceExhausted, resp.Response.Error.Category)
+		assert.True(t, resp.Response.Error.Retryable)
+		require.ErrorIs(t, resp.Response.Err(), types.ErrRetryable)
+	})
+
+	t.Run("details set by handlers are kept", func(t *testing.T) {
+		e := types.NewError(types.NotFound, "nc not found")
+		e.Details = map[string]string{"ncID": "nc1"}
+		b, err := json.Marshal(Response{ReturnCode: types.NotFound, Message: "nc not found", Error: e})
+		require.NoError(t, err)
+
+		var resp Response
+		require.NoError(t, json.Unmarshal(b, &resp))
+		assert.Equal(t, e, resp.Error)
+	})
+
+	t.Run("successful responses have no envelope", func(t *testing.T) {
+		b, err := json.Marshal(Response{})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"ReturnCode":0,"Message":""}`, string(b))
+		assert.NoError(t, (&Response{}).Err())
+	})
+
+	t.Run("responses of old versions get an envelope from the legacy fields", func(t *testing.T) {
+		var resp Response
+		require.NoError(t, json.Unmarshal([]byte(`{"ReturnCode":18,"Message":"unknown container"}`), &resp))
+		err := resp.Err()
+		require.ErrorIs(t, err, types.ErrNotFound)
+		assert.EqualError(t, err, "unknown container")
+	})
+}
@@ -0,0 +1,133 @@
+// Package authz authorizes requests to the CNS API by mapping the identity of the client, from its
+// mTLS certificate or its Unix socket peer credentials, to the API groups it's allowed to call.
+package authz
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Group is a set of CNS APIs which are authorized together.
+type Group string
+
+const (
+	// GroupIPAM is the IP address management APIs called by CNI on every Pod.
+	GroupIPAM Group = "ipam"
+	// GroupNCAdmin is the APIs which create, update and delete NetworkContainers and networks.
+	GroupNCAdmin Group = "nc-admin"
+	// GroupDebug is the debug and pprof APIs, which expose the internal state of CNS.
+	GroupDebug Group = "debug"
+)
+
+var errUnknownGroup = errors.New("unknown API group")
+
+// Rule allows its Groups to the clients matching any of its identities.
+type Rule struct {
+	// SubjectNames match the common name or a DNS SAN of the verified client certificate,
+	// case-insensitively.
+	SubjectNames []string
+	// UIDs and GIDs match the peer credentials of a client connected over a Unix socket.
+	UIDs   []uint32
+	GIDs   []uint32
+	Groups []Group
+}
+
+// Policy maps client identities to the API groups they're allowed to call.
+type Policy struct {
+	// DefaultGroups are allowed to every client, including clients without an identity.
+	DefaultGroups []Group
+	Rules         []Rule
+}
+
+// identity is who the client of a request is, as far as it can be told.
+type identity struct {
+	names []string
+	cred  *common.PeerCred
+}
+
+func identityOf(r *http.Request) identity {
+	var id identity
+	// only verified certificates identify the client
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cert := r.TLS.VerifiedChains[0][0]
+		if cert.Subject.CommonName != "" {
+			id.names = append(id.names, cert.Subject.CommonName)
+		}
+		id.names = append(id.names, cert.DNSNames...)
+	}
+	if cred, ok := common.PeerCredFromContext(r.Context()); ok {
+		id.cred = &cred
+	}
+	return id
+}
+
+func (r *Rule) matches(id identity) bool {
+	for _, name := range r.SubjectNames {
+		if slices.ContainsFunc(id.names, func(n string) bool { return strings.EqualFold(n, name) }) {
+			return true
+		}
+	}
+	if id.cred != nil {
+		return slices.Contains(r.UIDs, id.cred.UID) || slices.Contains(r.GIDs, id.cred.GID)
+	}
+	return false
+}
+
+// Authorizer enforces a Policy on the requests to the CNS API.
+type Authorizer struct {
+	z      *zap.Logger
+	policy Policy
+}
+
+// New creates an Authorizer enforcing the policy, and fails if the policy allows unknown groups.
+func New(z *zap.Logger, policy Policy) (*Authorizer, error) {
+	groups := slices.Clone(policy.DefaultGroups)
+	for i := range policy.Rules {
+		groups = append(groups, policy.Rules[i].Groups...)
+	}
+	for _, g := range groups {
+		if !slices.Contains([]Group{GroupIPAM, GroupNCAdmin, GroupDebug}, g) {
+			return nil, errors.Wrapf(errUnknownGroup, "%q", g)
+		}
+	}
+	return &Authorizer{z: z.With(zap.String("component", "authz")), policy: policy}, nil
+}
+
+// allowed reports whether the client of the request may call the group.
+func (a *Authorizer) allowed(r *http.Request, group Group) bool {
+	if slices.Contains(a.policy.DefaultGroups, group) {
+		return true
+	}
+	id := identityOf(r)
+	for i := range a.policy.Rules {
+		if slices.Contains(a.policy.Rules[i].Groups, group) && a.policy.Rules[i].matches(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects the requests to APIs the client isn't allowed to call with 403 Forbidden. APIs
+// which don't belong to any group are rejected for every client.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group, ok := GroupOf(r.URL.Path)
+		if ok && a.allowed(r, group) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		deniedRequests.WithLabelValues(string(group)).Inc()
+		id := identityOf(r)
+		fields := []zap.Field{zap.String("path", r.URL.Path), zap.String("group", string(group)), zap.Strings("names", id.names)}
+		if id.cred != nil {
+			fields = append(fields, zap.Int32("pid", id.cred.PID), zap.Uint32("uid", id.cred.UID), zap.Uint32("gid", id.cred.GID))
+		}
+		a.z.Warn("denied request", fields...)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
@@ -0,0 +1,124 @@
+package authz
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGroupOf(t *testing.T) {
+	tests := []struct {
+		path  string
+		group Group
+		ok    bool
+	}{
+		{path: cns.RequestIPConfigs, group: GroupIPAM, ok: true},
+		{path: cns.V2Prefix + cns.ReleaseIPConfigs, group: GroupIPAM, ok: true},
+		{path: cns.EndpointPath + "container-id", group: GroupIPAM, ok: true},
+		{path: cns.CreateOrUpdateNetworkContainer, group: GroupNCAdmin, ok: true},
+		{path: cns.V1Prefix + cns.DeleteNetworkContainer, group: GroupNCAdmin, ok: true},
+		{path: cns.PathDebugRestData, group: GroupDebug, ok: true},
+		{path: "/debug/pprof/heap", group: GroupDebug, ok: true},
+		{path: "/unknown", ok: false},
+		{path: cns.V2Prefix, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			group, ok := GroupOf(tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.group, group)
+		})
+	}
+}
+
+func TestNewRejectsUnknownGroups(t *testing.T) {
+	_, err := New(zap.NewNop(), Policy{Rules: []Rule{{SubjectNames: []string{"dnc"}, Groups: []Group{"admin"}}}})
+	require.ErrorIs(t, err, errUnknownGroup)
+}
+
+func TestMiddleware(t *testing.T) {
+	a, err := New(zap.NewNop(), Policy{
+		DefaultGroups: []Group{GroupIPAM},
+		Rules: []Rule{
+			{SubjectNames: []string{"dnc.azure.com"}, Groups: []Group{GroupNCAdmin}},
+			{UIDs: []uint32{0}, Groups: []Group{GroupDebug}},
+			{GIDs: []uint32{1000}, Groups: []Group{GroupNCAdmin, GroupDebug}},
+		},
+	})
+	require.NoError(t, err)
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	withCert := func(r *http.Request, cert *x509.Certificate) *http.Request {
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+	withPeerCred := func(r *http.Request, cred common.PeerCred) *http.Request {
+		return r.WithContext(common.WithPeerCred(r.Context(), cred))
+	}
+	dnc := &x509.Certificate{Subject: pkix.Name{CommonName: "other"}, DNSNames: []string{"DNC.azure.com"}}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{
+			name: "default group without identity",
+			req:  httptest.NewRequest(http.MethodPost, cns.RequestIPConfigs, http.NoBody),
+			want: http.StatusOK,
+		},
+		{
+			name: "group without identity",
+			req:  httptest.NewRequest(http.MethodPost, cns.CreateOrUpdateNetworkContainer, http.NoBody),
+			want: http.StatusForbidden,
+		},
+		{
+			name: "group allowed to certificate SAN",
+			req:  withCert(httptest.NewRequest(http.MethodPost, cns.CreateOrUpdateNetworkContainer, http.NoBody), dnc),
+			want: http.StatusOK,
+		},
+		{
+			name: "group not allowed to certificate",
+			req:  withCert(httptest.NewRequest(http.MethodGet, cns.PathDebugRestData, http.NoBody), dnc),
+			want: http.StatusForbidden,
+		},
+		{
+			name: "group allowed to peer UID",
+			req:  withPeerCred(httptest.NewRequest(http.MethodGet, cns.PathDebugRestData, http.NoBody), common.PeerCred{UID: 0, GID: 0}),
+			want: http.StatusOK,
+		},
+		{
+			name: "group allowed to peer GID",
+			req:  withPeerCred(httptest.NewRequest(http.MethodPost, cns.DeleteNetworkContainer, http.NoBody), common.PeerCred{UID: 1000, GID: 1000}),
+			want: http.StatusOK,
+		},
+		{
+			name: "group not allowed to peer",
+			req:  withPeerCred(httptest.NewRequest(http.MethodPost, cns.DeleteNetworkContainer, http.NoBody), common.PeerCred{UID: 0, GID: 0}),
+			want: http.StatusForbidden,
+		},
+		{
+			name: "API without group",
+			req:  withPeerCred(httptest.NewRequest(http.MethodGet, "/unknown", http.NoBody), common.PeerCred{UID: 1000, GID: 1000}),
+			want: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tt.req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
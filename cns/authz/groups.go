@@ -0,0 +1,61 @@
+package authz
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-container-networking/cns"
+)
+
+// groups maps the paths of the CNS API, without their version prefix, to their group.
+var groups = map[string]Group{
+	cns.RequestIPConfig:                          GroupIPAM,
+	cns.RequestIPConfigs:                         GroupIPAM,
+	cns.ReleaseIPConfig:                          GroupIPAM,
+	cns.ReleaseIPConfigs:                         GroupIPAM,
+	cns.GetNetworkContainerByOrchestratorContext: GroupIPAM,
+	cns.GetInterfaceForContainer:                 GroupIPAM,
+	cns.GetHostLocalIPPath:                       GroupIPAM,
+	cns.GetHealthReportPath:                      GroupIPAM,
+	cns.NumberOfCPUCoresPath:                     GroupIPAM,
+	cns.NmAgentSupportedApisPath:                 GroupIPAM,
+	cns.GetHomeAz:                                GroupIPAM,
+
+	cns.SetEnvironmentPath:             GroupNCAdmin,
+	cns.SetOrchestratorType:            GroupNCAdmin,
+	cns.CreateNetworkPath:              GroupNCAdmin,
+	cns.DeleteNetworkPath:              GroupNCAdmin,
+	cns.CreateHnsNetworkPath:           GroupNCAdmin,
+	cns.DeleteHnsNetworkPath:           GroupNCAdmin,
+	cns.CreateOrUpdateNetworkContainer: GroupNCAdmin,
+	cns.DeleteNetworkContainer:         GroupNCAdmin,
+	cns.PublishNetworkContainer:        GroupNCAdmin,
+	cns.UnpublishNetworkContainer:      GroupNCAdmin,
+	cns.GetAllNetworkContainers:        GroupNCAdmin,
+	cns.NetworkContainersURLPath:       GroupNCAdmin,
+	cns.AttachContainerToNetwork:       GroupNCAdmin,
+	cns.DetachContainerFromNetwork:     GroupNCAdmin,
+	cns.CreateHostNCApipaEndpointPath:  GroupNCAdmin,
+	cns.DeleteHostNCApipaEndpointPath:  GroupNCAdmin,
+	cns.GetNCList:                      GroupNCAdmin,
+	cns.GetVMUniqueID:                  GroupNCAdmin,
+}
+
+// GroupOf returns the group of the CNS API served at the path.
+func GroupOf(path string) (Group, bool) {
+	for _, prefix := range []string{cns.V1Prefix, cns.V2Prefix} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok && strings.HasPrefix(rest, "/") {
+			path = rest
+			break
+		}
+	}
+	switch {
+	case strings.HasPrefix(path, "/debug/"):
+		// includes the pprof endpoints
+		return GroupDebug, true
+	case strings.HasPrefix(path, cns.EndpointPath):
+		// endpoint state is updated by CNI for every Pod
+		return GroupIPAM, true
+	}
+	group, ok := groups[path]
+	return group, ok
+}
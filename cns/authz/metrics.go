@@ -0,0 +1,20 @@
+package authz
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// deniedRequests counts the requests rejected because the client isn't allowed the API group.
+// Requests to APIs without a group are counted with an empty group.
+var deniedRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cns_authz_denied_requests_total",
+		Help: "Number of requests to the CNS API denied by the authorization policy, by API group.",
+	},
+	[]string{"group"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(deniedRequests)
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package cns
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	localtls "github.com/Azure/azure-container-networking/server/tls"
+	"github.com/pkg/errors"
+)
+
+// certReloader serves the TLS certificate and client CAs read from files, and reloads them once
+// the files change on disk, so that they can be rotated without restarting CNS. The files are
+// polled rather than watched, since rotated Kubernetes secrets are swapped in through symlinks.
+type certReloader struct {
+	settings localtls.TlsSettings
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	digest    [sha256.Size]byte
+}
+
+// newCertReloader loads the certificate and client CAs of the settings.
+func newCertReloader(settings localtls.TlsSettings) (*certReloader, error) {
+	r := &certReloader{settings: settings}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// certificate returns the current certificate.
+func (r *certReloader) certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// getClientCAs returns the current client CAs.
+func (r *certReloader) getClientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clientCAs
+}
+
+// run reloads the files every interval until the context is canceled.
+func (r *certReloader) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.reload()
+		switch {
+		case err != nil:
+			tlsCertificateReloads.WithLabelValues("failure").Inc()
+			logger.Errorf("Failed to reload TLS certificates, serving the previous ones: %v", err)
+		case reloaded:
+			tlsCertificateReloads.WithLabelValues("success").Inc()
+			logger.Printf("Reloaded TLS certificates from %s", r.settings.TLSCertificatePath)
+		}
+	}
+}
+
+// reload loads the certificate and client CAs if the files have changed, and reports whether they
+// were loaded. The previous certificate and client CAs are kept if they can't be loaded.
+func (r *certReloader) reload() (bool, error) {
+	digest, err := r.fileDigest()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && digest == r.digest
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := loadTLSCertificate(r.settings)
+	if err != nil {
+		return false, err
+	}
+
+	var clientCAs *x509.CertPool
+	var clientCAsExpiry time.Time
+	if r.settings.UseMTLS {
+		if r.settings.MtlsClientCAPath != "" {
+			clientCAs, clientCAsExpiry, err = loadCertPool(r.settings.MtlsClientCAPath)
+		} else {
+			clientCAs, err = mtlsRootCAsFromCertificate(cert)
+			clientCAsExpiry = cert.Leaf.NotAfter
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get root CAs for configuring mTLS")
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.digest = cert, clientCAs, digest
+	r.mu.Unlock()
+
+	tlsCertificateExpiry.WithLabelValues(serverCertificate).Set(float64(cert.Leaf.NotAfter.Unix()))
+	if r.settings.UseMTLS {
+		tlsCertificateExpiry.WithLabelValues(clientCACertificate).Set(float64(clientCAsExpiry.Unix()))
+	}
+	return true, nil
+}
+
+// fileDigest hashes the certificate and client CA files, to tell whether they have changed.
+func (r *certReloader) fileDigest() ([sha256.Size]byte, error) {
+	h := sha256.New()
+	for _, path := range []string{r.settings.TLSCertificatePath, r.settings.MtlsClientCAPath} {
+		if path == "" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return [sha256.Size]byte{}, errors.Wrapf(err, "failed to read %s", path)
+		}
+		h.Write(b)
+	}
+	var digest [sha256.Size]byte
+	copy(digest[:], h.Sum(nil))
+	return digest, nil
+}
+
+// loadTLSCertificate loads the certificate and private key of the settings.
+func loadTLSCertificate(tlsSettings localtls.TlsSettings) (*tls.Certificate, error) {
+	tlsCertRetriever, err := localtls.GetTlsCertificateRetriever(tlsSettings)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get certificate retriever")
+	}
+
+	leafCertificate, err := tlsCertRetriever.GetCertificate()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get certificate")
+	}
+
+	if leafCertificate == nil {
+		return nil, errors.New("certificate retrieval returned empty")
+	}
+
+	privateKey, err := tlsCertRetriever.GetPrivateKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get certificate private key")
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{leafCertificate.Raw},
+		PrivateKey:  privateKey,
+		Leaf:        leafCertificate,
+	}, nil
+}
+
+// loadCertPool loads a PEM bundle of CA certificates, and returns the first expiry among them.
+func loadCertPool(path string) (*x509.CertPool, time.Time, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "failed to read CA bundle %s", path)
+	}
+	pool := x509.NewCertPool()
+	var expiry time.Time
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != localtls.CertLabel {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, time.Time{}, errors.Wrapf(err, "failed to parse CA certificate in %s", path)
+		}
+		pool.AddCert(cert)
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	if expiry.IsZero() {
+		return nil, time.Time{}, errors.Errorf("no CA certificates found in %s", path)
+	}
+	return pool, expiry, nil
+}
@@ -0,0 +1,119 @@
+package cns
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	serverTLS "github.com/Azure/azure-container-networking/server/tls"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertReloader(t *testing.T) {
+	certPath := filepath.Join(t.TempDir(), "cert.pem")
+	firstExpiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	require.NoError(t, os.WriteFile(certPath, newTestCertificatePEM(t, firstExpiry), 0o600))
+
+	r, err := newCertReloader(serverTLS.TlsSettings{TLSCertificatePath: certPath, UseMTLS: true})
+	require.NoError(t, err)
+	require.Equal(t, firstExpiry.Unix(), r.certificate().Leaf.NotAfter.Unix())
+	require.NotNil(t, r.getClientCAs())
+	require.InDelta(t, float64(firstExpiry.Unix()), testutil.ToFloat64(tlsCertificateExpiry.WithLabelValues(serverCertificate)), 0)
+
+	// unchanged files aren't reloaded
+	reloaded, err := r.reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	// a rotated certificate is reloaded
+	secondExpiry := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	require.NoError(t, os.WriteFile(certPath, newTestCertificatePEM(t, secondExpiry), 0o600))
+	reloaded, err = r.reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.Equal(t, secondExpiry.Unix(), r.certificate().Leaf.NotAfter.Unix())
+	require.InDelta(t, float64(secondExpiry.Unix()), testutil.ToFloat64(tlsCertificateExpiry.WithLabelValues(serverCertificate)), 0)
+
+	// an invalid certificate keeps the previous one
+	require.NoError(t, os.WriteFile(certPath, []byte("not a certificate"), 0o600))
+	_, err = r.reload()
+	require.Error(t, err)
+	require.Equal(t, secondExpiry.Unix(), r.certificate().Leaf.NotAfter.Unix())
+}
+
+func TestCertReloaderClientCABundle(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(certPath, newTestCertificatePEM(t, time.Now().Add(time.Hour)), 0o600))
+
+	// the bundle expires with its first CA
+	caExpiry := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+	caPath := filepath.Join(dir, "ca.pem")
+	bundle := append(newTestCertificatePEM(t, time.Now().Add(time.Hour)), newTestCertificatePEM(t, caExpiry)...)
+	require.NoError(t, os.WriteFile(caPath, bundle, 0o600))
+
+	r, err := newCertReloader(serverTLS.TlsSettings{TLSCertificatePath: certPath, UseMTLS: true, MtlsClientCAPath: caPath})
+	require.NoError(t, err)
+	require.NotNil(t, r.getClientCAs())
+	require.InDelta(t, float64(caExpiry.Unix()), testutil.ToFloat64(tlsCertificateExpiry.WithLabelValues(clientCACertificate)), 0)
+
+	// a bundle without CAs isn't loaded
+	require.NoError(t, os.WriteFile(caPath, []byte("no CAs"), 0o600))
+	_, err = r.reload()
+	require.Error(t, err)
+}
+
+func TestGetTLSConfigFromFileReloads(t *testing.T) {
+	logger.InitLogger("azure-cns.log", 0, 0, "/")
+	certPath := filepath.Join(t.TempDir(), "cert.pem")
+	firstExpiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	require.NoError(t, os.WriteFile(certPath, newTestCertificatePEM(t, firstExpiry), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tlsConfig, err := getTLSConfigFromFile(ctx, serverTLS.TlsSettings{
+		TLSCertificatePath:        certPath,
+		MinTLSVersion:             "TLS 1.2",
+		CertificateReloadInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	servedExpiry := func() int64 {
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+			// #nosec G402 for test purposes only
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return 0
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].NotAfter.Unix()
+	}
+	require.Equal(t, firstExpiry.Unix(), servedExpiry())
+
+	// the rotated certificate is served without restarting the listener
+	secondExpiry := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	require.NoError(t, os.WriteFile(certPath, newTestCertificatePEM(t, secondExpiry), 0o600))
+	require.Eventually(t, func() bool {
+		return servedExpiry() == secondExpiry.Unix()
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -19,6 +21,9 @@ import (
 const (
 	contentTypeJSON = "application/json"
 	defaultBaseURL  = "http://localhost:10090"
+	unixScheme      = "unix://"
+	// unixBaseURL is the base URL of the requests sent over a unix socket, whose host is ignored.
+	unixBaseURL = "http://localhost"
 	// DefaultTimeout default timeout duration for CNS Client.
 	DefaultTimeout    = 5 * time.Second
 	headerContentType = "Content-Type"
@@ -66,25 +71,46 @@ func (e *ConnectionFailureErr) Error() string {
 	return e.cause.Error()
 }
 
-// New returns a new CNS client configured with the passed URL and timeout.
+// New returns a new CNS client configured with the passed URL and timeout. A unix:// URL, such as
+// unix:///var/run/azure-cns/cns.sock, connects to CNS over the unix socket at its path.
 func New(baseURL string, requestTimeout time.Duration) (*Client, error) {
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 
+	httpClient := &http.Client{
+		Timeout: requestTimeout,
+	}
+	if socketPath, ok := strings.CutPrefix(baseURL, unixScheme); ok {
+		if socketPath == "" {
+			return nil, errors.Errorf("no socket path in unix URL %s", baseURL)
+		}
+		httpClient.Transport = unixSocketTransport(socketPath)
+		baseURL = unixBaseURL
+	}
+
 	routes, err := buildRoutes(baseURL, clientPaths)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		client: &http.Client{
-			Timeout: requestTimeout,
-		},
+		client: httpClient,
 		routes: routes,
 	}, nil
 }
 
+// unixSocketTransport dials the unix socket at the path for every request, whatever its host.
+func unixSocketTransport(path string) *http.Transport {
+	var dialer net.Dialer
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+	return transport
+}
+
 func buildRoutes(baseURL string, paths []string) (map[string]url.URL, error) {
 	base, err := url.Parse(baseURL)
 	if err != nil {
@@ -158,7 +184,7 @@ func (c *Client) GetAllNetworkContainers(ctx context.Context, orchestratorContex
 	if resp.Response.ReturnCode != 0 {
 		return nil, &CNSClientError{
 			Code: resp.Response.ReturnCode,
-			Err:  errors.New(resp.Response.Message),
+			Err:  resp.Response.Err(),
 		}
 	}
 
@@ -210,7 +236,7 @@ func (c *Client) GetNetworkContainer(ctx context.Context, orchestratorContext []
 	if resp.Response.ReturnCode != 0 {
 		return nil, &CNSClientError{
 			Code: resp.Response.ReturnCode,
-			Err:  errors.New(resp.Response.Message),
+			Err:  resp.Response.Err(),
 		}
 	}
 
@@ -252,7 +278,7 @@ func (c *Client) CreateHostNCApipaEndpoint(ctx context.Context, networkContainer
 	}
 
 	if resp.Response.ReturnCode != 0 {
-		return "", errors.New(resp.Response.Message)
+		return "", resp.Response.Err()
 	}
 
 	return resp.EndpointID, nil
@@ -294,7 +320,7 @@ func (c *Client) DeleteHostNCApipaEndpoint(ctx context.Context, networkContainer
 	}
 
 	if resp.Response.ReturnCode != 0 {
-		return errors.New(resp.Response.Message)
+		return resp.Response.Err()
 	}
 
 	return nil
@@ -341,7 +367,7 @@ func (c *Client) RequestIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 	}
 
 	if response.Response.ReturnCode != 0 {
-		return nil, errors.New(response.Response.Message)
+		return nil, response.Response.Err()
 	}
 
 	return &response, nil
@@ -381,7 +407,7 @@ func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig cns.IPConfigRequ
 	}
 
 	if resp.ReturnCode != 0 {
-		return errors.New(resp.Message)
+		return resp.Err()
 	}
 
 	return nil
@@ -434,7 +460,7 @@ func (c *Client) RequestIPs(ctx context.Context, ipconfig cns.IPConfigsRequest)
 	}
 
 	if response.Response.ReturnCode != 0 {
-		return nil, errors.New(response.Response.Message)
+		return nil, response.Response.Err()
 	}
 
 	return &response, nil
@@ -482,7 +508,7 @@ func (c *Client) ReleaseIPs(ctx context.Context, ipconfig cns.IPConfigsRequest)
 	}
 
 	if resp.ReturnCode != 0 {
-		return errors.New(resp.Message)
+		return resp.Err()
 	}
 
 	return nil
@@ -527,7 +553,7 @@ func (c *Client) GetIPAddressesMatchingStates(ctx context.Context, stateFilter .
 	}
 
 	if resp.Response.ReturnCode != 0 {
-		return nil, errors.New(resp.Response.Message)
+		return nil, resp.Response.Err()
 	}
 
 	return resp.IPConfigurationStatus, nil
@@ -556,7 +582,7 @@ func (c *Client) GetPodOrchestratorContext(ctx context.Context) (map[string][]st
 	}
 
 	if resp.Response.ReturnCode != 0 {
-		return nil, errors.New(resp.Response.Message)
+		return nil, resp.Response.Err()
 	}
 
 	return resp.PodContext, nil
@@ -590,7 +616,7 @@ func (c *Client) GetHTTPServiceData(ctx context.Context) (*restserver.GetHTTPSer
 	}
 
 	if resp.Response.ReturnCode != 0 {
-		return nil, errors.New(resp.Response.Message)
+		return nil, resp.Response.Err()
 	}
 
 	return &resp, nil
@@ -625,7 +651,7 @@ func (c *Client) NumOfCPUCores(ctx context.Context) (*cns.NumOfCPUCoresResponse,
 	if out.Response.ReturnCode != 0 {
 		return nil, &CNSClientError{
 			Code: out.Response.ReturnCode,
-			Err:  errors.New(out.Response.Message),
+			Err:  out.Response.Err(),
 		}
 	}
 
@@ -672,7 +698,7 @@ func (c *Client) DeleteNetworkContainer(ctx context.Context, ncID string) error
 	// if a non-zero response code was received from CNS, it means something went
 	// wrong and it should be surfaced to the caller as an error
 	if out.Response.ReturnCode != 0 {
-		return errors.New(out.Response.Message)
+		return out.Response.Err()
 	}
 
 	// otherwise the response isn't terribly useful in a successful case, so it
@@ -726,7 +752,7 @@ func (c *Client) SetOrchestratorType(ctx context.Context, sotr cns.SetOrchestrat
 	// if there was a non-zero response code, this is an error that
 	// should be communicated back to the caller...
 	if out.ReturnCode != 0 {
-		return errors.New(out.Message)
+		return out.Err()
 	}
 
 	// ...otherwise it's a success and returning nil is sufficient to
@@ -775,7 +801,7 @@ func (c *Client) CreateNetworkContainer(ctx context.Context, cncr cns.CreateNetw
 	// if there was a non-zero response code, this is an error that
 	// should be communicated back to the caller...
 	if out.ReturnCode != 0 {
-		return errors.New(out.Message)
+		return out.Err()
 	}
 
 	// ...otherwise the request was successful so
@@ -823,7 +849,7 @@ func (c *Client) PublishNetworkContainer(ctx context.Context, pncr cns.PublishNe
 	// if there was a non-zero response code, this is an error that
 	// should be communicated back to the caller...
 	if out.Response.ReturnCode != 0 {
-		return errors.New(out.Response.Message)
+		return out.Response.Err()
 	}
 
 	// ...otherwise the request was successful so
@@ -869,7 +895,7 @@ func (c *Client) UnpublishNC(ctx context.Context, uncr cns.UnpublishNetworkConta
 	// if there was a non-zero response code, this is an error that
 	// should be communicated back to the caller...
 	if out.Response.ReturnCode != 0 {
-		return errors.New(out.Response.Message)
+		return out.Response.Err()
 	}
 
 	// ...otherwise the request was successful so
@@ -922,7 +948,7 @@ func (c *Client) NMAgentSupportedAPIs(ctx context.Context) (*cns.NmAgentSupporte
 	if out.Response.ReturnCode != 0 {
 		return nil, &CNSClientError{
 			Code: out.Response.ReturnCode,
-			Err:  errors.New(out.Response.Message),
+			Err:  out.Response.Err(),
 		}
 	}
 
@@ -947,9 +973,12 @@ func (c *Client) GetAllNCsFromCns(ctx context.Context) (cns.GetAllNetworkContain
 	// Decode the response
 	var response cns.GetAllNetworkContainersResponse
 	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err != nil || response.Response.ReturnCode != types.Success {
+	if err != nil {
 		return cns.GetAllNetworkContainersResponse{}, errors.Wrap(err, "decoding GetAllNetworkContainersResponse as JSON")
 	}
+	if response.Response.ReturnCode != types.Success {
+		return cns.GetAllNetworkContainersResponse{}, response.Response.Err()
+	}
 
 	return response, nil
 }
@@ -981,9 +1010,12 @@ func (c *Client) PostAllNetworkContainers(ctx context.Context, createNcRequest c
 	// Decode the response
 	var response cns.PostNetworkContainersResponse
 	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err != nil || response.Response.ReturnCode != types.Success {
+	if err != nil {
 		return errors.Wrap(err, "decoding PostNetworkContainersResponse as JSON")
 	}
+	if response.Response.ReturnCode != types.Success {
+		return response.Response.Err()
+	}
 
 	return nil
 }
@@ -1016,7 +1048,7 @@ func (c *Client) GetHomeAz(ctx context.Context) (*cns.GetHomeAzResponse, error)
 	if getHomeAzResponse.Response.ReturnCode != 0 {
 		return nil, &CNSClientError{
 			Code: getHomeAzResponse.Response.ReturnCode,
-			Err:  errors.New(getHomeAzResponse.Response.Message),
+			Err:  getHomeAzResponse.Response.Err(),
 		}
 	}
 
@@ -1054,7 +1086,7 @@ func (c *Client) GetEndpoint(ctx context.Context, endpointID string) (*restserve
 		return &response, errors.Wrap(err, "failed to decode GetEndpointResponse")
 	}
 	if response.Response.ReturnCode != 0 {
-		return &response, errors.New(response.Response.Message)
+		return &response, response.Response.Err()
 	}
 
 	return &response, nil
@@ -1095,7 +1127,7 @@ func (c *Client) UpdateEndpoint(ctx context.Context, endpointID string, ipInfo m
 	}
 
 	if response.ReturnCode != 0 {
-		return nil, errors.New(response.Message)
+		return nil, response.Err()
 	}
 
 	return &response, nil
@@ -28,16 +28,24 @@ func (e *CNSClientError) Error() string {
 	return fmt.Sprintf("[Azure cnsclient] Code: %d , Error: %v", e.Code, e.Err)
 }
 
-// IsNotFound tests if the provided error is of type CNSClientError and then
-// further tests if the error code is of type UnknowContainerID
+func (e *CNSClientError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the error of the Code, so that errors.Is matches the sentinel errors of the types package
+// even when the error didn't come from a CNS response.
+func (e *CNSClientError) Is(target error) bool {
+	return types.NewError(e.Code, "").Is(target)
+}
+
+// IsNotFound tests if the provided error is from CNS and further tests if
+// the error code is of type UnknowContainerID
 func IsNotFound(err error) bool {
-	e := &CNSClientError{}
-	return errors.As(err, &e) && (e.Code == types.UnknownContainerID)
+	return errors.Is(err, &types.Error{Code: types.UnknownContainerID})
 }
 
-// IsUnsupportedAPI tests if the provided error is of type CNSClientError and then
-// further tests if the error code is of type UnsupportedAPI
+// IsUnsupportedAPI tests if the provided error is from CNS and further tests
+// if the error code is of type UnsupportedAPI
 func IsUnsupportedAPI(err error) bool {
-	e := &CNSClientError{}
-	return errors.As(err, &e) && (e.Code == types.UnsupportedAPI)
+	return errors.Is(err, &types.Error{Code: types.UnsupportedAPI})
 }
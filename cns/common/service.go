@@ -44,6 +44,8 @@ type ServiceConfig struct {
 	ChannelMode string
 	TLSSettings tls.TlsSettings
 	Logger      *zap.Logger
+	// UnixSocketPath is the path of the unix socket CNS also serves on, if set.
+	UnixSocketPath string
 }
 
 // server struct to store primaryInterfaceIP from VM, port where customer provides by -p and temporary flag EnableLocalServer
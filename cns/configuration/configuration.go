@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/authz"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	loggerv2 "github.com/Azure/azure-container-networking/cns/logger/v2"
 	"github.com/Azure/azure-container-networking/common"
@@ -24,19 +25,24 @@ const (
 type CNSConfig struct {
 	AZRSettings                 AZRSettings
 	AsyncPodDeletePath          string
+	AuthorizationPolicy         authz.Policy
 	CNIConflistFilepath         string
 	CNIConflistScenario         string
 	ChannelMode                 string
 	EnableAPIServerHealthPing   bool
 	EnableAsyncPodDelete        bool
+	EnableAuthorization         bool
 	EnableCNIConflistGeneration bool
+	EnableEgressIP              bool
 	EnableIPAMv2                bool
 	EnableK8sDevicePlugin       bool
 	EnableLoggerV2              bool
 	EnablePprof                 bool
+	EnableScheduledEvents       bool
 	EnableStateMigration        bool
 	EnableSubnetScarcity        bool
 	EnableSwiftV2               bool
+	IMDSEndpoint                string
 	InitializeFromCNI           bool
 	KeyVaultSettings            KeyVaultSettings
 	Logger                      loggerv2.Config
@@ -45,20 +51,27 @@ type CNSConfig struct {
 	ManagedSettings             ManagedSettings
 	MellanoxMonitorIntervalSecs int
 	MetricsBindAddress          string
+	NMAgentResilienceSettings   NMAgentResilienceSettings
 	ProgramSNATIPTables         bool
+	ScheduledEventsSettings     ScheduledEventsSettings
+	ShutdownSettings            ShutdownSettings
 	SyncHostNCTimeoutMs         int
 	SyncHostNCVersionIntervalMs int
+	TLSCertReloadIntervalSecs   int
 	TLSCertificatePath          string
+	TLSCertificateSource        TLSCertificateSourceSettings
 	TLSEndpoint                 string
 	TLSPort                     string
 	TLSSubjectName              string
 	TelemetrySettings           TelemetrySettings
+	UnixSocketPath              string
 	UseHTTPS                    bool
 	UseMTLS                     bool
 	WatchPods                   bool `json:"-"`
 	WireserverIP                string
 	GRPCSettings                GRPCSettings
 	MinTLSVersion               string
+	MtlsClientCAPath            string
 	MtlsClientCertSubjectName   string
 }
 
@@ -102,6 +115,35 @@ type AZRSettings struct {
 	PopulateHomeAzCacheRetryIntervalSecs int
 }
 
+// NMAgentResilienceSettings configures the resilience of NMAgent and
+// Wireserver requests. Zero values disable the circuit breakers, hedging, and
+// rate limit.
+type NMAgentResilienceSettings struct {
+	CircuitBreakerFailureThreshold int
+	CircuitBreakerOpenDurationMs   int
+	HedgeDelayMs                   int
+	RateLimitQPS                   float64
+	RateLimitBurst                 int
+}
+
+// ScheduledEventsSettings configures how CNS prepares for maintenance events
+// announced through IMDS Scheduled Events.
+type ScheduledEventsSettings struct {
+	PollIntervalSecs int
+	// CordonNode needs the permission to patch Nodes in azure-cns-cordon.yaml.
+	CordonNode        bool
+	AcknowledgeEvents bool
+}
+
+// ShutdownSettings configures how CNS drains its API before exiting. Readiness
+// fails for DrainPeriodSecs before the listener stops accepting requests, and
+// requests in flight are then waited for up to TimeoutSecs. A negative
+// DrainPeriodSecs skips the drain period.
+type ShutdownSettings struct {
+	DrainPeriodSecs int
+	TimeoutSecs     int
+}
+
 type MSISettings struct {
 	ResourceID string
 }
@@ -112,6 +154,21 @@ type KeyVaultSettings struct {
 	RefreshIntervalInHrs int
 }
 
+// TLSCertificateSourceSettings select where the TLS certificate is read from. The certificate is
+// refreshed from Kubernetes Secrets, CSI-mounted directories and self-signed sources every
+// TLSCertReloadIntervalSecs.
+type TLSCertificateSourceSettings struct {
+	// Source is one of file, keyvault, kubernetes-secret, secret-store-csi or self-signed. It defaults
+	// to file when TLSCertificatePath is set, and to keyvault otherwise.
+	Source                    string
+	KubernetesSecretNamespace string
+	KubernetesSecretName      string
+	SecretStoreDirectory      string
+	SecretStoreObjectName     string
+	SelfSignedDNSNames        []string
+	SelfSignedValidityHrs     int
+}
+
 type GRPCSettings struct {
 	Enable    bool
 	IPAddress string
@@ -200,6 +257,21 @@ func setAZRSettingsDefaults(azrSettings *AZRSettings) {
 	}
 }
 
+func setScheduledEventsSettingsDefaults(ses *ScheduledEventsSettings) {
+	if ses.PollIntervalSecs == 0 {
+		ses.PollIntervalSecs = 10 //nolint:gomnd // default times
+	}
+}
+
+func setShutdownSettingsDefaults(ss *ShutdownSettings) {
+	if ss.DrainPeriodSecs == 0 {
+		ss.DrainPeriodSecs = 5 //nolint:gomnd // default times
+	}
+	if ss.TimeoutSecs == 0 {
+		ss.TimeoutSecs = 15 //nolint:gomnd // default times
+	}
+}
+
 func setKeyVaultSettingsDefaults(kvs *KeyVaultSettings) {
 	if kvs.RefreshIntervalInHrs == 0 {
 		kvs.RefreshIntervalInHrs = 12 //nolint:gomnd // default times
@@ -212,6 +284,8 @@ func SetCNSConfigDefaults(config *CNSConfig) {
 	setManagedSettingDefaults(&config.ManagedSettings)
 	setKeyVaultSettingsDefaults(&config.KeyVaultSettings)
 	setAZRSettingsDefaults(&config.AZRSettings)
+	setScheduledEventsSettingsDefaults(&config.ScheduledEventsSettings)
+	setShutdownSettingsDefaults(&config.ShutdownSettings)
 
 	if config.ChannelMode == "" {
 		config.ChannelMode = cns.Direct
@@ -238,6 +312,10 @@ func SetCNSConfigDefaults(config *CNSConfig) {
 		config.GRPCSettings.Port = 8080
 	}
 
+	// negative intervals disable reloading the TLS certificates
+	if config.TLSCertReloadIntervalSecs == 0 {
+		config.TLSCertReloadIntervalSecs = 60 //nolint:gomnd // default times
+	}
 	if config.MinTLSVersion == "" {
 		config.MinTLSVersion = "TLS 1.2"
 	}
@@ -215,8 +215,16 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 				AZRSettings: AZRSettings{
 					PopulateHomeAzCacheRetryIntervalSecs: 60,
 				},
-				WireserverIP:       "168.63.129.16",
-				AsyncPodDeletePath: "/var/run/azure-vnet/deleteIDs",
+				ScheduledEventsSettings: ScheduledEventsSettings{
+					PollIntervalSecs: 10,
+				},
+				ShutdownSettings: ShutdownSettings{
+					DrainPeriodSecs: 5,
+					TimeoutSecs:     15,
+				},
+				TLSCertReloadIntervalSecs: 60,
+				WireserverIP:              "168.63.129.16",
+				AsyncPodDeletePath:        "/var/run/azure-vnet/deleteIDs",
 				GRPCSettings: GRPCSettings{
 					Enable:    false,
 					IPAddress: "localhost",
@@ -249,6 +257,14 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 				AZRSettings: AZRSettings{
 					PopulateHomeAzCacheRetryIntervalSecs: 10,
 				},
+				ScheduledEventsSettings: ScheduledEventsSettings{
+					PollIntervalSecs: 5,
+				},
+				ShutdownSettings: ShutdownSettings{
+					DrainPeriodSecs: -1,
+					TimeoutSecs:     30,
+				},
+				TLSCertReloadIntervalSecs: 30,
 				GRPCSettings: GRPCSettings{
 					Enable:    false,
 					IPAddress: "192.168.1.1",
@@ -278,8 +294,16 @@ func TestSetCNSConfigDefaults(t *testing.T) {
 				AZRSettings: AZRSettings{
 					PopulateHomeAzCacheRetryIntervalSecs: 10,
 				},
-				WireserverIP:       "168.63.129.16",
-				AsyncPodDeletePath: "/var/run/azure-vnet/deleteIDs",
+				ScheduledEventsSettings: ScheduledEventsSettings{
+					PollIntervalSecs: 5,
+				},
+				ShutdownSettings: ShutdownSettings{
+					DrainPeriodSecs: -1,
+					TimeoutSecs:     30,
+				},
+				TLSCertReloadIntervalSecs: 30,
+				WireserverIP:              "168.63.129.16",
+				AsyncPodDeletePath:        "/var/run/azure-vnet/deleteIDs",
 				GRPCSettings: GRPCSettings{
 					Enable:    false,
 					IPAddress: "192.168.1.1",
@@ -0,0 +1,54 @@
+package healthserver
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+var errNotReady = errors.New("not ready")
+
+// namedCheck is a check of a component of CNS which readiness depends on.
+type namedCheck struct {
+	name  string
+	check func(*http.Request) error
+}
+
+// Readiness is the readyz check of CNS. It passes once CNS is ready to serve and all the added
+// checks pass, and fails again when CNS starts draining requests before shutting down.
+type Readiness struct {
+	ready atomic.Bool
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// SetReady sets whether the check passes.
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+// AddCheck makes the readiness depend on the check of a component, such as the health check of a
+// refresh.Fetcher.
+func (r *Readiness) AddCheck(name string, check func(*http.Request) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// Check is the healthz.Checker of the readiness.
+func (r *Readiness) Check(req *http.Request) error {
+	if !r.ready.Load() {
+		return errNotReady
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.checks {
+		if err := c.check(req); err != nil {
+			return errors.Wrapf(err, "%s", c.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package healthserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+func TestReadiness(t *testing.T) {
+	r := &Readiness{}
+	handler := healthz.CheckHandler{Checker: r.Check}
+	status := func() int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusInternalServerError, status())
+	r.SetReady(true)
+	require.Equal(t, http.StatusOK, status())
+	// draining before shutdown
+	r.SetReady(false)
+	require.Equal(t, http.StatusInternalServerError, status())
+}
+
+func TestReadinessChecks(t *testing.T) {
+	r := &Readiness{}
+	r.SetReady(true)
+	errStale := errors.New("stale")
+	var stale bool
+	r.AddCheck("nodesubnet", func(*http.Request) error {
+		if stale {
+			return errStale
+		}
+		return nil
+	})
+
+	require.NoError(t, r.Check(nil))
+	stale = true
+	require.ErrorIs(t, r.Check(nil), errStale)
+}
@@ -5,6 +5,7 @@ package imds_test
 
 import (
 	"context"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -305,3 +306,100 @@ func TestGetIMDSVersionsInvalidEndpoint(t *testing.T) {
 	_, err := imdsClient.GetIMDSVersions(context.Background())
 	require.Error(t, err, "expected error for invalid endpoint")
 }
+
+func TestGetVMName(t *testing.T) {
+	computeMetadata, err := os.ReadFile("testdata/computeMetadata.json")
+	require.NoError(t, err, "error reading testdata compute metadata file")
+
+	mockIMDSServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, writeErr := w.Write(computeMetadata)
+		if writeErr != nil {
+			t.Errorf("error writing response: %v", writeErr)
+		}
+	}))
+	defer mockIMDSServer.Close()
+
+	imdsClient := imds.NewClient(imds.Endpoint(mockIMDSServer.URL))
+	vmName, err := imdsClient.GetVMName(context.Background())
+	require.NoError(t, err, "error querying testserver")
+	require.Equal(t, "aks-nodepool1-25781205-vmss_0", vmName)
+}
+
+func TestGetScheduledEvents(t *testing.T) {
+	scheduledEvents := []byte(`{
+        "DocumentIncarnation": 2,
+        "Events": [
+            {
+                "EventId": "602d9444-d2cd-49c7-8624-8643e7171297",
+                "EventType": "Reboot",
+                "ResourceType": "VirtualMachine",
+                "Resources": ["aks-nodepool1-25781205-vmss_0"],
+                "EventStatus": "Scheduled",
+                "NotBefore": "Mon, 19 Sep 2016 18:29:47 GMT",
+                "Description": "The virtual machine is being rebooted",
+                "EventSource": "Platform",
+                "DurationInSeconds": -1
+            }
+        ]
+    }`)
+
+	mockIMDSServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// request header "Metadata: true" must be present
+		assert.Equal(t, "true", r.Header.Get("Metadata"))
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/metadata/scheduledevents", r.URL.Path)
+		assert.Equal(t, "2020-07-01", r.URL.Query().Get("api-version"))
+
+		w.WriteHeader(http.StatusOK)
+		_, writeErr := w.Write(scheduledEvents)
+		if writeErr != nil {
+			t.Errorf("error writing response: %v", writeErr)
+		}
+	}))
+	defer mockIMDSServer.Close()
+
+	imdsClient := imds.NewClient(imds.Endpoint(mockIMDSServer.URL))
+	events, err := imdsClient.GetScheduledEvents(context.Background())
+	require.NoError(t, err, "error querying testserver")
+
+	require.Equal(t, 2, events.DocumentIncarnation)
+	require.Len(t, events.Events, 1)
+	event := events.Events[0]
+	assert.Equal(t, "602d9444-d2cd-49c7-8624-8643e7171297", event.EventID)
+	assert.Equal(t, imds.EventTypeReboot, event.EventType)
+	assert.Equal(t, imds.EventStatusScheduled, event.EventStatus)
+	assert.True(t, event.Affects("aks-nodepool1-25781205-vmss_0"))
+	assert.False(t, event.Affects("aks-nodepool1-25781205-vmss_1"))
+}
+
+func TestGetScheduledEventsInternalServerError(t *testing.T) {
+	mockIMDSServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mockIMDSServer.Close()
+
+	imdsClient := imds.NewClient(imds.Endpoint(mockIMDSServer.URL), imds.RetryAttempts(1))
+	_, err := imdsClient.GetScheduledEvents(context.Background())
+	require.ErrorIs(t, err, imds.ErrUnexpectedStatusCode, "expected internal server error")
+}
+
+func TestAckScheduledEvents(t *testing.T) {
+	var body []byte
+	mockIMDSServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("Metadata"))
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/metadata/scheduledevents", r.URL.Path)
+
+		var err error
+		body, err = io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockIMDSServer.Close()
+
+	imdsClient := imds.NewClient(imds.Endpoint(mockIMDSServer.URL))
+	err := imdsClient.AckScheduledEvents(context.Background(), "event-1", "event-2")
+	require.NoError(t, err, "error acknowledging events")
+	require.JSONEq(t, `{"StartRequests":[{"EventId":"event-1"},{"EventId":"event-2"}]}`, string(body))
+}
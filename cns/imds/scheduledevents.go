@@ -0,0 +1,203 @@
+// Copyright 2024 Microsoft. All rights reserved.
+// MIT License
+
+package imds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/pkg/errors"
+)
+
+// see docs for IMDS Scheduled Events here: https://learn.microsoft.com/en-us/azure/virtual-machines/linux/scheduled-events
+
+const (
+	vmNameProperty              = "name"
+	imdsScheduledEventsPath     = "/metadata/scheduledevents"
+	imdsScheduledEventsVersion  = "api-version=2020-07-01"
+	scheduledEventsContentType  = "application/json"
+	scheduledEventsResourceType = "VirtualMachine"
+)
+
+// EventType is the type of maintenance a scheduled event announces.
+type EventType string
+
+const (
+	// EventTypeFreeze pauses the VM for a few seconds, keeping its memory and open files.
+	EventTypeFreeze EventType = "Freeze"
+	// EventTypeReboot reboots the VM, losing its memory.
+	EventTypeReboot EventType = "Reboot"
+	// EventTypeRedeploy moves the VM to another host, losing its memory and ephemeral disks.
+	EventTypeRedeploy EventType = "Redeploy"
+	// EventTypePreempt deletes the Spot VM.
+	EventTypePreempt EventType = "Preempt"
+	// EventTypeTerminate deletes the VM.
+	EventTypeTerminate EventType = "Terminate"
+)
+
+// EventStatus is the status of a scheduled event.
+type EventStatus string
+
+const (
+	// EventStatusScheduled events start after their NotBefore time, or once they're acknowledged.
+	EventStatusScheduled EventStatus = "Scheduled"
+	// EventStatusStarted events are in progress.
+	EventStatusStarted EventStatus = "Started"
+)
+
+var ErrVMNameNotFound = errors.New("vm name not found")
+
+// ScheduledEvents is the document of upcoming maintenance events returned by IMDS.
+type ScheduledEvents struct {
+	DocumentIncarnation int              `json:"DocumentIncarnation"`
+	Events              []ScheduledEvent `json:"Events"`
+}
+
+// ScheduledEvent is an upcoming maintenance event for one or more VMs.
+type ScheduledEvent struct {
+	EventID           string      `json:"EventId"`
+	EventType         EventType   `json:"EventType"`
+	ResourceType      string      `json:"ResourceType"`
+	Resources         []string    `json:"Resources"`
+	EventStatus       EventStatus `json:"EventStatus"`
+	NotBefore         string      `json:"NotBefore"`
+	Description       string      `json:"Description"`
+	EventSource       string      `json:"EventSource"`
+	DurationInSeconds int         `json:"DurationInSeconds"`
+}
+
+// Affects reports whether the event applies to the named VM.
+func (e *ScheduledEvent) Affects(vmName string) bool {
+	if e.ResourceType != "" && e.ResourceType != scheduledEventsResourceType {
+		return false
+	}
+	for _, r := range e.Resources {
+		if r == vmName {
+			return true
+		}
+	}
+	return false
+}
+
+type startRequest struct {
+	EventID string `json:"EventId"`
+}
+
+type startRequests struct {
+	StartRequests []startRequest `json:"StartRequests"`
+}
+
+// GetVMName returns the name of the VM, which scheduled events use to identify the VMs they affect.
+func (c *Client) GetVMName(ctx context.Context) (string, error) {
+	var vmName string
+	err := retry.Do(func() error {
+		computeDoc, err := c.getInstanceMetadata(ctx, imdsComputePath, imdsDefaultAPIVersion)
+		if err != nil {
+			return errors.Wrap(err, "error getting IMDS compute metadata")
+		}
+		var ok bool
+		vmName, ok = computeDoc[vmNameProperty].(string)
+		if !ok {
+			return errors.New("unable to parse IMDS compute metadata, name property is not a string")
+		}
+		return nil
+	}, retry.Context(ctx), retry.Attempts(c.config.retryAttempts), retry.DelayType(retry.BackOffDelay))
+	if err != nil {
+		return "", errors.Wrap(err, "exhausted retries querying IMDS compute metadata")
+	}
+
+	if vmName == "" {
+		return "", ErrVMNameNotFound
+	}
+
+	return vmName, nil
+}
+
+// GetScheduledEvents returns the upcoming maintenance events of the VM. The first request enables
+// Scheduled Events for the VM, and may take a while to respond.
+func (c *Client) GetScheduledEvents(ctx context.Context) (*ScheduledEvents, error) {
+	var events ScheduledEvents
+	err := retry.Do(func() error {
+		resp, err := c.doScheduledEvents(ctx, http.MethodGet, http.NoBody)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return errors.Wrapf(ErrUnexpectedStatusCode, "unexpected status code %d", resp.StatusCode)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+			return errors.Wrap(err, "error decoding IMDS scheduled events response as json")
+		}
+		return nil
+	}, retry.Context(ctx), retry.Attempts(c.config.retryAttempts), retry.DelayType(retry.BackOffDelay))
+	if err != nil {
+		return nil, errors.Wrap(err, "exhausted retries querying IMDS scheduled events")
+	}
+
+	return &events, nil
+}
+
+// AckScheduledEvents acknowledges the events, which lets them start before their NotBefore time.
+func (c *Client) AckScheduledEvents(ctx context.Context, eventIDs ...string) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+	reqs := startRequests{StartRequests: make([]startRequest, 0, len(eventIDs))}
+	for _, id := range eventIDs {
+		reqs.StartRequests = append(reqs.StartRequests, startRequest{EventID: id})
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling IMDS scheduled events start requests")
+	}
+
+	err = retry.Do(func() error {
+		resp, err := c.doScheduledEvents(ctx, http.MethodPost, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return errors.Wrapf(ErrUnexpectedStatusCode, "unexpected status code %d", resp.StatusCode)
+		}
+		return nil
+	}, retry.Context(ctx), retry.Attempts(c.config.retryAttempts), retry.DelayType(retry.BackOffDelay))
+	if err != nil {
+		return errors.Wrap(err, "exhausted retries acknowledging IMDS scheduled events")
+	}
+
+	return nil
+}
+
+func (c *Client) doScheduledEvents(ctx context.Context, method string, body io.Reader) (*http.Response, error) {
+	imdsRequestURL, err := url.JoinPath(c.config.endpoint, imdsScheduledEventsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build path to IMDS scheduled events endpoint")
+	}
+	imdsRequestURL = imdsRequestURL + "?" + imdsScheduledEventsVersion
+
+	req, err := http.NewRequestWithContext(ctx, method, imdsRequestURL, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building IMDS scheduled events http request")
+	}
+
+	req.Header.Add(metadataHeaderKey, metadataHeaderValue)
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", scheduledEventsContentType)
+	}
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error querying IMDS scheduled events API")
+	}
+	return resp, nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/netip"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -64,6 +65,8 @@ type Monitor struct {
 	nncSource   chan v1alpha.NodeNetworkConfig
 	started     chan interface{}
 	once        sync.Once
+	// scaleDownPaused stops the Monitor from releasing IPs, e.g. during host maintenance.
+	scaleDownPaused atomic.Bool
 }
 
 func NewMonitor(httpService cns.HTTPService, nnccli nodeNetworkConfigSpecUpdater, cssSource <-chan v1alpha1.ClusterSubnetState, opts *Options) *Monitor {
@@ -237,6 +240,9 @@ func (pm *Monitor) reconcile(ctx context.Context) error {
 
 	// pod count is decreasing
 	case state.currentAvailableIPs >= meta.maxFreeCount:
+		if pm.scaleDownPaused.Load() {
+			return nil
+		}
 		logger.Printf("ipam-pool-monitor state %+v", state)
 		logger.Printf("[ipam-pool-monitor] Decreasing pool size...")
 		return pm.decreasePoolSize(ctx, meta, state)
@@ -411,6 +417,20 @@ func (pm *Monitor) createNNCSpecForCRD() v1alpha.NodeNetworkConfigSpec {
 	return spec
 }
 
+// PauseScaleDown stops the Monitor from decreasing the pool size until ResumeScaleDown is called.
+func (pm *Monitor) PauseScaleDown() {
+	if !pm.scaleDownPaused.Swap(true) {
+		logger.Printf("[ipam-pool-monitor] Pausing pool scale down")
+	}
+}
+
+// ResumeScaleDown lets the Monitor decrease the pool size again.
+func (pm *Monitor) ResumeScaleDown() {
+	if pm.scaleDownPaused.Swap(false) {
+		logger.Printf("[ipam-pool-monitor] Resuming pool scale down")
+	}
+}
+
 // GetStateSnapshot gets a snapshot of the IPAMPoolMonitor struct.
 func (pm *Monitor) GetStateSnapshot() cns.IpamPoolMonitorStateSnapshot {
 	spec, state := pm.spec, pm.metastate
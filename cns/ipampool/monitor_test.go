@@ -557,6 +557,33 @@ func TestDecreaseWithPendingRelease(t *testing.T) {
 	assert.Len(t, poolmonitor.spec.IPsNotInUse, int(initState.batch)+int(initState.pendingRelease))
 }
 
+func TestPoolDecreasePaused(t *testing.T) {
+	initState := testState{
+		batch:                   10,
+		assigned:                20,
+		allocated:               30,
+		requestThresholdPercent: 50,
+		releaseThresholdPercent: 150,
+		max:                     30,
+	}
+	fakecns, fakerc, poolmonitor := initFakes(initState, nil)
+	assert.NoError(t, fakerc.Reconcile(true))
+	assert.NoError(t, poolmonitor.reconcile(context.Background()))
+
+	// the pool doesn't scale down while paused
+	poolmonitor.PauseScaleDown()
+	assert.NoError(t, fakecns.SetNumberOfAssignedIPs(5))
+	assert.NoError(t, poolmonitor.reconcile(context.Background()))
+	assert.Equal(t, initState.allocated, poolmonitor.spec.RequestedIPCount)
+	assert.Empty(t, poolmonitor.spec.IPsNotInUse)
+
+	// it does once resumed
+	poolmonitor.ResumeScaleDown()
+	assert.NoError(t, poolmonitor.reconcile(context.Background()))
+	assert.Equal(t, initState.allocated-initState.batch, poolmonitor.spec.RequestedIPCount)
+	assert.Len(t, poolmonitor.spec.IPsNotInUse, int(initState.batch))
+}
+
 func TestDecreaseWithAPIServerFailure(t *testing.T) {
 	initState := testState{
 		batch:                   16,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -49,6 +50,8 @@ type Monitor struct {
 	started               chan interface{}
 	once                  sync.Once
 	legacyMetricsObserver func(context.Context) error
+	// scaleDownPaused stops the Monitor from releasing IPs, e.g. during host maintenance.
+	scaleDownPaused atomic.Bool
 }
 
 func NewMonitor(z *zap.Logger, store ipStateStore, nnccli nodeNetworkConfigSpecUpdater, demandSource <-chan int, nncSource <-chan v1alpha.NodeNetworkConfig, cssSource <-chan v1alpha1.ClusterSubnetState) *Monitor { //nolint:lll // it's fine
@@ -127,6 +130,10 @@ func (pm *Monitor) reconcile(ctx context.Context) error {
 		pm.z.Info("NNC already at target IPs, no scaling required")
 		return nil
 	}
+	if delta < 0 && pm.scaleDownPaused.Load() {
+		pm.z.Info("scale down paused, not releasing IPs", zap.Int64("delta", delta))
+		return nil
+	}
 	pm.z.Info("scaling pool", zap.Int64("delta", delta))
 	// try to release -delta IPs. this is no-op if delta is negative.
 	if _, err := pm.store.MarkNIPsPendingRelease(int(-delta)); err != nil {
@@ -155,6 +162,20 @@ func (pm *Monitor) buildNNCSpec(request int64) v1alpha.NodeNetworkConfigSpec {
 	return spec
 }
 
+// PauseScaleDown stops the Monitor from releasing IPs until ResumeScaleDown is called.
+func (pm *Monitor) PauseScaleDown() {
+	if !pm.scaleDownPaused.Swap(true) {
+		pm.z.Info("pausing pool scale down")
+	}
+}
+
+// ResumeScaleDown lets the Monitor release IPs again.
+func (pm *Monitor) ResumeScaleDown() {
+	if pm.scaleDownPaused.Swap(false) {
+		pm.z.Info("resuming pool scale down")
+	}
+}
+
 func (pm *Monitor) WithLegacyMetricsObserver(observer func(context.Context) error) {
 	pm.legacyMetricsObserver = observer
 }
@@ -122,6 +122,7 @@ func TestReconcile(t *testing.T) {
 		scaler             scaler
 		nnccli             nncClientMock
 		store              ipStateStoreMock
+		scaleDownPaused    bool
 		wantRequest        int64
 		wantPendingRelease int
 		wantErr            bool
@@ -330,6 +331,39 @@ func TestReconcile(t *testing.T) {
 			wantRequest:        16,
 			wantPendingRelease: 32,
 		},
+		// scale down paused for maintenance
+		{
+			name:    "paused scale down",
+			demand:  5,
+			request: 32,
+			scaler: scaler{
+				batch:  16,
+				buffer: .5,
+				max:    250,
+			},
+			nnccli: nncClientMock{
+				req: v1alpha.NodeNetworkConfigSpec{
+					RequestedIPCount: 32,
+				},
+			},
+			store:           ipStateStoreMock{},
+			scaleDownPaused: true,
+			wantRequest:     32,
+		},
+		{
+			name:    "paused scale up",
+			demand:  15,
+			request: 16,
+			scaler: scaler{
+				batch:  16,
+				buffer: .5,
+				max:    250,
+			},
+			nnccli:          nncClientMock{},
+			store:           ipStateStoreMock{},
+			scaleDownPaused: true,
+			wantRequest:     32,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -343,6 +377,7 @@ func TestReconcile(t *testing.T) {
 				nnccli:  &tt.nnccli,
 				store:   &tt.store,
 			}
+			pm.scaleDownPaused.Store(tt.scaleDownPaused)
 			err := pm.reconcile(context.Background())
 			if tt.wantErr {
 				require.Error(t, err)
@@ -0,0 +1,81 @@
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// EgressIPAnnotation opts the pods of a Namespace in to egress with a static egress IP when set to "true".
+	// The egress IP is per node: every node running pods of the Namespace reserves its own.
+	EgressIPAnnotation = "kubernetes.azure.com/egress-ip"
+	// resyncPeriod is how often an opted in Namespace is reconciled to detect a lost egress IP.
+	resyncPeriod = 5 * time.Minute
+)
+
+type namespaceGetter interface {
+	Get(ctx context.Context, key types.NamespacedName, obj client.Object, opts ...client.GetOption) error
+}
+
+type egressIPAssigner interface {
+	EnableEgressIP(namespace string) error
+	DisableEgressIP(namespace string) error
+}
+
+// Reconciler watches Namespaces and enables the static egress IP in CNS for the Namespaces annotated with
+// EgressIPAnnotation, disabling it when the annotation is removed or the Namespace is deleted. CNS reserves the
+// egress IP of a Namespace on the node when the first pod of the Namespace is scheduled on it.
+type Reconciler struct {
+	z        *zap.Logger
+	cli      namespaceGetter
+	assigner egressIPAssigner
+}
+
+func NewReconciler(z *zap.Logger, assigner egressIPAssigner) *Reconciler {
+	return &Reconciler{
+		z:        z.With(zap.String("component", "namespace-egressip-reconciler")),
+		assigner: assigner,
+	}
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ns := &v1.Namespace{}
+	if err := r.cli.Get(ctx, req.NamespacedName, ns); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to get namespace %s", req.Name)
+		}
+		ns = nil
+	}
+
+	if ns == nil || !ns.DeletionTimestamp.IsZero() || ns.Annotations[EgressIPAnnotation] != "true" {
+		if err := r.assigner.DisableEgressIP(req.Name); err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to disable egress ip of namespace %s", req.Name)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.assigner.EnableEgressIP(req.Name); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to enable egress ip of namespace %s", req.Name)
+	}
+	r.z.Info("namespace egress ip reconciled", zap.String("namespace", req.Name))
+	// requeue to pick up a failover of the egress ip.
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// SetupWithManager sets up the reconciler with a new manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.cli = mgr.GetClient()
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Namespace{}).
+		Complete(r)
+	return errors.Wrap(err, "failed to set up namespace egress ip reconciler with manager")
+}
@@ -0,0 +1,101 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type mockNamespaceGetter struct {
+	ns  *v1.Namespace
+	err error
+}
+
+func (m *mockNamespaceGetter) Get(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.ns.DeepCopyInto(obj.(*v1.Namespace))
+	return nil
+}
+
+type mockAssigner struct {
+	assigned map[string]bool
+}
+
+func (m *mockAssigner) EnableEgressIP(namespace string) error {
+	m.assigned[namespace] = true
+	return nil
+}
+
+func (m *mockAssigner) DisableEgressIP(namespace string) error {
+	delete(m.assigned, namespace)
+	return nil
+}
+
+func TestReconcile(t *testing.T) {
+	annotated := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "ns", Annotations: map[string]string{EgressIPAnnotation: "true"}},
+	}
+	tests := []struct {
+		name         string
+		getter       *mockNamespaceGetter
+		assigned     map[string]bool
+		want         reconcile.Result
+		wantAssigned map[string]bool
+		wantErr      bool
+	}{
+		{
+			name:         "annotated namespace is enabled",
+			getter:       &mockNamespaceGetter{ns: annotated},
+			assigned:     map[string]bool{},
+			want:         reconcile.Result{RequeueAfter: resyncPeriod},
+			wantAssigned: map[string]bool{"ns": true},
+		},
+		{
+			name:         "namespace without annotation is disabled",
+			getter:       &mockNamespaceGetter{ns: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}},
+			assigned:     map[string]bool{"ns": true},
+			wantAssigned: map[string]bool{},
+		},
+		{
+			name:         "deleted namespace is disabled",
+			getter:       &mockNamespaceGetter{err: apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "ns")},
+			assigned:     map[string]bool{"ns": true},
+			wantAssigned: map[string]bool{},
+		},
+		{
+			name:         "unknown get error",
+			getter:       &mockNamespaceGetter{err: errors.New("")},
+			assigned:     map[string]bool{"ns": true},
+			wantAssigned: map[string]bool{"ns": true},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assigner := &mockAssigner{assigned: tt.assigned}
+			r := NewReconciler(zap.NewNop(), assigner)
+			r.cli = tt.getter
+
+			got, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "ns"}})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantAssigned, assigner.assigned)
+		})
+	}
+}
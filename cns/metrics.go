@@ -0,0 +1,42 @@
+package cns
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	certificateLabel = "certificate"
+	resultLabel      = "result"
+
+	serverCertificate   = "server"
+	clientCACertificate = "client_ca"
+)
+
+var (
+	// tlsCertificateExpiry is the expiry of the served TLS certificate and of the client CAs, so that
+	// alerts can fire before they expire. The client CAs expire with the first CA in the bundle.
+	tlsCertificateExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cns_tls_certificate_expiry_timestamp_seconds",
+			Help: "Expiry of the served TLS certificates as a Unix timestamp, by certificate.",
+		},
+		[]string{certificateLabel},
+	)
+	// tlsCertificateReloads counts the reloads of the TLS certificates after they changed on disk. A
+	// failed reload keeps serving the previous certificates.
+	tlsCertificateReloads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cns_tls_certificate_reloads_total",
+			Help: "Number of reloads of the TLS certificates from file, by result.",
+		},
+		[]string{resultLabel},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		tlsCertificateExpiry,
+		tlsCertificateReloads,
+	)
+}
@@ -221,6 +221,14 @@ func (k *K8sSWIFTv2Middleware) UpdateIPConfigRequest(mtpnc v1alpha1.MultitenantP
 			}
 			req.BackendInterfaceExist = true
 			req.BackendInterfaceMacAddresses = append(req.BackendInterfaceMacAddresses, interfaceInfo.MacAddress)
+			if req.BackendInterfaceVFs == nil {
+				req.BackendInterfaceVFs = make(map[string]cns.VirtualFunction)
+			}
+			req.BackendInterfaceVFs[interfaceInfo.MacAddress] = cns.VirtualFunction{
+				MacAddress: interfaceInfo.VFMacAddress,
+				VlanID:     interfaceInfo.VlanID,
+				Trust:      interfaceInfo.VFTrust,
+			}
 
 		}
 		if interfaceInfo.DeviceType == v1alpha1.DeviceTypeVnetNIC {
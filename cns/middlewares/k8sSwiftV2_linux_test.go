@@ -183,6 +183,9 @@ func TestValidateMultitenantIPConfigsRequestSuccess(t *testing.T) {
 	assert.Equal(t, respCode, types.Success)
 	assert.Equal(t, happyReq3.SecondaryInterfacesExist, false)
 	assert.Equal(t, happyReq3.BackendInterfaceExist, true)
+	assert.DeepEqual(t, happyReq3.BackendInterfaceVFs, map[string]cns.VirtualFunction{
+		"00:00:00:00:00:00": {MacAddress: "02:00:00:00:00:01", VlanID: 100, Trust: true},
+	})
 }
 
 func TestValidateMultitenantIPConfigsRequestFailure(t *testing.T) {
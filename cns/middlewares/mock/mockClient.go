@@ -156,11 +156,14 @@ func NewClient() *Client {
 		Status: v1alpha1.MultitenantPodNetworkConfigStatus{
 			InterfaceInfos: []v1alpha1.InterfaceInfo{
 				{
-					PrimaryIP:  "192.168.0.1/32",
-					MacAddress: "00:00:00:00:00:00",
-					GatewayIP:  "10.0.0.1",
-					NCID:       "testncid",
-					DeviceType: v1alpha1.DeviceTypeInfiniBandNIC,
+					PrimaryIP:    "192.168.0.1/32",
+					MacAddress:   "00:00:00:00:00:00",
+					GatewayIP:    "10.0.0.1",
+					NCID:         "testncid",
+					DeviceType:   v1alpha1.DeviceTypeInfiniBandNIC,
+					VlanID:       100,
+					VFMacAddress: "02:00:00:00:00:01",
+					VFTrust:      true,
 				},
 			},
 		},
@@ -3,6 +3,7 @@ package nodesubnet
 import (
 	"context"
 	"log"
+	"net/http"
 	"net/netip"
 	"time"
 
@@ -34,12 +35,14 @@ type IPConsumer interface {
 // interval will vary within the range of minRefreshInterval and
 // maxRefreshInterval. When no diff is observed after a fetch, the interval
 // doubles (subject to the maximum interval). When a diff is observed, the
-// interval resets to the minimum.
+// interval resets to the minimum. When fetches fail, the interval backs off
+// exponentially from the minimum.
 type IPFetcher struct {
 	// Node subnet config
 	intfFetcherClient InterfaceRetriever
 	consumer          IPConsumer
 	fetcher           *refresh.Fetcher[nmagent.Interfaces]
+	maxInterval       time.Duration
 }
 
 // NewIPFetcher creates a new IPFetcher. If minInterval is 0, it will default to 4 seconds.
@@ -65,6 +68,7 @@ func NewIPFetcher(
 		intfFetcherClient: client,
 		consumer:          consumer,
 		fetcher:           nil,
+		maxInterval:       maxInterval,
 	}
 	fetcher := refresh.NewFetcher[nmagent.Interfaces](client.GetInterfaceIPInfo, minInterval, maxInterval, newIPFetcher.ProcessInterfaces, logger)
 	newIPFetcher.fetcher = fetcher
@@ -76,6 +80,17 @@ func (c *IPFetcher) Start(ctx context.Context) {
 	c.fetcher.Start(ctx)
 }
 
+// Trigger fetches the secondary IPs from NMAgent right away, and returns once they're passed to the consumer.
+func (c *IPFetcher) Trigger(ctx context.Context) error {
+	return errors.Wrap(c.fetcher.Trigger(ctx), "refreshing secondary IPs")
+}
+
+// HealthCheck returns a check which fails when the secondary IPs weren't fetched successfully from NMAgent
+// for twice the maximum refresh interval.
+func (c *IPFetcher) HealthCheck() func(*http.Request) error {
+	return c.fetcher.HealthCheck(2 * c.maxInterval) //nolint:gomnd // twice the max interval
+}
+
 // Fetch IPs from NMAgent and pass to the consumer
 func (c *IPFetcher) ProcessInterfaces(response nmagent.Interfaces) error {
 	if len(response.Entries) == 0 {
@@ -510,7 +510,7 @@ func (service *HTTPRestService) createOrUpdateNetworkContainer(w http.ResponseWr
 	}
 	if err := req.Validate(); err != nil {
 		logger.Errorf("[Azure CNS] invalid request %+v: %s", req, err)
-		w.WriteHeader(http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, types.InvalidRequest, fmt.Sprintf("invalid request: %s", err))
 		return
 	}
 
@@ -668,8 +668,8 @@ func (service *HTTPRestService) getOrRefreshNetworkContainers(w http.ResponseWri
 		service.handlePostNetworkContainers(w, r)
 		return
 	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
 		err := errors.New("[Azure CNS] getOrRefreshNetworkContainers did not receive a GET or POST")
+		respondError(w, http.StatusMethodNotAllowed, types.UnsupportedVerb, err.Error())
 		logger.Response(service.Name, nil, types.InvalidParameter, err)
 		return
 	}
@@ -905,16 +905,22 @@ func respondJSON(w http.ResponseWriter, statusCode int, body any) {
 	}
 }
 
+// respondError responds to requests which are rejected before their handler has a response to return,
+// with the HTTP status code and the Error envelope of the response code.
+func respondError(w http.ResponseWriter, statusCode int, code types.ResponseCode, message string) {
+	respondJSON(w, statusCode, cns.Response{ReturnCode: code, Message: message})
+}
+
 // Publish Network Container by calling nmagent
 func (service *HTTPRestService) publishNetworkContainer(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "PublishNetworkContainer expects a POST", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, types.UnsupportedVerb, "PublishNetworkContainer expects a POST")
 		return
 	}
 
 	var req cns.PublishNetworkContainerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("could not decode request body: %v", err), http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, types.InvalidRequest, fmt.Sprintf("could not decode request body: %v", err))
 		return
 	}
 
@@ -1004,13 +1010,13 @@ func (service *HTTPRestService) publishNetworkContainer(w http.ResponseWriter, r
 
 func (service *HTTPRestService) unpublishNetworkContainer(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "UnpublishNetworkContainer expects a POST", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, types.UnsupportedVerb, "UnpublishNetworkContainer expects a POST")
 		return
 	}
 
 	var req cns.UnpublishNetworkContainerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("could not decode request body: %v", err), http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, types.InvalidRequest, fmt.Sprintf("could not decode request body: %v", err))
 		return
 	}
 
@@ -1040,7 +1046,7 @@ func (service *HTTPRestService) unpublishNetworkContainer(w http.ResponseWriter,
 		// However, if the body is not `""\n`, it is invalid and therefore, we must return an error
 		// []byte{34, 34, 10} here represents []byte(`""`+"\n")
 		if !bytes.Equal(req.DeleteNetworkContainerRequestBody, []byte{34, 34, 10}) {
-			http.Error(w, fmt.Sprintf("could not unmarshal delete network container body: %v", err), http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, types.InvalidRequest, fmt.Sprintf("could not unmarshal delete network container body: %v", err))
 			return
 		}
 	} else {
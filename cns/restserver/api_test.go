@@ -287,6 +287,7 @@ func TestSetOrchestratorType_NCsPresent(t *testing.T) {
 			response: cns.Response{
 				ReturnCode: types.InvalidRequest,
 				Message:    "Invalid request since this node has already been registered as node1",
+				Error:      types.NewError(types.InvalidRequest, "Invalid request since this node has already been registered as node1"),
 			},
 			wanthttperror: false,
 		},
@@ -0,0 +1,353 @@
+package restserver
+
+import (
+	stderrors "errors"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// egressIPPodName is the pod name the reserved egress ips are assigned to in the ip state
+	egressIPPodName = "egressip"
+)
+
+var ErrNoEgressIPAvailable = errors.New("no available ip to reserve as egress ip")
+
+// egressIPPodInfo is the PodInfo an egress ip of the namespace is reserved for. It keeps the egress ip out of the pool
+// of available ips without a real pod holding it.
+func egressIPPodInfo(namespace, ipConfigID string) cns.PodInfo {
+	id := egressIPPodName + "-" + namespace + "-" + ipConfigID
+	return cns.NewPodInfo(id, id, egressIPPodName, namespace)
+}
+
+// EnableEgressIP opts the namespace in to egress with a static egress ip. The egress ip is reserved on the node when
+// the first pod of the namespace requests its ips, so only the nodes running pods of the namespace hold one.
+func (service *HTTPRestService) EnableEgressIP(namespace string) error {
+	service.Lock()
+	defer service.Unlock()
+
+	if service.state.EgressIPByNamespace == nil {
+		service.state.EgressIPByNamespace = make(map[string]*cns.EgressIPAssignment)
+	}
+	assignment, exists := service.state.EgressIPByNamespace[namespace]
+	if !exists {
+		assignment = &cns.EgressIPAssignment{Namespace: namespace}
+		service.state.EgressIPByNamespace[namespace] = assignment
+		logger.Printf("[EnableEgressIP] Enabled egress ip for namespace %s", namespace)
+	}
+	assignment.Disabled = false
+
+	// detect the loss of the current egress ip, the pods requesting their ips next get another one
+	if err := service.keepEgressIPUntransacted(assignment); err != nil {
+		return err
+	}
+	return errors.Wrap(service.saveState(), "failed to persist egress ip assignment")
+}
+
+// DisableEgressIP opts the namespace out of egress with a static egress ip. The current egress ip is released if no
+// pod uses it, otherwise it is retired until its pods are gone.
+func (service *HTTPRestService) DisableEgressIP(namespace string) error {
+	service.Lock()
+	defer service.Unlock()
+
+	assignment, exists := service.state.EgressIPByNamespace[namespace]
+	if !exists {
+		return nil
+	}
+
+	if err := service.retireEgressIPUntransacted(assignment); err != nil {
+		return err
+	}
+	assignment.Disabled = true
+	service.deleteDrainedEgressIPUntransacted(assignment)
+	logger.Printf("[DisableEgressIP] Disabled egress ip for namespace %s", namespace)
+	return errors.Wrap(service.saveState(), "failed to persist egress ip release")
+}
+
+// ReconcileEgressIPs reserves the persisted egress ips again after the ip state was rebuilt, and releases the ones
+// whose pods are gone. The namespaces whose egress ip was lost get another one when their next pod requests its ips.
+func (service *HTTPRestService) ReconcileEgressIPs() error {
+	service.Lock()
+	defer service.Unlock()
+
+	// the pods released while cns was down are forgotten first
+	released := func(podKey string) bool {
+		_, found := service.PodIPIDByPodInterfaceKey[podKey]
+		return !found
+	}
+
+	var errs []error
+	for namespace, assignment := range service.state.EgressIPByNamespace {
+		service.dropEgressIPPodsUntransacted(assignment, released)
+		if err := service.keepEgressIPUntransacted(assignment); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to reconcile egress ip of namespace %s", namespace))
+		}
+	}
+	if err := service.reserveRetiredEgressIPsUntransacted(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := service.saveState(); err != nil {
+		errs = append(errs, errors.Wrap(err, "failed to persist egress ip assignments"))
+	}
+	return stderrors.Join(errs...)
+}
+
+// reserveEgressIPUntransacted reserves the ip config for the namespace, and reports whether it is reserved for it.
+// An ip assigned to a pod or released from the network container can't be reserved.
+func (service *HTTPRestService) reserveEgressIPUntransacted(namespace, ipConfigID string) (bool, error) {
+	podInfo := egressIPPodInfo(namespace, ipConfigID)
+	ipConfig, found := service.PodIPConfigState[ipConfigID]
+	if !found {
+		return false, nil
+	}
+
+	switch ipConfig.GetState() { //nolint:exhaustive // the ip can't be reserved in any other state
+	case types.Assigned:
+		return ipConfig.PodInfo != nil && ipConfig.PodInfo.Key() == podInfo.Key(), nil
+	case types.Available:
+		// the ip state was rebuilt or the ip was released by the pod it was lost to, reserve it again
+		if err := service.assignIPConfig(ipConfig, podInfo); err != nil {
+			return false, errors.Wrapf(err, "failed to reserve egress ip %s", ipConfig.IPAddress)
+		}
+		logger.Printf("[EgressIP] Reserved egress ip %s of namespace %s again", ipConfig.IPAddress, namespace)
+		return true, nil
+	}
+	return false, nil
+}
+
+// unreserveEgressIPUntransacted returns the ip config to the pool if it is reserved for the namespace.
+func (service *HTTPRestService) unreserveEgressIPUntransacted(namespace, ipConfigID string) error {
+	podInfo := egressIPPodInfo(namespace, ipConfigID)
+	ipConfig, found := service.PodIPConfigState[ipConfigID]
+	if !found || ipConfig.GetState() != types.Assigned || ipConfig.PodInfo == nil || ipConfig.PodInfo.Key() != podInfo.Key() {
+		return nil
+	}
+	if _, err := service.unassignIPConfig(ipConfig, podInfo); err != nil {
+		return errors.Wrapf(err, "failed to release egress ip %s", ipConfig.IPAddress)
+	}
+	return nil
+}
+
+// keepEgressIPUntransacted reserves the current egress ip of the namespace again if needed. If it was lost, the
+// failover is recorded and the ip is retired for the pods using it.
+func (service *HTTPRestService) keepEgressIPUntransacted(assignment *cns.EgressIPAssignment) error {
+	if assignment.IPConfigID == "" {
+		return nil
+	}
+	reserved, err := service.reserveEgressIPUntransacted(assignment.Namespace, assignment.IPConfigID)
+	if err != nil || reserved {
+		return err
+	}
+
+	assignment.PreviousIPAddress = assignment.IPAddress
+	assignment.FailoverCount++
+	assignment.LastFailoverTime = time.Now()
+	egressIPFailoverCount.Inc()
+	logger.Errorf("[EgressIP] Egress ip %s of namespace %s was lost, %d pods keep using it until they are deleted",
+		assignment.IPAddress, assignment.Namespace, len(assignment.Pods))
+	return service.retireEgressIPUntransacted(assignment)
+}
+
+// retireEgressIPUntransacted clears the current egress ip of the namespace. It is kept out of the pool as a retired
+// ip if pods use it, and returned to the pool otherwise.
+func (service *HTTPRestService) retireEgressIPUntransacted(assignment *cns.EgressIPAssignment) error {
+	if assignment.IPConfigID == "" {
+		return nil
+	}
+	if len(assignment.Pods) > 0 {
+		assignment.RetiredIPs = append(assignment.RetiredIPs, cns.RetiredEgressIP{
+			IPConfigID: assignment.IPConfigID,
+			IPAddress:  assignment.IPAddress,
+			Pods:       assignment.Pods,
+		})
+	} else if err := service.unreserveEgressIPUntransacted(assignment.Namespace, assignment.IPConfigID); err != nil {
+		return err
+	}
+	assignment.IPConfigID, assignment.IPAddress, assignment.Pods = "", "", nil
+	return nil
+}
+
+// reserveRetiredEgressIPsUntransacted reserves the retired egress ips which are available again, so that no pod is
+// assigned an ip other pods still snat to.
+func (service *HTTPRestService) reserveRetiredEgressIPsUntransacted() error {
+	var errs []error
+	for namespace, assignment := range service.state.EgressIPByNamespace {
+		for _, retired := range assignment.RetiredIPs {
+			if _, err := service.reserveEgressIPUntransacted(namespace, retired.IPConfigID); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// deleteDrainedEgressIPUntransacted removes the assignment of a namespace which opted out once no pod uses its
+// egress ips anymore.
+func (service *HTTPRestService) deleteDrainedEgressIPUntransacted(assignment *cns.EgressIPAssignment) {
+	if assignment.Disabled && assignment.IPConfigID == "" && len(assignment.RetiredIPs) == 0 {
+		delete(service.state.EgressIPByNamespace, assignment.Namespace)
+	}
+}
+
+// assignEgressIPUntransacted reserves an egress ip for the namespace if it has none, preferring the network container
+// it was last reserved from.
+func (service *HTTPRestService) assignEgressIPUntransacted(assignment *cns.EgressIPAssignment) error {
+	if err := service.keepEgressIPUntransacted(assignment); err != nil {
+		return err
+	}
+	if assignment.IPConfigID != "" {
+		return nil
+	}
+
+	ipConfig, err := service.availableEgressIPUntransacted(assignment.NetworkContainerID)
+	if err != nil {
+		return err
+	}
+	if err := service.assignIPConfig(ipConfig, egressIPPodInfo(assignment.Namespace, ipConfig.ID)); err != nil {
+		return errors.Wrapf(err, "failed to reserve egress ip %s", ipConfig.IPAddress)
+	}
+	assignment.NetworkContainerID = ipConfig.NCID
+	assignment.IPConfigID = ipConfig.ID
+	assignment.IPAddress = ipConfig.IPAddress
+	logger.Printf("[EgressIP] Reserved egress ip %s for namespace %s", ipConfig.IPAddress, assignment.Namespace)
+	return nil
+}
+
+// availableEgressIPUntransacted returns the lowest available ipv4 ip, preferring the ips of the network container ncID.
+func (service *HTTPRestService) availableEgressIPUntransacted(ncID string) (cns.IPConfigurationStatus, error) {
+	candidates := []cns.IPConfigurationStatus{}
+	for _, ipConfig := range service.PodIPConfigState { //nolint:gocritic // ignore copy
+		if ipConfig.GetState() != types.Available || net.ParseIP(ipConfig.IPAddress).To4() == nil {
+			continue
+		}
+		candidates = append(candidates, ipConfig)
+	}
+	if len(candidates) == 0 {
+		return cns.IPConfigurationStatus{}, ErrNoEgressIPAvailable
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if (candidates[i].NCID == ncID) != (candidates[j].NCID == ncID) {
+			return candidates[i].NCID == ncID
+		}
+		return compareIPs(candidates[i].IPAddress, candidates[j].IPAddress) < 0
+	})
+	return candidates[0], nil
+}
+
+func compareIPs(a, b string) int {
+	ipA, ipB := net.ParseIP(a).To16(), net.ParseIP(b).To16()
+	for i := range ipA {
+		if ipA[i] != ipB[i] {
+			return int(ipA[i]) - int(ipB[i])
+		}
+	}
+	return 0
+}
+
+// setEgressIPAddresses sets the egress ip of the namespace of the pod on its ipv4 ip infos, reserving one if the pod
+// is the first of the namespace on the node. The pod keeps its ips without egress ip if none can be reserved.
+func (service *HTTPRestService) setEgressIPAddresses(podInfo cns.PodInfo, podIPInfo []cns.PodIpInfo) {
+	service.Lock()
+	defer service.Unlock()
+
+	assignment, exists := service.state.EgressIPByNamespace[podInfo.Namespace()]
+	if !exists || assignment.Disabled {
+		return
+	}
+	if err := service.assignEgressIPUntransacted(assignment); err != nil {
+		logger.Errorf("[EgressIP] Failed to reserve egress ip of namespace %s for pod %s: %v", assignment.Namespace, podInfo.Key(), err)
+		return
+	}
+
+	if !slices.Contains(assignment.Pods, podInfo.Key()) {
+		assignment.Pods = append(assignment.Pods, podInfo.Key())
+	}
+	if err := service.saveState(); err != nil {
+		logger.Errorf("[EgressIP] Failed to persist egress ip assignment of namespace %s: %v", assignment.Namespace, err)
+	}
+
+	for i := range podIPInfo {
+		if net.ParseIP(podIPInfo[i].PodIPConfig.IPAddress).To4() != nil {
+			podIPInfo[i].EgressIPAddress = assignment.IPAddress
+		}
+	}
+}
+
+// releaseEgressIPPodUntransacted forgets the released pod in the egress ips it was given.
+func (service *HTTPRestService) releaseEgressIPPodUntransacted(podKey string) {
+	changed := false
+	for _, assignment := range service.state.EgressIPByNamespace {
+		if service.dropEgressIPPodsUntransacted(assignment, func(key string) bool { return key == podKey }) {
+			changed = true
+		}
+	}
+
+	// the released ips may include a retired egress ip which was lost to the pod
+	if err := service.reserveRetiredEgressIPsUntransacted(); err != nil {
+		logger.Errorf("[EgressIP] Failed to reserve retired egress ips: %v", err)
+	}
+	if !changed {
+		return
+	}
+	if err := service.saveState(); err != nil {
+		logger.Errorf("[EgressIP] Failed to persist egress ip assignments: %v", err)
+	}
+}
+
+// dropEgressIPPodsUntransacted removes the pods matching gone from the egress ips of the namespace, and reports
+// whether any was removed. The current egress ip is released with the last pod using it, and the retired ones once no
+// pod uses them anymore.
+func (service *HTTPRestService) dropEgressIPPodsUntransacted(assignment *cns.EgressIPAssignment, gone func(string) bool) bool {
+	changed := false
+	if n := len(assignment.Pods); n > 0 {
+		assignment.Pods = slices.DeleteFunc(assignment.Pods, gone)
+		changed = len(assignment.Pods) != n
+		if len(assignment.Pods) == 0 {
+			if err := service.retireEgressIPUntransacted(assignment); err != nil {
+				logger.Errorf("[EgressIP] Failed to release egress ip of namespace %s: %v", assignment.Namespace, err)
+			}
+		}
+	}
+
+	retiredIPs := assignment.RetiredIPs[:0]
+	for _, retired := range assignment.RetiredIPs {
+		n := len(retired.Pods)
+		retired.Pods = slices.DeleteFunc(retired.Pods, gone)
+		changed = changed || len(retired.Pods) != n
+		if len(retired.Pods) > 0 {
+			retiredIPs = append(retiredIPs, retired)
+			continue
+		}
+		if err := service.unreserveEgressIPUntransacted(assignment.Namespace, retired.IPConfigID); err != nil {
+			logger.Errorf("[EgressIP] Failed to release retired egress ip %s of namespace %s: %v", retired.IPAddress, assignment.Namespace, err)
+		}
+		logger.Printf("[EgressIP] Released retired egress ip %s of namespace %s", retired.IPAddress, assignment.Namespace)
+	}
+	assignment.RetiredIPs = retiredIPs
+	service.deleteDrainedEgressIPUntransacted(assignment)
+	return changed
+}
+
+func (service *HTTPRestService) HandleDebugEgressIPs(w http.ResponseWriter, r *http.Request) { //nolint
+	opName := "handleDebugEgressIPs"
+	service.RLock()
+	defer service.RUnlock()
+	resp := cns.GetEgressIPsResponse{
+		EgressIPs: make(map[string]cns.EgressIPAssignment, len(service.state.EgressIPByNamespace)),
+	}
+	for namespace, assignment := range service.state.EgressIPByNamespace {
+		resp.EgressIPs[namespace] = *assignment
+	}
+	err := common.Encode(w, &resp)
+	logger.Response(opName, resp, resp.Response.ReturnCode, err)
+}
@@ -0,0 +1,183 @@
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	egressIPTestPod1 = cns.NewPodInfo("egress1-eth0", "egress1-eth0", "pod1", "ns")
+	egressIPTestPod2 = cns.NewPodInfo("egress2-eth0", "egress2-eth0", "pod2", "ns")
+)
+
+func newEgressIPTestService(t *testing.T) *HTTPRestService {
+	svc := getTestService(cns.KubernetesCRD)
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{
+		testIPID1: newSecondaryIPConfig(testIP1, -1),
+		testIPID2: newSecondaryIPConfig(testIP2, -1),
+		testIPID3: newSecondaryIPConfig(testIP3, -1),
+	}
+	createAndValidateNCRequest(t, secondaryIPConfigs, testNCID, "-1")
+	return svc
+}
+
+// setEgressIPTestAddresses sets the egress ip of the pod on a single ipv4 ip info and returns it.
+func setEgressIPTestAddresses(svc *HTTPRestService, podInfo cns.PodInfo) string {
+	podIPInfo := []cns.PodIpInfo{{PodIPConfig: cns.IPSubnet{IPAddress: testIP4, PrefixLength: 24}}}
+	svc.setEgressIPAddresses(podInfo, podIPInfo)
+	return podIPInfo[0].EgressIPAddress
+}
+
+func TestEgressIPReservedWithFirstPod(t *testing.T) {
+	svc := newEgressIPTestService(t)
+
+	require.NoError(t, svc.EnableEgressIP("ns"))
+	// no egress ip is reserved until a pod of the namespace is on the node
+	require.Empty(t, svc.state.EgressIPByNamespace["ns"].IPAddress)
+	require.Equal(t, types.Available, egressIPTestState(svc, testIPID1))
+
+	require.Equal(t, testIP1, setEgressIPTestAddresses(svc, egressIPTestPod1))
+	require.Equal(t, testIP1, setEgressIPTestAddresses(svc, egressIPTestPod2))
+	assignment := svc.state.EgressIPByNamespace["ns"]
+	require.Equal(t, testNCID, assignment.NetworkContainerID)
+	require.Equal(t, []string{egressIPTestPod1.Key(), egressIPTestPod2.Key()}, assignment.Pods)
+	require.Equal(t, types.Assigned, egressIPTestState(svc, testIPID1))
+	require.Equal(t, egressIPPodInfo("ns", testIPID1).Key(), svc.PodIPConfigState[testIPID1].PodInfo.Key())
+
+	// the egress ip is released with the last pod of the namespace
+	svc.releaseEgressIPPodUntransacted(egressIPTestPod1.Key())
+	require.Equal(t, types.Assigned, egressIPTestState(svc, testIPID1))
+	svc.releaseEgressIPPodUntransacted(egressIPTestPod2.Key())
+	require.Equal(t, types.Available, egressIPTestState(svc, testIPID1))
+	require.Empty(t, svc.state.EgressIPByNamespace["ns"].IPAddress)
+	require.False(t, svc.state.EgressIPByNamespace["ns"].Disabled)
+}
+
+func TestSetEgressIPAddresses(t *testing.T) {
+	svc := newEgressIPTestService(t)
+	require.NoError(t, svc.EnableEgressIP("ns"))
+
+	// pods of other namespaces don't get an egress ip
+	require.Empty(t, setEgressIPTestAddresses(svc, testPod1Info))
+	require.Equal(t, types.Available, egressIPTestState(svc, testIPID1))
+
+	podIPInfo := []cns.PodIpInfo{
+		{PodIPConfig: cns.IPSubnet{IPAddress: testIP2, PrefixLength: 24}},
+		{PodIPConfig: cns.IPSubnet{IPAddress: testIP2v6, PrefixLength: 120}},
+	}
+	svc.setEgressIPAddresses(egressIPTestPod1, podIPInfo)
+	require.Equal(t, testIP1, podIPInfo[0].EgressIPAddress)
+	require.Empty(t, podIPInfo[1].EgressIPAddress)
+}
+
+func TestEgressIPFailover(t *testing.T) {
+	svc := newEgressIPTestService(t)
+	require.NoError(t, svc.EnableEgressIP("ns"))
+	require.Equal(t, testIP1, setEgressIPTestAddresses(svc, egressIPTestPod1))
+
+	// the egress ip was assigned to a pod while the ip state was rebuilt
+	state, _ := newPodStateWithOrchestratorContext(testIP1, testIPID1, testNCID, types.Assigned, ipPrefixBitsv4, 0, testPod1Info)
+	svc.PodIPConfigState[testIPID1] = state
+	svc.PodIPIDByPodInterfaceKey[testPod1Info.Key()] = []string{testIPID1}
+
+	require.NoError(t, svc.EnableEgressIP("ns"))
+	assignment := svc.state.EgressIPByNamespace["ns"]
+	require.Equal(t, testIP1, assignment.PreviousIPAddress)
+	require.Equal(t, 1, assignment.FailoverCount)
+	require.False(t, assignment.LastFailoverTime.IsZero())
+	require.Equal(t, []cns.RetiredEgressIP{{IPConfigID: testIPID1, IPAddress: testIP1, Pods: []string{egressIPTestPod1.Key()}}}, assignment.RetiredIPs)
+
+	// the next pod of the namespace fails over to another egress ip
+	require.Equal(t, testIP2, setEgressIPTestAddresses(svc, egressIPTestPod2))
+
+	// once released by the pod it was lost to, the retired egress ip is kept out of the pool for the pods using it
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	require.Equal(t, types.Assigned, egressIPTestState(svc, testIPID1))
+	require.Equal(t, egressIPPodInfo("ns", testIPID1).Key(), svc.PodIPConfigState[testIPID1].PodInfo.Key())
+
+	// and returned to the pool with the last of them
+	svc.releaseEgressIPPodUntransacted(egressIPTestPod1.Key())
+	require.Equal(t, types.Available, egressIPTestState(svc, testIPID1))
+	require.Empty(t, svc.state.EgressIPByNamespace["ns"].RetiredIPs)
+	require.Equal(t, testIP2, svc.state.EgressIPByNamespace["ns"].IPAddress)
+}
+
+func TestReconcileEgressIPs(t *testing.T) {
+	t.Run("egress ip is reserved again after the ip state was rebuilt", func(t *testing.T) {
+		svc := newEgressIPTestService(t)
+		require.NoError(t, svc.EnableEgressIP("ns"))
+		require.Equal(t, testIP1, setEgressIPTestAddresses(svc, egressIPTestPod1))
+
+		ipConfig := svc.PodIPConfigState[testIPID1]
+		ipConfig.SetState(types.Available)
+		ipConfig.PodInfo = nil
+		svc.PodIPConfigState[testIPID1] = ipConfig
+		delete(svc.PodIPIDByPodInterfaceKey, egressIPPodInfo("ns", testIPID1).Key())
+		// the pod still holds its ips
+		svc.PodIPIDByPodInterfaceKey[egressIPTestPod1.Key()] = []string{testIPID3}
+
+		require.NoError(t, svc.ReconcileEgressIPs())
+		assignment := svc.state.EgressIPByNamespace["ns"]
+		require.Equal(t, testIP1, assignment.IPAddress)
+		require.Zero(t, assignment.FailoverCount)
+		require.Equal(t, types.Assigned, egressIPTestState(svc, testIPID1))
+	})
+
+	t.Run("egress ip of pods released while cns was down is released", func(t *testing.T) {
+		svc := newEgressIPTestService(t)
+		require.NoError(t, svc.EnableEgressIP("ns"))
+		require.Equal(t, testIP1, setEgressIPTestAddresses(svc, egressIPTestPod1))
+
+		require.NoError(t, svc.ReconcileEgressIPs())
+		require.Empty(t, svc.state.EgressIPByNamespace["ns"].IPAddress)
+		require.Equal(t, types.Available, egressIPTestState(svc, testIPID1))
+	})
+
+	t.Run("no ip left to fail over to", func(t *testing.T) {
+		svc := newEgressIPTestService(t)
+		require.NoError(t, svc.EnableEgressIP("ns"))
+		for _, ipID := range []string{testIPID1, testIPID2, testIPID3} {
+			ipConfig := svc.PodIPConfigState[ipID]
+			ipConfig.SetState(types.PendingRelease)
+			svc.PodIPConfigState[ipID] = ipConfig
+		}
+
+		require.NoError(t, svc.ReconcileEgressIPs())
+		require.Empty(t, setEgressIPTestAddresses(svc, egressIPTestPod1))
+	})
+}
+
+func TestDisableEgressIP(t *testing.T) {
+	t.Run("unused egress ip is released", func(t *testing.T) {
+		svc := newEgressIPTestService(t)
+		require.NoError(t, svc.EnableEgressIP("ns"))
+
+		require.NoError(t, svc.DisableEgressIP("ns"))
+		require.NotContains(t, svc.state.EgressIPByNamespace, "ns")
+
+		// disabling a namespace without egress ip is a noop
+		require.NoError(t, svc.DisableEgressIP("ns"))
+	})
+
+	t.Run("egress ip is kept until its pods are gone", func(t *testing.T) {
+		svc := newEgressIPTestService(t)
+		require.NoError(t, svc.EnableEgressIP("ns"))
+		require.Equal(t, testIP1, setEgressIPTestAddresses(svc, egressIPTestPod1))
+
+		require.NoError(t, svc.DisableEgressIP("ns"))
+		require.Equal(t, types.Assigned, egressIPTestState(svc, testIPID1))
+		require.Empty(t, setEgressIPTestAddresses(svc, egressIPTestPod2))
+
+		svc.releaseEgressIPPodUntransacted(egressIPTestPod1.Key())
+		require.Equal(t, types.Available, egressIPTestState(svc, testIPID1))
+		require.NotContains(t, svc.state.EgressIPByNamespace, "ns")
+	})
+}
+
+func egressIPTestState(svc *HTTPRestService, ipID string) types.IPState {
+	ipConfig := svc.PodIPConfigState[ipID]
+	return ipConfig.GetState()
+}
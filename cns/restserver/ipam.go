@@ -37,7 +37,7 @@ const (
 )
 
 // requestIPConfigHandlerHelper validates the request, assign IPs and return the IPConfigs
-func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context, ipconfigsRequest cns.IPConfigsRequest) (*cns.IPConfigsResponse, error) {
+func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context, ipconfigsRequest cns.IPConfigsRequest) (_ *cns.IPConfigsResponse, err error) {
 	// For SWIFT v2 scenario, the validator function will also modify the ipconfigsRequest.
 	podInfo, returnCode, returnMessage := service.validateIPConfigsRequest(ctx, ipconfigsRequest)
 	if returnCode != types.Success {
@@ -51,6 +51,14 @@ func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context
 
 	var podIPInfoResult []cns.PodIpInfo
 	if ipconfigsRequest.BackendInterfaceExist {
+		// return the virtual functions allocated to the pod if the request fails so they aren't leaked
+		defer func() {
+			if err != nil {
+				service.Lock()
+				service.releaseVFsUntransacted(podInfo.Key())
+				service.Unlock()
+			}
+		}()
 		for _, bNICMacAddress := range ipconfigsRequest.BackendInterfaceMacAddresses {
 			PnPID, err := service.getPNPIDFromMacAddress(ctx, bNICMacAddress)
 			if err != nil {
@@ -62,10 +70,21 @@ func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context
 					PodIPInfo: []cns.PodIpInfo{},
 				}, err
 			}
+			vf, err := service.allocateVF(podInfo.Key(), PnPID, ipconfigsRequest.BackendInterfaceVFs[bNICMacAddress])
+			if err != nil {
+				return &cns.IPConfigsResponse{
+					Response: cns.Response{
+						ReturnCode: types.FailedToAllocateIPConfig,
+						Message:    fmt.Sprintf("BackendNIC virtual function allocation failed: %v, config request is %v", err, ipconfigsRequest),
+					},
+					PodIPInfo: []cns.PodIpInfo{},
+				}, err
+			}
 			podBackendInfo := cns.PodIpInfo{
-				MacAddress: bNICMacAddress,
-				NICType:    cns.BackendNIC,
-				PnPID:      PnPID,
+				MacAddress:      bNICMacAddress,
+				NICType:         cns.BackendNIC,
+				PnPID:           PnPID,
+				VirtualFunction: vf,
 			}
 			podIPInfoResult = append(podIPInfoResult, podBackendInfo)
 		}
@@ -106,6 +125,7 @@ func (service *HTTPRestService) requestIPConfigHandlerHelper(ctx context.Context
 		}
 	}
 
+	service.setEgressIPAddresses(podInfo, podIPInfo)
 	podIPInfoResult = append(podIPInfoResult, podIPInfo...)
 	return &cns.IPConfigsResponse{
 		Response: cns.Response{
@@ -793,6 +813,8 @@ func (service *HTTPRestService) unassignIPConfig(ipconfig cns.IPConfigurationSta
 func (service *HTTPRestService) releaseIPConfigs(podInfo cns.PodInfo) error {
 	service.Lock()
 	defer service.Unlock()
+	// the virtual functions are released whether or not the ips are, the pod is gone either way
+	service.releaseVFsUntransacted(podInfo.Key())
 	ipsToBeReleased := make([]cns.IPConfigurationStatus, 0)
 	logger.Printf("[releaseIPConfigs] Releasing pod with key %s", podInfo.Key())
 	for i, ipID := range service.PodIPIDByPodInterfaceKey[podInfo.Key()] {
@@ -832,6 +854,7 @@ func (service *HTTPRestService) releaseIPConfigs(podInfo cns.PodInfo) error {
 		return fmt.Errorf("[releaseIPConfigs] Failed to release one or more IPs. Not releasing any IPs for pod %+v", podInfo)
 	}
 
+	service.releaseEgressIPPodUntransacted(podInfo.Key())
 	logger.Printf("[releaseIPConfigs] Successfully released all IPs for pod %+v", podInfo)
 	return nil
 }
@@ -1341,6 +1364,11 @@ func updateIPInfoMap(iPInfo map[string]*IPInfo, interfaceInfo *IPInfo, ifName, e
 		iPInfo[ifName].MacAddress = interfaceInfo.MacAddress
 		logger.Printf("[updateEndpoint] update the endpoint %s with MacAddress  %s", endpointID, interfaceInfo.MacAddress)
 	}
+	if interfaceInfo.VirtualFunction != nil {
+		iPInfo[ifName].PnPID = interfaceInfo.PnPID
+		iPInfo[ifName].VirtualFunction = interfaceInfo.VirtualFunction
+		logger.Printf("[updateEndpoint] update the endpoint %s with virtual function %d of %s", endpointID, interfaceInfo.VirtualFunction.Index, interfaceInfo.PnPID)
+	}
 }
 
 // verifyUpdateEndpointStateRequest verify the CNI request body for the UpdateENdpointState API
@@ -111,6 +111,12 @@ var (
 		},
 		[]string{},
 	)
+	egressIPFailoverCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "egress_ip_failover_total",
+			Help: "Count of namespaces whose egress IP was lost and reserved again from another IP",
+		},
+	)
 )
 
 func init() {
@@ -126,6 +132,7 @@ func init() {
 		availableIPCount,
 		pendingProgrammingIPCount,
 		pendingReleaseIPCount,
+		egressIPFailoverCount,
 	)
 }
 
@@ -2,6 +2,7 @@ package restserver
 
 import (
 	"context"
+	"net/http"
 	"net/netip"
 
 	"github.com/Azure/azure-container-networking/cns"
@@ -62,3 +63,9 @@ func (service *HTTPRestService) InitializeNodeSubnet(ctx context.Context, podInf
 func (service *HTTPRestService) StartNodeSubnet(ctx context.Context) {
 	service.nodesubnetIPFetcher.Start(ctx)
 }
+
+// NodeSubnetHealthCheck returns a check which fails when the secondary IPs for NodeSubnet weren't
+// refreshed from NMAgent recently. It must be called after InitializeNodeSubnet.
+func (service *HTTPRestService) NodeSubnetHealthCheck() func(*http.Request) error {
+	return service.nodesubnetIPFetcher.HealthCheck()
+}
@@ -2,6 +2,7 @@ package restserver
 
 import (
 	"context"
+	stderrors "errors"
 	"net"
 	"net/http"
 	"net/http/pprof"
@@ -16,7 +17,6 @@ import (
 	"github.com/Azure/azure-container-networking/cns/networkcontainers"
 	"github.com/Azure/azure-container-networking/cns/nodesubnet"
 	"github.com/Azure/azure-container-networking/cns/routes"
-	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/cns/types/bounded"
 	"github.com/Azure/azure-container-networking/cns/wireserver"
 	acn "github.com/Azure/azure-container-networking/common"
@@ -133,6 +133,9 @@ type IPInfo struct {
 	HostVethName  string      `json:",omitempty"`
 	MacAddress    string      `json:",omitempty"`
 	NICType       cns.NICType
+	// PnPID and VirtualFunction identify the SR-IOV virtual function moved into the pod for backend NICs on Linux
+	PnPID           string               `json:",omitempty"`
+	VirtualFunction *cns.VirtualFunction `json:",omitempty"`
 }
 
 type GetHTTPServiceDataResponse struct {
@@ -147,10 +150,8 @@ type HTTPRestServiceData struct { //nolint:musttag // not tagging struct for rev
 	PodIPConfigState         map[string]cns.IPConfigurationStatus // secondaryipid(uuid) is key
 }
 
-type Response struct {
-	ReturnCode types.ResponseCode
-	Message    string
-}
+// Response is the generic response of CNS, which carries the Error envelope of failed responses.
+type Response = cns.Response
 
 // GetEndpointResponse describes response from the The GetEndpoint API.
 type GetEndpointResponse struct {
@@ -181,6 +182,8 @@ type httpRestServiceState struct {
 	joinedNetworks                   map[string]struct{}
 	primaryInterface                 *wireserver.InterfaceInfo
 	PnpIDByMacAddress                map[string]string
+	EgressIPByNamespace              map[string]*cns.EgressIPAssignment `json:",omitempty"` // Namespace is key.
+	VFsByPodInterfaceKey             map[string][]VFAllocation          `json:",omitempty"` // PodInfo.Key() is key.
 }
 
 type networkInfo struct {
@@ -299,6 +302,7 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 	listener.AddHandler(cns.PathDebugIPAddresses, service.HandleDebugIPAddresses)
 	listener.AddHandler(cns.PathDebugPodContext, service.HandleDebugPodContext)
 	listener.AddHandler(cns.PathDebugRestData, service.HandleDebugRestData)
+	listener.AddHandler(cns.PathDebugEgressIPs, service.HandleDebugEgressIPs)
 	listener.AddHandler(cns.NetworkContainersURLPath, service.getOrRefreshNetworkContainers)
 	listener.AddHandler(cns.GetHomeAz, service.getHomeAz)
 	listener.AddHandler(cns.EndpointPath, service.EndpointHandlerAPI)
@@ -381,6 +385,33 @@ func (service *HTTPRestService) Stop() {
 	logger.Printf("[Azure CNS]  Service stopped.")
 }
 
+// Shutdown stops the service gracefully. The listener stops accepting requests and waits for the
+// requests in flight, such as RequestIPConfigs, to complete until the context is done, and the state
+// they wrote is then flushed to disk before the service is stopped.
+func (service *HTTPRestService) Shutdown(ctx context.Context) error {
+	var errs []error
+	if err := service.Listener.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	// flush under the lock, in case requests which outlived the drain are still writing state
+	service.Lock()
+	if service.store != nil {
+		if err := service.store.Flush(); err != nil {
+			errs = append(errs, errors.Wrap(err, "failed to flush state"))
+		}
+	}
+	if service.EndpointStateStore != nil {
+		if err := service.EndpointStateStore.Flush(); err != nil {
+			errs = append(errs, errors.Wrap(err, "failed to flush endpoint state"))
+		}
+	}
+	service.Unlock()
+
+	service.Stop()
+	return stderrors.Join(errs...)
+}
+
 // MustGenerateCNIConflistOnce will generate the CNI conflist once if the service was initialized with
 // a conflist generator. If not, this is a no-op.
 func (service *HTTPRestService) MustGenerateCNIConflistOnce() {
@@ -0,0 +1,97 @@
+package restserver
+
+import (
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoVFAvailable  = errors.New("no free virtual function on the physical function")
+	ErrNoVFMacAddress = errors.New("no mac address for the virtual function in the multitenant pod network config")
+)
+
+// VFAllocation is an SR-IOV virtual function of the physical function PnPID allocated to a pod.
+type VFAllocation struct {
+	PnPID string
+	cns.VirtualFunction
+}
+
+// allocateVF allocates the lowest free virtual function of the physical function pnpID to the pod and persists the
+// allocation. The mac address, vlan and trust of the virtual function are taken from config, as set from the
+// multitenant pod network config of the pod. The virtual function already allocated to the pod on pnpID is returned if
+// the pod requests it again. nil is returned if the physical function has no virtual functions.
+func (service *HTTPRestService) allocateVF(podKey, pnpID string, config cns.VirtualFunction) (*cns.VirtualFunction, error) {
+	service.Lock()
+	defer service.Unlock()
+
+	for i := range service.state.VFsByPodInterfaceKey[podKey] {
+		if alloc := service.state.VFsByPodInterfaceKey[podKey][i]; alloc.PnPID == pnpID {
+			return &alloc.VirtualFunction, nil
+		}
+	}
+
+	numVFs, err := sriovNumVFs(pnpID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get number of virtual functions of %s", pnpID)
+	}
+	if numVFs == 0 {
+		return nil, nil
+	}
+	if config.MacAddress == "" {
+		return nil, errors.Wrapf(ErrNoVFMacAddress, "failed to allocate virtual function of %s", pnpID)
+	}
+
+	used := make(map[int]bool)
+	for _, allocs := range service.state.VFsByPodInterfaceKey {
+		for i := range allocs {
+			if allocs[i].PnPID == pnpID {
+				used[allocs[i].Index] = true
+			}
+		}
+	}
+	index := -1
+	for i := 0; i < numVFs; i++ {
+		if !used[i] {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.Wrapf(ErrNoVFAvailable, "all %d virtual functions of %s are allocated", numVFs, pnpID)
+	}
+
+	alloc := VFAllocation{
+		PnPID: pnpID,
+		VirtualFunction: cns.VirtualFunction{
+			Index:      index,
+			MacAddress: config.MacAddress,
+			VlanID:     config.VlanID,
+			Trust:      config.Trust,
+		},
+	}
+	if service.state.VFsByPodInterfaceKey == nil {
+		service.state.VFsByPodInterfaceKey = make(map[string][]VFAllocation)
+	}
+	service.state.VFsByPodInterfaceKey[podKey] = append(service.state.VFsByPodInterfaceKey[podKey], alloc)
+	if err := service.saveState(); err != nil {
+		return nil, errors.Wrap(err, "failed to persist virtual function allocation")
+	}
+	logger.Printf("[allocateVF] Allocated virtual function %d of %s to pod %s", index, pnpID, podKey)
+	return &alloc.VirtualFunction, nil
+}
+
+// releaseVFsUntransacted returns the virtual functions allocated to the pod to their physical functions.
+func (service *HTTPRestService) releaseVFsUntransacted(podKey string) {
+	allocs, ok := service.state.VFsByPodInterfaceKey[podKey]
+	if !ok {
+		return
+	}
+	delete(service.state.VFsByPodInterfaceKey, podKey)
+	if err := service.saveState(); err != nil {
+		logger.Errorf("[releaseVFs] Failed to persist virtual function release of pod %s: %v", podKey, err)
+	}
+	for i := range allocs {
+		logger.Printf("[releaseVFs] Released virtual function %d of %s from pod %s", allocs[i].Index, allocs[i].PnPID, podKey)
+	}
+}
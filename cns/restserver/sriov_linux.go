@@ -0,0 +1,27 @@
+package restserver
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// pciDevicesPath is where the kernel exposes the pci devices, a variable to be overridden in tests.
+var pciDevicesPath = "/sys/bus/pci/devices"
+
+// sriovNumVFs returns the number of virtual functions enabled on the physical function with the pci address pnpID,
+// 0 if the device does not support SR-IOV.
+var sriovNumVFs = func(pnpID string) (int, error) {
+	b, err := os.ReadFile(filepath.Join(pciDevicesPath, pnpID, "sriov_numvfs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, errors.Wrap(err, "failed to read sriov_numvfs")
+	}
+	numVFs, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	return numVFs, errors.Wrap(err, "failed to parse sriov_numvfs")
+}
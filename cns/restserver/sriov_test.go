@@ -0,0 +1,103 @@
+package restserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPnPID         = "0000:3b:00.0"
+	testBackendNICMac = "00:0d:3a:00:00:01"
+)
+
+var testVFConfig = cns.VirtualFunction{MacAddress: "02:aa:bb:cc:00:01", VlanID: 100, Trust: true}
+
+func setTestNumVFs(t *testing.T, numVFs int) {
+	old := sriovNumVFs
+	sriovNumVFs = func(string) (int, error) { return numVFs, nil }
+	t.Cleanup(func() { sriovNumVFs = old })
+}
+
+func TestAllocateVF(t *testing.T) {
+	setTestNumVFs(t, 2)
+	svc := getTestService(cns.KubernetesCRD)
+
+	vf, err := svc.allocateVF("pod1", testPnPID, testVFConfig)
+	require.NoError(t, err)
+	require.Equal(t, cns.VirtualFunction{Index: 0, MacAddress: testVFConfig.MacAddress, VlanID: 100, Trust: true}, *vf)
+
+	// the pod gets the same virtual function when it requests it again
+	vf, err = svc.allocateVF("pod1", testPnPID, testVFConfig)
+	require.NoError(t, err)
+	require.Equal(t, 0, vf.Index)
+
+	vf, err = svc.allocateVF("pod2", testPnPID, cns.VirtualFunction{MacAddress: "02:aa:bb:cc:00:02"})
+	require.NoError(t, err)
+	require.Equal(t, 1, vf.Index)
+	require.Equal(t, "02:aa:bb:cc:00:02", vf.MacAddress)
+	require.False(t, vf.Trust)
+
+	_, err = svc.allocateVF("pod3", testPnPID, testVFConfig)
+	require.ErrorIs(t, err, ErrNoVFAvailable)
+
+	// the lowest released virtual function is allocated next
+	svc.releaseVFsUntransacted("pod1")
+	vf, err = svc.allocateVF("pod3", testPnPID, testVFConfig)
+	require.NoError(t, err)
+	require.Equal(t, 0, vf.Index)
+	require.NotContains(t, svc.state.VFsByPodInterfaceKey, "pod1")
+}
+
+func TestAllocateVFNoMacAddress(t *testing.T) {
+	setTestNumVFs(t, 2)
+	svc := getTestService(cns.KubernetesCRD)
+
+	_, err := svc.allocateVF("pod1", testPnPID, cns.VirtualFunction{VlanID: 100})
+	require.ErrorIs(t, err, ErrNoVFMacAddress)
+	require.Empty(t, svc.state.VFsByPodInterfaceKey)
+}
+
+func TestAllocateVFNoSRIOV(t *testing.T) {
+	setTestNumVFs(t, 0)
+	svc := getTestService(cns.KubernetesCRD)
+
+	vf, err := svc.allocateVF("pod1", testPnPID, cns.VirtualFunction{})
+	require.NoError(t, err)
+	require.Nil(t, vf)
+	require.Empty(t, svc.state.VFsByPodInterfaceKey)
+}
+
+func TestVFReleasedWhenIPRequestFails(t *testing.T) {
+	setTestNumVFs(t, 2)
+	svc := getTestService(cns.KubernetesCRD)
+	svc.state.PnpIDByMacAddress = map[string]string{testBackendNICMac: testPnPID}
+
+	b, _ := testPod1Info.OrchestratorContext()
+	req := cns.IPConfigsRequest{
+		PodInterfaceID:               testPod1Info.InterfaceID(),
+		InfraContainerID:             testPod1Info.InfraContainerID(),
+		OrchestratorContext:          b,
+		BackendInterfaceExist:        true,
+		BackendInterfaceMacAddresses: []string{testBackendNICMac},
+		BackendInterfaceVFs:          map[string]cns.VirtualFunction{testBackendNICMac: testVFConfig},
+	}
+	// there are no ncs to request ips from
+	_, err := svc.requestIPConfigHandlerHelper(context.Background(), req)
+	require.ErrorIs(t, err, ErrNoNCs)
+	require.Empty(t, svc.state.VFsByPodInterfaceKey)
+}
+
+func TestVFReleasedWithoutIPs(t *testing.T) {
+	setTestNumVFs(t, 2)
+	svc := getTestService(cns.KubernetesCRD)
+
+	_, err := svc.allocateVF(testPod1Info.Key(), testPnPID, testVFConfig)
+	require.NoError(t, err)
+
+	// the pod has no ips left to release
+	require.NoError(t, svc.releaseIPConfigs(testPod1Info))
+	require.Empty(t, svc.state.VFsByPodInterfaceKey)
+}
@@ -0,0 +1,6 @@
+package restserver
+
+// sriovNumVFs returns 0 as the virtual functions of backend NICs are mounted to the pod as a whole on Windows.
+var sriovNumVFs = func(string) (int, error) {
+	return 0, nil
+}
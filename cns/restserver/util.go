@@ -528,6 +528,8 @@ func (service *HTTPRestService) getAllNetworkContainerResponses(
 			MultiTenancyInfo:           savedReq.MultiTenancyInfo,
 			PrimaryInterfaceIdentifier: savedReq.PrimaryInterfaceIdentifier,
 			LocalIPConfiguration:       savedReq.LocalIPConfiguration,
+			IPv6Configuration:          savedReq.IPv6Configuration,
+			LocalIPv6Configuration:     savedReq.LocalIPv6Configuration,
 			AllowHostToNCCommunication: savedReq.AllowHostToNCCommunication,
 			AllowNCToHostCommunication: savedReq.AllowNCToHostCommunication,
 			SkipDefaultRoutes:          savedReq.SkipDefaultRoutes,
@@ -935,6 +937,8 @@ func (service *HTTPRestService) handleGetNetworkContainers(w http.ResponseWriter
 			MultiTenancyInfo:           ncDetails.CreateNetworkContainerRequest.MultiTenancyInfo,
 			PrimaryInterfaceIdentifier: ncDetails.CreateNetworkContainerRequest.PrimaryInterfaceIdentifier,
 			LocalIPConfiguration:       ncDetails.CreateNetworkContainerRequest.LocalIPConfiguration,
+			IPv6Configuration:          ncDetails.CreateNetworkContainerRequest.IPv6Configuration,
+			LocalIPv6Configuration:     ncDetails.CreateNetworkContainerRequest.LocalIPv6Configuration,
 			AllowHostToNCCommunication: ncDetails.CreateNetworkContainerRequest.AllowHostToNCCommunication,
 			AllowNCToHostCommunication: ncDetails.CreateNetworkContainerRequest.AllowNCToHostCommunication,
 			SkipDefaultRoutes:          ncDetails.CreateNetworkContainerRequest.SkipDefaultRoutes,
@@ -973,7 +977,7 @@ func (service *HTTPRestService) handlePostNetworkContainers(w http.ResponseWrite
 	}
 	if err := req.Validate(); err != nil { //nolint:govet // shadow okay
 		logger.Errorf("[Azure CNS] handlePostNetworkContainers failed with error: %s", err.Error())
-		w.WriteHeader(http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, types.InvalidRequest, fmt.Sprintf("[Azure CNS] handlePostNetworkContainers failed with error: %s", err))
 		return
 	}
 
@@ -3,6 +3,7 @@ package v2
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/logger"
@@ -13,15 +14,25 @@ import (
 
 type Server struct {
 	*restserver.HTTPRestService
+	// ShutdownTimeout bounds how long the requests in flight are waited for once the server is
+	// stopped. Zero waits for them indefinitely.
+	ShutdownTimeout time.Duration
+	// Middleware wraps the handlers of the local API if it's set, e.g. to authorize the requests.
+	Middleware func(http.Handler) http.Handler
 }
 
 func New(s *restserver.HTTPRestService) *Server {
-	return &Server{s}
+	return &Server{HTTPRestService: s}
 }
 
+// Start serves the local API on the address until the context is done, and then shuts down
+// gracefully.
 func (s Server) Start(ctx context.Context, addr string) error {
 	e := echo.New()
 	e.HideBanner = true
+	if s.Middleware != nil {
+		e.Use(echo.WrapMiddleware(s.Middleware))
+	}
 	e.POST(cns.RequestIPConfig, echo.WrapHandler(restserver.NewHandlerFuncWithHistogram(s.RequestIPConfigHandler, restserver.HTTPRequestLatency)))
 	e.POST(cns.RequestIPConfigs, echo.WrapHandler(restserver.NewHandlerFuncWithHistogram(s.RequestIPConfigsHandler, restserver.HTTPRequestLatency)))
 	e.POST(cns.ReleaseIPConfig, echo.WrapHandler(restserver.NewHandlerFuncWithHistogram(s.ReleaseIPConfigHandler, restserver.HTTPRequestLatency)))
@@ -40,14 +51,26 @@ func (s Server) Start(ctx context.Context, addr string) error {
 	e.POST(cns.V2Prefix+cns.CreateHostNCApipaEndpointPath, echo.WrapHandler(http.HandlerFunc(s.CreateHostNCApipaEndpoint)))
 	e.POST(cns.V2Prefix+cns.DeleteHostNCApipaEndpointPath, echo.WrapHandler(http.HandlerFunc(s.DeleteHostNCApipaEndpoint)))
 
-	if err := e.Start(addr); err != nil {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Start(addr)
+	}()
+
+	select {
+	case err := <-errCh:
 		logger.Errorf("failed to run echo server due to %+v", err)
 		return errors.Wrap(err, "failed to start echo server")
+	case <-ctx.Done():
 	}
 
-	// after context is done, shutdown local server
-	<-ctx.Done()
-	if err := e.Shutdown(ctx); err != nil {
+	// after context is done, stop accepting requests and wait for the ones in flight
+	shutdownCtx := context.WithoutCancel(ctx)
+	if s.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.ShutdownTimeout)
+		defer cancel()
+	}
+	if err := e.Shutdown(shutdownCtx); err != nil {
 		logger.Errorf("failed to shutdown echo server due to %+v", err)
 		return errors.Wrap(err, "failed to shutdown echo server")
 	}
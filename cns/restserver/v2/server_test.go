@@ -1,17 +1,23 @@
 package v2
 
 import (
+	"context"
 	"net"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/authz"
 	"github.com/Azure/azure-container-networking/cns/common"
 	"github.com/Azure/azure-container-networking/cns/fakes"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	acncommon "github.com/Azure/azure-container-networking/common"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 // TestStartServices will test three scenarios:
@@ -42,6 +48,56 @@ func TestStartServerWithCNSURL(t *testing.T) {
 	}
 }
 
+func TestLocalServerAuthorization(t *testing.T) {
+	logger.InitLogger("testlogs", 0, 0, "./")
+
+	// the local server has no client identities, so only the default groups are allowed
+	authorizer, err := authz.New(zap.NewNop(), authz.Policy{DefaultGroups: []authz.Group{authz.GroupDebug}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(&restserver.HTTPRestService{})
+	s.Middleware = authorizer.Middleware
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Start(ctx, addr) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	post := func(path string) int {
+		t.Helper()
+		var resp *http.Response
+		for i := 0; i < 50; i++ {
+			req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+addr+path, strings.NewReader("{}"))
+			if reqErr != nil {
+				t.Fatal(reqErr)
+			}
+			if resp, err = http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatal("failed to reach the local server:", err)
+		return 0
+	}
+	if code := post(cns.RequestIPConfigs); code != http.StatusForbidden {
+		t.Fatalf("got %d, exp %d", code, http.StatusForbidden)
+	}
+	if code := post(cns.PathDebugRestData); code == http.StatusForbidden {
+		t.Fatal("expected the debug API to be allowed")
+	}
+}
+
 // startService will return a URL that running server is using and check if sever can start
 // mock primaryVMIP as a fixed IP
 func startService(cnsPort, cnsURL string) error {
@@ -0,0 +1,67 @@
+package scheduledevents
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CordonedAnnotation marks a Node cordoned by CNS for a scheduled event, so that CNS only uncordons
+// Nodes it cordoned itself.
+const CordonedAnnotation = "cns.azure.com/cordoned-for-scheduled-event"
+
+var _ Cordoner = (*NodeCordoner)(nil)
+
+// NodeCordoner cordons a Node through the API server.
+type NodeCordoner struct {
+	cli      kubernetes.Interface
+	nodeName string
+}
+
+// NewNodeCordoner creates a NodeCordoner for the named Node.
+func NewNodeCordoner(cli kubernetes.Interface, nodeName string) *NodeCordoner {
+	return &NodeCordoner{cli: cli, nodeName: nodeName}
+}
+
+// Cordon marks the Node unschedulable, unless it's already cordoned.
+func (c *NodeCordoner) Cordon(ctx context.Context) error {
+	node, err := c.cli.CoreV1().Nodes().Get(ctx, c.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get node %s", c.nodeName)
+	}
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	return c.patch(ctx, true, map[string]any{CordonedAnnotation: "true"})
+}
+
+// Uncordon marks the Node schedulable again, if CNS cordoned it.
+func (c *NodeCordoner) Uncordon(ctx context.Context) error {
+	node, err := c.cli.CoreV1().Nodes().Get(ctx, c.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get node %s", c.nodeName)
+	}
+	if _, ok := node.Annotations[CordonedAnnotation]; !ok {
+		return nil
+	}
+	// a nil value removes the annotation
+	return c.patch(ctx, false, map[string]any{CordonedAnnotation: nil})
+}
+
+func (c *NodeCordoner) patch(ctx context.Context, unschedulable bool, annotations map[string]any) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": annotations},
+		"spec":     map[string]any{"unschedulable": unschedulable},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal node patch")
+	}
+	if _, err := c.cli.CoreV1().Nodes().Patch(ctx, c.nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to patch node %s", c.nodeName)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+// Package scheduledevents prepares CNS for planned maintenance of the host announced through IMDS
+// Scheduled Events.
+package scheduledevents
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/imds"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultPollInterval is how often IMDS is polled for scheduled events by default.
+const DefaultPollInterval = 10 * time.Second
+
+type imdsClient interface {
+	GetVMName(context.Context) (string, error)
+	GetScheduledEvents(context.Context) (*imds.ScheduledEvents, error)
+	AckScheduledEvents(context.Context, ...string) error
+}
+
+// Flusher is a state store which can be committed to disk.
+type Flusher interface {
+	Flush() error
+}
+
+// ScaleDownPauser is an IPAM pool monitor which can stop releasing IPs during maintenance.
+type ScaleDownPauser interface {
+	PauseScaleDown()
+	ResumeScaleDown()
+}
+
+// Cordoner marks the Node unschedulable during maintenance.
+type Cordoner interface {
+	Cordon(context.Context) error
+	Uncordon(context.Context) error
+}
+
+// Options configures the Watcher.
+type Options struct {
+	// PollInterval is how often IMDS is polled for scheduled events.
+	PollInterval time.Duration
+	// CordonNode cordons the Node ahead of events which take the VM down.
+	CordonNode bool
+	// AcknowledgeEvents lets events start early, once CNS is prepared for them.
+	AcknowledgeEvents bool
+}
+
+// Watcher polls IMDS for scheduled events affecting the VM. Ahead of disruptive events, it pauses
+// IPAM pool scale down, flushes the state stores, optionally cordons the Node, and optionally
+// acknowledges the events. Once the events have passed, it resumes scale down and uncordons the Node.
+type Watcher struct {
+	z      *zap.Logger
+	imds   imdsClient
+	opts   Options
+	stores []Flusher
+
+	mu       sync.Mutex
+	pauser   ScaleDownPauser
+	cordoner Cordoner
+
+	vmName string
+	// prepared and acked hold the IDs of the events CNS has prepared for and acknowledged.
+	prepared map[string]struct{}
+	acked    map[string]struct{}
+	// settled is set once CNS has recovered from the last events, and is unset while preparing.
+	settled bool
+}
+
+// New creates a Watcher which flushes the stores ahead of maintenance.
+func New(z *zap.Logger, cli imdsClient, opts Options, stores ...Flusher) *Watcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	return &Watcher{
+		z:        z.With(zap.String("component", "scheduled-events-watcher")),
+		imds:     cli,
+		opts:     opts,
+		stores:   stores,
+		prepared: map[string]struct{}{},
+		acked:    map[string]struct{}{},
+	}
+}
+
+// SetScaleDownPauser sets the IPAM pool monitor to pause during maintenance.
+func (w *Watcher) SetScaleDownPauser(p ScaleDownPauser) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pauser = p
+}
+
+// SetCordoner sets the Cordoner used when Options.CordonNode is set.
+func (w *Watcher) SetCordoner(c Cordoner) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cordoner = c
+}
+
+// Run polls for scheduled events until the context is canceled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.poll(ctx); err != nil {
+			w.z.Error("failed to handle scheduled events", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// disruptive reports whether the event interrupts the VM, so that CNS should prepare for it.
+func disruptive(t imds.EventType) bool {
+	switch t {
+	case imds.EventTypeFreeze, imds.EventTypeReboot, imds.EventTypeRedeploy, imds.EventTypePreempt, imds.EventTypeTerminate:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) error {
+	if w.vmName == "" {
+		vmName, err := w.imds.GetVMName(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get VM name")
+		}
+		w.vmName = vmName
+	}
+
+	doc, err := w.imds.GetScheduledEvents(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get scheduled events")
+	}
+
+	var events []imds.ScheduledEvent
+	for i := range doc.Events {
+		if disruptive(doc.Events[i].EventType) && doc.Events[i].Affects(w.vmName) {
+			events = append(events, doc.Events[i])
+		}
+	}
+
+	w.mu.Lock()
+	pauser, cordoner := w.pauser, w.cordoner
+	w.mu.Unlock()
+
+	if len(events) == 0 {
+		return w.recover(ctx, pauser, cordoner)
+	}
+	w.settled = false
+	return w.prepare(ctx, events, pauser, cordoner)
+}
+
+// prepare readies CNS for the events, and acknowledges the ones it's prepared for.
+func (w *Watcher) prepare(ctx context.Context, events []imds.ScheduledEvent, pauser ScaleDownPauser, cordoner Cordoner) error {
+	if pauser != nil {
+		pauser.PauseScaleDown()
+	}
+
+	for i := range events {
+		e := &events[i]
+		if _, ok := w.prepared[e.EventID]; ok {
+			continue
+		}
+		w.z.Info("preparing for scheduled event", zap.String("id", e.EventID), zap.String("type", string(e.EventType)),
+			zap.String("status", string(e.EventStatus)), zap.String("notBefore", e.NotBefore))
+		for _, s := range w.stores {
+			if err := s.Flush(); err != nil {
+				return errors.Wrap(err, "failed to flush state store")
+			}
+		}
+		// a Freeze keeps the VM's memory and only pauses it for a few seconds, so Pods stay put
+		if w.opts.CordonNode && cordoner != nil && e.EventType != imds.EventTypeFreeze {
+			if err := cordoner.Cordon(ctx); err != nil {
+				return errors.Wrap(err, "failed to cordon node")
+			}
+		}
+		w.prepared[e.EventID] = struct{}{}
+	}
+
+	if !w.opts.AcknowledgeEvents {
+		return nil
+	}
+	var ids []string
+	for i := range events {
+		if _, ok := w.acked[events[i].EventID]; ok || events[i].EventStatus != imds.EventStatusScheduled {
+			continue
+		}
+		ids = append(ids, events[i].EventID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := w.imds.AckScheduledEvents(ctx, ids...); err != nil {
+		return errors.Wrap(err, "failed to acknowledge scheduled events")
+	}
+	for _, id := range ids {
+		w.acked[id] = struct{}{}
+	}
+	w.z.Info("acknowledged scheduled events", zap.Strings("ids", ids))
+	return nil
+}
+
+// recover undoes the preparations once there are no more events. It runs after a restart too, to
+// uncordon a Node cordoned before CNS restarted.
+func (w *Watcher) recover(ctx context.Context, pauser ScaleDownPauser, cordoner Cordoner) error {
+	if w.settled {
+		return nil
+	}
+	if pauser != nil {
+		pauser.ResumeScaleDown()
+	}
+	if cordoner != nil {
+		if err := cordoner.Uncordon(ctx); err != nil {
+			return errors.Wrap(err, "failed to uncordon node")
+		}
+	}
+	if len(w.prepared) > 0 {
+		w.z.Info("scheduled events have passed")
+	}
+	w.prepared = map[string]struct{}{}
+	w.acked = map[string]struct{}{}
+	// keep checking until the Cordoner is set, in case the Node was left cordoned
+	w.settled = cordoner != nil || !w.opts.CordonNode
+	return nil
+}
@@ -0,0 +1,188 @@
+package scheduledevents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/imds"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const vmName = "aks-nodepool1-25781205-vmss_0"
+
+type imdsClientFake struct {
+	events []imds.ScheduledEvent
+	acked  []string
+}
+
+func (f *imdsClientFake) GetVMName(context.Context) (string, error) {
+	return vmName, nil
+}
+
+func (f *imdsClientFake) GetScheduledEvents(context.Context) (*imds.ScheduledEvents, error) {
+	return &imds.ScheduledEvents{Events: f.events}, nil
+}
+
+func (f *imdsClientFake) AckScheduledEvents(_ context.Context, ids ...string) error {
+	f.acked = append(f.acked, ids...)
+	return nil
+}
+
+type storeFake struct {
+	flushes int
+	err     error
+}
+
+func (s *storeFake) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.flushes++
+	return nil
+}
+
+type pauserFake struct {
+	paused bool
+}
+
+func (p *pauserFake) PauseScaleDown()  { p.paused = true }
+func (p *pauserFake) ResumeScaleDown() { p.paused = false }
+
+type cordonerFake struct {
+	cordoned bool
+}
+
+func (c *cordonerFake) Cordon(context.Context) error {
+	c.cordoned = true
+	return nil
+}
+
+func (c *cordonerFake) Uncordon(context.Context) error {
+	c.cordoned = false
+	return nil
+}
+
+func event(id string, t imds.EventType, status imds.EventStatus, resources ...string) imds.ScheduledEvent {
+	return imds.ScheduledEvent{
+		EventID:      id,
+		EventType:    t,
+		EventStatus:  status,
+		ResourceType: "VirtualMachine",
+		Resources:    resources,
+	}
+}
+
+func TestWatcherPreparesForEvents(t *testing.T) {
+	cli := &imdsClientFake{}
+	store := &storeFake{}
+	pauser := &pauserFake{}
+	cordoner := &cordonerFake{}
+	w := New(zap.NewNop(), cli, Options{CordonNode: true, AcknowledgeEvents: true}, store)
+	w.SetScaleDownPauser(pauser)
+	w.SetCordoner(cordoner)
+	ctx := context.Background()
+
+	// events for other VMs, and non-disruptive events, are ignored
+	cli.events = []imds.ScheduledEvent{
+		event("other-vm", imds.EventTypeReboot, imds.EventStatusScheduled, "aks-nodepool1-25781205-vmss_1"),
+		event("unknown", "Unknown", imds.EventStatusScheduled, vmName),
+	}
+	require.NoError(t, w.poll(ctx))
+	assert.Zero(t, store.flushes)
+	assert.False(t, pauser.paused)
+	assert.Empty(t, cli.acked)
+
+	// a Freeze pauses scale down and flushes the stores, but doesn't cordon the Node
+	cli.events = append(cli.events, event("freeze", imds.EventTypeFreeze, imds.EventStatusScheduled, vmName))
+	require.NoError(t, w.poll(ctx))
+	assert.Equal(t, 1, store.flushes)
+	assert.True(t, pauser.paused)
+	assert.False(t, cordoner.cordoned)
+	assert.Equal(t, []string{"freeze"}, cli.acked)
+
+	// a Reboot cordons the Node, and events are only prepared for and acknowledged once
+	cli.events = append(cli.events, event("reboot", imds.EventTypeReboot, imds.EventStatusScheduled, vmName))
+	require.NoError(t, w.poll(ctx))
+	require.NoError(t, w.poll(ctx))
+	assert.Equal(t, 2, store.flushes)
+	assert.True(t, cordoner.cordoned)
+	assert.Equal(t, []string{"freeze", "reboot"}, cli.acked)
+
+	// started events aren't acknowledged
+	cli.events = append(cli.events, event("started", imds.EventTypeRedeploy, imds.EventStatusStarted, vmName))
+	require.NoError(t, w.poll(ctx))
+	assert.Equal(t, []string{"freeze", "reboot"}, cli.acked)
+
+	// once the events have passed, scale down resumes and the Node is uncordoned
+	cli.events = nil
+	require.NoError(t, w.poll(ctx))
+	assert.False(t, pauser.paused)
+	assert.False(t, cordoner.cordoned)
+}
+
+func TestWatcherDoesNotAcknowledgeUnpreparedEvents(t *testing.T) {
+	cli := &imdsClientFake{
+		events: []imds.ScheduledEvent{event("reboot", imds.EventTypeReboot, imds.EventStatusScheduled, vmName)},
+	}
+	store := &storeFake{err: errors.New("disk full")}
+	w := New(zap.NewNop(), cli, Options{AcknowledgeEvents: true}, store)
+
+	require.Error(t, w.poll(context.Background()))
+	assert.Empty(t, cli.acked)
+
+	// the event is prepared for and acknowledged once the store can be flushed
+	store.err = nil
+	require.NoError(t, w.poll(context.Background()))
+	assert.Equal(t, 1, store.flushes)
+	assert.Equal(t, []string{"reboot"}, cli.acked)
+}
+
+func TestWatcherWithoutAcknowledgement(t *testing.T) {
+	cli := &imdsClientFake{
+		events: []imds.ScheduledEvent{event("reboot", imds.EventTypeReboot, imds.EventStatusScheduled, vmName)},
+	}
+	w := New(zap.NewNop(), cli, Options{})
+
+	require.NoError(t, w.poll(context.Background()))
+	assert.Empty(t, cli.acked)
+}
+
+func TestNodeCordoner(t *testing.T) {
+	ctx := context.Background()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+	cli := fake.NewSimpleClientset(node)
+	c := NewNodeCordoner(cli, "node")
+
+	require.NoError(t, c.Cordon(ctx))
+	got, err := cli.CoreV1().Nodes().Get(ctx, "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, got.Spec.Unschedulable)
+	assert.Contains(t, got.Annotations, CordonedAnnotation)
+
+	require.NoError(t, c.Uncordon(ctx))
+	got, err = cli.CoreV1().Nodes().Get(ctx, "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, got.Spec.Unschedulable)
+	assert.NotContains(t, got.Annotations, CordonedAnnotation)
+}
+
+func TestNodeCordonerLeavesCordonedNodes(t *testing.T) {
+	ctx := context.Background()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}, Spec: corev1.NodeSpec{Unschedulable: true}}
+	cli := fake.NewSimpleClientset(node)
+	c := NewNodeCordoner(cli, "node")
+
+	// a Node cordoned by someone else stays cordoned
+	require.NoError(t, c.Cordon(ctx))
+	require.NoError(t, c.Uncordon(ctx))
+	got, err := cli.CoreV1().Nodes().Get(ctx, "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, got.Spec.Unschedulable)
+	assert.NotContains(t, got.Annotations, CordonedAnnotation)
+}
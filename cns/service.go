@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/common"
 	"github.com/Azure/azure-container-networking/cns/logger"
@@ -21,6 +22,8 @@ import (
 	"github.com/Azure/azure-container-networking/store"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 const (
@@ -35,6 +38,8 @@ type Service struct {
 	*common.Service
 	EndpointType string
 	Listener     *acn.Listener
+	// stopTLSReload stops reloading the TLS certificates of the Listener.
+	stopTLSReload context.CancelFunc
 }
 
 // NewService creates a new Service object.
@@ -111,8 +116,11 @@ func (service *Service) AddListener(config *common.ServiceConfig) error {
 		tlsAddress := net.JoinHostPort(hostParts[0], config.TLSSettings.TLSPort)
 
 		// Start the listener and HTTP and HTTPS server.
-		tlsConfig, err := getTLSConfig(config.TLSSettings, config.ErrChan) //nolint
+		ctx, cancel := context.WithCancel(context.Background())
+		service.stopTLSReload = cancel
+		tlsConfig, err := getTLSConfig(ctx, config.TLSSettings, config.ErrChan) //nolint
 		if err != nil {
+			cancel()
 			logger.Printf("Failed to compose Tls Configuration with error: %+v", err)
 			return errors.Wrap(err, "could not get tls config")
 		}
@@ -144,18 +152,43 @@ func (service *Service) Initialize(config *common.ServiceConfig) error {
 	return nil
 }
 
-func getTLSConfig(tlsSettings localtls.TlsSettings, errChan chan<- error) (*tls.Config, error) {
-	if tlsSettings.TLSCertificatePath != "" {
-		return getTLSConfigFromFile(tlsSettings)
-	}
-
-	if tlsSettings.KeyVaultURL != "" {
-		return getTLSConfigFromKeyVault(tlsSettings, errChan)
+func getTLSConfig(ctx context.Context, tlsSettings localtls.TlsSettings, errChan chan<- error) (*tls.Config, error) {
+	switch certificateSource(tlsSettings) {
+	case localtls.CertificateSourceFile:
+		return getTLSConfigFromFile(ctx, tlsSettings)
+	case localtls.CertificateSourceKeyVault:
+		return getTLSConfigFromKeyVault(ctx, tlsSettings, errChan)
+	case localtls.CertificateSourceKubernetesSecret:
+		return getTLSConfigFromKubernetesSecret(ctx, tlsSettings, errChan)
+	case localtls.CertificateSourceSecretStoreCSI:
+		provider := keyvault.NewDirectoryProvider(tlsSettings.SecretStoreDirectory)
+		return getTLSConfigFromProvider(ctx, tlsSettings, provider, tlsSettings.SecretStoreObjectName, tlsSettings.CertificateReloadInterval, errChan)
+	case localtls.CertificateSourceSelfSigned:
+		dnsNames := tlsSettings.SelfSignedDNSNames
+		if len(dnsNames) == 0 && tlsSettings.TLSSubjectName != "" {
+			dnsNames = []string{tlsSettings.TLSSubjectName}
+		}
+		provider := keyvault.NewSelfSignedProvider(dnsNames, tlsSettings.SelfSignedValidity)
+		return getTLSConfigFromProvider(ctx, tlsSettings, provider, tlsSettings.TLSSubjectName, tlsSettings.CertificateReloadInterval, errChan)
 	}
 
 	return nil, errors.Errorf("invalid tls settings: %+v", tlsSettings)
 }
 
+// certificateSource returns the source of the certificate, which is inferred from the file and
+// KeyVault settings if it's unset.
+func certificateSource(tlsSettings localtls.TlsSettings) string {
+	switch {
+	case tlsSettings.CertificateSource != "":
+		return tlsSettings.CertificateSource
+	case tlsSettings.TLSCertificatePath != "":
+		return localtls.CertificateSourceFile
+	case tlsSettings.KeyVaultURL != "":
+		return localtls.CertificateSourceKeyVault
+	}
+	return ""
+}
+
 // verifyPeerCertificate verifies the client certificate's subject name matches the expected subject name.
 func verifyPeerCertificate(verifiedChains [][]*x509.Certificate, clientSubjectName string) error {
 	// no client subject name provided, skip verification
@@ -204,31 +237,14 @@ func maskHalf(s string) string {
 	return s[:half] + strings.Repeat("*", n-half)
 }
 
-func getTLSConfigFromFile(tlsSettings localtls.TlsSettings) (*tls.Config, error) {
-	tlsCertRetriever, err := localtls.GetTlsCertificateRetriever(tlsSettings)
+// getTLSConfigFromFile serves the certificate read from file, and reloads it and the client CAs
+// every reload interval until the context is canceled.
+func getTLSConfigFromFile(ctx context.Context, tlsSettings localtls.TlsSettings) (*tls.Config, error) {
+	reloader, err := newCertReloader(tlsSettings)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get certificate retriever")
-	}
-
-	leafCertificate, err := tlsCertRetriever.GetCertificate()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get certificate")
-	}
-
-	if leafCertificate == nil {
-		return nil, errors.New("certificate retrieval returned empty")
-	}
-
-	privateKey, err := tlsCertRetriever.GetPrivateKey()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get certificate private key")
+		return nil, err
 	}
 
-	tlsCert := tls.Certificate{
-		Certificate: [][]byte{leafCertificate.Raw},
-		PrivateKey:  privateKey,
-		Leaf:        leafCertificate,
-	}
 	minTLSVersionNumber, err := parseTLSVersionName(tlsSettings.MinTLSVersion)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing MinTLSVersion from config")
@@ -237,29 +253,40 @@ func getTLSConfigFromFile(tlsSettings localtls.TlsSettings) (*tls.Config, error)
 	tlsConfig := &tls.Config{
 		MaxVersion: tls.VersionTLS13,
 		MinVersion: minTLSVersionNumber,
-		Certificates: []tls.Certificate{
-			tlsCert,
+		GetCertificate: func(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return reloader.certificate(), nil
+		},
+		GetClientCertificate: func(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return reloader.certificate(), nil
 		},
 	}
 
 	if tlsSettings.UseMTLS {
-		rootCAs, err := mtlsRootCAsFromCertificate(&tlsCert)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to get root CAs for configuring mTLS")
-		}
+		rootCAs := reloader.getClientCAs()
 		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 		tlsConfig.ClientCAs = rootCAs
 		tlsConfig.RootCAs = rootCAs
 		tlsConfig.VerifyPeerCertificate = func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
 			return verifyPeerCertificate(verifiedChains, tlsSettings.MtlsClientCertSubjectName)
 		}
+		// verify client certificates against the current client CAs
+		base := tlsConfig.Clone()
+		tlsConfig.GetConfigForClient = func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+			c := base.Clone()
+			c.ClientCAs = reloader.getClientCAs()
+			return c, nil
+		}
+	}
+
+	if tlsSettings.CertificateReloadInterval > 0 {
+		go reloader.run(ctx, tlsSettings.CertificateReloadInterval)
 	}
 	logger.Debugf("TLS configured successfully from file: %+v", tlsSettings)
 
 	return tlsConfig, nil
 }
 
-func getTLSConfigFromKeyVault(tlsSettings localtls.TlsSettings, errChan chan<- error) (*tls.Config, error) {
+func getTLSConfigFromKeyVault(ctx context.Context, tlsSettings localtls.TlsSettings, errChan chan<- error) (*tls.Config, error) {
 	credOpts := azidentity.ManagedIdentityCredentialOptions{ID: azidentity.ResourceID(tlsSettings.MSIResourceID)}
 	cred, err := azidentity.NewManagedIdentityCredential(&credOpts)
 	if err != nil {
@@ -271,16 +298,55 @@ func getTLSConfigFromKeyVault(tlsSettings localtls.TlsSettings, errChan chan<- e
 		return nil, errors.Wrap(err, "could not create new keyvault shim")
 	}
 
-	ctx := context.TODO()
+	return getTLSConfigFromProvider(ctx, tlsSettings, kvs, tlsSettings.KeyVaultCertificateName, tlsSettings.KeyVaultCertificateRefreshInterval, errChan)
+}
+
+func getTLSConfigFromKubernetesSecret(ctx context.Context, tlsSettings localtls.TlsSettings, errChan chan<- error) (*tls.Config, error) {
+	if tlsSettings.KubernetesSecretName == "" {
+		return nil, errors.New("no kubernetes secret name provided")
+	}
 
-	cr, err := keyvault.NewCertRefresher(ctx, kvs, logger.Log, tlsSettings.KeyVaultCertificateName)
+	kubeConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get kubeconfig")
+	}
+
+	clientset, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create kubernetes clientset")
+	}
+
+	provider := keyvault.NewSecretProvider(clientset.CoreV1().Secrets(tlsSettings.KubernetesSecretNamespace))
+	return getTLSConfigFromProvider(ctx, tlsSettings, provider, tlsSettings.KubernetesSecretName, tlsSettings.CertificateReloadInterval, errChan)
+}
+
+// getTLSConfigFromProvider serves the certificate fetched from the provider, and refreshes it every
+// refresh interval until the context is canceled. Failing to refresh it before it expires is sent to
+// the error channel.
+func getTLSConfigFromProvider(
+	ctx context.Context,
+	tlsSettings localtls.TlsSettings,
+	provider keyvault.CertProvider,
+	certName string,
+	refreshInterval time.Duration,
+	errChan chan<- error,
+) (*tls.Config, error) {
+	cr, err := keyvault.NewCertRefresher(ctx, provider, logger.Log, certName)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create new cert refresher")
 	}
 
-	go func() {
-		errChan <- cr.Refresh(ctx, tlsSettings.KeyVaultCertificateRefreshInterval)
-	}()
+	cr.OnRefresh(func(cert *tls.Certificate) {
+		tlsCertificateExpiry.WithLabelValues(serverCertificate).Set(float64(cert.Leaf.NotAfter.Unix()))
+	})
+
+	if refreshInterval > 0 {
+		go func() {
+			if err := cr.Refresh(ctx, refreshInterval); !errors.Is(err, context.Canceled) {
+				errChan <- err
+			}
+		}()
+	}
 
 	minTLSVersionNumber, err := parseTLSVersionName(tlsSettings.MinTLSVersion)
 	if err != nil {
@@ -309,7 +375,7 @@ func getTLSConfigFromKeyVault(tlsSettings localtls.TlsSettings, errChan chan<- e
 		}
 	}
 
-	logger.Debugf("TLS configured successfully from KV: %+v", tlsSettings)
+	logger.Debugf("TLS configured successfully from %s: %+v", certificateSource(tlsSettings), tlsSettings)
 
 	return &tlsConfig, nil
 }
@@ -355,6 +421,13 @@ func (service *Service) StartListener(config *common.ServiceConfig) error {
 		if err := service.Listener.Start(config.ErrChan); err != nil {
 			return err
 		}
+
+		// serve node-local clients on the unix socket, where their peer credentials are known
+		if config.UnixSocketPath != "" {
+			if err := service.Listener.StartUnix(config.ErrChan, config.UnixSocketPath); err != nil {
+				return errors.Wrap(err, "could not start unix socket listener")
+			}
+		}
 	} else {
 		return fmt.Errorf("Failed to start a listener, it is not initialized, config %+v", config)
 	}
@@ -364,6 +437,9 @@ func (service *Service) StartListener(config *common.ServiceConfig) error {
 
 // Uninitialize cleans up the plugin.
 func (service *Service) Uninitialize() {
+	if service.stopTLSReload != nil {
+		service.stopTLSReload()
+	}
 	service.Listener.Stop()
 	service.Service.Uninitialize()
 }
@@ -20,6 +20,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/authz"
 	cnsclient "github.com/Azure/azure-container-networking/cns/client"
 	cnscli "github.com/Azure/azure-container-networking/cns/cmd/cli"
 	"github.com/Azure/azure-container-networking/cns/cniconflist"
@@ -37,6 +38,7 @@ import (
 	ipampoolv2 "github.com/Azure/azure-container-networking/cns/ipampool/v2"
 	cssctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/clustersubnetstate"
 	mtpncctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/multitenantpodnetworkconfig"
+	namespacectrl "github.com/Azure/azure-container-networking/cns/kubecontroller/namespace"
 	nncctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/nodenetworkconfig"
 	podctrl "github.com/Azure/azure-container-networking/cns/kubecontroller/pod"
 	"github.com/Azure/azure-container-networking/cns/logger"
@@ -47,6 +49,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/multitenantcontroller/multitenantoperator"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	restserverv2 "github.com/Azure/azure-container-networking/cns/restserver/v2"
+	"github.com/Azure/azure-container-networking/cns/scheduledevents"
 	cnipodprovider "github.com/Azure/azure-container-networking/cns/stateprovider/cni"
 	cnspodprovider "github.com/Azure/azure-container-networking/cns/stateprovider/cns"
 	cnstypes "github.com/Azure/azure-container-networking/cns/types"
@@ -62,6 +65,7 @@ import (
 	acnfs "github.com/Azure/azure-container-networking/internal/fs"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/nmagent"
+	"github.com/Azure/azure-container-networking/nmagent/resilience"
 	"github.com/Azure/azure-container-networking/platform"
 	"github.com/Azure/azure-container-networking/processlock"
 	localtls "github.com/Azure/azure-container-networking/server/tls"
@@ -654,16 +658,9 @@ func main() {
 	}
 
 	// start the healthz/readyz/metrics server
-	readyCh := make(chan any)
+	readiness := &healthserver.Readiness{}
 	readyChecker := healthz.CheckHandler{
-		Checker: healthz.Checker(func(*http.Request) error {
-			select {
-			default:
-				return errors.New("not ready")
-			case <-readyCh:
-			}
-			return nil
-		}),
+		Checker: readiness.Check,
 	}
 
 	healthzHandler, err := healthserver.NewHealthzHandlerWithChecks(&healthserver.Config{PingAPIServer: cnsconfig.EnableAPIServerHealthPing})
@@ -679,6 +676,7 @@ func main() {
 		return
 	}
 
+	nmaConfig.Resilience = nmagentResilienceConfig(cnsconfig.NMAgentResilienceSettings)
 	nmaClient, err := nmagent.NewClient(nmaConfig)
 	if err != nil {
 		logger.Errorf("[Azure CNS] Failed to start nmagent client due to error: %v", err)
@@ -757,18 +755,20 @@ func main() {
 		}
 	}
 
+	// the wireserver requests share a single rate limit and set of circuit breakers
+	wsHTTPClient := &http.Client{Transport: resilience.NewTransport(http.DefaultTransport, nmaConfig.Resilience)}
 	wsProxy := wireserver.Proxy{
 		Host:       cnsconfig.WireserverIP,
-		HTTPClient: &http.Client{},
+		HTTPClient: wsHTTPClient,
 	}
 
 	wsclient := &wireserver.Client{
 		HostPort:   cnsconfig.WireserverIP,
-		HTTPClient: &http.Client{},
+		HTTPClient: wsHTTPClient,
 		Logger:     logger.Log,
 	}
 
-	imdsClient := imds.NewClient()
+	imdsClient := imds.NewClient(imdsClientOptions(cnsconfig)...)
 	httpRemoteRestService, err := restserver.NewHTTPRestService(&config, wsclient, &wsProxy, &restserver.IPtablesProvider{}, nmaClient,
 		endpointStateStore, conflistGenerator, homeAzMonitor, imdsClient)
 	if err != nil {
@@ -776,6 +776,21 @@ func main() {
 		return
 	}
 
+	// prepare for planned maintenance of the host so that it doesn't interrupt writes to the state stores
+	var scheduledEventsWatcher *scheduledevents.Watcher
+	if cnsconfig.EnableScheduledEvents {
+		stores := []scheduledevents.Flusher{config.Store}
+		if endpointStateStore != nil {
+			stores = append(stores, endpointStateStore)
+		}
+		scheduledEventsWatcher = scheduledevents.New(z, imdsClient, scheduledevents.Options{
+			PollInterval:      time.Duration(cnsconfig.ScheduledEventsSettings.PollIntervalSecs) * time.Second,
+			CordonNode:        cnsconfig.ScheduledEventsSettings.CordonNode,
+			AcknowledgeEvents: cnsconfig.ScheduledEventsSettings.AcknowledgeEvents,
+		}, stores...)
+		go scheduledEventsWatcher.Run(rootCtx)
+	}
+
 	// Set CNS options.
 	httpRemoteRestService.SetOption(acn.OptCnsURL, cnsURL)
 	httpRemoteRestService.SetOption(acn.OptCnsPort, cnsPort)
@@ -798,6 +813,7 @@ func main() {
 	}
 
 	logger.Printf("[Azure CNS] Initialize HTTPRemoteRestService")
+	var authorizer *authz.Authorizer
 	if httpRemoteRestService != nil {
 		if cnsconfig.UseHTTPS {
 			config.TLSSettings = localtls.TlsSettings{
@@ -811,14 +827,35 @@ func main() {
 				UseMTLS:                            cnsconfig.UseMTLS,
 				MinTLSVersion:                      cnsconfig.MinTLSVersion,
 				MtlsClientCertSubjectName:          cnsconfig.MtlsClientCertSubjectName,
+				MtlsClientCAPath:                   cnsconfig.MtlsClientCAPath,
+				CertificateReloadInterval:          time.Duration(cnsconfig.TLSCertReloadIntervalSecs) * time.Second,
+				CertificateSource:                  cnsconfig.TLSCertificateSource.Source,
+				KubernetesSecretNamespace:          cnsconfig.TLSCertificateSource.KubernetesSecretNamespace,
+				KubernetesSecretName:               cnsconfig.TLSCertificateSource.KubernetesSecretName,
+				SecretStoreDirectory:               cnsconfig.TLSCertificateSource.SecretStoreDirectory,
+				SecretStoreObjectName:              cnsconfig.TLSCertificateSource.SecretStoreObjectName,
+				SelfSignedDNSNames:                 cnsconfig.TLSCertificateSource.SelfSignedDNSNames,
+				SelfSignedValidity:                 time.Duration(cnsconfig.TLSCertificateSource.SelfSignedValidityHrs) * time.Hour,
 			}
 		}
 
+		config.UnixSocketPath = cnsconfig.UnixSocketPath
 		err = httpRemoteRestService.Init(&config)
 		if err != nil {
 			logger.Errorf("Failed to init HTTPService, err:%v.\n", err)
 			return
 		}
+
+		// authorize the API groups of the clients before they reach the handlers
+		if cnsconfig.EnableAuthorization {
+			var authzErr error
+			authorizer, authzErr = authz.New(z, cnsconfig.AuthorizationPolicy)
+			if authzErr != nil {
+				logger.Errorf("Failed to create authorizer, err:%v.\n", authzErr)
+				return
+			}
+			httpRemoteRestService.Listener.Use(authorizer.Middleware)
+		}
 	}
 
 	// Setting the remote ARP MAC address to 12-34-56-78-9a-bc on windows for external traffic if HNS is enabled
@@ -871,7 +908,7 @@ func main() {
 
 		logger.Printf("Set GlobalPodInfoScheme %v (InitializeFromCNI=%t)", cns.GlobalPodInfoScheme, cnsconfig.InitializeFromCNI)
 
-		err = InitializeCRDState(rootCtx, z, httpRemoteRestService, cnsconfig)
+		err = InitializeCRDState(rootCtx, z, httpRemoteRestService, cnsconfig, scheduledEventsWatcher)
 		if err != nil {
 			logger.Errorf("Failed to start CRD Controller, err:%v.\n", err)
 			return
@@ -916,6 +953,8 @@ func main() {
 			logger.Errorf("[Azure CNS] Failed to initialize node subnet: %v", err)
 			return
 		}
+		// CNS is not ready to serve IPAM requests while its secondary IPs are stale
+		readiness.AddCheck("nodesubnet", httpRemoteRestService.NodeSubnetHealthCheck())
 	}
 
 	// Initialize multi-tenant controller if the CNS is running in MultiTenantCRD mode.
@@ -1015,6 +1054,10 @@ func main() {
 
 	}
 
+	// the local server is stopped after the drain period rather than with the root context
+	localServerCtx, stopLocalServer := context.WithCancel(context.Background())
+	defer stopLocalServer()
+	localServerDone := make(chan struct{})
 	// if user does not provide cns url by -c option, then start http local server
 	// TODO: we will deprecated -c option in next phase and start local server in any case
 	if config.Server.EnableLocalServer {
@@ -1029,8 +1072,13 @@ func main() {
 
 		httpLocalRestService := restserverv2.New(httpRemoteRestService)
 		if httpLocalRestService != nil {
+			httpLocalRestService.ShutdownTimeout = time.Duration(cnsconfig.ShutdownSettings.TimeoutSecs) * time.Second
+			if authorizer != nil {
+				httpLocalRestService.Middleware = authorizer.Middleware
+			}
 			go func() {
-				err = httpLocalRestService.Start(rootCtx, localServerURL)
+				defer close(localServerDone)
+				err = httpLocalRestService.Start(localServerCtx, localServerURL)
 				if err != nil {
 					logger.Errorf("Failed to start local echo server, err:%v.\n", err)
 					return
@@ -1119,10 +1167,18 @@ func main() {
 	}
 
 	// mark the service as "ready"
-	close(readyCh)
+	readiness.SetReady(true)
 	// block until process exiting
 	<-rootCtx.Done()
 
+	// fail readiness and keep serving for the drain period, so that clients stop sending requests
+	// before the listener stops accepting them
+	readiness.SetReady(false)
+	if drainPeriod := time.Duration(cnsconfig.ShutdownSettings.DrainPeriodSecs) * time.Second; drainPeriod > 0 {
+		logger.Printf("draining cns service for %v", drainPeriod)
+		time.Sleep(drainPeriod)
+	}
+
 	if len(strings.TrimSpace(createDefaultExtNetworkType)) > 0 {
 		if err := hnsclient.DeleteDefaultExtNetwork(); err == nil {
 			logger.Printf("[Azure CNS] Successfully deleted default ext network")
@@ -1133,8 +1189,16 @@ func main() {
 
 	logger.Printf("stop cns service")
 	// Cleanup.
+	stopLocalServer()
+	if config.Server.EnableLocalServer {
+		<-localServerDone
+	}
 	if httpRemoteRestService != nil {
-		httpRemoteRestService.Stop()
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Duration(cnsconfig.ShutdownSettings.TimeoutSecs)*time.Second)
+		if err = httpRemoteRestService.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("failed to shut down cns service gracefully: %v", err)
+		}
+		cancelShutdown()
 	}
 
 	if err = lockclient.Unlock(); err != nil {
@@ -1376,7 +1440,7 @@ func reconcileInitialCNSState(ctx context.Context, cli nodeNetworkConfigGetter,
 // InitializeCRDState builds and starts the CRD controllers.
 //
 //nolint:gocyclo // legacy
-func InitializeCRDState(ctx context.Context, z *zap.Logger, httpRestService cns.HTTPService, cnsconfig *configuration.CNSConfig) error {
+func InitializeCRDState(ctx context.Context, z *zap.Logger, httpRestService cns.HTTPService, cnsconfig *configuration.CNSConfig, scheduledEventsWatcher *scheduledevents.Watcher) error { //nolint:lll // it's fine
 	// convert interface type to implementation type
 	httpRestServiceImplementation, ok := httpRestService.(*restserver.HTTPRestService)
 	if !ok {
@@ -1419,7 +1483,7 @@ func InitializeCRDState(ctx context.Context, z *zap.Logger, httpRestService cns.
 	if _, ok := node.Labels[configuration.LabelNodeSwiftV2]; ok {
 		cnsconfig.EnableSwiftV2 = true
 		cnsconfig.WatchPods = true
-		if nodeInfoErr := createOrUpdateNodeInfoCRD(ctx, kubeConfig, node); nodeInfoErr != nil {
+		if nodeInfoErr := createOrUpdateNodeInfoCRD(ctx, kubeConfig, node, imds.NewClient(imdsClientOptions(cnsconfig)...)); nodeInfoErr != nil {
 			return errors.Wrap(nodeInfoErr, "error creating or updating nodeinfo crd")
 		}
 	}
@@ -1468,6 +1532,12 @@ func InitializeCRDState(ctx context.Context, z *zap.Logger, httpRestService cns.
 		return errors.Wrap(err, "failed to initialize CNS state")
 	}, retry.Context(ctx), retry.Delay(initCNSInitalDelay), retry.MaxDelay(time.Minute), retry.UntilSucceeded())
 	logger.Printf("reconciled initial CNS state after %d attempts", attempt)
+	if cnsconfig.EnableEgressIP {
+		// the ip state was rebuilt from the NNC, reserve the persisted egress ips again before any pod can be assigned them.
+		if err := httpRestServiceImplementation.ReconcileEgressIPs(); err != nil { //nolint:govet // intentional shadow
+			logger.Errorf("failed to reconcile egress ips: %v", err)
+		}
+	}
 	hasNNCInitialized.Set(1)
 	scheme := kuberuntime.NewScheme()
 	if err := corev1.AddToScheme(scheme); err != nil { //nolint:govet // intentional shadow
@@ -1553,6 +1623,16 @@ func InitializeCRDState(ctx context.Context, z *zap.Logger, httpRestService cns.
 		poolMonitor = ipampool.NewMonitor(httpRestServiceImplementation, cachedscopedcli, cssCh, &poolOpts)
 	}
 
+	// pause the pool scale down and cordon the Node ahead of maintenance
+	if scheduledEventsWatcher != nil {
+		if pauser, ok := poolMonitor.(scheduledevents.ScaleDownPauser); ok {
+			scheduledEventsWatcher.SetScaleDownPauser(pauser)
+		}
+		if cnsconfig.ScheduledEventsSettings.CordonNode {
+			scheduledEventsWatcher.SetCordoner(scheduledevents.NewNodeCordoner(clientset, nodeName))
+		}
+	}
+
 	// Start building the NNC Reconciler
 
 	// get CNS Node IP to compare NC Node IP with this Node IP to ensure NCs were created for this node
@@ -1588,6 +1668,13 @@ func InitializeCRDState(ctx context.Context, z *zap.Logger, httpRestService cns.
 		}
 	}
 
+	if cnsconfig.EnableEgressIP {
+		nsReconciler := namespacectrl.NewReconciler(z, httpRestServiceImplementation)
+		if err := nsReconciler.SetupWithManager(manager); err != nil {
+			return errors.Wrapf(err, "failed to setup namespace egress ip reconciler with manager")
+		}
+	}
+
 	if cnsconfig.EnableSwiftV2 {
 		if err := mtpncctrl.SetupWithManager(manager); err != nil {
 			return errors.Wrapf(err, "failed to setup mtpnc reconciler with manager")
@@ -1683,6 +1770,14 @@ func getPodInfoByIPProvider(
 			}
 		}
 	default:
+		logger.Printf("Reconciling CNI endpoints")
+		if result, reconcileErr := cnipodprovider.ReconcileEndpoints(); reconcileErr != nil {
+			// the endpoints are still read from the CNI state, a failed reconciliation only leaves stale endpoints in it
+			logger.Errorf("[Azure CNS] Failed to reconcile CNI endpoints: %v", reconcileErr)
+		} else {
+			logger.Printf("Reconciled %d CNI endpoints, repaired %d, reapplied %d, removed %d, failed %d",
+				result.Checked, len(result.Repaired), len(result.Reapplied), len(result.Removed), len(result.Failed))
+		}
 		logger.Printf("Initializing from CNI")
 		podInfoByIPProvider, err = cnipodprovider.New()
 		if err != nil {
@@ -1692,10 +1787,28 @@ func getPodInfoByIPProvider(
 	return podInfoByIPProvider, nil
 }
 
+// nmagentResilienceConfig converts the NMAgent resilience settings of the CNS config.
+func nmagentResilienceConfig(settings configuration.NMAgentResilienceSettings) resilience.Config {
+	return resilience.Config{
+		FailureThreshold: settings.CircuitBreakerFailureThreshold,
+		OpenDuration:     time.Duration(settings.CircuitBreakerOpenDurationMs) * time.Millisecond,
+		HedgeDelay:       time.Duration(settings.HedgeDelayMs) * time.Millisecond,
+		RateLimit:        settings.RateLimitQPS,
+		RateBurst:        settings.RateLimitBurst,
+	}
+}
+
+// imdsClientOptions points the IMDS client at the configured IMDS endpoint, if any.
+func imdsClientOptions(cnsconfig *configuration.CNSConfig) []imds.ClientOption {
+	if cnsconfig.IMDSEndpoint == "" {
+		return nil
+	}
+	return []imds.ClientOption{imds.Endpoint(cnsconfig.IMDSEndpoint)}
+}
+
 // createOrUpdateNodeInfoCRD polls imds to learn the VM Unique ID and then creates or updates the NodeInfo CRD
 // with that vm unique ID
-func createOrUpdateNodeInfoCRD(ctx context.Context, restConfig *rest.Config, node *corev1.Node) error {
-	imdsCli := imds.NewClient()
+func createOrUpdateNodeInfoCRD(ctx context.Context, restConfig *rest.Config, node *corev1.Node, imdsCli *imds.Client) error {
 	vmUniqueID, err := imdsCli.GetVMUniqueID(ctx)
 	if err != nil {
 		return errors.Wrap(err, "error getting vm unique ID from imds")
@@ -193,7 +193,7 @@ func TestNewService(t *testing.T) {
 				err = svc.StartListener(config)
 				require.NoError(t, err)
 
-				mTLSConfig, err := getTLSConfigFromFile(config.TLSSettings)
+				mTLSConfig, err := getTLSConfigFromFile(context.Background(), config.TLSSettings)
 				require.NoError(t, err)
 
 				client := &http.Client{
@@ -330,6 +330,21 @@ func TestMtlsRootCAsFromCertificate(t *testing.T) {
 func createTestCertificate(t *testing.T) string {
 	t.Helper()
 
+	// Write PEM cert and key to a file in a temp dir
+	testCertFilePath := filepath.Join(t.TempDir(), "dummy.pem")
+	err := os.WriteFile(testCertFilePath, newTestCertificatePEM(t, time.Now().Add(3*time.Hour)), 0o600)
+	require.NoError(t, err)
+
+	t.Log("Created test certificate file at: ", testCertFilePath)
+
+	return testCertFilePath
+}
+
+// newTestCertificatePEM is a test helper that creates a self signed test certificate
+// expiring at notAfter, and returns it and its private key as PEM.
+func newTestCertificatePEM(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+
 	t.Log("Creating test certificate...")
 
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -346,7 +361,7 @@ func createTestCertificate(t *testing.T) string {
 		},
 		DNSNames:  []string{"localhost", "127.0.0.1", "example.com"},
 		NotBefore: time.Now(),
-		NotAfter:  time.Now().Add(3 * time.Hour),
+		NotAfter:  notAfter,
 
 		KeyUsage:              x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
@@ -367,16 +382,7 @@ func createTestCertificate(t *testing.T) string {
 	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
 	require.NotNil(t, pemKey)
 
-	pemCert = append(pemCert, pemKey...)
-
-	// Write PEM cert and key to a file in a temp dir
-	testCertFilePath := filepath.Join(t.TempDir(), "dummy.pem")
-	err = os.WriteFile(testCertFilePath, pemCert, 0o600)
-	require.NoError(t, err)
-
-	t.Log("Created test certificate file at: ", testCertFilePath)
-
-	return testCertFilePath
+	return append(pemCert, pemKey...)
 }
 
 func TestTLSVersionNumber(t *testing.T) {
@@ -431,3 +437,65 @@ func TestMaskHalf(t *testing.T) {
 		})
 	}
 }
+
+func TestCertificateSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		tlsSettings serverTLS.TlsSettings
+		want        string
+	}{
+		{"file", serverTLS.TlsSettings{TLSCertificatePath: "cert.pem", KeyVaultURL: "https://kv"}, serverTLS.CertificateSourceFile},
+		{"keyvault", serverTLS.TlsSettings{KeyVaultURL: "https://kv"}, serverTLS.CertificateSourceKeyVault},
+		{"explicit source", serverTLS.TlsSettings{TLSCertificatePath: "cert.pem", CertificateSource: serverTLS.CertificateSourceSelfSigned}, serverTLS.CertificateSourceSelfSigned},
+		{"none", serverTLS.TlsSettings{}, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, certificateSource(tc.tlsSettings))
+		})
+	}
+}
+
+func TestGetTLSConfigFromSecretStoreCSIAndSelfSigned(t *testing.T) {
+	logger.InitLogger("azure-cns.log", 0, 0, "/")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cns-tls"), newTestCertificatePEM(t, time.Now().Add(time.Hour)), 0o600))
+
+	tests := []struct {
+		name        string
+		tlsSettings serverTLS.TlsSettings
+	}{
+		{
+			name: "secret store csi",
+			tlsSettings: serverTLS.TlsSettings{
+				CertificateSource:     serverTLS.CertificateSourceSecretStoreCSI,
+				SecretStoreDirectory:  dir,
+				SecretStoreObjectName: "cns-tls",
+				MinTLSVersion:         "TLS 1.2",
+			},
+		},
+		{
+			name: "self-signed",
+			tlsSettings: serverTLS.TlsSettings{
+				CertificateSource: serverTLS.CertificateSourceSelfSigned,
+				TLSSubjectName:    "localhost",
+				MinTLSVersion:     "TLS 1.2",
+				UseMTLS:           true,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig, err := getTLSConfig(ctx, tc.tlsSettings, make(chan error, 1))
+			require.NoError(t, err)
+			cert, err := tlsConfig.GetCertificate(nil)
+			require.NoError(t, err)
+			require.NoError(t, cert.Leaf.VerifyHostname("localhost"))
+		})
+	}
+}
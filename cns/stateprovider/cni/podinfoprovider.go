@@ -16,6 +16,14 @@ func New() (cns.PodInfoByIPProvider, error) {
 	return podInfoProvider(kexec.New())
 }
 
+// ReconcileEndpoints execs out to the CNI to repair the host side objects of its endpoints and to remove the
+// endpoints whose netns no longer exists, so that the IPs of the removed endpoints are left out of the PodInfo
+// map built by New afterwards.
+func ReconcileEndpoints() (*api.AzureCNIReconcileResult, error) {
+	result, err := client.New(kexec.New()).ReconcileEndpoints()
+	return result, errors.Wrap(err, "failed to invoke CNI client.ReconcileEndpoints()")
+}
+
 func podInfoProvider(exec kexec.Interface) (cns.PodInfoByIPProvider, error) {
 	cli := client.New(exec)
 	state, err := cli.GetEndpointState()
@@ -0,0 +1,157 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorVersion is the version of the Error envelope returned by CNS. It's incremented when the meaning of
+// its fields changes, so that clients can tell which categories to expect.
+const ErrorVersion = 1
+
+// ErrorCategory groups the ResponseCodes by how clients should handle them.
+type ErrorCategory string
+
+const (
+	// CategoryInvalidRequest is a request which will keep failing until it's fixed.
+	CategoryInvalidRequest ErrorCategory = "InvalidRequest"
+	// CategoryNotFound is a request for a resource CNS doesn't know of.
+	CategoryNotFound ErrorCategory = "NotFound"
+	// CategoryUnsupported is an API this CNS doesn't support.
+	CategoryUnsupported ErrorCategory = "Unsupported"
+	// CategoryUnauthorized is a request the client isn't allowed to make.
+	CategoryUnauthorized ErrorCategory = "Unauthorized"
+	// CategoryUnavailable is a transient failure of CNS or of a host component, such as NMAgent.
+	CategoryUnavailable ErrorCategory = "Unavailable"
+	// CategoryResourceExhausted is a request which can't be served until resources, such as IPs, are freed
+	// or allocated.
+	CategoryResourceExhausted ErrorCategory = "ResourceExhausted"
+	// CategoryInternal is an unexpected failure of CNS.
+	CategoryInternal ErrorCategory = "Internal"
+)
+
+// Sentinel errors matching the Errors of each category with errors.Is.
+var (
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrNotFound          = errors.New("not found")
+	ErrUnsupported       = errors.New("unsupported")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrUnavailable       = errors.New("unavailable")
+	ErrResourceExhausted = errors.New("resource exhausted")
+	ErrInternal          = errors.New("internal error")
+	// ErrRetryable matches the Errors which may succeed when retried.
+	ErrRetryable = errors.New("retryable")
+)
+
+var categorySentinels = map[ErrorCategory]error{
+	CategoryInvalidRequest:    ErrInvalidRequest,
+	CategoryNotFound:          ErrNotFound,
+	CategoryUnsupported:       ErrUnsupported,
+	CategoryUnauthorized:      ErrUnauthorized,
+	CategoryUnavailable:       ErrUnavailable,
+	CategoryResourceExhausted: ErrResourceExhausted,
+	CategoryInternal:          ErrInternal,
+}
+
+// codeCategories maps the ResponseCodes to their category. Codes which aren't listed are internal errors.
+var codeCategories = map[ResponseCode]ErrorCategory{
+	UnsupportedNetworkType:          CategoryInvalidRequest,
+	InvalidParameter:                CategoryInvalidRequest,
+	UnsupportedEnvironment:          CategoryInvalidRequest,
+	MalformedSubnet:                 CategoryInvalidRequest,
+	UnspecifiedNetworkName:          CategoryInvalidRequest,
+	NetworkContainerNotSpecified:    CategoryInvalidRequest,
+	UnsupportedOrchestratorType:     CategoryInvalidRequest,
+	DockerContainerNotSpecified:     CategoryInvalidRequest,
+	UnsupportedVerb:                 CategoryInvalidRequest,
+	UnsupportedNetworkContainerType: CategoryInvalidRequest,
+	InvalidRequest:                  CategoryInvalidRequest,
+	InvalidPrimaryIPConfig:          CategoryInvalidRequest,
+	PrimaryCANotSame:                CategoryInvalidRequest,
+	InvalidSecondaryIPConfig:        CategoryInvalidRequest,
+	EmptyOrchestratorContext:        CategoryInvalidRequest,
+	UnsupportedOrchestratorContext:  CategoryInvalidRequest,
+	UnsupportedNCVersion:            CategoryInvalidRequest,
+
+	ReservationNotFound: CategoryNotFound,
+	NotFound:            CategoryNotFound,
+	UnknownContainerID:  CategoryNotFound,
+
+	UnsupportedAPI: CategoryUnsupported,
+
+	StatusUnauthorized: CategoryUnauthorized,
+
+	UnreachableHost:                   CategoryUnavailable,
+	UnreachableDockerDaemon:           CategoryUnavailable,
+	CallToHostFailed:                  CategoryUnavailable,
+	NetworkJoinFailed:                 CategoryUnavailable,
+	NetworkContainerPublishFailed:     CategoryUnavailable,
+	NetworkContainerUnpublishFailed:   CategoryUnavailable,
+	NetworkContainerVfpProgramPending: CategoryUnavailable,
+	NmAgentSupportedApisError:         CategoryUnavailable,
+	NmAgentInternalServerError:        CategoryUnavailable,
+	NmAgentNCVersionListError:         CategoryUnavailable,
+	ConnectionError:                   CategoryUnavailable,
+
+	AddressUnavailable:            CategoryResourceExhausted,
+	FailedToAllocateIPConfig:      CategoryResourceExhausted,
+	FailedToAllocateBackendConfig: CategoryResourceExhausted,
+}
+
+// Category returns the category of the code.
+func (c ResponseCode) Category() ErrorCategory {
+	if category, ok := codeCategories[c]; ok {
+		return category
+	}
+	return CategoryInternal
+}
+
+// Retryable reports whether requests failing with the code may succeed when retried.
+func (c ResponseCode) Retryable() bool {
+	category := c.Category()
+	return category == CategoryUnavailable || category == CategoryResourceExhausted
+}
+
+// Error is the envelope of the errors returned by CNS, alongside the legacy ReturnCode and Message of
+// the Response.
+type Error struct {
+	Version   int               `json:"Version"`
+	Code      ResponseCode      `json:"Code"`
+	Category  ErrorCategory     `json:"Category"`
+	Retryable bool              `json:"Retryable"`
+	Message   string            `json:"Message"`
+	Details   map[string]string `json:"Details,omitempty"`
+}
+
+// NewError returns the Error of the code.
+func NewError(code ResponseCode, message string) *Error {
+	return &Error{
+		Version:   ErrorVersion,
+		Code:      code,
+		Category:  code.Category(),
+		Retryable: code.Retryable(),
+		Message:   message,
+	}
+}
+
+// Error returns the message of the error, like the errors built from the legacy Message.
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s (%d)", e.Code, e.Code)
+	}
+	return e.Message
+}
+
+// Is matches the sentinel error of the category of e, ErrRetryable if e is retryable, and the Errors with
+// the same Code.
+func (e *Error) Is(target error) bool {
+	if t, ok := target.(*Error); ok {
+		return t.Code == e.Code
+	}
+	if target == ErrRetryable {
+		return e.Retryable
+	}
+	sentinel, ok := categorySentinels[e.Category]
+	return ok && target == sentinel
+}
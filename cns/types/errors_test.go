@@ -0,0 +1,69 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorIs(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       *Error
+		match     []error
+		mismatch  []error
+		retryable bool
+	}{
+		{
+			name:     "invalid request",
+			err:      NewError(InvalidParameter, "bad ipconfig"),
+			match:    []error{ErrInvalidRequest, &Error{Code: InvalidParameter}},
+			mismatch: []error{ErrRetryable, ErrNotFound, &Error{Code: InvalidRequest}},
+		},
+		{
+			name:     "not found",
+			err:      NewError(UnknownContainerID, ""),
+			match:    []error{ErrNotFound},
+			mismatch: []error{ErrRetryable},
+		},
+		{
+			name:      "transient",
+			err:       NewError(NmAgentInternalServerError, "nmagent returned 500"),
+			match:     []error{ErrUnavailable, ErrRetryable},
+			mismatch:  []error{ErrInternal},
+			retryable: true,
+		},
+		{
+			name:      "exhausted",
+			err:       NewError(FailedToAllocateIPConfig, "no IPs available"),
+			match:     []error{ErrResourceExhausted, ErrRetryable},
+			retryable: true,
+		},
+		{
+			name:     "uncategorized",
+			err:      NewError(FailedToRunIPTableCmd, ""),
+			match:    []error{ErrInternal},
+			mismatch: []error{ErrRetryable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, ErrorVersion, tt.err.Version)
+			assert.Equal(t, tt.retryable, tt.err.Retryable)
+			wrapped := errors.Wrap(tt.err, "calling CNS")
+			for _, target := range tt.match {
+				assert.ErrorIs(t, wrapped, target)
+			}
+			for _, target := range tt.mismatch {
+				assert.NotErrorIs(t, wrapped, target)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	assert.Equal(t, "no IPs available", NewError(FailedToAllocateIPConfig, "no IPs available").Error())
+	assert.Equal(t, "UnknownContainerID (18)", NewError(UnknownContainerID, "").Error())
+}
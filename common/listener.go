@@ -4,6 +4,7 @@
 package common
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"net"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 // Listener represents an HTTP listener.
@@ -24,7 +26,10 @@ type Listener struct {
 	active       bool
 	listener     net.Listener
 	tlsListener  net.Listener
+	unixListener net.Listener
 	mux          *http.ServeMux
+	middlewares  []func(http.Handler) http.Handler
+	servers      []*http.Server
 }
 
 // NewListener creates a new Listener.
@@ -42,9 +47,9 @@ func NewListener(u *url.URL) (*Listener, error) {
 
 // StartTLS creates the listener socket and starts the HTTPS server.
 func (l *Listener) StartTLS(errChan chan<- error, tlsConfig *tls.Config, address string) error {
-	server := http.Server{
+	server := &http.Server{
 		TLSConfig: tlsConfig,
-		Handler:   l.mux,
+		Handler:   l.handler(),
 	}
 
 	// listen on a separate endpoint for secure tls connections
@@ -58,9 +63,40 @@ func (l *Listener) StartTLS(errChan chan<- error, tlsConfig *tls.Config, address
 	log.Printf("[Listener] Started listening on tls endpoint %s.", address)
 
 	// Launch goroutine for servicing https requests
-	go func() {
-		errChan <- server.ServeTLS(l.tlsListener, "", "")
-	}()
+	l.serve(errChan, server, func() error {
+		return server.ServeTLS(l.tlsListener, "", "")
+	})
+
+	l.active = true
+	return nil
+}
+
+// StartUnix creates a unix socket at the path and starts an HTTP server on it, in addition to the
+// other servers of the listener. The peer credentials of the connections are set on the context of
+// their requests, see PeerCredFromContext.
+func (l *Listener) StartUnix(errChan chan<- error, path string) error {
+	// remove the socket left behind by a previous process
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("[Listener] Failed to remove stale unix socket %s: %+v", path, err)
+		return errors.Wrapf(err, "failed to remove stale unix socket %s", path)
+	}
+
+	list, err := net.Listen("unix", path)
+	if err != nil {
+		log.Printf("[Listener] Failed to listen on unix socket %s: %+v", path, err)
+		return errors.Wrapf(err, "failed to listen on unix socket %s", path)
+	}
+
+	l.unixListener = list
+	log.Printf("[Listener] Started listening on unix socket %s.", path)
+
+	server := &http.Server{
+		Handler:     l.handler(),
+		ConnContext: peerCredConnContext,
+	}
+	l.serve(errChan, server, func() error {
+		return server.Serve(l.unixListener)
+	})
 
 	l.active = true
 	return nil
@@ -78,15 +114,52 @@ func (l *Listener) Start(errChan chan<- error) error {
 	log.Printf("[Listener] Started listening on %s.", l.localAddress)
 
 	// Launch goroutine for servicing requests.
+	server := &http.Server{
+		Handler: l.handler(),
+	}
+	l.serve(errChan, server, func() error {
+		return server.Serve(l.listener)
+	})
+
+	l.active = true
+	return nil
+}
+
+// serve runs the server in the background, and reports why it stopped unless it was stopped by the
+// listener.
+func (l *Listener) serve(errChan chan<- error, server *http.Server, serve func() error) {
+	l.servers = append(l.servers, server)
 	go func() {
-		errChan <- http.Serve(l.listener, l.mux)
+		if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+			errChan <- err
+		}
 	}()
+}
 
-	l.active = true
+// Shutdown stops listening for requests, and waits for the requests in flight to complete until the
+// context is done. The connections still open then are closed.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	// Ignore if not active.
+	if !l.active {
+		return nil
+	}
+
+	var g errgroup.Group
+	for _, server := range l.servers {
+		g.Go(func() error {
+			return server.Shutdown(ctx) // nolint:wrapcheck // wrapped below
+		})
+	}
+	err := g.Wait()
+	l.Stop()
+	if err != nil {
+		return errors.Wrap(err, "failed to drain requests")
+	}
 	return nil
 }
 
-// Stop stops listening for requests.
+// Stop stops listening for requests, and closes the connections without waiting for the requests
+// in flight.
 func (l *Listener) Stop() {
 	// Ignore if not active.
 	if !l.active {
@@ -94,8 +167,15 @@ func (l *Listener) Stop() {
 	}
 	l.active = false
 
-	// Stop servicing requests.
-	_ = l.listener.Close()
+	// Stop servicing requests. The sockets are also closed here, since a server only closes them
+	// once it has started serving.
+	for _, server := range l.servers {
+		_ = server.Close()
+	}
+
+	if l.listener != nil {
+		_ = l.listener.Close()
+	}
 
 	if l.tlsListener != nil {
 		// Stop servicing requests on secure listener
@@ -103,12 +183,35 @@ func (l *Listener) Stop() {
 		log.Printf("[Listener] Stopped listening on tls endpoint %s", l.tlsListener.Addr())
 	}
 
+	if l.unixListener != nil {
+		// Stop servicing requests on the unix socket, which also deletes it
+		_ = l.unixListener.Close()
+		log.Printf("[Listener] Stopped listening on unix socket %s", l.unixListener.Addr())
+	}
+
 	// Delete the unix socket.
 	if l.protocol == "unix" {
 		_ = os.Remove(l.localAddress)
 	}
 
-	log.Printf("[Listener] Stopped listening on %s", l.listener.Addr())
+	if l.listener != nil {
+		log.Printf("[Listener] Stopped listening on %s", l.listener.Addr())
+	}
+}
+
+// Use wraps the handlers of the listener in the middleware. Middlewares must be added before the
+// listener is started, and the first one added sees the requests first.
+func (l *Listener) Use(middleware func(http.Handler) http.Handler) {
+	l.middlewares = append(l.middlewares, middleware)
+}
+
+// handler returns the HTTP mux for the listener wrapped in its middlewares.
+func (l *Listener) handler() http.Handler {
+	var h http.Handler = l.mux
+	for i := len(l.middlewares) - 1; i >= 0; i-- {
+		h = l.middlewares[i](h)
+	}
+	return h
 }
 
 // GetMux returns the HTTP mux for the listener.
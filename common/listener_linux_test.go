@@ -0,0 +1,64 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerUnixSocketPeerCred(t *testing.T) {
+	u, err := url.Parse("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+	l, err := NewListener(u)
+	require.NoError(t, err)
+	l.AddHandler("/peer", func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := PeerCredFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(cred)
+	})
+
+	errChan := make(chan error, 2)
+	socketPath := filepath.Join(t.TempDir(), "cns.sock")
+	// a stale socket is replaced
+	require.NoError(t, os.WriteFile(socketPath, nil, 0o600))
+	require.NoError(t, l.Start(errChan))
+	require.NoError(t, l.StartUnix(errChan, socketPath))
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://localhost/peer", http.NoBody)
+	require.NoError(t, err)
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var cred PeerCred
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&cred))
+	assert.Equal(t, PeerCred{PID: int32(os.Getpid()), UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}, cred) //nolint:gosec // test process IDs fit
+
+	// requests on the TCP endpoint carry no peer credentials
+	res, err = http.Get("http://" + l.listener.Addr().String() + "/peer") //nolint:noctx // ignore for unit test
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	l.Stop()
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerShutdownDrainsRequests(t *testing.T) {
+	u, err := url.Parse("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+	l, err := NewListener(u)
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	l.AddHandler("/slow", func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	errChan := make(chan error, 1)
+	require.NoError(t, l.Start(errChan))
+	addr := "http://" + l.listener.Addr().String()
+
+	resCh := make(chan *http.Response, 1)
+	go func() {
+		res, err := http.Get(addr + "/slow") //nolint:noctx // ignore for unit test
+		if err != nil {
+			resCh <- nil
+			return
+		}
+		res.Body.Close()
+		resCh <- res
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- l.Shutdown(context.Background())
+	}()
+
+	// new requests are refused while the request in flight completes
+	require.Eventually(t, func() bool {
+		res, err := http.Get(addr + "/slow") //nolint:noctx // ignore for unit test
+		if err == nil {
+			res.Body.Close()
+		}
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	close(release)
+
+	res := <-resCh
+	require.NotNil(t, res)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, <-shutdownErr)
+	assert.Empty(t, errChan)
+}
+
+func TestListenerShutdownTimeout(t *testing.T) {
+	u, err := url.Parse("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+	l, err := NewListener(u)
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	l.AddHandler("/stuck", func(_ http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	require.NoError(t, l.Start(make(chan error, 1)))
+	go func() {
+		res, err := http.Get("http://" + l.listener.Addr().String() + "/stuck") //nolint:noctx // ignore for unit test
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+	<-started
+
+	// the connection is closed once the requests in flight can't be waited for anymore
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Error(t, l.Shutdown(ctx))
+}
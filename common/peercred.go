@@ -0,0 +1,49 @@
+package common
+
+import (
+	"context"
+	"net"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+)
+
+var errPeerCredUnsupported = errors.New("peer credentials of unix sockets are not supported on this platform")
+
+// PeerCred is the credentials of the process on the other end of a Unix socket connection.
+type PeerCred struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+type peerCredKey struct{}
+
+// WithPeerCred returns a copy of the context carrying the peer credentials of the connection.
+func WithPeerCred(ctx context.Context, cred PeerCred) context.Context {
+	return context.WithValue(ctx, peerCredKey{}, cred)
+}
+
+// PeerCredFromContext returns the peer credentials carried by the context, which are set on the
+// requests served on a Unix socket by a Listener.
+func PeerCredFromContext(ctx context.Context) (PeerCred, bool) {
+	cred, ok := ctx.Value(peerCredKey{}).(PeerCred)
+	return cred, ok
+}
+
+// peerCredConnContext sets the peer credentials of the connection on the context of its requests.
+func peerCredConnContext(ctx context.Context, c net.Conn) context.Context {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+	cred, err := getPeerCred(uc)
+	if errors.Is(err, errPeerCredUnsupported) {
+		return ctx
+	}
+	if err != nil {
+		log.Printf("[Listener] Failed to get peer credentials of unix socket connection: %v", err)
+		return ctx
+	}
+	return WithPeerCred(ctx, cred)
+}
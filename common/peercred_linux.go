@@ -0,0 +1,27 @@
+package common
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// getPeerCred reads the SO_PEERCRED of the connection.
+func getPeerCred(c *net.UnixConn) (PeerCred, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return PeerCred{}, errors.Wrap(err, "failed to get raw connection")
+	}
+	var ucred *unix.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return PeerCred{}, errors.Wrap(err, "failed to control raw connection")
+	}
+	if credErr != nil {
+		return PeerCred{}, errors.Wrap(credErr, "failed to get SO_PEERCRED")
+	}
+	return PeerCred{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
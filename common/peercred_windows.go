@@ -0,0 +1,8 @@
+package common
+
+import "net"
+
+// getPeerCred fails, since Windows doesn't expose the credentials of unix socket peers.
+func getPeerCred(*net.UnixConn) (PeerCred, error) {
+	return PeerCred{}, errPeerCredUnsupported
+}
@@ -110,6 +110,15 @@ type InterfaceInfo struct {
 	// IBStatus is the programming status of the infiniband device
 	// +kubebuilder:validation:Optional
 	IBStatus InfinibandStatus `json:"ibStatus,omitempty"`
+	// VlanID is the vlan the SR-IOV virtual function of a backend NIC is tagged with, 0 for untagged
+	// +kubebuilder:validation:Optional
+	VlanID int `json:"vlanID,omitempty"`
+	// VFMacAddress is the MAC Address to program on the SR-IOV virtual function of a backend NIC
+	// +kubebuilder:validation:Optional
+	VFMacAddress string `json:"vfMacAddress,omitempty"`
+	// VFTrust allows the pod to change the MAC Address and enable promiscuous mode of the SR-IOV virtual function
+	// +kubebuilder:validation:Optional
+	VFTrust bool `json:"vfTrust,omitempty"`
 }
 
 // MultitenantPodNetworkConfigStatus defines the observed state of PodNetworkConfig
@@ -0,0 +1,66 @@
+//go:build !ignore_uncovered
+// +build !ignore_uncovered
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NetworkPolicyNodeStatus is the Schema for the networkpolicynodestatuses API.
+// NPM names each NetworkPolicyNodeStatus after its node.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:resource:shortName=npns
+// +kubebuilder:printcolumn:name="Failed Policies",type=integer,JSONPath=`.status.failedPolicyCount`
+type NetworkPolicyNodeStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status NetworkPolicyNodeStatusStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NetworkPolicyNodeStatusList contains a list of NetworkPolicyNodeStatus
+type NetworkPolicyNodeStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NetworkPolicyNodeStatus `json:"items"`
+}
+
+// NetworkPolicyNodeStatusStatus defines the observed state of NetworkPolicyNodeStatus
+type NetworkPolicyNodeStatusStatus struct {
+	// FailedPolicyCount is the number of FailedPolicies.
+	FailedPolicyCount int `json:"failedPolicyCount"`
+	// FailedPolicies are the NetworkPolicies which NPM failed to enforce on the node.
+	// +kubebuilder:validation:Optional
+	FailedPolicies []FailedPolicy `json:"failedPolicies,omitempty"`
+}
+
+type FailureReason string
+
+const (
+	// UnsupportedFeature means the policy uses a feature which the dataplane of the node doesn't support.
+	UnsupportedFeature FailureReason = "UnsupportedFeature"
+	// TranslationFailed means the policy couldn't be translated.
+	TranslationFailed FailureReason = "TranslationFailed"
+	// ApplyFailed means the dataplane failed to apply the policy. NPM retries applying it.
+	ApplyFailed FailureReason = "ApplyFailed"
+)
+
+// FailedPolicy is a NetworkPolicy which NPM failed to enforce.
+type FailedPolicy struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// +kubebuilder:validation:Enum=UnsupportedFeature;TranslationFailed;ApplyFailed
+	Reason  FailureReason `json:"reason"`
+	Message string        `json:"message,omitempty"`
+	// LastTransitionTime is when the policy started failing with the reason.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NetworkPolicyNodeStatus{}, &NetworkPolicyNodeStatusList{})
+}
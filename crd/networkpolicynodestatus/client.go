@@ -0,0 +1,119 @@
+package networkpolicynodestatus
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/Azure/azure-container-networking/crd"
+	"github.com/Azure/azure-container-networking/crd/networkpolicynodestatus/api/v1alpha1"
+	"github.com/pkg/errors"
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	typedv1 "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Scheme is a runtime scheme containing the client-go scheme and the NetworkPolicyNodeStatus scheme.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	_ = scheme.AddToScheme(Scheme)
+	_ = v1alpha1.AddToScheme(Scheme)
+}
+
+// Installer provides methods to manage the lifecycle of the NetworkPolicyNodeStatus resource definition.
+type Installer struct {
+	cli typedv1.CustomResourceDefinitionInterface
+}
+
+func NewInstaller(c *rest.Config) (*Installer, error) {
+	cli, err := crd.NewCRDClientFromConfig(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init crd client")
+	}
+	return &Installer{
+		cli: cli,
+	}, nil
+}
+
+func (i *Installer) create(ctx context.Context, res *v1.CustomResourceDefinition) (*v1.CustomResourceDefinition, error) {
+	res, err := i.cli.Create(ctx, res, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create npns crd")
+	}
+	return res, nil
+}
+
+// InstallOrUpdate installs the embedded NetworkPolicyNodeStatus CRD definition in the cluster or updates it if present.
+func (i *Installer) InstallOrUpdate(ctx context.Context) (*v1.CustomResourceDefinition, error) {
+	npns, err := GetNetworkPolicyNodeStatuses()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get embedded npns crd")
+	}
+	current, err := i.create(ctx, npns)
+	if !apierrors.IsAlreadyExists(err) {
+		return current, err
+	}
+	if current == nil {
+		current, err = i.cli.Get(ctx, npns.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get existing npns crd")
+		}
+	}
+	if !reflect.DeepEqual(npns.Spec.Versions, current.Spec.Versions) {
+		npns.SetResourceVersion(current.GetResourceVersion())
+		previous := *current
+		current, err = i.cli.Update(ctx, npns, metav1.UpdateOptions{})
+		if err != nil {
+			return &previous, errors.Wrap(err, "failed to update existing npns crd")
+		}
+	}
+	return current, nil
+}
+
+// Client provides methods to interact with instances of the NetworkPolicyNodeStatus custom resource.
+type Client struct {
+	cli client.Client
+}
+
+// NewClient creates a new NetworkPolicyNodeStatus client around the passed ctrlcli.Client.
+func NewClient(cli client.Client) *Client {
+	return &Client{
+		cli: cli,
+	}
+}
+
+// Get returns the NetworkPolicyNodeStatus of the node.
+func (c *Client) Get(ctx context.Context, nodeName string) (*v1alpha1.NetworkPolicyNodeStatus, error) {
+	networkPolicyNodeStatus := &v1alpha1.NetworkPolicyNodeStatus{}
+	err := c.cli.Get(ctx, types.NamespacedName{Name: nodeName}, networkPolicyNodeStatus)
+	return networkPolicyNodeStatus, errors.Wrapf(err, "failed to get npns %s", nodeName)
+}
+
+// UpdateStatus sets the status of the NetworkPolicyNodeStatus of the node, creating the NetworkPolicyNodeStatus if it doesn't exist.
+func (c *Client) UpdateStatus(ctx context.Context, nodeName string, status *v1alpha1.NetworkPolicyNodeStatusStatus) (*v1alpha1.NetworkPolicyNodeStatus, error) {
+	networkPolicyNodeStatus, err := c.Get(ctx, nodeName)
+	if err != nil {
+		if !apierrors.IsNotFound(errors.Cause(err)) {
+			return nil, err
+		}
+		networkPolicyNodeStatus = &v1alpha1.NetworkPolicyNodeStatus{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: nodeName,
+			},
+		}
+		if err := c.cli.Create(ctx, networkPolicyNodeStatus); err != nil {
+			return nil, errors.Wrap(err, "failed to create npns")
+		}
+	}
+	status.DeepCopyInto(&networkPolicyNodeStatus.Status)
+	if err := c.cli.Status().Update(ctx, networkPolicyNodeStatus); err != nil {
+		return nil, errors.Wrap(err, "failed to update npns status")
+	}
+	return networkPolicyNodeStatus, nil
+}
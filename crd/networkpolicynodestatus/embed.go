@@ -0,0 +1,26 @@
+package networkpolicynodestatus
+
+import (
+	_ "embed"
+
+	// import the manifests package so that caller of this package have the manifests compiled in as a side-effect.
+	_ "github.com/Azure/azure-container-networking/crd/networkpolicynodestatus/manifests"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// NetworkPolicyNodeStatusesYAML embeds the CRD YAML for downstream consumers.
+//
+//go:embed manifests/acn.azure.com_networkpolicynodestatuses.yaml
+var NetworkPolicyNodeStatusesYAML []byte
+
+// GetNetworkPolicyNodeStatuses parses the raw []byte NetworkPolicyNodeStatuses in
+// to a CustomResourceDefinition and returns it or an unmarshalling error.
+func GetNetworkPolicyNodeStatuses() (*apiextensionsv1.CustomResourceDefinition, error) {
+	networkPolicyNodeStatuses := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(NetworkPolicyNodeStatusesYAML, &networkPolicyNodeStatuses); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling embedded npns")
+	}
+	return networkPolicyNodeStatuses, nil
+}
@@ -0,0 +1,22 @@
+package networkpolicynodestatus
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const filename = "manifests/acn.azure.com_networkpolicynodestatuses.yaml"
+
+func TestEmbed(t *testing.T) {
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, b, NetworkPolicyNodeStatusesYAML)
+}
+
+func TestGetNetworkPolicyNodeStatuses(t *testing.T) {
+	_, err := GetNetworkPolicyNodeStatuses()
+	require.NoError(t, err)
+}
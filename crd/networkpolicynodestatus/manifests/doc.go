@@ -0,0 +1,3 @@
+// Package manifests exists to allow the rendered CRD manifests to be
+// packaged in to dependent components.
+package manifests
@@ -128,6 +128,7 @@ require (
 	golang.org/x/sync v0.17.0
 	gotest.tools/v3 v3.5.2
 	k8s.io/kubectl v0.34.1
+	sigs.k8s.io/network-policy-api v0.1.5
 	sigs.k8s.io/yaml v1.6.0
 )
 
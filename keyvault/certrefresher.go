@@ -21,7 +21,9 @@ func (e *EventualExpirationErr) Error() string {
 	return fmt.Sprintf("could not refresh before expiration on %s", e.Time.String())
 }
 
-type tlsCertFetcher interface {
+// CertProvider is a source of TLS certificates, such as KeyVault or a Kubernetes Secret. The returned
+// certificate must have its Leaf set.
+type CertProvider interface {
 	GetLatestTLSCertificate(ctx context.Context, certName string) (tls.Certificate, error)
 }
 
@@ -30,19 +32,20 @@ type logger interface {
 	Errorf(format string, args ...any)
 }
 
-// CertRefresher offers a mechanism to present the latest version of a tls.Certificate from KeyVault, refreshed at an interval.
+// CertRefresher offers a mechanism to present the latest version of a tls.Certificate from a CertProvider, refreshed at an interval.
 type CertRefresher struct {
 	certName string
-	kvc      tlsCertFetcher
+	kvc      CertProvider
 	logger   logger
 
-	m    sync.RWMutex
-	cert *tls.Certificate
+	m         sync.RWMutex
+	cert      *tls.Certificate
+	onRefresh []func(*tls.Certificate)
 }
 
 // NewCertRefresher returns a CertRefresher. When there's no error, the CertRefresher's GetCertificate method is ready
-// for use, returning a valid tls.Certificate fetched from KeyVault during construction.
-func NewCertRefresher(ctx context.Context, kvc tlsCertFetcher, l logger, certName string) (*CertRefresher, error) {
+// for use, returning a valid tls.Certificate fetched from the CertProvider during construction.
+func NewCertRefresher(ctx context.Context, kvc CertProvider, l logger, certName string) (*CertRefresher, error) {
 	cf := CertRefresher{
 		certName: certName,
 		kvc:      kvc,
@@ -63,13 +66,23 @@ func (c *CertRefresher) String() string {
 	return fmt.Sprintf("cert name: %s, sha1 thumbprint: %s, expiration: %s", c.certName, sha1String(c.cert.Leaf.Raw), c.cert.Leaf.NotAfter.String())
 }
 
-// GetCertificate returns the latest certificate fetched from KeyVault.
+// GetCertificate returns the latest certificate fetched from the CertProvider.
 func (c *CertRefresher) GetCertificate() *tls.Certificate {
 	c.m.RLock()
 	defer c.m.RUnlock()
 	return c.cert
 }
 
+// OnRefresh calls fn with the current certificate, and then with every refreshed certificate, e.g. to
+// track its expiry.
+func (c *CertRefresher) OnRefresh(fn func(*tls.Certificate)) {
+	c.m.Lock()
+	c.onRefresh = append(c.onRefresh, fn)
+	cert := c.cert
+	c.m.Unlock()
+	fn(cert)
+}
+
 // Refresh starts refreshing the certificate at the interval provided.
 // It blocks until context is done or refreshing fails.
 func (c *CertRefresher) Refresh(ctx context.Context, interval time.Duration) error {
@@ -110,17 +123,21 @@ func (c *CertRefresher) refresh(ctx context.Context) error {
 	}
 
 	c.m.Lock()
-	defer c.m.Unlock()
-
 	if latestCert.Leaf.Equal(c.cert.Leaf) {
 		c.logger.Printf("certificate unchanged. certificate %s", c)
+		c.m.Unlock()
 		return nil
 	}
 
 	oldThumbprint := sha1String(c.cert.Leaf.Raw)
 	c.cert = &latestCert
 	c.logger.Printf("certificate refreshed. old sha1 thumbprint: %s, certificate: %s", oldThumbprint, c)
+	onRefresh := c.onRefresh
+	c.m.Unlock()
 
+	for _, fn := range onRefresh {
+		fn(&latestCert)
+	}
 	return nil
 }
 
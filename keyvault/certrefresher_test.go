@@ -88,6 +88,29 @@ func TestCertRefresher_RetryUntilExpiration(t *testing.T) {
 	assert.Eventually(t, condFn, waitFor, checkEvery)
 }
 
+func TestCertRefresher_OnRefresh(t *testing.T) {
+	ctx, cancel := testContext(t)
+	defer cancel()
+
+	cf, err := NewCertRefresher(ctx, NewSelfSignedProvider(nil, time.Hour), testLogger{t}, "dummy")
+	require.NoError(t, err)
+
+	var expiries []time.Time
+	cf.OnRefresh(func(cert *tls.Certificate) {
+		expiries = append(expiries, cert.Leaf.NotAfter)
+	})
+	require.Len(t, expiries, 1)
+
+	// unchanged certificates aren't notified
+	require.NoError(t, cf.refresh(ctx))
+	require.Len(t, expiries, 1)
+
+	cf.kvc = NewSelfSignedProvider(nil, 2*time.Hour)
+	require.NoError(t, cf.refresh(ctx))
+	require.Len(t, expiries, 2)
+	assert.True(t, expiries[1].After(expiries[0]))
+}
+
 type tlsFunc func() (tls.Certificate, error)
 
 func (t tlsFunc) GetLatestTLSCertificate(_ context.Context, _ string) (tls.Certificate, error) {
@@ -0,0 +1,44 @@
+package keyvault
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// DirectoryProvider provides the certificates written as files to a directory, such as a volume mounted by the
+// Secrets Store CSI driver, named by the certificate name.
+type DirectoryProvider struct {
+	dir string
+}
+
+// NewDirectoryProvider constructs a DirectoryProvider for the directory.
+func NewDirectoryProvider(dir string) *DirectoryProvider {
+	return &DirectoryProvider{dir: dir}
+}
+
+// GetLatestTLSCertificate reads the file and transforms it into a usable tls.Certificate. The file holds either
+// PEM blocks, or a base64 encoded PFX as written by the Azure KeyVault provider of the CSI driver.
+func (p *DirectoryProvider) GetLatestTLSCertificate(_ context.Context, certName string) (tls.Certificate, error) {
+	path := filepath.Join(p.dir, certName)
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "could not read certificate file")
+	}
+
+	contentType := pkcs12ContentType
+	if block, _ := pem.Decode(bs); block != nil {
+		contentType = pemContentType
+	}
+
+	pemBlocks, err := getPEMBlocks(contentType, string(bs))
+	if err != nil {
+		return tls.Certificate{}, errors.Wrapf(err, "could not get pem blocks of %s", path)
+	}
+
+	return tlsCertificateFromPEMBlocks(pemBlocks)
+}
@@ -0,0 +1,34 @@
+package keyvault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryProvider(t *testing.T) {
+	dir := t.TempDir()
+	pemBytes, err := os.ReadFile("testdata/dummy.pem")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pem"), pemBytes, 0o600))
+	// base64 encoded, like the PFX certificates written by the CSI driver
+	pfxBytes, err := os.ReadFile("testdata/dummy.pfx")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pfx"), pfxBytes, 0o600))
+
+	p := NewDirectoryProvider(dir)
+	for _, name := range []string{"pem", "pfx"} {
+		t.Run(name, func(t *testing.T) {
+			cert, err := p.GetLatestTLSCertificate(context.TODO(), name)
+			require.NoError(t, err)
+			assert.NotNil(t, cert.Leaf)
+		})
+	}
+
+	_, err = p.GetLatestTLSCertificate(context.TODO(), "missing")
+	require.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package keyvault
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// caCertKey is the key of the CA certificate in TLS Secrets issued by cert-manager.
+const caCertKey = "ca.crt"
+
+type secretGetter interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Secret, error)
+}
+
+// SecretProvider provides the certificates stored in Kubernetes TLS Secrets, named by the certificate name.
+type SecretProvider struct {
+	secrets secretGetter
+}
+
+// NewSecretProvider constructs a SecretProvider for the Secrets of a namespace, such as
+// clientset.CoreV1().Secrets(namespace).
+func NewSecretProvider(secrets secretGetter) *SecretProvider {
+	return &SecretProvider{secrets: secrets}
+}
+
+// GetLatestTLSCertificate gets the Secret and transforms its tls.crt, tls.key and, if present, ca.crt into a
+// usable tls.Certificate.
+func (p *SecretProvider) GetLatestTLSCertificate(ctx context.Context, certName string) (tls.Certificate, error) {
+	secret, err := p.secrets.Get(ctx, certName, metav1.GetOptions{})
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "could not get secret")
+	}
+
+	var payload []byte
+	for _, key := range []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey, caCertKey} {
+		payload = append(payload, secret.Data[key]...)
+		payload = append(payload, '\n')
+	}
+
+	pemBlocks, err := handlePEMBytes(string(payload))
+	if err != nil {
+		return tls.Certificate{}, errors.Wrapf(err, "could not get pem blocks of secret %s", certName)
+	}
+
+	return tlsCertificateFromPEMBlocks(pemBlocks)
+}
@@ -0,0 +1,50 @@
+package keyvault
+
+import (
+	"context"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeSecretGetter map[string]*corev1.Secret
+
+func (f fakeSecretGetter) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	secret, ok := f[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	return secret, nil
+}
+
+func TestSecretProvider(t *testing.T) {
+	bs, err := os.ReadFile("testdata/dummy.pem")
+	require.NoError(t, err)
+	data := map[string][]byte{}
+	for block, rest := pem.Decode(bs); block != nil; block, rest = pem.Decode(rest) {
+		key := corev1.TLSCertKey
+		if block.Type == "PRIVATE KEY" {
+			key = corev1.TLSPrivateKeyKey
+		}
+		data[key] = pem.EncodeToMemory(block)
+	}
+
+	p := NewSecretProvider(fakeSecretGetter{
+		"cns-tls": {Type: corev1.SecretTypeTLS, Data: data},
+	})
+
+	cert, err := p.GetLatestTLSCertificate(context.TODO(), "cns-tls")
+	require.NoError(t, err)
+	assert.NotNil(t, cert.Leaf)
+	assert.NotNil(t, cert.PrivateKey)
+
+	_, err = p.GetLatestTLSCertificate(context.TODO(), "missing")
+	require.Error(t, err)
+}
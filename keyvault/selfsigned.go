@@ -0,0 +1,85 @@
+package keyvault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultSelfSignedValidity is how long self-signed certificates are valid for by default.
+const DefaultSelfSignedValidity = 7 * 24 * time.Hour
+
+// SelfSignedProvider provides self-signed certificates, to bootstrap TLS on dev clusters without a
+// certificate authority. It issues a new certificate once half of the validity of the current one has passed,
+// so that it's rotated by a CertRefresher well before it expires.
+type SelfSignedProvider struct {
+	dnsNames []string
+	validity time.Duration
+
+	m    sync.Mutex
+	cert *tls.Certificate
+}
+
+// NewSelfSignedProvider constructs a SelfSignedProvider issuing certificates for the DNS names, valid for the
+// validity, or DefaultSelfSignedValidity if it's 0.
+func NewSelfSignedProvider(dnsNames []string, validity time.Duration) *SelfSignedProvider {
+	if validity == 0 {
+		validity = DefaultSelfSignedValidity
+	}
+	return &SelfSignedProvider{dnsNames: dnsNames, validity: validity}
+}
+
+// GetLatestTLSCertificate returns the current self-signed certificate, with the certificate name as common name,
+// or issues a new one if it's past half of its validity.
+func (p *SelfSignedProvider) GetLatestTLSCertificate(_ context.Context, certName string) (tls.Certificate, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.cert != nil && p.cert.Leaf.Subject.CommonName == certName && time.Now().Before(p.cert.Leaf.NotBefore.Add(p.validity/2)) {
+		return *p.cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "could not generate private key")
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)) //nolint:gomnd // 128 bit serial number
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "could not generate serial number")
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: certName},
+		DNSNames:     p.dnsNames,
+		NotBefore:    now,
+		NotAfter:     now.Add(p.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "could not create certificate")
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "could not parse certificate")
+	}
+
+	p.cert = &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	return *p.cert, nil
+}
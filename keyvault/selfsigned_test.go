@@ -0,0 +1,31 @@
+package keyvault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfSignedProvider(t *testing.T) {
+	p := NewSelfSignedProvider([]string{"localhost"}, time.Hour)
+
+	cert, err := p.GetLatestTLSCertificate(context.TODO(), "azure-cns")
+	require.NoError(t, err)
+	assert.Equal(t, "azure-cns", cert.Leaf.Subject.CommonName)
+	assert.Equal(t, []string{"localhost"}, cert.Leaf.DNSNames)
+	require.NoError(t, cert.Leaf.VerifyHostname("localhost"))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cert.Leaf.NotAfter, time.Minute)
+
+	// the certificate is kept until half of its validity has passed
+	again, err := p.GetLatestTLSCertificate(context.TODO(), "azure-cns")
+	require.NoError(t, err)
+	assert.True(t, cert.Leaf.Equal(again.Leaf))
+
+	p.cert.Leaf.NotBefore = time.Now().Add(-time.Hour / 2)
+	renewed, err := p.GetLatestTLSCertificate(context.TODO(), "azure-cns")
+	require.NoError(t, err)
+	assert.False(t, cert.Leaf.Equal(renewed.Leaf))
+}
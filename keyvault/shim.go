@@ -55,7 +55,14 @@ func (s *Shim) GetLatestTLSCertificate(ctx context.Context, certName string) (tl
 		return tls.Certificate{}, errors.Wrap(err, "could not get pem blocks")
 	}
 
+	return tlsCertificateFromPEMBlocks(pemBlocks)
+}
+
+// tlsCertificateFromPEMBlocks builds a tls.Certificate from the private key, leaf certificate and CA
+// certificates among the PEM blocks.
+func tlsCertificateFromPEMBlocks(pemBlocks []*pem.Block) (tls.Certificate, error) {
 	var (
+		err       error
 		key       crypto.PrivateKey
 		leaf      *x509.Certificate
 		leafBytes []byte
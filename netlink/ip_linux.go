@@ -232,7 +232,12 @@ func setIpRoute(route *Route, add bool) error {
 
 	msg := newRtMsg(route.Family)
 	msg.Tos = uint8(route.Tos)
-	msg.Table = uint8(route.Table)
+	// Table ids above 255 (e.g. VRF tables) don't fit in the header and are passed as an attribute.
+	if route.Table < 256 {
+		msg.Table = uint8(route.Table)
+	} else {
+		msg.Table = unix.RT_TABLE_UNSPEC
+	}
 
 	if route.Protocol != 0 {
 		msg.Protocol = uint8(route.Protocol)
@@ -276,6 +281,10 @@ func setIpRoute(route *Route, add bool) error {
 		req.addPayload(newAttributeUint32(unix.RTA_IIF, uint32(route.ILinkIndex)))
 	}
 
+	if route.Table >= 256 {
+		req.addPayload(newAttributeUint32(unix.RTA_TABLE, uint32(route.Table)))
+	}
+
 	return s.sendAndWaitForAck(req)
 }
 
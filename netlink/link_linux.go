@@ -9,6 +9,8 @@ package netlink
 import (
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/pkg/errors"
@@ -21,6 +23,8 @@ const (
 	LINK_TYPE_VETH   = "veth"
 	LINK_TYPE_IPVLAN = "ipvlan"
 	LINK_TYPE_DUMMY  = "dummy"
+	LINK_TYPE_VLAN   = "vlan"
+	LINK_TYPE_VRF    = "vrf"
 )
 
 // IPVLAN link attributes.
@@ -81,6 +85,18 @@ type DummyLink struct {
 	LinkInfo
 }
 
+// VlanLink represents an 802.1Q vlan interface on top of the parent interface.
+type VlanLink struct {
+	LinkInfo
+	VlanID uint16
+}
+
+// VRFLink represents a virtual routing and forwarding device bound to a routing table.
+type VRFLink struct {
+	LinkInfo
+	Table uint32
+}
+
 // AddLink adds a new network interface of a specified type.
 func (Netlink) AddLink(link Link) error {
 	info := link.Info()
@@ -151,6 +167,18 @@ func (Netlink) AddLink(link Link) error {
 		attrData := newAttribute(IFLA_INFO_DATA, nil)
 		attrData.addNested(newAttributeUint16(IFLA_IPVLAN_MODE, uint16(ipvlan.Mode)))
 
+		attrLinkInfo.addNested(attrData)
+	} else if vlan, ok := link.(*VlanLink); ok {
+		// Set vlan attributes.
+		attrData := newAttribute(IFLA_INFO_DATA, nil)
+		attrData.addNested(newAttributeUint16(IFLA_VLAN_ID, vlan.VlanID))
+
+		attrLinkInfo.addNested(attrData)
+	} else if vrf, ok := link.(*VRFLink); ok {
+		// Set VRF attributes.
+		attrData := newAttribute(IFLA_INFO_DATA, nil)
+		attrData.addNested(newAttributeUint32(IFLA_VRF_TABLE, vrf.Table))
+
 		attrLinkInfo.addNested(attrData)
 	}
 
@@ -303,6 +331,20 @@ func (Netlink) SetLinkMaster(name string, master string) error {
 	return s.sendAndWaitForAck(req)
 }
 
+// GetLinkMaster returns the name of the master (upper) device of a network interface,
+// or an empty string if the interface is not enslaved.
+func (Netlink) GetLinkMaster(name string) (string, error) {
+	master, err := os.Readlink(filepath.Join("/sys/class/net", name, "master"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to read master of %s", name)
+	}
+
+	return filepath.Base(master), nil
+}
+
 // SetLinkNetNs sets the network namespace of a network interface.
 func (Netlink) SetLinkNetNs(name string, fd uintptr) error {
 	s, err := getSocket()
@@ -422,6 +464,58 @@ func (Netlink) SetLinkHairpin(bridgeName string, on bool) error {
 	return s.sendAndWaitForAck(req)
 }
 
+// SetVFConfig sets the mac address, vlan and trust mode of a virtual function of the physical function pfName.
+func (Netlink) SetVFConfig(pfName string, vf VFConfig) error {
+	s, err := getSocket()
+	if err != nil {
+		return err
+	}
+
+	iface, err := net.InterfaceByName(pfName)
+	if err != nil {
+		return err
+	}
+
+	req := newRequest(unix.RTM_SETLINK, unix.NLM_F_ACK)
+
+	ifInfo := newIfInfoMsg()
+	ifInfo.Type = unix.RTM_SETLINK
+	ifInfo.Index = int32(iface.Index)
+	ifInfo.Flags = unix.NLM_F_REQUEST
+	ifInfo.Change = DEFAULT_CHANGE
+	req.addPayload(ifInfo)
+
+	// struct ifla_vf_mac { __u32 vf; __u8 mac[32]; }
+	vfMac := make([]byte, 36)
+	encoder.PutUint32(vfMac[0:4], uint32(vf.Index))
+	copy(vfMac[4:], vf.MacAddress)
+
+	// struct ifla_vf_vlan { __u32 vf; __u32 vlan; __u32 qos; }
+	vfVlan := make([]byte, 12)
+	encoder.PutUint32(vfVlan[0:4], uint32(vf.Index))
+	encoder.PutUint32(vfVlan[4:8], uint32(vf.VlanID))
+
+	// struct ifla_vf_trust { __u32 vf; __u32 setting; }
+	vfTrust := make([]byte, 8)
+	encoder.PutUint32(vfTrust[0:4], uint32(vf.Index))
+	if vf.Trust {
+		encoder.PutUint32(vfTrust[4:8], 1)
+	}
+
+	attrVfInfo := newAttribute(unix.IFLA_VF_INFO|unix.NLA_F_NESTED, nil)
+	if len(vf.MacAddress) > 0 {
+		attrVfInfo.addNested(newAttribute(unix.IFLA_VF_MAC, vfMac))
+	}
+	attrVfInfo.addNested(newAttribute(unix.IFLA_VF_VLAN, vfVlan))
+	attrVfInfo.addNested(newAttribute(unix.IFLA_VF_TRUST, vfTrust))
+
+	attrVfInfoList := newAttribute(unix.IFLA_VFINFO_LIST|unix.NLA_F_NESTED, nil)
+	attrVfInfoList.addNested(attrVfInfo)
+	req.addPayload(attrVfInfoList)
+
+	return s.sendAndWaitForAck(req)
+}
+
 // SetOrRemoveLinkAddress sets/removes static arp entry based on mode
 func (Netlink) SetOrRemoveLinkAddress(linkInfo LinkInfo, mode, linkState int) error {
 	s, err := getSocket()
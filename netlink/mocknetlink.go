@@ -23,6 +23,10 @@ type MockNetlink struct {
 	deleteRouteFn routeValidateFn
 	addRouteFn    routeValidateFn
 	DeleteLinkFn  func(name string) error
+	// GetLinkMasterFn overrides the master returned by GetLinkMaster
+	GetLinkMasterFn func(name string) (string, error)
+	// SetVFConfigFn validates the virtual function configuration passed to SetVFConfig
+	SetVFConfigFn func(pfName string, vf VFConfig) error
 }
 
 func NewMockNetlink(returnError bool, errorString string) *MockNetlink {
@@ -78,6 +82,13 @@ func (f *MockNetlink) SetLinkMaster(string, string) error {
 	return f.error()
 }
 
+func (f *MockNetlink) GetLinkMaster(name string) (string, error) {
+	if f.GetLinkMasterFn != nil {
+		return f.GetLinkMasterFn(name)
+	}
+	return "", f.error()
+}
+
 func (f *MockNetlink) SetLinkNetNs(string, uintptr) error {
 	return f.error()
 }
@@ -94,6 +105,13 @@ func (f *MockNetlink) SetLinkHairpin(string, bool) error {
 	return f.error()
 }
 
+func (f *MockNetlink) SetVFConfig(pfName string, vf VFConfig) error {
+	if f.SetVFConfigFn != nil {
+		return f.SetVFConfigFn(pfName, vf)
+	}
+	return f.error()
+}
+
 func (f *MockNetlink) SetOrRemoveLinkAddress(LinkInfo, int, int) error {
 	return f.error()
 }
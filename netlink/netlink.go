@@ -2,6 +2,14 @@ package netlink
 
 type Netlink struct{}
 
+// VFConfig is the configuration of an SR-IOV virtual function, applied through its physical function.
+type VFConfig struct {
+	Index      int
+	MacAddress []byte
+	VlanID     int
+	Trust      bool
+}
+
 func NewNetlink() *Netlink {
 	return &Netlink{}
 }
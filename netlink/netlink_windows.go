@@ -46,6 +46,10 @@ func (Netlink) SetLinkMaster(name string, master string) error {
 	return nil
 }
 
+func (Netlink) GetLinkMaster(name string) (string, error) {
+	return "", nil
+}
+
 func (Netlink) SetLinkNetNs(name string, fd uintptr) error {
 	return nil
 }
@@ -62,6 +66,10 @@ func (Netlink) SetLinkHairpin(bridgeName string, on bool) error {
 	return nil
 }
 
+func (Netlink) SetVFConfig(pfName string, vf VFConfig) error {
+	return nil
+}
+
 func (Netlink) SetOrRemoveLinkAddress(linkInfo LinkInfo, mode, linkState int) error {
 	return nil
 }
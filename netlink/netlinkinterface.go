@@ -14,10 +14,12 @@ type NetlinkInterface interface {
 	SetLinkState(name string, up bool) error
 	SetLinkMTU(name string, mtu int) error
 	SetLinkMaster(name string, master string) error
+	GetLinkMaster(name string) (string, error)
 	SetLinkNetNs(name string, fd uintptr) error
 	SetLinkAddress(ifName string, hwAddress net.HardwareAddr) error
 	SetLinkPromisc(ifName string, on bool) error
 	SetLinkHairpin(bridgeName string, on bool) error
+	SetVFConfig(pfName string, vf VFConfig) error
 	SetOrRemoveLinkAddress(linkInfo LinkInfo, mode, linkState int) error
 	AddIPAddress(ifName string, ipAddress net.IP, ipNet *net.IPNet) error
 	DeleteIPAddress(ifName string, ipAddress net.IP, ipNet *net.IPNet) error
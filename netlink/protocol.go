@@ -54,6 +54,8 @@ const (
 	IFLA_INFO_DATA   = 2
 	IFLA_NET_NS_FD   = 28
 	IFLA_IPVLAN_MODE = 1
+	IFLA_VLAN_ID     = 1
+	IFLA_VRF_TABLE   = 1
 	IFLA_BRPORT_MODE = 4
 	VETH_INFO_PEER   = 1
 	DEFAULT_CHANGE   = 0xFFFFFFFF
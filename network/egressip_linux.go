@@ -0,0 +1,153 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// EgressIPChain is the nat chain holding the snat rules of the pods of namespaces with a static egress ip
+	EgressIPChain = "AZURECNIEGRESSIP"
+	// egressIPTable is the routing table holding the default route used by the pods of namespaces with a static egress ip
+	egressIPTable         = 3000
+	egressIPRulePriority  = 3000
+	addEgressIPRuleCmd    = "ip rule add from %s lookup %d priority %d"
+	deleteEgressIPRuleCmd = "ip rule del from %s lookup %d priority %d"
+	// the main table is looked up ahead of the egress ip table without its default route, so that the pod keeps
+	// reaching the pods of the node through their host routes and the subnets of the node
+	mainTableRulePriority  = egressIPRulePriority - 1
+	addMainTableRuleCmd    = "ip rule add from %s lookup main suppress_prefixlength 0 priority %d"
+	deleteMainTableRuleCmd = "ip rule del from %s lookup main suppress_prefixlength 0 priority %d"
+)
+
+// egressIPClient programs the policy routing and snat which make a pod egress with the static egress ip of its namespace.
+// Namespace: VM
+type egressIPClient struct {
+	hostIfName     string
+	netlink        netlink.NetlinkInterface
+	netioshim      netio.NetIOInterface
+	plClient       platform.ExecClient
+	iptablesClient ipTablesClient
+}
+
+func newEgressIPClient(
+	hostIfName string,
+	nl netlink.NetlinkInterface,
+	nioc netio.NetIOInterface,
+	plc platform.ExecClient,
+	iptc ipTablesClient,
+) *egressIPClient {
+	return &egressIPClient{
+		hostIfName:     hostIfName,
+		netlink:        nl,
+		netioshim:      nioc,
+		plClient:       plc,
+		iptablesClient: iptc,
+	}
+}
+
+// addEgressIPRules routes the ipv4 addresses of the pod through the default route of the egress ip table and snats
+// them to the egress ip. The other routes of the main table still apply, and traffic to the pod subnets and to
+// excludedCidrs keeps its source address.
+func (client *egressIPClient) addEgressIPRules(ipAddresses []net.IPNet, egressIP, gateway net.IP, excludedCidrs []string) error {
+	logger.Info("Adding egress ip rules", zap.String("egressIP", egressIP.String()), zap.Any("ipAddresses", ipAddresses))
+
+	// ip route add default via <gateway> dev <host if> table <egress ip table>
+	_, defaultIPNet, _ := net.ParseCIDR(defaultGwCidr)
+	routeInfo := RouteInfo{
+		Dst:   *defaultIPNet,
+		Gw:    gateway,
+		Table: egressIPTable,
+	}
+	if err := addRoutes(client.netlink, client.netioshim, client.hostIfName, []RouteInfo{routeInfo}); err != nil {
+		return errors.Wrap(err, "failed to add egress ip default route")
+	}
+
+	if err := client.iptablesClient.CreateChain(iptables.V4, iptables.Nat, EgressIPChain); err != nil {
+		return errors.Wrap(err, "failed to create egress ip chain")
+	}
+	if err := client.iptablesClient.InsertIptableRule(iptables.V4, iptables.Nat, iptables.Postrouting, "", EgressIPChain); err != nil {
+		return errors.Wrap(err, "failed to jump to egress ip chain")
+	}
+
+	for _, ipAddr := range ipAddresses {
+		if ipAddr.IP.To4() == nil {
+			continue
+		}
+
+		subnet := net.IPNet{IP: ipAddr.IP.Mask(ipAddr.Mask), Mask: ipAddr.Mask}
+		for _, cidr := range append([]string{subnet.String()}, excludedCidrs...) {
+			// iptables -t nat -I AZURECNIEGRESSIP -d <cidr> -j RETURN
+			match := fmt.Sprintf("-d %s", cidr)
+			if err := client.iptablesClient.InsertIptableRule(iptables.V4, iptables.Nat, EgressIPChain, match, iptables.Return); err != nil {
+				return errors.Wrapf(err, "failed to exclude %s from egress ip snat", cidr)
+			}
+		}
+
+		// iptables -t nat -A AZURECNIEGRESSIP -s <pod ip> -j SNAT --to <egress ip>
+		if err := client.iptablesClient.AppendIptableRule(iptables.V4, iptables.Nat, EgressIPChain, egressIPSnatMatch(ipAddr.IP), egressIPSnatTarget(egressIP)); err != nil {
+			return errors.Wrap(err, "failed to add egress ip snat rule")
+		}
+
+		// ip rule add from <pod ip> lookup main suppress_prefixlength 0
+		// ip rule add from <pod ip> lookup <egress ip table>
+		// deleting first keeps the rules unique when the endpoint is added again
+		_, _ = client.plClient.ExecuteRawCommand(fmt.Sprintf(deleteMainTableRuleCmd, ipAddr.IP.String(), mainTableRulePriority))
+		if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(addMainTableRuleCmd, ipAddr.IP.String(), mainTableRulePriority)); err != nil {
+			return errors.Wrap(err, "failed to add main table policy routing rule")
+		}
+		_, _ = client.plClient.ExecuteRawCommand(fmt.Sprintf(deleteEgressIPRuleCmd, ipAddr.IP.String(), egressIPTable, egressIPRulePriority))
+		if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(addEgressIPRuleCmd, ipAddr.IP.String(), egressIPTable, egressIPRulePriority)); err != nil {
+			return errors.Wrap(err, "failed to add egress ip policy routing rule")
+		}
+	}
+	return nil
+}
+
+// deleteEgressIPRules removes the snat rules and policy routing rules of the pod ipv4 addresses.
+// The default route of the egress ip table and the excluded cidrs are shared by all pods and are left as is.
+func (client *egressIPClient) deleteEgressIPRules(ipAddresses []net.IPNet, egressIP net.IP) {
+	logger.Info("Deleting egress ip rules", zap.String("egressIP", egressIP.String()), zap.Any("ipAddresses", ipAddresses))
+	for _, ipAddr := range ipAddresses {
+		if ipAddr.IP.To4() == nil {
+			continue
+		}
+
+		if err := client.iptablesClient.DeleteIptableRule(iptables.V4, iptables.Nat, EgressIPChain, egressIPSnatMatch(ipAddr.IP), egressIPSnatTarget(egressIP)); err != nil {
+			logger.Error("Failed to delete egress ip snat rule", zap.String("ip", ipAddr.IP.String()), zap.Error(err))
+		}
+		if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(deleteEgressIPRuleCmd, ipAddr.IP.String(), egressIPTable, egressIPRulePriority)); err != nil {
+			logger.Error("Failed to delete egress ip policy routing rule", zap.String("ip", ipAddr.IP.String()), zap.Error(err))
+		}
+		if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(deleteMainTableRuleCmd, ipAddr.IP.String(), mainTableRulePriority)); err != nil {
+			logger.Error("Failed to delete main table policy routing rule", zap.String("ip", ipAddr.IP.String()), zap.Error(err))
+		}
+	}
+}
+
+func egressIPSnatMatch(ip net.IP) string {
+	return fmt.Sprintf("-s %s", ip.String())
+}
+
+func egressIPSnatTarget(egressIP net.IP) string {
+	return fmt.Sprintf("%s --to %s", iptables.Snat, egressIP.String())
+}
+
+// splitCidrs splits a comma separated list of cidrs, skipping empty entries
+func splitCidrs(cidrs string) []string {
+	result := []string{}
+	for _, cidr := range strings.Split(cidrs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			result = append(result, cidr)
+		}
+	}
+	return result
+}
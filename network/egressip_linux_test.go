@@ -0,0 +1,194 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingIPTablesClient records the iptables rules programmed through it as "<op> <table> <chain> <match> -j <target>".
+type recordingIPTablesClient struct {
+	rules []string
+}
+
+func (c *recordingIPTablesClient) record(op, tableName, chainName, match, target string) error {
+	c.rules = append(c.rules, op+" "+tableName+" "+chainName+" "+match+" -j "+target)
+	return nil
+}
+
+func (c *recordingIPTablesClient) InsertIptableRule(_, tableName, chainName, match, target string) error {
+	return c.record("-I", tableName, chainName, match, target)
+}
+
+func (c *recordingIPTablesClient) AppendIptableRule(_, tableName, chainName, match, target string) error {
+	return c.record("-A", tableName, chainName, match, target)
+}
+
+func (c *recordingIPTablesClient) DeleteIptableRule(_, tableName, chainName, match, target string) error {
+	return c.record("-D", tableName, chainName, match, target)
+}
+
+func (c *recordingIPTablesClient) CreateChain(_, tableName, chainName string) error {
+	c.rules = append(c.rules, "-N "+tableName+" "+chainName)
+	return nil
+}
+
+func (c *recordingIPTablesClient) RunCmd(_, _ string) error {
+	return nil
+}
+
+func TestEgressIPRules(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	var routes []netlink.Route
+	nl.SetAddRouteValidationFn(func(r *netlink.Route) error {
+		routes = append(routes, *r)
+		return nil
+	})
+	plc := platform.NewMockExecClient(false)
+	var cmds []string
+	plc.SetExecRawCommand(func(cmd string) (string, error) {
+		cmds = append(cmds, cmd)
+		return "", nil
+	})
+	iptc := &recordingIPTablesClient{}
+	nio := &mockNetIO{existingInterfaces: map[string]bool{"eth0": true}, err: errMockNetIOFail}
+	client := newEgressIPClient("eth0", nl, nio, plc, iptc)
+
+	ipAddresses := []net.IPNet{
+		{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+		{IP: net.ParseIP("fd00::5"), Mask: net.CIDRMask(64, ipv6Bits)},
+	}
+	egressIP := net.ParseIP("10.0.0.4")
+	require.NoError(t, client.addEgressIPRules(ipAddresses, egressIP, net.ParseIP("10.0.0.1"), splitCidrs("10.1.0.0/16, ")))
+
+	require.Len(t, routes, 1)
+	require.Equal(t, "0.0.0.0/0", routes[0].Dst.String())
+	require.Equal(t, "10.0.0.1", routes[0].Gw.String())
+	require.Equal(t, egressIPTable, routes[0].Table)
+
+	require.Equal(t, []string{
+		"-N " + iptables.Nat + " " + EgressIPChain,
+		"-I " + iptables.Nat + " " + iptables.Postrouting + "  -j " + EgressIPChain,
+		"-I " + iptables.Nat + " " + EgressIPChain + " -d 10.0.0.0/24 -j " + iptables.Return,
+		"-I " + iptables.Nat + " " + EgressIPChain + " -d 10.1.0.0/16 -j " + iptables.Return,
+		"-A " + iptables.Nat + " " + EgressIPChain + " -s 10.0.0.5 -j SNAT --to 10.0.0.4",
+	}, iptc.rules)
+	require.Equal(t, []string{
+		"ip rule del from 10.0.0.5 lookup main suppress_prefixlength 0 priority 2999",
+		"ip rule add from 10.0.0.5 lookup main suppress_prefixlength 0 priority 2999",
+		"ip rule del from 10.0.0.5 lookup 3000 priority 3000",
+		"ip rule add from 10.0.0.5 lookup 3000 priority 3000",
+	}, cmds)
+
+	iptc.rules, cmds = nil, nil
+	client.deleteEgressIPRules(ipAddresses, egressIP)
+	require.Equal(t, []string{"-D " + iptables.Nat + " " + EgressIPChain + " -s 10.0.0.5 -j SNAT --to 10.0.0.4"}, iptc.rules)
+	require.Equal(t, []string{
+		"ip rule del from 10.0.0.5 lookup 3000 priority 3000",
+		"ip rule del from 10.0.0.5 lookup main suppress_prefixlength 0 priority 2999",
+	}, cmds)
+}
+
+// policyRoute is a route of a routing table, as seen by lookupPolicyRoute.
+type policyRoute struct {
+	dst *net.IPNet
+	dev string
+}
+
+// lookupPolicyRoute resolves the device a packet from src to dst leaves through, following the "ip rule add" commands
+// in cmds and then the main table, like the kernel does with the rules it has by default.
+func lookupPolicyRoute(t *testing.T, cmds []string, tables map[string][]policyRoute, src, dst net.IP) string {
+	type rule struct {
+		from     net.IP
+		table    string
+		suppress int
+		priority int
+	}
+	var rules []rule
+	for _, cmd := range cmds {
+		var r rule
+		var from string
+		if _, err := fmt.Sscanf(cmd, "ip rule add from %s lookup %s suppress_prefixlength %d priority %d", &from, &r.table, &r.suppress, &r.priority); err != nil {
+			r.suppress = -1
+			if _, err := fmt.Sscanf(cmd, "ip rule add from %s lookup %s priority %d", &from, &r.table, &r.priority); err != nil {
+				continue
+			}
+		}
+		r.from = net.ParseIP(from)
+		rules = append(rules, r)
+	}
+	rules = append(rules, rule{table: "main", suppress: -1, priority: 32766})
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].priority < rules[j].priority })
+
+	for _, r := range rules {
+		if r.from != nil && !r.from.Equal(src) {
+			continue
+		}
+		var best *policyRoute
+		for i, route := range tables[r.table] {
+			ones, _ := route.dst.Mask.Size()
+			if !route.dst.Contains(dst) || ones <= r.suppress {
+				continue
+			}
+			if best == nil {
+				best = &tables[r.table][i]
+			} else if bestOnes, _ := best.dst.Mask.Size(); ones > bestOnes {
+				best = &tables[r.table][i]
+			}
+		}
+		if best != nil {
+			return best.dev
+		}
+	}
+	t.Fatalf("no route from %s to %s", src, dst)
+	return ""
+}
+
+func TestEgressIPRulesKeepSameNodeReachability(t *testing.T) {
+	plc := platform.NewMockExecClient(false)
+	var cmds []string
+	plc.SetExecRawCommand(func(cmd string) (string, error) {
+		cmds = append(cmds, cmd)
+		return "", nil
+	})
+	nio := &mockNetIO{existingInterfaces: map[string]bool{"eth0": true}, err: errMockNetIOFail}
+	client := newEgressIPClient("eth0", netlink.NewMockNetlink(false, ""), nio, plc, &recordingIPTablesClient{})
+
+	podIP := net.ParseIP("10.0.0.5")
+	ipAddresses := []net.IPNet{{IP: podIP, Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)}}
+	require.NoError(t, client.addEgressIPRules(ipAddresses, net.ParseIP("10.0.0.4"), net.ParseIP("10.0.0.1"), nil))
+
+	mustCIDR := func(cidr string) *net.IPNet {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		return ipNet
+	}
+	tables := map[string][]policyRoute{
+		"main": {
+			{dst: mustCIDR("0.0.0.0/0"), dev: "eth0"},
+			{dst: mustCIDR("10.0.0.0/24"), dev: "eth0"},
+			{dst: mustCIDR("10.0.0.6/32"), dev: "azvpeer"},
+		},
+		strconv.Itoa(egressIPTable): {
+			{dst: mustCIDR("0.0.0.0/0"), dev: "egress"},
+		},
+	}
+
+	// a pod on the same node is reached through its host veth rather than through the egress ip table
+	require.Equal(t, "azvpeer", lookupPolicyRoute(t, cmds, tables, podIP, net.ParseIP("10.0.0.6")))
+	require.Equal(t, "eth0", lookupPolicyRoute(t, cmds, tables, podIP, net.ParseIP("10.0.0.7")))
+	// only the default route is taken from the egress ip table
+	require.Equal(t, "egress", lookupPolicyRoute(t, cmds, tables, podIP, net.ParseIP("8.8.8.8")))
+	// the other pods of the node are left as is
+	require.Equal(t, "eth0", lookupPolicyRoute(t, cmds, tables, net.ParseIP("10.0.0.6"), net.ParseIP("8.8.8.8")))
+}
@@ -38,11 +38,16 @@ type endpoint struct {
 	MacAddress               net.HardwareAddr
 	InfraVnetIP              net.IPNet
 	LocalIP                  string
+	LocalIPv6                string `json:",omitempty"`
 	IPAddresses              []net.IPNet
 	Gateways                 []net.IP
 	DNS                      DNSInfo
 	Routes                   []RouteInfo
 	VlanID                   int
+	VlanIsolationMode        string `json:",omitempty"`
+	EgressIP                 net.IP `json:",omitempty"`
+	EgressGateway            net.IP `json:",omitempty"`
+	VnetCidrs                string `json:",omitempty"`
 	EnableSnatOnHost         bool
 	EnableInfraVnet          bool
 	EnableMultitenancy       bool
@@ -59,6 +64,9 @@ type endpoint struct {
 	SecondaryInterfaces map[string]*InterfaceInfo
 	// Store nic type since we no longer populate SecondaryInterfaces
 	NICType cns.NICType
+	// PnPID and VirtualFunction identify the SR-IOV virtual function of a backend NIC moved into the container
+	PnPID           string               `json:",omitempty"`
+	VirtualFunction *cns.VirtualFunction `json:",omitempty"`
 }
 
 // EndpointInfo contains read-only information about an endpoint.
@@ -91,6 +99,9 @@ type EndpointInfo struct {
 	InfraVnetAddressSpace    string
 	SkipHotAttachEp          bool
 	IPV6Mode                 string
+	VlanIsolationMode        string
+	EgressIP                 net.IP // static egress ip of the pod namespace, nil if the namespace has none
+	EgressGateway            net.IP
 	VnetCidrs                string
 	ServiceCidrs             string
 	NATInfo                  []policy.NATInfo // windows only
@@ -112,6 +123,7 @@ type EndpointInfo struct {
 	IsIPv6Enabled                 bool
 	HostSubnetPrefix              string // can be used later to add an external interface
 	PnPID                         string
+	VirtualFunction               *cns.VirtualFunction // linux only, nil if the backend NIC is not an SR-IOV virtual function
 }
 
 // RouteInfo contains information about an IP route.
@@ -138,7 +150,10 @@ type InterfaceInfo struct {
 	HostSubnetPrefix  net.IPNet // Move this field from ipamAddResult
 	NCResponse        *cns.GetNetworkContainerResponse
 	PnPID             string
+	VirtualFunction   *cns.VirtualFunction
 	EndpointPolicies  []policy.Policy
+	EgressIP          net.IP
+	EgressGateway     net.IP
 }
 
 type IPConfig struct {
@@ -321,6 +336,12 @@ func (ep *endpoint) getInfo() *EndpointInfo {
 		HNSEndpointID:            ep.HnsId,
 		HostIfName:               ep.HostIfName,
 		NICType:                  ep.NICType,
+		VlanIsolationMode:        ep.VlanIsolationMode,
+		EgressIP:                 ep.EgressIP,
+		EgressGateway:            ep.EgressGateway,
+		VnetCidrs:                ep.VnetCidrs,
+		PnPID:                    ep.PnPID,
+		VirtualFunction:          ep.VirtualFunction,
 	}
 
 	info.Routes = append(info.Routes, ep.Routes...)
@@ -65,6 +65,7 @@ func (nw *network) newEndpointImpl(
 		hostIfName  string
 		contIfName  string
 		localIP     string
+		localIPv6   string
 		vlanid      = 0
 		containerIf *net.Interface
 	)
@@ -75,6 +76,10 @@ func (nw *network) newEndpointImpl(
 		return nil, err
 	}
 
+	if !isValidVlanIsolationMode(epInfo.VlanIsolationMode) {
+		return nil, fmt.Errorf("%w: %q", errVlanIsolationModeInvalid, epInfo.VlanIsolationMode)
+	}
+
 	if epInfo.Data != nil {
 		if _, ok := epInfo.Data[VlanIDKey]; ok {
 			vlanid = epInfo.Data[VlanIDKey].(int)
@@ -83,6 +88,10 @@ func (nw *network) newEndpointImpl(
 		if _, ok := epInfo.Data[LocalIPKey]; ok {
 			localIP = epInfo.Data[LocalIPKey].(string)
 		}
+
+		if _, ok := epInfo.Data[LocalIPv6Key]; ok {
+			localIPv6 = epInfo.Data[LocalIPv6Key].(string)
+		}
 	}
 
 	if _, ok := epInfo.Data[OptVethName]; ok {
@@ -113,9 +122,14 @@ func (nw *network) newEndpointImpl(
 		HostIfName:               hostIfName,
 		InfraVnetIP:              epInfo.InfraVnetIP,
 		LocalIP:                  localIP,
+		LocalIPv6:                localIPv6,
 		IPAddresses:              epInfo.IPAddresses,
 		DNS:                      epInfo.EndpointDNS,
 		VlanID:                   vlanid,
+		VlanIsolationMode:        epInfo.VlanIsolationMode,
+		EgressIP:                 epInfo.EgressIP,
+		EgressGateway:            epInfo.EgressGateway,
+		VnetCidrs:                epInfo.VnetCidrs,
 		EnableSnatOnHost:         epInfo.EnableSnatOnHost,
 		EnableInfraVnet:          epInfo.EnableInfraVnet,
 		EnableMultitenancy:       epInfo.EnableMultiTenancy,
@@ -128,6 +142,8 @@ func (nw *network) newEndpointImpl(
 		Routes:                   epInfo.Routes,
 		SecondaryInterfaces:      make(map[string]*InterfaceInfo),
 		NICType:                  epInfo.NICType,
+		PnPID:                    epInfo.PnPID,
+		VirtualFunction:          epInfo.VirtualFunction,
 	}
 	if nw.extIf != nil {
 		ep.Gateways = []net.IP{nw.extIf.IPv4Gateway}
@@ -144,7 +160,10 @@ func (nw *network) newEndpointImpl(
 				if _, ok := epInfo.Data[SnatBridgeIPKey]; ok {
 					nw.SnatBridgeIP = epInfo.Data[SnatBridgeIPKey].(string)
 				}
-				epClient = NewTransparentVlanEndpointClient(nw, epInfo, hostIfName, contIfName, vlanid, localIP, nl, plc, nsc, iptc)
+				if _, ok := epInfo.Data[SnatBridgeIPv6Key]; ok {
+					nw.SnatBridgeIPv6 = epInfo.Data[SnatBridgeIPv6Key].(string)
+				}
+				epClient = newTransparentVlanClient(nw, epInfo, hostIfName, contIfName, vlanid, localIP, localIPv6, nl, plc, nsc, iptc)
 			} else {
 				logger.Info("OVS client")
 				if _, ok := epInfo.Data[SnatBridgeIPKey]; ok {
@@ -163,6 +182,9 @@ func (nw *network) newEndpointImpl(
 					plc,
 					iptc)
 			}
+		} else if epInfo.NICType == cns.BackendNIC && epInfo.VirtualFunction != nil {
+			logger.Info("SR-IOV client")
+			epClient = NewSRIOVEndpointClient(nl, netioCli, plc, nsc, ep)
 		} else if nw.Mode != opModeTransparent {
 			logger.Info("Bridge client")
 			epClient = NewLinuxBridgeEndpointClient(nw.extIf, hostIfName, contIfName, nw.Mode, nl, plc)
@@ -182,6 +204,9 @@ func (nw *network) newEndpointImpl(
 			logger.Error("CNI error. Delete Endpoint and rules that are created", zap.Error(err), zap.String("contIfName", contIfName))
 			if containerIf != nil {
 				client.DeleteEndpointRules(ep)
+				if ep.EgressIP != nil {
+					newEgressIPClient(nw.extIf.Name, nl, netioCli, plc, iptc).deleteEgressIPRules(ep.IPAddresses, ep.EgressIP)
+				}
 			}
 			// set deleteHostVeth to true to cleanup host veth interface if created
 			//nolint:errcheck // ignore error
@@ -210,6 +235,14 @@ func (nw *network) newEndpointImpl(
 			return epErr
 		}
 
+		// Route the pod through the static egress ip of its namespace
+		if epInfo.EgressIP != nil {
+			egressClient := newEgressIPClient(nw.extIf.Name, nl, netioCli, plc, iptc)
+			if epErr := egressClient.addEgressIPRules(epInfo.IPAddresses, epInfo.EgressIP, epInfo.EgressGateway, splitCidrs(epInfo.VnetCidrs)); epErr != nil {
+				return epErr
+			}
+		}
+
 		// If a network namespace for the container interface is specified...
 		if epInfo.NetNsPath != "" {
 			// Open the network namespace.
@@ -239,7 +272,7 @@ func (nw *network) newEndpointImpl(
 			}()
 		}
 
-		if epInfo.IPV6Mode != "" {
+		if epInfo.IPV6Mode != "" || epInfo.IsIPv6Enabled {
 			// Enable ipv6 setting in container
 			logger.Info("Enable ipv6 setting in container.")
 			nuc := networkutils.NewNetworkUtils(nl, plc)
@@ -278,10 +311,12 @@ func (nw *network) deleteEndpointImpl(nl netlink.NetlinkInterface, plc platform.
 		if ep.VlanID != 0 {
 			epInfo := ep.getInfo()
 			if nw.Mode == opModeTransparentVlan {
-				epClient = NewTransparentVlanEndpointClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, nl, plc, nsc, iptc)
+				epClient = newTransparentVlanClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, ep.LocalIPv6, nl, plc, nsc, iptc)
 			} else {
 				epClient = NewOVSEndpointClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, nl, ovsctl.NewOvsctl(), plc, iptc)
 			}
+		} else if ep.NICType == cns.BackendNIC && ep.VirtualFunction != nil {
+			epClient = NewSRIOVEndpointClient(nl, nioc, plc, nsc, ep)
 		} else if nw.Mode != opModeTransparent {
 			epClient = NewLinuxBridgeEndpointClient(nw.extIf, ep.HostIfName, "", nw.Mode, nl, plc)
 		} else {
@@ -302,6 +337,9 @@ func (nw *network) deleteEndpointImpl(nl netlink.NetlinkInterface, plc platform.
 	}
 
 	epClient.DeleteEndpointRules(ep)
+	if ep.EgressIP != nil {
+		newEgressIPClient(nw.extIf.Name, nl, nioc, plc, iptc).deleteEgressIPRules(ep.IPAddresses, ep.EgressIP)
+	}
 	// deleteHostVeth set to false not to delete veth as CRI will remove network namespace and
 	// veth will get removed as part of that.
 	//nolint:errcheck // ignore error
@@ -392,6 +430,7 @@ func deleteRoutes(nl netlink.NetlinkInterface, netioshim netio.NetIOInterface, i
 			Gw:        route.Gw,
 			Protocol:  route.Protocol,
 			Scope:     route.Scope,
+			Table:     route.Table,
 		}
 
 		logger.Info("Deleting IP route from link", zap.Any("route", route), zap.String("interfaceName", interfaceName))
@@ -88,6 +88,9 @@ type networkManager struct {
 	nsClient           NamespaceClientInterface
 	iptablesClient     ipTablesClient
 	dhcpClient         dhcpClient
+	// vlanEndpointClient builds the endpoint client of transparent-vlan endpoints during reconciliation,
+	// newTransparentVlanClient is used if it is nil
+	vlanEndpointClient func(nw *network, ep *endpoint, epInfo *EndpointInfo) EndpointClient
 	sync.Mutex
 }
 
@@ -121,6 +124,7 @@ type NetworkManager interface {
 	DeleteState(epInfos []*EndpointInfo) error
 	GetEndpointInfosFromContainerID(containerID string) []*EndpointInfo
 	GetEndpointState(networkID, containerID string) ([]*EndpointInfo, error)
+	ReconcileEndpoints() (*ReconcileReport, error)
 }
 
 // Creates a new network manager.
@@ -538,6 +542,8 @@ func (nm *networkManager) DeleteEndpointState(networkID string, epInfo *Endpoint
 		NetNs:                    dummyGUID,                 // to trigger hnsv2, windows
 		NICType:                  epInfo.NICType,
 		IfName:                   epInfo.IfName, // TODO: For stateless cni linux populate IfName here to use in deletion in secondary endpoint client
+		PnPID:                    epInfo.PnPID,
+		VirtualFunction:          epInfo.VirtualFunction,
 	}
 	logger.Info("Deleting endpoint with", zap.String("Endpoint Info: ", epInfo.PrettyString()), zap.String("HNISID : ", ep.HnsId))
 
@@ -809,6 +815,8 @@ func cnsEndpointInfotoCNIEpInfos(endpointInfo restserver.EndpointInfo, endpointI
 		epInfo.NICType = ipInfo.NICType
 		epInfo.HNSNetworkID = ipInfo.HnsNetworkID
 		epInfo.MacAddress = net.HardwareAddr(ipInfo.MacAddress)
+		epInfo.PnPID = ipInfo.PnPID
+		epInfo.VirtualFunction = ipInfo.VirtualFunction
 		ret = append(ret, epInfo)
 	}
 	return ret
@@ -837,11 +845,13 @@ func generateCNSIPInfoMap(eps []*endpoint) map[string]*restserver.IPInfo {
 
 	for _, ep := range eps {
 		ifNametoIPInfoMap[ep.IfName] = &restserver.IPInfo{ // in windows, the nicname is args ifname, in linux, it's ethX
-			NICType:       ep.NICType,
-			HnsEndpointID: ep.HnsId,
-			HnsNetworkID:  ep.HNSNetworkID,
-			HostVethName:  ep.HostIfName,
-			MacAddress:    ep.MacAddress.String(),
+			NICType:         ep.NICType,
+			HnsEndpointID:   ep.HnsId,
+			HnsNetworkID:    ep.HNSNetworkID,
+			HostVethName:    ep.HostIfName,
+			MacAddress:      ep.MacAddress.String(),
+			PnPID:           ep.PnPID,
+			VirtualFunction: ep.VirtualFunction,
 		}
 	}
 
@@ -210,3 +210,7 @@ func (nm *MockNetworkManager) GetEndpointInfosFromContainerID(containerID string
 func (nm *MockNetworkManager) GetEndpointState(_, _ string) ([]*EndpointInfo, error) {
 	return []*EndpointInfo{}, nil
 }
+
+func (nm *MockNetworkManager) ReconcileEndpoints() (*ReconcileReport, error) {
+	return &ReconcileReport{Checked: len(nm.TestEndpointInfoMap)}, nil
+}
@@ -5,9 +5,16 @@ package network
 
 import "github.com/pkg/errors"
 
-var errMockEnterNamespaceFailure = errors.New("failed to enter namespace")
+var (
+	errMockEnterNamespaceFailure = errors.New("failed to enter namespace")
+	errMockOpenNamespaceFailure  = errors.New("failed to open namespace")
+)
 
-const failToEnterNamespaceName = "failns"
+const (
+	failToEnterNamespaceName = "failns"
+	deletedNamespaceName     = "deletedns"
+	failToOpenNamespaceName  = "failopenns"
+)
 
 type MockNamespace struct {
 	namespace string
@@ -21,9 +28,12 @@ func NewMockNamespaceClient() *MockNamespaceClient {
 
 // OpenNamespace creates a new namespace object for the given netns path.
 func (c *MockNamespaceClient) OpenNamespace(ns string) (NamespaceInterface, error) {
-	if ns == "" {
+	if ns == "" || ns == deletedNamespaceName {
 		return nil, errFileNotExist
 	}
+	if ns == failToOpenNamespaceName {
+		return nil, errMockOpenNamespaceFailure
+	}
 	return &MockNamespace{namespace: ns}, nil
 }
 
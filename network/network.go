@@ -29,6 +29,17 @@ const (
 	IPV6Nat = "ipv6nat"
 )
 
+const (
+	// vlan isolation modes of transparent-vlan networks
+	VlanIsolationNetns = "netns"
+	VlanIsolationVRF   = "vrf"
+)
+
+// isValidVlanIsolationMode returns true if mode is empty (netns isolation) or one of the known vlan isolation modes.
+func isValidVlanIsolationMode(mode string) bool {
+	return mode == "" || mode == VlanIsolationNetns || mode == VlanIsolationVRF
+}
+
 // externalInterface is a host network interface that bridges containers to external networks.
 type externalInterface struct {
 	Name        string
@@ -56,6 +67,7 @@ type network struct {
 	EnableSnatOnHost bool
 	NetNs            string
 	SnatBridgeIP     string
+	SnatBridgeIPv6   string `json:",omitempty"`
 }
 
 // NetworkInfo contains read-only information about a container network. Use EndpointInfo instead when possible.
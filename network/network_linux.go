@@ -38,6 +38,10 @@ const (
 	SnatBridgeIPKey = "snatBridgeIP"
 	// LocalIPKey key for local IP
 	LocalIPKey = "localIP"
+	// SnatBridgeIPv6Key key for the IPv6 address of the SNAT bridge
+	SnatBridgeIPv6Key = "snatBridgeIPv6"
+	// LocalIPv6Key key for local IPv6
+	LocalIPv6Key = "localIPv6"
 	// InfraVnetIPKey key for infra vnet
 	InfraVnetIPKey = "infraVnetIP"
 	// Ubuntu Release Version for checking which command to use.
@@ -98,7 +102,15 @@ func (nm *networkManager) newNetworkImpl(nwInfo *EndpointInfo, extIf *externalIn
 		if err := nu.EnableIPV4Forwarding(); err != nil {
 			return nil, errors.Wrap(err, "ipv4 forwarding failed")
 		}
-		if err := nu.UpdateIPV6Setting(1); err != nil {
+		if nwInfo.IsIPv6Enabled {
+			// dual-stack networks route the ipv6 traffic of the pods through the vm
+			if err := nu.UpdateIPV6Setting(0); err != nil {
+				return nil, errors.Wrap(err, "failed to enable ipv6 on vm")
+			}
+			if err := nu.EnableIPV6Forwarding(); err != nil {
+				return nil, errors.Wrap(err, "ipv6 forwarding failed")
+			}
+		} else if err := nu.UpdateIPV6Setting(1); err != nil {
 			return nil, errors.Wrap(err, "failed to disable ipv6 on vm")
 		}
 		// Blocks wireserver traffic from apipa nic
@@ -0,0 +1,97 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package network
+
+import (
+	"net"
+
+	"go.uber.org/zap"
+)
+
+// ReconcileAction describes a single change made to the dataplane or the endpoint state during reconciliation.
+type ReconcileAction struct {
+	NetworkID   string
+	EndpointID  string
+	ContainerID string
+	NetNsPath   string
+	IPAddresses []net.IPNet
+	Reason      string
+}
+
+// ReconcileReport summarizes the result of ReconcileEndpoints.
+// Repaired lists host side objects that were missing and have been re-created for endpoints with a live netns.
+// Reapplied lists endpoints whose idempotent rules were applied again because missing rules can't be detected.
+// Removed lists endpoints whose netns no longer exists and whose state (and host side objects) were cleaned up.
+// The network manager doesn't own the IPAM state, so the caller releases the IP addresses of removed endpoints.
+// Failed lists endpoints that could not be reconciled.
+type ReconcileReport struct {
+	Checked   int
+	Repaired  []ReconcileAction
+	Reapplied []ReconcileAction
+	Removed   []ReconcileAction
+	Failed    []ReconcileAction
+}
+
+func (r *ReconcileReport) repaired(nw *network, ep *endpoint, reason string) {
+	r.Repaired = append(r.Repaired, newReconcileAction(nw, ep, reason))
+}
+
+func (r *ReconcileReport) reapplied(nw *network, ep *endpoint, reason string) {
+	r.Reapplied = append(r.Reapplied, newReconcileAction(nw, ep, reason))
+}
+
+func (r *ReconcileReport) removed(nw *network, ep *endpoint, reason string) {
+	r.Removed = append(r.Removed, newReconcileAction(nw, ep, reason))
+}
+
+func (r *ReconcileReport) failed(nw *network, ep *endpoint, reason string) {
+	r.Failed = append(r.Failed, newReconcileAction(nw, ep, reason))
+}
+
+func newReconcileAction(nw *network, ep *endpoint, reason string) ReconcileAction {
+	return ReconcileAction{
+		NetworkID:   nw.Id,
+		EndpointID:  ep.Id,
+		ContainerID: ep.ContainerID,
+		NetNsPath:   ep.NetworkNameSpace,
+		IPAddresses: ep.IPAddresses,
+		Reason:      reason,
+	}
+}
+
+// ReconcileEndpoints walks every persisted endpoint and verifies that the host side dataplane objects it describes
+// still exist. Missing objects are re-created for endpoints whose netns is alive, and the state of endpoints whose
+// netns is gone is removed. The resulting state is saved if anything was removed.
+func (nm *networkManager) ReconcileEndpoints() (*ReconcileReport, error) {
+	nm.Lock()
+	defer nm.Unlock()
+
+	report := &ReconcileReport{}
+	if nm.IsStatelessCNIMode() {
+		logger.Info("Skipping endpoint reconciliation in stateless CNI mode")
+		return report, nil
+	}
+
+	for _, extIf := range nm.ExternalInterfaces {
+		for _, nw := range extIf.Networks {
+			for _, ep := range nw.Endpoints {
+				report.Checked++
+				nm.reconcileEndpointImpl(nw, ep, report)
+			}
+		}
+	}
+
+	logger.Info("Endpoint reconciliation finished",
+		zap.Int("checked", report.Checked),
+		zap.Int("repaired", len(report.Repaired)),
+		zap.Int("reapplied", len(report.Reapplied)),
+		zap.Int("removed", len(report.Removed)),
+		zap.Int("failed", len(report.Failed)))
+
+	if len(report.Removed) == 0 {
+		return report, nil
+	}
+
+	return report, nm.save()
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// reconcileEndpointImpl verifies the host side objects of a single endpoint and records what was done in the report.
+func (nm *networkManager) reconcileEndpointImpl(nw *network, ep *endpoint, report *ReconcileReport) {
+	// endpoints without a netns (cnm) are owned by the container runtime
+	if ep.NetworkNameSpace == "" {
+		return
+	}
+
+	ns, err := nm.nsClient.OpenNamespace(ep.NetworkNameSpace)
+	if err != nil {
+		if !isNamespaceNotExist(err) {
+			report.failed(nw, ep, fmt.Sprintf("failed to open netns: %v", err))
+			return
+		}
+
+		logger.Info("Netns of endpoint no longer exists, removing endpoint",
+			zap.String("id", ep.Id), zap.String("netns", ep.NetworkNameSpace))
+		// host side cleanup is best effort, the endpoint is removed from state regardless
+		//nolint:errcheck // ignore error
+		nw.deleteEndpointImpl(nm.netlink, nm.plClient, nil, nm.netio, nm.nsClient, nm.iptablesClient, nm.dhcpClient, ep)
+		delete(nw.Endpoints, ep.Id)
+		report.removed(nw, ep, "netns no longer exists")
+		return
+	}
+	if err := ns.Close(); err != nil {
+		logger.Error("Failed to close netns", zap.String("netns", ep.NetworkNameSpace), zap.Error(err))
+	}
+
+	// delegated and backend nics are moved into the container netns as is and have no host side objects
+	if ep.NICType != "" && ep.NICType != cns.InfraNIC {
+		return
+	}
+
+	epInfo := ep.getInfo()
+
+	if ep.EgressIP != nil {
+		// the egress ip rules are inserted idempotently, so they are applied as a whole and reported as re-applied
+		egressClient := newEgressIPClient(nw.extIf.Name, nm.netlink, nm.netio, nm.plClient, nm.iptablesClient)
+		if err := egressClient.addEgressIPRules(ep.IPAddresses, ep.EgressIP, ep.EgressGateway, splitCidrs(ep.VnetCidrs)); err != nil {
+			report.failed(nw, ep, fmt.Sprintf("failed to re-apply egress ip rules: %v", err))
+			return
+		}
+		report.reapplied(nw, ep, "egress ip rules")
+	}
+
+	//nolint:gocritic
+	if ep.VlanID != 0 {
+		// ovs flows are not idempotent and can't be listed per endpoint, so they are left as is
+		if nw.Mode != opModeTransparentVlan {
+			return
+		}
+
+		epClient := nm.newVlanEndpointClient(nw, ep, epInfo)
+
+		// the ipTablesClient can't tell which rules are missing, but the transparent vlan rules are
+		// inserted idempotently, so they are applied as a whole and reported as re-applied
+		if err := epClient.AddEndpointRules(epInfo); err != nil {
+			report.failed(nw, ep, fmt.Sprintf("failed to re-apply endpoint rules: %v", err))
+			return
+		}
+		report.reapplied(nw, ep, "snat and vnet endpoint rules")
+	} else if nw.Mode != opModeTransparent {
+		if _, err := nm.netio.GetNetworkInterfaceByName(ep.HostIfName); err != nil {
+			report.failed(nw, ep, fmt.Sprintf("host veth %s is missing, the endpoint must be re-added", ep.HostIfName))
+			return
+		}
+
+		// the ebtables rules of the bridge client are appended rather than inserted idempotently,
+		// so only the bridge membership of the host veth is restored here
+		master, err := nm.netlink.GetLinkMaster(ep.HostIfName)
+		if err != nil {
+			report.failed(nw, ep, fmt.Sprintf("failed to get master of host veth: %v", err))
+			return
+		}
+		if master == nw.extIf.BridgeName {
+			return
+		}
+
+		if err := nm.netlink.SetLinkMaster(ep.HostIfName, nw.extIf.BridgeName); err != nil {
+			report.failed(nw, ep, fmt.Sprintf("failed to attach host veth to bridge: %v", err))
+			return
+		}
+		report.repaired(nw, ep, fmt.Sprintf("attached host veth %s to bridge %s", ep.HostIfName, nw.extIf.BridgeName))
+	} else {
+		nm.reconcileTransparentEndpoint(nw, ep, epInfo, report)
+	}
+}
+
+// newVlanEndpointClient returns the endpoint client used to re-apply the rules of a transparent-vlan endpoint.
+func (nm *networkManager) newVlanEndpointClient(nw *network, ep *endpoint, epInfo *EndpointInfo) EndpointClient {
+	if nm.vlanEndpointClient != nil {
+		return nm.vlanEndpointClient(nw, ep, epInfo)
+	}
+	return newTransparentVlanClient(nw, epInfo, ep.HostIfName, "", ep.VlanID, ep.LocalIP, ep.LocalIPv6, nm.netlink, nm.plClient, nm.nsClient, nm.iptablesClient)
+}
+
+// reconcileTransparentEndpoint re-creates the host routes that steer pod traffic into the host veth.
+func (nm *networkManager) reconcileTransparentEndpoint(nw *network, ep *endpoint, epInfo *EndpointInfo, report *ReconcileReport) {
+	hostIf, err := nm.netio.GetNetworkInterfaceByName(ep.HostIfName)
+	if err != nil {
+		report.failed(nw, ep, fmt.Sprintf("host veth %s is missing, the endpoint must be re-added", ep.HostIfName))
+		return
+	}
+
+	var missing []string
+	for _, ipAddr := range ep.IPAddresses {
+		ipNet := net.IPNet{IP: ipAddr.IP, Mask: net.CIDRMask(ipv4FullMask, ipv4Bits)}
+		if ipAddr.IP.To4() == nil {
+			ipNet.Mask = net.CIDRMask(ipv6FullMask, ipv6Bits)
+		}
+
+		filter := &netlink.Route{Family: netlink.GetIPAddressFamily(ipAddr.IP), Dst: &ipNet, LinkIndex: hostIf.Index}
+		routes, err := nm.netlink.GetIPRoute(filter)
+		if err != nil {
+			report.failed(nw, ep, fmt.Sprintf("failed to list host routes: %v", err))
+			return
+		}
+
+		if len(routes) == 0 {
+			missing = append(missing, ipNet.String())
+		}
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	epClient := NewTransparentEndpointClient(nw.extIf, ep.HostIfName, "", nw.Mode, nm.netlink, nm.netio, nm.plClient)
+	if err := epClient.AddEndpointRules(epInfo); err != nil {
+		report.failed(nw, ep, fmt.Sprintf("failed to re-add host routes: %v", err))
+		return
+	}
+
+	for _, dst := range missing {
+		report.repaired(nw, ep, fmt.Sprintf("host route %s dev %s", dst, ep.HostIfName))
+	}
+}
+
+func isNamespaceNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || errors.Is(err, errFileNotExist)
+}
@@ -0,0 +1,264 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
+)
+
+func newReconcileTestManager(mode string, nl netlink.NetlinkInterface, nio netio.NetIOInterface, eps ...*endpoint) (*networkManager, *network) {
+	extIf := &externalInterface{
+		Name:       "eth0",
+		BridgeName: "azure0",
+		Networks:   map[string]*network{},
+	}
+	nw := &network{
+		Id:        "azure",
+		Mode:      mode,
+		Endpoints: map[string]*endpoint{},
+		extIf:     extIf,
+	}
+	for _, ep := range eps {
+		nw.Endpoints[ep.Id] = ep
+	}
+	extIf.Networks[nw.Id] = nw
+
+	nm := &networkManager{
+		ExternalInterfaces: map[string]*externalInterface{extIf.Name: extIf},
+		netlink:            nl,
+		netio:              nio,
+		plClient:           platform.NewMockExecClient(false),
+		nsClient:           NewMockNamespaceClient(),
+	}
+	return nm, nw
+}
+
+func TestReconcileEndpoints(t *testing.T) {
+	podIP := &net.IPNet{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(16, 32)}
+
+	live := &endpoint{
+		Id:               "live-eth0",
+		ContainerID:      "live",
+		HostIfName:       "azvlive",
+		IfName:           "eth0",
+		NetworkNameSpace: "/var/run/netns/live",
+		IPAddresses:      []net.IPNet{*podIP},
+		NICType:          cns.InfraNIC,
+	}
+	dead := &endpoint{
+		Id:               "dead-eth0",
+		ContainerID:      "dead",
+		HostIfName:       "azvdead",
+		IfName:           "eth0",
+		NetworkNameSpace: deletedNamespaceName,
+		IPAddresses:      []net.IPNet{*podIP},
+		NICType:          cns.InfraNIC,
+	}
+	delegated := &endpoint{
+		Id:               "delegated-eth1",
+		ContainerID:      "live",
+		IfName:           "eth1",
+		NetworkNameSpace: "/var/run/netns/live",
+		NICType:          cns.NodeNetworkInterfaceFrontendNIC,
+	}
+
+	var added []string
+	nl := netlink.NewMockNetlink(false, "")
+	nl.SetAddRouteValidationFn(func(r *netlink.Route) error {
+		added = append(added, r.Dst.String())
+		return nil
+	})
+
+	nm, nw := newReconcileTestManager(opModeTransparent, nl, netio.NewMockNetIO(false, 0), live, dead, delegated)
+
+	report, err := nm.ReconcileEndpoints()
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Checked)
+	require.Empty(t, report.Failed)
+
+	require.Len(t, report.Removed, 1)
+	require.Equal(t, dead.Id, report.Removed[0].EndpointID)
+	// the ip addresses of removed endpoints are reported so that the caller releases them
+	require.Equal(t, dead.IPAddresses, report.Removed[0].IPAddresses)
+	require.NotContains(t, nw.Endpoints, dead.Id)
+
+	// the mock netlink reports no routes, so the host route of the live endpoint is re-created
+	require.Len(t, report.Repaired, 1)
+	require.Equal(t, live.Id, report.Repaired[0].EndpointID)
+	require.Equal(t, "host route 10.240.0.5/32 dev azvlive", report.Repaired[0].Reason)
+	require.Equal(t, []string{"10.240.0.5/32"}, added)
+	require.Contains(t, nw.Endpoints, live.Id)
+	require.Contains(t, nw.Endpoints, delegated.Id)
+}
+
+func TestReconcileEndpointsHostVethMissing(t *testing.T) {
+	podIP := &net.IPNet{IP: net.ParseIP("10.240.0.5"), Mask: net.CIDRMask(16, 32)}
+	ep := &endpoint{
+		Id:               "live-eth0",
+		ContainerID:      "live",
+		HostIfName:       "azvlive",
+		NetworkNameSpace: "/var/run/netns/live",
+		IPAddresses:      []net.IPNet{*podIP},
+		NICType:          cns.InfraNIC,
+	}
+
+	nm, nw := newReconcileTestManager(opModeTransparent, netlink.NewMockNetlink(false, ""), netio.NewMockNetIO(true, 1), ep)
+
+	report, err := nm.ReconcileEndpoints()
+	require.NoError(t, err)
+	require.Empty(t, report.Repaired)
+	require.Empty(t, report.Removed)
+	require.Len(t, report.Failed, 1)
+	require.Contains(t, report.Failed[0].Reason, "host veth azvlive is missing")
+	require.Contains(t, nw.Endpoints, ep.Id)
+}
+
+func TestReconcileEndpointsStateless(t *testing.T) {
+	nm, _ := newReconcileTestManager(opModeTransparent, netlink.NewMockNetlink(false, ""), netio.NewMockNetIO(false, 0), &endpoint{Id: "ep"})
+	nm.statelessCniMode = true
+
+	report, err := nm.ReconcileEndpoints()
+	require.NoError(t, err)
+	require.Zero(t, report.Checked)
+}
+
+func TestReconcileEndpointsOpenNamespaceFailure(t *testing.T) {
+	ep := &endpoint{
+		Id:               "ep-eth0",
+		HostIfName:       "azvep",
+		NetworkNameSpace: failToOpenNamespaceName,
+		NICType:          cns.InfraNIC,
+	}
+
+	nm, nw := newReconcileTestManager(opModeTransparent, netlink.NewMockNetlink(false, ""), netio.NewMockNetIO(false, 0), ep)
+
+	report, err := nm.ReconcileEndpoints()
+	require.NoError(t, err)
+	require.Empty(t, report.Removed)
+	require.Len(t, report.Failed, 1)
+	require.Contains(t, report.Failed[0].Reason, "failed to open netns")
+	// the endpoint is kept since its netns may still exist
+	require.Contains(t, nw.Endpoints, ep.Id)
+}
+
+func TestReconcileBridgeEndpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		master       string
+		wantRepaired bool
+	}{
+		{
+			name:         "host veth already attached to bridge",
+			master:       "azure0",
+			wantRepaired: false,
+		},
+		{
+			name:         "host veth detached from bridge",
+			master:       "",
+			wantRepaired: true,
+		},
+		{
+			name:         "host veth attached to another bridge",
+			master:       "docker0",
+			wantRepaired: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ep := &endpoint{
+				Id:               "ep-eth0",
+				HostIfName:       "azvep",
+				NetworkNameSpace: "/var/run/netns/ep",
+				NICType:          cns.InfraNIC,
+			}
+
+			setMasterCalled := false
+			nl := &setLinkMasterRecorder{MockNetlink: netlink.NewMockNetlink(false, ""), called: &setMasterCalled}
+			nl.GetLinkMasterFn = func(string) (string, error) {
+				return tt.master, nil
+			}
+
+			nm, _ := newReconcileTestManager(opModeBridge, nl, netio.NewMockNetIO(false, 0), ep)
+
+			report, err := nm.ReconcileEndpoints()
+			require.NoError(t, err)
+			require.Empty(t, report.Failed)
+			require.Equal(t, tt.wantRepaired, setMasterCalled)
+			if !tt.wantRepaired {
+				require.Empty(t, report.Repaired)
+				return
+			}
+			require.Len(t, report.Repaired, 1)
+			require.Equal(t, "attached host veth azvep to bridge azure0", report.Repaired[0].Reason)
+		})
+	}
+}
+
+// setLinkMasterRecorder records whether SetLinkMaster was called on the mock netlink.
+type setLinkMasterRecorder struct {
+	*netlink.MockNetlink
+	called *bool
+}
+
+func (r *setLinkMasterRecorder) SetLinkMaster(name, master string) error {
+	*r.called = true
+	return r.MockNetlink.SetLinkMaster(name, master)
+}
+
+func TestReconcileVlanEndpoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		wantReapplied bool
+	}{
+		{
+			name:          "transparent vlan rules are re-applied",
+			mode:          opModeTransparentVlan,
+			wantReapplied: true,
+		},
+		{
+			name:          "ovs flows are left as is",
+			mode:          opModeBridge,
+			wantReapplied: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ep := &endpoint{
+				Id:               "ep-eth0",
+				HostIfName:       "azvep",
+				NetworkNameSpace: "/var/run/netns/ep",
+				VlanID:           1,
+				NICType:          cns.InfraNIC,
+			}
+
+			nm, _ := newReconcileTestManager(tt.mode, netlink.NewMockNetlink(false, ""), netio.NewMockNetIO(false, 0), ep)
+			nm.vlanEndpointClient = func(*network, *endpoint, *EndpointInfo) EndpointClient {
+				return NewMockEndpointClient(nil)
+			}
+
+			report, err := nm.ReconcileEndpoints()
+			require.NoError(t, err)
+			require.Empty(t, report.Failed)
+			require.Empty(t, report.Repaired)
+			if !tt.wantReapplied {
+				require.Empty(t, report.Reapplied)
+				return
+			}
+			require.Len(t, report.Reapplied, 1)
+			require.Equal(t, ep.Id, report.Reapplied[0].EndpointID)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package network
+
+// reconcileEndpointImpl in windows does nothing for now, HNS owns the host side objects of an endpoint.
+func (nm *networkManager) reconcileEndpointImpl(_ *network, _ *endpoint, _ *ReconcileReport) {
+}
@@ -0,0 +1,247 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package snat
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	enableIPv6ForwardCmd  = "sysctl -w net.ipv6.conf.all.forwarding=1"
+	enableIPv6OnBridgeCmd = "sysctl -w net.ipv6.conf.%s.disable_ipv6=0"
+)
+
+// privateIPv6Space is the ipv6 counterpart of the private ipv4 ranges blocked on the snat bridge: unique local and
+// link local addresses.
+var privateIPv6Space = []string{"fc00::/7", "fe80::/10"}
+
+// neighborDiscoveryTypes are the icmpv6 types the snat bridge exchanges with the container snat veth to resolve
+// their addresses, which must not be blocked along with the link local and unique local ranges.
+var neighborDiscoveryTypes = []string{"neighbour-solicitation", "neighbour-advertisement"}
+
+// SetIPv6Config sets the IPv6 address of the container snat veth and of the snat bridge (both with prefix length).
+// The snat endpoint is dual-stack only if both are set.
+func (client *Client) SetIPv6Config(localIPv6, snatBridgeIPv6 string) {
+	client.localIPv6 = localIPv6
+	client.SnatBridgeIPv6 = snatBridgeIPv6
+}
+
+func (client *Client) isIPv6Enabled() bool {
+	return client.localIPv6 != "" && client.SnatBridgeIPv6 != ""
+}
+
+func getNCLocalAndGatewayIPv6(client *Client) (brIP, contIP net.IP) {
+	bridgeIP, _, _ := net.ParseCIDR(client.SnatBridgeIPv6)
+	containerIP, _, _ := net.ParseCIDR(client.localIPv6)
+	return bridgeIP, containerIP
+}
+
+// addSnatBridgeIPv6Address enables ipv6 on the snat bridge and assigns the snat bridge ipv6 address to it.
+func (client *Client) addSnatBridgeIPv6Address() error {
+	if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(enableIPv6OnBridgeCmd, SnatBridgeName)); err != nil {
+		return errors.Wrap(err, "failed to enable ipv6 on snat bridge")
+	}
+
+	ip, addr, _ := net.ParseCIDR(client.SnatBridgeIPv6)
+	err := client.netlink.AddIPAddress(SnatBridgeName, ip, addr)
+	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "file exists") {
+		logger.Error("Failed to add IPv6 address", zap.Any("addr", addr), zap.Error(err))
+		return newErrorSnatClient(err.Error())
+	}
+	return nil
+}
+
+// blockIPv6AddressesOnSnatBridge adds the ip6tables rules that block the private ipv6 ranges and the ipv6 ranges of
+// the host primary interface flowing via the snat bridge, like BlockIPAddressesOnSnatBridge does for ipv4.
+func (client *Client) blockIPv6AddressesOnSnatBridge() error {
+	hostRanges, err := client.getHostIPv6Ranges()
+	if err != nil {
+		return err
+	}
+
+	for _, chain := range []string{iptables.Input, iptables.Output} {
+		for _, icmpType := range neighborDiscoveryTypes {
+			matchCondition := fmt.Sprintf("-%s %s -p ipv6-icmp --icmpv6-type %s", bridgeOption(chain), SnatBridgeName, icmpType)
+			if err := client.ipTablesClient.AppendIptableRule(iptables.V6, iptables.Filter, chain, matchCondition, iptables.Accept); err != nil {
+				return errors.Wrapf(err, "failed to allow ipv6 neighbor discovery in %s", chain)
+			}
+		}
+	}
+
+	blocked := append(append([]string{}, privateIPv6Space...), hostRanges...)
+	logger.Info("IPv6 addresses to block", zap.Strings("addresses", blocked))
+	for _, ipRange := range blocked {
+		for _, chain := range []string{iptables.Forward, iptables.Input, iptables.Output} {
+			matchCondition := fmt.Sprintf("-%s %s -d %s", bridgeOption(chain), SnatBridgeName, ipRange)
+			if err := client.ipTablesClient.AppendIptableRule(iptables.V6, iptables.Filter, chain, matchCondition, iptables.Drop); err != nil {
+				return errors.Wrapf(err, "failed to block %s in %s", ipRange, chain)
+			}
+		}
+	}
+	return nil
+}
+
+// getHostIPv6Ranges returns the global ipv6 subnets of the host primary interface, the link local ones are part of
+// the private ranges already.
+func (client *Client) getHostIPv6Ranges() ([]string, error) {
+	if client.hostPrimaryMac == "" {
+		return nil, nil
+	}
+
+	mac, err := net.ParseMAC(client.hostPrimaryMac)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse host primary mac %s", client.hostPrimaryMac)
+	}
+	hostIf, err := client.netioClient.GetNetworkInterfaceByMac(mac)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get host primary interface")
+	}
+	addrs, err := client.netioClient.GetNetworkInterfaceAddrs(hostIf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get addresses of host primary interface %s", hostIf.Name)
+	}
+
+	var ranges []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() != nil || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		ranges = append(ranges, (&net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask}).String())
+	}
+	return ranges, nil
+}
+
+// bridgeOption returns the iptables option matching the snat bridge as the interface packets leave through in
+// the OUTPUT chain, and as the one they come in through otherwise.
+func bridgeOption(chain string) string {
+	if chain == iptables.Output {
+		return "o"
+	}
+	return "i"
+}
+
+// enableIPv6Forwarding enables ipv6 forwarding in VM and allows forwarding ipv6 packets. The rules blocking the
+// private and host ipv6 ranges are appended before it by BlockIPAddressesOnSnatBridge.
+func (client *Client) enableIPv6Forwarding() error {
+	// sysctl -w net.ipv6.conf.all.forwarding=1
+	if _, err := client.plClient.ExecuteRawCommand(enableIPv6ForwardCmd); err != nil {
+		return errors.Wrap(err, "enable ipv6 forwarding command failed")
+	}
+
+	if err := client.ipTablesClient.AppendIptableRule(iptables.V6, iptables.Filter, iptables.Forward, "", iptables.Accept); err != nil {
+		return errors.Wrap(err, "appending ip6tables forward chain rule to allow traffic from snat bridge failed")
+	}
+	return nil
+}
+
+// configureSnatContainerInterfaceIPv6 assigns the local ipv6 address to the container snat veth.
+func (client *Client) configureSnatContainerInterfaceIPv6() error {
+	logger.Info("[snat] IPv6 address", zap.String("localIPv6", client.localIPv6),
+		zap.String("containerSnatVethName", client.containerSnatVethName))
+	ip, ipNet, _ := net.ParseCIDR(client.localIPv6)
+	if err := client.netlink.AddIPAddress(client.containerSnatVethName, ip, ipNet); err != nil {
+		return newErrorSnatClient(err.Error())
+	}
+	return nil
+}
+
+// ensureCNIChainIPv6 creates the ip6tables chain and jumps to it from parentChain.
+func (client *Client) ensureCNIChainIPv6(parentChain, chain string) error {
+	if err := client.ipTablesClient.CreateChain(iptables.V6, iptables.Filter, chain); err != nil {
+		return errors.Wrapf(err, "failed to create ip6tables chain %s", chain)
+	}
+	if err := client.ipTablesClient.InsertIptableRule(iptables.V6, iptables.Filter, parentChain, "", chain); err != nil {
+		return errors.Wrapf(err, "failed to jump from %s to ip6tables chain %s", parentChain, chain)
+	}
+	return nil
+}
+
+// setContainerIPv6Neigh adds or removes the static neighbor entry of the container ipv6 address on the snat bridge,
+// which prevents neighbor solicitations going out of VM.
+func (client *Client) setContainerIPv6Neigh(containerIP net.IP, operation int) error {
+	linkInfo := netlink.LinkInfo{
+		Name:   SnatBridgeName,
+		IPAddr: containerIP,
+	}
+	state := netlink.NUD_INCOMPLETE
+	if operation == netlink.ADD {
+		snatContainerVeth, err := client.netioClient.GetNetworkInterfaceByName(client.containerSnatVethName)
+		if err != nil {
+			return errors.Wrap(newErrorSnatClient(err.Error()), "could not find container snat veth name")
+		}
+		linkInfo.MacAddress = snatContainerVeth.HardwareAddr
+		state = netlink.NUD_PERMANENT
+	}
+
+	logger.Info("Setting static neighbor entry for ip", zap.Any("containerIP", containerIP), zap.Int("operation", operation))
+	if err := client.netlink.SetOrRemoveLinkAddress(linkInfo, operation, state); err != nil {
+		return newErrorSnatClient(err.Error())
+	}
+	return nil
+}
+
+// allowInboundFromHostToNCIPv6 adds the ip6tables rules that allow only host to NC communication.
+func (client *Client) allowInboundFromHostToNCIPv6() error {
+	bridgeIP, containerIP := getNCLocalAndGatewayIPv6(client)
+
+	if err := client.ensureCNIChainIPv6(iptables.Output, iptables.CNIOutputChain); err != nil {
+		return err
+	}
+	matchCondition := fmt.Sprintf("-s %s -d %s", bridgeIP.String(), containerIP.String())
+	if err := client.ipTablesClient.InsertIptableRule(iptables.V6, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept); err != nil {
+		return errors.Wrap(err, "failed to allow ipv6 host to nc")
+	}
+
+	if err := client.ensureCNIChainIPv6(iptables.Input, iptables.CNIInputChain); err != nil {
+		return err
+	}
+	matchCondition = fmt.Sprintf(" -i %s -m state --state %s,%s", SnatBridgeName, iptables.Established, iptables.Related)
+	if err := client.ipTablesClient.InsertIptableRule(iptables.V6, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept); err != nil {
+		return errors.Wrap(err, "failed to allow ipv6 established connections from nc")
+	}
+
+	return client.setContainerIPv6Neigh(containerIP, netlink.ADD)
+}
+
+// allowInboundFromNCToHostIPv6 adds the ip6tables rules that allow only NC to host communication.
+func (client *Client) allowInboundFromNCToHostIPv6() error {
+	bridgeIP, containerIP := getNCLocalAndGatewayIPv6(client)
+
+	if err := client.ensureCNIChainIPv6(iptables.Input, iptables.CNIInputChain); err != nil {
+		return err
+	}
+	matchCondition := fmt.Sprintf("-s %s -d %s", containerIP.String(), bridgeIP.String())
+	if err := client.ipTablesClient.InsertIptableRule(iptables.V6, iptables.Filter, iptables.CNIInputChain, matchCondition, iptables.Accept); err != nil {
+		return errors.Wrap(err, "failed to allow ipv6 nc to host")
+	}
+
+	if err := client.ensureCNIChainIPv6(iptables.Output, iptables.CNIOutputChain); err != nil {
+		return err
+	}
+	matchCondition = fmt.Sprintf(" -o %s -m state --state %s,%s", SnatBridgeName, iptables.Established, iptables.Related)
+	if err := client.ipTablesClient.InsertIptableRule(iptables.V6, iptables.Filter, iptables.CNIOutputChain, matchCondition, iptables.Accept); err != nil {
+		return errors.Wrap(err, "failed to allow ipv6 established connections from host")
+	}
+
+	return client.setContainerIPv6Neigh(containerIP, netlink.ADD)
+}
+
+// deleteInboundIPv6 removes the ip6tables accept rule between src and dst in chain and the static neighbor entry
+// of the container ipv6 address.
+func (client *Client) deleteInboundIPv6(chain string, src, dst, containerIP net.IP) error {
+	matchCondition := fmt.Sprintf("-s %s -d %s", src.String(), dst.String())
+	if err := client.ipTablesClient.DeleteIptableRule(iptables.V6, iptables.Filter, chain, matchCondition, iptables.Accept); err != nil {
+		logger.Error("Error removing ipv6 rule", zap.String("chain", chain), zap.Error(err))
+	}
+
+	return client.setContainerIPv6Neigh(containerIP, netlink.REMOVE)
+}
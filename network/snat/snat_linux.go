@@ -51,6 +51,8 @@ type Client struct {
 	containerSnatVethName  string
 	localIP                string
 	SnatBridgeIP           string
+	localIPv6              string
+	SnatBridgeIPv6         string
 	SkipAddressesFromBlock []string
 	enableProxyArpOnBridge bool
 	netlink                netlink.NetlinkInterface
@@ -113,6 +115,13 @@ func (client *Client) CreateSnatEndpoint() error {
 		return err
 	}
 
+	if client.isIPv6Enabled() {
+		if err := client.addMasqueradeRule(client.SnatBridgeIPv6); err != nil {
+			logger.Error("Adding ipv6 snat rule failed with", zap.Error(err))
+			return err
+		}
+	}
+
 	// Drop all vlan packets coming via linux bridge.
 	if err := client.addVlanDropRule(); err != nil {
 		logger.Error("Adding vlan drop rule failed", zap.Error(err))
@@ -143,7 +152,8 @@ func (client *Client) AllowIPAddressesOnSnatBridge() error {
 	return nil
 }
 
-// BlockIPAddressesOnSnatBridge adds iptables rules  that blocks all private IPs flowing via linux bridge
+// BlockIPAddressesOnSnatBridge adds iptables rules  that blocks all private IPs flowing via linux bridge, and the ip6tables
+// rules blocking the private and host ipv6 ranges if the snat endpoint is dual-stack
 func (client *Client) BlockIPAddressesOnSnatBridge() error {
 	nu := networkutils.NewNetworkUtils(client.netlink, client.plClient)
 	if err := nu.BlockIPAddresses(client.ipTablesClient, SnatBridgeName, iptables.Append); err != nil {
@@ -151,6 +161,13 @@ func (client *Client) BlockIPAddressesOnSnatBridge() error {
 		return newErrorSnatClient(err.Error())
 	}
 
+	if client.isIPv6Enabled() {
+		if err := client.blockIPv6AddressesOnSnatBridge(); err != nil {
+			logger.Error("Blocking ipv6 addresses failed with", zap.Error(err))
+			return newErrorSnatClient(err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -249,6 +266,10 @@ func (client *Client) AllowInboundFromHostToNC() error {
 		return newErrorSnatClient(err.Error())
 	}
 
+	if client.isIPv6Enabled() {
+		return client.allowInboundFromHostToNCIPv6()
+	}
+
 	return nil
 }
 
@@ -276,6 +297,14 @@ func (client *Client) DeleteInboundFromHostToNC() error {
 			zap.Error(err))
 	}
 
+	if client.isIPv6Enabled() {
+		bridgeIPv6, containerIPv6 := getNCLocalAndGatewayIPv6(client)
+		if v6Err := client.deleteInboundIPv6(iptables.CNIOutputChain, bridgeIPv6, containerIPv6, containerIPv6); v6Err != nil {
+			logger.Error("DeleteInboundFromHostToNC: Error removing static neighbor entry for ipv6", zap.Error(v6Err))
+			err = v6Err
+		}
+	}
+
 	return err
 }
 
@@ -345,9 +374,14 @@ func (client *Client) AllowInboundFromNCToHost() error {
 	if err != nil {
 		logger.Error("AllowInboundFromNCToHost: Error adding static arp entry for ip", zap.Any("containerIP", containerIP),
 			zap.String("HardwareAddr", snatContainerVeth.HardwareAddr.String()), zap.Error(err))
+		return err
 	}
 
-	return err
+	if client.isIPv6Enabled() {
+		return client.allowInboundFromNCToHostIPv6()
+	}
+
+	return nil
 }
 
 func (client *Client) DeleteInboundFromNCToHost() error {
@@ -374,6 +408,14 @@ func (client *Client) DeleteInboundFromNCToHost() error {
 			zap.Any("containerIP", containerIP), zap.Error(err))
 	}
 
+	if client.isIPv6Enabled() {
+		bridgeIPv6, containerIPv6 := getNCLocalAndGatewayIPv6(client)
+		if v6Err := client.deleteInboundIPv6(iptables.CNIInputChain, containerIPv6, bridgeIPv6, containerIPv6); v6Err != nil {
+			logger.Error("DeleteInboundFromNCToHost: Error removing static neighbor entry for ipv6", zap.Error(v6Err))
+			err = v6Err
+		}
+	}
+
 	return err
 }
 
@@ -386,6 +428,10 @@ func (client *Client) ConfigureSnatContainerInterface() error {
 	if err != nil {
 		return newErrorSnatClient(err.Error())
 	}
+
+	if client.isIPv6Enabled() {
+		return client.configureSnatContainerInterfaceIPv6()
+	}
 	return nil
 }
 
@@ -464,6 +510,12 @@ func (client *Client) createSnatBridge(snatBridgeIP, hostPrimaryMac string) erro
 		return newErrorSnatClient(err.Error())
 	}
 
+	if client.isIPv6Enabled() {
+		if err = client.addSnatBridgeIPv6Address(); err != nil {
+			return err
+		}
+	}
+
 	if err = client.netlink.SetLinkState(SnatBridgeName, true); err != nil {
 		return newErrorSnatClient(err.Error())
 	}
@@ -471,11 +523,16 @@ func (client *Client) createSnatBridge(snatBridgeIP, hostPrimaryMac string) erro
 	return nil
 }
 
-// This function adds iptable rules that will snat all traffic that has source ip in apipa range and coming via linux bridge
+// This function adds iptable rules that will snat all traffic that has source ip in apipa range (or the ipv6 snat bridge
+// subnet) and coming via linux bridge
 func (client *Client) addMasqueradeRule(snatBridgeIPWithPrefix string) error {
 	_, ipNet, _ := net.ParseCIDR(snatBridgeIPWithPrefix)
+	version := iptables.V4
+	if ipNet.IP.To4() == nil {
+		version = iptables.V6
+	}
 	matchCondition := fmt.Sprintf("-s %s", ipNet.String())
-	return errors.Wrap(client.ipTablesClient.InsertIptableRule(iptables.V4, iptables.Nat, iptables.Postrouting, matchCondition, iptables.Masquerade),
+	return errors.Wrap(client.ipTablesClient.InsertIptableRule(version, iptables.Nat, iptables.Postrouting, matchCondition, iptables.Masquerade),
 		"failed to add masquerade rule")
 }
 
@@ -512,5 +569,9 @@ func (client *Client) EnableIPForwarding() error {
 		return errors.Wrap(err, "appending forward chain rule to allow traffic from snat bridge failed")
 	}
 
+	if client.isIPv6Enabled() {
+		return client.enableIPv6Forwarding()
+	}
+
 	return nil
 }
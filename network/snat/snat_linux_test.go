@@ -1,11 +1,15 @@
 package snat
 
 import (
+	"net"
 	"os"
 	"testing"
 
+	"github.com/Azure/azure-container-networking/iptables"
 	"github.com/Azure/azure-container-networking/netio"
 	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
 )
 
 var anyInterface = "dummy"
@@ -144,3 +148,122 @@ func TestAllowInboundFromNCToHost(t *testing.T) {
 		t.Errorf("Expected error when interface not found in allow nc to host but got nil")
 	}
 }
+
+type iptablesRule struct {
+	version, table, chain, match, target string
+}
+
+// recordingIPTablesClient records the inserted and appended rules.
+type recordingIPTablesClient struct {
+	mockIPTablesClient
+	rules []iptablesRule
+}
+
+func (c *recordingIPTablesClient) InsertIptableRule(version, table, chain, match, target string) error {
+	c.rules = append(c.rules, iptablesRule{version, table, chain, match, target})
+	return nil
+}
+
+func (c *recordingIPTablesClient) AppendIptableRule(version, table, chain, match, target string) error {
+	c.rules = append(c.rules, iptablesRule{version, table, chain, match, target})
+	return nil
+}
+
+func TestIPv6SnatRules(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	iptc := &recordingIPTablesClient{}
+	client := GetTestClient(nl, iptc, netio.NewMockNetIO(false, 0))
+	client.plClient = platform.NewMockExecClient(false)
+	client.SetIPv6Config("fd00:a9fe:8000::4/64", "fd00:a9fe:8000::1/64")
+
+	require.NoError(t, client.addMasqueradeRule(client.SnatBridgeIPv6))
+	require.NoError(t, client.EnableIPForwarding())
+	require.NoError(t, client.AllowInboundFromHostToNC())
+
+	require.Contains(t, iptc.rules, iptablesRule{iptables.V6, iptables.Nat, iptables.Postrouting, "-s fd00:a9fe:8000::/64", iptables.Masquerade})
+	require.Contains(t, iptc.rules, iptablesRule{iptables.V6, iptables.Filter, iptables.Forward, "", iptables.Accept})
+	require.Contains(t, iptc.rules, iptablesRule{iptables.V6, iptables.Filter, iptables.CNIOutputChain, "-s fd00:a9fe:8000::1 -d fd00:a9fe:8000::4", iptables.Accept})
+	// the ipv4 rules are still added
+	require.Contains(t, iptc.rules, iptablesRule{iptables.V4, iptables.Filter, iptables.CNIOutputChain, "-s 169.254.0.1 -d 169.254.0.4", iptables.Accept})
+}
+
+func TestIPv6SnatRulesDisabled(t *testing.T) {
+	iptc := &recordingIPTablesClient{}
+	client := GetTestClient(netlink.NewMockNetlink(false, ""), iptc, netio.NewMockNetIO(false, 0))
+	client.plClient = platform.NewMockExecClient(false)
+	// the snat endpoint is dual-stack only if both ipv6 addresses are set
+	client.SetIPv6Config("fd00:a9fe:8000::4/64", "")
+
+	require.NoError(t, client.EnableIPForwarding())
+	require.NoError(t, client.AllowInboundFromNCToHost())
+	for _, rule := range iptc.rules {
+		require.Equal(t, iptables.V4, rule.version)
+	}
+}
+
+// addrsNetIO returns addrs as the addresses of every interface.
+type addrsNetIO struct {
+	*netio.MockNetIO
+	addrs []net.Addr
+}
+
+func (n *addrsNetIO) GetNetworkInterfaceAddrs(*net.Interface) ([]net.Addr, error) {
+	return n.addrs, nil
+}
+
+func TestBlockIPv6AddressesOnSnatBridge(t *testing.T) {
+	_, hostV4, _ := net.ParseCIDR("10.224.0.4/16")
+	hostV6 := &net.IPNet{IP: net.ParseIP("2001:db8:1::4"), Mask: net.CIDRMask(64, 128)}
+	hostLinkLocal := &net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}
+	nio := &addrsNetIO{MockNetIO: netio.NewMockNetIO(false, 0), addrs: []net.Addr{hostV4, hostV6, hostLinkLocal}}
+
+	iptc := &recordingIPTablesClient{}
+	client := GetTestClient(netlink.NewMockNetlink(false, ""), iptc, nio)
+	client.plClient = platform.NewMockExecClient(false)
+	client.hostPrimaryMac = netio.HwAddr.String()
+	client.SetIPv6Config("fd00:a9fe:8000::4/64", "fd00:a9fe:8000::1/64")
+
+	require.NoError(t, client.BlockIPAddressesOnSnatBridge())
+	require.NoError(t, client.EnableIPForwarding())
+
+	var v6Rules []iptablesRule
+	for _, rule := range iptc.rules {
+		if rule.version == iptables.V6 {
+			v6Rules = append(v6Rules, rule)
+		}
+	}
+
+	for _, ipRange := range []string{"fc00::/7", "fe80::/10", "2001:db8:1::/64"} {
+		require.Contains(t, v6Rules, iptablesRule{iptables.V6, iptables.Filter, iptables.Forward, "-i azSnatbr -d " + ipRange, iptables.Drop})
+		require.Contains(t, v6Rules, iptablesRule{iptables.V6, iptables.Filter, iptables.Input, "-i azSnatbr -d " + ipRange, iptables.Drop})
+		require.Contains(t, v6Rules, iptablesRule{iptables.V6, iptables.Filter, iptables.Output, "-o azSnatbr -d " + ipRange, iptables.Drop})
+	}
+
+	// neighbor discovery with the container snat veth is accepted ahead of the drop rules
+	ndAccept := iptablesRule{iptables.V6, iptables.Filter, iptables.Output, "-o azSnatbr -p ipv6-icmp --icmpv6-type neighbour-advertisement", iptables.Accept}
+	require.Contains(t, v6Rules, ndAccept)
+	// the blanket forward accept is appended after the drop rules
+	forwardAccept := iptablesRule{iptables.V6, iptables.Filter, iptables.Forward, "", iptables.Accept}
+	require.Equal(t, forwardAccept, v6Rules[len(v6Rules)-1])
+	require.Less(t, indexOfRule(v6Rules, ndAccept), indexOfRule(v6Rules, iptablesRule{iptables.V6, iptables.Filter, iptables.Output, "-o azSnatbr -d fc00::/7", iptables.Drop}))
+}
+
+func TestBlockIPv6AddressesOnSnatBridgeDisabled(t *testing.T) {
+	iptc := &recordingIPTablesClient{}
+	client := GetTestClient(netlink.NewMockNetlink(false, ""), iptc, netio.NewMockNetIO(false, 0))
+	client.plClient = platform.NewMockExecClient(false)
+
+	require.NoError(t, client.BlockIPAddressesOnSnatBridge())
+	for _, rule := range iptc.rules {
+		require.Equal(t, iptables.V4, rule.version)
+	}
+}
+
+func indexOfRule(rules []iptablesRule, rule iptablesRule) int {
+	for i := range rules {
+		if rules[i] == rule {
+			return i
+		}
+	}
+	return -1
+}
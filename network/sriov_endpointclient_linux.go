@@ -0,0 +1,220 @@
+package network
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/netns"
+	"github.com/Azure/azure-container-networking/network/networkutils"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// pciDevicesPath is where the kernel exposes the pci devices and the net devices of their functions
+	pciDevicesPath = "/sys/bus/pci/devices"
+)
+
+var (
+	errorSRIOVEndpointClient = errors.New("SRIOVEndpointClient Error")
+	errNoNetDevice           = errors.New("no net device found")
+)
+
+func newErrorSRIOVEndpointClient(err error) error {
+	return errors.Wrapf(err, "%s", errorSRIOVEndpointClient)
+}
+
+// SRIOVEndpointClient moves the SR-IOV virtual function allocated by CNS for a backend NIC into the container and
+// returns it to its physical function when the endpoint is deleted.
+type SRIOVEndpointClient struct {
+	netlink        netlink.NetlinkInterface
+	netioshim      netio.NetIOInterface
+	plClient       platform.ExecClient
+	netUtilsClient networkutils.NetworkUtils
+	nsClient       NamespaceClientInterface
+	ep             *endpoint
+	pciDevicesPath string
+}
+
+func NewSRIOVEndpointClient(
+	nl netlink.NetlinkInterface,
+	nioc netio.NetIOInterface,
+	plc platform.ExecClient,
+	nsc NamespaceClientInterface,
+	endpoint *endpoint,
+) *SRIOVEndpointClient {
+	return &SRIOVEndpointClient{
+		netlink:        nl,
+		netioshim:      nioc,
+		plClient:       plc,
+		netUtilsClient: networkutils.NewNetworkUtils(nl, plc),
+		nsClient:       nsc,
+		ep:             endpoint,
+		pciDevicesPath: pciDevicesPath,
+	}
+}
+
+// netDeviceName returns the name of the net device of the pci function at path in the current namespace.
+func netDeviceName(path string) (string, error) {
+	entries, err := os.ReadDir(filepath.Join(path, "net"))
+	if err != nil || len(entries) == 0 {
+		return "", errors.Wrap(errNoNetDevice, path)
+	}
+	return entries[0].Name(), nil
+}
+
+// pfName returns the name of the net device of the physical function with the pci address pnpID.
+func (client *SRIOVEndpointClient) pfName(pnpID string) (string, error) {
+	return netDeviceName(filepath.Join(client.pciDevicesPath, pnpID))
+}
+
+// vfName returns the name of the net device of the virtual function index of the physical function with the pci
+// address pnpID. The net device is only found while the virtual function is in the host namespace.
+func (client *SRIOVEndpointClient) vfName(pnpID string, index int) (string, error) {
+	return netDeviceName(filepath.Join(client.pciDevicesPath, pnpID, "virtfn"+strconv.Itoa(index)))
+}
+
+// AddEndpoints programs the mac address, vlan and trust mode of the virtual function on its physical function.
+func (client *SRIOVEndpointClient) AddEndpoints(epInfo *EndpointInfo) error {
+	vf := epInfo.VirtualFunction
+	pfName, err := client.pfName(epInfo.PnPID)
+	if err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+	vfName, err := client.vfName(epInfo.PnPID, vf.Index)
+	if err != nil {
+		return newErrorSRIOVEndpointClient(errors.Wrapf(err, "virtual function %d of %s is not available", vf.Index, pfName))
+	}
+
+	macAddress, err := net.ParseMAC(vf.MacAddress)
+	if err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+
+	logger.Info("Configuring virtual function", zap.String("pf", pfName), zap.Int("vf", vf.Index), zap.String("vfName", vfName),
+		zap.String("macAddress", vf.MacAddress), zap.Int("vlanID", vf.VlanID), zap.Bool("trust", vf.Trust))
+	vfConfig := netlink.VFConfig{
+		Index:      vf.Index,
+		MacAddress: macAddress,
+		VlanID:     vf.VlanID,
+		Trust:      vf.Trust,
+	}
+	if err := client.netlink.SetVFConfig(pfName, vfConfig); err != nil {
+		return newErrorSRIOVEndpointClient(errors.Wrapf(err, "failed to configure virtual function %d of %s", vf.Index, pfName))
+	}
+
+	// the net device keeps the name it has in the host namespace until it is renamed in the container
+	epInfo.IfName = vfName
+	client.ep.HostIfName = vfName
+	client.ep.MacAddress = macAddress
+	return nil
+}
+
+func (client *SRIOVEndpointClient) AddEndpointRules(_ *EndpointInfo) error {
+	return nil
+}
+
+func (client *SRIOVEndpointClient) DeleteEndpointRules(_ *endpoint) {
+}
+
+func (client *SRIOVEndpointClient) MoveEndpointsToContainerNS(epInfo *EndpointInfo, nsID uintptr) error {
+	logger.Info("Setting link netns", zap.String("IfName", epInfo.IfName), zap.String("NetNsPath", epInfo.NetNsPath))
+	if err := client.netlink.SetLinkNetNs(epInfo.IfName, nsID); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+	return nil
+}
+
+// SetupContainerInterfaces renames the virtual function to the endpoint interface name and sets it up. Namespace: container
+func (client *SRIOVEndpointClient) SetupContainerInterfaces(epInfo *EndpointInfo) error {
+	if err := client.netlink.SetLinkName(epInfo.IfName, client.ep.IfName); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+	epInfo.IfName = client.ep.IfName
+
+	logger.Info("Setting link state up", zap.String("IfName", epInfo.IfName))
+	if err := client.netlink.SetLinkState(epInfo.IfName, true); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+	return nil
+}
+
+func (client *SRIOVEndpointClient) ConfigureContainerInterfacesAndRoutes(epInfo *EndpointInfo) error {
+	if len(epInfo.IPAddresses) > 0 {
+		if err := client.netUtilsClient.AssignIPToInterface(epInfo.IfName, epInfo.IPAddresses); err != nil {
+			return newErrorSRIOVEndpointClient(err)
+		}
+	}
+
+	if err := addRoutes(client.netlink, client.netioshim, epInfo.IfName, epInfo.Routes); err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+	return nil
+}
+
+// DeleteEndpoints moves the virtual function back to the host namespace under its host name and resets its vlan and
+// trust mode so it can be allocated to another pod. The mac address is left as is, CNS programs a stable one per
+// virtual function.
+func (client *SRIOVEndpointClient) DeleteEndpoints(ep *endpoint) error {
+	if ep.NetworkNameSpace != "" {
+		if err := client.moveToHostNS(ep); err != nil {
+			// the virtual function returns to the host namespace when the container namespace is deleted
+			logger.Error("Failed to move virtual function to host namespace", zap.String("IfName", ep.IfName), zap.Error(err))
+		}
+	}
+
+	pfName, err := client.pfName(ep.PnPID)
+	if err != nil {
+		return newErrorSRIOVEndpointClient(err)
+	}
+	vfConfig := netlink.VFConfig{
+		Index: ep.VirtualFunction.Index,
+	}
+	logger.Info("Resetting virtual function", zap.String("pf", pfName), zap.Int("vf", vfConfig.Index))
+	if err := client.netlink.SetVFConfig(pfName, vfConfig); err != nil {
+		return newErrorSRIOVEndpointClient(errors.Wrapf(err, "failed to reset virtual function %d of %s", vfConfig.Index, pfName))
+	}
+	return nil
+}
+
+func (client *SRIOVEndpointClient) moveToHostNS(ep *endpoint) error {
+	vmns, err := netns.New().Get()
+	if err != nil {
+		return errors.Wrap(err, "failed to get host namespace")
+	}
+
+	logger.Info("Opening netns", zap.Any("NetNsPath", ep.NetworkNameSpace))
+	ns, err := client.nsClient.OpenNamespace(ep.NetworkNameSpace)
+	if err != nil {
+		return errors.Wrap(err, "failed to open container namespace")
+	}
+	defer ns.Close()
+
+	logger.Info("Entering netns", zap.Any("NetNsPath", ep.NetworkNameSpace))
+	if err := ns.Enter(); err != nil {
+		return errors.Wrap(err, "failed to enter container namespace")
+	}
+	defer func() {
+		logger.Info("Exiting netns", zap.Any("NetNsPath", ep.NetworkNameSpace))
+		if err := ns.Exit(); err != nil {
+			logger.Error("Failed to exit netns with", zap.Error(newErrorSRIOVEndpointClient(err)))
+		}
+	}()
+
+	if err := client.netlink.SetLinkState(ep.IfName, false); err != nil {
+		return errors.Wrapf(err, "failed to set %s down", ep.IfName)
+	}
+	// rename before the move so the name does not clash with an interface of the host namespace
+	if ep.HostIfName != "" {
+		if err := client.netlink.SetLinkName(ep.IfName, ep.HostIfName); err != nil {
+			return errors.Wrapf(err, "failed to rename %s to %s", ep.IfName, ep.HostIfName)
+		}
+		return errors.Wrap(client.netlink.SetLinkNetNs(ep.HostIfName, uintptr(vmns)), "failed to move virtual function")
+	}
+	return errors.Wrap(client.netlink.SetLinkNetNs(ep.IfName, uintptr(vmns)), "failed to move virtual function")
+}
@@ -0,0 +1,94 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
+)
+
+const testPnPID = "0000:3b:00.0"
+
+// newTestSRIOVClient returns a client reading a fake pci device tree with the physical function eth1 and the net
+// device of its virtual function 1.
+func newTestSRIOVClient(t *testing.T, nl netlink.NetlinkInterface, ep *endpoint) *SRIOVEndpointClient {
+	root := t.TempDir()
+	for _, dir := range []string{
+		filepath.Join(root, testPnPID, "net", "eth1"),
+		filepath.Join(root, testPnPID, "virtfn0"),
+		filepath.Join(root, testPnPID, "virtfn1", "net", "eth3"),
+	} {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+	}
+	client := NewSRIOVEndpointClient(nl, netio.NewMockNetIO(false, 0), platform.NewMockExecClient(false), NewMockNamespaceClient(), ep)
+	client.pciDevicesPath = root
+	return client
+}
+
+func TestSRIOVAddEndpoints(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	var pfName string
+	var vfConfig netlink.VFConfig
+	nl.SetVFConfigFn = func(name string, vf netlink.VFConfig) error {
+		pfName, vfConfig = name, vf
+		return nil
+	}
+	ep := &endpoint{IfName: "ib0"}
+	client := newTestSRIOVClient(t, nl, ep)
+	epInfo := &EndpointInfo{
+		PnPID:           testPnPID,
+		VirtualFunction: &cns.VirtualFunction{Index: 1, MacAddress: "02:aa:bb:cc:00:01", VlanID: 100},
+	}
+
+	require.NoError(t, client.AddEndpoints(epInfo))
+	require.Equal(t, "eth1", pfName)
+	require.Equal(t, 1, vfConfig.Index)
+	require.Equal(t, "02:aa:bb:cc:00:01", ep.MacAddress.String())
+	require.Equal(t, 100, vfConfig.VlanID)
+	require.False(t, vfConfig.Trust)
+	require.Equal(t, "eth3", epInfo.IfName)
+	require.Equal(t, "eth3", ep.HostIfName)
+
+	require.NoError(t, client.SetupContainerInterfaces(epInfo))
+	require.Equal(t, "ib0", epInfo.IfName)
+}
+
+func TestSRIOVAddEndpointsVFInUse(t *testing.T) {
+	client := newTestSRIOVClient(t, netlink.NewMockNetlink(false, ""), &endpoint{})
+	epInfo := &EndpointInfo{
+		PnPID:           testPnPID,
+		VirtualFunction: &cns.VirtualFunction{Index: 0, MacAddress: "02:aa:bb:cc:00:00"},
+	}
+
+	// the net device of virtual function 0 is not in the host namespace
+	err := client.AddEndpoints(epInfo)
+	require.ErrorIs(t, err, errNoNetDevice)
+}
+
+func TestSRIOVDeleteEndpoints(t *testing.T) {
+	nl := netlink.NewMockNetlink(false, "")
+	var vfConfig netlink.VFConfig
+	nl.SetVFConfigFn = func(_ string, vf netlink.VFConfig) error {
+		vfConfig = vf
+		return nil
+	}
+	ep := &endpoint{
+		IfName:          "ib0",
+		NICType:         cns.BackendNIC,
+		PnPID:           testPnPID,
+		VirtualFunction: &cns.VirtualFunction{Index: 1, MacAddress: "02:aa:bb:cc:00:01", VlanID: 100, Trust: true},
+	}
+	client := newTestSRIOVClient(t, nl, ep)
+
+	require.NoError(t, client.DeleteEndpoints(ep))
+	// the vlan and trust mode are reset, the mac address is kept
+	require.Equal(t, netlink.VFConfig{Index: 1}, vfConfig)
+}
@@ -0,0 +1,164 @@
+package network
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/pkg/errors"
+	vishnetlink "github.com/vishvananda/netlink"
+	"go.uber.org/zap"
+)
+
+const (
+	enableNdpProxyCmd = "sysctl -w net.ipv6.conf.%s.proxy_ndp=1"
+	addNdpProxyCmd    = "ip -6 neigh replace proxy %s dev %s"
+	deleteNdpProxyCmd = "ip -6 neigh del proxy %s dev %s"
+)
+
+// hasIPv6 returns true if any of the ip addresses is an ipv6 address
+func hasIPv6(ipAddresses []net.IPNet) bool {
+	for _, ipAddr := range ipAddresses {
+		if ipAddr.IP.To4() == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ipv6Addresses returns the ipv6 addresses of the list
+func ipv6Addresses(ipAddresses []net.IPNet) []net.IPNet {
+	v6 := []net.IPNet{}
+	for _, ipAddr := range ipAddresses {
+		if ipAddr.IP.To4() == nil {
+			v6 = append(v6, ipAddr)
+		}
+	}
+	return v6
+}
+
+// Enables ipv6 and ipv6 forwarding in the current namespace (idempotent)
+func (client *TransparentVlanEndpointClient) enableIPv6() error {
+	if err := client.netUtilsClient.UpdateIPV6Setting(0); err != nil {
+		return errors.Wrap(err, "failed to enable ipv6")
+	}
+	if err := client.netUtilsClient.EnableIPV6Forwarding(); err != nil {
+		return errors.Wrap(err, "failed to enable ipv6 forwarding")
+	}
+	return nil
+}
+
+// Helper that creates the ipv6 routing rules for the current NS which direct packets
+// to the virtual ipv6 gateway on linkToName device interface
+// Route 1: fe80::1234:5678:9abc dev <linkToName>
+// Route 2: default via fe80::1234:5678:9abc dev <linkToName>
+func (client *TransparentVlanEndpointClient) addDefaultIPv6Routes(linkToName string, table int) error {
+	virtualGwIP, virtualGwNet, _ := net.ParseCIDR(virtualv6GwString)
+	gwRoute := RouteInfo{
+		Dst:   *virtualGwNet,
+		Scope: netlink.RT_SCOPE_LINK,
+		Table: table,
+	}
+
+	_, defaultIPNet, _ := net.ParseCIDR(defaultv6Cidr)
+	defaultRoute := RouteInfo{
+		Dst:   *defaultIPNet,
+		Gw:    virtualGwIP,
+		Table: table,
+	}
+
+	return addRoutes(client.netlink, client.netioshim, linkToName, []RouteInfo{gwRoute, defaultRoute})
+}
+
+// Helper that creates the neighbor entry for the current NS which maps the virtual
+// ipv6 gateway to destMac on a particular interfaceName
+// Example: fe80::1234:5678:9abc dev <interfaceName> lladdr 12:34:56:78:9a:bc PERMANENT
+func (client *TransparentVlanEndpointClient) addDefaultIPv6Neigh(interfaceName, destMac string) error {
+	virtualGwIP, _, _ := net.ParseCIDR(virtualv6GwString)
+	logger.Info("Adding static neighbor entry for",
+		zap.String("IP", virtualGwIP.String()), zap.String("MAC", destMac))
+	hardwareAddr, err := net.ParseMAC(destMac)
+	if err != nil {
+		return errors.Wrap(err, "unable to parse mac")
+	}
+	linkInfo := netlink.LinkInfo{
+		Name:       interfaceName,
+		IPAddr:     virtualGwIP,
+		MacAddress: hardwareAddr,
+	}
+
+	if err := client.netlink.SetOrRemoveLinkAddress(linkInfo, netlink.ADD, netlink.NUD_PERMANENT); err != nil {
+		return fmt.Errorf("adding neighbor entry failed: %w", err)
+	}
+	return nil
+}
+
+// Adds the ipv6 default routes of the virtual gateway on the vlan interface to each of the tables, the neighbor
+// entry of the virtual gateway and answers neighbor solicitations for the pod ipv6 addresses on the vlan interface
+func (client *TransparentVlanEndpointClient) configureVnetIPv6(epInfo *EndpointInfo, tables ...int) error {
+	for _, table := range tables {
+		if err := client.addDefaultIPv6Routes(client.vlanIfName, table); err != nil {
+			return errors.Wrapf(err, "failed add default ipv6 routes to table %d (idempotent)", table)
+		}
+	}
+	if err := client.addDefaultIPv6Neigh(client.vlanIfName, azureMac); err != nil {
+		return errors.Wrap(err, "failed add default ipv6 neighbor entry (idempotent)")
+	}
+	return client.addNdpProxy(client.vlanIfName, epInfo.IPAddresses)
+}
+
+// Answers neighbor solicitations for the pod ipv6 addresses on ifName, the ipv6 equivalent of the arp proxy
+func (client *TransparentVlanEndpointClient) addNdpProxy(ifName string, ipAddresses []net.IPNet) error {
+	if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(enableNdpProxyCmd, ifName)); err != nil {
+		return errors.Wrapf(err, "failed to enable ndp proxy on %s", ifName)
+	}
+	for _, ipAddr := range ipv6Addresses(ipAddresses) {
+		if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(addNdpProxyCmd, ipAddr.IP.String(), ifName)); err != nil {
+			return errors.Wrapf(err, "failed to add ndp proxy entry for %s", ipAddr.IP.String())
+		}
+	}
+	return nil
+}
+
+// Removes the ndp proxy entries of the pod ipv6 addresses on ifName
+func (client *TransparentVlanEndpointClient) deleteNdpProxy(ifName string, ipAddresses []net.IPNet) {
+	for _, ipAddr := range ipv6Addresses(ipAddresses) {
+		if _, err := client.plClient.ExecuteRawCommand(fmt.Sprintf(deleteNdpProxyCmd, ipAddr.IP.String(), ifName)); err != nil {
+			logger.Error("Failed to delete ndp proxy entry", zap.String("ip", ipAddr.IP.String()), zap.Error(err))
+		}
+	}
+}
+
+// Add ipv6 rules related to tunneling the packet outside of the VM, assumes all calls are idempotent. Namespace: vnet
+func (client *TransparentVlanEndpointClient) AddVnetIPv6Rules() error {
+	// ip6tables -t mangle -I PREROUTING -j MARK --set-mark <TUNNELING MARK>
+	markOption := fmt.Sprintf("MARK --set-mark %d", tunnelingMark)
+	if err := client.iptablesClient.InsertIptableRule(iptables.V6, "mangle", "PREROUTING", "", markOption); err != nil {
+		return errors.Wrap(err, "unable to insert ip6tables rule mark all packets not entering on vlan interface")
+	}
+	// ip6tables -t mangle -I PREROUTING -j ACCEPT -i <VLAN IF>
+	match := fmt.Sprintf("-i %s", client.vlanIfName)
+	if err := client.iptablesClient.InsertIptableRule(iptables.V6, "mangle", "PREROUTING", match, "ACCEPT"); err != nil {
+		return errors.Wrap(err, "unable to insert ip6tables rule accept all incoming from vlan interface")
+	}
+
+	// Packets that are marked should go to the tunneling table
+	newRule := vishnetlink.NewRule()
+	newRule.Family = vishnetlink.FAMILY_V6
+	newRule.Mark = tunnelingMark
+	newRule.Table = tunnelingTable
+	rules, err := vishnetlink.RuleList(vishnetlink.FAMILY_V6)
+	if err != nil {
+		return errors.Wrap(err, "unable to get existing ipv6 rule list")
+	}
+	for index := range rules {
+		if rules[index].Mark == newRule.Mark {
+			return nil
+		}
+	}
+	if err := vishnetlink.RuleAdd(newRule); err != nil {
+		return errors.Wrap(err, "failed to add ipv6 rule that forwards packet with mark to tunneling routing table")
+	}
+	return nil
+}
@@ -8,7 +8,7 @@ func (client *TransparentVlanEndpointClient) isSnatEnabled() bool {
 	return client.enableSnatOnHost || client.allowInboundFromHostToNC || client.allowInboundFromNCToHost || client.enableSnatForDNS
 }
 
-func (client *TransparentVlanEndpointClient) NewSnatClient(snatBridgeIP, localIP string, epInfo *EndpointInfo) {
+func (client *TransparentVlanEndpointClient) NewSnatClient(snatBridgeIP, localIP, snatBridgeIPv6, localIPv6 string, epInfo *EndpointInfo) {
 	if client.isSnatEnabled() {
 		client.snatClient = snat.NewSnatClient(
 			GetSnatHostIfName(epInfo),
@@ -23,6 +23,7 @@ func (client *TransparentVlanEndpointClient) NewSnatClient(snatBridgeIP, localIP
 			client.iptablesClient,
 			client.netioshim,
 		)
+		client.snatClient.SetIPv6Config(localIPv6, snatBridgeIPv6)
 	}
 }
 
@@ -76,6 +76,7 @@ func NewTransparentVlanEndpointClient(
 	containerVethName string,
 	vlanid int,
 	localIP string,
+	localIPv6 string,
 	nl netlink.NetlinkInterface,
 	plc platform.ExecClient,
 	nsc NamespaceClientInterface,
@@ -105,7 +106,7 @@ func NewTransparentVlanEndpointClient(
 		iptablesClient:           iptc,
 	}
 
-	client.NewSnatClient(nw.SnatBridgeIP, localIP, ep)
+	client.NewSnatClient(nw.SnatBridgeIP, localIP, nw.SnatBridgeIPv6, localIPv6, ep)
 
 	return client
 }
@@ -129,6 +130,11 @@ func (client *TransparentVlanEndpointClient) AddEndpoints(epInfo *EndpointInfo)
 	if err := client.ensureCleanPopulateVM(); err != nil {
 		return errors.Wrap(err, "failed to ensure both network namespace and vlan interface were present or both absent")
 	}
+	if hasIPv6(epInfo.IPAddresses) {
+		if err := client.enableIPv6(); err != nil {
+			return err
+		}
+	}
 	if err := client.PopulateVM(epInfo); err != nil {
 		return err
 	}
@@ -137,6 +143,11 @@ func (client *TransparentVlanEndpointClient) AddEndpoints(epInfo *EndpointInfo)
 	}
 	// VNET Namespace
 	return ExecuteInNS(client.nsClient, client.vnetNSName, func() error {
+		if hasIPv6(epInfo.IPAddresses) {
+			if err := client.enableIPv6(); err != nil {
+				return err
+			}
+		}
 		return client.PopulateVnet(epInfo)
 	})
 }
@@ -419,6 +430,11 @@ func (client *TransparentVlanEndpointClient) AddEndpointRules(epInfo *EndpointIn
 		if err := client.AddVnetRules(epInfo); err != nil {
 			return err
 		}
+		if hasIPv6(epInfo.IPAddresses) {
+			if err := client.AddVnetIPv6Rules(); err != nil {
+				return err
+			}
+		}
 
 		// Set ARP proxy on vnet veth (inside vnet namespace)
 		logger.Info("calling setArpProxy for", zap.String("vnetVethName", client.vnetVethName))
@@ -548,6 +564,15 @@ func (client *TransparentVlanEndpointClient) ConfigureContainerInterfacesAndRout
 	if err := client.AddDefaultArp(client.containerVethName, client.vnetMac.String()); err != nil {
 		return errors.Wrap(err, "failed container ns add default arp")
 	}
+
+	if hasIPv6(epInfo.IPAddresses) {
+		if err := client.addDefaultIPv6Routes(client.containerVethName, 0); err != nil {
+			return errors.Wrap(err, "failed container ns add default ipv6 routes")
+		}
+		if err := client.addDefaultIPv6Neigh(client.containerVethName, client.vnetMac.String()); err != nil {
+			return errors.Wrap(err, "failed container ns add default ipv6 neighbor entry")
+		}
+	}
 	return nil
 }
 
@@ -577,6 +602,12 @@ func (client *TransparentVlanEndpointClient) ConfigureVnetInterfacesAndRoutesImp
 	if err = client.addDefaultRoutes(client.vlanIfName, tunnelingTable); err != nil {
 		return errors.Wrap(err, "failed vnet ns add outbound routing table routes for tunneling (idempotent)")
 	}
+
+	if hasIPv6(epInfo.IPAddresses) {
+		if err = client.configureVnetIPv6(epInfo, 0, tunnelingTable); err != nil {
+			return err
+		}
+	}
 	// Return to ConfigureContainerInterfacesAndRoutes
 	return err
 }
@@ -689,6 +720,7 @@ func (client *TransparentVlanEndpointClient) DeleteEndpointsImpl(ep *endpoint, _
 	if err := deleteRoutes(client.netlink, client.netioshim, client.vnetVethName, routeInfoList); err != nil {
 		logger.Error("Failed to remove routes", zap.Error(err))
 	}
+	client.deleteNdpProxy(client.vlanIfName, ep.IPAddresses)
 
 	logger.Info("Deleting host veth", zap.String("vnetVethName", client.vnetVethName))
 	// Delete Host Veth
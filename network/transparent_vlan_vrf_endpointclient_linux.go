@@ -0,0 +1,308 @@
+package network
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	vrfTableBase = 1000 // The routing table of a vlan's VRF is vrfTableBase + vlan id
+)
+
+// TransparentVlanVrfEndpointClient isolates the vlans of a transparent-vlan network with one VRF per vlan in the VM
+// namespace instead of one network namespace per vlan. The vlan interface and the vnet veths of the vlan are enslaved
+// to the VRF, so the pod routes and the default route towards the vlan interface live in the VRF's routing table.
+// Everything inside the container namespace, as well as snat, is handled the same way as in netns isolation mode.
+type TransparentVlanVrfEndpointClient struct {
+	*TransparentVlanEndpointClient
+	vrfName  string // So like azvrf_1
+	vrfTable int
+}
+
+func NewTransparentVlanVrfEndpointClient(
+	nw *network,
+	ep *EndpointInfo,
+	vnetVethName string,
+	containerVethName string,
+	vlanid int,
+	localIP string,
+	localIPv6 string,
+	nl netlink.NetlinkInterface,
+	plc platform.ExecClient,
+	nsc NamespaceClientInterface,
+	iptc ipTablesClient,
+) *TransparentVlanVrfEndpointClient {
+	return &TransparentVlanVrfEndpointClient{
+		TransparentVlanEndpointClient: NewTransparentVlanEndpointClient(nw, ep, vnetVethName, containerVethName, vlanid, localIP, localIPv6, nl, plc, nsc, iptc),
+		vrfName:                       fmt.Sprintf("azvrf_%d", vlanid),
+		vrfTable:                      vrfTableBase + vlanid,
+	}
+}
+
+// newTransparentVlanClient returns the transparent-vlan endpoint client matching the vlan isolation mode of the endpoint.
+func newTransparentVlanClient(
+	nw *network,
+	ep *EndpointInfo,
+	vnetVethName string,
+	containerVethName string,
+	vlanid int,
+	localIP string,
+	localIPv6 string,
+	nl netlink.NetlinkInterface,
+	plc platform.ExecClient,
+	nsc NamespaceClientInterface,
+	iptc ipTablesClient,
+) EndpointClient {
+	if ep.VlanIsolationMode == VlanIsolationVRF {
+		logger.Info("Transparent vlan client with vrf isolation")
+		return NewTransparentVlanVrfEndpointClient(nw, ep, vnetVethName, containerVethName, vlanid, localIP, localIPv6, nl, plc, nsc, iptc)
+	}
+	return NewTransparentVlanEndpointClient(nw, ep, vnetVethName, containerVethName, vlanid, localIP, localIPv6, nl, plc, nsc, iptc)
+}
+
+// Adds the vrf and vlan interface (created if not existing) and the veth pair of the endpoint, Namespace: VM
+func (client *TransparentVlanVrfEndpointClient) AddEndpoints(epInfo *EndpointInfo) error {
+	if hasIPv6(epInfo.IPAddresses) {
+		if err := client.enableIPv6(); err != nil {
+			return err
+		}
+	}
+	if err := client.ensureVrf(); err != nil {
+		return errors.Wrap(err, "failed to ensure vrf")
+	}
+	if err := client.ensureVlanInterface(); err != nil {
+		return errors.Wrap(err, "failed to ensure vlan interface")
+	}
+	if err := client.populateVrf(epInfo); err != nil {
+		return err
+	}
+	if err := client.AddSnatEndpoint(); err != nil {
+		return errors.Wrap(err, "failed to add snat endpoint")
+	}
+	return nil
+}
+
+// Called from AddEndpoints, Namespace: VM
+func (client *TransparentVlanVrfEndpointClient) ensureVrf() error {
+	if _, err := client.netioshim.GetNetworkInterfaceByName(client.vrfName); err != nil {
+		logger.Info("Creating vrf", zap.String("vrfName", client.vrfName), zap.Int("table", client.vrfTable))
+		link := &netlink.VRFLink{
+			LinkInfo: netlink.LinkInfo{
+				Type: netlink.LINK_TYPE_VRF,
+				Name: client.vrfName,
+			},
+			Table: uint32(client.vrfTable),
+		}
+		if err = client.netlink.AddLink(link); err != nil {
+			return errors.Wrapf(err, "failed to create vrf %s", client.vrfName)
+		}
+	}
+
+	if err := client.netlink.SetLinkState(client.vrfName, true); err != nil {
+		return errors.Wrapf(err, "failed to set vrf %s up", client.vrfName)
+	}
+	return nil
+}
+
+// Called from AddEndpoints, Namespace: VM
+func (client *TransparentVlanVrfEndpointClient) ensureVlanInterface() error {
+	if _, err := client.netioshim.GetNetworkInterfaceByName(client.vlanIfName); err != nil {
+		eth0, err := client.netioshim.GetNetworkInterfaceByName(client.primaryHostIfName)
+		if err != nil {
+			return errors.Wrap(err, "failed to get eth0 interface")
+		}
+
+		logger.Info("Creating vlan interface", zap.String("vlanIfName", client.vlanIfName), zap.Int("vlanID", client.vlanID))
+		link := &netlink.VlanLink{
+			LinkInfo: netlink.LinkInfo{
+				Type:        netlink.LINK_TYPE_VLAN,
+				Name:        client.vlanIfName,
+				ParentIndex: eth0.Index,
+			},
+			VlanID: uint16(client.vlanID),
+		}
+		if err = client.netlink.AddLink(link); err != nil {
+			return errors.Wrapf(err, "failed to create vlan interface %s", client.vlanIfName)
+		}
+
+		// sometimes there is slight delay in interface creation. check if it exists
+		err = RunWithRetries(func() error {
+			_, getErr := client.netioshim.GetNetworkInterfaceByName(client.vlanIfName)
+			return errors.Wrap(getErr, "failed to get vlan interface")
+		}, numRetries, sleepInMs)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get vlan interface: %s", client.vlanIfName)
+		}
+
+		if err = client.netUtilsClient.DisableRAForInterface(client.vlanIfName); err != nil {
+			if delErr := client.netlink.DeleteLink(client.vlanIfName); delErr != nil {
+				logger.Error("Deleting vlan interface failed on addendpoint failure with", zap.Error(delErr))
+			}
+			return errors.Wrap(err, "failed to disable router advertisements for vlan interface")
+		}
+	}
+
+	if err := client.enslaveToVrf(client.vlanIfName); err != nil {
+		return err
+	}
+	if err := client.netlink.SetLinkState(client.vlanIfName, true); err != nil {
+		return errors.Wrapf(err, "failed to set vlan interface %s up", client.vlanIfName)
+	}
+
+	if err := client.addDefaultRoutes(client.vlanIfName, client.vrfTable); err != nil {
+		return errors.Wrap(err, "failed vrf add default/gateway routes (idempotent)")
+	}
+	if err := client.AddDefaultArp(client.vlanIfName, azureMac); err != nil {
+		return errors.Wrap(err, "failed vrf add default arp entry (idempotent)")
+	}
+	return nil
+}
+
+// enslaveToVrf sets the vrf as the master of ifName unless it already is.
+func (client *TransparentVlanVrfEndpointClient) enslaveToVrf(ifName string) error {
+	master, err := client.netlink.GetLinkMaster(ifName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get master of %s", ifName)
+	}
+	if master == client.vrfName {
+		return nil
+	}
+
+	logger.Info("Enslaving interface to vrf", zap.String("ifName", ifName), zap.String("vrfName", client.vrfName))
+	if err := client.netlink.SetLinkMaster(ifName, client.vrfName); err != nil {
+		return errors.Wrapf(err, "failed to enslave %s to vrf %s", ifName, client.vrfName)
+	}
+	return nil
+}
+
+// Called from AddEndpoints, Namespace: VM
+func (client *TransparentVlanVrfEndpointClient) populateVrf(_ *EndpointInfo) error {
+	// Get the default constant host veth mac
+	mac, err := net.ParseMAC(defaultHostVethHwAddr)
+	if err != nil {
+		logger.Info("Failed to parse the mac address", zap.String("defaultHostVethHwAddr", defaultHostVethHwAddr))
+	}
+
+	// Proactively clean up any leftover veth interfaces before creating new ones
+	if vnetDelErr := client.cleanupInterfaceIfExists(client.vnetVethName); vnetDelErr != nil {
+		logger.Info("Could not proactively clean up vnet veth", zap.String("vnetVethName", client.vnetVethName), zap.Error(vnetDelErr))
+	}
+	if containerDelErr := client.cleanupInterfaceIfExists(client.containerVethName); containerDelErr != nil {
+		logger.Info("Could not proactively clean up container veth", zap.String("containerVethName", client.containerVethName), zap.Error(containerDelErr))
+	}
+
+	// Create veth pair
+	if err = client.netUtilsClient.CreateEndpoint(client.vnetVethName, client.containerVethName, mac); err != nil {
+		return errors.Wrap(err, "failed to create veth pair")
+	}
+
+	// Ensure the veth pair is created, as there may be a slight delay
+	var vnetVethIf, containerIf *net.Interface
+	err = RunWithRetries(func() error {
+		var getErr error
+		if vnetVethIf, getErr = client.netioshim.GetNetworkInterfaceByName(client.vnetVethName); getErr != nil {
+			return errors.Wrap(getErr, "failed to get vnet veth")
+		}
+		containerIf, getErr = client.netioshim.GetNetworkInterfaceByName(client.containerVethName)
+		return errors.Wrap(getErr, "failed to get container veth")
+	}, numRetries, sleepInMs)
+	if err != nil {
+		return errors.Wrap(err, "veth pair does not exist")
+	}
+
+	// Disable RA for veth pair, and delete if any failure
+	for _, ifName := range []string{client.vnetVethName, client.containerVethName} {
+		if err = client.netUtilsClient.DisableRAForInterface(ifName); err != nil {
+			if delErr := client.netlink.DeleteLink(client.vnetVethName); delErr != nil {
+				logger.Error("Deleting vnet veth failed on addendpoint failure with", zap.Error(delErr))
+			}
+			return errors.Wrapf(err, "failed to disable RA on %s, deleting", ifName)
+		}
+	}
+
+	if err = client.enslaveToVrf(client.vnetVethName); err != nil {
+		if delErr := client.netlink.DeleteLink(client.vnetVethName); delErr != nil {
+			logger.Error("Deleting vnet veth failed on addendpoint failure with", zap.Error(delErr))
+		}
+		return errors.Wrap(err, "failed to enslave vnet veth to vrf, deleting")
+	}
+
+	client.vnetMac = vnetVethIf.HardwareAddr
+	client.containerMac = containerIf.HardwareAddr
+	return nil
+}
+
+// Adds the pod routes to the vrf table, Namespace: VM
+func (client *TransparentVlanVrfEndpointClient) AddEndpointRules(epInfo *EndpointInfo) error {
+	if err := client.AddSnatEndpointRules(); err != nil {
+		return errors.Wrap(err, "failed to add snat endpoint rules")
+	}
+
+	// Wireserver traffic forwarded from the vnet veth is already dropped by the FORWARD rule added with the network
+	routeInfoList := client.getVrfRoutes(epInfo.IPAddresses)
+
+	// Delete old route if any for this IP
+	err := deleteRoutes(client.netlink, client.netioshim, "", routeInfoList)
+	logger.Info("[transparent-vlan] Deleting old vrf routes returned", zap.Error(err))
+
+	if err = addRoutes(client.netlink, client.netioshim, client.vnetVethName, routeInfoList); err != nil {
+		return errors.Wrap(err, "failed adding routes to vrf specific to this container")
+	}
+
+	if hasIPv6(epInfo.IPAddresses) {
+		if err = client.configureVnetIPv6(epInfo, client.vrfTable); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("calling setArpProxy for", zap.String("vnetVethName", client.vnetVethName))
+	return client.setArpProxy(client.vnetVethName)
+}
+
+// getVrfRoutes returns the routes of the pod ip(s) in the vrf table
+// Example: 192.168.0.4 dev <vnet veth> table <vrf table>
+func (client *TransparentVlanVrfEndpointClient) getVrfRoutes(ipAddresses []net.IPNet) []RouteInfo {
+	routeInfoList := client.GetVnetRoutes(ipAddresses)
+	for i := range routeInfoList {
+		routeInfoList[i].Table = client.vrfTable
+	}
+	return routeInfoList
+}
+
+// Adds routes and arp entries to the container namespace
+func (client *TransparentVlanVrfEndpointClient) ConfigureContainerInterfacesAndRoutes(epInfo *EndpointInfo) error {
+	// Container NS
+	if err := client.ConfigureContainerInterfacesAndRoutesImpl(epInfo); err != nil {
+		return err
+	}
+
+	if err := client.ConfigureSnatContainerInterface(); err != nil {
+		return errors.Wrap(err, "failed to configure snat container interface")
+	}
+	return nil
+}
+
+// Deletes the pod routes from the vrf table and the veth pair of the endpoint, Namespace: VM
+// The vrf and the vlan interface are shared by all endpoints of the vlan and are left as is.
+func (client *TransparentVlanVrfEndpointClient) DeleteEndpoints(ep *endpoint) error {
+	routeInfoList := client.getVrfRoutes(ep.IPAddresses)
+	if err := deleteRoutes(client.netlink, client.netioshim, client.vnetVethName, routeInfoList); err != nil {
+		logger.Error("Failed to remove vrf routes", zap.Error(err))
+	}
+	client.deleteNdpProxy(client.vlanIfName, ep.IPAddresses)
+
+	logger.Info("Deleting host veth", zap.String("vnetVethName", client.vnetVethName))
+	if err := client.netlink.DeleteLink(client.vnetVethName); err != nil {
+		logger.Error("Failed to delete link", zap.Error(err), zap.String("vnetVethName", client.vnetVethName))
+	}
+
+	if err := client.DeleteSnatEndpoint(); err != nil {
+		return errors.Wrap(err, "failed to delete snat endpoint")
+	}
+	return nil
+}
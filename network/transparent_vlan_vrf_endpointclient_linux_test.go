@@ -0,0 +1,257 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/iptables"
+	"github.com/Azure/azure-container-networking/netio"
+	"github.com/Azure/azure-container-networking/netlink"
+	"github.com/Azure/azure-container-networking/network/networkutils"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/stretchr/testify/require"
+)
+
+// vrfNetlink records the links, masters and routes programmed by the vrf endpoint client.
+// Links added through it are reported as existing by the shared mockNetIO.
+type vrfNetlink struct {
+	*netlink.MockNetlink
+	netio         *mockNetIO
+	links         []netlink.Link
+	masters       map[string]string
+	addedRoutes   []netlink.Route
+	deletedRoutes []netlink.Route
+}
+
+func newVrfNetlink(existing ...string) *vrfNetlink {
+	nl := &vrfNetlink{
+		MockNetlink: netlink.NewMockNetlink(false, ""),
+		netio:       &mockNetIO{existingInterfaces: map[string]bool{}, err: errMockNetIOFail},
+		masters:     map[string]string{},
+	}
+	for _, name := range existing {
+		nl.netio.existingInterfaces[name] = true
+	}
+	nl.GetLinkMasterFn = func(name string) (string, error) {
+		return nl.masters[name], nil
+	}
+	nl.SetAddRouteValidationFn(func(r *netlink.Route) error {
+		nl.addedRoutes = append(nl.addedRoutes, *r)
+		return nil
+	})
+	nl.SetDeleteRouteValidationFn(func(r *netlink.Route) error {
+		nl.deletedRoutes = append(nl.deletedRoutes, *r)
+		return nil
+	})
+	return nl
+}
+
+func (nl *vrfNetlink) AddLink(l netlink.Link) error {
+	nl.links = append(nl.links, l)
+	nl.netio.existingInterfaces[l.Info().Name] = true
+	if veth, ok := l.(*netlink.VEthLink); ok {
+		nl.netio.existingInterfaces[veth.PeerName] = true
+	}
+	return nil
+}
+
+func (nl *vrfNetlink) DeleteLink(name string) error {
+	delete(nl.netio.existingInterfaces, name)
+	return nil
+}
+
+func (nl *vrfNetlink) SetLinkMaster(name, master string) error {
+	nl.masters[name] = master
+	return nil
+}
+
+func newTestVrfClient(nl netlink.NetlinkInterface, nio netio.NetIOInterface) *TransparentVlanVrfEndpointClient {
+	plc := platform.NewMockExecClient(false)
+	return &TransparentVlanVrfEndpointClient{
+		TransparentVlanEndpointClient: &TransparentVlanEndpointClient{
+			primaryHostIfName: "eth0",
+			vlanIfName:        "eth0_1",
+			vnetVethName:      "A1veth0",
+			containerVethName: "B1veth0",
+			vlanID:            1,
+			netlink:           nl,
+			plClient:          plc,
+			netUtilsClient:    networkutils.NewNetworkUtils(nl, plc),
+			netioshim:         nio,
+			nsClient:          NewMockNamespaceClient(),
+		},
+		vrfName:  "azvrf_1",
+		vrfTable: vrfTableBase + 1,
+	}
+}
+
+func TestTransparentVlanVrfAddEndpoints(t *testing.T) {
+	t.Run("vrf and vlan interface are created", func(t *testing.T) {
+		nl := newVrfNetlink("eth0")
+		client := newTestVrfClient(nl, nl.netio)
+
+		require.NoError(t, client.AddEndpoints(&EndpointInfo{}))
+
+		require.Len(t, nl.links, 3)
+		vrf, ok := nl.links[0].(*netlink.VRFLink)
+		require.True(t, ok)
+		require.Equal(t, "azvrf_1", vrf.Name)
+		require.Equal(t, uint32(1001), vrf.Table)
+		vlan, ok := nl.links[1].(*netlink.VlanLink)
+		require.True(t, ok)
+		require.Equal(t, "eth0_1", vlan.Name)
+		require.Equal(t, uint16(1), vlan.VlanID)
+		require.Equal(t, 2, vlan.ParentIndex)
+		_, ok = nl.links[2].(*netlink.VEthLink)
+		require.True(t, ok)
+
+		require.Equal(t, map[string]string{"eth0_1": "azvrf_1", "A1veth0": "azvrf_1"}, nl.masters)
+		require.Len(t, nl.addedRoutes, 2)
+		for _, r := range nl.addedRoutes {
+			require.Equal(t, 1001, r.Table)
+		}
+		require.NotNil(t, client.vnetMac)
+		require.NotNil(t, client.containerMac)
+	})
+
+	t.Run("existing vrf and vlan interface are reused", func(t *testing.T) {
+		nl := newVrfNetlink("eth0", "azvrf_1", "eth0_1")
+		nl.masters["eth0_1"] = "azvrf_1"
+		client := newTestVrfClient(nl, nl.netio)
+
+		require.NoError(t, client.AddEndpoints(&EndpointInfo{}))
+
+		// only the veth pair of the endpoint is created
+		require.Len(t, nl.links, 1)
+		_, ok := nl.links[0].(*netlink.VEthLink)
+		require.True(t, ok)
+		require.Equal(t, "azvrf_1", nl.masters["A1veth0"])
+	})
+
+	t.Run("fail to create vrf", func(t *testing.T) {
+		nl := netlink.NewMockNetlink(true, "netlink fail")
+		client := newTestVrfClient(nl, &mockNetIO{existingInterfaces: map[string]bool{}, err: errMockNetIOFail})
+
+		err := client.AddEndpoints(&EndpointInfo{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create vrf azvrf_1")
+	})
+
+	t.Run("fail to get eth0", func(t *testing.T) {
+		nl := newVrfNetlink("azvrf_1")
+		client := newTestVrfClient(nl, nl.netio)
+
+		err := client.AddEndpoints(&EndpointInfo{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get eth0 interface")
+	})
+}
+
+func TestTransparentVlanVrfAddEndpointRules(t *testing.T) {
+	nl := newVrfNetlink("A1veth0")
+	client := newTestVrfClient(nl, nl.netio)
+	epInfo := &EndpointInfo{
+		IPAddresses: []net.IPNet{
+			{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+		},
+	}
+
+	require.NoError(t, client.AddEndpointRules(epInfo))
+
+	// the old route of the pod ip is deleted before the new one is added
+	require.Len(t, nl.deletedRoutes, 1)
+	require.Equal(t, 1001, nl.deletedRoutes[0].Table)
+	require.Len(t, nl.addedRoutes, 1)
+	require.Equal(t, "192.168.0.4/32", nl.addedRoutes[0].Dst.String())
+	require.Equal(t, 1001, nl.addedRoutes[0].Table)
+	require.Equal(t, 2, nl.addedRoutes[0].LinkIndex)
+}
+
+func TestTransparentVlanVrfDeleteEndpoints(t *testing.T) {
+	nl := newVrfNetlink("A1veth0")
+	client := newTestVrfClient(nl, nl.netio)
+	ep := &endpoint{
+		IPAddresses: []net.IPNet{
+			{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+			{IP: net.ParseIP("192.168.0.6"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+		},
+	}
+
+	require.NoError(t, client.DeleteEndpoints(ep))
+
+	require.Len(t, nl.deletedRoutes, 2)
+	for _, r := range nl.deletedRoutes {
+		require.Equal(t, 1001, r.Table)
+	}
+	require.NotContains(t, nl.netio.existingInterfaces, "A1veth0")
+}
+
+func TestNewTransparentVlanClient(t *testing.T) {
+	nw := &network{extIf: &externalInterface{Name: "eth0"}}
+	nl := netlink.NewMockNetlink(false, "")
+	plc := platform.NewMockExecClient(false)
+
+	client := newTransparentVlanClient(nw, &EndpointInfo{}, "A1veth0", "B1veth0", 1, "", "", nl, plc, NewMockNamespaceClient(), iptables.NewClient())
+	require.IsType(t, &TransparentVlanEndpointClient{}, client)
+
+	client = newTransparentVlanClient(nw, &EndpointInfo{VlanIsolationMode: VlanIsolationNetns}, "A1veth0", "B1veth0", 1, "", "", nl, plc, NewMockNamespaceClient(), iptables.NewClient())
+	require.IsType(t, &TransparentVlanEndpointClient{}, client)
+
+	client = newTransparentVlanClient(nw, &EndpointInfo{VlanIsolationMode: VlanIsolationVRF}, "A1veth0", "B1veth0", 1, "", "", nl, plc, NewMockNamespaceClient(), iptables.NewClient())
+	require.IsType(t, &TransparentVlanVrfEndpointClient{}, client)
+	vrfClient := client.(*TransparentVlanVrfEndpointClient)
+	require.Equal(t, "azvrf_1", vrfClient.vrfName)
+	require.Equal(t, 1001, vrfClient.vrfTable)
+}
+
+func TestNewEndpointInvalidVlanIsolationMode(t *testing.T) {
+	nw := &network{Endpoints: map[string]*endpoint{}}
+	epInfo := &EndpointInfo{
+		EndpointID:        "768e8deb-eth1",
+		IfName:            "eth0",
+		NICType:           cns.InfraNIC,
+		VlanIsolationMode: "bogus",
+	}
+
+	ep, err := nw.newEndpointImpl(nil, netlink.NewMockNetlink(false, ""), platform.NewMockExecClient(false),
+		netio.NewMockNetIO(false, 0), NewMockEndpointClient(nil), NewMockNamespaceClient(), iptables.NewClient(), &mockDHCP{}, epInfo)
+	require.ErrorIs(t, err, errVlanIsolationModeInvalid)
+	require.Nil(t, ep)
+}
+
+func TestTransparentVlanVrfAddEndpointRulesDualStack(t *testing.T) {
+	nl := newVrfNetlink("A1veth0", "eth0_1")
+	client := newTestVrfClient(nl, nl.netio)
+	epInfo := &EndpointInfo{
+		IPAddresses: []net.IPNet{
+			{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)},
+			{IP: net.ParseIP("fd00::4"), Mask: net.CIDRMask(64, ipv6Bits)},
+		},
+	}
+
+	require.NoError(t, client.AddEndpointRules(epInfo))
+
+	// pod routes for both addresses and the ipv6 gateway and default routes, all in the vrf table
+	require.Len(t, nl.addedRoutes, 4)
+	for _, r := range nl.addedRoutes {
+		require.Equal(t, 1001, r.Table)
+	}
+	require.Equal(t, "fd00::4/128", nl.addedRoutes[1].Dst.String())
+	require.Equal(t, "fe80::1234:5678:9abc/128", nl.addedRoutes[2].Dst.String())
+	require.Equal(t, "::/0", nl.addedRoutes[3].Dst.String())
+	require.Equal(t, "fe80::1234:5678:9abc", nl.addedRoutes[3].Gw.String())
+}
+
+func TestHasIPv6(t *testing.T) {
+	v4 := net.IPNet{IP: net.ParseIP("192.168.0.4"), Mask: net.CIDRMask(subnetv4Mask, ipv4Bits)}
+	v6 := net.IPNet{IP: net.ParseIP("fd00::4"), Mask: net.CIDRMask(64, ipv6Bits)}
+
+	require.False(t, hasIPv6(nil))
+	require.False(t, hasIPv6([]net.IPNet{v4}))
+	require.True(t, hasIPv6([]net.IPNet{v4, v6}))
+	require.Equal(t, []net.IPNet{v6}, ipv6Addresses([]net.IPNet{v4, v6}))
+}
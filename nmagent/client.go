@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-container-networking/nmagent/internal"
+	"github.com/Azure/azure-container-networking/nmagent/resilience"
 	"github.com/pkg/errors"
 )
 
@@ -23,9 +24,9 @@ func NewClient(c Config) (*Client, error) {
 
 	client := &Client{
 		httpClient: &http.Client{
-			Transport: &internal.WireserverTransport{
+			Transport: resilience.NewTransport(&internal.WireserverTransport{
 				Transport: http.DefaultTransport,
-			},
+			}, c.Resilience),
 		},
 		host:      c.Host,
 		port:      c.Port,
@@ -354,8 +355,9 @@ func (c *Client) buildRequest(ctx context.Context, req Request) (*http.Request,
 		return nil, errors.Wrap(err, "retrieving request body")
 	}
 
+	// the attempts are tracked so that retries of the request are reported
 	// nolint:wrapcheck // wrapping doesn't provide useful information
-	return http.NewRequestWithContext(ctx, req.Method(), fullURL.String(), body)
+	return http.NewRequestWithContext(resilience.TrackAttempts(ctx), req.Method(), fullURL.String(), body)
 }
 
 func (c *Client) scheme() string {
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-container-networking/nmagent/internal"
+	"github.com/Azure/azure-container-networking/nmagent/resilience"
 	"github.com/pkg/errors"
 )
 
@@ -22,6 +23,10 @@ type Config struct {
 	// Optional Config //
 	/////////////////////
 	UseTLS bool // forces all connections to use TLS
+
+	// Resilience configures the circuit breakers, hedging, and rate limit of
+	// requests. The zero value only reports metrics.
+	Resilience resilience.Config
 }
 
 // Validate reports whether this configuration is a valid configuration for a
@@ -0,0 +1,53 @@
+package resilience
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	wirePluginPath = "/machine/plugins"
+	deleteSuffix   = "/method/DELETE"
+)
+
+// APIName identifies the NMAgent API of a request, either made to NMAgent
+// directly or through the Wireserver plugin path, so that it can be used as a
+// metric label.
+func APIName(req *http.Request) string {
+	path := req.URL.Path
+	if strings.TrimSuffix(path, "/") == wirePluginPath {
+		path = req.URL.Query().Get("type")
+	}
+	path = strings.TrimPrefix(path, "/")
+
+	switch {
+	case path == "GetSupportedApis":
+		return "SupportedAPIs"
+	case strings.HasPrefix(path, "GetHomeAz"):
+		return "GetHomeAz"
+	case path == "getinterfaceinfov1":
+		return "GetInterfaceIPInfo"
+	case path == "NetworkManagement/interfaces/api-version/2":
+		return "GetNCVersionList"
+	case strings.HasPrefix(path, "NetworkManagement/joinedVirtualNetworks/"):
+		switch {
+		case strings.HasSuffix(path, deleteSuffix):
+			return "DeleteNetwork"
+		case req.Method == http.MethodGet:
+			return "GetNetworkConfiguration"
+		default:
+			return "JoinNetwork"
+		}
+	case strings.HasPrefix(path, "NetworkManagement/interfaces/"):
+		switch {
+		case strings.HasSuffix(path, deleteSuffix):
+			return "DeleteNetworkContainer"
+		case strings.Contains(path, "/version/authenticationToken/"):
+			return "GetNCVersion"
+		default:
+			return "PutNetworkContainer"
+		}
+	default:
+		return "Unknown"
+	}
+}
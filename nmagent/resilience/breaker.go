@@ -0,0 +1,109 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	closed breakerState = iota
+	halfOpen
+	open
+)
+
+// breaker is a circuit breaker for a single API. It opens after a number of
+// consecutive failures and rejects requests while open. Once the open duration
+// has passed, it lets a single probe request through, and closes if the probe
+// succeeds or opens again if it fails. Each state change starts a new
+// generation, and the outcomes of requests allowed in an older generation are
+// ignored, so a slow request can't affect the state it wasn't allowed in.
+type breaker struct {
+	api              string
+	failureThreshold int
+	openDuration     time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+	// generation is incremented by every state change
+	generation uint64
+}
+
+// allow reports whether a request may be made, and reserves the probe if the
+// breaker is half-open. It returns the generation which the outcome of the
+// request must be recorded with.
+func (b *breaker) allow() (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case open:
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return b.generation, false
+		}
+		b.setState(halfOpen)
+		b.probing = true
+		return b.generation, true
+	case halfOpen:
+		if b.probing {
+			return b.generation, false
+		}
+		b.probing = true
+		return b.generation, true
+	default:
+		return b.generation, true
+	}
+}
+
+// record records the outcome of a request allowed in the generation. Outcomes
+// of older generations are ignored.
+func (b *breaker) record(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case halfOpen:
+		b.probing = false
+		if success {
+			b.failures = 0
+			b.setState(closed)
+			return
+		}
+		b.openedAt = b.now()
+		b.setState(open)
+	case closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.openedAt = b.now()
+			b.setState(open)
+		}
+	case open:
+		// unreachable since the breaker doesn't allow requests while open
+	}
+}
+
+// release releases the probe of a half-open breaker without recording an
+// outcome if the request was allowed in the generation.
+func (b *breaker) release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation == b.generation && b.state == halfOpen {
+		b.probing = false
+	}
+}
+
+func (b *breaker) setState(s breakerState) {
+	b.generation++
+	b.state = s
+	circuitBreakerState.WithLabelValues(b.api).Set(float64(s))
+}
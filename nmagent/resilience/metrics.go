@@ -0,0 +1,61 @@
+package resilience
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	apiLabel  = "api"
+	codeLabel = "code"
+)
+
+var (
+	requestLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "nmagent_request_latency_seconds",
+			Help: "NMAgent and Wireserver request latency in seconds by API and status code.",
+			//nolint:gomnd // default bucket consts
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1 ms to ~16 seconds
+		},
+		[]string{apiLabel, codeLabel},
+	)
+	requestRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nmagent_request_retries_total",
+			Help: "Number of retried NMAgent and Wireserver requests by API.",
+		},
+		[]string{apiLabel},
+	)
+	hedgedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nmagent_hedged_requests_total",
+			Help: "Number of hedged NMAgent and Wireserver requests by API.",
+		},
+		[]string{apiLabel},
+	)
+	circuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nmagent_circuit_breaker_state",
+			Help: "State of the circuit breaker by API: 0 is closed, 1 is half-open, and 2 is open.",
+		},
+		[]string{apiLabel},
+	)
+	rejectedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nmagent_circuit_breaker_rejected_requests_total",
+			Help: "Number of NMAgent and Wireserver requests rejected by an open circuit breaker by API.",
+		},
+		[]string{apiLabel},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		requestLatency,
+		requestRetries,
+		hedgedRequests,
+		circuitBreakerState,
+		rejectedRequests,
+	)
+}
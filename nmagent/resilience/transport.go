@@ -0,0 +1,251 @@
+// Package resilience makes requests to NMAgent and Wireserver resilient to
+// failures of the host, and reports them through Prometheus metrics.
+package resilience
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// ErrCircuitOpen is returned for requests to an API while its circuit breaker
+// is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Config configures the resilience of requests. The zero value only reports
+// metrics.
+type Config struct {
+	// FailureThreshold is the number of consecutive failures of an API which
+	// opens its circuit breaker. Zero disables the circuit breakers.
+	FailureThreshold int
+	// OpenDuration is how long a circuit breaker stays open before it lets a
+	// single probe request through to test whether the API has recovered.
+	OpenDuration time.Duration
+	// HedgeDelay is how long a GET request may take before a second, hedged,
+	// request is made. The first response is used. Zero disables hedging.
+	HedgeDelay time.Duration
+	// RateLimit is the maximum number of requests per second. Zero disables
+	// the rate limiter.
+	RateLimit float64
+	// RateBurst is the number of requests which may exceed the rate limit at
+	// once. It's at least 1.
+	RateBurst int
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// Transport is an http.RoundTripper which applies a rate limit, a circuit
+// breaker per API, and hedging to the requests of the downstream
+// RoundTripper, and reports their latency, status codes, retries, and hedges.
+type Transport struct {
+	next    http.RoundTripper
+	config  Config
+	limiter *rate.Limiter
+	now     func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewTransport returns a Transport making requests through the downstream
+// RoundTripper.
+func NewTransport(next http.RoundTripper, c Config) *Transport {
+	t := &Transport{
+		next:     next,
+		config:   c,
+		now:      time.Now,
+		breakers: map[string]*breaker{},
+	}
+	if c.RateLimit > 0 {
+		t.limiter = rate.NewLimiter(rate.Limit(c.RateLimit), max(c.RateBurst, 1))
+	}
+	return t
+}
+
+type attemptsKey struct{}
+
+// TrackAttempts returns a context which counts the attempts of a request made
+// with it, so that the Transport reports the retries of the request.
+func TrackAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, &atomic.Int32{})
+}
+
+// RoundTrip makes the request through the downstream RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	api := APIName(req)
+	if attempts, ok := req.Context().Value(attemptsKey{}).(*atomic.Int32); ok && attempts.Add(1) > 1 {
+		requestRetries.WithLabelValues(api).Inc()
+	}
+
+	if t.limiter != nil {
+		if err := t.limiter.Wait(req.Context()); err != nil {
+			closeBody(req)
+			return nil, errors.Wrap(err, "waiting for the rate limiter")
+		}
+	}
+
+	b := t.breaker(api)
+	var generation uint64
+	if b != nil {
+		var allowed bool
+		if generation, allowed = b.allow(); !allowed {
+			closeBody(req)
+			rejectedRequests.WithLabelValues(api).Inc()
+			return nil, errors.Wrapf(ErrCircuitOpen, "%s request rejected", api)
+		}
+	}
+
+	start := time.Now()
+	var resp *http.Response
+	var err error
+	if t.hedgeable(req) {
+		resp, err = t.hedge(req, api)
+	} else {
+		resp, err = t.next.RoundTrip(req)
+	}
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	requestLatency.WithLabelValues(api, code).Observe(time.Since(start).Seconds())
+
+	if b != nil {
+		if err != nil && errors.Is(req.Context().Err(), context.Canceled) {
+			// the caller gave up, which says nothing about the API
+			b.release(generation)
+		} else {
+			b.record(generation, succeeded(resp, err))
+		}
+	}
+	return resp, err //nolint:wrapcheck // the downstream RoundTripper's errors are returned as they are
+}
+
+// breaker returns the circuit breaker of the API, or nil if the circuit
+// breakers are disabled.
+func (t *Transport) breaker(api string) *breaker {
+	if t.config.FailureThreshold <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[api]
+	if !ok {
+		b = &breaker{
+			api:              api,
+			failureThreshold: t.config.FailureThreshold,
+			openDuration:     t.config.OpenDuration,
+			now:              t.now,
+		}
+		t.breakers[api] = b
+	}
+	return b
+}
+
+// succeeded reports whether a request succeeded, as far as the circuit breaker
+// is concerned.
+func succeeded(resp *http.Response, err error) bool {
+	if err != nil {
+		return false
+	}
+	return resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests
+}
+
+// hedgeable reports whether the request is idempotent, and may be hedged.
+func (t *Transport) hedgeable(req *http.Request) bool {
+	return t.config.HedgeDelay > 0 && req.Method == http.MethodGet && (req.Body == nil || req.Body == http.NoBody)
+}
+
+type attempt struct {
+	index  int
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+// discard cancels the attempt and closes its response.
+func (a attempt) discard() {
+	a.cancel()
+	if a.resp != nil {
+		a.resp.Body.Close()
+	}
+}
+
+// hedge makes the request, and makes it again if it hasn't completed after the
+// hedge delay. The first successful attempt is used, and the other attempt is
+// canceled.
+func (t *Transport) hedge(req *http.Request, api string) (*http.Response, error) {
+	attempts := make(chan attempt, 2) //nolint:gomnd // the request and its hedge
+	var cancels []context.CancelFunc
+	start := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := t.next.RoundTrip(req.Clone(ctx))
+			attempts <- attempt{index: index, resp: resp, err: err, cancel: cancel}
+		}()
+	}
+	start()
+
+	timer := time.NewTimer(t.config.HedgeDelay)
+	defer timer.Stop()
+
+	var winner attempt
+	select {
+	case winner = <-attempts:
+	case <-timer.C:
+		// the hedge counts against the rate limit, so it's skipped when the
+		// limit has been reached
+		if t.limiter != nil && !t.limiter.Allow() {
+			winner = <-attempts
+			break
+		}
+		hedgedRequests.WithLabelValues(api).Inc()
+		start()
+		winner = <-attempts
+		if winner.err != nil {
+			winner.discard()
+			winner = <-attempts
+			break
+		}
+		// cancel the slower attempt, and close its response once it returns
+		cancels[1-winner.index]()
+		go func() {
+			loser := <-attempts
+			loser.discard()
+		}()
+	}
+
+	if winner.err != nil {
+		winner.cancel()
+		return nil, winner.err
+	}
+	winner.resp.Body = &cancelOnClose{ReadCloser: winner.resp.Body, cancel: winner.cancel}
+	return winner.resp, nil
+}
+
+// cancelOnClose cancels the context of the request once its response body is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close() //nolint:wrapcheck // the body's errors are returned as they are
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
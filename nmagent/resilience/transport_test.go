@@ -0,0 +1,265 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func respond(code int) (*http.Response, error) {
+	rr := httptest.NewRecorder()
+	rr.WriteHeader(code)
+	return rr.Result(), nil
+}
+
+func newRequest(t *testing.T, ctx context.Context, method, url string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, url, http.NoBody)
+	if err != nil {
+		t.Fatal("unexpected error building request: err:", err)
+	}
+	return req
+}
+
+func roundTrip(t *testing.T, tr http.RoundTripper, req *http.Request) (int, error) {
+	t.Helper()
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestAPIName(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		exp    string
+	}{
+		{http.MethodGet, "http://localhost/NetworkManagement/interfaces/api-version/2", "GetNCVersionList"},
+		{http.MethodGet, "http://localhost/GetHomeAz/api-version/1", "GetHomeAz"},
+		{http.MethodGet, "http://localhost/GetSupportedApis", "SupportedAPIs"},
+		{http.MethodGet, "http://localhost/getinterfaceinfov1", "GetInterfaceIPInfo"},
+		{http.MethodPost, "http://localhost/NetworkManagement/joinedVirtualNetworks/vnet/api-version/1", "JoinNetwork"},
+		{http.MethodGet, "http://localhost/NetworkManagement/joinedVirtualNetworks/vnet/api-version/1", "GetNetworkConfiguration"},
+		{http.MethodPost, "http://localhost/NetworkManagement/joinedVirtualNetworks/vnet/api-version/1/method/DELETE", "DeleteNetwork"},
+		{http.MethodPost, "http://localhost/NetworkManagement/interfaces/10.0.0.4/networkContainers/nc/authenticationToken/token/api-version/1", "PutNetworkContainer"},
+		{http.MethodPost, "http://localhost/NetworkManagement/interfaces/10.0.0.4/networkContainers/nc/authenticationToken/token/api-version/1/method/DELETE", "DeleteNetworkContainer"},
+		{http.MethodGet, "http://localhost/NetworkManagement/interfaces/10.0.0.4/networkContainers/nc/version/authenticationToken/token/api-version/1", "GetNCVersion"},
+		// requests through the Wireserver plugin path
+		{http.MethodGet, "http://localhost/machine/plugins?comp=nmagent&type=getinterfaceinfov1", "GetInterfaceIPInfo"},
+		{http.MethodPost, "http://localhost/machine/plugins/?comp=nmagent&type=NetworkManagement/joinedVirtualNetworks/vnet/api-version/1", "JoinNetwork"},
+		{http.MethodGet, "http://localhost/somewhere/else", "Unknown"},
+	}
+
+	for _, test := range tests {
+		req := newRequest(t, context.Background(), test.method, test.url)
+		if got := APIName(req); got != test.exp {
+			t.Errorf("APIName(%s %s): got %q, exp %q", test.method, test.url, got, test.exp)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	code := http.StatusInternalServerError
+	calls := 0
+	tr := NewTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		calls++
+		return respond(code)
+	}), Config{FailureThreshold: 2, OpenDuration: time.Minute})
+	now := time.Now()
+	tr.now = func() time.Time { return now }
+
+	const url = "http://localhost/GetHomeAz/api-version/1"
+	req := newRequest(t, context.Background(), http.MethodGet, url)
+
+	// the breaker opens after two consecutive failures
+	for i := 0; i < 2; i++ {
+		if got, err := roundTrip(t, tr, req); err != nil || got != code {
+			t.Fatalf("request %d: got %d, %v, exp %d", i, got, err, code)
+		}
+	}
+	if _, err := roundTrip(t, tr, req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected the circuit to be open: err:", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the rejected request not to be made: calls: %d", calls)
+	}
+	if got := testutil.ToFloat64(circuitBreakerState.WithLabelValues("GetHomeAz")); got != float64(open) {
+		t.Fatalf("unexpected breaker state metric: got %v, exp %v", got, open)
+	}
+
+	// other APIs have their own breakers
+	other := newRequest(t, context.Background(), http.MethodGet, "http://localhost/GetSupportedApis")
+	if _, err := roundTrip(t, tr, other); err != nil {
+		t.Fatal("unexpected error for another API: err:", err)
+	}
+
+	// a failed probe opens the breaker again
+	now = now.Add(time.Minute)
+	if _, err := roundTrip(t, tr, req); err != nil {
+		t.Fatal("expected the probe to be made: err:", err)
+	}
+	if _, err := roundTrip(t, tr, req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected the circuit to be open again: err:", err)
+	}
+
+	// a successful probe closes the breaker
+	now = now.Add(time.Minute)
+	code = http.StatusOK
+	for i := 0; i < 3; i++ {
+		if got, err := roundTrip(t, tr, req); err != nil || got != code {
+			t.Fatalf("request %d: got %d, %v, exp %d", i, got, err, code)
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	b := &breaker{api: "test", failureThreshold: 1, openDuration: time.Minute, now: time.Now}
+	generation, _ := b.allow()
+	b.record(generation, false)
+	b.openedAt = time.Now().Add(-time.Minute)
+
+	probe, allowed := b.allow()
+	if !allowed {
+		t.Fatal("expected the probe to be allowed")
+	}
+	if _, allowed = b.allow(); allowed {
+		t.Fatal("expected a single probe at a time")
+	}
+	// a canceled probe lets the next request probe
+	b.release(probe)
+	if probe, allowed = b.allow(); !allowed {
+		t.Fatal("expected another probe to be allowed")
+	}
+	b.record(probe, true)
+	if b.state != closed {
+		t.Fatal("expected the breaker to close after a successful probe")
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleOutcomes(t *testing.T) {
+	b := &breaker{api: "test", failureThreshold: 1, openDuration: time.Minute, now: time.Now}
+	slow, _ := b.allow()
+	generation, _ := b.allow()
+	b.record(generation, false)
+	b.openedAt = time.Now().Add(-time.Minute)
+
+	probe, allowed := b.allow()
+	if !allowed {
+		t.Fatal("expected the probe to be allowed")
+	}
+	// the outcome of a request allowed before the breaker opened doesn't end the probe
+	b.record(slow, true)
+	b.release(slow)
+	if b.state != halfOpen || !b.probing {
+		t.Fatal("expected the breaker to keep waiting for the probe")
+	}
+	if _, allowed = b.allow(); allowed {
+		t.Fatal("expected a single probe at a time")
+	}
+
+	b.record(probe, true)
+	if b.state != closed {
+		t.Fatal("expected the breaker to close after a successful probe")
+	}
+	// nor does it open the closed breaker again
+	b.record(slow, false)
+	if b.state != closed || b.failures != 0 {
+		t.Fatal("expected the breaker to stay closed")
+	}
+}
+
+func TestHedging(t *testing.T) {
+	var calls atomic.Int32
+	firstCanceled := make(chan struct{})
+	tr := NewTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			// the first request stalls until it's canceled
+			<-req.Context().Done()
+			close(firstCanceled)
+			return nil, req.Context().Err()
+		}
+		return respond(http.StatusOK)
+	}), Config{HedgeDelay: 10 * time.Millisecond})
+
+	const api = "GetNCVersionList"
+	before := testutil.ToFloat64(hedgedRequests.WithLabelValues(api))
+	req := newRequest(t, context.Background(), http.MethodGet, "http://localhost/NetworkManagement/interfaces/api-version/2")
+	if got, err := roundTrip(t, tr, req); err != nil || got != http.StatusOK {
+		t.Fatalf("got %d, %v, exp %d", got, err, http.StatusOK)
+	}
+
+	select {
+	case <-firstCanceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stalled request to be canceled")
+	}
+	if got := testutil.ToFloat64(hedgedRequests.WithLabelValues(api)) - before; got != 1 {
+		t.Fatalf("unexpected hedged requests: got %v, exp 1", got)
+	}
+}
+
+func TestNoHedgingForPOST(t *testing.T) {
+	var calls atomic.Int32
+	tr := NewTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return respond(http.StatusOK)
+	}), Config{HedgeDelay: time.Millisecond})
+
+	req := newRequest(t, context.Background(), http.MethodPost, "http://localhost/NetworkManagement/joinedVirtualNetworks/vnet/api-version/1")
+	if _, err := roundTrip(t, tr, req); err != nil {
+		t.Fatal("unexpected error: err:", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected a single request: got %d", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	tr := NewTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return respond(http.StatusOK)
+	}), Config{RateLimit: 0.001, RateBurst: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := newRequest(t, ctx, http.MethodGet, "http://localhost/GetSupportedApis")
+	if _, err := roundTrip(t, tr, req); err != nil {
+		t.Fatal("expected the burst to be allowed: err:", err)
+	}
+	if _, err := roundTrip(t, tr, req); err == nil {
+		t.Fatal("expected the request to exceed the rate limit")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tr := NewTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return respond(http.StatusOK)
+	}), Config{})
+
+	const api = "DeleteNetwork"
+	before := testutil.ToFloat64(requestRetries.WithLabelValues(api))
+	req := newRequest(t, TrackAttempts(context.Background()), http.MethodPost, "http://localhost/NetworkManagement/joinedVirtualNetworks/vnet/api-version/1/method/DELETE")
+	for i := 0; i < 3; i++ {
+		if _, err := roundTrip(t, tr, req); err != nil {
+			t.Fatal("unexpected error: err:", err)
+		}
+	}
+	if got := testutil.ToFloat64(requestRetries.WithLabelValues(api)) - before; got != 2 {
+		t.Fatalf("unexpected retries: got %v, exp 2", got)
+	}
+}
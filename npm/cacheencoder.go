@@ -30,7 +30,7 @@ func CacheEncoder(nodeName string) json.Marshaler {
 	cfg.Toggles.EnableV2NPM = false
 	cfg.Toggles.EnableNPMLite = false
 	// TODO test v2 NPM debug API when it's implemented
-	npMgr := NewNetworkPolicyManager(cfg, kubeInformer, kubeInformer, &dpmocks.MockGenericDataplane{}, exec, npmVersion, fakeK8sVersion)
+	npMgr := NewNetworkPolicyManager(cfg, kubeInformer, kubeInformer, nil, &dpmocks.MockGenericDataplane{}, exec, npmVersion, fakeK8sVersion)
 	npMgr.NodeName = nodeName
 	return npMgr
 }
@@ -13,12 +13,17 @@ import (
 const (
 	iptableSaveFile = "../pkg/dataplane/testdata/iptablesave-v1"
 	npmCacheFile    = "../pkg/dataplane/testdata/npmcachev1.json"
+	testdataDir     = "../pkg/dataplane/testdata"
+	netPolFile      = "../pkg/dataplane/testdata/netpol.yaml"
+	podsFile        = "../pkg/dataplane/testdata/simulator-pods.yaml"
 	nonExistingFile = "non-existing-iptables-file"
 
 	npmCacheFlag         = "-c"
 	iptablesSaveFileFlag = "-i"
 	dstFlag              = "-d"
 	srcFlag              = "-s"
+	fileFlag             = "-f"
+	portFlag             = "-p"
 	unknownShorthandFlag = "-z"
 
 	testIP1 = "10.224.0.87" // from npmCacheWithCustomFormat.json
@@ -28,6 +33,7 @@ const (
 	convertIPTableCmdString = "convertiptable"
 	getTuplesCmdString      = "gettuples"
 	parseIPTableCmdString   = "parseiptable"
+	simulateCmdString       = "simulate"
 )
 
 type testCases struct {
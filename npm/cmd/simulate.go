@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/debug"
+	"github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	expectAllowed = "allowed"
+	expectDenied  = "denied"
+	outputJSON    = "json"
+)
+
+var errInvalidExpect = fmt.Errorf("expect must be %q or %q", expectAllowed, expectDenied)
+
+func newSimulateCmd() *cobra.Command {
+	simulateCmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate whether a flow between pods is allowed by the NetworkPolicies in manifest files",
+		Long: "Simulate whether a flow between pods is allowed by the NetworkPolicies in manifest files.\n" +
+			"The manifests contain Namespaces, Pods or workloads, and NetworkPolicies. " +
+			"Endpoints are <namespace>/<pod or workload name> or an IP.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, _ := cmd.Flags().GetStringSlice("file")
+			if len(files) == 0 {
+				return fmt.Errorf("%w", errors.ErrManifestsNotSpecified)
+			}
+			src, _ := cmd.Flags().GetString("src")
+			if src == "" {
+				return fmt.Errorf("%w", errors.ErrSrcNotSpecified)
+			}
+			dst, _ := cmd.Flags().GetString("dst")
+			if dst == "" {
+				return fmt.Errorf("%w", errors.ErrDstNotSpecified)
+			}
+			protocol, _ := cmd.Flags().GetString("protocol")
+			port, _ := cmd.Flags().GetInt32("port")
+			output, _ := cmd.Flags().GetString("output")
+			expect, _ := cmd.Flags().GetString("expect")
+			expect = strings.ToLower(expect)
+			if expect != "" && expect != expectAllowed && expect != expectDenied {
+				return errInvalidExpect
+			}
+
+			s, err := debug.NewSimulatorFromFiles(files)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			result, err := s.Simulate(src, dst, protocol, port)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			if output == outputJSON {
+				b, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal simulation result: %w", err)
+				}
+				fmt.Println(string(b))
+			} else {
+				debug.PrettyPrintSimulation(result)
+			}
+
+			if (expect == expectAllowed && !result.Allowed) || (expect == expectDenied && result.Allowed) {
+				return fmt.Errorf("%w: expected the flow to be %s", errors.ErrUnexpectedVerdict, expect)
+			}
+			return nil
+		},
+	}
+
+	simulateCmd.Flags().StringSliceP("file", "f", nil, "Set the manifest files or directories (repeatable)")
+	simulateCmd.Flags().StringP("src", "s", "", "set the source")
+	simulateCmd.Flags().StringP("dst", "d", "", "set the destination")
+	simulateCmd.Flags().StringP("protocol", "", "TCP", "set the protocol: TCP, UDP, or SCTP")
+	simulateCmd.Flags().Int32P("port", "p", 0, "set the destination port")
+	simulateCmd.Flags().StringP("output", "o", "", "set the output format: json (optional)")
+	simulateCmd.Flags().StringP("expect", "", "", "fail unless the flow is allowed or denied (optional)")
+
+	return simulateCmd
+}
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestSimulateCmd(t *testing.T) {
+	baseArgs := []string{debugCmdString, simulateCmdString}
+	fileArgs := concatArgs(baseArgs, fileFlag, netPolFile, fileFlag, podsFile)
+	standardArgs := concatArgs(fileArgs, srcFlag, "y/a", dstFlag, "y/b", portFlag, "80")
+
+	tests := []*testCases{
+		{
+			name:    "no files",
+			args:    concatArgs(baseArgs, srcFlag, "y/a", dstFlag, "y/b", portFlag, "80"),
+			wantErr: true,
+		},
+		{
+			name:    "no src",
+			args:    concatArgs(fileArgs, dstFlag, "y/b", portFlag, "80"),
+			wantErr: true,
+		},
+		{
+			name:    "no dst",
+			args:    concatArgs(fileArgs, srcFlag, "y/a", portFlag, "80"),
+			wantErr: true,
+		},
+		{
+			name:    "no port",
+			args:    concatArgs(fileArgs, srcFlag, "y/a", dstFlag, "y/b"),
+			wantErr: true,
+		},
+		{
+			name:    "non-existing file",
+			args:    concatArgs(standardArgs, fileFlag, nonExistingFile),
+			wantErr: true,
+		},
+		{
+			name:    "unknown pod",
+			args:    concatArgs(fileArgs, srcFlag, "y/unknown", dstFlag, "y/b", portFlag, "80"),
+			wantErr: true,
+		},
+		{
+			name:    "allowed flow",
+			args:    concatArgs(standardArgs, "--expect", "allowed"),
+			wantErr: false,
+		},
+		{
+			name:    "allowed flow by IP in json",
+			args:    concatArgs(fileArgs, srcFlag, "10.0.2.1", dstFlag, "10.0.2.2", portFlag, "80", "-o", "json"),
+			wantErr: false,
+		},
+		{
+			name:    "denied flow",
+			args:    concatArgs(standardArgs, "--protocol", "UDP", "--expect", "denied"),
+			wantErr: false,
+		},
+		{
+			name:    "unexpected verdict",
+			args:    concatArgs(standardArgs, "--protocol", "UDP", "--expect", "allowed"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid expect",
+			args:    concatArgs(standardArgs, "--expect", "maybe"),
+			wantErr: true,
+		},
+		{
+			name:    "directory",
+			args:    concatArgs(baseArgs, fileFlag, testdataDir, srcFlag, "y/a", dstFlag, "x/a", portFlag, "80", "--expect", "denied"),
+			wantErr: false,
+		},
+	}
+
+	testCommand(t, tests)
+}
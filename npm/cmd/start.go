@@ -3,34 +3,52 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/crd/networkpolicynodestatus"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/npm"
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
 	restserver "github.com/Azure/azure-container-networking/npm/http/server"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/policystatus"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/flowlog"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/fqdn"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/pkg/models"
 	"github.com/Azure/azure-container-networking/npm/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	k8sversion "k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 	"k8s.io/utils/exec"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	anpclientset "sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned"
+	anpinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
 )
 
+// kubeDNSServiceName is the name of the Service in kube-system whose DNS responses are snooped by default
+const kubeDNSServiceName = "kube-dns"
+
 var npmV2DataplaneCfg = &dataplane.Config{
 	IPSetManagerCfg: &ipsets.IPSetManagerCfg{
 		// NOTE: NetworkName and IPSetMode must be set later by the npm ConfigMap or default config
@@ -135,6 +153,15 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		)
 	}
 
+	var adminPolicyFactory anpinformers.SharedInformerFactory
+	if config.Toggles.EnableAdminNetworkPolicies {
+		adminPolicyClientset, err := anpclientset.NewForConfig(k8sConfig)
+		if err != nil {
+			return fmt.Errorf("failed to generate admin network policy clientset with cluster config: %w", err)
+		}
+		adminPolicyFactory = anpinformers.NewSharedInformerFactory(adminPolicyClientset, resyncPeriod)
+	}
+
 	logLevel := config.LogLevel
 	if logLevel == "" {
 		logLevel = npmconfig.DefaultConfig.LogLevel
@@ -145,6 +172,7 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 	}
 
 	var dp dataplane.GenericDataplane
+	var v2Dataplane *dataplane.DataPlane
 	stopChannel := wait.NeverStop
 	if config.Toggles.EnableV2NPM {
 		// update the dataplane config
@@ -184,6 +212,50 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		}
 
 		npmV2DataplaneCfg.PlaceAzureChainFirst = config.Toggles.PlaceAzureChainFirst
+		npmV2DataplaneCfg.IPSetManagerCfg.UseNFTables = config.Toggles.EnableNFTables
+		npmV2DataplaneCfg.PolicyManagerCfg.UseNFTables = config.Toggles.EnableNFTables
+		npmV2DataplaneCfg.PolicyManagerCfg.LogDeniedFlows = config.Toggles.EnableDeniedFlowLogging
+		if config.Toggles.EnableIPv6 && (util.IsWindowsDP() || config.Toggles.EnableNFTables) {
+			klog.Warning("IPv6 is only supported in Linux with iptables. ignoring EnableIPv6")
+			config.Toggles.EnableIPv6 = false
+		}
+		npmV2DataplaneCfg.IPSetManagerCfg.EnableIPv6 = config.Toggles.EnableIPv6
+		npmV2DataplaneCfg.PolicyManagerCfg.EnableIPv6 = config.Toggles.EnableIPv6
+		if config.DeniedFlowLogGroup > 0 {
+			npmV2DataplaneCfg.PolicyManagerCfg.NFLogGroup = config.DeniedFlowLogGroup
+		} else {
+			npmV2DataplaneCfg.PolicyManagerCfg.NFLogGroup = npmconfig.DefaultConfig.DeniedFlowLogGroup
+		}
+		if config.Toggles.EnableFQDNEgress && (util.IsWindowsDP() || config.Toggles.EnableNFTables) {
+			klog.Warning("FQDN egress is only supported in Linux with iptables. ignoring EnableFQDNEgress")
+			config.Toggles.EnableFQDNEgress = false
+		}
+		if config.Toggles.EnableFQDNEgress {
+			dnsServers := config.DNSServers
+			if len(dnsServers) == 0 {
+				dnsServers = kubeDNSServiceIPs(clientset)
+			}
+			if len(dnsServers) == 0 {
+				klog.Warning("found no DNS servers to snoop. ignoring EnableFQDNEgress")
+				config.Toggles.EnableFQDNEgress = false
+			}
+			npmV2DataplaneCfg.PolicyManagerCfg.DNSServers = dnsServers
+		}
+		npmV2DataplaneCfg.PolicyManagerCfg.SnoopDNS = config.Toggles.EnableFQDNEgress
+		if config.DNSNFLogGroup > 0 {
+			npmV2DataplaneCfg.PolicyManagerCfg.DNSNFLogGroup = config.DNSNFLogGroup
+		} else {
+			npmV2DataplaneCfg.PolicyManagerCfg.DNSNFLogGroup = npmconfig.DefaultConfig.DNSNFLogGroup
+		}
+		if config.Toggles.EnableDriftDetection {
+			if config.Toggles.EnableNFTables {
+				klog.Warning("drift detection isn't supported with nftables. ignoring EnableDriftDetection")
+			} else if config.DriftCheckIntervalInMinutes > 0 {
+				npmV2DataplaneCfg.DriftCheckInterval = time.Duration(config.DriftCheckIntervalInMinutes) * time.Minute
+			} else {
+				npmV2DataplaneCfg.DriftCheckInterval = time.Duration(npmconfig.DefaultConfig.DriftCheckIntervalInMinutes) * time.Minute
+			}
+		}
 		if config.Toggles.ApplyIPSetsOnNeed {
 			npmV2DataplaneCfg.IPSetMode = ipsets.ApplyOnNeed
 		} else {
@@ -201,18 +273,50 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		}
 		npmV2DataplaneCfg.NodeIP = nodeIP
 
-		dp, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
+		v2Dataplane, err = dataplane.NewDataPlane(models.GetNodeName(), common.NewIOShim(), npmV2DataplaneCfg, stopChannel)
 		if err != nil {
 			metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to create dataplane with error %v", err)
 			return fmt.Errorf("failed to create dataplane with error %w", err)
 		}
+		dp = v2Dataplane
 		dp.RunPeriodicTasks()
 	}
 
 	k8sServerVersion := k8sServerVersion(clientset)
-	npMgr := npm.NewNetworkPolicyManager(config, factory, podFactory, dp, exec.New(), version, k8sServerVersion)
+	npMgr := npm.NewNetworkPolicyManager(config, factory, podFactory, adminPolicyFactory, dp, exec.New(), version, k8sServerVersion)
+
+	// serve the HTTP API until NPM is terminated, and drain it before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	restServerDone := make(chan struct{})
+	go func() {
+		defer close(restServerDone)
+		restserver.NPMRestServerListenAndServe(ctx, config, npMgr)
+	}()
+
+	if config.Toggles.EnableV2NPM && config.Toggles.EnableDeniedFlowLogging {
+		deniedFlowLogger := flowlog.NewLogger(npmV2DataplaneCfg.PolicyManagerCfg.NFLogGroup, v2Dataplane, npMgr.PodControllerV2)
+		go func() {
+			if err := deniedFlowLogger.Run(stopChannel); err != nil {
+				metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to log denied flows: %v", err)
+			}
+		}()
+	}
+
+	if config.Toggles.EnableV2NPM && config.Toggles.EnableFQDNEgress {
+		dnsSnooper := fqdn.NewSnooper(npmV2DataplaneCfg.PolicyManagerCfg.DNSNFLogGroup, v2Dataplane.FQDNTracker())
+		go func() {
+			if err := dnsSnooper.Run(stopChannel); err != nil {
+				metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to snoop DNS responses: %v", err)
+			}
+		}()
+	}
 
-	go restserver.NPMRestServerListenAndServe(config, npMgr)
+	if config.Toggles.EnableV2NPM && config.Toggles.EnablePolicyStatusReporting {
+		statusReporter := newPolicyStatusReporter(clientset, k8sConfig)
+		npMgr.NetPolControllerV2.SetStatusReporter(statusReporter)
+		go statusReporter.Run(stopChannel)
+	}
 
 	metrics.SendLog(util.NpmID, "starting NPM", metrics.PrintLog)
 	if err = npMgr.Start(config, stopChannel); err != nil {
@@ -220,7 +324,26 @@ func start(config npmconfig.Config, flags npmconfig.Flags) error {
 		return fmt.Errorf("failed to start with err: %w", err)
 	}
 
-	select {}
+	<-ctx.Done()
+	metrics.SendLog(util.NpmID, "stopping NPM", metrics.PrintLog)
+	<-restServerDone
+	return nil
+}
+
+// newPolicyStatusReporter creates a reporter which emits Events on the NetworkPolicies which fail to be enforced on this node
+// and updates the NetworkPolicyNodeStatus of this node. Only Events are emitted if the NetworkPolicyNodeStatus client can't be created.
+func newPolicyStatusReporter(clientset kubernetes.Interface, k8sConfig *rest.Config) *policystatus.Reporter {
+	nodeName := models.GetNodeName()
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "azure-npm", Host: nodeName})
+
+	cli, err := ctrlclient.New(k8sConfig, ctrlclient.Options{Scheme: networkpolicynodestatus.Scheme})
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.NpmID, "error: failed to create NetworkPolicyNodeStatus client. only reporting policy status with events: %v", err)
+		return policystatus.NewReporter(nodeName, recorder, nil)
+	}
+	return policystatus.NewReporter(nodeName, recorder, networkpolicynodestatus.NewClient(cli))
 }
 
 func initLogging() error {
@@ -234,6 +357,16 @@ func initLogging() error {
 	return nil
 }
 
+// kubeDNSServiceIPs returns the ClusterIPs of the kube-dns Service, or nil if it can't be retrieved.
+func kubeDNSServiceIPs(kubeclientset kubernetes.Interface) []string {
+	svc, err := kubeclientset.CoreV1().Services(util.KubeSystemFlag).Get(context.TODO(), kubeDNSServiceName, metav1.GetOptions{})
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.NpmID, "Error: failed to get the kube-dns Service with err: %s", err.Error())
+		return nil
+	}
+	return svc.Spec.ClusterIPs
+}
+
 func k8sServerVersion(kubeclientset kubernetes.Interface) *k8sversion.Info {
 	var err error
 	var serverVersion *k8sversion.Info
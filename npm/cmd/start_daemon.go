@@ -74,7 +74,7 @@ func startDaemon(config npmconfig.Config) error {
 
 	dp.RunPeriodicTasks()
 	// TODO Daemon should implement cache encoder
-	go restserver.NPMRestServerListenAndServe(config, nil)
+	go restserver.NPMRestServerListenAndServe(ctx, config, nil)
 
 	client, err := transport.NewEventsClient(ctx, pod, node, addr)
 	if err != nil {
@@ -82,7 +82,7 @@ func startDaemon(config npmconfig.Config) error {
 		return fmt.Errorf("failed to create dataplane events client: %w", err)
 	}
 
-	gsp, err := goalstateprocessor.NewGoalStateProcessor(ctx, node, pod, client.EventsChannel(), dp)
+	gsp, err := goalstateprocessor.NewGoalStateProcessor(ctx, node, pod, client.EventsChannel(), dp, client)
 	if err != nil {
 		klog.Errorf("failed to create goalstate processor with error %v", err)
 		return fmt.Errorf("failed to create goalstate processor: %w", err)
@@ -122,7 +122,7 @@ func startControlplane(config npmconfig.Config, flags npmconfig.Flags) error {
 		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
 	}
 
-	go restserver.NPMRestServerListenAndServe(config, npMgr)
+	go restserver.NPMRestServerListenAndServe(context.Background(), config, npMgr)
 
 	metrics.SendLog(util.FanOutServerID, "starting fan-out server", metrics.PrintLog)
 
@@ -12,6 +12,7 @@ const (
 	defaultListeningPort        = 10091
 	defaultGrpcPort             = 10092
 	defaultGrpcServicePort      = 9002
+	defaultDriftCheckInterval   = 5
 	// ConfigEnvPath is what's used by viper to load config path
 	ConfigEnvPath = "NPM_CONFIG"
 
@@ -40,6 +41,11 @@ var DefaultConfig = Config{
 	MaxPendingNetPols:            defaultMaxPendingNetPols,
 	NetPolInvervalInMilliseconds: defaultNetPolInterval,
 
+	DeniedFlowLogGroup: util.DefaultNFLogGroup,
+	DNSNFLogGroup:      util.DefaultDNSNFLogGroup,
+
+	DriftCheckIntervalInMinutes: defaultDriftCheckInterval,
+
 	Toggles: Toggles{
 		EnablePrometheusMetrics: true,
 		EnablePprof:             true,
@@ -52,6 +58,15 @@ var DefaultConfig = Config{
 		// NetPolInBackground is currently used in Linux to apply NetPol controller Add events in the background
 		NetPolInBackground: true,
 		EnableNPMLite:      false,
+		EnableNFTables:     false,
+		// EnableAdminNetworkPolicies requires the AdminNetworkPolicy and BaselineAdminNetworkPolicy CRDs to be installed
+		EnableAdminNetworkPolicies: false,
+		EnableDeniedFlowLogging:    false,
+		EnableIPv6:                 false,
+		EnableFQDNEgress:           false,
+		EnableDriftDetection:       false,
+		// EnablePolicyStatusReporting requires the NetworkPolicyNodeStatus CRD to be installed
+		EnablePolicyStatusReporting: false,
 	},
 
 	// Setting LogLevel to "info" by default. Set to "debug" to get application insight logs (creates a listener that outputs diagnosticMessageWriter logs).
@@ -81,11 +96,23 @@ type Config struct {
 	// MaxBatchedACLsPerPod is the maximum number of ACLs that can be added to a Pod at once in Windows.
 	// The zero value is valid.
 	// A NetworkPolicy's ACLs are always in the same batch, and there will be at least one NetworkPolicy per batch.
-	MaxBatchedACLsPerPod         int     `json:"MaxBatchedACLsPerPod,omitempty"`
-	MaxPendingNetPols            int     `json:"MaxPendingNetPols,omitempty"`
-	NetPolInvervalInMilliseconds int     `json:"NetPolInvervalInMilliseconds,omitempty"`
-	Toggles                      Toggles `json:"Toggles,omitempty"`
-	LogLevel                     string  `json:"LogLevel,omitempty"`
+	MaxBatchedACLsPerPod         int `json:"MaxBatchedACLsPerPod,omitempty"`
+	MaxPendingNetPols            int `json:"MaxPendingNetPols,omitempty"`
+	NetPolInvervalInMilliseconds int `json:"NetPolInvervalInMilliseconds,omitempty"`
+	// DeniedFlowLogGroup is the NFLOG group which denied flows and audited policies log to in Linux.
+	DeniedFlowLogGroup int `json:"DeniedFlowLogGroup,omitempty"`
+	// DNSNFLogGroup is the NFLOG group which DNS responses are copied to in Linux when EnableFQDNEgress is true.
+	DNSNFLogGroup int `json:"DNSNFLogGroup,omitempty"`
+	// DNSServers are the IPs of the DNS servers whose responses are snooped when EnableFQDNEgress is true.
+	// The ClusterIPs of the kube-dns Service are used if it's empty.
+	DNSServers []string `json:"DNSServers,omitempty"`
+	// DriftCheckIntervalInMinutes is how often the dataplane is verified when EnableDriftDetection is true.
+	DriftCheckIntervalInMinutes int `json:"DriftCheckIntervalInMinutes,omitempty"`
+	// AuditNamespaces lists the namespaces whose NetworkPolicies log the traffic they deny instead of dropping it.
+	// A single NetworkPolicy is audited with the npm.azure.com/audit: "true" annotation.
+	AuditNamespaces []string `json:"AuditNamespaces,omitempty"`
+	Toggles         Toggles  `json:"Toggles,omitempty"`
+	LogLevel        string   `json:"LogLevel,omitempty"`
 }
 
 type Toggles struct {
@@ -100,6 +127,26 @@ type Toggles struct {
 	// NetPolInBackground
 	NetPolInBackground bool
 	EnableNPMLite      bool
+	// EnableNFTables applies for Linux only. It replaces the iptables and ipset dataplane with nftables.
+	EnableNFTables bool
+	// EnableAdminNetworkPolicies applies for v2 only. It watches the policy.networking.k8s.io AdminNetworkPolicy
+	// and BaselineAdminNetworkPolicy APIs in addition to NetworkPolicies.
+	EnableAdminNetworkPolicies bool
+	// EnableDeniedFlowLogging applies for v2 in Linux only. It logs the flows which policies drop,
+	// naming the policy and the source and destination pods.
+	EnableDeniedFlowLogging bool
+	// EnableIPv6 applies for v2 in Linux only. It enforces policies on the IPv6 addresses of dual-stack Pods
+	// with family inet6 ipsets and ip6tables chains. It isn't supported with EnableNFTables.
+	EnableIPv6 bool
+	// EnableFQDNEgress applies for v2 in Linux only. It allows egress to the FQDNs in the npm.azure.com/fqdn-egress
+	// annotation of NetworkPolicies by snooping the DNS responses forwarded to Pods. It isn't supported with EnableNFTables.
+	EnableFQDNEgress bool
+	// EnableDriftDetection applies for v2 only. It periodically verifies the ipsets and AZURE-NPM chains in Linux,
+	// or the ACLs of each endpoint in Windows, and repairs what another agent removed. It isn't supported with EnableNFTables.
+	EnableDriftDetection bool
+	// EnablePolicyStatusReporting applies for v2 only. It emits Events on the NetworkPolicies which NPM fails to translate
+	// or apply on the node, and aggregates the failures in the acn.azure.com NetworkPolicyNodeStatus named after the node.
+	EnablePolicyStatusReporting bool
 }
 
 type Flags struct {
@@ -1,11 +1,15 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	_ "net/http/pprof"
+	"time"
 
 	"github.com/Azure/azure-container-networking/log"
 	npmconfig "github.com/Azure/azure-container-networking/npm/config"
@@ -16,12 +20,17 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// ShutdownTimeout bounds how long the requests in flight are waited for when the server is stopped.
+const ShutdownTimeout = 10 * time.Second
+
 type NPMRestServer struct {
 	listeningAddress string
 	router           *mux.Router
 }
 
-func NPMRestServerListenAndServe(config npmconfig.Config, npmEncoder json.Marshaler) {
+// NPMRestServerListenAndServe serves the NPM HTTP API until the context is done, and then shuts it
+// down gracefully.
+func NPMRestServerListenAndServe(ctx context.Context, config npmconfig.Config, npmEncoder json.Marshaler) {
 	rs := NPMRestServer{}
 
 	rs.router = mux.NewRouter()
@@ -58,7 +67,34 @@ func NPMRestServerListenAndServe(config npmconfig.Config, npmEncoder json.Marsha
 	}
 
 	klog.Infof("Starting NPM HTTP API on %s... ", rs.listeningAddress)
-	klog.Errorf("Failed to start NPM HTTP Server with error: %+v", srv.ListenAndServe())
+	l, err := net.Listen("tcp", rs.listeningAddress)
+	if err != nil {
+		klog.Errorf("Failed to start NPM HTTP Server with error: %+v", err)
+		return
+	}
+	serve(ctx, srv, l)
+}
+
+// serve serves on the listener until the context is done, and then stops accepting connections and
+// waits for the requests in flight for up to ShutdownTimeout.
+func serve(ctx context.Context, srv *http.Server, l net.Listener) {
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shut down NPM HTTP Server gracefully with error: %+v", err)
+		}
+	}()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		klog.Errorf("Failed to start NPM HTTP Server with error: %+v", err)
+		return
+	}
+	<-shutdownDone
+	klog.Infof("Stopped NPM HTTP API on %s", l.Addr())
 }
 
 func (n *NPMRestServer) npmCacheHandler(npmCacheEncoder json.Marshaler) http.Handler {
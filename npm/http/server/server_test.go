@@ -1,16 +1,20 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/npm"
 	"github.com/Azure/azure-container-networking/npm/http/api"
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/controllers/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetNPMCacheHandler(t *testing.T) {
@@ -55,3 +59,52 @@ func TestGetNPMCacheHandler(t *testing.T) {
 
 	assert.Exactly(expected, actual)
 }
+
+func TestServeDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		serve(ctx, srv, l)
+	}()
+
+	status := make(chan int, 1)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String()) //nolint:noctx // ignore for unit test
+		if err != nil {
+			status <- 0
+			return
+		}
+		res.Body.Close()
+		status <- res.StatusCode
+	}()
+	<-started
+
+	// new connections are refused, but the request in flight completes
+	cancel()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	select {
+	case <-served:
+		t.Fatal("server stopped before the request in flight completed")
+	default:
+	}
+	close(release)
+	assert.Equal(t, http.StatusOK, <-status)
+	<-served
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -41,3 +43,20 @@ func TotalIPTablesRestoreFailures(op OperationKind) (int, error) {
 		operationLabel: string(op),
 	}))
 }
+
+// IncDeniedFlows counts a flow logged by the denied-flow logger. An audited flow was logged instead of dropped.
+func IncDeniedFlows(policyKey, direction string, audit bool) {
+	deniedFlows.With(deniedFlowLabels(policyKey, direction, audit)).Inc()
+}
+
+func TotalDeniedFlows(policyKey, direction string, audit bool) (int, error) {
+	return counterValue(deniedFlows.With(deniedFlowLabels(policyKey, direction, audit)))
+}
+
+func deniedFlowLabels(policyKey, direction string, audit bool) prometheus.Labels {
+	return prometheus.Labels{
+		policyLabel:    policyKey,
+		directionLabel: direction,
+		auditLabel:     strconv.FormatBool(audit),
+	}
+}
@@ -47,3 +47,17 @@ func TestIncIPTablesRestoreFailures(t *testing.T) {
 	require.Nil(t, err, "failed to get metric")
 	require.Equal(t, 1, count, "should have failed to update once")
 }
+
+func TestIncDeniedFlows(t *testing.T) {
+	IncDeniedFlows("x/deny", "IN", false)
+	IncDeniedFlows("x/deny", "IN", false)
+	IncDeniedFlows("x/deny", "IN", true)
+
+	count, err := TotalDeniedFlows("x/deny", "IN", false)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 2, count, "should have denied twice")
+
+	count, err = TotalDeniedFlows("x/deny", "IN", true)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, count, "should have audited once")
+}
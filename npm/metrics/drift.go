@@ -0,0 +1,34 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// DriftKind is the kind of kernel object which drifted from NPM's cache.
+type DriftKind string
+
+const (
+	IPSetDrift  DriftKind = "ipset"
+	ChainDrift  DriftKind = "chain"
+	PolicyDrift DriftKind = "policy"
+)
+
+// AddDataplaneDrift counts the objects of the kind which drifted from the cache and were repaired.
+func AddDataplaneDrift(kind DriftKind, count int) {
+	dataplaneDrift.With(driftLabels(kind)).Add(float64(count))
+}
+
+// IncDataplaneDriftRepairFailures counts a failure to check for or repair drift of the kind.
+func IncDataplaneDriftRepairFailures(kind DriftKind) {
+	dataplaneDriftRepairFailures.With(driftLabels(kind)).Inc()
+}
+
+func TotalDataplaneDrift(kind DriftKind) (int, error) {
+	return counterValue(dataplaneDrift.With(driftLabels(kind)))
+}
+
+func TotalDataplaneDriftRepairFailures(kind DriftKind) (int, error) {
+	return counterValue(dataplaneDriftRepairFailures.With(driftLabels(kind)))
+}
+
+func driftLabels(kind DriftKind) prometheus.Labels {
+	return prometheus.Labels{driftKindLabel: string(kind)}
+}
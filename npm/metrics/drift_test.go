@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddDataplaneDrift(t *testing.T) {
+	AddDataplaneDrift(IPSetDrift, 2)
+	AddDataplaneDrift(PolicyDrift, 1)
+	AddDataplaneDrift(IPSetDrift, 1)
+
+	count, err := TotalDataplaneDrift(IPSetDrift)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 3, count, "should have repaired three ipsets")
+
+	count, err = TotalDataplaneDrift(PolicyDrift)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, count, "should have repaired one policy")
+}
+
+func TestIncDataplaneDriftRepairFailures(t *testing.T) {
+	IncDataplaneDriftRepairFailures(ChainDrift)
+
+	count, err := TotalDataplaneDriftRepairFailures(ChainDrift)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 1, count, "should have failed once")
+
+	count, err = TotalDataplaneDriftRepairFailures(IPSetDrift)
+	require.Nil(t, err, "failed to get metric")
+	require.Equal(t, 0, count, "should not have failed")
+}
@@ -127,6 +127,31 @@ var (
 	iptablesRestoreFailures *prometheus.CounterVec
 )
 
+// linux denied-flow metrics
+const (
+	policyLabel    = "policy"
+	directionLabel = "direction"
+	auditLabel     = "audit"
+)
+
+var deniedFlows *prometheus.CounterVec
+
+// dataplane drift metrics
+const (
+	dataplaneDriftName = "dataplane_drift_total"
+	dataplaneDriftHelp = "The number of ipsets, chains, and policies that were found to drift from NPM's cache in the kernel and were repaired"
+
+	dataplaneDriftRepairFailuresName = "dataplane_drift_repair_failure_total"
+	dataplaneDriftRepairFailuresHelp = "The number of times NPM failed to check for or repair drift in the kernel"
+
+	driftKindLabel = "kind"
+)
+
+var (
+	dataplaneDrift               *prometheus.CounterVec
+	dataplaneDriftRepairFailures *prometheus.CounterVec
+)
+
 type RegistryType string
 
 const (
@@ -196,6 +221,7 @@ func InitializeAll() {
 		register(itpablesRestoreLatency, "iptables_restore_latency_seconds", NodeMetrics)
 		register(iptablesDeleteLatency, "iptables_delete_latency_seconds", NodeMetrics)
 		register(iptablesRestoreFailures, "iptables_restore_failure_total", NodeMetrics)
+		register(deniedFlows, "denied_flows_total", NodeMetrics)
 	}
 
 	log.Logf("Finished initializing all Prometheus metrics")
@@ -354,6 +380,16 @@ func InitializeLinuxMetrics() {
 		},
 		[]string{operationLabel},
 	)
+
+	deniedFlows = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "denied_flows_total",
+			Subsystem: linuxPrefix,
+			Help:      "Number of flows logged by the denied-flow logger by policy, direction, and whether the policy is audited (logged instead of dropped)",
+		},
+		[]string{policyLabel, directionLabel, auditLabel},
+	)
 }
 
 // GetHandler returns the HTTP handler for the metrics endpoint
@@ -378,6 +414,8 @@ func initializeDaemonMetrics() {
 	// NODE METRICS
 	addACLRuleExecTime = createNodeSummary(addACLRuleExecTimeName, addACLRuleExecTimeHelp)
 	addIPSetExecTime = createNodeSummary(addIPSetExecTimeName, addIPSetExecTimeHelp)
+	dataplaneDrift = createNodeCounterVec(dataplaneDriftName, dataplaneDriftHelp, []string{driftKindLabel})
+	dataplaneDriftRepairFailures = createNodeCounterVec(dataplaneDriftRepairFailuresName, dataplaneDriftRepairFailuresHelp, []string{driftKindLabel})
 }
 
 // initializeControllerMetrics creates metrics modified by the controller
@@ -436,6 +474,19 @@ func createClusterGaugeVec(name, helpMessage string, labels []string) *prometheu
 	return gaugeVec
 }
 
+func createNodeCounterVec(name, helpMessage string, labels []string) *prometheus.CounterVec {
+	counterVec := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      helpMessage,
+		},
+		labels,
+	)
+	register(counterVec, name, NodeMetrics)
+	return counterVec
+}
+
 func createNodeSummary(name, helpMessage string) prometheus.Summary {
 	// uses default observation TTL of 10 minutes
 	summary := prometheus.NewSummary(
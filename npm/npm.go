@@ -21,6 +21,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
+	anpinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
 )
 
 var aiMetadata string //nolint // aiMetadata is set in Makefile
@@ -61,6 +62,7 @@ type NetworkPolicyManager struct {
 func NewNetworkPolicyManager(config npmconfig.Config,
 	informerFactory informers.SharedInformerFactory,
 	podFactory informers.SharedInformerFactory,
+	adminPolicyFactory anpinformers.SharedInformerFactory,
 	dp dataplane.GenericDataplane,
 	exec utilexec.Interface,
 	npmVersion string,
@@ -89,10 +91,16 @@ func NewNetworkPolicyManager(config npmconfig.Config,
 	// create v2 NPM specific components.
 	if npMgr.config.Toggles.EnableV2NPM {
 		npMgr.NpmNamespaceCacheV2 = &controllersv2.NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
-		npMgr.PodControllerV2 = controllersv2.NewPodController(npMgr.PodInformer, dp, npMgr.NpmNamespaceCacheV2)
+		npMgr.PodControllerV2 = controllersv2.NewPodController(npMgr.PodInformer, dp, npMgr.NpmNamespaceCacheV2, config.Toggles.EnableIPv6)
 		npMgr.NamespaceControllerV2 = controllersv2.NewNamespaceController(npMgr.NsInformer, dp, npMgr.NpmNamespaceCacheV2)
 		// Question(jungukcho): Is config.Toggles.PlaceAzureChainFirst needed for v2?
-		npMgr.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(npMgr.NpInformer, dp, config.Toggles.EnableNPMLite)
+		npMgr.NetPolControllerV2 = controllersv2.NewNetworkPolicyController(npMgr.NpInformer, dp, config.Toggles.EnableNPMLite, config.AuditNamespaces)
+		if config.Toggles.EnableAdminNetworkPolicies {
+			npMgr.AdminPolicyInformerFactory = adminPolicyFactory
+			npMgr.AnpInformer = adminPolicyFactory.Policy().V1alpha1().AdminNetworkPolicies()
+			npMgr.BanpInformer = adminPolicyFactory.Policy().V1alpha1().BaselineAdminNetworkPolicies()
+			npMgr.AdminPolControllerV2 = controllersv2.NewAdminNetworkPolicyController(npMgr.AnpInformer, npMgr.BanpInformer, dp)
+		}
 		return npMgr
 	}
 
@@ -210,9 +218,19 @@ func (npMgr *NetworkPolicyManager) Start(config npmconfig.Config, stopCh <-chan
 		return fmt.Errorf("NetworkPolicy informer error: %w", models.ErrInformerSyncFailure)
 	}
 
+	if npMgr.AdminPolControllerV2 != nil {
+		npMgr.AdminPolicyInformerFactory.Start(stopCh)
+		if !cache.WaitForCacheSync(stopCh, npMgr.AnpInformer.Informer().HasSynced, npMgr.BanpInformer.Informer().HasSynced) {
+			return fmt.Errorf("AdminNetworkPolicy informer error: %w", models.ErrInformerSyncFailure)
+		}
+	}
+
 	// start v2 NPM controllers after synced
 	if config.Toggles.EnableV2NPM {
 		go npMgr.NetPolControllerV2.Run(stopCh)
+		if npMgr.AdminPolControllerV2 != nil {
+			go npMgr.AdminPolControllerV2.Run(stopCh)
+		}
 
 		if util.IsWindowsDP() && config.Toggles.ApplyInBackground {
 			klog.Infof("optimizing NPM bootup by letting NetPol controller process changes first. waiting %v before starting pod and namespace controllers", waitDurationAfterStartingNetPolController)
@@ -0,0 +1,295 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	anpinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions/apis/v1alpha1"
+	anplisters "sigs.k8s.io/network-policy-api/pkg/client/listers/apis/v1alpha1"
+)
+
+var errAdminPolKeyFormat = errors.New("invalid admin network policy key format")
+
+// AdminNetworkPolicyController watches the cluster-scoped AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+// Workqueue keys are the PolicyKeys of the translated policies (e.g. "AdminNetworkPolicy/<name>"),
+// so the kind of a key decides which lister and translation to use.
+type AdminNetworkPolicyController struct {
+	sync.RWMutex
+	anpLister      anplisters.AdminNetworkPolicyLister
+	banpLister     anplisters.BaselineAdminNetworkPolicyLister
+	workqueue      workqueue.RateLimitingInterface
+	rawAnpSpecMap  map[string]*v1alpha1.AdminNetworkPolicySpec         // Key is AdminNetworkPolicy/<policyname>
+	rawBanpSpecMap map[string]*v1alpha1.BaselineAdminNetworkPolicySpec // Key is BaselineAdminNetworkPolicy/<policyname>
+	dp             dataplane.GenericDataplane
+}
+
+func NewAdminNetworkPolicyController(anpInformer anpinformers.AdminNetworkPolicyInformer,
+	banpInformer anpinformers.BaselineAdminNetworkPolicyInformer,
+	dp dataplane.GenericDataplane,
+) *AdminNetworkPolicyController {
+	adminPolController := &AdminNetworkPolicyController{
+		anpLister:      anpInformer.Lister(),
+		banpLister:     banpInformer.Lister(),
+		workqueue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "AdminNetworkPolicy"),
+		rawAnpSpecMap:  make(map[string]*v1alpha1.AdminNetworkPolicySpec),
+		rawBanpSpecMap: make(map[string]*v1alpha1.BaselineAdminNetworkPolicySpec),
+		dp:             dp,
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    adminPolController.addPolicy,
+		UpdateFunc: adminPolController.updatePolicy,
+		DeleteFunc: adminPolController.deletePolicy,
+	}
+	anpInformer.Informer().AddEventHandler(handler)
+	banpInformer.Informer().AddEventHandler(handler)
+	return adminPolController
+}
+
+func (c *AdminNetworkPolicyController) LengthOfRawSpecMaps() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.rawAnpSpecMap) + len(c.rawBanpSpecMap)
+}
+
+// getPolicyKey returns the PolicyKey of an AdminNetworkPolicy or BaselineAdminNetworkPolicy object.
+// If obj is neither, it returns error.
+func (c *AdminNetworkPolicyController) getPolicyKey(obj interface{}) (string, error) {
+	switch policy := obj.(type) {
+	case *v1alpha1.AdminNetworkPolicy:
+		return policies.AdminPolicyKey(policy.Name), nil
+	case *v1alpha1.BaselineAdminNetworkPolicy:
+		return policies.BaselineAdminPolicyKey(policy.Name), nil
+	default:
+		return "", fmt.Errorf("cannot cast obj (%v) to admin network policy obj err: %w", obj, errAdminPolKeyFormat)
+	}
+}
+
+func (c *AdminNetworkPolicyController) addPolicy(obj interface{}) {
+	key, err := c.getPolicyKey(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) updatePolicy(old, newPolicy interface{}) {
+	key, err := c.getPolicyKey(newPolicy)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	oldMeta, oldOK := old.(metav1.Object)
+	newMeta, newOK := newPolicy.(metav1.Object)
+	if oldOK && newOK && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		// Periodic resync will send update events for all known policies.
+		// Two different versions of the same policy will always have different RVs.
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) deletePolicy(obj interface{}) {
+	// DeleteFunc gets the final state of the resource (if it is known).
+	// Otherwise, it gets an object of type DeletedFinalStateUnknown.
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	key, err := c.getPolicyKey(obj)
+	if err != nil {
+		metrics.SendErrorLogAndMetric(util.NetpolID, "[ADMIN NETPOL DELETE EVENT] Received unexpected object type: %v", obj)
+		return
+	}
+
+	c.workqueue.Add(key)
+}
+
+func (c *AdminNetworkPolicyController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	klog.Info("Starting Admin Network Policy worker")
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	<-stopCh
+	klog.Info("Shutting down Admin Network Policy workers")
+}
+
+func (c *AdminNetworkPolicyController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *AdminNetworkPolicyController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.workqueue.Done(obj)
+		var key string
+		var ok bool
+		if key, ok = obj.(string); !ok {
+			// As the item in the workqueue is actually invalid, we call
+			// Forget here else we'd go into a loop of attempting to
+			// process a work item that is invalid.
+			c.workqueue.Forget(obj)
+			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v, err %w", obj, errWorkqueueFormatting))
+			return nil
+		}
+		if err := c.syncAdminPolicy(key); err != nil {
+			// Put the item back on the workqueue to handle any transient errors.
+			c.workqueue.AddRateLimited(key)
+			return fmt.Errorf("error syncing '%s': %w, requeuing", key, err)
+		}
+		c.workqueue.Forget(obj)
+		return nil
+	}(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		metrics.SendErrorLogAndMetric(util.NetpolID, "syncAdminPolicy error due to %v", err)
+		return true
+	}
+
+	return true
+}
+
+// syncAdminPolicy compares the actual state with the desired, and attempts to converge the two.
+func (c *AdminNetworkPolicyController) syncAdminPolicy(key string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	_, name, _ := strings.Cut(key, "/")
+	switch key {
+	case policies.AdminPolicyKey(name):
+		return c.syncAdminNetworkPolicy(key, name)
+	case policies.BaselineAdminPolicyKey(name):
+		return c.syncBaselineAdminNetworkPolicy(key, name)
+	default:
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s err: %w", key, errAdminPolKeyFormat))
+		return nil //nolint HandleError  is used instead of returning error to caller
+	}
+}
+
+func (c *AdminNetworkPolicyController) syncAdminNetworkPolicy(key, name string) error {
+	anpObj, err := c.anpLister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Infof("AdminNetworkPolicy %s is not found, may be it is deleted", name)
+			return c.cleanUpAdminPolicy(key)
+		}
+		return err
+	}
+
+	if anpObj.DeletionTimestamp != nil || anpObj.DeletionGracePeriodSeconds != nil {
+		return c.cleanUpAdminPolicy(key)
+	}
+
+	if cachedSpec, ok := c.rawAnpSpecMap[key]; ok && reflect.DeepEqual(cachedSpec, &anpObj.Spec) {
+		return nil
+	}
+
+	npmNetPolObj, err := translation.TranslateAdminNetworkPolicy(anpObj)
+	if err != nil {
+		return c.handleTranslationErr(key, err)
+	}
+
+	if err := c.dp.UpdatePolicy(npmNetPolObj); err != nil {
+		return fmt.Errorf("[syncAdminNetworkPolicy] Error: failed to update translated NPMNetworkPolicy into Dataplane due to %w", err)
+	}
+	c.rawAnpSpecMap[key] = &anpObj.Spec
+	return nil
+}
+
+func (c *AdminNetworkPolicyController) syncBaselineAdminNetworkPolicy(key, name string) error {
+	banpObj, err := c.banpLister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Infof("BaselineAdminNetworkPolicy %s is not found, may be it is deleted", name)
+			return c.cleanUpAdminPolicy(key)
+		}
+		return err
+	}
+
+	if banpObj.DeletionTimestamp != nil || banpObj.DeletionGracePeriodSeconds != nil {
+		return c.cleanUpAdminPolicy(key)
+	}
+
+	if cachedSpec, ok := c.rawBanpSpecMap[key]; ok && reflect.DeepEqual(cachedSpec, &banpObj.Spec) {
+		return nil
+	}
+
+	npmNetPolObj, err := translation.TranslateBaselineAdminNetworkPolicy(banpObj)
+	if err != nil {
+		return c.handleTranslationErr(key, err)
+	}
+
+	if err := c.dp.UpdatePolicy(npmNetPolObj); err != nil {
+		return fmt.Errorf("[syncBaselineAdminNetworkPolicy] Error: failed to update translated NPMNetworkPolicy into Dataplane due to %w", err)
+	}
+	c.rawBanpSpecMap[key] = &banpObj.Spec
+	return nil
+}
+
+// handleTranslationErr logs a policy which can't be translated and removes its previous version from the dataplane,
+// since leaving stale rules of an admin policy in place could allow or deny traffic the updated policy doesn't.
+// It returns nil unless the removal fails, since re-queuing the policy will result in the same translation error.
+func (c *AdminNetworkPolicyController) handleTranslationErr(key string, err error) error {
+	if isUnsupportedWindowsTranslationErr(err) || isUnsupportedAdminTranslationErr(err) {
+		klog.Warningf("%s is not translated because it has unsupported translated features: %s", key, err.Error())
+	} else {
+		klog.Errorf("Failed to translate %s: %s", key, err.Error())
+	}
+	return c.cleanUpAdminPolicy(key)
+}
+
+// cleanUpAdminPolicy handles deleting an admin policy based on its PolicyKey.
+func (c *AdminNetworkPolicyController) cleanUpAdminPolicy(key string) error {
+	_, anpExists := c.rawAnpSpecMap[key]
+	_, banpExists := c.rawBanpSpecMap[key]
+	// if there is no applied policy with the key, do not need to clean up process.
+	if !anpExists && !banpExists {
+		return nil
+	}
+
+	if err := c.dp.RemovePolicy(key); err != nil {
+		return fmt.Errorf("[cleanUpAdminPolicy] Error: failed to remove policy due to %w", err)
+	}
+
+	delete(c.rawAnpSpecMap, key)
+	delete(c.rawBanpSpecMap, key)
+	return nil
+}
+
+func isUnsupportedAdminTranslationErr(err error) bool {
+	return errors.Is(err, translation.ErrUnsupportedNodePeer) ||
+		errors.Is(err, translation.ErrUnsupportedSubject) ||
+		errors.Is(err, translation.ErrUnsupportedPassAction) ||
+		errors.Is(err, translation.ErrUnsupportedAdminPriority)
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 Microsoft. All rights reserved.
+// MIT License
+package controllers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
+	dpmocks "github.com/Azure/azure-container-networking/npm/pkg/dataplane/mocks"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	anpfake "sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned/fake"
+	anpinformers "sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+)
+
+type adminPolFixture struct {
+	t *testing.T
+
+	adminPolController *AdminNetworkPolicyController
+	anpInformer        anpinformers.SharedInformerFactory
+}
+
+func newAdminPolFixture(t *testing.T, dp dataplane.GenericDataplane) *adminPolFixture {
+	f := &adminPolFixture{
+		t:           t,
+		anpInformer: anpinformers.NewSharedInformerFactory(anpfake.NewSimpleClientset(), noResyncPeriodFunc()),
+	}
+	f.adminPolController = NewAdminNetworkPolicyController(
+		f.anpInformer.Policy().V1alpha1().AdminNetworkPolicies(),
+		f.anpInformer.Policy().V1alpha1().BaselineAdminNetworkPolicies(),
+		dp,
+	)
+	return f
+}
+
+func (f *adminPolFixture) indexer(obj interface{}) cache.Indexer {
+	if _, ok := obj.(*v1alpha1.AdminNetworkPolicy); ok {
+		return f.anpInformer.Policy().V1alpha1().AdminNetworkPolicies().Informer().GetIndexer()
+	}
+	return f.anpInformer.Policy().V1alpha1().BaselineAdminNetworkPolicies().Informer().GetIndexer()
+}
+
+func (f *adminPolFixture) addPolicy(obj interface{}) {
+	require.NoError(f.t, f.indexer(obj).Add(obj))
+	f.adminPolController.addPolicy(obj)
+	if f.adminPolController.workqueue.Len() > 0 {
+		f.adminPolController.processNextWorkItem()
+	}
+}
+
+func (f *adminPolFixture) updatePolicy(old, obj interface{}) {
+	require.NoError(f.t, f.indexer(obj).Update(obj))
+	f.adminPolController.updatePolicy(old, obj)
+	if f.adminPolController.workqueue.Len() > 0 {
+		f.adminPolController.processNextWorkItem()
+	}
+}
+
+func (f *adminPolFixture) deletePolicy(obj interface{}) {
+	require.NoError(f.t, f.indexer(obj).Delete(obj))
+	f.adminPolController.deletePolicy(cache.DeletedFinalStateUnknown{Obj: obj})
+	if f.adminPolController.workqueue.Len() > 0 {
+		f.adminPolController.processNextWorkItem()
+	}
+}
+
+func createANP(priority int32) *v1alpha1.AdminNetworkPolicy {
+	return &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "deny-sensitive", ResourceVersion: "0"},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: priority,
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "sensitive"}},
+			},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+				{
+					Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+					From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+				},
+			},
+		},
+	}
+}
+
+func createBANP() *v1alpha1.BaselineAdminNetworkPolicy {
+	return &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", ResourceVersion: "0"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Networks: []v1alpha1.CIDR{"10.0.0.0/8"}}},
+				},
+			},
+		},
+	}
+}
+
+func TestAddAndDeleteAdminNetworkPolicies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminPolFixture(t, dp)
+
+	anp := createANP(10)
+	banp := createBANP()
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(netPol *policies.NPMNetworkPolicy) error {
+		require.Equal(t, policies.AdminPolicyKey(anp.Name), netPol.PolicyKey)
+		require.Equal(t, policies.AdminTier, netPol.Tier)
+		return nil
+	}).Times(1)
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(netPol *policies.NPMNetworkPolicy) error {
+		require.Equal(t, policies.BaselineAdminPolicyKey(banp.Name), netPol.PolicyKey)
+		require.Equal(t, policies.BaselineTier, netPol.Tier)
+		return nil
+	}).Times(1)
+	dp.EXPECT().RemovePolicy(policies.AdminPolicyKey(anp.Name)).Return(nil).Times(1)
+	dp.EXPECT().RemovePolicy(policies.BaselineAdminPolicyKey(banp.Name)).Return(nil).Times(1)
+
+	f.addPolicy(anp)
+	f.addPolicy(banp)
+	// already exists (will be a no-op)
+	f.addPolicy(anp)
+	require.Equal(t, 2, f.adminPolController.LengthOfRawSpecMaps())
+
+	f.deletePolicy(anp)
+	f.deletePolicy(banp)
+	require.Equal(t, 0, f.adminPolController.LengthOfRawSpecMaps())
+	require.Equal(t, 0, f.adminPolController.workqueue.Len())
+}
+
+func TestUpdateAdminNetworkPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminPolFixture(t, dp)
+
+	oldANP := createANP(10)
+	newANP := createANP(20)
+	newANP.ResourceVersion = "1"
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Return(nil).Times(2)
+	f.addPolicy(oldANP)
+	// resync with the same resource version (will be a no-op)
+	f.updatePolicy(oldANP, oldANP)
+	f.updatePolicy(oldANP, newANP)
+	require.Equal(t, 1, f.adminPolController.LengthOfRawSpecMaps())
+	require.Equal(t, int32(20), f.adminPolController.rawAnpSpecMap[policies.AdminPolicyKey(newANP.Name)].Priority)
+}
+
+func TestUpdateAdminNetworkPolicyToUnsupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newAdminPolFixture(t, dp)
+
+	oldANP := createANP(10)
+	newANP := oldANP.DeepCopy()
+	newANP.ResourceVersion = "1"
+	newANP.Spec.Egress = []v1alpha1.AdminNetworkPolicyEgressRule{
+		{
+			Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+			To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Nodes: &metav1.LabelSelector{}}},
+		},
+	}
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Return(nil).Times(1)
+	// stale rules of the previous version are removed since the update can't be translated
+	dp.EXPECT().RemovePolicy(policies.AdminPolicyKey(oldANP.Name)).Return(nil).Times(1)
+	f.addPolicy(oldANP)
+	f.updatePolicy(oldANP, newANP)
+	require.Equal(t, 0, f.adminPolController.LengthOfRawSpecMaps())
+	require.Equal(t, 0, f.adminPolController.workqueue.Len())
+}
@@ -9,7 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/azure-container-networking/crd/networkpolicynodestatus/api/v1alpha1"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/policystatus"
 	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -36,6 +38,14 @@ type NetworkPolicyController struct {
 	rawNpSpecMap  map[string]*networkingv1.NetworkPolicySpec // Key is <nsname>/<policyname>
 	dp            dataplane.GenericDataplane
 	npmLiteToggle bool
+	// auditNamespaces holds the namespaces whose network policies log the traffic they deny instead of dropping it
+	auditNamespaces map[string]struct{}
+	// auditedNetPols holds the keys of the network policies which are applied in audit mode
+	auditedNetPols map[string]struct{}
+	// fqdnAnnotations holds the FQDN egress annotations of the applied network policies which have one
+	fqdnAnnotations map[string]string
+	// statusReporter reports the network policies which fail to be enforced. It is nil if status reporting is disabled.
+	statusReporter *policystatus.Reporter
 }
 
 func (c *NetworkPolicyController) GetCache() map[string]*networkingv1.NetworkPolicySpec {
@@ -44,13 +54,21 @@ func (c *NetworkPolicyController) GetCache() map[string]*networkingv1.NetworkPol
 	return c.rawNpSpecMap
 }
 
-func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInformer, dp dataplane.GenericDataplane, npmLiteToggle bool) *NetworkPolicyController {
+func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInformer, dp dataplane.GenericDataplane, npmLiteToggle bool,
+	auditNamespaces []string,
+) *NetworkPolicyController {
 	netPolController := &NetworkPolicyController{
-		netPolLister:  npInformer.Lister(),
-		workqueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "NetworkPolicy"),
-		rawNpSpecMap:  make(map[string]*networkingv1.NetworkPolicySpec),
-		dp:            dp,
-		npmLiteToggle: npmLiteToggle,
+		netPolLister:    npInformer.Lister(),
+		workqueue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "NetworkPolicy"),
+		rawNpSpecMap:    make(map[string]*networkingv1.NetworkPolicySpec),
+		dp:              dp,
+		npmLiteToggle:   npmLiteToggle,
+		auditNamespaces: make(map[string]struct{}, len(auditNamespaces)),
+		auditedNetPols:  make(map[string]struct{}),
+		fqdnAnnotations: make(map[string]string),
+	}
+	for _, ns := range auditNamespaces {
+		netPolController.auditNamespaces[ns] = struct{}{}
 	}
 
 	npInformer.Informer().AddEventHandler(
@@ -63,6 +81,12 @@ func NewNetworkPolicyController(npInformer networkinginformers.NetworkPolicyInfo
 	return netPolController
 }
 
+// SetStatusReporter sets the reporter of the network policies which fail to be enforced.
+// It must be called before the controller runs.
+func (c *NetworkPolicyController) SetStatusReporter(statusReporter *policystatus.Reporter) {
+	c.statusReporter = statusReporter
+}
+
 func (c *NetworkPolicyController) LengthOfRawNpMap() int {
 	return len(c.rawNpSpecMap)
 }
@@ -266,7 +290,10 @@ func (c *NetworkPolicyController) syncNetPol(key string) error {
 		// netPolController does not need to reconcile this update.
 		// In this updateNetworkPolicy event,
 		// newNetPol was updated with states which netPolController does not need to reconcile.
-		if reflect.DeepEqual(cachedNetPolSpecObj, &netPolObj.Spec) {
+		// The audit and FQDN egress annotations are not in the spec, so their changes are checked separately.
+		_, wasAudited := c.auditedNetPols[key]
+		if reflect.DeepEqual(cachedNetPolSpecObj, &netPolObj.Spec) && wasAudited == c.isAudited(netPolObj) &&
+			c.fqdnAnnotations[key] == netPolObj.Annotations[util.NetworkPolicyFQDNEgressAnnotation] {
 			return nil
 		}
 	}
@@ -294,6 +321,7 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 		if isUnsupportedWindowsTranslationErr(err) {
 			klog.Warningf("NetworkPolicy %s in namespace %s is not translated because it has unsupported translated features of Windows: %s",
 				netPolObj.ObjectMeta.Name, netPolObj.ObjectMeta.Namespace, err.Error())
+			c.statusReporter.PolicyFailed(netPolObj, v1alpha1.UnsupportedFeature, err)
 
 			// We can safely suppress unsupported network policy because re-Queuing will result in same error.
 			// The exec time isn't relevant here, so consider a no-op.
@@ -301,9 +329,15 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 		}
 
 		klog.Errorf("Failed to translate podSelector in NetworkPolicy %s in namespace %s: %s", netPolObj.ObjectMeta.Name, netPolObj.ObjectMeta.Namespace, err.Error())
+		if errors.Is(err, translation.ErrUnsupportedNonCIDR) {
+			c.statusReporter.PolicyFailed(netPolObj, v1alpha1.UnsupportedFeature, err)
+		} else {
+			c.statusReporter.PolicyFailed(netPolObj, v1alpha1.TranslationFailed, err)
+		}
 		// The exec time isn't relevant here, so consider a no-op. Returning nil to prevent re-queuing since this is not a transient error.
 		return metrics.NoOp, nil
 	}
+	npmNetPolObj.Audit = c.isAudited(netPolObj)
 
 	_, policyExisted := c.rawNpSpecMap[netpolKey]
 	var operationKind metrics.OperationKind
@@ -321,6 +355,7 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 	if err != nil {
 		// if error occurred the key is re-queued in workqueue and process this function again,
 		// which eventually meets desired states of network policy
+		c.statusReporter.PolicyFailed(netPolObj, v1alpha1.ApplyFailed, err)
 		return operationKind, fmt.Errorf("[syncAddAndUpdateNetPol] Error: failed to update translated NPMNetworkPolicy into Dataplane due to %w", err)
 	}
 
@@ -330,11 +365,34 @@ func (c *NetworkPolicyController) syncAddAndUpdateNetPol(netPolObj *networkingv1
 	}
 
 	c.rawNpSpecMap[netpolKey] = &netPolObj.Spec
+	if npmNetPolObj.Audit {
+		c.auditedNetPols[netpolKey] = struct{}{}
+	} else {
+		delete(c.auditedNetPols, netpolKey)
+	}
+	if fqdnAnnotation, ok := netPolObj.Annotations[util.NetworkPolicyFQDNEgressAnnotation]; ok {
+		c.fqdnAnnotations[netpolKey] = fqdnAnnotation
+	} else {
+		delete(c.fqdnAnnotations, netpolKey)
+	}
+	c.statusReporter.PolicyHealthy(netPolObj)
 	return operationKind, nil
 }
 
+// isAudited returns true if the network policy or its namespace is in audit mode.
+func (c *NetworkPolicyController) isAudited(netPolObj *networkingv1.NetworkPolicy) bool {
+	if netPolObj.Annotations[util.NetworkPolicyAuditAnnotation] == "true" {
+		return true
+	}
+	_, ok := c.auditNamespaces[netPolObj.Namespace]
+	return ok
+}
+
 // DeleteNetworkPolicy handles deleting network policy based on netPolKey.
 func (c *NetworkPolicyController) cleanUpNetworkPolicy(netPolKey string) error {
+	// a policy which failed to be translated is not cached, so forget its failure before checking the cache
+	c.statusReporter.PolicyDeleted(netPolKey)
+
 	_, cachedNetPolObjExists := c.rawNpSpecMap[netPolKey]
 	// if there is no applied network policy with the netPolKey, do not need to clean up process.
 	if !cachedNetPolObjExists {
@@ -348,6 +406,8 @@ func (c *NetworkPolicyController) cleanUpNetworkPolicy(netPolKey string) error {
 
 	// Success to clean up ipset and iptables operations in kernel and delete the cached network policy from RawNpMap
 	delete(c.rawNpSpecMap, netPolKey)
+	delete(c.auditedNetPols, netPolKey)
+	delete(c.fqdnAnnotations, netPolKey)
 	metrics.DecNumPolicies()
 	return nil
 }
@@ -356,5 +416,6 @@ func isUnsupportedWindowsTranslationErr(err error) bool {
 	return errors.Is(err, translation.ErrUnsupportedNamedPort) ||
 		errors.Is(err, translation.ErrUnsupportedNegativeMatch) ||
 		errors.Is(err, translation.ErrUnsupportedSCTP) ||
-		errors.Is(err, translation.ErrUnsupportedExceptCIDR)
+		errors.Is(err, translation.ErrUnsupportedExceptCIDR) ||
+		errors.Is(err, translation.ErrUnsupportedFQDN)
 }
@@ -7,10 +7,13 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/Azure/azure-container-networking/crd/networkpolicynodestatus/api/v1alpha1"
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/metrics/promutil"
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/policystatus"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane"
 	dpmocks "github.com/Azure/azure-container-networking/npm/pkg/dataplane/mocks"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/util"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
@@ -22,6 +25,7 @@ import (
 	kubeinformers "k8s.io/client-go/informers"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 type netPolFixture struct {
@@ -50,7 +54,7 @@ func (f *netPolFixture) newNetPolController(_ chan struct{}, dp dataplane.Generi
 	kubeclient := k8sfake.NewSimpleClientset(f.kubeobjects...)
 	f.kubeInformer = kubeinformers.NewSharedInformerFactory(kubeclient, noResyncPeriodFunc())
 
-	f.netPolController = NewNetworkPolicyController(f.kubeInformer.Networking().V1().NetworkPolicies(), dp, npmLiteToggle)
+	f.netPolController = NewNetworkPolicyController(f.kubeInformer.Networking().V1().NetworkPolicies(), dp, npmLiteToggle, nil)
 
 	for _, netPol := range f.netPolLister {
 		err := f.kubeInformer.Networking().V1().NetworkPolicies().Informer().GetIndexer().Add(netPol)
@@ -618,3 +622,141 @@ func TestLabelUpdateNetworkPolicy(t *testing.T) {
 
 	checkNetPolTestResult("TestUpdateNetPol", f, testCases)
 }
+
+func TestAuditNetworkPolicy(t *testing.T) {
+	oldNetPolObj := createNetPol()
+	oldNetPolObj.Spec.Egress[0].Ports[0].Port = &intstr.IntOrString{IntVal: 8000}
+
+	f := newNetPolFixture(t)
+	f.netPolLister = append(f.netPolLister, oldNetPolObj)
+	f.kubeobjects = append(f.kubeobjects, oldNetPolObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newNetPolController(stopCh, dp, false)
+
+	// only the annotation changes, which still needs to be reconciled
+	newNetPolObj := oldNetPolObj.DeepCopy()
+	newNetPolObj.Annotations = map[string]string{util.NetworkPolicyAuditAnnotation: "true"}
+	newRV, _ := strconv.Atoi(oldNetPolObj.ResourceVersion)
+	newNetPolObj.ResourceVersion = fmt.Sprintf("%d", newRV+1)
+
+	gomock.InOrder(
+		dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(netPol *policies.NPMNetworkPolicy) error {
+			require.False(t, netPol.Audit)
+			return nil
+		}),
+		dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(netPol *policies.NPMNetworkPolicy) error {
+			require.True(t, netPol.Audit)
+			return nil
+		}),
+	)
+	updateNetPol(t, f, oldNetPolObj, newNetPolObj)
+
+	testCases := []expectedNetPolValues{
+		{1, 0, netPolPromVals{1, 1, 1, 0}},
+	}
+	checkNetPolTestResult("TestAuditNetPol", f, testCases)
+	require.Contains(t, f.netPolController.auditedNetPols, getKey(newNetPolObj, t))
+}
+
+func TestFQDNEgressNetworkPolicy(t *testing.T) {
+	if util.IsWindowsDP() {
+		t.Skip("FQDN egress rules are only supported in Linux")
+	}
+	oldNetPolObj := createNetPol()
+	oldNetPolObj.Spec.Egress[0].Ports[0].Port = &intstr.IntOrString{IntVal: 8000}
+
+	f := newNetPolFixture(t)
+	f.netPolLister = append(f.netPolLister, oldNetPolObj)
+	f.kubeobjects = append(f.kubeobjects, oldNetPolObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newNetPolController(stopCh, dp, false)
+
+	// only the annotation changes, which still needs to be reconciled
+	newNetPolObj := oldNetPolObj.DeepCopy()
+	newNetPolObj.Annotations = map[string]string{util.NetworkPolicyFQDNEgressAnnotation: "*.blob.core.windows.net"}
+	newRV, _ := strconv.Atoi(oldNetPolObj.ResourceVersion)
+	newNetPolObj.ResourceVersion = fmt.Sprintf("%d", newRV+1)
+
+	gomock.InOrder(
+		dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(netPol *policies.NPMNetworkPolicy) error {
+			require.Empty(t, netPol.FQDNs)
+			return nil
+		}),
+		dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(netPol *policies.NPMNetworkPolicy) error {
+			require.Equal(t, []string{"*.blob.core.windows.net"}, netPol.FQDNs)
+			return nil
+		}),
+	)
+	updateNetPol(t, f, oldNetPolObj, newNetPolObj)
+
+	testCases := []expectedNetPolValues{
+		{1, 0, netPolPromVals{1, 1, 1, 0}},
+	}
+	checkNetPolTestResult("TestFQDNEgressNetworkPolicy", f, testCases)
+	require.Equal(t, "*.blob.core.windows.net", f.netPolController.fqdnAnnotations[getKey(newNetPolObj, t)])
+}
+
+func TestAuditNamespace(t *testing.T) {
+	netPolObj := createNetPol()
+	netPolObj.Spec.Egress[0].Ports[0].Port = &intstr.IntOrString{IntVal: 8000}
+
+	f := newNetPolFixture(t)
+	f.netPolLister = append(f.netPolLister, netPolObj)
+	f.kubeobjects = append(f.kubeobjects, netPolObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newNetPolController(stopCh, dp, false)
+	f.netPolController.auditNamespaces = map[string]struct{}{netPolObj.Namespace: {}}
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).DoAndReturn(func(netPol *policies.NPMNetworkPolicy) error {
+		require.True(t, netPol.Audit)
+		return nil
+	}).Times(1)
+	dp.EXPECT().RemovePolicy(getKey(netPolObj, t)).Return(nil).Times(1)
+	deleteNetPol(t, f, netPolObj, DeletedFinalStateknownObject)
+
+	require.Empty(t, f.netPolController.auditedNetPols)
+}
+
+func TestPolicyStatusReporting(t *testing.T) {
+	oldNetPolObj := createNetPol()
+
+	f := newNetPolFixture(t)
+	f.netPolLister = append(f.netPolLister, oldNetPolObj)
+	f.kubeobjects = append(f.kubeobjects, oldNetPolObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f.newNetPolController(stopCh, dp, true)
+	recorder := record.NewFakeRecorder(10)
+	f.netPolController.SetStatusReporter(policystatus.NewReporter("test-node", recorder, nil))
+
+	// the policy only becomes supported by NPM Lite after removing its pod selectors and named port
+	newNetPolObj := createNetPolNpmLite()
+	newRV, _ := strconv.Atoi(oldNetPolObj.ResourceVersion)
+	newNetPolObj.ResourceVersion = fmt.Sprintf("%d", newRV+1)
+
+	dp.EXPECT().UpdatePolicy(gomock.Any()).Return(nil).Times(1)
+	updateNetPol(t, f, oldNetPolObj, newNetPolObj)
+
+	require.Len(t, recorder.Events, 2)
+	require.Contains(t, <-recorder.Events, "Warning "+string(v1alpha1.UnsupportedFeature))
+	require.Contains(t, <-recorder.Events, "Normal "+policystatus.PolicyEnforcedReason)
+}
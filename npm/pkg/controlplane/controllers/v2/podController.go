@@ -46,15 +46,18 @@ type PodController struct {
 	podMap    map[string]*common.NpmPod // Key is <nsname>/<podname>
 	sync.RWMutex
 	npmNamespaceCache *NpmNamespaceCache
+	// enableIPv6 adds the IPv6 IPs of dual-stack pods to ipsets too
+	enableIPv6 bool
 }
 
-func NewPodController(podInformer coreinformer.PodInformer, dp dataplane.GenericDataplane, npmNamespaceCache *NpmNamespaceCache) *PodController {
+func NewPodController(podInformer coreinformer.PodInformer, dp dataplane.GenericDataplane, npmNamespaceCache *NpmNamespaceCache, enableIPv6 bool) *PodController {
 	podController := &PodController{
 		podLister:         podInformer.Lister(),
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Pods"),
 		dp:                dp,
 		podMap:            make(map[string]*common.NpmPod),
 		npmNamespaceCache: npmNamespaceCache,
+		enableIPv6:        enableIPv6,
 	}
 
 	podInformer.Informer().AddEventHandler(
@@ -83,6 +86,19 @@ func (c *PodController) LengthOfPodMap() int {
 	return len(c.podMap)
 }
 
+// PodKeyForIP returns the <nsname>/<podname> key of the pod with the IP.
+func (c *PodController) PodKeyForIP(podIP string) (string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	for key, pod := range c.podMap {
+		if pod.PodIP == podIP || (pod.PodIPv6 != "" && pod.PodIPv6 == podIP) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
 // needSync filters the event if the event is not required to handle
 func (c *PodController) needSync(eventType string, obj interface{}) (string, bool) {
 	needSync := false
@@ -370,14 +386,14 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 	var err error
 	podKey, _ := cache.MetaNamespaceKeyFunc(podObj)
 
-	podMetadata := dataplane.NewPodMetadata(podKey, podObj.Status.PodIP, podObj.Spec.NodeName)
+	podIPs := c.podIPs(podObj.Status.PodIP, common.PodIPv6(podObj))
 
 	namespaceSet := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(podObj.Namespace, ipsets.Namespace)}
 
 	// Add the pod ip information into namespace's ipset.
 	// TODO: Refactor non-error/warning klogs with Zap and set the following logs to "debug" level
 	// klog.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, podObj.Status.PodIP, podObj.Namespace)
-	if err = c.dp.AddToSets(namespaceSet, podMetadata); err != nil {
+	if err = c.addToSets(namespaceSet, podKey, podIPs, podObj.Spec.NodeName); err != nil {
 		return fmt.Errorf("[syncAddedPod] Error: failed to add pod to namespace ipset with err: %w", err)
 	}
 
@@ -397,7 +413,7 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 		// TODO: Refactor non-error/warning klogs with Zap and set the following logs to "debug" level
 		// klog.Infof("Creating ipsets %+v and %+v if they do not exist", targetSetKey, targetSetKeyValue)
 		// klog.Infof("Adding pod %s (ip : %s) to ipset %s and %s", podKey, npmPodObj.PodIP, labelKey, labelKeyValue)
-		if err = c.dp.AddToSets(allSets, podMetadata); err != nil {
+		if err = c.addToSets(allSets, podKey, podIPs, podObj.Spec.NodeName); err != nil {
 			return fmt.Errorf("[syncAddedPod] Error: failed to add pod to label ipset with err: %w", err)
 		}
 		npmPodObj.AppendLabels(map[string]string{labelKey: labelVal}, common.AppendToExistingLabels)
@@ -407,7 +423,7 @@ func (c *PodController) syncAddedPod(podObj *corev1.Pod) error {
 	// TODO: Refactor non-error/warning klogs with Zap and set the following logs to "debug" level
 	// klog.Infof("Adding named port ipsets")
 	containerPorts := common.GetContainerPortList(podObj)
-	if err = c.manageNamedPortIpsets(containerPorts, podKey, npmPodObj.PodIP, podObj.Spec.NodeName, addNamedPort); err != nil {
+	if err = c.manageNamedPortIpsets(containerPorts, podKey, podIPs, podObj.Spec.NodeName, addNamedPort); err != nil {
 		return fmt.Errorf("[syncAddedPod] Error: failed to add pod to named port ipset with err: %w", err)
 	}
 	npmPodObj.AppendContainerPorts(podObj)
@@ -455,7 +471,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	// Dealing with #2 pod update event, the IP addresses of cached npmPod and newPodObj are different
 	// NPM should clean up existing references of cached pod obj and its IP.
 	// then, re-add new pod obj.
-	if cachedNpmPod.PodIP != newPodObj.Status.PodIP {
+	if cachedNpmPod.PodIP != newPodObj.Status.PodIP || cachedNpmPod.PodIPv6 != common.PodIPv6(newPodObj) {
 		// TODO: Refactor non-error/warning klogs with Zap and set the following logs to "debug" level
 		// klog.Infof("Pod (Namespace:%s, Name:%s, newUid:%s), has cachedPodIp:%s which is different from PodIp:%s",
 		// 	newPodObj.Namespace, newPodObj.Name, string(newPodObj.UID), cachedNpmPod.PodIP, newPodObj.Status.PodIP)
@@ -476,9 +492,8 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	// Otherwise it returns list of deleted PodIP from cached pod's labels and list of added PodIp from new pod's labels
 	addToIPSets, deleteFromIPSets := util.GetIPSetListCompareLabels(cachedNpmPod.Labels, newPodObj.Labels)
 
-	newPodMetadata := dataplane.NewPodMetadata(podKey, newPodObj.Status.PodIP, newPodObj.Spec.NodeName)
-	// should have newPodMetadata == cachedPodMetadata since from branch above, we have cachedNpmPod.PodIP == newPodObj.Status.PodIP
-	cachedPodMetadata := dataplane.NewPodMetadata(podKey, cachedNpmPod.PodIP, newPodMetadata.NodeName)
+	// the cached and new pod IPs are the same from the branch above
+	podIPs := c.podIPs(cachedNpmPod.PodIP, cachedNpmPod.PodIPv6)
 	// Delete the pod from its label's ipset.
 	for _, removeIPSetName := range deleteFromIPSets {
 		// TODO: Refactor non-error/warning klogs with Zap and set the following logs to "debug" level
@@ -490,7 +505,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 		} else {
 			toRemoveSet = ipsets.NewIPSetMetadata(removeIPSetName, ipsets.KeyLabelOfPod)
 		}
-		if err = c.dp.RemoveFromSets([]*ipsets.IPSetMetadata{toRemoveSet}, cachedPodMetadata); err != nil {
+		if err = c.removeFromSets([]*ipsets.IPSetMetadata{toRemoveSet}, podKey, podIPs, newPodObj.Spec.NodeName); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to delete pod from label ipset with err: %w", err)
 		}
 		// {IMPORTANT} The order of compared list will be key and then key+val. NPM should only append after both key
@@ -516,7 +531,7 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 
 		// TODO: Refactor non-error/warning klogs with Zap and set the following logs to "debug" level
 		// klog.Infof("Adding pod %s (ip : %s) to ipset %s", podKey, newPodObj.Status.PodIP, addIPSetName)
-		if err = c.dp.AddToSets([]*ipsets.IPSetMetadata{toAddSet}, newPodMetadata); err != nil {
+		if err = c.addToSets([]*ipsets.IPSetMetadata{toAddSet}, podKey, podIPs, newPodObj.Spec.NodeName); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to add pod to label ipset with err: %w", err)
 		}
 		// {IMPORTANT} Same as above order is assumed to be key and then key+val. NPM should only append to existing labels
@@ -539,14 +554,14 @@ func (c *PodController) syncAddAndUpdatePod(newPodObj *corev1.Pod) (metrics.Oper
 	if !reflect.DeepEqual(cachedNpmPod.ContainerPorts, newPodPorts) {
 		// Delete cached pod's named ports from its ipset.
 		if err = c.manageNamedPortIpsets(
-			cachedNpmPod.ContainerPorts, podKey, cachedNpmPod.PodIP, "", deleteNamedPort); err != nil {
+			cachedNpmPod.ContainerPorts, podKey, podIPs, "", deleteNamedPort); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to delete pod from named port ipset with err: %w", err)
 		}
 		// Since portList ipset deletion is successful, NPM can remove cachedContainerPorts
 		cachedNpmPod.RemoveContainerPorts()
 
 		// Add new pod's named ports from its ipset.
-		if err = c.manageNamedPortIpsets(newPodPorts, podKey, newPodObj.Status.PodIP, newPodObj.Spec.NodeName, addNamedPort); err != nil {
+		if err = c.manageNamedPortIpsets(newPodPorts, podKey, podIPs, newPodObj.Spec.NodeName, addNamedPort); err != nil {
 			return metrics.UpdateOp, fmt.Errorf("[syncAddAndUpdatePod] Error: failed to add pod to named port ipset with err: %w", err)
 		}
 		cachedNpmPod.AppendContainerPorts(newPodObj)
@@ -567,12 +582,12 @@ func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
 	}
 
 	var err error
-	cachedPodMetadata := dataplane.NewPodMetadata(cachedNpmPodKey, cachedNpmPod.PodIP, "")
+	podIPs := c.podIPs(cachedNpmPod.PodIP, cachedNpmPod.PodIPv6)
 	// Delete the pod from its namespace's ipset.
 	// note: NodeName empty is not going to call update pod
-	if err = c.dp.RemoveFromSets(
+	if err = c.removeFromSets(
 		[]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(cachedNpmPod.Namespace, ipsets.Namespace)},
-		cachedPodMetadata); err != nil {
+		cachedNpmPodKey, podIPs, ""); err != nil {
 		return fmt.Errorf("[cleanUpDeletedPod] Error: failed to delete pod from namespace ipset with err: %w", err)
 	}
 
@@ -581,12 +596,12 @@ func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
 		labelKeyValue := util.GetIpSetFromLabelKV(labelKey, labelVal)
 		// TODO: Refactor non-error/warning klogs with Zap and set the following logs to "debug" level
 		// klog.Infof("Deleting pod %s (ip : %s) from ipsets %s and %s", cachedNpmPodKey, cachedNpmPod.PodIP, labelKey, labelKeyValue)
-		if err = c.dp.RemoveFromSets(
+		if err = c.removeFromSets(
 			[]*ipsets.IPSetMetadata{
 				ipsets.NewIPSetMetadata(labelKey, ipsets.KeyLabelOfPod),
 				ipsets.NewIPSetMetadata(labelKeyValue, ipsets.KeyValueLabelOfPod),
 			},
-			cachedPodMetadata); err != nil {
+			cachedNpmPodKey, podIPs, ""); err != nil {
 			return fmt.Errorf("[cleanUpDeletedPod] Error: failed to delete pod from label ipset with err: %w", err)
 		}
 		cachedNpmPod.RemoveLabelsWithKey(labelKey)
@@ -594,7 +609,7 @@ func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
 
 	// Delete pod's named ports from its ipset. Need to pass true in the manageNamedPortIpsets function call
 	if err = c.manageNamedPortIpsets(
-		cachedNpmPod.ContainerPorts, cachedNpmPodKey, cachedNpmPod.PodIP, "", deleteNamedPort); err != nil {
+		cachedNpmPod.ContainerPorts, cachedNpmPodKey, podIPs, "", deleteNamedPort); err != nil {
 		return fmt.Errorf("[cleanUpDeletedPod] Error: failed to delete pod from named port ipset with err: %w", err)
 	}
 
@@ -604,8 +619,8 @@ func (c *PodController) cleanUpDeletedPod(cachedNpmPodKey string) error {
 }
 
 // manageNamedPortIpsets helps with adding or deleting Pod namedPort IPsets.
-func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, podKey,
-	podIP, nodeName string, namedPortOperation NamedPortOperation) error {
+func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, podKey string,
+	podIPs []string, nodeName string, namedPortOperation NamedPortOperation) error {
 	if util.IsWindowsDP() {
 		// NOTE: if we support namedport operations, need to be careful of implications of including the node name in the pod metadata below
 		// since we say the node name is "" in cleanUpDeletedPod
@@ -626,17 +641,19 @@ func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, p
 			protocol = fmt.Sprintf("%s:", port.Protocol)
 		}
 
-		namedPortIpsetEntry := fmt.Sprintf("%s,%s%d", podIP, protocol, port.ContainerPort)
+		namedPortIpsetEntries := make([]string, 0, len(podIPs))
+		for _, podIP := range podIPs {
+			namedPortIpsetEntries = append(namedPortIpsetEntries, fmt.Sprintf("%s,%s%d", podIP, protocol, port.ContainerPort))
+		}
 
 		// nodename in NewPodMetadata is nil so UpdatePod is ignored
-		podMetadata := dataplane.NewPodMetadata(podKey, namedPortIpsetEntry, nodeName)
 		switch namedPortOperation {
 		case deleteNamedPort:
-			if err := c.dp.RemoveFromSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podMetadata); err != nil {
+			if err := c.removeFromSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podKey, namedPortIpsetEntries, nodeName); err != nil {
 				return fmt.Errorf("failed to remove from set when deleting named port with err %w", err)
 			}
 		case addNamedPort:
-			if err := c.dp.AddToSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podMetadata); err != nil {
+			if err := c.addToSets([]*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata(port.Name, ipsets.NamedPorts)}, podKey, namedPortIpsetEntries, nodeName); err != nil {
 				return fmt.Errorf("failed to add to set when deleting named port with err %w", err)
 			}
 		}
@@ -645,6 +662,34 @@ func (c *PodController) manageNamedPortIpsets(portList []corev1.ContainerPort, p
 	return nil
 }
 
+// podIPs returns the IPv4 IP of a pod, plus the IPv6 IP of a dual-stack pod if IPv6 is enabled.
+func (c *PodController) podIPs(podIP, podIPv6 string) []string {
+	if c.enableIPv6 && podIPv6 != "" {
+		return []string{podIP, podIPv6}
+	}
+	return []string{podIP}
+}
+
+// addToSets adds each of a pod's IPs (or ipset entries) to the sets.
+func (c *PodController) addToSets(setMetadatas []*ipsets.IPSetMetadata, podKey string, podIPs []string, nodeName string) error {
+	for _, podIP := range podIPs {
+		if err := c.dp.AddToSets(setMetadatas, dataplane.NewPodMetadata(podKey, podIP, nodeName)); err != nil {
+			return err //nolint:wrapcheck // callers wrap the error
+		}
+	}
+	return nil
+}
+
+// removeFromSets removes each of a pod's IPs (or ipset entries) from the sets.
+func (c *PodController) removeFromSets(setMetadatas []*ipsets.IPSetMetadata, podKey string, podIPs []string, nodeName string) error {
+	for _, podIP := range podIPs {
+		if err := c.dp.RemoveFromSets(setMetadatas, dataplane.NewPodMetadata(podKey, podIP, nodeName)); err != nil {
+			return err //nolint:wrapcheck // callers wrap the error
+		}
+	}
+	return nil
+}
+
 // isCompletePod evaluates whether this pod is completely in terminated states,
 // which means pod is gracefully shutdown.
 func isCompletePod(podObj *corev1.Pod) bool {
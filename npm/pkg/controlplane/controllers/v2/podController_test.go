@@ -84,7 +84,7 @@ func (f *podFixture) newPodController(_ chan struct{}) {
 	f.kubeInformer = kubeinformers.NewSharedInformerFactory(kubeclient, noResyncPeriodFunc())
 
 	npmNamespaceCache := &NpmNamespaceCache{NsMap: make(map[string]*common.Namespace)}
-	f.podController = NewPodController(f.kubeInformer.Core().V1().Pods(), f.dp, npmNamespaceCache)
+	f.podController = NewPodController(f.kubeInformer.Core().V1().Pods(), f.dp, npmNamespaceCache, false)
 
 	for _, pod := range f.podLister {
 		err := f.kubeInformer.Core().V1().Pods().Informer().GetIndexer().Add(pod)
@@ -474,6 +474,71 @@ func TestDeletePod(t *testing.T) {
 	}
 }
 
+func TestAddAndDeleteDualStackPod(t *testing.T) {
+	if util.IsWindowsDP() {
+		t.Skip("IPv6 is only supported in Linux")
+	}
+
+	labels := map[string]string{
+		"app": "test-pod",
+	}
+	podObj := createPod("test-pod", "test-namespace", "0", "1.2.3.4", labels, NonHostNetwork, corev1.PodRunning)
+	podObj.Status.PodIPs = []corev1.PodIP{{IP: "1.2.3.4"}, {IP: "fd00::4"}}
+	podKey := getKey(podObj, t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	f := newFixture(t, dp)
+	f.podLister = append(f.podLister, podObj)
+	f.kubeobjects = append(f.kubeobjects, podObj)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	f.newPodController(stopCh)
+	f.podController.enableIPv6 = true
+
+	mockIPSets := []*ipsets.IPSetMetadata{
+		ipsets.NewIPSetMetadata("test-namespace", ipsets.Namespace),
+		ipsets.NewIPSetMetadata("app", ipsets.KeyLabelOfPod),
+		ipsets.NewIPSetMetadata("app:test-pod", ipsets.KeyValueLabelOfPod),
+	}
+	namedPortSet := []*ipsets.IPSetMetadata{ipsets.NewIPSetMetadata("app:test-pod", ipsets.NamedPorts)}
+	podMetadatas := []*dataplane.PodMetadata{
+		dataplane.NewPodMetadata(podKey, "1.2.3.4", ""),
+		dataplane.NewPodMetadata(podKey, "fd00::4", ""),
+	}
+	namedPortMetadatas := []*dataplane.PodMetadata{
+		dataplane.NewPodMetadata(podKey, "1.2.3.4,8080", ""),
+		dataplane.NewPodMetadata(podKey, "fd00::4,8080", ""),
+	}
+
+	dp.EXPECT().AddToLists([]*ipsets.IPSetMetadata{kubeAllNamespaces}, mockIPSets[:1]).Return(nil).Times(1)
+	for i := range podMetadatas {
+		dp.EXPECT().AddToSets(mockIPSets[:1], podMetadatas[i]).Return(nil).Times(1)
+		dp.EXPECT().AddToSets(mockIPSets[1:], podMetadatas[i]).Return(nil).Times(1)
+		dp.EXPECT().AddToSets(namedPortSet, namedPortMetadatas[i]).Return(nil).Times(1)
+		dp.EXPECT().RemoveFromSets(mockIPSets[:1], podMetadatas[i]).Return(nil).Times(1)
+		dp.EXPECT().RemoveFromSets(mockIPSets[1:], podMetadatas[i]).Return(nil).Times(1)
+		dp.EXPECT().RemoveFromSets(namedPortSet, namedPortMetadatas[i]).Return(nil).Times(1)
+	}
+	// deletePod adds the pod again before deleting it
+	dp.EXPECT().ApplyDataPlane().Return(nil).Times(3)
+
+	addPod(t, f, podObj)
+	require.Equal(t, "fd00::4", f.podController.podMap[podKey].PodIPv6)
+	key, ok := f.podController.PodKeyForIP("fd00::4")
+	require.True(t, ok)
+	require.Equal(t, podKey, key)
+
+	deletePod(t, f, podObj, DeletedFinalStateknownObject)
+	// sleep in case rate limiter adds back to workqueue
+	time.Sleep(sleepDurationForRateLimiter)
+	if _, exists := f.podController.podMap[podKey]; exists {
+		t.Error("TestAddAndDeleteDualStackPod failed @ cached pod obj exists check")
+	}
+}
+
 func TestDeletePodControllerError(t *testing.T) {
 	labels := map[string]string{
 		"app": "test-pod",
@@ -14,7 +14,16 @@ import (
 	"k8s.io/klog"
 )
 
-var ErrPodOrNodeNameNil = fmt.Errorf("both pod and node name must be set")
+var (
+	ErrPodOrNodeNameNil  = fmt.Errorf("both pod and node name must be set")
+	ErrMissedGenerations = fmt.Errorf("missed goal state generations")
+)
+
+// GoalStateAcknowledger reports the goal state generations which the GoalStateProcessor applied to the controller.
+type GoalStateAcknowledger interface {
+	// Acknowledge reports the generation. err is set if the generation failed to apply.
+	Acknowledge(generation uint64, err error)
+}
 
 type GoalStateProcessor struct {
 	ctx            context.Context
@@ -24,6 +33,11 @@ type GoalStateProcessor struct {
 	dp             dataplane.GenericDataplane
 	inputChannel   chan *protos.Events
 	backoffChannel chan *protos.Events
+	// acker can be nil if the controller doesn't support acknowledgements.
+	acker GoalStateAcknowledger
+	// appliedGeneration is the generation of the last event applied.
+	// It is zero if the controller doesn't send generations.
+	appliedGeneration uint64
 }
 
 func NewGoalStateProcessor(
@@ -31,7 +45,8 @@ func NewGoalStateProcessor(
 	nodeID string,
 	podName string,
 	inputChan chan *protos.Events,
-	dp dataplane.GenericDataplane) (*GoalStateProcessor, error) {
+	dp dataplane.GenericDataplane,
+	acker GoalStateAcknowledger) (*GoalStateProcessor, error) {
 
 	if nodeID == "" || podName == "" {
 		return nil, ErrPodOrNodeNameNil
@@ -46,6 +61,7 @@ func NewGoalStateProcessor(
 		dp:             dp,
 		inputChannel:   inputChan,
 		backoffChannel: make(chan *protos.Events),
+		acker:          acker,
 	}, nil
 }
 
@@ -95,35 +111,72 @@ func (gsp *GoalStateProcessor) processNext(stopCh <-chan struct{}) bool {
 }
 
 func (gsp *GoalStateProcessor) process(inputEvent *protos.Events) {
+	generation := inputEvent.GetGeneration()
+	if generation == 0 {
+		// the controller doesn't send generations
+		_ = gsp.processEvent(inputEvent)
+		return
+	}
+
+	var gapErr error
+	if inputEvent.GetEventType() == protos.Events_GoalState && gsp.appliedGeneration != 0 {
+		if generation <= gsp.appliedGeneration {
+			klog.Infof("Skipping goal state generation %d since generation %d is applied", generation, gsp.appliedGeneration)
+			return
+		}
+		if generation > gsp.appliedGeneration+1 {
+			// apply the event anyways since it has the whole objects, and let the controller hydrate the missed events
+			gapErr = fmt.Errorf("%w: applied generation %d and received generation %d", ErrMissedGenerations, gsp.appliedGeneration, generation)
+		}
+	}
+
+	err := gsp.processEvent(inputEvent)
+	if err == nil {
+		err = gapErr
+	}
+	if err == nil || inputEvent.GetEventType() == protos.Events_Hydration {
+		gsp.appliedGeneration = generation
+	}
+	if gsp.acker != nil {
+		gsp.acker.Acknowledge(generation, err)
+	}
+}
+
+func (gsp *GoalStateProcessor) processEvent(inputEvent *protos.Events) (err error) {
 	klog.Infof("Processing event")
 	// apply dataplane after syncing
 	defer func() {
 		dperr := gsp.dp.ApplyDataPlane()
 		if dperr != nil {
 			klog.Errorf("Apply Dataplane failed with %v", dperr)
+			if err == nil {
+				err = npmerrors.SimpleErrorWrapper("failed to apply dataplane", dperr)
+			}
 		}
 	}()
 
 	payload := inputEvent.GetPayload()
 	if !validatePayload(payload) {
 		klog.Warningf("Empty payload in event %s", inputEvent)
-		return
+		return nil
 	}
 
 	switch inputEvent.GetEventType() {
 	case protos.Events_Hydration:
 		// in hydration event, any thing in local cache and not in event should be deleted.
 		klog.Infof("Received hydration event")
-		gsp.processHydrationEvent(payload)
+		return gsp.processHydrationEvent(payload)
 	case protos.Events_GoalState:
 		klog.Infof("Received goal state event")
-		gsp.processGoalStateEvent(payload)
+		return gsp.processGoalStateEvent(payload)
 	default:
 		klog.Errorf("Received unknown event type %s", inputEvent.GetEventType())
+		return nil
 	}
 }
 
-func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.GoalState) {
+// processHydrationEvent returns the last error, if any, after processing the whole event.
+func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.GoalState) error {
 	// Hydration events are sent when the daemon first starts up, or a reconnection to controller happens.
 	// In this case, the controller will send a current state of the cache down to daemon.
 	// Daemon will need to calculate what updates and deleted have been missed and send them to the dataplane.
@@ -138,11 +191,13 @@ func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.
 	var appendedIPSets map[string]struct{}
 	var appendedPolicies map[string]struct{}
 	var err error
+	var lastErr error
 
 	if ipsetApplyPayload, ok := payload[cp.IpsetApply]; ok {
 		appendedIPSets, err = gsp.processIPSetsApplyEvent(ipsetApplyPayload)
 		if err != nil {
 			klog.Errorf("Error processing IPSET apply HYDRATION event %s", err)
+			lastErr = err
 		}
 	}
 
@@ -150,6 +205,7 @@ func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.
 		appendedPolicies, err = gsp.processPolicyApplyEvent(policyApplyPayload)
 		if err != nil {
 			klog.Errorf("Error processing POLICY apply HYDRATION event %s", err)
+			lastErr = err
 		}
 	}
 
@@ -170,6 +226,7 @@ func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.
 		err = gsp.processPolicyRemoveEvent(toDeletePolicies)
 		if err != nil {
 			klog.Errorf("Error processing POLICY remove HYDRATION event %s", err)
+			lastErr = err
 		}
 	}
 
@@ -198,9 +255,12 @@ func (gsp *GoalStateProcessor) processHydrationEvent(payload map[string]*protos.
 		klog.Infof("Deleting %d ipsets", len(toDeleteIPSets))
 		gsp.processIPSetsRemoveEvent(toDeleteIPSets, util.ForceDelete)
 	}
+	return lastErr
 }
 
-func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.GoalState) {
+// processGoalStateEvent returns the last error, if any, after processing the whole event.
+func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.GoalState) error {
+	var lastErr error
 	// Process these individual buckets in order
 	// 1. Apply IPSET
 	// 2. Apply POLICY
@@ -210,6 +270,7 @@ func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.
 		_, err := gsp.processIPSetsApplyEvent(ipsetApplyPayload)
 		if err != nil {
 			klog.Errorf("Error processing IPSET apply event %s", err)
+			lastErr = err
 		}
 	}
 
@@ -217,6 +278,7 @@ func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.
 		_, err := gsp.processPolicyApplyEvent(policyApplyPayload)
 		if err != nil {
 			klog.Errorf("Error processing POLICY apply event %s", err)
+			lastErr = err
 		}
 	}
 
@@ -225,10 +287,12 @@ func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.
 		netpolNames, err := cp.DecodeStrings(payload)
 		if err != nil {
 			klog.Errorf("Error processing POLICY remove event, failed to decode Policy remove event %s", err)
+			lastErr = err
 		}
 		err = gsp.processPolicyRemoveEvent(netpolNames)
 		if err != nil {
 			klog.Errorf("Error processing POLICY remove event %s", err)
+			lastErr = err
 		}
 	}
 
@@ -237,9 +301,11 @@ func (gsp *GoalStateProcessor) processGoalStateEvent(payload map[string]*protos.
 		ipsetNames, err := cp.DecodeStrings(payload)
 		if err != nil {
 			klog.Errorf("Error processing IPSET remove event, failed to decode IPSet remove event: %s", err)
+			lastErr = err
 		}
 		gsp.processIPSetsRemoveEvent(ipsetNames, util.SoftDelete)
 	}
+	return lastErr
 }
 
 func (gsp *GoalStateProcessor) processIPSetsApplyEvent(goalState *protos.GoalState) (map[string]struct{}, error) {
@@ -289,12 +355,11 @@ func (gsp *GoalStateProcessor) processIPSetsApplyEvent(goalState *protos.GoalSta
 }
 
 func (gsp *GoalStateProcessor) applySets(ipSet *cp.ControllerIPSets, cachedIPSet *ipsets.IPSet) error {
+	setMetadata := ipSet.GetMetadata()
 	if len(ipSet.IPPodMetadata) == 0 {
-		gsp.dp.CreateIPSets([]*ipsets.IPSetMetadata{ipSet.GetMetadata()})
-		return nil
+		gsp.dp.CreateIPSets([]*ipsets.IPSetMetadata{setMetadata})
 	}
 
-	setMetadata := ipSet.GetMetadata()
 	for _, podMetadata := range ipSet.IPPodMetadata {
 		err := gsp.dp.AddToSets([]*ipsets.IPSetMetadata{setMetadata}, podMetadata)
 		if err != nil {
@@ -90,7 +90,7 @@ func TestPolicyApplyEvent(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	gsp, _ := NewGoalStateProcessor(ctx, "node1", "pod1", inputChan, dp)
+	gsp, _ := NewGoalStateProcessor(ctx, "node1", "pod1", inputChan, dp, nil)
 
 	go func() {
 		inputChan <- &protos.Events{
@@ -129,7 +129,7 @@ func TestIPSetsApply(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	gsp, _ := NewGoalStateProcessor(ctx, "node1", "pod1", inputChan, dp)
+	gsp, _ := NewGoalStateProcessor(ctx, "node1", "pod1", inputChan, dp, nil)
 	go func() {
 		inputChan <- &protos.Events{
 			Payload: goalState,
@@ -170,7 +170,7 @@ func TestIPSetsApplyUpdateMembers(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	gsp, _ := NewGoalStateProcessor(ctx, "node1", "pod1", inputChan, dp)
+	gsp, _ := NewGoalStateProcessor(ctx, "node1", "pod1", inputChan, dp, nil)
 	go func() {
 		inputChan <- &protos.Events{
 			EventType: protos.Events_GoalState,
@@ -201,6 +201,47 @@ func TestIPSetsApplyUpdateMembers(t *testing.T) {
 	gsp.processNext(wait.NeverStop)
 }
 
+type fakeAcknowledger struct {
+	generations []uint64
+	errs        []error
+}
+
+func (a *fakeAcknowledger) Acknowledge(generation uint64, err error) {
+	a.generations = append(a.generations, generation)
+	a.errs = append(a.errs, err)
+}
+
+func TestGoalStateGenerations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dp := dpmocks.NewMockGenericDataplane(ctrl)
+	dp.EXPECT().GetIPSet(gomock.Any()).AnyTimes()
+	dp.EXPECT().CreateIPSets(gomock.Any()).AnyTimes()
+	dp.EXPECT().GetAllPolicies().Times(1)
+	dp.EXPECT().GetAllIPSets().Times(1)
+	// the stale generation isn't applied
+	dp.EXPECT().ApplyDataPlane().Times(3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	acker := &fakeAcknowledger{}
+	gsp, _ := NewGoalStateProcessor(ctx, "node1", "pod1", make(chan *protos.Events), dp, acker)
+
+	goalState := getGoalStateForControllerSets(t, []*controlplane.ControllerIPSets{controlplane.NewControllerIPSets(testNSSet)})
+	gsp.process(&protos.Events{EventType: protos.Events_Hydration, Payload: goalState, Generation: 10})
+	gsp.process(&protos.Events{EventType: protos.Events_GoalState, Payload: goalState, Generation: 10})
+	gsp.process(&protos.Events{EventType: protos.Events_GoalState, Payload: goalState, Generation: 11})
+	// generation 12 was missed
+	gsp.process(&protos.Events{EventType: protos.Events_GoalState, Payload: goalState, Generation: 13})
+
+	assert.Equal(t, []uint64{10, 11, 13}, acker.generations)
+	assert.NoError(t, acker.errs[0])
+	assert.NoError(t, acker.errs[1])
+	assert.ErrorIs(t, acker.errs[2], ErrMissedGenerations)
+	assert.Equal(t, uint64(11), gsp.appliedGeneration)
+}
+
 func getGoalStateForControllerSets(t *testing.T, sets []*controlplane.ControllerIPSets) map[string]*protos.GoalState {
 	goalState := map[string]*protos.GoalState{
 		controlplane.IpsetApply: {
@@ -0,0 +1,166 @@
+// Package policystatus reports the NetworkPolicies which NPM failed to enforce to the Kubernetes API.
+package policystatus
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/crd/networkpolicynodestatus/api/v1alpha1"
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// PolicyEnforcedReason is the reason of the event which clears the failure of a policy.
+	PolicyEnforcedReason = "PolicyEnforced"
+
+	// nodeStatusInterval is how often the NetworkPolicyNodeStatus is updated if the failures changed.
+	nodeStatusInterval = 30 * time.Second
+	nodeStatusTimeout  = 10 * time.Second
+)
+
+// NodeStatusClient updates the NetworkPolicyNodeStatus of a node.
+type NodeStatusClient interface {
+	UpdateStatus(ctx context.Context, nodeName string, status *v1alpha1.NetworkPolicyNodeStatusStatus) (*v1alpha1.NetworkPolicyNodeStatus, error)
+}
+
+// Reporter emits Kubernetes Events on the NetworkPolicies which NPM failed to enforce on the node,
+// and aggregates the failures in the NetworkPolicyNodeStatus of the node.
+// A nil Reporter reports nothing.
+type Reporter struct {
+	sync.Mutex
+	nodeName     string
+	recorder     record.EventRecorder
+	statusClient NodeStatusClient
+	// failures has the failed policies keyed by <namespace>/<name>
+	failures map[string]*v1alpha1.FailedPolicy
+	// statusChanged is true if the failures changed since the last NetworkPolicyNodeStatus update
+	statusChanged bool
+}
+
+// NewReporter creates a Reporter. statusClient can be nil to only emit events.
+func NewReporter(nodeName string, recorder record.EventRecorder, statusClient NodeStatusClient) *Reporter {
+	return &Reporter{
+		nodeName:     nodeName,
+		recorder:     recorder,
+		statusClient: statusClient,
+		failures:     make(map[string]*v1alpha1.FailedPolicy),
+		// start with an empty NetworkPolicyNodeStatus in case the node had failures before NPM restarted
+		statusChanged: true,
+	}
+}
+
+// PolicyFailed emits a Warning event on the policy if it wasn't already failing with the same reason and message.
+func (r *Reporter) PolicyFailed(netPol *networkingv1.NetworkPolicy, reason v1alpha1.FailureReason, err error) {
+	if r == nil {
+		return
+	}
+	key, keyErr := cache.MetaNamespaceKeyFunc(netPol)
+	if keyErr != nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	failure, ok := r.failures[key]
+	if ok && failure.Reason == reason && failure.Message == err.Error() {
+		return
+	}
+	r.failures[key] = &v1alpha1.FailedPolicy{
+		Namespace:          netPol.Namespace,
+		Name:               netPol.Name,
+		Reason:             reason,
+		Message:            err.Error(),
+		LastTransitionTime: metav1.Now(),
+	}
+	r.statusChanged = true
+	r.recorder.Eventf(netPol, corev1.EventTypeWarning, string(reason), "NPM on node %s failed to enforce the policy: %s", r.nodeName, err.Error())
+}
+
+// PolicyHealthy emits a Normal event on the policy if it was failing.
+func (r *Reporter) PolicyHealthy(netPol *networkingv1.NetworkPolicy) {
+	if r == nil {
+		return
+	}
+	key, keyErr := cache.MetaNamespaceKeyFunc(netPol)
+	if keyErr != nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.failures[key]; !ok {
+		return
+	}
+	delete(r.failures, key)
+	r.statusChanged = true
+	r.recorder.Eventf(netPol, corev1.EventTypeNormal, PolicyEnforcedReason, "NPM on node %s enforced the policy", r.nodeName)
+}
+
+// PolicyDeleted forgets the failure of the deleted policy.
+func (r *Reporter) PolicyDeleted(key string) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.failures[key]; !ok {
+		return
+	}
+	delete(r.failures, key)
+	r.statusChanged = true
+}
+
+// Run periodically updates the NetworkPolicyNodeStatus of the node until the stop channel is closed.
+func (r *Reporter) Run(stopCh <-chan struct{}) {
+	if r == nil || r.statusClient == nil {
+		return
+	}
+	wait.Until(r.updateNodeStatus, nodeStatusInterval, stopCh)
+}
+
+func (r *Reporter) updateNodeStatus() {
+	r.Lock()
+	if !r.statusChanged {
+		r.Unlock()
+		return
+	}
+	status := r.nodeStatus()
+	r.statusChanged = false
+	r.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), nodeStatusTimeout)
+	defer cancel()
+	if _, err := r.statusClient.UpdateStatus(ctx, r.nodeName, status); err != nil {
+		metrics.SendErrorLogAndMetric(util.NetpolID, "error: failed to update NetworkPolicyNodeStatus: %v", err)
+		r.Lock()
+		r.statusChanged = true
+		r.Unlock()
+	}
+}
+
+// nodeStatus returns the failures sorted by namespace and name. Assumes the Reporter is locked.
+func (r *Reporter) nodeStatus() *v1alpha1.NetworkPolicyNodeStatusStatus {
+	keys := make([]string, 0, len(r.failures))
+	for key := range r.failures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	status := &v1alpha1.NetworkPolicyNodeStatusStatus{
+		FailedPolicyCount: len(keys),
+	}
+	for _, key := range keys {
+		status.FailedPolicies = append(status.FailedPolicies, *r.failures[key].DeepCopy())
+	}
+	return status
+}
@@ -0,0 +1,108 @@
+package policystatus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/crd/networkpolicynodestatus/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var errTest = errors.New("test error")
+
+type fakeStatusClient struct {
+	statuses []*v1alpha1.NetworkPolicyNodeStatusStatus
+	err      error
+}
+
+func (c *fakeStatusClient) UpdateStatus(_ context.Context, nodeName string, status *v1alpha1.NetworkPolicyNodeStatusStatus) (*v1alpha1.NetworkPolicyNodeStatus, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.statuses = append(c.statuses, status)
+	return &v1alpha1.NetworkPolicyNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Status:     *status,
+	}, nil
+}
+
+func netPol(namespace, name string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
+func TestPolicyFailedAndHealthy(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	statusClient := &fakeStatusClient{}
+	r := NewReporter("node1", recorder, statusClient)
+
+	// the status is reset on start
+	r.updateNodeStatus()
+	require.Len(t, statusClient.statuses, 1)
+	require.Equal(t, 0, statusClient.statuses[0].FailedPolicyCount)
+
+	r.PolicyFailed(netPol("y", "b"), v1alpha1.ApplyFailed, errTest)
+	r.PolicyFailed(netPol("x", "a"), v1alpha1.UnsupportedFeature, errTest)
+	// the same failure is only reported once
+	r.PolicyFailed(netPol("x", "a"), v1alpha1.UnsupportedFeature, errTest)
+	require.Len(t, recorder.Events, 2)
+	require.Equal(t, "Warning ApplyFailed NPM on node node1 failed to enforce the policy: test error", <-recorder.Events)
+	require.Equal(t, "Warning UnsupportedFeature NPM on node node1 failed to enforce the policy: test error", <-recorder.Events)
+
+	r.updateNodeStatus()
+	require.Len(t, statusClient.statuses, 2)
+	status := statusClient.statuses[1]
+	require.Equal(t, 2, status.FailedPolicyCount)
+	require.Equal(t, "x", status.FailedPolicies[0].Namespace)
+	require.Equal(t, v1alpha1.UnsupportedFeature, status.FailedPolicies[0].Reason)
+	require.Equal(t, "y", status.FailedPolicies[1].Namespace)
+	require.Equal(t, v1alpha1.ApplyFailed, status.FailedPolicies[1].Reason)
+
+	// nothing changed
+	r.updateNodeStatus()
+	require.Len(t, statusClient.statuses, 2)
+
+	r.PolicyHealthy(netPol("x", "a"))
+	// a policy which never failed has no event
+	r.PolicyHealthy(netPol("z", "c"))
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, "Normal PolicyEnforced NPM on node node1 enforced the policy", <-recorder.Events)
+
+	r.PolicyDeleted("y/b")
+	require.Empty(t, recorder.Events)
+
+	r.updateNodeStatus()
+	require.Len(t, statusClient.statuses, 3)
+	require.Equal(t, 0, statusClient.statuses[2].FailedPolicyCount)
+	require.Empty(t, statusClient.statuses[2].FailedPolicies)
+}
+
+func TestUpdateNodeStatusRetries(t *testing.T) {
+	statusClient := &fakeStatusClient{err: errTest}
+	r := NewReporter("node1", record.NewFakeRecorder(10), statusClient)
+	r.PolicyFailed(netPol("x", "a"), v1alpha1.TranslationFailed, errTest)
+
+	r.updateNodeStatus()
+	require.Empty(t, statusClient.statuses)
+
+	statusClient.err = nil
+	r.updateNodeStatus()
+	require.Len(t, statusClient.statuses, 1)
+	require.Equal(t, 1, statusClient.statuses[0].FailedPolicyCount)
+}
+
+func TestNilReporter(t *testing.T) {
+	var r *Reporter
+	r.PolicyFailed(netPol("x", "a"), v1alpha1.ApplyFailed, errTest)
+	r.PolicyHealthy(netPol("x", "a"))
+	r.PolicyDeleted("x/a")
+	r.Run(make(chan struct{}))
+}
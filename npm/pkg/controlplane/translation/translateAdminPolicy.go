@@ -0,0 +1,318 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+var (
+	// ErrUnsupportedNodePeer is returned when an AdminNetworkPolicy or BaselineAdminNetworkPolicy selects nodes as egress peers.
+	ErrUnsupportedNodePeer = errors.New("unsupported nodes peer in admin network policy")
+	// ErrUnsupportedSubject is returned when the namespace selector of a subject expands to multiple selectors
+	// (e.g. a NotIn matchExpression with several values), since a subject must translate into a single pod selector.
+	ErrUnsupportedSubject = errors.New("unsupported multi-value matchExpression in admin network policy subject")
+	// ErrUnsupportedPassAction is returned when the Pass action is used in windows.
+	ErrUnsupportedPassAction = errors.New("unsupported Pass action in admin network policy used on windows")
+	// ErrUnsupportedAdminPriority is returned when an AdminNetworkPolicy priority can't be ordered in windows.
+	ErrUnsupportedAdminPriority = fmt.Errorf("unsupported admin network policy priority used on windows, priority must be at most %d",
+		policies.MaxWindowsAdminPriority)
+	errUnknownAdminAction = errors.New("unknown admin network policy action")
+)
+
+const (
+	anpSetPrefix         = "anp"
+	banpSetPrefix        = "banp"
+	networkSetNameFormat = "%s-%s-%d-%d%s"
+)
+
+// adminRule is the common form of the ingress and egress rules of
+// AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+type adminRule struct {
+	action v1alpha1.AdminNetworkPolicyRuleAction
+	peers  []v1alpha1.AdminNetworkPolicyEgressPeer
+	ports  *[]v1alpha1.AdminNetworkPolicyPort
+}
+
+// networkSetName returns the name of the CIDR set for the networks of an egress peer.
+// For example, the first peer of the second egress rule in AdminNetworkPolicy "test"
+// translates to "anp-test-1-0OUT".
+func networkSetName(setPrefix, policyName string, direction policies.Direction, ruleIndex, peerIndex int) string {
+	return fmt.Sprintf(networkSetNameFormat, setPrefix, policyName, ruleIndex, peerIndex, direction)
+}
+
+// adminVerdict maps the action of a rule to the verdict of its ACLs.
+func adminVerdict(action v1alpha1.AdminNetworkPolicyRuleAction) (policies.Verdict, error) {
+	switch action {
+	case v1alpha1.AdminNetworkPolicyRuleActionAllow:
+		return policies.Allowed, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionDeny:
+		return policies.Dropped, nil
+	case v1alpha1.AdminNetworkPolicyRuleActionPass:
+		if util.IsWindowsDP() {
+			return "", ErrUnsupportedPassAction
+		}
+		return policies.Passed, nil
+	default:
+		return "", fmt.Errorf("%w: %s", errUnknownAdminAction, action)
+	}
+}
+
+// adminPort converts the port of an admin rule to a NetworkPolicyPort so the port helpers of NetworkPolicies can be reused.
+func adminPort(port *v1alpha1.AdminNetworkPolicyPort) networkingv1.NetworkPolicyPort {
+	switch {
+	case port.PortNumber != nil:
+		portNumber := intstr.FromInt(int(port.PortNumber.Port))
+		protocol := port.PortNumber.Protocol
+		return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &portNumber}
+	case port.PortRange != nil:
+		start := intstr.FromInt(int(port.PortRange.Start))
+		end := port.PortRange.End
+		protocol := port.PortRange.Protocol
+		if protocol == "" {
+			return networkingv1.NetworkPolicyPort{Port: &start, EndPort: &end}
+		}
+		return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &start, EndPort: &end}
+	case port.NamedPort != nil:
+		namedPort := intstr.FromString(*port.NamedPort)
+		return networkingv1.NetworkPolicyPort{Port: &namedPort}
+	default:
+		return networkingv1.NetworkPolicyPort{}
+	}
+}
+
+// adminPeerAndPortRule adds one ACL with the rule's verdict per port, or a single ACL if the rule has no ports.
+func adminPeerAndPortRule(npmNetPol *policies.NPMNetworkPolicy, verdict policies.Verdict, direction policies.Direction, ruleIndex int,
+	ports *[]v1alpha1.AdminNetworkPolicyPort, setInfo []policies.SetInfo,
+) error { //nolint // gofumpt
+	if ports == nil || len(*ports) == 0 {
+		acl := policies.NewACLPolicy(verdict, direction)
+		acl.RuleIndex = ruleIndex
+		acl.AddSetInfo(setInfo)
+		npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+		return nil
+	}
+
+	for i := range *ports {
+		port := adminPort(&(*ports)[i])
+		portKind, err := portType(port)
+		if err != nil {
+			return err
+		}
+
+		acl := policies.NewACLPolicy(verdict, direction)
+		acl.RuleIndex = ruleIndex
+		acl.AddSetInfo(setInfo)
+		npmNetPol.RuleIPSets = portRule(npmNetPol.RuleIPSets, acl, &port, portKind)
+		npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+	}
+	return nil
+}
+
+// networksRule translates the networks of an egress peer into one CIDR set since the networks are ORed.
+func networksRule(setName string, matchType policies.MatchType, networks []v1alpha1.CIDR) (*ipsets.TranslatedIPSet, policies.SetInfo, error) {
+	members := make([]string, 0, len(networks))
+	for _, network := range networks {
+		cidr := string(network)
+		if !isSupportedCIDR(cidr) {
+			return nil, policies.SetInfo{}, ErrUnsupportedIPAddress
+		}
+		// Ipset doesn't allow 0.0.0.0/0 or ::/0 to be added, so split it in half like an IPBlock.
+		if splitCIDRs, ok := allIPsSplitCIDRs[cidr]; ok {
+			members = append(members, splitCIDRs...)
+			continue
+		}
+		members = append(members, cidr)
+	}
+
+	networkIPSet := ipsets.NewTranslatedIPSet(setName, ipsets.CIDRBlocks, members...)
+	setInfo := policies.NewSetInfo(setName, ipsets.CIDRBlocks, included, matchType)
+	return networkIPSet, setInfo, nil
+}
+
+// translateAdminRule translates an ingress or egress rule of an admin policy and updates npmNetPol object.
+// Unlike NetworkPolicies, each peer is exactly one of namespaces, pods, nodes, or networks.
+func translateAdminRule(npmNetPol *policies.NPMNetworkPolicy,
+	setPrefix, policyName string,
+	direction policies.Direction,
+	matchType policies.MatchType,
+	ruleIndex int,
+	rule adminRule,
+) error {
+	verdict, err := adminVerdict(rule.action)
+	if err != nil {
+		return err
+	}
+
+	for peerIdx, peer := range rule.peers {
+		switch {
+		case peer.Namespaces != nil:
+			// Before translating NamespaceSelector, flattenNameSpaceSelector function call should be called
+			// to handle multiple values in matchExpressions spec.
+			flattenNSSelector, err := flattenNameSpaceSelector(peer.Namespaces)
+			if err != nil {
+				return err
+			}
+
+			for i := range flattenNSSelector {
+				nsSelectorIPSets, nsSelectorList := nameSpaceSelector(matchType, &flattenNSSelector[i])
+				npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, nsSelectorIPSets...)
+				if err := adminPeerAndPortRule(npmNetPol, verdict, direction, ruleIndex, rule.ports, nsSelectorList); err != nil {
+					return err
+				}
+			}
+		case peer.Pods != nil:
+			psResult, err := podSelector(npmNetPol.PolicyKey, matchType, &peer.Pods.PodSelector)
+			if err != nil {
+				return err
+			}
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.psSets...)
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, psResult.childPSSets...)
+
+			flattenNSSelector, err := flattenNameSpaceSelector(&peer.Pods.NamespaceSelector)
+			if err != nil {
+				return err
+			}
+
+			for i := range flattenNSSelector {
+				nsSelectorIPSets, nsSelectorList := nameSpaceSelector(matchType, &flattenNSSelector[i])
+				npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, nsSelectorIPSets...)
+				nsSelectorList = append(nsSelectorList, psResult.psList...)
+				if err := adminPeerAndPortRule(npmNetPol, verdict, direction, ruleIndex, rule.ports, nsSelectorList); err != nil {
+					return err
+				}
+			}
+		case peer.Nodes != nil:
+			return ErrUnsupportedNodePeer
+		case len(peer.Networks) > 0:
+			setName := networkSetName(setPrefix, policyName, direction, ruleIndex, peerIdx)
+			networkIPSet, networkSetInfo, err := networksRule(setName, matchType, peer.Networks)
+			if err != nil {
+				return err
+			}
+			npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, networkIPSet)
+			if err := adminPeerAndPortRule(npmNetPol, verdict, direction, ruleIndex, rule.ports, []policies.SetInfo{networkSetInfo}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// adminSubject translates the subject of an admin policy into the pod selector of npmNetPol object.
+func adminSubject(npmNetPol *policies.NPMNetworkPolicy, subject *v1alpha1.AdminNetworkPolicySubject) error {
+	psResult := &podSelectorResult{
+		psSets:      make([]*ipsets.TranslatedIPSet, 0),
+		childPSSets: make([]*ipsets.TranslatedIPSet, 0),
+		psList:      make([]policies.SetInfo, 0),
+	}
+
+	nsSelector := subject.Namespaces
+	if subject.Pods != nil {
+		var err error
+		psResult, err = podSelector(npmNetPol.PolicyKey, policies.EitherMatch, &subject.Pods.PodSelector)
+		if err != nil {
+			return err
+		}
+		nsSelector = &subject.Pods.NamespaceSelector
+	}
+
+	// The subject is a single pod selector, so its namespace selector can't be split into ORed selectors.
+	flattenNSSelector, err := flattenNameSpaceSelector(nsSelector)
+	if err != nil {
+		return err
+	}
+	if len(flattenNSSelector) != 1 {
+		return ErrUnsupportedSubject
+	}
+	nsSelectorIPSets, nsSelectorList := nameSpaceSelector(policies.EitherMatch, &flattenNSSelector[0])
+
+	npmNetPol.PodSelectorIPSets = append(psResult.psSets, nsSelectorIPSets...)
+	npmNetPol.ChildPodSelectorIPSets = psResult.childPSSets
+	npmNetPol.PodSelectorList = append(psResult.psList, nsSelectorList...)
+	return nil
+}
+
+// translateAdminPolicy fills in npmNetPol object from the subject and rules of an admin policy.
+// Admin policies have no default drop rules: traffic matching none of their rules falls through to the next tier.
+func translateAdminPolicy(npmNetPol *policies.NPMNetworkPolicy, setPrefix, policyName string,
+	subject *v1alpha1.AdminNetworkPolicySubject, ingress, egress []adminRule,
+) (*policies.NPMNetworkPolicy, error) { //nolint // gofumpt
+	if err := adminSubject(npmNetPol, subject); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range ingress {
+		if err := translateAdminRule(npmNetPol, setPrefix, policyName, policies.Ingress, policies.SrcMatch, i, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, rule := range egress {
+		if err := translateAdminRule(npmNetPol, setPrefix, policyName, policies.Egress, policies.DstMatch, i, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	if util.IsWindowsDP() {
+		for _, acl := range npmNetPol.ACLs {
+			if acl.Protocol == policies.SCTP {
+				return nil, ErrUnsupportedSCTP
+			}
+		}
+	}
+	return npmNetPol, nil
+}
+
+// TranslateAdminNetworkPolicy translates AdminNetworkPolicy object to NPMNetworkPolicy object in the AdminTier
+// and returns the NPMNetworkPolicy object.
+func TranslateAdminNetworkPolicy(anpObj *v1alpha1.AdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	if util.IsWindowsDP() && anpObj.Spec.Priority > policies.MaxWindowsAdminPriority {
+		return nil, ErrUnsupportedAdminPriority
+	}
+
+	ingress := make([]adminRule, len(anpObj.Spec.Ingress))
+	for i, rule := range anpObj.Spec.Ingress {
+		ingress[i] = adminRule{action: rule.Action, peers: ingressPeers(rule.From), ports: rule.Ports}
+	}
+	egress := make([]adminRule, len(anpObj.Spec.Egress))
+	for i, rule := range anpObj.Spec.Egress {
+		egress[i] = adminRule{action: rule.Action, peers: rule.To, ports: rule.Ports}
+	}
+
+	npmNetPol := policies.NewAdminNPMNetworkPolicy(anpObj.Name, anpObj.Spec.Priority)
+	return translateAdminPolicy(npmNetPol, anpSetPrefix, anpObj.Name, &anpObj.Spec.Subject, ingress, egress)
+}
+
+// TranslateBaselineAdminNetworkPolicy translates BaselineAdminNetworkPolicy object to NPMNetworkPolicy object in the BaselineTier
+// and returns the NPMNetworkPolicy object.
+func TranslateBaselineAdminNetworkPolicy(banpObj *v1alpha1.BaselineAdminNetworkPolicy) (*policies.NPMNetworkPolicy, error) {
+	ingress := make([]adminRule, len(banpObj.Spec.Ingress))
+	for i, rule := range banpObj.Spec.Ingress {
+		action := v1alpha1.AdminNetworkPolicyRuleAction(rule.Action)
+		ingress[i] = adminRule{action: action, peers: ingressPeers(rule.From), ports: rule.Ports}
+	}
+	egress := make([]adminRule, len(banpObj.Spec.Egress))
+	for i, rule := range banpObj.Spec.Egress {
+		action := v1alpha1.AdminNetworkPolicyRuleAction(rule.Action)
+		egress[i] = adminRule{action: action, peers: rule.To, ports: rule.Ports}
+	}
+
+	npmNetPol := policies.NewBaselineAdminNPMNetworkPolicy(banpObj.Name)
+	return translateAdminPolicy(npmNetPol, banpSetPrefix, banpObj.Name, &banpObj.Spec.Subject, ingress, egress)
+}
+
+// ingressPeers converts ingress peers to egress peers, which have a superset of their fields.
+func ingressPeers(from []v1alpha1.AdminNetworkPolicyIngressPeer) []v1alpha1.AdminNetworkPolicyEgressPeer {
+	peers := make([]v1alpha1.AdminNetworkPolicyEgressPeer, len(from))
+	for i := range from {
+		peers[i] = v1alpha1.AdminNetworkPolicyEgressPeer{Namespaces: from[i].Namespaces, Pods: from[i].Pods}
+	}
+	return peers
+}
@@ -0,0 +1,270 @@
+package translation
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+func TestTranslateAdminNetworkPolicy(t *testing.T) {
+	sensitiveNS := &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "sensitive"}}
+	monitoringNS := metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "monitoring"}}
+	webPods := metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	tests := []struct {
+		name        string
+		anp         *v1alpha1.AdminNetworkPolicy
+		npmNetPol   *policies.NPMNetworkPolicy
+		wantErr     error
+		skipWindows bool
+	}{
+		{
+			name: "namespace subject with pods peer and port",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "allow-monitoring"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Priority: 10,
+					Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: sensitiveNS},
+					Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+							From: []v1alpha1.AdminNetworkPolicyIngressPeer{
+								{Pods: &v1alpha1.NamespacedPod{NamespaceSelector: monitoringNS, PodSelector: webPods}},
+							},
+							Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+								{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolTCP, Port: 9090}},
+							},
+						},
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+							From: []v1alpha1.AdminNetworkPolicyIngressPeer{
+								{Namespaces: &metav1.LabelSelector{}},
+							},
+						},
+					},
+				},
+			},
+			npmNetPol: &policies.NPMNetworkPolicy{
+				PolicyKey: "AdminNetworkPolicy/allow-monitoring",
+				Tier:      policies.AdminTier,
+				Priority:  10,
+				PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("kubernetes.io/metadata.name:sensitive", ipsets.KeyValueLabelOfNamespace),
+				},
+				ChildPodSelectorIPSets: []*ipsets.TranslatedIPSet{},
+				PodSelectorList: []policies.SetInfo{
+					policies.NewSetInfo("kubernetes.io/metadata.name:sensitive", ipsets.KeyValueLabelOfNamespace, included, policies.EitherMatch),
+				},
+				RuleIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("app:web", ipsets.KeyValueLabelOfPod),
+					ipsets.NewTranslatedIPSet("kubernetes.io/metadata.name:monitoring", ipsets.KeyValueLabelOfNamespace),
+					ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+				},
+				ACLs: []*policies.ACLPolicy{
+					{
+						Target:    policies.Allowed,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo("kubernetes.io/metadata.name:monitoring", ipsets.KeyValueLabelOfNamespace, included, policies.SrcMatch),
+							policies.NewSetInfo("app:web", ipsets.KeyValueLabelOfPod, included, policies.SrcMatch),
+						},
+						DstPorts: policies.Ports{Port: 9090},
+						Protocol: "TCP",
+					},
+					{
+						Target:    policies.Dropped,
+						Direction: policies.Ingress,
+						SrcList: []policies.SetInfo{
+							policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.SrcMatch),
+						},
+						RuleIndex: 1,
+					},
+				},
+			},
+		},
+		{
+			name: "pods subject with pass to networks and port range",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "pass-egress"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Priority: 20,
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Pods: &v1alpha1.NamespacedPod{NamespaceSelector: monitoringNS, PodSelector: webPods},
+					},
+					Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
+							To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+								{Networks: []v1alpha1.CIDR{"0.0.0.0/0", "10.0.0.0/8"}},
+							},
+							Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+								{PortRange: &v1alpha1.PortRange{Protocol: v1.ProtocolUDP, Start: 1000, End: 2000}},
+							},
+						},
+					},
+				},
+			},
+			npmNetPol: &policies.NPMNetworkPolicy{
+				PolicyKey: "AdminNetworkPolicy/pass-egress",
+				Tier:      policies.AdminTier,
+				Priority:  20,
+				PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("app:web", ipsets.KeyValueLabelOfPod),
+					ipsets.NewTranslatedIPSet("kubernetes.io/metadata.name:monitoring", ipsets.KeyValueLabelOfNamespace),
+				},
+				ChildPodSelectorIPSets: []*ipsets.TranslatedIPSet{},
+				PodSelectorList: []policies.SetInfo{
+					policies.NewSetInfo("app:web", ipsets.KeyValueLabelOfPod, included, policies.EitherMatch),
+					policies.NewSetInfo("kubernetes.io/metadata.name:monitoring", ipsets.KeyValueLabelOfNamespace, included, policies.EitherMatch),
+				},
+				RuleIPSets: []*ipsets.TranslatedIPSet{
+					ipsets.NewTranslatedIPSet("anp-pass-egress-0-0OUT", ipsets.CIDRBlocks, "0.0.0.0/1", "128.0.0.0/1", "10.0.0.0/8"),
+				},
+				ACLs: []*policies.ACLPolicy{
+					{
+						Target:    policies.Passed,
+						Direction: policies.Egress,
+						DstList: []policies.SetInfo{
+							policies.NewSetInfo("anp-pass-egress-0-0OUT", ipsets.CIDRBlocks, included, policies.DstMatch),
+						},
+						DstPorts: policies.Ports{Port: 1000, EndPort: 2000},
+						Protocol: "UDP",
+					},
+				},
+			},
+			skipWindows: true,
+		},
+		{
+			name: "nodes peer",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "deny-nodes"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: sensitiveNS},
+					Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+						{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+							To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Nodes: &metav1.LabelSelector{}}},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedNodePeer,
+		},
+		{
+			name: "multi-value subject",
+			anp: &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "multi-value"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Subject: v1alpha1.AdminNetworkPolicySubject{
+						Namespaces: &metav1.LabelSelector{
+							MatchExpressions: []metav1.LabelSelectorRequirement{
+								{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: ErrUnsupportedSubject,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			npmNetPol, err := TranslateAdminNetworkPolicy(tt.anp)
+			if tt.skipWindows && util.IsWindowsDP() {
+				require.Error(t, err)
+				return
+			}
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.npmNetPol.ACLPolicyID = policies.NewAdminNPMNetworkPolicy(tt.anp.Name, tt.anp.Spec.Priority).ACLPolicyID
+			require.Equal(t, tt.npmNetPol, npmNetPol)
+		})
+	}
+}
+
+func TestTranslateAdminNetworkPolicyWindowsPriority(t *testing.T) {
+	anp := &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "low-priority"},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: policies.MaxWindowsAdminPriority + 1,
+			Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+		},
+	}
+	_, err := TranslateAdminNetworkPolicy(anp)
+	if util.IsWindowsDP() {
+		require.ErrorIs(t, err, ErrUnsupportedAdminPriority)
+		return
+	}
+	require.NoError(t, err)
+}
+
+func TestTranslateBaselineAdminNetworkPolicy(t *testing.T) {
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow,
+					To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+						{Networks: []v1alpha1.CIDR{"168.63.129.16/32"}},
+					},
+				},
+				{
+					Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+						{Namespaces: &metav1.LabelSelector{}},
+					},
+				},
+			},
+		},
+	}
+
+	want := &policies.NPMNetworkPolicy{
+		PolicyKey:   "BaselineAdminNetworkPolicy/default",
+		ACLPolicyID: policies.NewBaselineAdminNPMNetworkPolicy("default").ACLPolicyID,
+		Tier:        policies.BaselineTier,
+		PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		ChildPodSelectorIPSets: []*ipsets.TranslatedIPSet{},
+		PodSelectorList: []policies.SetInfo{
+			policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.EitherMatch),
+		},
+		RuleIPSets: []*ipsets.TranslatedIPSet{
+			ipsets.NewTranslatedIPSet("banp-default-0-0OUT", ipsets.CIDRBlocks, "168.63.129.16/32"),
+			ipsets.NewTranslatedIPSet(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace),
+		},
+		ACLs: []*policies.ACLPolicy{
+			{
+				Target:    policies.Allowed,
+				Direction: policies.Egress,
+				DstList: []policies.SetInfo{
+					policies.NewSetInfo("banp-default-0-0OUT", ipsets.CIDRBlocks, included, policies.DstMatch),
+				},
+			},
+			{
+				Target:    policies.Dropped,
+				Direction: policies.Egress,
+				DstList: []policies.SetInfo{
+					policies.NewSetInfo(util.KubeAllNamespacesFlag, ipsets.KeyLabelOfNamespace, included, policies.DstMatch),
+				},
+				RuleIndex: 1,
+			},
+		},
+	}
+
+	npmNetPol, err := TranslateBaselineAdminNetworkPolicy(banp)
+	require.NoError(t, err)
+	require.Equal(t, want, npmNetPol)
+}
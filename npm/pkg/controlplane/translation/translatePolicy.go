@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/fqdn"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -28,11 +29,13 @@ var (
 	ErrUnsupportedExceptCIDR = errors.New("unsupported Except CIDR block translation features used on windows")
 	// ErrUnsupportedSCTP is returned when SCTP protocol is used in windows.
 	ErrUnsupportedSCTP = errors.New("unsupported SCTP protocol used on windows")
+	// ErrUnsupportedFQDN is returned when the FQDN egress annotation is used in windows.
+	ErrUnsupportedFQDN = errors.New("unsupported FQDN egress rules used on windows")
 	// ErrInvalidMatchExpressionValues ensures proper matchExpression label values since k8s doesn't perform this check.
 	ErrInvalidMatchExpressionValues = errors.New(
 		"matchExpression label values must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character",
 	)
-	// ErrUnsupportedIPAddress is returned when an unsupported IP address, such as IPV6, is used
+	// ErrUnsupportedIPAddress is returned when an unsupported IP address, such as IPV6 in Windows, is used
 	ErrUnsupportedIPAddress = errors.New("unsupported IP address")
 	// ErrUnsupportedNonCIDR is returned when non-CIDR blocks are passed in with NPM Lite enabled. NPM Lite allows deny-all and allow-all policies
 	ErrUnsupportedNonCIDR = errors.New("Non-CIDR blocks, named ports, and ingress/egress namespace/pod selectors are not supported when NPM Lite is enabled, allowing only CIDR-based policies")
@@ -51,6 +54,7 @@ const (
 	namedPortType        netpolPortType = "namedport"
 	included             bool           = true
 	ipBlocksetNameFormat                = "%s-in-ns-%s-%d-%d%s"
+	fqdnSetNameFormat                   = "%s-in-ns-%s-fqdn"
 )
 
 // portType returns type of ports (e.g., numeric port or namedPort) given NetworkPolicyPort object.
@@ -146,6 +150,17 @@ func ipBlockSetName(policyName, ns string, direction policies.Direction, ipBlock
 	return fmt.Sprintf(ipBlocksetNameFormat, policyName, ns, ipBlockSetIndex, ipBlockPeerIndex, direction)
 }
 
+// allIPsSplitCIDRs maps the CIDRs which match all IPs of a family to their halves.
+var allIPsSplitCIDRs = map[string][]string{
+	"0.0.0.0/0": {"0.0.0.0/1", "128.0.0.0/1"},
+	"::/0":      {"::/1", "8000::/1"},
+}
+
+// isSupportedCIDR returns true for IPv4 CIDRs, and for IPv6 CIDRs in Linux.
+func isSupportedCIDR(cidr string) bool {
+	return util.IsIPV4(cidr) || (!util.IsWindowsDP() && util.IsIPV6(cidr))
+}
+
 // exceptCidr returns "cidr + " " (space) + nomatch" format.
 // e.g., "10.0.0.0/1 nomatch"
 func exceptCidr(exceptCidr string) string {
@@ -181,17 +196,16 @@ func ipBlockIPSet(policyName, ns string, direction policies.Direction, ipBlockSe
 
 	var members []string
 	indexOfMembers := 0
-	// Ipset doesn't allow 0.0.0.0/0 to be added.
+	// Ipset doesn't allow 0.0.0.0/0 (or ::/0) to be added.
 	// A solution is split 0.0.0.0/0 in half which convert to 0.0.0.0/1 and 128.0.0.0/1.
 	// splitCIDRSet is used to handle case where IPBlock has "0.0.0.0/0" in CIDR and "0.0.0.0/1" or "128.0.0.0/1"  in Except.
 	// splitCIDRSet has two entries ("0.0.0.0/1" and "128.0.0.0/1") as key.
 	splitCIDRLen := 2
 	splitCIDRSet := make(map[string]int, splitCIDRLen)
-	if ipBlockRule.CIDR == "0.0.0.0/0" {
+	if splitCIDRs, ok := allIPsSplitCIDRs[ipBlockRule.CIDR]; ok {
 		// two cidrs (0.0.0.0/1 and 128.0.0.0/1) for 0.0.0.0/0 + except.
 		members = make([]string, lenOfDeDupExcepts+splitCIDRLen)
 		// in case of "0.0.0.0/0", "0.0.0.0/1" or "0.0.0.0/1 nomatch" comes eariler than "128.0.0.0/1" or "128.0.0.0/1 nomatch".
-		splitCIDRs := []string{"0.0.0.0/1", "128.0.0.0/1"}
 		for _, cidr := range splitCIDRs {
 			members[indexOfMembers] = cidr
 			splitCIDRSet[cidr] = indexOfMembers
@@ -230,7 +244,7 @@ func ipBlockRule(policyName, ns string, direction policies.Direction, matchType
 		return nil, policies.SetInfo{}, nil
 	}
 
-	if !util.IsIPV4(ipBlockRule.CIDR) {
+	if !isSupportedCIDR(ipBlockRule.CIDR) {
 		return nil, policies.SetInfo{}, ErrUnsupportedIPAddress
 	}
 
@@ -564,9 +578,40 @@ func isAllowAllToEgress(egress []networkingv1.NetworkPolicyEgressRule) bool {
 	return false
 }
 
+// fqdnNames returns the names in the FQDN egress annotation of the networkpolicy object, or nil if it has none.
+func fqdnNames(npObj *networkingv1.NetworkPolicy) ([]string, error) {
+	annotation, ok := npObj.Annotations[util.NetworkPolicyFQDNEgressAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	if util.IsWindowsDP() {
+		return nil, ErrUnsupportedFQDN
+	}
+	names, err := fqdn.ParseNames(annotation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s annotation: %w", util.NetworkPolicyFQDNEgressAnnotation, err)
+	}
+	return names, nil
+}
+
+// fqdnRule allows egress to an ipset which the DNS snooper fills with the IPs that the FQDNs resolve to.
+func fqdnRule(npmNetPol *policies.NPMNetworkPolicy, netPolName string, fqdns []string) {
+	if len(fqdns) == 0 {
+		return
+	}
+	fqdnIPSet := ipsets.NewTranslatedIPSet(fmt.Sprintf(fqdnSetNameFormat, netPolName, npmNetPol.Namespace), ipsets.CIDRBlocks)
+	npmNetPol.RuleIPSets = append(npmNetPol.RuleIPSets, fqdnIPSet)
+	npmNetPol.FQDNs = fqdns
+	npmNetPol.FQDNIPSet = fqdnIPSet.Metadata
+
+	acl := policies.NewACLPolicy(policies.Allowed, policies.Egress)
+	acl.AddSetInfo([]policies.SetInfo{policies.NewSetInfo(fqdnIPSet.Metadata.Name, ipsets.CIDRBlocks, included, policies.DstMatch)})
+	npmNetPol.ACLs = append(npmNetPol.ACLs, acl)
+}
+
 // egressPolicy traslates NetworkPolicyEgressRule in networkpolicy object
-// to NPMNetworkPolicy object.
-func egressPolicy(npmNetPol *policies.NPMNetworkPolicy, netPolName string, egress []networkingv1.NetworkPolicyEgressRule, npmLiteToggle bool) error {
+// to NPMNetworkPolicy object. fqdns are allowed in addition to the egress rules.
+func egressPolicy(npmNetPol *policies.NPMNetworkPolicy, netPolName string, egress []networkingv1.NetworkPolicyEgressRule, fqdns []string, npmLiteToggle bool) error {
 	// #1. Allow all traffic to both internal and external.
 	// In yaml file, it is specified with '{}'.
 	if isAllowAllToEgress(egress) {
@@ -576,6 +621,7 @@ func egressPolicy(npmNetPol *policies.NPMNetworkPolicy, netPolName string, egres
 
 	// #2. If egress is nil (in yaml file, it is specified with '[]'), it means "Deny all" - it does not allow sending traffic to others.
 	if egress == nil {
+		fqdnRule(npmNetPol, netPolName, fqdns)
 		// Except for allow all traffic case in #1, the rest of them should have default drop rules.
 		dropACL := defaultDropACL(policies.Egress)
 		npmNetPol.ACLs = append(npmNetPol.ACLs, dropACL)
@@ -590,6 +636,7 @@ func egressPolicy(npmNetPol *policies.NPMNetworkPolicy, netPolName string, egres
 			return err
 		}
 	}
+	fqdnRule(npmNetPol, netPolName, fqdns)
 
 	// #3. Except for allow all traffic case in #1, the rest of them should have default drop rules.
 	// Add drop ACL to drop the rest of traffic which is not specified in Egress Spec.
@@ -614,6 +661,11 @@ func TranslatePolicy(npObj *networkingv1.NetworkPolicy, npmLiteToggle bool) (*po
 	npmNetPol.ChildPodSelectorIPSets = psResult.childPSSets
 	npmNetPol.PodSelectorList = psResult.psList
 
+	fqdns, err := fqdnNames(npObj)
+	if err != nil {
+		return nil, err
+	}
+
 	// Each NetworkPolicy includes a policyTypes list which may include either Ingress, Egress, or both.
 	// If no policyTypes are specified on a NetworkPolicy then by default Ingress will always be set
 	// and Egress will be set if the NetworkPolicy has any egress rules.
@@ -624,7 +676,7 @@ func TranslatePolicy(npObj *networkingv1.NetworkPolicy, npmLiteToggle bool) (*po
 				return nil, err
 			}
 		} else {
-			err := egressPolicy(npmNetPol, netPolName, npObj.Spec.Egress, npmLiteToggle)
+			err := egressPolicy(npmNetPol, netPolName, npObj.Spec.Egress, fqdns, npmLiteToggle)
 			if err != nil {
 				return nil, err
 			}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/fqdn"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -542,11 +543,32 @@ func TestIPBlockRule(t *testing.T) {
 			skipWindows:     true,
 		},
 		{
-			name:        "invalid ipv6",
+			name:        "ipv6",
 			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
 			ipBlockRule: &networkingv1.IPBlock{
 				CIDR: "2002::1234:abcd:ffff:c0a8:101/64",
 			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"2002::1234:abcd:ffff:c0a8:101/64"}...),
+			setInfo:         policies.NewSetInfo("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, included, policies.SrcMatch),
+			skipWindows:     true,
+		},
+		{
+			name:        "ipv6 ::/0 and except",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR:   "::/0",
+				Except: []string{"8000::/1", "fd00::/8"},
+			},
+			translatedIPSet: ipsets.NewTranslatedIPSet("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, []string{"::/1", "8000::/1 nomatch", "fd00::/8 nomatch"}...),
+			setInfo:         policies.NewSetInfo("test-in-ns-default-0-0IN", ipsets.CIDRBlocks, included, policies.SrcMatch),
+			skipWindows:     true,
+		},
+		{
+			name:        "invalid ipv6 cidr",
+			ipBlockInfo: createIPBlockInfo("test", defaultNS, policies.Ingress, policies.SrcMatch, 0, 0),
+			ipBlockRule: &networkingv1.IPBlock{
+				CIDR: "2002::1/129",
+			},
 			translatedIPSet: nil,
 			setInfo:         policies.SetInfo{},
 			wantErr:         true,
@@ -2910,7 +2932,7 @@ func TestEgressPolicy(t *testing.T) {
 			npmNetPol.PodSelectorList = psResult.psList
 			splitPolicyKey := strings.Split(npmNetPol.PolicyKey, "/")
 			require.Len(t, splitPolicyKey, 2, "policy key must include name")
-			err = egressPolicy(npmNetPol, splitPolicyKey[1], tt.rules, false)
+			err = egressPolicy(npmNetPol, splitPolicyKey[1], tt.rules, nil, false)
 			if tt.wantErr || (tt.skipWindows && util.IsWindowsDP()) {
 				require.Error(t, err)
 			} else {
@@ -3137,3 +3159,59 @@ func TestCheckForNamedPortType(t *testing.T) {
 		})
 	}
 }
+
+func TestFQDNEgress(t *testing.T) {
+	port53 := intstr.FromInt(53)
+	udp := v1.ProtocolUDP
+	netPol := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "allow-blob",
+			Namespace: "default",
+			Annotations: map[string]string{
+				util.NetworkPolicyFQDNEgressAnnotation: "*.blob.core.windows.net,Login.MicrosoftOnline.com",
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &port53}}},
+			},
+		},
+	}
+
+	npmNetPol, err := TranslatePolicy(netPol, false)
+	if util.IsWindowsDP() {
+		require.ErrorIs(t, err, ErrUnsupportedFQDN)
+		return
+	}
+	require.NoError(t, err)
+
+	fqdnSet := ipsets.NewTranslatedIPSet("allow-blob-in-ns-default-fqdn", ipsets.CIDRBlocks)
+	require.Equal(t, []string{"*.blob.core.windows.net", "login.microsoftonline.com"}, npmNetPol.FQDNs)
+	require.Equal(t, fqdnSet.Metadata, npmNetPol.FQDNIPSet)
+	require.Contains(t, npmNetPol.RuleIPSets, fqdnSet)
+
+	// the FQDN ACL is after the rules and before the default drop
+	require.Len(t, npmNetPol.ACLs, 3)
+	fqdnACL := policies.NewACLPolicy(policies.Allowed, policies.Egress)
+	fqdnACL.AddSetInfo([]policies.SetInfo{policies.NewSetInfo("allow-blob-in-ns-default-fqdn", ipsets.CIDRBlocks, included, policies.DstMatch)})
+	require.Equal(t, fqdnACL, npmNetPol.ACLs[1])
+	require.Equal(t, defaultDropACL(policies.Egress), npmNetPol.ACLs[2])
+
+	// FQDNs are allowed when all other egress is denied
+	netPol.Spec.Egress = nil
+	npmNetPol, err = TranslatePolicy(netPol, false)
+	require.NoError(t, err)
+	require.Equal(t, []*policies.ACLPolicy{fqdnACL, defaultDropACL(policies.Egress)}, npmNetPol.ACLs)
+
+	// FQDNs don't apply to ingress-only policies
+	netPol.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	npmNetPol, err = TranslatePolicy(netPol, false)
+	require.NoError(t, err)
+	require.Empty(t, npmNetPol.FQDNs)
+	require.Nil(t, npmNetPol.FQDNIPSet)
+
+	netPol.Annotations[util.NetworkPolicyFQDNEgressAnnotation] = "https://example.com"
+	_, err = TranslatePolicy(netPol, false)
+	require.ErrorIs(t, err, fqdn.ErrInvalidName)
+}
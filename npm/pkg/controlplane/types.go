@@ -6,12 +6,15 @@ import (
 )
 
 const (
-	IpsetApply      string = "IPSETAPPLY"
-	IpsetRemove     string = "IPSETREMOVE"
-	PolicyApply     string = "POLICYAPPLY"
-	PolicyRemove    string = "POLICYREMOVE"
-	ListReference   string = "LISTREFERENCE"
-	PolicyReference string = "POLICYREFERENCE"
+	IpsetApply   string = "IPSETAPPLY"
+	IpsetRemove  string = "IPSETREMOVE"
+	PolicyApply  string = "POLICYAPPLY"
+	PolicyRemove string = "POLICYREMOVE"
+	// NodeScopedIPSets lists the sets in IpsetApply which daemons only need the members of their node for.
+	// The controller removes it when filtering the sets for a daemon.
+	NodeScopedIPSets string = "NODESCOPEDIPSETS"
+	ListReference    string = "LISTREFERENCE"
+	PolicyReference  string = "POLICYREFERENCE"
 )
 
 // ControllerIPSets is used in fan-out design for controller pod to calculate
@@ -298,7 +298,7 @@ func basicTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -309,7 +309,7 @@ func basicTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -410,7 +410,7 @@ func basicTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -421,7 +421,7 @@ func basicTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -599,7 +599,7 @@ func basicTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -610,7 +610,7 @@ func basicTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -773,7 +773,7 @@ func capzCalicoTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -784,7 +784,7 @@ func capzCalicoTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -920,7 +920,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -931,7 +931,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -988,7 +988,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -999,7 +999,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1055,7 +1055,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1066,7 +1066,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1121,7 +1121,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1132,7 +1132,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1191,7 +1191,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1202,7 +1202,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1299,7 +1299,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1310,7 +1310,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1367,7 +1367,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1378,7 +1378,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1434,7 +1434,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1445,7 +1445,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1501,7 +1501,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1512,7 +1512,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1688,7 +1688,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1699,7 +1699,7 @@ func updatePodTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1805,7 +1805,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1816,7 +1816,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1946,7 +1946,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -1957,7 +1957,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -2019,7 +2019,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -2030,7 +2030,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
@@ -2048,7 +2048,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base3",
@@ -2059,7 +2059,7 @@ func podAssignmentSequence3Tests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base3",
@@ -2192,7 +2192,7 @@ func remoteEndpointTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -2203,7 +2203,7 @@ func remoteEndpointTests() []*SerialTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -2273,7 +2273,7 @@ func getAllMultiJobTests() []*MultiJobTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -2284,7 +2284,7 @@ func getAllMultiJobTests() []*MultiJobTestCase {
 							RemoteAddresses: "",
 							LocalPorts:      "",
 							RemotePorts:     "",
-							Priority:        222,
+							Priority:        20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -2440,13 +2440,13 @@ func applyInBackgroundBootupPhaseTests() []*SerialTestCase {
 							ID:        "azure-acl-x-base",
 							Action:    "Allow",
 							Direction: "In",
-							Priority:  222,
+							Priority:  20000,
 						},
 						{
 							ID:        "azure-acl-x-base",
 							Action:    "Allow",
 							Direction: "Out",
-							Priority:  222,
+							Priority:  20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -2491,13 +2491,13 @@ func applyInBackgroundBootupPhaseTests() []*SerialTestCase {
 							ID:        "azure-acl-x-base",
 							Action:    "Allow",
 							Direction: "In",
-							Priority:  222,
+							Priority:  20000,
 						},
 						{
 							ID:        "azure-acl-x-base",
 							Action:    "Allow",
 							Direction: "Out",
-							Priority:  222,
+							Priority:  20000,
 						},
 						{
 							ID:              "azure-acl-x-base",
@@ -2510,13 +2510,13 @@ func applyInBackgroundBootupPhaseTests() []*SerialTestCase {
 							ID:        "azure-acl-x-base2",
 							Action:    "Allow",
 							Direction: "In",
-							Priority:  222,
+							Priority:  20000,
 						},
 						{
 							ID:        "azure-acl-x-base2",
 							Action:    "Allow",
 							Direction: "Out",
-							Priority:  222,
+							Priority:  20000,
 						},
 						{
 							ID:              "azure-acl-x-base2",
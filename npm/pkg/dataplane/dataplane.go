@@ -9,6 +9,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/fqdn"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -46,6 +47,9 @@ type Config struct {
 	MaxPendingNetPols  int
 	NetPolInterval     time.Duration
 	EnableNPMLite      bool
+	// DriftCheckInterval is how often the ipsets and policies in the kernel are verified against the cache.
+	// Drifted ipsets and policies are repaired. Drift detection is disabled if the interval is zero.
+	DriftCheckInterval time.Duration
 	*ipsets.IPSetManagerCfg
 	*policies.PolicyManagerCfg
 }
@@ -75,7 +79,9 @@ type DataPlane struct {
 	// removePolicyInfo tracks when a policy was removed yet had ApplyIPSet failures.
 	// This field is only relevant for Linux.
 	removePolicyInfo removePolicyInfo
-	stopChannel      <-chan struct{}
+	// fqdnTracker fills the FQDN ipsets of policies. It is nil unless DNS snooping is enabled in Linux.
+	fqdnTracker *fqdn.Tracker
+	stopChannel <-chan struct{}
 }
 
 func NewDataPlane(nodeName string, ioShim *common.IOShim, cfg *Config, stopChannel <-chan struct{}) (*DataPlane, error) {
@@ -103,6 +109,10 @@ func NewDataPlane(nodeName string, ioShim *common.IOShim, cfg *Config, stopChann
 		stopChannel: stopChannel,
 	}
 
+	if cfg.PolicyManagerCfg.SnoopDNS && !util.IsWindowsDP() {
+		dp.fqdnTracker = fqdn.NewTracker(dp.ipsetMgr, dp.ApplyDataPlane, cfg.IPSetManagerCfg.EnableIPv6)
+	}
+
 	// do not let Linux apply in background
 	dp.applyInBackground = cfg.ApplyInBackground && util.IsWindowsDP()
 	if dp.applyInBackground {
@@ -178,6 +188,22 @@ func (dp *DataPlane) RunPeriodicTasks() {
 		}
 	}()
 
+	if dp.DriftCheckInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(dp.DriftCheckInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-dp.stopChannel:
+					return
+				case <-ticker.C:
+					dp.repairDrift()
+				}
+			}
+		}()
+	}
+
 	if dp.netPolInBackground {
 		go func() {
 			ticker := time.NewTicker(dp.NetPolInterval)
@@ -534,6 +560,15 @@ func (dp *DataPlane) addPolicies(netPols []*policies.NPMNetworkPolicy) error {
 			return fmt.Errorf("[DataPlane] error while adding Rule IPSet references: %w", err)
 		}
 
+		// Fill the FQDN IPSet with the IPs already resolved for the FQDNs
+		if len(netPol.FQDNs) > 0 {
+			if dp.fqdnTracker != nil {
+				dp.fqdnTracker.AddPolicy(netPol)
+			} else {
+				klog.Warningf("[DataPlane] FQDN egress rules of policy %s won't allow traffic since DNS snooping is disabled", netPol.PolicyKey)
+			}
+		}
+
 		if inBootupPhase {
 			// This branch can only be taken in Windows.
 			// During bootup phase, the Pod controller will not be running.
@@ -606,6 +641,11 @@ func (dp *DataPlane) RemovePolicy(policyKey string) error {
 		dp.netPolQueue.delete(policyKey)
 	}
 
+	// Empty the FQDN IPSet before its references are removed so that it can be deleted
+	if dp.fqdnTracker != nil {
+		dp.fqdnTracker.RemovePolicy(policyKey)
+	}
+
 	// because policy Manager will remove from policy from cache
 	// keep a local copy to remove references for ipsets
 	policy, ok := dp.policyMgr.GetPolicy(policyKey)
@@ -700,6 +740,16 @@ func (dp *DataPlane) GetAllIPSets() map[string]string {
 	return dp.ipsetMgr.GetAllIPSets()
 }
 
+// FQDNTracker returns the Tracker of the FQDN IPSets, or nil if DNS snooping is disabled.
+func (dp *DataPlane) FQDNTracker() *fqdn.Tracker {
+	return dp.fqdnTracker
+}
+
+// PolicyKeyForHash returns the PolicyKey of the applied policy whose hash is in the NFLOG prefix of a denied flow.
+func (dp *DataPlane) PolicyKeyForHash(hash string) (string, bool) {
+	return dp.policyMgr.PolicyKeyForHash(hash)
+}
+
 // GetAllPolicies is deprecated and only used in the goalstateprocessor, which is deprecated
 func (dp *DataPlane) GetAllPolicies() []string {
 	return nil
@@ -726,7 +776,11 @@ func (dp *DataPlane) createIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, n
 		if setType == ipsets.CIDRBlocks {
 			// ipblock can have either cidr (CIDR in IPBlock) or "cidr + " " (space) + nomatch" (Except in IPBlock)
 			// (TODO) need to revise it for windows
+			// IPv6 CIDRs are only enforced when IPv6 is enabled
 			for _, ipblock := range set.Members {
+				if !dp.IPSetManagerCfg.EnableIPv6 && ipsets.IsIPv6Member(ipblock) {
+					continue
+				}
 				err := dp.ipsetMgr.AddToSets([]*ipsets.IPSetMetadata{set.Metadata}, ipblock, "")
 				if err != nil {
 					return npmerrors.Errorf(npmErrorString, false, fmt.Sprintf("[DataPlane] failed to AddToSet in addIPSetReferences with err: %s", err.Error()))
@@ -769,6 +823,9 @@ func (dp *DataPlane) deleteIPSetsAndReferences(sets []*ipsets.TranslatedIPSet, n
 			// ipblock can have either cidr (CIDR in IPBlock) or "cidr + " " (space) + nomatch" (Except in IPBlock)
 			// (TODO) need to revise it for windows
 			for _, ipblock := range set.Members {
+				if !dp.IPSetManagerCfg.EnableIPv6 && ipsets.IsIPv6Member(ipblock) {
+					continue
+				}
 				err := dp.ipsetMgr.RemoveFromSets([]*ipsets.IPSetMetadata{set.Metadata}, ipblock, "")
 				if err != nil {
 					return npmerrors.Errorf(npmErrorString, false, fmt.Sprintf("[DataPlane] failed to RemoveFromSet in deleteIPSetReferences with err: %s", err.Error()))
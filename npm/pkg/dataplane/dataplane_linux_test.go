@@ -190,3 +190,84 @@ func TestNetPolInBackgroundFailureToAddFirstTime(t *testing.T) {
 
 	require.Equal(t, 1, dp.netPolQueue.len(), "expected one netpol to still be in the queue after it fails when adding one at a time")
 }
+
+func TestFQDNPolicy(t *testing.T) {
+	metrics.ReinitializeAll()
+
+	fqdnSet := ipsets.NewTranslatedIPSet("testpolicy-in-ns-ns1-fqdn", ipsets.CIDRBlocks)
+	fqdnPolicy := testPolicyobj
+	fqdnPolicy.RuleIPSets = append([]*ipsets.TranslatedIPSet{fqdnSet}, testPolicyobj.RuleIPSets...)
+	fqdnPolicy.FQDNs = []string{"example.com"}
+	fqdnPolicy.FQDNIPSet = fqdnSet.Metadata
+
+	// the DNS snoop rule already exists
+	calls := append(policies.GetBootupTestCalls(), testutils.TestCmd{
+		Cmd: []string{"iptables-nft", "-w", "60", "-C", "FORWARD", "-p", "UDP", "--sport", "53", "-m", "conntrack", "--ctstate", "ESTABLISHED", "--ctdir", "REPLY", "--ctorigdst", "10.0.0.10", "-j", "NFLOG", "--nflog-group", "101", "--nflog-prefix", "NPM-DNS"},
+	})
+	calls = append(calls, ipsets.GetResetTestCalls()...)
+	calls = append(calls, getAddPolicyTestCallsForDP(&fqdnPolicy)...)
+	calls = append(calls, getRemovePolicyTestCallsForDP(&fqdnPolicy)...)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+
+	policyMgrCfg := *dpCfg.PolicyManagerCfg
+	policyMgrCfg.SnoopDNS = true
+	policyMgrCfg.DNSNFLogGroup = util.DefaultDNSNFLogGroup
+	policyMgrCfg.DNSServers = []string{"10.0.0.10"}
+	cfg := &Config{
+		IPSetManagerCfg:  dpCfg.IPSetManagerCfg,
+		PolicyManagerCfg: &policyMgrCfg,
+	}
+	stopCh := make(chan struct{}, 1)
+	dp, err := NewDataPlane("testnode", ioshim, cfg, stopCh)
+	require.NoError(t, err)
+	defer func() {
+		stopCh <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+	}()
+	require.NotNil(t, dp.FQDNTracker())
+
+	require.NoError(t, dp.AddPolicy(&fqdnPolicy))
+	require.NotNil(t, dp.ipsetMgr.GetIPSet(fqdnSet.Metadata.GetPrefixName()))
+
+	require.NoError(t, dp.RemovePolicy(fqdnPolicy.PolicyKey))
+	require.Nil(t, dp.ipsetMgr.GetIPSet(fqdnSet.Metadata.GetPrefixName()))
+}
+
+func TestRepairDrift(t *testing.T) {
+	metrics.ReinitializeAll()
+
+	calls := append(getBootupTestCalls(),
+		// no NPM ipsets in the kernel
+		testutils.TestCmd{Cmd: []string{"ipset", "save"}, PipedToCommand: true},
+		testutils.TestCmd{Cmd: []string{"grep", "azure-npm-"}, ExitCode: 1},
+		// another agent deleted the NPM chains but not the jump to AZURE-NPM
+		testutils.TestCmd{
+			Cmd:    []string{"iptables-nft-save", "-t", "filter"},
+			Stdout: "*filter\n:FORWARD ACCEPT [0:0]\n-A FORWARD -m conntrack --ctstate NEW -j AZURE-NPM\nCOMMIT\n",
+		},
+		testutils.TestCmd{Cmd: []string{"iptables-nft-restore", "-w", "60", "-T", "filter", "--noflush"}},
+	)
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+
+	stopCh := make(chan struct{}, 1)
+	dp, err := NewDataPlane("testnode", ioshim, dpCfg, stopCh)
+	require.NoError(t, err)
+	defer func() {
+		stopCh <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	dp.repairDrift()
+
+	count, err := metrics.TotalDataplaneDrift(metrics.ChainDrift)
+	require.NoError(t, err)
+	require.Equal(t, 5, count, "should have repaired the base chains")
+	count, err = metrics.TotalDataplaneDrift(metrics.IPSetDrift)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	count, err = metrics.TotalDataplaneDriftRepairFailures(metrics.PolicyDrift)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
@@ -37,4 +37,7 @@ const (
 	// stored file with json compatible form (i.e., can call json.Unmarshal)
 	npmCacheFileV1 = "../testdata/npmcachev1.json"
 	npmCacheFileV2 = "../testdata/npmcachev2.json"
+	// NetworkPolicy with the expected connectivity of the pods in simulatorPodsFile
+	netPolFile        = "../testdata/netpol.yaml"
+	simulatorPodsFile = "../testdata/simulator-pods.yaml"
 )
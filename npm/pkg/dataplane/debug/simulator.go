@@ -0,0 +1,535 @@
+package debug
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane/translation"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	ErrUnknownEndpoint   = errors.New("endpoint is neither a pod in the manifests nor an IP")
+	ErrInvalidProtocol   = errors.New("protocol must be TCP, UDP, or SCTP")
+	ErrInvalidPort       = errors.New("port must be between 1 and 65535")
+	ErrPolicyTranslation = errors.New("failed to translate network policy")
+)
+
+const (
+	defaultNamespace = "default"
+	maxPort          = 65535
+)
+
+// Simulator answers whether a flow between pods is allowed by NetworkPolicies without a cluster.
+// The NetworkPolicies are translated with the translation package like the NetworkPolicyController does,
+// and the NPMNetworkPolicies are evaluated the way the Linux dataplane evaluates them:
+// a flow is allowed in a direction if any matching ACL allows it, or if no matching ACL drops it.
+type Simulator struct {
+	namespaces map[string]*corev1.Namespace
+	// pods is keyed by <nsname>/<podname>. Workloads are simulated as one pod named after the workload.
+	pods     map[string]*corev1.Pod
+	policies []*simulatedPolicy
+}
+
+type simulatedPolicy struct {
+	*policies.NPMNetworkPolicy
+	// sets holds the TranslatedIPSets with members (nested labels and CIDRs) by prefixed name
+	sets map[string]*ipsets.TranslatedIPSet
+}
+
+// SimulationResult is the verdict for a flow, with the policies and ACLs behind it.
+type SimulationResult struct {
+	Src      string `json:"src"`
+	Dst      string `json:"dst"`
+	Protocol string `json:"protocol"`
+	Port     int32  `json:"port"`
+	Allowed  bool   `json:"allowed"`
+	// Egress is evaluated for the source pod and Ingress for the destination pod
+	Egress  *DirectionResult `json:"egress"`
+	Ingress *DirectionResult `json:"ingress"`
+}
+
+type DirectionResult struct {
+	Allowed bool `json:"allowed"`
+	// Policies are the keys of the policies which select the pod in this direction
+	Policies []string `json:"policies,omitempty"`
+	// Rules are the ACLs of those policies which match the flow
+	Rules []*MatchedRule `json:"rules,omitempty"`
+}
+
+type MatchedRule struct {
+	Policy string `json:"policy"`
+	Target string `json:"target"`
+	Rule   string `json:"rule"`
+}
+
+type simulatedEndpoint struct {
+	name string
+	ip   net.IP
+	// pod is nil for IPs outside the manifests
+	pod *corev1.Pod
+}
+
+type simulatedFlow struct {
+	src, dst *simulatedEndpoint
+	protocol policies.Protocol
+	port     int32
+}
+
+// NewSimulatorFromFiles reads the manifests in the files, or in the yaml and json files of directories.
+// Documents may contain multiple objects separated by "---" and v1 Lists.
+// Kinds other than Namespaces, Pods, workloads, and NetworkPolicies are ignored.
+func NewSimulatorFromFiles(paths []string) (*Simulator, error) {
+	var objs []runtime.Object
+	for _, path := range paths {
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			fileObjs, err := objectsFromFile(file)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, fileObjs...)
+		}
+	}
+	return NewSimulator(objs)
+}
+
+// NewSimulator translates the NetworkPolicies among the objects.
+// It returns an error if a NetworkPolicy can't be translated, since NPM wouldn't enforce it.
+func NewSimulator(objs []runtime.Object) (*Simulator, error) {
+	s := &Simulator{
+		namespaces: make(map[string]*corev1.Namespace),
+		pods:       make(map[string]*corev1.Pod),
+	}
+	var netPols []*networkingv1.NetworkPolicy
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.Namespace:
+			s.namespaces[o.Name] = o
+		case *corev1.Pod:
+			s.addPod(o.ObjectMeta.DeepCopy(), o.Spec, o.Status.PodIP)
+		case *appsv1.Deployment:
+			s.addWorkload(o.Namespace, o.Name, o.Spec.Template)
+		case *appsv1.StatefulSet:
+			s.addWorkload(o.Namespace, o.Name, o.Spec.Template)
+		case *appsv1.DaemonSet:
+			s.addWorkload(o.Namespace, o.Name, o.Spec.Template)
+		case *appsv1.ReplicaSet:
+			s.addWorkload(o.Namespace, o.Name, o.Spec.Template)
+		case *batchv1.Job:
+			s.addWorkload(o.Namespace, o.Name, o.Spec.Template)
+		case *networkingv1.NetworkPolicy:
+			netPols = append(netPols, o)
+		}
+	}
+
+	// the kubernetes.io/metadata.name label is set on all namespaces by the API server
+	for _, pod := range s.pods {
+		if _, ok := s.namespaces[pod.Namespace]; !ok {
+			s.namespaces[pod.Namespace] = &corev1.Namespace{}
+			s.namespaces[pod.Namespace].Name = pod.Namespace
+		}
+	}
+	for name, ns := range s.namespaces {
+		if ns.Labels == nil {
+			ns.Labels = make(map[string]string)
+		}
+		ns.Labels[corev1.LabelMetadataName] = name
+	}
+
+	for _, netPol := range netPols {
+		if netPol.Namespace == "" {
+			netPol.Namespace = defaultNamespace
+		}
+		npmNetPol, err := translation.TranslatePolicy(netPol, false)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s/%s: %w", ErrPolicyTranslation, netPol.Namespace, netPol.Name, err)
+		}
+		// like PolicyManager.AddPolicies
+		policies.NormalizePolicy(npmNetPol)
+		if err := policies.ValidatePolicy(npmNetPol); err != nil {
+			return nil, fmt.Errorf("%w %s/%s: %w", ErrPolicyTranslation, netPol.Namespace, netPol.Name, err)
+		}
+		s.policies = append(s.policies, newSimulatedPolicy(npmNetPol))
+	}
+	sort.Slice(s.policies, func(i, j int) bool {
+		return s.policies[i].PolicyKey < s.policies[j].PolicyKey
+	})
+	return s, nil
+}
+
+func newSimulatedPolicy(npmNetPol *policies.NPMNetworkPolicy) *simulatedPolicy {
+	p := &simulatedPolicy{
+		NPMNetworkPolicy: npmNetPol,
+		sets:             make(map[string]*ipsets.TranslatedIPSet),
+	}
+	for _, sets := range [][]*ipsets.TranslatedIPSet{npmNetPol.PodSelectorIPSets, npmNetPol.ChildPodSelectorIPSets, npmNetPol.RuleIPSets} {
+		for _, set := range sets {
+			p.sets[set.Metadata.GetPrefixName()] = set
+		}
+	}
+	return p
+}
+
+func (s *Simulator) addWorkload(namespace, name string, template corev1.PodTemplateSpec) {
+	meta := template.ObjectMeta.DeepCopy()
+	meta.Namespace = namespace
+	meta.Name = name
+	s.addPod(meta, template.Spec, "")
+}
+
+func (s *Simulator) addPod(meta *metav1.ObjectMeta, spec corev1.PodSpec, podIP string) {
+	if meta.Namespace == "" {
+		meta.Namespace = defaultNamespace
+	}
+	pod := &corev1.Pod{ObjectMeta: *meta, Spec: spec}
+	pod.Status.PodIP = podIP
+	s.pods[pod.Namespace+"/"+pod.Name] = pod
+}
+
+// Simulate evaluates a flow from src to dst on the protocol and port.
+// Endpoints are either <nsname>/<podname> of a pod in the manifests or an IP.
+// An IP outside the manifests isn't selected by any policy and only matches ipBlocks.
+func (s *Simulator) Simulate(src, dst, protocol string, port int32) (*SimulationResult, error) {
+	flowProtocol := policies.Protocol(strings.ToUpper(protocol))
+	if flowProtocol != policies.TCP && flowProtocol != policies.UDP && flowProtocol != policies.SCTP {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProtocol, protocol)
+	}
+	if port < 1 || port > maxPort {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPort, port)
+	}
+	srcEndpoint, err := s.endpoint(src)
+	if err != nil {
+		return nil, err
+	}
+	dstEndpoint, err := s.endpoint(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	flow := &simulatedFlow{src: srcEndpoint, dst: dstEndpoint, protocol: flowProtocol, port: port}
+	result := &SimulationResult{
+		Src:      srcEndpoint.name,
+		Dst:      dstEndpoint.name,
+		Protocol: string(flowProtocol),
+		Port:     port,
+		Egress:   s.evaluate(flow, policies.Egress),
+		Ingress:  s.evaluate(flow, policies.Ingress),
+	}
+	result.Allowed = result.Egress.Allowed && result.Ingress.Allowed
+	return result, nil
+}
+
+func (s *Simulator) endpoint(input string) (*simulatedEndpoint, error) {
+	if pod, ok := s.pods[input]; ok {
+		return &simulatedEndpoint{name: input, ip: net.ParseIP(pod.Status.PodIP), pod: pod}, nil
+	}
+	ip := net.ParseIP(input)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEndpoint, input)
+	}
+	for key, pod := range s.pods {
+		if pod.Status.PodIP != "" && ip.Equal(net.ParseIP(pod.Status.PodIP)) {
+			return &simulatedEndpoint{name: key, ip: ip, pod: pod}, nil
+		}
+	}
+	return &simulatedEndpoint{name: input, ip: ip}, nil
+}
+
+// evaluate decides on the flow for the source pod in the Egress direction or for the destination pod in the Ingress direction.
+func (s *Simulator) evaluate(flow *simulatedFlow, direction policies.Direction) *DirectionResult {
+	target := flow.dst
+	if direction == policies.Egress {
+		target = flow.src
+	}
+	result := &DirectionResult{}
+	var allowed, dropped bool
+	for _, policy := range s.policies {
+		if !s.selects(policy, target) {
+			continue
+		}
+		selected := false
+		for _, acl := range policy.ACLs {
+			if acl.Direction != direction && acl.Direction != policies.Both {
+				continue
+			}
+			selected = true
+			if !s.matches(policy, acl, flow) {
+				continue
+			}
+			result.Rules = append(result.Rules, &MatchedRule{Policy: policy.PolicyKey, Target: string(acl.Target), Rule: acl.PrettyString()})
+			switch acl.Target { //nolint:exhaustive // NetworkPolicies only have Allowed and Dropped ACLs
+			case policies.Allowed:
+				allowed = true
+			case policies.Dropped:
+				dropped = true
+			}
+		}
+		if selected {
+			result.Policies = append(result.Policies, policy.PolicyKey)
+		}
+	}
+	result.Allowed = allowed || !dropped
+	return result
+}
+
+// selects returns true if the pod is in all sets of the policy's pod selector.
+func (s *Simulator) selects(policy *simulatedPolicy, target *simulatedEndpoint) bool {
+	if target.pod == nil {
+		return false
+	}
+	for _, setInfo := range policy.PodSelectorList {
+		if s.isMember(policy, setInfo.IPSet, target, nil) != setInfo.Included {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Simulator) matches(policy *simulatedPolicy, acl *policies.ACLPolicy, flow *simulatedFlow) bool {
+	if acl.Protocol != policies.UnspecifiedProtocol && acl.Protocol != flow.protocol {
+		return false
+	}
+	if acl.DstPorts.Port != 0 && (flow.port < acl.DstPorts.Port || flow.port > acl.DstPorts.EndPort) {
+		return false
+	}
+	for _, setInfo := range append(append([]policies.SetInfo{}, acl.SrcList...), acl.DstList...) {
+		endpoint := flow.dst
+		if setInfo.MatchType == policies.SrcMatch {
+			endpoint = flow.src
+		}
+		if s.isMember(policy, setInfo.IPSet, endpoint, flow) != setInfo.Included {
+			return false
+		}
+	}
+	return true
+}
+
+// isMember returns true if the endpoint would be a member of the ipset in the dataplane.
+// The flow is only needed for named ports.
+func (s *Simulator) isMember(policy *simulatedPolicy, set *ipsets.IPSetMetadata, endpoint *simulatedEndpoint, flow *simulatedFlow) bool {
+	if set.Type == ipsets.CIDRBlocks {
+		translatedSet, ok := policy.sets[set.GetPrefixName()]
+		return ok && endpoint.ip != nil && cidrMember(translatedSet.Members, endpoint.ip)
+	}
+
+	pod := endpoint.pod
+	if pod == nil {
+		return false
+	}
+	nsLabels := s.namespaces[pod.Namespace].Labels
+	switch set.Type { //nolint:exhaustive // other sets have no members
+	case ipsets.Namespace:
+		return pod.Namespace == set.Name
+	case ipsets.KeyLabelOfNamespace:
+		_, ok := nsLabels[set.Name]
+		return ok || set.Name == util.KubeAllNamespacesFlag
+	case ipsets.KeyValueLabelOfNamespace:
+		return hasLabel(nsLabels, set.Name)
+	case ipsets.KeyLabelOfPod:
+		_, ok := pod.Labels[set.Name]
+		return ok
+	case ipsets.KeyValueLabelOfPod:
+		return hasLabel(pod.Labels, set.Name)
+	case ipsets.NestedLabelOfPod:
+		translatedSet, ok := policy.sets[set.GetPrefixName()]
+		if !ok {
+			return false
+		}
+		for _, member := range translatedSet.Members {
+			if hasLabel(pod.Labels, member) {
+				return true
+			}
+		}
+		return false
+	case ipsets.NamedPorts:
+		return flow != nil && hasNamedPort(pod, set.Name, flow.protocol, flow.port)
+	default:
+		return false
+	}
+}
+
+// hasLabel returns true if the labels have the "key:value" of an ipset name.
+func hasLabel(labels map[string]string, keyValue string) bool {
+	key, value, ok := strings.Cut(keyValue, util.IpsetLabelDelimter)
+	if !ok {
+		return false
+	}
+	labelValue, exists := labels[key]
+	return exists && labelValue == value
+}
+
+func hasNamedPort(pod *corev1.Pod, portName string, protocol policies.Protocol, port int32) bool {
+	for i := range pod.Spec.Containers {
+		for _, containerPort := range pod.Spec.Containers[i].Ports {
+			containerProtocol := containerPort.Protocol
+			if containerProtocol == "" {
+				containerProtocol = corev1.ProtocolTCP
+			}
+			if containerPort.Name == portName && containerPort.ContainerPort == port && string(containerProtocol) == string(protocol) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cidrMember returns true if the most specific CIDR containing the IP isn't a "nomatch" CIDR, like hash:net ipsets.
+func cidrMember(members []string, ip net.IP) bool {
+	longestPrefix := -1
+	member := false
+	for _, m := range members {
+		fields := strings.Fields(m)
+		if len(fields) == 0 {
+			continue
+		}
+		cidr := fields[0]
+		if !strings.Contains(cidr, "/") {
+			cidr += "/32"
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || !ipNet.Contains(ip) {
+			continue
+		}
+		if ones, _ := ipNet.Mask.Size(); ones > longestPrefix {
+			longestPrefix = ones
+			member = len(fields) == 1 || fields[1] != util.IpsetNomatch
+		}
+	}
+	return member
+}
+
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", path, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+func objectsFromFile(file string) ([]runtime.Object, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var objs []runtime.Object
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document in %s: %w", file, err)
+		}
+		docObjs, err := decodeObjects(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode document in %s: %w", file, err)
+		}
+		objs = append(objs, docObjs...)
+	}
+}
+
+func decodeObjects(doc []byte) ([]runtime.Object, error) {
+	jsonDoc, err := utilyaml.ToJSON(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert yaml to json: %w", err)
+	}
+	if len(bytes.TrimSpace(jsonDoc)) == 0 || string(bytes.TrimSpace(jsonDoc)) == "null" {
+		return nil, nil
+	}
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(jsonDoc, nil, nil)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) {
+			// e.g. custom resources, or other json files in a directory of manifests
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to decode object: %w", err)
+	}
+
+	list, ok := obj.(*corev1.List)
+	if !ok {
+		return []runtime.Object{obj}, nil
+	}
+	var objs []runtime.Object
+	for _, item := range list.Items {
+		itemObjs, err := decodeObjects(item.Raw)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, itemObjs...)
+	}
+	return objs, nil
+}
+
+// PrettyPrintSimulation prints the verdict of the flow and the rules which decided it.
+func PrettyPrintSimulation(result *SimulationResult) {
+	fmt.Printf("%s -> %s on %s/%d: %s\n", result.Src, result.Dst, result.Protocol, result.Port, verdict(result.Allowed))
+	prettyPrintDirection("Egress", result.Src, result.Egress)
+	prettyPrintDirection("Ingress", result.Dst, result.Ingress)
+}
+
+func prettyPrintDirection(direction, endpoint string, result *DirectionResult) {
+	fmt.Printf("\t%s of %s: %s\n", direction, endpoint, verdict(result.Allowed))
+	if len(result.Policies) == 0 {
+		fmt.Printf("\t\tnot selected by any policy\n")
+		return
+	}
+	fmt.Printf("\t\tpolicies: %s\n", strings.Join(result.Policies, ", "))
+	if len(result.Rules) == 0 {
+		fmt.Printf("\t\tno matching rules\n")
+	}
+	for _, rule := range result.Rules {
+		fmt.Printf("\t\t%s by %s:\n", rule.Target, rule.Policy)
+		for _, line := range strings.Split(rule.Rule, "\n") {
+			fmt.Printf("\t\t\t%s\n", line)
+		}
+	}
+}
+
+func verdict(allowed bool) string {
+	if allowed {
+		return "ALLOWED"
+	}
+	return "DENIED"
+}
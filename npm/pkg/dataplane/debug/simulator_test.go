@@ -0,0 +1,164 @@
+package debug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestSimulateTruthTable(t *testing.T) {
+	s, err := NewSimulatorFromFiles([]string{netPolFile, simulatorPodsFile})
+	require.NoError(t, err)
+
+	// the rows of the truth table in netpol.yaml: "." is allowed and "X" is denied,
+	// for TCP/80, TCP/81, UDP/80, and UDP/81 to x/a, x/b, ..., z/c
+	pods := []string{"x/a", "x/b", "x/c", "y/a", "y/b", "y/c", "z/a", "z/b", "z/c"}
+	truthTable := map[string][]string{
+		"x/a": {"....", "....", "....", "XXXX", "....", "....", "....", "....", "...."},
+		"x/b": {"....", "....", "....", ".XXX", "....", "....", "....", "....", "...."},
+		"x/c": {"....", "....", "....", ".XXX", "....", "....", "....", "....", "...."},
+		"y/a": {"XXXX", "XXXX", "XXXX", "XXXX", ".XXX", "XXXX", ".XXX", ".XXX", "XXXX"},
+		"y/b": {"....", "....", "....", ".XXX", "....", "....", "....", "....", "...."},
+		"y/c": {"....", "....", "....", ".XXX", "....", "....", "....", "....", "...."},
+		"z/a": {"....", "....", "....", "XXXX", "....", "....", "....", "....", "...."},
+		"z/b": {"....", "....", "....", "XXXX", "....", "....", "....", "....", "...."},
+		"z/c": {"....", "....", "....", "XXXX", "....", "....", "....", "....", "...."},
+	}
+	flows := []struct {
+		protocol string
+		port     int32
+	}{{"TCP", 80}, {"TCP", 81}, {"UDP", 80}, {"UDP", 81}}
+
+	for _, src := range pods {
+		for i, dst := range pods {
+			for j, flow := range flows {
+				result, err := s.Simulate(src, dst, flow.protocol, flow.port)
+				require.NoError(t, err)
+				want := truthTable[src][i][j] == '.'
+				require.Equal(t, want, result.Allowed, "%s -> %s on %s/%d", src, dst, flow.protocol, flow.port)
+			}
+		}
+	}
+}
+
+func TestSimulateMatchedRules(t *testing.T) {
+	s, err := NewSimulatorFromFiles([]string{netPolFile, simulatorPodsFile})
+	require.NoError(t, err)
+
+	// pods can be given by IP
+	result, err := s.Simulate("10.0.2.1", "10.0.2.2", "tcp", 80)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, "y/a", result.Src)
+	require.Equal(t, "y/b", result.Dst)
+	require.Equal(t, []string{"y/base"}, result.Egress.Policies)
+	// the allow rule wins over the policy's default drop rule
+	require.Len(t, result.Egress.Rules, 2)
+	require.Equal(t, "ALLOW", result.Egress.Rules[0].Target)
+	require.Equal(t, "DROP", result.Egress.Rules[1].Target)
+	require.Empty(t, result.Ingress.Policies)
+
+	result, err = s.Simulate("y/a", "y/b", "TCP", 81)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.False(t, result.Egress.Allowed)
+	require.True(t, result.Ingress.Allowed)
+	require.Len(t, result.Egress.Rules, 1)
+	require.Equal(t, "DROP", result.Egress.Rules[0].Target)
+
+	_, err = s.Simulate("y/unknown", "y/b", "TCP", 80)
+	require.ErrorIs(t, err, ErrUnknownEndpoint)
+	_, err = s.Simulate("y/a", "y/b", "ICMP", 80)
+	require.ErrorIs(t, err, ErrInvalidProtocol)
+	_, err = s.Simulate("y/a", "y/b", "TCP", 0)
+	require.ErrorIs(t, err, ErrInvalidPort)
+}
+
+func TestSimulateIPBlocksAndNamedPorts(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	httpPort := intstr.FromString("http")
+	objs := []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "web", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+						},
+					},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "client", Namespace: "other"},
+			Status:     corev1.PodStatus{PodIP: "10.1.0.5"},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-ingress", Namespace: "app"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{
+					{
+						From: []networkingv1.NetworkPolicyPeer{
+							{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.2.0.0/16"}}},
+						},
+						Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &httpPort}},
+					},
+				},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		},
+	}
+	s, err := NewSimulator(objs)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		src     string
+		port    int32
+		allowed bool
+	}{
+		{name: "pod in ipBlock on named port", src: "other/client", port: 8080, allowed: true},
+		{name: "external IP in ipBlock on named port", src: "10.3.0.1", port: 8080, allowed: true},
+		{name: "external IP in except", src: "10.2.0.1", port: 8080, allowed: false},
+		{name: "external IP outside ipBlock", src: "192.168.0.1", port: 8080, allowed: false},
+		{name: "port other than named port", src: "10.3.0.1", port: 80, allowed: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Simulate(tt.src, "app/web", "TCP", tt.port)
+			require.NoError(t, err)
+			require.Equal(t, tt.allowed, result.Allowed)
+			require.Equal(t, []string{"app/web-ingress"}, result.Ingress.Policies)
+		})
+	}
+}
+
+func TestNewSimulatorFromFilesErrors(t *testing.T) {
+	_, err := NewSimulatorFromFiles([]string{"non-existing-file"})
+	require.Error(t, err)
+
+	// translation errors fail the simulation since NPM wouldn't enforce the policy
+	_, err = NewSimulator([]runtime.Object{
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad", Namespace: "x"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"not a label value"}},
+					},
+				},
+			},
+		},
+	})
+	require.ErrorIs(t, err, ErrPolicyTranslation)
+}
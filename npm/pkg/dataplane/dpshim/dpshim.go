@@ -17,7 +17,11 @@ import (
 	"k8s.io/klog"
 )
 
-const cleanEmptySetsInHrs = 24
+const (
+	cleanEmptySetsInHrs = 24
+	// goalStateHistorySize is the number of GoalState events kept to resume daemons which reconnect.
+	goalStateHistorySize = 256
+)
 
 var ErrChannelUnset = errors.New("channel must be set")
 
@@ -30,7 +34,11 @@ type DPShim struct {
 	setCache    map[string]*controlplane.ControllerIPSets
 	policyCache map[string]*policies.NPMNetworkPolicy
 	dirtyCache  *dirtyCache
-	mu          *sync.Mutex
+	// generation is the generation of the last GoalState event.
+	generation uint64
+	// history has the last GoalState events in order of generation.
+	history []*protos.Events
+	mu      *sync.Mutex
 }
 
 func NewDPSim(stopChannel <-chan struct{}) (*DPShim, error) {
@@ -40,7 +48,10 @@ func NewDPSim(stopChannel <-chan struct{}) (*DPShim, error) {
 		policyCache: make(map[string]*policies.NPMNetworkPolicy),
 		stopChannel: stopChannel,
 		dirtyCache:  newDirtyCache(),
-		mu:          &sync.Mutex{},
+		// generations start from the current time so that a daemon can't resume
+		// from a generation of a previous controller.
+		generation: uint64(time.Now().UnixNano()),
+		mu:         &sync.Mutex{},
 	}, nil
 }
 
@@ -72,6 +83,18 @@ func (dp *DPShim) HydrateClients() (*protos.Events, error) {
 		goalStates[controlplane.IpsetApply] = toApplySets
 	}
 
+	cachedSets := make(map[string]struct{}, len(dp.setCache))
+	for setName := range dp.setCache {
+		cachedSets[setName] = struct{}{}
+	}
+	nodeScopedSets, err := dp.processNodeScopedSets(cachedSets)
+	if err != nil {
+		return nil, err
+	}
+	if nodeScopedSets != nil {
+		goalStates[controlplane.NodeScopedIPSets] = nodeScopedSets
+	}
+
 	toApplyPolicies, err := dp.hydratePolicyCache()
 	if err != nil {
 		return nil, err
@@ -86,11 +109,41 @@ func (dp *DPShim) HydrateClients() (*protos.Events, error) {
 	}
 
 	return &protos.Events{
-		EventType: protos.Events_Hydration,
-		Payload:   goalStates,
+		EventType:  protos.Events_Hydration,
+		Payload:    goalStates,
+		Generation: dp.generation,
 	}, nil
 }
 
+// Generation returns the generation of the last GoalState event.
+func (dp *DPShim) Generation() uint64 {
+	dp.lock()
+	defer dp.unlock()
+	return dp.generation
+}
+
+// GoalStatesSince returns the GoalState events after the given generation in order.
+// It returns false if some of the events are no longer in the history, in which case the daemon must be hydrated.
+func (dp *DPShim) GoalStatesSince(generation uint64) ([]*protos.Events, bool) {
+	dp.lock()
+	defer dp.unlock()
+
+	if generation > dp.generation {
+		return nil, false
+	}
+	if generation == dp.generation {
+		return nil, true
+	}
+	if len(dp.history) == 0 || dp.history[0].GetGeneration() > generation+1 {
+		return nil, false
+	}
+
+	idx := len(dp.history) - int(dp.generation-generation)
+	events := make([]*protos.Events, len(dp.history)-idx)
+	copy(events, dp.history[idx:])
+	return events, true
+}
+
 func (dp *DPShim) RunPeriodicTasks() {
 	// Here Run periodic task to check if any sets with empty references are present and delete them
 	dp.deleteUnusedSets(dp.stopChannel)
@@ -347,6 +400,7 @@ func (dp *DPShim) AddPolicy(networkpolicies *policies.NPMNetworkPolicy) error {
 	}
 	dp.policyCache[networkpolicies.PolicyKey] = networkpolicies
 	dp.dirtyCache.modifyAddorUpdatePolicies(networkpolicies.PolicyKey)
+	dp.modifyPolicySets(networkpolicies)
 
 	return err
 }
@@ -365,6 +419,9 @@ func (dp *DPShim) RemovePolicy(policyKey string) error {
 	dp.lock()
 	defer dp.unlock()
 	// keeping err different so we can catch the defer func err
+	if cachedPolicy, ok := dp.policyCache[policyKey]; ok {
+		dp.modifyPolicySets(cachedPolicy)
+	}
 	delete(dp.policyCache, policyKey)
 	dp.dirtyCache.modifyDeletePolicies(policyKey)
 
@@ -388,8 +445,12 @@ func (dp *DPShim) UpdatePolicy(networkpolicies *policies.NPMNetworkPolicy) error
 	// For simplicity, we will not be adding references of netpols to ipsets.
 	// DP in daemon will take care of tracking the references.
 
+	if cachedPolicy, ok := dp.policyCache[networkpolicies.PolicyKey]; ok {
+		dp.modifyPolicySets(cachedPolicy)
+	}
 	dp.policyCache[networkpolicies.PolicyKey] = networkpolicies
 	dp.dirtyCache.modifyAddorUpdatePolicies(networkpolicies.PolicyKey)
+	dp.modifyPolicySets(networkpolicies)
 
 	return err
 }
@@ -416,6 +477,14 @@ func (dp *DPShim) ApplyDataPlane() error {
 		goalStates[controlplane.IpsetApply] = toApplySets
 	}
 
+	nodeScopedSets, err := dp.processNodeScopedSets(dp.dirtyCache.toAddorUpdateSets)
+	if err != nil {
+		return err
+	}
+	if nodeScopedSets != nil {
+		goalStates[controlplane.NodeScopedIPSets] = nodeScopedSets
+	}
+
 	toDeleteSets, err := dp.processIPSetsDelete()
 	if err != nil {
 		return err
@@ -445,11 +514,20 @@ func (dp *DPShim) ApplyDataPlane() error {
 		return nil
 	}
 
+	dp.generation++
+	event := &protos.Events{
+		EventType:  protos.Events_GoalState,
+		Payload:    goalStates,
+		Generation: dp.generation,
+	}
+	dp.history = append(dp.history, event)
+	if len(dp.history) > goalStateHistorySize {
+		dp.history = dp.history[len(dp.history)-goalStateHistorySize:]
+	}
+
+	// the events may arrive out of order, so consumers should use GoalStatesSince to get the events in order
 	go func() {
-		dp.OutChannel <- &protos.Events{
-			EventType: protos.Events_GoalState,
-			Payload:   goalStates,
-		}
+		dp.OutChannel <- event
 	}()
 
 	dp.dirtyCache.clearCache()
@@ -2,6 +2,7 @@ package dpshim
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -225,3 +226,76 @@ func getPayload(t *testing.T, outChan chan *protos.Events, key string) *bytes.Bu
 		}
 	}
 }
+
+func TestGoalStatesSince(t *testing.T) {
+	dp, err := NewDPSim(nil)
+	require.NoError(t, err)
+	startGeneration := dp.Generation()
+
+	events, ok := dp.GoalStatesSince(startGeneration)
+	require.True(t, ok)
+	require.Empty(t, events)
+
+	for i := 0; i < goalStateHistorySize+1; i++ {
+		require.NoError(t, dp.AddToSets([]*ipsets.IPSetMetadata{testNSSet}, dataplane.NewPodMetadata("a", fmt.Sprintf("10.0.%d.%d", i/256, i%256), "")))
+		require.NoError(t, dp.ApplyDataPlane())
+	}
+	require.Equal(t, startGeneration+goalStateHistorySize+1, dp.Generation())
+
+	// the first event is no longer in the history
+	_, ok = dp.GoalStatesSince(startGeneration)
+	require.False(t, ok)
+	// generations of a previous controller can't be resumed
+	_, ok = dp.GoalStatesSince(dp.Generation() + 1)
+	require.False(t, ok)
+
+	events, ok = dp.GoalStatesSince(dp.Generation() - 2)
+	require.True(t, ok)
+	require.Len(t, events, 2)
+	require.Equal(t, dp.Generation()-1, events[0].GetGeneration())
+	require.Equal(t, dp.Generation(), events[1].GetGeneration())
+
+	hydration, err := dp.HydrateClients()
+	require.NoError(t, err)
+	require.Equal(t, dp.Generation(), hydration.GetGeneration())
+}
+
+func TestFilterGoalStateForNode(t *testing.T) {
+	dp, err := NewDPSim(nil)
+	require.NoError(t, err)
+
+	// setns1 only selects pods, and setns2 is also a peer in the rules
+	selectorSet := testPolicyobj.PodSelectorIPSets[0].Metadata
+	ruleSet := testPolicyobj.RuleIPSets[0].Metadata
+	for _, pod := range []*dataplane.PodMetadata{
+		dataplane.NewPodMetadata("a", "10.0.0.1", "node1"),
+		dataplane.NewPodMetadata("b", "10.0.0.2", "node2"),
+		dataplane.NewPodMetadata("c", "10.0.0.3", ""),
+	} {
+		require.NoError(t, dp.AddToSets([]*ipsets.IPSetMetadata{selectorSet, ruleSet}, pod))
+	}
+	require.NoError(t, dp.UpdatePolicy(testPolicyobj))
+
+	hydration, err := dp.HydrateClients()
+	require.NoError(t, err)
+	nodeScopedSets, err := controlplane.DecodeStrings(bytes.NewBuffer(hydration.GetPayload()[controlplane.NodeScopedIPSets].GetData()))
+	require.NoError(t, err)
+	require.Equal(t, []string{selectorSet.GetPrefixName()}, nodeScopedSets)
+
+	filtered, err := FilterGoalStateForNode(hydration, "node1")
+	require.NoError(t, err)
+	require.Equal(t, hydration.GetGeneration(), filtered.GetGeneration())
+	require.NotContains(t, filtered.GetPayload(), controlplane.NodeScopedIPSets)
+	require.Equal(t, hydration.GetPayload()[controlplane.PolicyApply], filtered.GetPayload()[controlplane.PolicyApply])
+
+	sets, err := controlplane.DecodeControllerIPSets(bytes.NewBuffer(filtered.GetPayload()[controlplane.IpsetApply].GetData()))
+	require.NoError(t, err)
+	members := make(map[string][]string)
+	for _, set := range sets {
+		for podIP := range set.IPPodMetadata {
+			members[set.GetPrefixName()] = append(members[set.GetPrefixName()], podIP)
+		}
+	}
+	require.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.3"}, members[selectorSet.GetPrefixName()])
+	require.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, members[ruleSet.GetPrefixName()])
+}
@@ -0,0 +1,162 @@
+package dpshim
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/controlplane"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/pkg/protos"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"k8s.io/klog"
+)
+
+// A hash set is node-scoped when policies only use it to select pods.
+// A daemon only needs the members of a node-scoped set which are on its node,
+// since a policy is only enforced for the pods it selects on the node.
+
+// modifyPolicySets marks the cached sets of the policy as dirty,
+// so that daemons get the sets again when the sets become node-scoped or stop being node-scoped.
+func (dp *DPShim) modifyPolicySets(networkPolicy *policies.NPMNetworkPolicy) {
+	for _, translatedSets := range [][]*ipsets.TranslatedIPSet{
+		networkPolicy.PodSelectorIPSets,
+		networkPolicy.ChildPodSelectorIPSets,
+		networkPolicy.RuleIPSets,
+	} {
+		for _, translatedSet := range translatedSets {
+			setName := translatedSet.Metadata.GetPrefixName()
+			if dp.setExists(setName) {
+				dp.dirtyCache.modifyAddorUpdateSets(setName)
+			}
+		}
+	}
+}
+
+// nodeScopedSets returns the cached hash sets which policies use to select pods and not in their rules.
+func (dp *DPShim) nodeScopedSets() map[string]struct{} {
+	selectorSets := make(map[string]struct{})
+	ruleSets := make(map[string]struct{})
+	for _, networkPolicy := range dp.policyCache {
+		for _, translatedSet := range networkPolicy.PodSelectorIPSets {
+			selectorSets[translatedSet.Metadata.GetPrefixName()] = struct{}{}
+		}
+		for _, translatedSet := range networkPolicy.ChildPodSelectorIPSets {
+			selectorSets[translatedSet.Metadata.GetPrefixName()] = struct{}{}
+		}
+		for _, translatedSet := range networkPolicy.RuleIPSets {
+			setName := translatedSet.Metadata.GetPrefixName()
+			ruleSets[setName] = struct{}{}
+			// members of lists in rules match peers on any node
+			if list, ok := dp.setCache[setName]; ok {
+				for memberName := range list.MemberIPSets {
+					ruleSets[memberName] = struct{}{}
+				}
+			}
+		}
+		if networkPolicy.FQDNIPSet != nil {
+			ruleSets[networkPolicy.FQDNIPSet.GetPrefixName()] = struct{}{}
+		}
+	}
+
+	nodeScopedSets := make(map[string]struct{})
+	for setName := range selectorSets {
+		if _, ok := ruleSets[setName]; ok {
+			continue
+		}
+		set, ok := dp.setCache[setName]
+		if !ok || set.GetSetKind() != ipsets.HashSet {
+			continue
+		}
+		nodeScopedSets[setName] = struct{}{}
+	}
+	return nodeScopedSets
+}
+
+// processNodeScopedSets encodes the names of the given sets which are node-scoped.
+// It returns nil if none of the sets are node-scoped.
+func (dp *DPShim) processNodeScopedSets(setNames map[string]struct{}) (*protos.GoalState, error) {
+	if len(setNames) == 0 {
+		return nil, nil
+	}
+
+	nodeScopedSets := dp.nodeScopedSets()
+	toFilterSets := make([]string, 0)
+	for setName := range setNames {
+		if _, ok := nodeScopedSets[setName]; ok {
+			toFilterSets = append(toFilterSets, setName)
+		}
+	}
+	if len(toFilterSets) == 0 {
+		return nil, nil
+	}
+	sort.Strings(toFilterSets)
+
+	payload, err := controlplane.EncodeStrings(toFilterSets)
+	if err != nil {
+		klog.Errorf("processNodeScopedSets: failed to encode sets %v", err)
+		return nil, npmerrors.ErrorWrapper(npmerrors.AppendIPSet, false, "processNodeScopedSets: failed to encode sets", err)
+	}
+
+	return getGoalStateFromBuffer(payload), nil
+}
+
+// FilterGoalStateForNode returns a copy of the event without the members of node-scoped sets which are on other nodes.
+// Members without a node, like IPs of host network pods, are kept.
+func FilterGoalStateForNode(event *protos.Events, nodeName string) (*protos.Events, error) {
+	nodeScopedPayload, ok := event.GetPayload()[controlplane.NodeScopedIPSets]
+	if !ok {
+		return event, nil
+	}
+
+	filteredEvent := &protos.Events{
+		EventType:  event.GetEventType(),
+		Payload:    make(map[string]*protos.GoalState, len(event.GetPayload())),
+		Generation: event.GetGeneration(),
+	}
+	for key, goalState := range event.GetPayload() {
+		if key != controlplane.NodeScopedIPSets {
+			filteredEvent.Payload[key] = goalState
+		}
+	}
+
+	ipsetApplyPayload, ok := event.GetPayload()[controlplane.IpsetApply]
+	if !ok || nodeName == "" {
+		return filteredEvent, nil
+	}
+
+	nodeScopedSetNames, err := controlplane.DecodeStrings(bytes.NewBuffer(nodeScopedPayload.GetData()))
+	if err != nil {
+		return nil, npmerrors.SimpleErrorWrapper("failed to decode node-scoped sets", err)
+	}
+	nodeScopedSets := make(map[string]struct{}, len(nodeScopedSetNames))
+	for _, setName := range nodeScopedSetNames {
+		nodeScopedSets[setName] = struct{}{}
+	}
+
+	sets, err := controlplane.DecodeControllerIPSets(bytes.NewBuffer(ipsetApplyPayload.GetData()))
+	if err != nil {
+		return nil, npmerrors.SimpleErrorWrapper("failed to decode IPSet apply event", err)
+	}
+	for i, set := range sets {
+		if _, ok := nodeScopedSets[set.GetPrefixName()]; !ok {
+			continue
+		}
+		filteredSet := controlplane.NewControllerIPSets(set.IPSetMetadata)
+		filteredSet.NetPolReference = set.NetPolReference
+		for podIP, podMetadata := range set.IPPodMetadata {
+			if podMetadata.NodeName == "" || podMetadata.NodeName == nodeName {
+				filteredSet.IPPodMetadata[podIP] = podMetadata
+			}
+		}
+		sets[i] = filteredSet
+	}
+
+	payload, err := controlplane.EncodeControllerIPSets(sets)
+	if err != nil {
+		return nil, npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to encode IPSets for node %s", nodeName), err)
+	}
+	filteredEvent.Payload[controlplane.IpsetApply] = getGoalStateFromBuffer(payload)
+	return filteredEvent, nil
+}
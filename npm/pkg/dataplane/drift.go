@@ -0,0 +1,34 @@
+package dataplane
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+// repairDrift verifies the ipsets and then the policies in the kernel against the cache, in case another agent modified them.
+// Drifted ipsets and policies are repaired, counted in metrics, and logged.
+func (dp *DataPlane) repairDrift() {
+	// ipsets first since the policies reference them
+	driftedSets, err := dp.ipsetMgr.ReconcileWithKernel()
+	if len(driftedSets) > 0 {
+		metrics.AddDataplaneDrift(metrics.IPSetDrift, len(driftedSets))
+		metrics.SendLog(util.DaemonDataplaneID, fmt.Sprintf("[DataPlane] repaired ipsets which drifted from the cache: %+v", driftedSets), metrics.PrintLog)
+	}
+	if err != nil {
+		metrics.IncDataplaneDriftRepairFailures(metrics.IPSetDrift)
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "error: failed to repair drifted ipsets: %s", err.Error())
+	}
+
+	drift, err := dp.policyMgr.ReconcileWithKernel()
+	if drift != nil && !drift.IsEmpty() {
+		metrics.AddDataplaneDrift(metrics.ChainDrift, len(drift.BaseChains))
+		metrics.AddDataplaneDrift(metrics.PolicyDrift, len(drift.Policies))
+		metrics.SendLog(util.DaemonDataplaneID, fmt.Sprintf("[DataPlane] repaired chains %+v and policies %+v which drifted from the cache", drift.BaseChains, drift.Policies), metrics.PrintLog)
+	}
+	if err != nil {
+		metrics.IncDataplaneDriftRepairFailures(metrics.PolicyDrift)
+		metrics.SendErrorLogAndMetric(util.DaemonDataplaneID, "error: failed to repair drifted policies: %s", err.Error())
+	}
+}
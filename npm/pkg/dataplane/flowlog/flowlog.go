@@ -0,0 +1,213 @@
+// Package flowlog logs the flows which NPM's policies deny.
+// In Linux, the Dropped ACLs of policies send their flows to an NFLOG group when denied-flow logging is enabled,
+// and the ACLs of audited policies only send their flows there instead of dropping them.
+// The Logger reads the NFLOG group and emits a structured record for each flow.
+package flowlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/npm/util"
+)
+
+const (
+	ingress = "IN"
+	egress  = "OUT"
+
+	ipv4HeaderMinLength = 20
+	ipv6HeaderLength    = 40
+	l4PortsLength       = 4
+
+	protocolICMP   = 1
+	protocolTCP    = 6
+	protocolUDP    = 17
+	protocolICMPv6 = 58
+	protocolSCTP   = 132
+)
+
+var (
+	ErrInvalidPrefix = errors.New("invalid denied flow prefix")
+	ErrInvalidPacket = errors.New("invalid packet")
+)
+
+// PolicyResolver finds the PolicyKey of an applied policy from the hash in a denied flow's prefix.
+type PolicyResolver interface {
+	PolicyKeyForHash(hash string) (string, bool)
+}
+
+// PodResolver finds the <nsname>/<podname> key of a pod from its IP.
+type PodResolver interface {
+	PodKeyForIP(podIP string) (string, bool)
+}
+
+// DeniedFlow is the record of a flow which a policy denied, or would have denied if it weren't audited.
+type DeniedFlow struct {
+	// Policy is the PolicyKey of the NPMNetworkPolicy which denied the flow.
+	// It is empty if the policy was removed before the flow was read.
+	Policy string `json:"policy,omitempty"`
+	// PolicyHash is the hash of the PolicyKey which the dataplane logged the flow with
+	PolicyHash string `json:"policyHash"`
+	// Direction is relative to the pod selected by the policy: IN or OUT
+	Direction string `json:"direction"`
+	// Audit is true if the flow was logged instead of dropped
+	Audit    bool   `json:"audit"`
+	Protocol string `json:"protocol"`
+	SrcIP    string `json:"srcIP"`
+	SrcPod   string `json:"srcPod,omitempty"`
+	SrcPort  uint16 `json:"srcPort,omitempty"`
+	DstIP    string `json:"dstIP"`
+	DstPod   string `json:"dstPod,omitempty"`
+	DstPort  uint16 `json:"dstPort,omitempty"`
+}
+
+// Logger emits a DeniedFlow for each packet in the NFLOG group of denied flows.
+type Logger struct {
+	group    int
+	policies PolicyResolver
+	pods     PodResolver
+
+	sync.Mutex
+	// policyKeys caches the PolicyKeys of hashes already resolved
+	policyKeys map[string]string
+}
+
+func NewLogger(group int, policies PolicyResolver, pods PodResolver) *Logger {
+	return &Logger{
+		group:      group,
+		policies:   policies,
+		pods:       pods,
+		policyKeys: make(map[string]string),
+	}
+}
+
+// deniedFlow builds the record of a packet which the dataplane logged with the prefix.
+func (l *Logger) deniedFlow(prefix string, packet []byte) (*DeniedFlow, error) {
+	flow, err := parsePacket(packet)
+	if err != nil {
+		return nil, err
+	}
+	if err := parsePrefix(prefix, flow); err != nil {
+		return nil, err
+	}
+
+	flow.Policy = l.policyKey(flow.PolicyHash)
+	flow.SrcPod, _ = l.pods.PodKeyForIP(flow.SrcIP)
+	flow.DstPod, _ = l.pods.PodKeyForIP(flow.DstIP)
+	return flow, nil
+}
+
+func (l *Logger) policyKey(hash string) string {
+	l.Lock()
+	defer l.Unlock()
+
+	if policyKey, ok := l.policyKeys[hash]; ok {
+		return policyKey
+	}
+	policyKey, ok := l.policies.PolicyKeyForHash(hash)
+	if !ok {
+		return ""
+	}
+	l.policyKeys[hash] = policyKey
+	return policyKey
+}
+
+// parsePrefix fills in the policy hash, direction, and audit fields from a prefix like "NPM-DROP-IN:1234567".
+func parsePrefix(prefix string, flow *DeniedFlow) error {
+	// NFLOG prefixes are null-terminated
+	prefix = strings.TrimRight(prefix, "\x00")
+	kindAndDirection, hash, ok := strings.Cut(prefix, ":")
+	if !ok || hash == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidPrefix, prefix)
+	}
+
+	kind, direction, ok := cutLast(kindAndDirection, "-")
+	if !ok || (direction != ingress && direction != egress) {
+		return fmt.Errorf("%w: %q", ErrInvalidPrefix, prefix)
+	}
+	switch kind {
+	case util.NFLogDropPrefix:
+		flow.Audit = false
+	case util.NFLogAuditPrefix:
+		flow.Audit = true
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidPrefix, prefix)
+	}
+
+	flow.PolicyHash = hash
+	flow.Direction = direction
+	return nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// parsePacket fills in the addresses, protocol, and ports from the network and transport headers of an IPv4 or IPv6 packet.
+// IPv6 extension headers aren't followed, so the ports of such packets are left empty.
+func parsePacket(packet []byte) (*DeniedFlow, error) {
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("%w: empty packet", ErrInvalidPacket)
+	}
+
+	flow := &DeniedFlow{}
+	var protocol byte
+	var l4Header []byte
+	switch version := packet[0] >> 4; version {
+	case 4: //nolint:gomnd // IP version
+		headerLength := int(packet[0]&0x0f) * 4 //nolint:gomnd // IHL is in 32-bit words
+		if headerLength < ipv4HeaderMinLength || len(packet) < headerLength {
+			return nil, fmt.Errorf("%w: truncated IPv4 header", ErrInvalidPacket)
+		}
+		protocol = packet[9]
+		flow.SrcIP = net.IP(packet[12:16]).String()
+		flow.DstIP = net.IP(packet[16:20]).String()
+		l4Header = packet[headerLength:]
+	case 6: //nolint:gomnd // IP version
+		if len(packet) < ipv6HeaderLength {
+			return nil, fmt.Errorf("%w: truncated IPv6 header", ErrInvalidPacket)
+		}
+		protocol = packet[6]
+		flow.SrcIP = net.IP(packet[8:24]).String()
+		flow.DstIP = net.IP(packet[24:40]).String()
+		l4Header = packet[ipv6HeaderLength:]
+	default:
+		return nil, fmt.Errorf("%w: unknown IP version %d", ErrInvalidPacket, version)
+	}
+
+	flow.Protocol = protocolName(protocol)
+	if hasPorts(protocol) && len(l4Header) >= l4PortsLength {
+		flow.SrcPort = binary.BigEndian.Uint16(l4Header[0:2])
+		flow.DstPort = binary.BigEndian.Uint16(l4Header[2:4])
+	}
+	return flow, nil
+}
+
+func hasPorts(protocol byte) bool {
+	return protocol == protocolTCP || protocol == protocolUDP || protocol == protocolSCTP
+}
+
+func protocolName(protocol byte) string {
+	switch protocol {
+	case protocolICMP:
+		return "ICMP"
+	case protocolTCP:
+		return "TCP"
+	case protocolUDP:
+		return "UDP"
+	case protocolICMPv6:
+		return "ICMPv6"
+	case protocolSCTP:
+		return "SCTP"
+	default:
+		return strconv.Itoa(int(protocol))
+	}
+}
@@ -0,0 +1,153 @@
+package flowlog
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakePolicyResolver map[string]string
+
+func (r fakePolicyResolver) PolicyKeyForHash(hash string) (string, bool) {
+	policyKey, ok := r[hash]
+	return policyKey, ok
+}
+
+type fakePodResolver map[string]string
+
+func (r fakePodResolver) PodKeyForIP(podIP string) (string, bool) {
+	podKey, ok := r[podIP]
+	return podKey, ok
+}
+
+func ipv4TCPPacket(src, dst string, srcPort, dstPort uint16) []byte {
+	packet := make([]byte, 24)
+	packet[0] = 0x45 // version 4, IHL 5
+	packet[9] = protocolTCP
+	copy(packet[12:16], net.ParseIP(src).To4())
+	copy(packet[16:20], net.ParseIP(dst).To4())
+	binary.BigEndian.PutUint16(packet[20:22], srcPort)
+	binary.BigEndian.PutUint16(packet[22:24], dstPort)
+	return packet
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		want    *DeniedFlow
+		wantErr bool
+	}{
+		{
+			name:   "dropped ingress",
+			prefix: "NPM-DROP-IN:1234567\x00",
+			want:   &DeniedFlow{PolicyHash: "1234567", Direction: "IN"},
+		},
+		{
+			name:   "audited egress",
+			prefix: "NPM-AUDIT-OUT:89",
+			want:   &DeniedFlow{PolicyHash: "89", Direction: "OUT", Audit: true},
+		},
+		{
+			name:    "unknown kind",
+			prefix:  "NPM-ALLOW-IN:89",
+			wantErr: true,
+		},
+		{
+			name:    "unknown direction",
+			prefix:  "NPM-DROP-BOTH:89",
+			wantErr: true,
+		},
+		{
+			name:    "no hash",
+			prefix:  "NPM-DROP-IN:",
+			wantErr: true,
+		},
+		{
+			name:    "not from NPM",
+			prefix:  "some other log",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			flow := &DeniedFlow{}
+			err := parsePrefix(tt.prefix, flow)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidPrefix)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, flow)
+		})
+	}
+}
+
+func TestParsePacket(t *testing.T) {
+	flow, err := parsePacket(ipv4TCPPacket("10.0.0.1", "10.0.0.2", 34567, 80))
+	require.NoError(t, err)
+	require.Equal(t, &DeniedFlow{Protocol: "TCP", SrcIP: "10.0.0.1", SrcPort: 34567, DstIP: "10.0.0.2", DstPort: 80}, flow)
+
+	ipv6Packet := make([]byte, 48)
+	ipv6Packet[0] = 0x60
+	ipv6Packet[6] = protocolUDP
+	copy(ipv6Packet[8:24], net.ParseIP("fd00::1"))
+	copy(ipv6Packet[24:40], net.ParseIP("fd00::2"))
+	binary.BigEndian.PutUint16(ipv6Packet[40:42], 5353)
+	binary.BigEndian.PutUint16(ipv6Packet[42:44], 53)
+	flow, err = parsePacket(ipv6Packet)
+	require.NoError(t, err)
+	require.Equal(t, &DeniedFlow{Protocol: "UDP", SrcIP: "fd00::1", SrcPort: 5353, DstIP: "fd00::2", DstPort: 53}, flow)
+
+	// ICMP has no ports
+	icmpPacket := ipv4TCPPacket("10.0.0.1", "10.0.0.2", 1, 2)
+	icmpPacket[9] = protocolICMP
+	flow, err = parsePacket(icmpPacket)
+	require.NoError(t, err)
+	require.Equal(t, &DeniedFlow{Protocol: "ICMP", SrcIP: "10.0.0.1", DstIP: "10.0.0.2"}, flow)
+
+	_, err = parsePacket(nil)
+	require.ErrorIs(t, err, ErrInvalidPacket)
+	_, err = parsePacket(ipv4TCPPacket("10.0.0.1", "10.0.0.2", 1, 2)[:10])
+	require.ErrorIs(t, err, ErrInvalidPacket)
+	_, err = parsePacket([]byte{0x10})
+	require.ErrorIs(t, err, ErrInvalidPacket)
+}
+
+func TestDeniedFlow(t *testing.T) {
+	policies := fakePolicyResolver{"1234567": "x/deny-all"}
+	pods := fakePodResolver{"10.0.0.1": "x/client", "10.0.0.2": "y/server"}
+	l := NewLogger(100, policies, pods)
+
+	flow, err := l.deniedFlow("NPM-DROP-OUT:1234567", ipv4TCPPacket("10.0.0.1", "10.0.0.2", 34567, 80))
+	require.NoError(t, err)
+	require.Equal(t, &DeniedFlow{
+		Policy:     "x/deny-all",
+		PolicyHash: "1234567",
+		Direction:  "OUT",
+		Protocol:   "TCP",
+		SrcIP:      "10.0.0.1",
+		SrcPod:     "x/client",
+		SrcPort:    34567,
+		DstIP:      "10.0.0.2",
+		DstPod:     "y/server",
+		DstPort:    80,
+	}, flow)
+
+	// resolved policies are cached
+	delete(policies, "1234567")
+	flow, err = l.deniedFlow("NPM-AUDIT-IN:1234567", ipv4TCPPacket("10.1.0.1", "10.0.0.2", 34567, 80))
+	require.NoError(t, err)
+	require.Equal(t, "x/deny-all", flow.Policy)
+	require.True(t, flow.Audit)
+	require.Empty(t, flow.SrcPod)
+
+	// unknown policies are left empty
+	flow, err = l.deniedFlow("NPM-DROP-IN:89", ipv4TCPPacket("10.1.0.1", "10.0.0.2", 34567, 80))
+	require.NoError(t, err)
+	require.Empty(t, flow.Policy)
+	require.Equal(t, "89", flow.PolicyHash)
+}
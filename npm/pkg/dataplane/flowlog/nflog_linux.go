@@ -0,0 +1,47 @@
+package flowlog
+
+import (
+	"encoding/json"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/nflog"
+	"k8s.io/klog"
+)
+
+// copyRange is enough for the IPv6 header and the ports of the transport header
+const copyRange = 128
+
+// Run binds to the NFLOG group and logs the denied flows in it until stopCh is closed.
+// It returns an error if it can't bind to the group, e.g. because another process is bound to it.
+func (l *Logger) Run(stopCh <-chan struct{}) error {
+	klog.Infof("[flowlog] logging denied flows in NFLOG group %d", l.group)
+	if err := nflog.Read(l.group, copyRange, stopCh, l.handlePacket); err != nil {
+		return err //nolint:wrapcheck // the error names the group
+	}
+	klog.Info("[flowlog] stopped logging denied flows")
+	return nil
+}
+
+func (l *Logger) handlePacket(prefix string, packet []byte) {
+	flow, err := l.deniedFlow(prefix, packet)
+	if err != nil {
+		klog.Errorf("[flowlog] failed to parse denied flow. err: %v", err)
+		return
+	}
+	logDeniedFlow(flow)
+}
+
+func logDeniedFlow(flow *DeniedFlow) {
+	policy := flow.Policy
+	if policy == "" {
+		policy = flow.PolicyHash
+	}
+	metrics.IncDeniedFlows(policy, flow.Direction, flow.Audit)
+
+	record, err := json.Marshal(flow)
+	if err != nil {
+		klog.Errorf("[flowlog] failed to marshal denied flow. err: %v", err)
+		return
+	}
+	klog.Infof("[flowlog] denied flow: %s", record)
+}
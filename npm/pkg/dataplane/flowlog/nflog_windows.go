@@ -0,0 +1,10 @@
+package flowlog
+
+import "errors"
+
+var ErrNotSupported = errors.New("denied flows can't be logged in Windows")
+
+// Run returns ErrNotSupported since HNS ACLs can't log the flows they deny.
+func (l *Logger) Run(_ <-chan struct{}) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,181 @@
+package fqdn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	ipv4HeaderMinLength = 20
+	ipv6HeaderLength    = 40
+	udpHeaderLength     = 8
+	protocolUDP         = 17
+	dnsPort             = 53
+)
+
+var (
+	ErrInvalidPacket   = errors.New("invalid DNS response packet")
+	ErrInvalidResponse = errors.New("invalid DNS response")
+)
+
+// answer holds the IPs which a DNS response resolved its question to.
+// names are the question and the targets of its CNAME chain, any of which can match a policy's FQDNs.
+type answer struct {
+	names []string
+	ips   []netip.Addr
+	// ttl is the lowest TTL of the records in the chain
+	ttl time.Duration
+}
+
+// udpPayload returns the destination IP and the payload of an IPv4 or IPv6 UDP packet from port 53.
+// IPv6 extension headers aren't followed.
+func udpPayload(packet []byte) (netip.Addr, []byte, error) {
+	if len(packet) == 0 {
+		return netip.Addr{}, nil, fmt.Errorf("%w: empty packet", ErrInvalidPacket)
+	}
+
+	var protocol byte
+	var dst netip.Addr
+	var l4 []byte
+	switch version := packet[0] >> 4; version {
+	case 4: //nolint:gomnd // IP version
+		headerLength := int(packet[0]&0x0f) * 4 //nolint:gomnd // IHL is in 32-bit words
+		if headerLength < ipv4HeaderMinLength || len(packet) < headerLength {
+			return netip.Addr{}, nil, fmt.Errorf("%w: truncated IPv4 header", ErrInvalidPacket)
+		}
+		protocol = packet[9]
+		dst = netip.AddrFrom4([4]byte(packet[16:20]))
+		l4 = packet[headerLength:]
+	case 6: //nolint:gomnd // IP version
+		if len(packet) < ipv6HeaderLength {
+			return netip.Addr{}, nil, fmt.Errorf("%w: truncated IPv6 header", ErrInvalidPacket)
+		}
+		protocol = packet[6]
+		dst = netip.AddrFrom16([16]byte(packet[24:40]))
+		l4 = packet[ipv6HeaderLength:]
+	default:
+		return netip.Addr{}, nil, fmt.Errorf("%w: unknown IP version %d", ErrInvalidPacket, version)
+	}
+
+	if protocol != protocolUDP {
+		return netip.Addr{}, nil, fmt.Errorf("%w: protocol %d is not UDP", ErrInvalidPacket, protocol)
+	}
+	if len(l4) < udpHeaderLength {
+		return netip.Addr{}, nil, fmt.Errorf("%w: truncated UDP header", ErrInvalidPacket)
+	}
+	if srcPort := binary.BigEndian.Uint16(l4[0:2]); srcPort != dnsPort {
+		return netip.Addr{}, nil, fmt.Errorf("%w: source port %d is not %d", ErrInvalidPacket, srcPort, dnsPort)
+	}
+	return dst, l4[udpHeaderLength:], nil
+}
+
+// parseResponse returns the answer to the question of a successful DNS response, or nil if it has no addresses.
+func parseResponse(msg []byte) (*answer, error) {
+	var p dnsmessage.Parser
+	header, startErr := p.Start(msg)
+	if startErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, startErr)
+	}
+	if !header.Response || header.RCode != dnsmessage.RCodeSuccess {
+		return nil, nil
+	}
+
+	questions, questionsErr := p.AllQuestions()
+	if questionsErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, questionsErr)
+	}
+	if len(questions) != 1 {
+		return nil, nil
+	}
+
+	type record struct {
+		ips []netip.Addr
+		ttl uint32
+	}
+	addresses := make(map[string]*record)
+	cnames := make(map[string]string)
+	cnameTTLs := make(map[string]uint32)
+	for {
+		h, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+		}
+
+		name := normalize(h.Name.String())
+		ip, target, err := parseRecord(&p, h.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+		}
+		if target != "" {
+			cnames[name] = target
+			cnameTTLs[name] = h.TTL
+		}
+		if !ip.IsValid() {
+			continue
+		}
+
+		r, ok := addresses[name]
+		if !ok {
+			r = &record{ttl: h.TTL}
+			addresses[name] = r
+		}
+		r.ips = append(r.ips, ip)
+		r.ttl = min(r.ttl, h.TTL)
+	}
+
+	// follow the CNAME chain from the question to the name with the addresses
+	name := normalize(questions[0].Name.String())
+	a := &answer{names: []string{name}}
+	ttl := ^uint32(0)
+	for {
+		if r, ok := addresses[name]; ok {
+			a.ips = r.ips
+			ttl = min(ttl, r.ttl)
+			break
+		}
+		target, ok := cnames[name]
+		if !ok || len(a.names) > len(cnames) {
+			// no addresses, or a CNAME loop
+			return nil, nil
+		}
+		ttl = min(ttl, cnameTTLs[name])
+		a.names = append(a.names, target)
+		name = target
+	}
+	a.ttl = time.Duration(ttl) * time.Second
+	return a, nil
+}
+
+// parseRecord returns the address of an A or AAAA record or the target of a CNAME record, and skips other records.
+func parseRecord(p *dnsmessage.Parser, recordType dnsmessage.Type) (netip.Addr, string, error) {
+	switch recordType {
+	case dnsmessage.TypeA:
+		r, err := p.AResource()
+		if err != nil {
+			return netip.Addr{}, "", err //nolint:wrapcheck // wrapped by the caller
+		}
+		return netip.AddrFrom4(r.A), "", nil
+	case dnsmessage.TypeAAAA:
+		r, err := p.AAAAResource()
+		if err != nil {
+			return netip.Addr{}, "", err //nolint:wrapcheck // wrapped by the caller
+		}
+		return netip.AddrFrom16(r.AAAA), "", nil
+	case dnsmessage.TypeCNAME:
+		r, err := p.CNAMEResource()
+		if err != nil {
+			return netip.Addr{}, "", err //nolint:wrapcheck // wrapped by the caller
+		}
+		return netip.Addr{}, normalize(r.CNAME.String()), nil
+	default:
+		return netip.Addr{}, "", p.SkipAnswer() //nolint:wrapcheck // wrapped by the caller
+	}
+}
@@ -0,0 +1,135 @@
+package fqdn
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type testRecord struct {
+	name  string
+	ttl   uint32
+	ip    string
+	cname string
+}
+
+func dnsResponse(t *testing.T, rcode dnsmessage.RCode, question string, records ...testRecord) []byte {
+	t.Helper()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, RCode: rcode})
+	require.NoError(t, b.StartQuestions())
+	require.NoError(t, b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(question),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}))
+	require.NoError(t, b.StartAnswers())
+	for _, r := range records {
+		header := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(r.name), Class: dnsmessage.ClassINET, TTL: r.ttl}
+		switch {
+		case r.cname != "":
+			require.NoError(t, b.CNAMEResource(header, dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(r.cname)}))
+		case netip.MustParseAddr(r.ip).Is4():
+			require.NoError(t, b.AResource(header, dnsmessage.AResource{A: netip.MustParseAddr(r.ip).As4()}))
+		default:
+			require.NoError(t, b.AAAAResource(header, dnsmessage.AAAAResource{AAAA: netip.MustParseAddr(r.ip).As16()}))
+		}
+	}
+	msg, err := b.Finish()
+	require.NoError(t, err)
+	return msg
+}
+
+// testClient is the destination of the packets from ipv4UDPPacket
+var testClient = netip.MustParseAddr("10.0.0.1")
+
+func ipv4UDPPacket(srcPort uint16, payload []byte) []byte {
+	packet := make([]byte, ipv4HeaderMinLength+udpHeaderLength, ipv4HeaderMinLength+udpHeaderLength+len(payload))
+	packet[0] = 0x45 // version 4, IHL 5
+	packet[9] = protocolUDP
+	copy(packet[16:20], testClient.AsSlice())
+	binary.BigEndian.PutUint16(packet[ipv4HeaderMinLength:], srcPort)
+	return append(packet, payload...)
+}
+
+func TestUDPPayload(t *testing.T) {
+	dst, payload, err := udpPayload(ipv4UDPPacket(dnsPort, []byte("dns")))
+	require.NoError(t, err)
+	require.Equal(t, testClient, dst)
+	require.Equal(t, []byte("dns"), payload)
+
+	ipv6Client := netip.MustParseAddr("fd00::10")
+	ipv6Packet := make([]byte, ipv6HeaderLength+udpHeaderLength)
+	ipv6Packet[0] = 0x60
+	ipv6Packet[6] = protocolUDP
+	copy(ipv6Packet[24:40], ipv6Client.AsSlice())
+	binary.BigEndian.PutUint16(ipv6Packet[ipv6HeaderLength:], dnsPort)
+	dst, payload, err = udpPayload(append(ipv6Packet, []byte("dns")...))
+	require.NoError(t, err)
+	require.Equal(t, ipv6Client, dst)
+	require.Equal(t, []byte("dns"), payload)
+
+	_, _, err = udpPayload(ipv4UDPPacket(5353, []byte("dns")))
+	require.ErrorIs(t, err, ErrInvalidPacket)
+
+	tcpPacket := ipv4UDPPacket(dnsPort, nil)
+	tcpPacket[9] = 6
+	_, _, err = udpPayload(tcpPacket)
+	require.ErrorIs(t, err, ErrInvalidPacket)
+
+	_, _, err = udpPayload(ipv4UDPPacket(dnsPort, nil)[:10])
+	require.ErrorIs(t, err, ErrInvalidPacket)
+}
+
+func TestParseResponse(t *testing.T) {
+	t.Run("addresses", func(t *testing.T) {
+		msg := dnsResponse(t, dnsmessage.RCodeSuccess, "Example.com.",
+			testRecord{name: "example.com.", ttl: 300, ip: "1.1.1.1"},
+			testRecord{name: "example.com.", ttl: 60, ip: "1.1.1.2"},
+			testRecord{name: "example.com.", ttl: 60, ip: "fd00::1"},
+		)
+		a, err := parseResponse(msg)
+		require.NoError(t, err)
+		require.Equal(t, []string{"example.com"}, a.names)
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("1.1.1.2"), netip.MustParseAddr("fd00::1")}, a.ips)
+		require.Equal(t, 60*time.Second, a.ttl)
+	})
+
+	t.Run("CNAME chain", func(t *testing.T) {
+		msg := dnsResponse(t, dnsmessage.RCodeSuccess, "account.blob.core.windows.net.",
+			testRecord{name: "account.blob.core.windows.net.", ttl: 30, cname: "blob.store.core.windows.net."},
+			testRecord{name: "blob.store.core.windows.net.", ttl: 120, ip: "2.2.2.2"},
+			testRecord{name: "unrelated.com.", ttl: 120, ip: "3.3.3.3"},
+		)
+		a, err := parseResponse(msg)
+		require.NoError(t, err)
+		require.Equal(t, []string{"account.blob.core.windows.net", "blob.store.core.windows.net"}, a.names)
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("2.2.2.2")}, a.ips)
+		require.Equal(t, 30*time.Second, a.ttl)
+	})
+
+	t.Run("CNAME loop", func(t *testing.T) {
+		msg := dnsResponse(t, dnsmessage.RCodeSuccess, "a.com.",
+			testRecord{name: "a.com.", ttl: 30, cname: "b.com."},
+			testRecord{name: "b.com.", ttl: 30, cname: "a.com."},
+		)
+		a, err := parseResponse(msg)
+		require.NoError(t, err)
+		require.Nil(t, a)
+	})
+
+	t.Run("NXDOMAIN", func(t *testing.T) {
+		a, err := parseResponse(dnsResponse(t, dnsmessage.RCodeNameError, "example.com."))
+		require.NoError(t, err)
+		require.Nil(t, a)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		msg := dnsResponse(t, dnsmessage.RCodeSuccess, "example.com.", testRecord{name: "example.com.", ttl: 300, ip: "1.1.1.1"})
+		_, err := parseResponse(msg[:len(msg)-2])
+		require.ErrorIs(t, err, ErrInvalidResponse)
+	})
+}
@@ -0,0 +1,90 @@
+// Package fqdn lets NetworkPolicies allow egress to domain names, which ipBlocks can't express.
+// The npm.azure.com/fqdn-egress annotation of a NetworkPolicy lists the names, and translation adds an egress ACL
+// to an ipset of the policy. In Linux, the dataplane sends the DNS responses forwarded to pods to an NFLOG group.
+// The Snooper reads the group, and the Tracker adds the IPs of answers for matching names to the ipsets
+// of the policies until their TTL expires.
+//
+// Only UDP responses from port 53 which traverse the FORWARD chain are snooped, so DNS over TCP and node-local DNS caches
+// aren't supported. A connection opened right after the answer arrives can race the ipset update, in which case
+// the client's retransmit is allowed.
+package fqdn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	wildcardPrefix = "*."
+	maxNameLength  = 253
+	maxLabelLength = 63
+)
+
+var ErrInvalidName = errors.New("invalid FQDN")
+
+// ParseNames parses the comma-separated names of the npm.azure.com/fqdn-egress annotation.
+// A name is a domain name, optionally prefixed with "*." to match any of its subdomains.
+// Names are returned lowercase, without a trailing dot, and without duplicates.
+func ParseNames(annotation string) ([]string, error) {
+	names := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, name := range strings.Split(annotation, ",") {
+		name = normalize(name)
+		if name == "" {
+			continue
+		}
+		if err := validateName(name); err != nil {
+			return nil, err
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: no names in %q", ErrInvalidName, annotation)
+	}
+	return names, nil
+}
+
+// Matches returns true if the domain name from a DNS answer matches the name from an annotation.
+// "*.example.com" matches "a.example.com" and "a.b.example.com" but not "example.com".
+func Matches(pattern, name string) bool {
+	name = normalize(name)
+	if suffix, ok := strings.CutPrefix(pattern, wildcardPrefix); ok {
+		return strings.HasSuffix(name, "."+suffix)
+	}
+	return name == pattern
+}
+
+func normalize(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+func validateName(name string) error {
+	domain := strings.TrimPrefix(name, wildcardPrefix)
+	if len(domain) > maxNameLength {
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidName, name, maxNameLength)
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if !validLabel(label) {
+			return fmt.Errorf("%w: %q has an invalid label %q", ErrInvalidName, name, label)
+		}
+	}
+	return nil
+}
+
+// validLabel allows underscores since names like _service._tcp.example.com can have address records.
+func validLabel(label string) bool {
+	if label == "" || len(label) > maxLabelLength || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, c := range label {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
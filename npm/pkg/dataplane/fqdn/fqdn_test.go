@@ -0,0 +1,86 @@
+package fqdn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name:       "names and wildcards",
+			annotation: "*.blob.core.windows.net, Login.MicrosoftOnline.com.",
+			want:       []string{"*.blob.core.windows.net", "login.microsoftonline.com"},
+		},
+		{
+			name:       "duplicates and empty entries",
+			annotation: "example.com,,EXAMPLE.com,",
+			want:       []string{"example.com"},
+		},
+		{
+			name:       "service name with underscores",
+			annotation: "_http._tcp.example.com",
+			want:       []string{"_http._tcp.example.com"},
+		},
+		{
+			name:       "empty",
+			annotation: " , ",
+			wantErr:    true,
+		},
+		{
+			name:       "wildcard in the middle",
+			annotation: "a.*.example.com",
+			wantErr:    true,
+		},
+		{
+			name:       "bare wildcard",
+			annotation: "*",
+			wantErr:    true,
+		},
+		{
+			name:       "empty label",
+			annotation: "a..example.com",
+			wantErr:    true,
+		},
+		{
+			name:       "label starting with a hyphen",
+			annotation: "-a.example.com",
+			wantErr:    true,
+		},
+		{
+			name:       "URL",
+			annotation: "https://example.com/path",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := ParseNames(tt.annotation)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidName)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	require.True(t, Matches("example.com", "example.com"))
+	require.True(t, Matches("example.com", "Example.COM."))
+	require.False(t, Matches("example.com", "www.example.com"))
+
+	require.True(t, Matches("*.example.com", "a.example.com"))
+	require.True(t, Matches("*.example.com", "a.b.example.com."))
+	require.False(t, Matches("*.example.com", "example.com"))
+	require.False(t, Matches("*.example.com", "badexample.com"))
+}
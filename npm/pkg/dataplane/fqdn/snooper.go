@@ -0,0 +1,80 @@
+package fqdn
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/nflog"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"k8s.io/klog"
+)
+
+const (
+	// copyRange fits the IPv6 and UDP headers and a DNS message with the common EDNS buffer size.
+	// Longer responses are truncated and ignored.
+	copyRange = ipv6HeaderLength + udpHeaderLength + 4096
+	// expiryInterval is how often the Snooper removes expired IPs from the ipsets
+	expiryInterval = 10 * time.Second
+)
+
+// Snooper reads the DNS responses in an NFLOG group and passes their answers to a Tracker.
+type Snooper struct {
+	group   int
+	tracker *Tracker
+}
+
+func NewSnooper(group int, tracker *Tracker) *Snooper {
+	return &Snooper{
+		group:   group,
+		tracker: tracker,
+	}
+}
+
+// Run snoops DNS responses and expires IPs until stopCh is closed.
+// It returns an error if it can't bind to the NFLOG group, e.g. because another process is bound to it.
+func (s *Snooper) Run(stopCh <-chan struct{}) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(expiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				s.tracker.expireIPs()
+			}
+		}
+	}()
+
+	klog.Infof("[fqdn] snooping DNS responses in NFLOG group %d", s.group)
+	if err := nflog.Read(s.group, copyRange, stopCh, s.handlePacket); err != nil {
+		return err //nolint:wrapcheck // the error names the group
+	}
+	klog.Info("[fqdn] stopped snooping DNS responses")
+	return nil
+}
+
+func (s *Snooper) handlePacket(prefix string, packet []byte) {
+	if !strings.HasPrefix(prefix, util.NFLogDNSPrefix) {
+		return
+	}
+
+	client, payload, err := udpPayload(packet)
+	if err != nil {
+		klog.Errorf("[fqdn] failed to parse DNS response packet. err: %v", err)
+		return
+	}
+	a, err := parseResponse(payload)
+	if err != nil {
+		klog.Warningf("[fqdn] ignoring DNS response. err: %v", err)
+		return
+	}
+	if a == nil {
+		return
+	}
+	s.tracker.observe(client, a)
+}
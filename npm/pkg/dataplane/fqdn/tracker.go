@@ -0,0 +1,287 @@
+package fqdn
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"k8s.io/klog"
+)
+
+const (
+	// minTTL keeps IPs in the ipsets for a while after answers with short TTLs,
+	// since clients cache answers for a bit longer than their TTL.
+	minTTL = 30 * time.Second
+	// maxTTL bounds how long an answer keeps an IP in the ipsets, like the cache of CoreDNS bounds its TTL.
+	maxTTL = time.Hour
+)
+
+// IPSetManager is the part of *ipsets.IPSetManager which the Tracker uses.
+type IPSetManager interface {
+	AddToSets(addToSets []*ipsets.IPSetMetadata, ip, podKey string) error
+	RemoveFromSets(removeFromSets []*ipsets.IPSetMetadata, ip, podKey string) error
+	ContainsIP(name, ip string) bool
+}
+
+// Tracker keeps the ipsets of policies with FQDNs filled with the unexpired IPs of DNS answers for their FQDNs
+// to the pods which the policies select.
+type Tracker struct {
+	ipsetMgr IPSetManager
+	// apply applies the dirty ipsets to the kernel
+	apply      func() error
+	enableIPv6 bool
+	now        func() time.Time
+
+	sync.Mutex
+	// policies is keyed by PolicyKey
+	policies map[string]*policyIPs
+	// resolved maps a domain name tracked by a policy to the expiry of each IP it resolved to for each client
+	resolved map[string]map[resolution]time.Time
+}
+
+type resolution struct {
+	client netip.Addr
+	ip     netip.Addr
+}
+
+type policyIPs struct {
+	fqdns []string
+	// podSelector selects the clients whose answers fill the set
+	podSelector []policies.SetInfo
+	set         *ipsets.IPSetMetadata
+	// expiries maps each IP in the set to when it expires
+	expiries map[netip.Addr]time.Time
+}
+
+// NewTracker creates a Tracker which updates the ipsets in ipsetMgr and calls apply after updating them in the background.
+// IPv6 answers are ignored unless enableIPv6 is true.
+func NewTracker(ipsetMgr IPSetManager, apply func() error, enableIPv6 bool) *Tracker {
+	return &Tracker{
+		ipsetMgr:   ipsetMgr,
+		apply:      apply,
+		enableIPv6: enableIPv6,
+		now:        time.Now,
+		policies:   make(map[string]*policyIPs),
+		resolved:   make(map[string]map[resolution]time.Time),
+	}
+}
+
+// AddPolicy starts tracking the FQDNs of the policy and adds the unexpired IPs already resolved for them
+// to the pods which the policy selects to its ipset.
+// The ipset must exist in the IPSetManager. The caller must apply the ipsets.
+func (t *Tracker) AddPolicy(policy *policies.NPMNetworkPolicy) {
+	if len(policy.FQDNs) == 0 || policy.FQDNIPSet == nil {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.removePolicyLocked(policy.PolicyKey)
+	p := &policyIPs{
+		fqdns:       policy.FQDNs,
+		podSelector: policy.PodSelectorList,
+		set:         policy.FQDNIPSet,
+		expiries:    make(map[netip.Addr]time.Time),
+	}
+	t.policies[policy.PolicyKey] = p
+
+	now := t.now()
+	selected := make(map[netip.Addr]bool)
+	for name, expiries := range t.resolved {
+		if !p.matches([]string{name}) {
+			continue
+		}
+		for r, expiry := range expiries {
+			if !expiry.After(now) {
+				continue
+			}
+			isSelected, ok := selected[r.client]
+			if !ok {
+				isSelected = t.selects(p, r.client)
+				selected[r.client] = isSelected
+			}
+			if isSelected {
+				t.addIP(policy.PolicyKey, p, r.ip, expiry)
+			}
+		}
+	}
+}
+
+// RemovePolicy stops tracking the policy and removes the IPs in its ipset. The caller must apply the ipsets.
+func (t *Tracker) RemovePolicy(policyKey string) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.removePolicyLocked(policyKey)
+}
+
+func (t *Tracker) removePolicyLocked(policyKey string) {
+	p, ok := t.policies[policyKey]
+	if !ok {
+		return
+	}
+	for ip := range p.expiries {
+		t.removeIP(policyKey, p, ip)
+	}
+	delete(t.policies, policyKey)
+}
+
+// observe adds the IPs of the answer to the client to the ipsets of the policies whose FQDNs match and which select the client,
+// and applies the ipsets if they changed.
+func (t *Tracker) observe(client netip.Addr, a *answer) {
+	expiry := t.now().Add(min(max(a.ttl, minTTL), maxTTL))
+	ips := make([]netip.Addr, 0, len(a.ips))
+	for _, ip := range a.ips {
+		if ip.Is4() || (t.enableIPv6 && ip.Is6() && !ip.Is4In6()) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return
+	}
+
+	if t.observeLocked(client.Unmap(), a.names, ips, expiry) {
+		t.applyIPSets()
+	}
+}
+
+func (t *Tracker) observeLocked(client netip.Addr, names []string, ips []netip.Addr, expiry time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	// only names which a policy tracks are remembered for policies added later
+	for _, name := range names {
+		if !t.tracks(name) {
+			continue
+		}
+		expiries, ok := t.resolved[name]
+		if !ok {
+			expiries = make(map[resolution]time.Time, len(ips))
+			t.resolved[name] = expiries
+		}
+		for _, ip := range ips {
+			r := resolution{client: client, ip: ip}
+			if expiry.After(expiries[r]) {
+				expiries[r] = expiry
+			}
+		}
+	}
+
+	changed := false
+	for policyKey, p := range t.policies {
+		if !p.matches(names) || !t.selects(p, client) {
+			continue
+		}
+		for _, ip := range ips {
+			if t.addIP(policyKey, p, ip, expiry) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// expireIPs removes the expired IPs from the ipsets and applies the ipsets if they changed.
+func (t *Tracker) expireIPs() {
+	if t.expireIPsLocked() {
+		t.applyIPSets()
+	}
+}
+
+func (t *Tracker) expireIPsLocked() bool {
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.now()
+	for name, expiries := range t.resolved {
+		for r, expiry := range expiries {
+			if !expiry.After(now) {
+				delete(expiries, r)
+			}
+		}
+		if len(expiries) == 0 {
+			delete(t.resolved, name)
+		}
+	}
+
+	changed := false
+	for policyKey, p := range t.policies {
+		for ip, expiry := range p.expiries {
+			if !expiry.After(now) {
+				t.removeIP(policyKey, p, ip)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// addIP adds the IP to the policy's ipset if it isn't there yet and extends its expiry.
+// It returns true if the ipset changed.
+func (t *Tracker) addIP(policyKey string, p *policyIPs, ip netip.Addr, expiry time.Time) bool {
+	if current, ok := p.expiries[ip]; ok {
+		if expiry.After(current) {
+			p.expiries[ip] = expiry
+		}
+		return false
+	}
+
+	if err := t.ipsetMgr.AddToSets([]*ipsets.IPSetMetadata{p.set}, ip.String(), ""); err != nil {
+		klog.Errorf("[fqdn] failed to add IP %s to the FQDN ipset of policy %s. err: %v", ip, policyKey, err)
+		return false
+	}
+	p.expiries[ip] = expiry
+	return true
+}
+
+func (t *Tracker) removeIP(policyKey string, p *policyIPs, ip netip.Addr) {
+	if err := t.ipsetMgr.RemoveFromSets([]*ipsets.IPSetMetadata{p.set}, ip.String(), ""); err != nil {
+		klog.Errorf("[fqdn] failed to remove IP %s from the FQDN ipset of policy %s. err: %v", ip, policyKey, err)
+	}
+	delete(p.expiries, ip)
+}
+
+// tracks returns true if the name matches the FQDNs of any policy.
+func (t *Tracker) tracks(name string) bool {
+	for _, p := range t.policies {
+		if p.matches([]string{name}) {
+			return true
+		}
+	}
+	return false
+}
+
+// selects returns true if the policy's pod selector selects the pod with the client IP.
+func (t *Tracker) selects(p *policyIPs, client netip.Addr) bool {
+	if len(p.podSelector) == 0 {
+		return false
+	}
+	ip := client.String()
+	for _, setInfo := range p.podSelector {
+		if t.ipsetMgr.ContainsIP(setInfo.IPSet.GetPrefixName(), ip) != setInfo.Included {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tracker) applyIPSets() {
+	if err := t.apply(); err != nil {
+		klog.Errorf("[fqdn] failed to apply FQDN ipsets. err: %v", err)
+	}
+}
+
+// matches returns true if any of the names matches any of the policy's FQDNs.
+func (p *policyIPs) matches(names []string) bool {
+	for _, name := range names {
+		for _, fqdn := range p.fqdns {
+			if Matches(fqdn, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
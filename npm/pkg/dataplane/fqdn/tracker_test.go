@@ -0,0 +1,213 @@
+package fqdn
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/policies"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeIPSetManager records the members of each set.
+// The pod selector sets are keyed by their prefixed names.
+type fakeIPSetManager struct {
+	members map[string]map[string]struct{}
+}
+
+func (f *fakeIPSetManager) AddToSets(sets []*ipsets.IPSetMetadata, ip, _ string) error {
+	for _, set := range sets {
+		if _, ok := f.members[set.Name]; !ok {
+			f.members[set.Name] = make(map[string]struct{})
+		}
+		f.members[set.Name][ip] = struct{}{}
+	}
+	return nil
+}
+
+func (f *fakeIPSetManager) RemoveFromSets(sets []*ipsets.IPSetMetadata, ip, _ string) error {
+	for _, set := range sets {
+		delete(f.members[set.Name], ip)
+	}
+	return nil
+}
+
+func (f *fakeIPSetManager) ContainsIP(name, ip string) bool {
+	_, ok := f.members[name][ip]
+	return ok
+}
+
+func (f *fakeIPSetManager) addPod(setName string, ip netip.Addr) {
+	if _, ok := f.members[setName]; !ok {
+		f.members[setName] = make(map[string]struct{})
+	}
+	f.members[setName][ip.String()] = struct{}{}
+}
+
+func (f *fakeIPSetManager) requireMembers(t *testing.T, setName string, ips ...string) {
+	t.Helper()
+	members := make([]string, 0, len(f.members[setName]))
+	for ip := range f.members[setName] {
+		members = append(members, ip)
+	}
+	require.ElementsMatch(t, ips, members)
+}
+
+// fqdnPolicy returns a policy which selects the pods in namespace x
+func fqdnPolicy(name string, fqdns ...string) *policies.NPMNetworkPolicy {
+	policy := policies.NewNPMNetworkPolicy(name, "x")
+	policy.PodSelectorList = []policies.SetInfo{policies.NewSetInfo("x", ipsets.Namespace, true, policies.SrcMatch)}
+	policy.FQDNs = fqdns
+	policy.FQDNIPSet = ipsets.NewIPSetMetadata(name+"-fqdn", ipsets.CIDRBlocks)
+	return policy
+}
+
+func newTestTracker(enableIPv6 bool) (*Tracker, *fakeIPSetManager, *int, *time.Time) {
+	ipsetMgr := &fakeIPSetManager{members: make(map[string]map[string]struct{})}
+	applies := 0
+	now := time.Unix(0, 0)
+	tracker := NewTracker(ipsetMgr, func() error {
+		applies++
+		return nil
+	}, enableIPv6)
+	tracker.now = func() time.Time { return now }
+	ipsetMgr.addPod(ipsets.NewIPSetMetadata("x", ipsets.Namespace).GetPrefixName(), testClient)
+	return tracker, ipsetMgr, &applies, &now
+}
+
+func TestTrackerObserveAndExpire(t *testing.T) {
+	tracker, ipsetMgr, applies, now := newTestTracker(false)
+	tracker.AddPolicy(fqdnPolicy("blob", "*.blob.core.windows.net"))
+	tracker.AddPolicy(fqdnPolicy("login", "login.microsoftonline.com"))
+	// policies without FQDNs are ignored
+	tracker.AddPolicy(policies.NewNPMNetworkPolicy("plain", "x"))
+	require.Len(t, tracker.policies, 2)
+
+	tracker.observe(testClient, &answer{names: []string{"a.blob.core.windows.net"}, ips: addrs("1.1.1.1", "fd00::1"), ttl: time.Minute})
+	ipsetMgr.requireMembers(t, "blob-fqdn", "1.1.1.1")
+	require.Empty(t, ipsetMgr.members["login-fqdn"])
+	require.Equal(t, 1, *applies)
+
+	// the same answer doesn't change the sets
+	tracker.observe(testClient, &answer{names: []string{"a.blob.core.windows.net"}, ips: addrs("1.1.1.1"), ttl: 2 * time.Minute})
+	require.Equal(t, 1, *applies)
+
+	// short TTLs are extended to minTTL
+	tracker.observe(testClient, &answer{names: []string{"login.microsoftonline.com"}, ips: addrs("2.2.2.2"), ttl: time.Second})
+	ipsetMgr.requireMembers(t, "login-fqdn", "2.2.2.2")
+	require.Equal(t, 2, *applies)
+
+	*now = now.Add(minTTL)
+	tracker.expireIPs()
+	ipsetMgr.requireMembers(t, "login-fqdn")
+	ipsetMgr.requireMembers(t, "blob-fqdn", "1.1.1.1")
+	require.Equal(t, 3, *applies)
+
+	// the second answer extended the expiry of 1.1.1.1
+	*now = now.Add(time.Minute)
+	tracker.expireIPs()
+	ipsetMgr.requireMembers(t, "blob-fqdn", "1.1.1.1")
+	*now = now.Add(time.Minute)
+	tracker.expireIPs()
+	ipsetMgr.requireMembers(t, "blob-fqdn")
+	require.Empty(t, tracker.resolved)
+	require.Equal(t, 4, *applies)
+
+	// nothing to expire
+	tracker.expireIPs()
+	require.Equal(t, 4, *applies)
+
+	// long TTLs are capped at maxTTL
+	tracker.observe(testClient, &answer{names: []string{"a.blob.core.windows.net"}, ips: addrs("1.1.1.1"), ttl: 24 * time.Hour})
+	*now = now.Add(maxTTL)
+	tracker.expireIPs()
+	ipsetMgr.requireMembers(t, "blob-fqdn")
+}
+
+func TestTrackerAddAndRemovePolicy(t *testing.T) {
+	tracker, ipsetMgr, applies, now := newTestTracker(true)
+	tracker.observe(testClient, &answer{names: []string{"example.com"}, ips: addrs("1.1.1.1"), ttl: time.Minute})
+	require.Equal(t, 0, *applies, "no policy matched")
+	require.Empty(t, tracker.resolved, "names which no policy tracks aren't remembered")
+
+	tracker.AddPolicy(fqdnPolicy("cdn", "*.example.net"))
+	tracker.observe(testClient, &answer{names: []string{"example.com", "cdn.example.net"}, ips: addrs("1.1.1.1", "fd00::1"), ttl: time.Minute})
+	ipsetMgr.requireMembers(t, "cdn-fqdn", "1.1.1.1", "fd00::1")
+	require.NotContains(t, tracker.resolved, "example.com")
+
+	// names resolved before the policy was added are in its set
+	tracker.AddPolicy(fqdnPolicy("early", "cdn.example.net"))
+	ipsetMgr.requireMembers(t, "early-fqdn", "1.1.1.1", "fd00::1")
+
+	// expired names aren't added
+	*now = now.Add(time.Hour)
+	tracker.AddPolicy(fqdnPolicy("late", "cdn.example.net"))
+	ipsetMgr.requireMembers(t, "late-fqdn")
+
+	// re-adding a policy replaces its FQDNs
+	tracker.AddPolicy(fqdnPolicy("cdn", "other.com"))
+	ipsetMgr.requireMembers(t, "cdn-fqdn")
+	tracker.observe(testClient, &answer{names: []string{"other.com"}, ips: addrs("3.3.3.3"), ttl: time.Minute})
+	ipsetMgr.requireMembers(t, "cdn-fqdn", "3.3.3.3")
+
+	tracker.RemovePolicy("x/cdn")
+	ipsetMgr.requireMembers(t, "cdn-fqdn")
+	require.NotContains(t, tracker.policies, "x/cdn")
+	tracker.RemovePolicy("x/cdn")
+}
+
+func TestTrackerScopesAnswersToSelectedPods(t *testing.T) {
+	tracker, ipsetMgr, _, _ := newTestTracker(false)
+	// the policy selects the pods in namespace x without the label "untrusted"
+	untrusted := ipsets.NewIPSetMetadata("untrusted", ipsets.KeyLabelOfPod)
+	policy := fqdnPolicy("web", "example.com")
+	policy.PodSelectorList = append(policy.PodSelectorList, policies.NewSetInfo("untrusted", ipsets.KeyLabelOfPod, false, policies.SrcMatch))
+	tracker.AddPolicy(policy)
+
+	untrustedPod := netip.MustParseAddr("10.0.0.2")
+	ipsetMgr.addPod(ipsets.NewIPSetMetadata("x", ipsets.Namespace).GetPrefixName(), untrustedPod)
+	ipsetMgr.addPod(untrusted.GetPrefixName(), untrustedPod)
+	otherNamespacePod := netip.MustParseAddr("10.0.1.1")
+	ipsetMgr.addPod(ipsets.NewIPSetMetadata("y", ipsets.Namespace).GetPrefixName(), otherNamespacePod)
+
+	tracker.observe(untrustedPod, &answer{names: []string{"example.com"}, ips: addrs("1.1.1.1"), ttl: time.Minute})
+	tracker.observe(otherNamespacePod, &answer{names: []string{"example.com"}, ips: addrs("2.2.2.2"), ttl: time.Minute})
+	ipsetMgr.requireMembers(t, "web-fqdn")
+	tracker.observe(testClient, &answer{names: []string{"example.com"}, ips: addrs("3.3.3.3"), ttl: time.Minute})
+	ipsetMgr.requireMembers(t, "web-fqdn", "3.3.3.3")
+
+	// a policy added later only gets the answers to the pods it selects
+	other := fqdnPolicy("other", "example.com")
+	other.PodSelectorList = []policies.SetInfo{policies.NewSetInfo("y", ipsets.Namespace, true, policies.SrcMatch)}
+	tracker.AddPolicy(other)
+	ipsetMgr.requireMembers(t, "other-fqdn", "2.2.2.2")
+}
+
+func TestSnooperHandlePacket(t *testing.T) {
+	tracker, ipsetMgr, _, _ := newTestTracker(false)
+	tracker.AddPolicy(fqdnPolicy("example", "example.com"))
+	snooper := NewSnooper(util.DefaultDNSNFLogGroup, tracker)
+
+	msg := dnsResponse(t, dnsmessage.RCodeSuccess, "example.com.", testRecord{name: "example.com.", ttl: 300, ip: "1.1.1.1"})
+	// packets from other NFLOG rules are ignored
+	snooper.handlePacket("NPM-DROP-IN:123\x00", ipv4UDPPacket(dnsPort, msg))
+	ipsetMgr.requireMembers(t, "example-fqdn")
+
+	snooper.handlePacket(util.NFLogDNSPrefix+"\x00", ipv4UDPPacket(dnsPort, msg))
+	ipsetMgr.requireMembers(t, "example-fqdn", "1.1.1.1")
+
+	// malformed packets are ignored
+	snooper.handlePacket(util.NFLogDNSPrefix+"\x00", ipv4UDPPacket(dnsPort, msg[:5]))
+	snooper.handlePacket(util.NFLogDNSPrefix+"\x00", nil)
+}
+
+func addrs(ips ...string) []netip.Addr {
+	result := make([]netip.Addr, 0, len(ips))
+	for _, ip := range ips {
+		result = append(result, netip.MustParseAddr(ip))
+	}
+	return result
+}
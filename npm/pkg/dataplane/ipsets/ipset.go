@@ -388,6 +388,20 @@ func (set *IPSet) hasMember(memberName string) bool {
 	return isMember
 }
 
+// hasIP returns true if the IP is in the hash set or in one of the member sets of the list.
+func (set *IPSet) hasIP(ip string) bool {
+	if set.Kind == HashSet {
+		_, ok := set.IPPodKey[ip]
+		return ok
+	}
+	for _, memberSet := range set.MemberIPSets {
+		if _, ok := memberSet.IPPodKey[ip]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (set *IPSet) canSetBeSelectorIPSet() bool {
 	return (set.Type == KeyLabelOfPod ||
 		set.Type == KeyValueLabelOfPod ||
@@ -65,6 +65,10 @@ type IPSetManagerCfg struct {
 	// This is necessary for HNS (Windows); otherwise, an allow ACL with a list condition
 	// allows all IPs if the list has no members.
 	AddEmptySetToLists bool
+	// UseNFTables only affects Linux. It renders the sets into nftables sets instead of ipsets.
+	UseNFTables bool
+	// EnableIPv6 only affects Linux. It creates a family inet6 counterpart for each ipset and accepts IPv6 members.
+	EnableIPv6 bool
 }
 
 func NewIPSetManager(iMgrCfg *IPSetManagerCfg, ioShim *common.IOShim) *IPSetManager {
@@ -98,6 +102,15 @@ func (iMgr *IPSetManager) Reconcile() {
 	}
 }
 
+// ReconcileWithKernel repairs the ipsets in the kernel which drifted from the cache and returns the names of the repaired sets.
+// Sets with changes that haven't been applied yet are skipped.
+// It only verifies the ipsets in Linux.
+func (iMgr *IPSetManager) ReconcileWithKernel() ([]string, error) {
+	iMgr.Lock()
+	defer iMgr.Unlock()
+	return iMgr.reconcileWithKernel()
+}
+
 func (iMgr *IPSetManager) ResetIPSets() error {
 	iMgr.Lock()
 	defer iMgr.Unlock()
@@ -175,6 +188,15 @@ func (iMgr *IPSetManager) GetIPSet(name string) *IPSet {
 	return iMgr.setMap[name]
 }
 
+// ContainsIP returns true if the set exists and has the IP, or if it is a list, one of its member sets has the IP.
+// It needs the prefixed ipset name.
+func (iMgr *IPSetManager) ContainsIP(name, ip string) bool {
+	iMgr.RLock()
+	defer iMgr.RUnlock()
+	set, ok := iMgr.setMap[name]
+	return ok && set.hasIP(ip)
+}
+
 // AddReference creates the set if necessary and adds relevant reference
 // it throws an error if the set and reference type are an invalid combination
 func (iMgr *IPSetManager) AddReference(setMetadata *IPSetMetadata, referenceName string, referenceType ReferenceType) error {
@@ -244,7 +266,7 @@ func (iMgr *IPSetManager) AddToSets(addToSets []*IPSetMetadata, ip, podKey strin
 		return nil
 	}
 
-	if !validateIPSetMemberIP(ip) {
+	if !iMgr.validateMemberIP(ip) {
 		msg := fmt.Sprintf("error: failed to add to sets: invalid ip %s", ip)
 		metrics.SendErrorLogAndMetric(util.IpsmID, "%s", msg)
 		return npmerrors.Errorf(npmerrors.AppendIPSet, true, msg)
@@ -280,7 +302,7 @@ func (iMgr *IPSetManager) RemoveFromSets(removeFromSets []*IPSetMetadata, ip, po
 		return nil
 	}
 
-	if !validateIPSetMemberIP(ip) {
+	if !iMgr.validateMemberIP(ip) {
 		msg := fmt.Sprintf("error: failed to add to sets: invalid ip %s", ip)
 		metrics.SendErrorLogAndMetric(util.IpsmID, "%s", msg)
 		return npmerrors.Errorf(npmerrors.AppendIPSet, true, msg)
@@ -592,8 +614,22 @@ func (iMgr *IPSetManager) clearDirtyCache() {
 	iMgr.dirtyCache.reset()
 }
 
+// validateMemberIP accepts IPv6 members too if IPv6 is enabled
+func (iMgr *IPSetManager) validateMemberIP(ip string) bool {
+	return validateIPSetMemberIP(ip) || (iMgr.iMgrCfg.EnableIPv6 && IsIPv6Member(ip))
+}
+
 // validateIPSetMemberIP helps valid if a member added to an HashSet has valid IP or CIDR
 func validateIPSetMemberIP(ip string) bool {
+	return util.IsIPV4(memberIP(ip))
+}
+
+// IsIPv6Member returns true if a member of a HashSet has an IPv6 IP or CIDR
+func IsIPv6Member(ip string) bool {
+	return util.IsIPV6(memberIP(ip))
+}
+
+func memberIP(ip string) string {
 	// possible formats
 	// 192.168.0.1
 	// 192.168.0.1,tcp:25227
@@ -601,9 +637,9 @@ func validateIPSetMemberIP(ip string) bool {
 	// 192.168.0.0/24
 	// 192.168.0.0/24,tcp:25227
 	// 192.168.0.0/24 nomatch
+	// 2001:db8::1,tcp:25227
 	// always guaranteed to have ip, not guaranteed to have port + protocol
 	ipDetails := strings.Split(ip, ",")
 	ipField := strings.Split(ipDetails[0], " ")
-
-	return util.IsIPV4(ipField[0])
+	return ipField[0]
 }
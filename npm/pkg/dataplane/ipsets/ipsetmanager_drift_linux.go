@@ -0,0 +1,210 @@
+package ipsets
+
+// This file contains code for repairing NPM ipsets which drifted from the cache in the kernel.
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	"k8s.io/klog"
+)
+
+const driftSectionPrefix = "drift"
+
+// reconcileWithKernel compares the sets which should be in the kernel with ipset save.
+// It creates missing sets and adds/deletes the members of sets whose members differ from the cache.
+func (iMgr *IPSetManager) reconcileWithKernel() ([]string, error) {
+	if iMgr.iMgrCfg.UseNFTables {
+		// nftables sets aren't verified
+		return nil, nil
+	}
+
+	saveFile, err := iMgr.ipsetSave()
+	if err != nil {
+		return nil, npmerrors.SimpleErrorWrapper("failed to run ipset save while checking for drift", err)
+	}
+
+	creator, driftedSets := iMgr.fileCreatorForDrift(saveFile)
+	if len(driftedSets) == 0 {
+		return nil, nil
+	}
+
+	klog.Infof("[IPSetManager] repairing ipsets which drifted from the cache: %+v", driftedSets)
+	if err := creator.RunCommandWithFile(ipsetCommand, ipsetRestoreFlag); err != nil {
+		return driftedSets, npmerrors.SimpleErrorWrapper("ipset restore failed when repairing drifted ipsets", err)
+	}
+	return driftedSets, nil
+}
+
+// fileCreatorForDrift returns a restore file which repairs the sets that drifted from the cache and the names of those sets.
+// Sets in the dirty cache are skipped since the next apply updates them.
+func (iMgr *IPSetManager) fileCreatorForDrift(saveFile []byte) (*ioutil.FileCreator, []string) {
+	kernelSets := savedSetMembers(saveFile)
+
+	prefixedNames := make([]string, 0, len(iMgr.setMap))
+	for prefixedName := range iMgr.setMap {
+		prefixedNames = append(prefixedNames, prefixedName)
+	}
+	sort.Strings(prefixedNames)
+
+	creator := ioutil.NewFileCreator(iMgr.ioShim, maxTryCount, ipsetRestoreLineFailurePattern)
+	driftedSets := make([]string, 0)
+	var memberLines [][]string
+	for _, prefixedName := range prefixedNames {
+		set := iMgr.setMap[prefixedName]
+		if !iMgr.shouldBeInKernel(set) || iMgr.dirtyCache.isSetToAddOrUpdate(prefixedName) || iMgr.dirtyCache.isSetToDelete(prefixedName) {
+			continue
+		}
+
+		isMissing := false
+		var lines [][]string
+		expectedSets := iMgr.expectedKernelMembers(set)
+		for _, hashedSetName := range iMgr.kernelSetNames(set) {
+			expectedMembers := expectedSets[hashedSetName]
+			kernelMembers, ok := kernelSets[hashedSetName]
+			if !ok {
+				isMissing = true
+			}
+			for _, member := range sortedMembers(kernelMembers) {
+				if _, ok := expectedMembers[member]; !ok {
+					lines = append(lines, []string{ipsetDeleteFlag, hashedSetName, strings.TrimSuffix(kernelMembers[member], space+util.IpsetNomatch)})
+				}
+			}
+			for _, member := range sortedMembers(expectedMembers) {
+				if _, ok := kernelMembers[member]; !ok {
+					lines = append(lines, []string{ipsetAddFlag, hashedSetName, expectedMembers[member]})
+				}
+			}
+		}
+		if !isMissing && len(lines) == 0 {
+			continue
+		}
+
+		driftedSets = append(driftedSets, prefixedName)
+		if isMissing {
+			// create missing sets before any members are added so lists can reference them
+			iMgr.createSetForApply(creator, set)
+		}
+		memberLines = append(memberLines, lines...)
+	}
+
+	for _, line := range memberLines {
+		setName := line[1]
+		errorHandlers := []*ioutil.LineErrorHandler{
+			{
+				Definition: ioutil.AlwaysMatchDefinition,
+				Method:     ioutil.Continue,
+				Callback: func() {
+					metrics.SendErrorLogAndMetric(util.IpsmID, "skipping line to repair drifted set %s due to unknown error", setName)
+				},
+			},
+		}
+		creator.AddLine(sectionID(driftSectionPrefix, setName), errorHandlers, line...)
+	}
+	return creator, driftedSets
+}
+
+// kernelSetNames returns the hashed names of the set and, if IPv6 is enabled, its IPv6 counterpart.
+func (iMgr *IPSetManager) kernelSetNames(set *IPSet) []string {
+	if !iMgr.iMgrCfg.EnableIPv6 {
+		return []string{set.HashedName}
+	}
+	return []string{set.HashedName, util.GetIPv6HashedName(set.HashedName)}
+}
+
+// expectedKernelMembers maps the hashed name of each kernel set of the set to its members,
+// keyed by how ipset save prints them.
+func (iMgr *IPSetManager) expectedKernelMembers(set *IPSet) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for _, hashedSetName := range iMgr.kernelSetNames(set) {
+		result[hashedSetName] = make(map[string]string)
+	}
+
+	var members []string
+	if set.Kind == HashSet {
+		for ip := range set.IPPodKey {
+			members = append(members, ip)
+		}
+	} else {
+		for _, member := range set.MemberIPSets {
+			members = append(members, member.HashedName)
+		}
+	}
+	for _, member := range members {
+		for _, m := range iMgr.kernelMembers(set, "", member) {
+			result[m.hashedSetName][savedMember(m.member)] = m.member
+		}
+	}
+	return result
+}
+
+// savedSetMembers maps the hashed name of each set in an ipset save file to its members,
+// keyed by their normalized form.
+func savedSetMembers(saveFile []byte) map[string]map[string]string {
+	sets := make(map[string]map[string]string)
+	readIndex := 0
+	var line []byte
+	for readIndex < len(saveFile) {
+		line, readIndex = parse.Line(readIndex, saveFile)
+		// the last line may end with a newline
+		lineString := strings.TrimSpace(string(line))
+		switch {
+		case strings.HasPrefix(lineString, createStringWithSpace):
+			hashedName, _, _ := strings.Cut(lineString[len(createStringWithSpace):], space)
+			if _, ok := sets[hashedName]; !ok {
+				sets[hashedName] = make(map[string]string)
+			}
+		case strings.HasPrefix(lineString, addStringWithSpace):
+			hashedName, member, ok := strings.Cut(lineString[len(addStringWithSpace):], space)
+			if !ok {
+				continue
+			}
+			if _, ok := sets[hashedName]; !ok {
+				sets[hashedName] = make(map[string]string)
+			}
+			sets[hashedName][savedMember(member)] = member
+		}
+	}
+	return sets
+}
+
+// savedMember normalizes a member the way ipset save prints it.
+// For example, 10.0.0.1/32 is printed as 10.0.0.1 in a hash:net set.
+func savedMember(member string) string {
+	ipAndPort, nomatch, hasNomatch := strings.Cut(member, space)
+	ip, port, hasPort := strings.Cut(ipAndPort, ",")
+
+	if prefix, err := netip.ParsePrefix(ip); err == nil {
+		if prefix.IsSingleIP() {
+			ip = prefix.Addr().String()
+		} else {
+			ip = prefix.Masked().String()
+		}
+	} else if addr, err := netip.ParseAddr(ip); err == nil {
+		ip = addr.String()
+	}
+
+	if hasPort {
+		ip += "," + port
+	}
+	if hasNomatch {
+		ip += space + nomatch
+	}
+	return ip
+}
+
+// sortedMembers returns the normalized members in order.
+func sortedMembers(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
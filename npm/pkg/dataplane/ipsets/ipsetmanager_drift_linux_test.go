@@ -0,0 +1,76 @@
+package ipsets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCreatorForDrift(t *testing.T) {
+	iMgr := NewIPSetManager(applyAlwaysCfg, common.NewMockIOShim(nil))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.0", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "b"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKeyPodSet.Metadata}, "10.0.0.5", "c"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "1.2.3.4/32", ""))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata, TestKeyPodSet.Metadata}))
+	iMgr.clearDirtyCache()
+	// dirty sets are skipped
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestKVPodSet.Metadata}, "10.0.0.9", "d"))
+
+	saveFileLines := []string{
+		fmt.Sprintf(createNethashFormat, TestNSSet.HashedName),
+		fmt.Sprintf("add %s 10.0.0.0", TestNSSet.HashedName),
+		fmt.Sprintf("add %s 5.6.7.8", TestNSSet.HashedName), // delete this member
+		fmt.Sprintf(createNethashFormat, TestCIDRSet.HashedName),
+		fmt.Sprintf("add %s 1.2.3.4", TestCIDRSet.HashedName), // same as 1.2.3.4/32
+		fmt.Sprintf(createListFormat, TestKeyNSList.HashedName),
+		fmt.Sprintf("add %s %s", TestKeyNSList.HashedName, TestNSSet.HashedName),
+		fmt.Sprintf("add %s %s", TestKeyNSList.HashedName, TestKeyPodSet.HashedName),
+		"",
+	}
+	creator, driftedSets := iMgr.fileCreatorForDrift([]byte(strings.Join(saveFileLines, "\n")))
+	require.Equal(t, []string{TestNSSet.PrefixName, TestKeyPodSet.PrefixName}, driftedSets)
+
+	expectedLines := []string{
+		fmt.Sprintf("-N %s --exist nethash", TestKeyPodSet.HashedName),
+		fmt.Sprintf("-D %s 5.6.7.8", TestNSSet.HashedName),
+		fmt.Sprintf("-A %s 10.0.0.1", TestNSSet.HashedName),
+		fmt.Sprintf("-A %s 10.0.0.5", TestKeyPodSet.HashedName),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+}
+
+func TestReconcileWithKernel(t *testing.T) {
+	saveFile := strings.Join([]string{
+		fmt.Sprintf(createNethashFormat, TestNSSet.HashedName),
+		fmt.Sprintf("add %s 10.0.0.0", TestNSSet.HashedName),
+	}, "\n")
+	calls := []testutils.TestCmd{
+		// no drift
+		{Cmd: ipsetSaveStringSlice, PipedToCommand: true},
+		{Cmd: []string{"grep", "azure-npm-"}, Stdout: saveFile},
+		// the set was flushed
+		{Cmd: ipsetSaveStringSlice, PipedToCommand: true},
+		{Cmd: []string{"grep", "azure-npm-"}, Stdout: fmt.Sprintf(createNethashFormat, TestNSSet.HashedName)},
+		fakeRestoreSuccessCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(applyAlwaysCfg, ioshim)
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.0", "a"))
+	iMgr.clearDirtyCache()
+
+	driftedSets, err := iMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.Empty(t, driftedSets)
+
+	driftedSets, err = iMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.Equal(t, []string{TestNSSet.PrefixName}, driftedSets)
+}
@@ -39,6 +39,8 @@ const (
 	ipsetIPPortHashFlag = "hash:ip,port"
 	ipsetMaxelemName    = "maxelem"
 	ipsetMaxelemNum     = "4294967295"
+	ipsetFamilyFlag     = "family"
+	ipsetInet6Flag      = "inet6"
 
 	// constants for parsing ipset save
 	createStringWithSpace = "create "
@@ -101,6 +103,14 @@ prometheus metrics:
 	If a flush fails, we could update the num entries for that set, but that would be a lot of overhead.
 */
 func (iMgr *IPSetManager) resetIPSets() error {
+	if iMgr.iMgrCfg.UseNFTables {
+		return iMgr.resetNFTSets()
+	}
+	return iMgr.resetKernelIPSets()
+}
+
+// resetKernelIPSets flushes and destroys all NPM ipsets. See resetIPSets.
+func (iMgr *IPSetManager) resetKernelIPSets() error {
 	if success := iMgr.resetWithoutRestore(); success {
 		return nil
 	}
@@ -421,8 +431,13 @@ example where:
 		-X set4
 */
 func (iMgr *IPSetManager) applyIPSets() error {
-	creator := iMgr.fileCreatorForApply(maxTryCount)
-	restoreError := creator.RunCommandWithFile(ipsetCommand, ipsetRestoreFlag)
+	var restoreError error
+	if iMgr.iMgrCfg.UseNFTables {
+		restoreError = iMgr.applyNFTSets()
+	} else {
+		creator := iMgr.fileCreatorForApply(maxTryCount)
+		restoreError = creator.RunCommandWithFile(ipsetCommand, ipsetRestoreFlag)
+	}
 	if restoreError != nil {
 		iMgr.consecutiveApplyFailures++
 		if iMgr.consecutiveApplyFailures >= maxConsecutiveFailures {
@@ -731,6 +746,9 @@ func (iMgr *IPSetManager) flushSetForApply(creator *ioutil.FileCreator, prefixed
 	sectionID := sectionID(destroySectionPrefix, prefixedName)
 	hashedName := util.GetHashedName(prefixedName)
 	creator.AddLine(sectionID, errorHandlers, ipsetFlushFlag, hashedName) // flush set
+	if iMgr.iMgrCfg.EnableIPv6 {
+		creator.AddLine(ipv6SectionID(sectionID), errorHandlers, ipsetFlushFlag, util.GetIPv6HashedName(hashedName)) // flush IPv6 set
+	}
 }
 
 func (iMgr *IPSetManager) destroySetForApply(creator *ioutil.FileCreator, prefixedName string) {
@@ -754,6 +772,9 @@ func (iMgr *IPSetManager) destroySetForApply(creator *ioutil.FileCreator, prefix
 	sectionID := sectionID(destroySectionPrefix, prefixedName)
 	hashedName := util.GetHashedName(prefixedName)
 	creator.AddLine(sectionID, errorHandlers, ipsetDestroyFlag, hashedName) // destroy set
+	if iMgr.iMgrCfg.EnableIPv6 {
+		creator.AddLine(ipv6SectionID(sectionID), errorHandlers, ipsetDestroyFlag, util.GetIPv6HashedName(hashedName)) // destroy IPv6 set
+	}
 }
 
 func (iMgr *IPSetManager) createSetForApply(creator *ioutil.FileCreator, set *IPSet) {
@@ -790,6 +811,18 @@ func (iMgr *IPSetManager) createSetForApply(creator *ioutil.FileCreator, set *IP
 	}
 	sectionID := sectionID(addOrUpdateSectionPrefix, prefixedName)
 	creator.AddLine(sectionID, errorHandlers, specs...) // create set
+
+	if iMgr.iMgrCfg.EnableIPv6 {
+		// the IPv6 set is in its own section so that a failure for one family doesn't abort the other
+		specs = []string{ipsetCreateFlag, util.GetIPv6HashedName(set.HashedName), ipsetExistFlag, methodFlag}
+		if set.Kind == HashSet {
+			specs = append(specs, ipsetFamilyFlag, ipsetInet6Flag)
+		}
+		if set.Type == CIDRBlocks {
+			specs = append(specs, ipsetMaxelemName, ipsetMaxelemNum)
+		}
+		creator.AddLine(ipv6SectionID(sectionID), errorHandlers, specs...) // create IPv6 set
+	}
 }
 
 func (iMgr *IPSetManager) deleteMemberForApply(creator *ioutil.FileCreator, set *IPSet, sectionID, member string) {
@@ -813,7 +846,9 @@ func (iMgr *IPSetManager) deleteMemberForApply(creator *ioutil.FileCreator, set
 		member = splitMember[0]
 	}
 
-	creator.AddLine(sectionID, errorHandlers, ipsetDeleteFlag, set.HashedName, member) // delete member
+	for _, m := range iMgr.kernelMembers(set, sectionID, member) {
+		creator.AddLine(m.sectionID, errorHandlers, ipsetDeleteFlag, m.hashedSetName, m.member) // delete member
+	}
 }
 
 func (iMgr *IPSetManager) addMemberForApply(creator *ioutil.FileCreator, set *IPSet, sectionID, member string) {
@@ -847,13 +882,46 @@ func (iMgr *IPSetManager) addMemberForApply(creator *ioutil.FileCreator, set *IP
 			},
 		}
 	}
-	creator.AddLine(sectionID, errorHandlers, ipsetAddFlag, set.HashedName, member) // add member
+	for _, m := range iMgr.kernelMembers(set, sectionID, member) {
+		creator.AddLine(m.sectionID, errorHandlers, ipsetAddFlag, m.hashedSetName, m.member) // add member
+	}
+}
+
+// kernelMember is a member of the set or the set's IPv6 counterpart in the kernel
+type kernelMember struct {
+	sectionID     string
+	hashedSetName string
+	member        string
+}
+
+// kernelMembers routes a member of the set to the kernel sets of the right family.
+// If IPv6 is enabled, IPv6 IPs belong to the IPv6 counterpart of a hash set,
+// and a list's IPv6 counterpart has the IPv6 counterparts of the list's member sets.
+func (iMgr *IPSetManager) kernelMembers(set *IPSet, sectionID, member string) []kernelMember {
+	ipv4Member := kernelMember{sectionID: sectionID, hashedSetName: set.HashedName, member: member}
+	if !iMgr.iMgrCfg.EnableIPv6 {
+		return []kernelMember{ipv4Member}
+	}
+
+	ipv6Member := kernelMember{sectionID: ipv6SectionID(sectionID), hashedSetName: util.GetIPv6HashedName(set.HashedName), member: member}
+	if set.Kind == ListSet {
+		ipv6Member.member = util.GetIPv6HashedName(member)
+		return []kernelMember{ipv4Member, ipv6Member}
+	}
+	if IsIPv6Member(member) {
+		return []kernelMember{ipv6Member}
+	}
+	return []kernelMember{ipv4Member}
 }
 
 func sectionID(prefix, prefixedName string) string {
 	return fmt.Sprintf("%s-%s", prefix, prefixedName)
 }
 
+func ipv6SectionID(sectionID string) string {
+	return sectionID + util.IPv6SetNameSuffix
+}
+
 func readByteLinesToMap(output []byte) map[string]struct{} {
 	readIndex := 0
 	var line []byte
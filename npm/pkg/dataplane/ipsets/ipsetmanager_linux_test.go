@@ -1031,6 +1031,52 @@ func TestCidrExceptMembers(t *testing.T) {
 	require.False(t, wasFileAltered, "file should not be altered")
 }
 
+func TestIPv6Sets(t *testing.T) {
+	calls := []testutils.TestCmd{
+		fakeRestoreSuccessCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(&IPSetManagerCfg{IPSetMode: ApplyAllIPSets, NetworkName: "azure", EnableIPv6: true}, ioshim)
+
+	iMgr.CreateIPSets([]*IPSetMetadata{TestCIDRSet.Metadata}) // create so we can delete
+	// clear dirty cache, otherwise a set deletion will be a no-op
+	iMgr.clearDirtyCache()
+
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "fd00::1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "fd00::1,tcp:8080", "a"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata}))
+	iMgr.DeleteIPSet(TestCIDRSet.PrefixName, util.SoftDelete)
+
+	ipv6Name := util.GetIPv6HashedName
+	expectedLines := []string{
+		fmt.Sprintf("-N %s --exist nethash", TestNSSet.HashedName),
+		fmt.Sprintf("-N %s --exist nethash family inet6", ipv6Name(TestNSSet.HashedName)),
+		fmt.Sprintf("-N %s --exist hash:ip,port", TestNamedportSet.HashedName),
+		fmt.Sprintf("-N %s --exist hash:ip,port family inet6", ipv6Name(TestNamedportSet.HashedName)),
+		fmt.Sprintf("-N %s --exist setlist", TestKeyNSList.HashedName),
+		fmt.Sprintf("-N %s --exist setlist", ipv6Name(TestKeyNSList.HashedName)),
+		fmt.Sprintf("-A %s 10.0.0.1", TestNSSet.HashedName),
+		fmt.Sprintf("-A %s fd00::1", ipv6Name(TestNSSet.HashedName)),
+		fmt.Sprintf("-A %s fd00::1,tcp:8080", ipv6Name(TestNamedportSet.HashedName)),
+		fmt.Sprintf("-A %s %s", TestKeyNSList.HashedName, TestNSSet.HashedName),
+		fmt.Sprintf("-A %s %s", ipv6Name(TestKeyNSList.HashedName), ipv6Name(TestNSSet.HashedName)),
+		fmt.Sprintf("-F %s", TestCIDRSet.HashedName),
+		fmt.Sprintf("-F %s", ipv6Name(TestCIDRSet.HashedName)),
+		fmt.Sprintf("-X %s", TestCIDRSet.HashedName),
+		fmt.Sprintf("-X %s", ipv6Name(TestCIDRSet.HashedName)),
+		"",
+	}
+	sortedExpectedLines := testAndSortRestoreFileLines(t, expectedLines)
+	creator := iMgr.fileCreatorForApply(len(calls))
+	actualLines := testAndSortRestoreFileString(t, creator.ToString())
+	dptestutils.AssertEqualLines(t, sortedExpectedLines, actualLines)
+	wasFileAltered, err := creator.RunCommandOnceWithFile("ipset", "restore")
+	require.NoError(t, err, "ipset restore should be successful")
+	require.False(t, wasFileAltered, "file should not be altered")
+}
+
 func TestUpdateWithIdenticalSaveFile(t *testing.T) {
 	calls := []testutils.TestCmd{fakeRestoreSuccessCommand}
 	ioshim := common.NewMockIOShim(calls)
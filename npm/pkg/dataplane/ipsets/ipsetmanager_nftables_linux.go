@@ -0,0 +1,306 @@
+package ipsets
+
+// This file contains code for the nftables implementation of applying sets.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	"k8s.io/klog"
+)
+
+const (
+	// nft applies the whole file in one transaction, so there is nothing to salvage from a failed try
+	nftMaxTryCount = 2
+
+	nftIPSetSpec        = "{ type ipv4_addr ; }"
+	nftCIDRSetSpec      = "{ type ipv4_addr ; flags interval ; auto-merge ; }"
+	nftNamedPortSetSpec = "{ type ipv4_addr . inet_proto . inet_service ; }"
+
+	ipv4Bits = 32
+)
+
+var nftTable = util.NftFamily + " " + util.NftTable
+
+/*
+nftables sets are rendered from the IPSetManager cache like so:
+  - hash sets become sets of ipv4 addresses
+  - CIDRBlocks sets become interval sets. nftables has no nomatch elements, so nomatch CIDRs are subtracted from the intervals.
+  - NamedPorts sets become sets of ipv4_addr . inet_proto . inet_service
+  - list sets are flattened since nftables sets can't hold other sets. A list set holds the members of its member sets
+    and is rendered again whenever one of its member sets changes.
+
+Each dirty set is rendered in full (flush the set, then add all of its elements) and all sets are applied in one nft
+transaction, so either the whole batch is applied or none of it.
+
+example file:
+
+	add table inet azure-npm
+	add set inet azure-npm azure-npm-123 { type ipv4_addr ; }
+	flush set inet azure-npm azure-npm-123
+	add element inet azure-npm azure-npm-123 { 10.0.0.4, 10.0.0.5 }
+	add set inet azure-npm azure-npm-456 { type ipv4_addr ; }
+	delete set inet azure-npm azure-npm-456
+*/
+func (iMgr *IPSetManager) applyNFTSets() error {
+	creator := iMgr.nftCreatorForApply()
+	return creator.RunCommandWithFile(util.Nft, util.NftFileFlag, util.NftStdin) //nolint:wrapcheck // wrapped by applyIPSets
+}
+
+func (iMgr *IPSetManager) nftCreatorForApply() *ioutil.FileCreator {
+	creator := ioutil.NewFileCreator(iMgr.ioShim, nftMaxTryCount)
+	creator.AddLine("", nil, "add table", nftTable)
+
+	// 1. render the dirty sets and the list sets containing them
+	setsToRender := make(map[string]struct{})
+	for prefixedName := range iMgr.dirtyCache.setsToAddOrUpdate() {
+		setsToRender[prefixedName] = struct{}{}
+	}
+	for prefixedName, set := range iMgr.setMap {
+		if set.Kind != ListSet || iMgr.dirtyCache.isSetToDelete(prefixedName) || !iMgr.isListInNFT(set) {
+			continue
+		}
+		for memberName := range set.MemberIPSets {
+			if _, ok := setsToRender[memberName]; ok {
+				setsToRender[prefixedName] = struct{}{}
+				break
+			}
+		}
+	}
+	for _, prefixedName := range sortedKeys(setsToRender) {
+		set, ok := iMgr.setMap[prefixedName]
+		if !ok {
+			continue
+		}
+		iMgr.renderNFTSet(creator, set)
+	}
+
+	// 2. delete sets. Declaring the set first makes the delete succeed even if the set isn't in the kernel.
+	for _, prefixedName := range sortedKeys(iMgr.dirtyCache.setsToDelete()) {
+		hashedName := util.GetHashedName(prefixedName)
+		creator.AddLine("", nil, "add set", nftTable, hashedName, nftSetSpec(prefixedName))
+		creator.AddLine("", nil, "delete set", nftTable, hashedName)
+	}
+	return creator
+}
+
+func (iMgr *IPSetManager) isListInNFT(set *IPSet) bool {
+	return iMgr.iMgrCfg.IPSetMode == ApplyAllIPSets || set.shouldBeInKernel()
+}
+
+func (iMgr *IPSetManager) renderNFTSet(creator *ioutil.FileCreator, set *IPSet) {
+	creator.AddLine("", nil, "add set", nftTable, set.HashedName, nftSetSpec(set.Name))
+	creator.AddLine("", nil, "flush set", nftTable, set.HashedName)
+	elements := nftElements(set)
+	if len(elements) > 0 {
+		creator.AddLine("", nil, "add element", nftTable, set.HashedName, "{", strings.Join(elements, ", "), "}")
+	}
+}
+
+// nftSetSpec returns the set type based on the prefix of the set name, so that sets can be declared when only their
+// name is known.
+func nftSetSpec(prefixedName string) string {
+	switch {
+	case strings.HasPrefix(prefixedName, util.NamedPortIPSetPrefix):
+		return nftNamedPortSetSpec
+	case strings.HasPrefix(prefixedName, util.CIDRPrefix):
+		return nftCIDRSetSpec
+	default:
+		return nftIPSetSpec
+	}
+}
+
+// nftElements returns the sorted elements of the set in nft syntax.
+func nftElements(set *IPSet) []string {
+	switch {
+	case set.Kind == ListSet:
+		elements := make(map[string]struct{})
+		for _, member := range set.MemberIPSets {
+			for ip := range member.IPPodKey {
+				elements[ip] = struct{}{}
+			}
+		}
+		return sortedKeys(elements)
+	case set.Type == CIDRBlocks:
+		return nftIntervalElements(set)
+	case set.Type == NamedPorts:
+		elements := make([]string, 0, len(set.IPPodKey))
+		for member := range set.IPPodKey {
+			element, err := nftNamedPortElement(member)
+			if err != nil {
+				metrics.SendErrorLogAndMetric(util.IpsmID, "error: skipping member of set %s: %s", set.Name, err.Error())
+				continue
+			}
+			elements = append(elements, element)
+		}
+		sort.Strings(elements)
+		return elements
+	default:
+		elements := make(map[string]struct{}, len(set.IPPodKey))
+		for ip := range set.IPPodKey {
+			elements[ip] = struct{}{}
+		}
+		return sortedKeys(elements)
+	}
+}
+
+// nftNamedPortElement converts an ipset member like 10.0.0.4,tcp:8080 to 10.0.0.4 . tcp . 8080
+func nftNamedPortElement(member string) (string, error) {
+	ip, protocolPort, ok := strings.Cut(member, ",")
+	if !ok {
+		return "", npmerrors.SimpleError(fmt.Sprintf("named port member %s has no protocol and port", member))
+	}
+	protocol, port, ok := strings.Cut(protocolPort, ":")
+	if !ok {
+		return "", npmerrors.SimpleError(fmt.Sprintf("named port member %s has no protocol", member))
+	}
+	return fmt.Sprintf("%s . %s . %s", ip, protocol, port), nil
+}
+
+// ipv4Interval is an inclusive range of ipv4 addresses. uint64 avoids overflow at 255.255.255.255.
+type ipv4Interval struct {
+	start uint64
+	end   uint64
+}
+
+type cidrMember struct {
+	interval  ipv4Interval
+	prefixLen int
+	nomatch   bool
+}
+
+// nftIntervalElements returns the ranges matched by the CIDRs of the set.
+// ipset matches the most specific CIDR, so the CIDRs are applied from the least to the most specific, and nomatch
+// CIDRs are subtracted from the ranges matched so far.
+func nftIntervalElements(set *IPSet) []string {
+	members := make([]cidrMember, 0, len(set.IPPodKey))
+	for member := range set.IPPodKey {
+		cidr, option, _ := strings.Cut(member, " ")
+		parsed, err := parseCIDRMember(cidr)
+		if err != nil {
+			metrics.SendErrorLogAndMetric(util.IpsmID, "error: skipping member of set %s: %s", set.Name, err.Error())
+			continue
+		}
+		parsed.nomatch = option == util.IpsetNomatch
+		members = append(members, parsed)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].prefixLen != members[j].prefixLen {
+			return members[i].prefixLen < members[j].prefixLen
+		}
+		// the match wins over a nomatch of the same CIDR
+		return members[i].nomatch && !members[j].nomatch
+	})
+
+	var intervals []ipv4Interval
+	for _, member := range members {
+		if member.nomatch {
+			intervals = subtractInterval(intervals, member.interval)
+		} else {
+			intervals = addInterval(intervals, member.interval)
+		}
+	}
+
+	elements := make([]string, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.start == interval.end {
+			elements = append(elements, uint64ToIPv4(interval.start))
+		} else {
+			elements = append(elements, uint64ToIPv4(interval.start)+"-"+uint64ToIPv4(interval.end))
+		}
+	}
+	return elements
+}
+
+func parseCIDRMember(cidr string) (cidrMember, error) {
+	if !strings.Contains(cidr, "/") {
+		cidr += "/" + strconv.Itoa(ipv4Bits)
+	}
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ipNet.IP.To4() == nil {
+		return cidrMember{}, npmerrors.SimpleError("invalid ipv4 CIDR " + cidr)
+	}
+	prefixLen, _ := ipNet.Mask.Size()
+	start := uint64(binary.BigEndian.Uint32(ipNet.IP.To4()))
+	size := uint64(1) << (ipv4Bits - prefixLen)
+	return cidrMember{
+		interval:  ipv4Interval{start: start, end: start + size - 1},
+		prefixLen: prefixLen,
+	}, nil
+}
+
+// addInterval adds the interval to the sorted, disjoint intervals and merges overlapping and adjacent intervals.
+func addInterval(intervals []ipv4Interval, toAdd ipv4Interval) []ipv4Interval {
+	result := make([]ipv4Interval, 0, len(intervals)+1)
+	for _, interval := range intervals {
+		switch {
+		case interval.end+1 < toAdd.start:
+			result = append(result, interval)
+		case toAdd.end+1 < interval.start:
+			result = append(result, toAdd)
+			toAdd = interval
+		default:
+			toAdd.start = min(toAdd.start, interval.start)
+			toAdd.end = max(toAdd.end, interval.end)
+		}
+	}
+	return append(result, toAdd)
+}
+
+// subtractInterval removes the interval from the sorted, disjoint intervals.
+func subtractInterval(intervals []ipv4Interval, toRemove ipv4Interval) []ipv4Interval {
+	result := make([]ipv4Interval, 0, len(intervals)+1)
+	for _, interval := range intervals {
+		if interval.end < toRemove.start || toRemove.end < interval.start {
+			result = append(result, interval)
+			continue
+		}
+		if interval.start < toRemove.start {
+			result = append(result, ipv4Interval{start: interval.start, end: toRemove.start - 1})
+		}
+		if toRemove.end < interval.end {
+			result = append(result, ipv4Interval{start: toRemove.end + 1, end: interval.end})
+		}
+	}
+	return result
+}
+
+func uint64ToIPv4(ip uint64) string {
+	b := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(b, uint32(ip))
+	return b.String()
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resetNFTSets creates the NPM table if it doesn't exist.
+// The PolicyManager recreates the table at bootup, which deletes all nftables sets, so only ipsets left behind by the
+// iptables dataplane need to be cleaned up.
+func (iMgr *IPSetManager) resetNFTSets() error {
+	if err := iMgr.resetKernelIPSets(); err != nil {
+		// leftover ipsets aren't referenced by NPM anymore, so they only take up memory
+		klog.Warningf("failed to clean up ipsets left behind by the iptables dataplane. err: %v", err)
+	}
+
+	creator := ioutil.NewFileCreator(iMgr.ioShim, nftMaxTryCount)
+	creator.AddLine("", nil, "add table", nftTable)
+	if err := creator.RunCommandWithFile(util.Nft, util.NftFileFlag, util.NftStdin); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to create nftables table", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package ipsets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	nftCfg = &IPSetManagerCfg{
+		IPSetMode:   ApplyAllIPSets,
+		NetworkName: "azure",
+		UseNFTables: true,
+	}
+
+	fakeNFTCommand = testutils.TestCmd{Cmd: []string{"nft", "-f", "-"}}
+)
+
+func TestNFTCreatorForApply(t *testing.T) {
+	calls := []testutils.TestCmd{fakeNFTCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+
+	// 1. add and update sets
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata}, "10.0.0.5", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNSSet.Metadata, TestKeyPodSet.Metadata}, "10.0.0.4", "b"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "10.0.0.0/24", ""))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestCIDRSet.Metadata}, "10.0.0.16/28 nomatch", ""))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{TestNamedportSet.Metadata}, "10.0.0.4,tcp:8080", "b"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{TestKeyNSList.Metadata}, []*IPSetMetadata{TestNSSet.Metadata, TestKeyPodSet.Metadata}))
+
+	creator := iMgr.nftCreatorForApply()
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"add table inet azure-npm",
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestCIDRSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.0-10.0.0.15, 10.0.0.32-10.0.0.255 }", TestCIDRSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr . inet_proto . inet_service ; }", TestNamedportSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestNamedportSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.4 . tcp . 8080 }", TestNamedportSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestNSSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestNSSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.4, 10.0.0.5 }", TestNSSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKeyNSList.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKeyNSList.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.4, 10.0.0.5 }", TestKeyNSList.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKeyPodSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKeyPodSet.HashedName),
+		fmt.Sprintf("add element inet azure-npm %s { 10.0.0.4 }", TestKeyPodSet.HashedName),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+	require.NoError(t, iMgr.ApplyIPSets())
+
+	// 2. a member update renders the set and the lists containing it, and deleted sets are removed
+	require.NoError(t, iMgr.RemoveFromSets([]*IPSetMetadata{TestKeyPodSet.Metadata}, "10.0.0.4", "b"))
+	require.NoError(t, iMgr.RemoveFromList(TestKeyNSList.Metadata, []*IPSetMetadata{TestNSSet.Metadata}))
+	iMgr.DeleteIPSet(TestCIDRSet.PrefixName, util.ForceDelete)
+
+	creator = iMgr.nftCreatorForApply()
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{
+		"add table inet azure-npm",
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKeyNSList.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKeyNSList.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; }", TestKeyPodSet.HashedName),
+		fmt.Sprintf("flush set inet azure-npm %s", TestKeyPodSet.HashedName),
+		fmt.Sprintf("add set inet azure-npm %s { type ipv4_addr ; flags interval ; auto-merge ; }", TestCIDRSet.HashedName),
+		fmt.Sprintf("delete set inet azure-npm %s", TestCIDRSet.HashedName),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestApplyNFTSetsFailure(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"nft", "-f", "-"}, ExitCode: 1},
+		{Cmd: []string{"nft", "-f", "-"}, ExitCode: 1},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	iMgr := NewIPSetManager(nftCfg, ioshim)
+
+	iMgr.CreateIPSets([]*IPSetMetadata{TestNSSet.Metadata})
+	require.Error(t, iMgr.ApplyIPSets())
+}
+
+func TestNFTIntervalElements(t *testing.T) {
+	tests := []struct {
+		name     string
+		members  []string
+		expected []string
+	}{
+		{
+			name:     "single ip",
+			members:  []string{"10.0.0.1"},
+			expected: []string{"10.0.0.1"},
+		},
+		{
+			name:     "adjacent cidrs are merged",
+			members:  []string{"10.0.0.0/25", "10.0.0.128/25"},
+			expected: []string{"10.0.0.0-10.0.0.255"},
+		},
+		{
+			name:     "nomatch cidr is subtracted",
+			members:  []string{"10.0.0.0/24", "10.0.0.0/25 nomatch"},
+			expected: []string{"10.0.0.128-10.0.0.255"},
+		},
+		{
+			name:     "more specific cidr wins over nomatch",
+			members:  []string{"10.0.0.0/16", "10.0.1.0/24 nomatch", "10.0.1.5/32"},
+			expected: []string{"10.0.0.0-10.0.0.255", "10.0.1.5", "10.0.2.0-10.0.255.255"},
+		},
+		{
+			name:     "match wins over nomatch of the same cidr",
+			members:  []string{"10.0.0.0/24 nomatch", "10.0.0.0/24"},
+			expected: []string{"10.0.0.0-10.0.0.255"},
+		},
+		{
+			name:     "whole address space",
+			members:  []string{"0.0.0.0/0", "255.255.255.255 nomatch"},
+			expected: []string{"0.0.0.0-255.255.255.254"},
+		},
+		{
+			name:     "invalid members are skipped",
+			members:  []string{"10.0.0.0/33", "abc", "10.0.0.1"},
+			expected: []string{"10.0.0.1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			set := NewIPSet(TestCIDRSet.Metadata)
+			for _, member := range tt.members {
+				set.IPPodKey[member] = ""
+			}
+			require.Equal(t, tt.expected, nftIntervalElements(set))
+		})
+	}
+}
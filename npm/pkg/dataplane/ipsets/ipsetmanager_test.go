@@ -703,6 +703,24 @@ func TestAddToSets(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "add IPv6 with IPv6 enabled",
+			args: args{
+				cfg:               &IPSetManagerCfg{IPSetMode: ApplyAllIPSets, NetworkName: "azure", EnableIPv6: true},
+				toCreateMetadatas: []*IPSetMetadata{namespaceSet},
+				toAddMetadatas:    []*IPSetMetadata{namespaceSet},
+				member:            ipv6,
+			},
+			expectedInfo: expectedInfo{
+				mainCache: []setMembers{
+					{metadata: namespaceSet, members: []member{{ipv6, isHashMember}}},
+				},
+				toAddUpdateCache: []*IPSetMetadata{namespaceSet},
+				toDeleteCache:    nil,
+				setsForKernel:    []*IPSetMetadata{namespaceSet},
+			},
+			wantErr: false,
+		},
 		{
 			name: "add cidr",
 			args: args{
@@ -1120,6 +1138,19 @@ func TestRemoveFromListMissing(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestContainsIP(t *testing.T) {
+	iMgr := NewIPSetManager(applyOnNeedCfg, common.NewMockIOShim([]testutils.TestCmd{}))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{namespaceSet}, "10.0.0.1", "a"))
+	require.NoError(t, iMgr.AddToSets([]*IPSetMetadata{keyLabelOfPodSet}, "10.0.0.2", "b"))
+	require.NoError(t, iMgr.AddToLists([]*IPSetMetadata{list}, []*IPSetMetadata{keyLabelOfPodSet}))
+
+	require.True(t, iMgr.ContainsIP(namespaceSet.GetPrefixName(), "10.0.0.1"))
+	require.False(t, iMgr.ContainsIP(namespaceSet.GetPrefixName(), "10.0.0.2"))
+	require.True(t, iMgr.ContainsIP(list.GetPrefixName(), "10.0.0.2"))
+	require.False(t, iMgr.ContainsIP(list.GetPrefixName(), "10.0.0.1"))
+	require.False(t, iMgr.ContainsIP("missing", "10.0.0.1"))
+}
+
 func TestAddReference(t *testing.T) {
 	ref0 := "ref0" // for alreadyReferenced
 	ref1 := "ref1"
@@ -195,6 +195,12 @@ func (iMgr *IPSetManager) resetIPSets() error {
 	return nil
 }
 
+// reconcileWithKernel doesn't verify the SetPolicies in HNS.
+// The PolicyManager verifies the ACLs on each endpoint instead.
+func (iMgr *IPSetManager) reconcileWithKernel() ([]string, error) {
+	return nil, nil
+}
+
 func (iMgr *IPSetManager) applyIPSets() error {
 	network, err := iMgr.getHCnNetwork()
 	if err != nil {
@@ -0,0 +1,7 @@
+// Package nflog reads the packets which the Linux dataplane sends to an NFLOG group.
+// Denied-flow logging and FQDN egress rules both read from their own group.
+package nflog
+
+// Handler is called with the null-terminated prefix of the NFLOG rule and the packet it logged,
+// starting at the network header. The packet is only valid until the Handler returns.
+type Handler func(prefix string, packet []byte)
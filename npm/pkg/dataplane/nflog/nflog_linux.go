@@ -0,0 +1,189 @@
+package nflog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog"
+)
+
+// nfnetlink_log constants from linux/netfilter/nfnetlink_log.h
+const (
+	nfnlSubsysULog   = 4
+	nfulnlMsgPacket  = 0
+	nfulnlMsgConfig  = 1
+	nfulaCfgCmd      = 1
+	nfulaCfgMode     = 2
+	nfulaPayload     = 9
+	nfulaPrefix      = 10
+	nfulnlCfgCmdBind = 1
+	nfulnlCopyPacket = 2
+
+	nfgenmsgLength  = 4
+	nlaHeaderLength = 4
+	nlaTypeMask     = 0x3fff
+
+	receiveBuffer = 65536
+	// receiveTimeout bounds how long Read takes to notice that it should stop
+	receiveTimeout = time.Second
+)
+
+var errNetlinkAck = errors.New("nfnetlink_log rejected the config")
+
+// Read binds to the NFLOG group and passes its packets to handle until stopCh is closed.
+// Only the first copyRange bytes of each packet are copied from the kernel.
+// It returns an error if it can't bind to the group, e.g. because another process is bound to it.
+func Read(group, copyRange int, stopCh <-chan struct{}, handle Handler) error {
+	fd, err := bind(group, copyRange)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	buffer := make([]byte, receiveBuffer)
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
+
+		n, _, err := unix.Recvfrom(fd, buffer, 0)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if errors.Is(err, unix.ENOBUFS) {
+				// the kernel dropped messages because we didn't read fast enough
+				klog.Warningf("[nflog] some packets in NFLOG group %d weren't read. err: %v", group, err)
+				continue
+			}
+			return fmt.Errorf("failed to receive from NFLOG group %d: %w", group, err)
+		}
+		handleMessages(buffer[:n], handle)
+	}
+}
+
+func bind(group, copyRange int) (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create netfilter netlink socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to bind netfilter netlink socket: %w", err)
+	}
+
+	cmd := []byte{nfulnlCfgCmdBind}
+	mode := make([]byte, 6) //nolint:gomnd // struct nfulnl_msg_config_mode
+	binary.BigEndian.PutUint32(mode[0:4], uint32(copyRange))
+	mode[4] = nfulnlCopyPacket
+	for seq, attr := range [][]byte{netlinkAttr(nfulaCfgCmd, cmd), netlinkAttr(nfulaCfgMode, mode)} {
+		if err := sendConfig(fd, group, uint32(seq+1), attr); err != nil {
+			unix.Close(fd)
+			return -1, err
+		}
+	}
+
+	tv := unix.NsecToTimeval(receiveTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to set receive timeout: %w", err)
+	}
+	return fd, nil
+}
+
+// sendConfig sends a config message for the group and waits for its ack.
+func sendConfig(fd, group int, seq uint32, attr []byte) error {
+	msg := make([]byte, unix.NLMSG_HDRLEN+nfgenmsgLength, unix.NLMSG_HDRLEN+nfgenmsgLength+len(attr))
+	msg = append(msg, attr...)
+	binary.NativeEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:6], nfnlSubsysULog<<8|nfulnlMsgConfig)
+	binary.NativeEndian.PutUint16(msg[6:8], unix.NLM_F_REQUEST|unix.NLM_F_ACK)
+	binary.NativeEndian.PutUint32(msg[8:12], seq)
+	// nfgenmsg: family AF_UNSPEC, version 0, and the group as res_id
+	binary.BigEndian.PutUint16(msg[unix.NLMSG_HDRLEN+2:], uint16(group))
+
+	if err := unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to configure NFLOG group %d: %w", group, err)
+	}
+
+	buffer := make([]byte, unix.Getpagesize())
+	n, _, err := unix.Recvfrom(fd, buffer, 0)
+	if err != nil {
+		return fmt.Errorf("failed to receive ack for NFLOG group %d: %w", group, err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(buffer[:n])
+	if err != nil {
+		return fmt.Errorf("failed to parse ack for NFLOG group %d: %w", group, err)
+	}
+	for _, m := range msgs {
+		if m.Header.Type != unix.NLMSG_ERROR || m.Header.Seq != seq || len(m.Data) < 4 {
+			continue
+		}
+		if errCode := int32(binary.NativeEndian.Uint32(m.Data[0:4])); errCode != 0 {
+			return fmt.Errorf("%w for NFLOG group %d: %w", errNetlinkAck, group, syscall.Errno(-errCode))
+		}
+		return nil
+	}
+	return fmt.Errorf("%w for NFLOG group %d: no ack", errNetlinkAck, group)
+}
+
+func handleMessages(data []byte, handle Handler) {
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		klog.Errorf("[nflog] failed to parse netlink messages. err: %v", err)
+		return
+	}
+	for _, m := range msgs {
+		if m.Header.Type != nfnlSubsysULog<<8|nfulnlMsgPacket {
+			continue
+		}
+		handle(parsePacketMessage(m.Data))
+	}
+}
+
+// parsePacketMessage returns the prefix and payload attributes of an NFULNL_MSG_PACKET message after its netlink header.
+func parsePacketMessage(data []byte) (prefix string, packet []byte) {
+	if len(data) < nfgenmsgLength {
+		return "", nil
+	}
+	attrs := data[nfgenmsgLength:]
+	for len(attrs) >= nlaHeaderLength {
+		attrLength := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		attrType := binary.NativeEndian.Uint16(attrs[2:4]) & nlaTypeMask
+		if attrLength < nlaHeaderLength || attrLength > len(attrs) {
+			break
+		}
+		value := attrs[nlaHeaderLength:attrLength]
+		switch attrType {
+		case nfulaPrefix:
+			prefix = string(value)
+		case nfulaPayload:
+			packet = value
+		}
+		aligned := nlaAlign(attrLength)
+		if aligned > len(attrs) {
+			break
+		}
+		attrs = attrs[aligned:]
+	}
+	return prefix, packet
+}
+
+func netlinkAttr(attrType uint16, value []byte) []byte {
+	attrLength := nlaHeaderLength + len(value)
+	attr := make([]byte, nlaAlign(attrLength))
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(attrLength))
+	binary.NativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[nlaHeaderLength:], value)
+	return attr
+}
+
+func nlaAlign(length int) int {
+	return (length + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
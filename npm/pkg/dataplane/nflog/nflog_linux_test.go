@@ -0,0 +1,43 @@
+package nflog
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePacketMessage(t *testing.T) {
+	packet := []byte{0x45, 0, 0, 24, 0, 0, 0, 0, 64, 6}
+	hwProtocol := make([]byte, 4)
+	binary.BigEndian.PutUint16(hwProtocol[0:2], 0x0800)
+
+	// nfgenmsg followed by a packet header, payload, and null-terminated prefix (unaligned length)
+	data := []byte{2, 0, 0, 100}
+	data = append(data, netlinkAttr(1, hwProtocol)...)
+	data = append(data, netlinkAttr(nfulaPayload, packet)...)
+	data = append(data, netlinkAttr(nfulaPrefix, []byte("NPM-DROP-IN:1234567\x00"))...)
+
+	prefix, payload := parsePacketMessage(data)
+	require.Equal(t, "NPM-DROP-IN:1234567\x00", prefix)
+	require.Equal(t, packet, payload)
+
+	// the byte order flag of an attribute's type is ignored
+	flagged := netlinkAttr(nfulaPayload, packet)
+	binary.NativeEndian.PutUint16(flagged[2:4], nfulaPayload|0x4000)
+	_, payload = parsePacketMessage(append([]byte{2, 0, 0, 100}, flagged...))
+	require.Equal(t, packet, payload)
+
+	// truncated attributes are ignored
+	prefix, payload = parsePacketMessage(data[:len(data)-4])
+	require.Empty(t, prefix)
+	require.Equal(t, packet, payload)
+}
+
+func TestNetlinkAttr(t *testing.T) {
+	attr := netlinkAttr(nfulaCfgCmd, []byte{nfulnlCfgCmdBind})
+	require.Len(t, attr, 8)
+	require.Equal(t, uint16(5), binary.NativeEndian.Uint16(attr[0:2]))
+	require.Equal(t, uint16(nfulaCfgCmd), binary.NativeEndian.Uint16(attr[2:4]))
+	require.Equal(t, byte(nfulnlCfgCmdBind), attr[4])
+}
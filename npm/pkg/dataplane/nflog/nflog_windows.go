@@ -0,0 +1,10 @@
+package nflog
+
+import "errors"
+
+var ErrNotSupported = errors.New("NFLOG groups can't be read in Windows")
+
+// Read returns ErrNotSupported since Windows has no NFLOG.
+func Read(_, _ int, _ <-chan struct{}, _ Handler) error {
+	return ErrNotSupported
+}
@@ -28,6 +28,9 @@ var (
 
 type IPTablesParser struct {
 	IOShim *common.IOShim
+	// IptablesSave is the iptables-save command to list the rules with, e.g. ip6tables-nft-save for ip6tables.
+	// Defaults to util.IptablesSave.
+	IptablesSave string
 }
 
 // runCommand returns (stdout, stderr, error)
@@ -54,7 +57,11 @@ func (i *IPTablesParser) runCommand(command string, args ...string) ([]byte, err
 func (i *IPTablesParser) Iptables(tableName string) (*NPMIPtable.Table, error) {
 	cmdArgs := []string{util.IptablesTableFlag, string(tableName)}
 
-	output, err := i.runCommand(util.IptablesSave, cmdArgs...)
+	iptablesSave := i.IptablesSave
+	if iptablesSave == "" {
+		iptablesSave = util.IptablesSave
+	}
+	output, err := i.runCommand(iptablesSave, cmdArgs...)
 	if err != nil {
 		return nil, err
 	}
@@ -207,6 +214,11 @@ func parseChainNameFromRuleLine(ruleLine []byte) (chainName string, ruleReadInde
 	return
 }
 
+// Rule creates an iptable rule object from a rule line of iptables-save output with "-A <chain>" excluded.
+func Rule(ruleLine []byte) *NPMIPtable.Rule {
+	return parseRuleFromLine(ruleLine)
+}
+
 // parseRuleFromLine creates an iptable rule object from rule line with chain name excluded from the byte array.
 func parseRuleFromLine(ruleLine []byte) *NPMIPtable.Rule {
 	iptableRule := &NPMIPtable.Rule{}
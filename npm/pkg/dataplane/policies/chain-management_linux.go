@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/netip"
 	"strconv"
 	"strings"
 
@@ -33,6 +34,10 @@ var (
 		util.IptablesAzureIngressAllowMarkChain,
 		util.IptablesAzureEgressChain,
 		util.IptablesAzureAcceptChain,
+		util.IptablesAzureIngressAdminChain,
+		util.IptablesAzureEgressAdminChain,
+		util.IptablesAzureIngressBaselineChain,
+		util.IptablesAzureEgressBaselineChain,
 	}
 	// Should not be used directly. Initialized from iptablesAzureChains on first use of isAzureChain().
 	iptablesAzureChainsMap map[string]struct{}
@@ -74,6 +79,14 @@ var (
 		},
 	}
 
+	checkDNSSnoopRuleIgnoredErrors = []*exitErrorInfo{
+		{
+			exitCode:     doesNotExistErrorCode,
+			stdErr:       "does a matching rule exist in that chain?",
+			messageToLog: "the rule which snoops DNS responses doesn't exist yet",
+		},
+	}
+
 	listForwardEntriesArgs = []string{
 		util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesTableFlag, util.IptablesFilterTable,
 		util.IptablesNumericFlag, util.IptablesListFlag, util.IptablesForwardChain, util.IptablesLineNumbersFlag,
@@ -186,6 +199,10 @@ TODO: could use one grep call instead of separate calls for getting jump line nu
   - would use a grep pattern like so: <line num...AZURE-NPM>|<Chain AZURE-NPM>
 */
 func (pMgr *PolicyManager) bootup(_ []string) error {
+	if pMgr.UseNFTables {
+		return pMgr.bootupNFT()
+	}
+
 	klog.Infof("booting up iptables Azure chains")
 
 	// 0.1. Detect iptables version
@@ -198,21 +215,38 @@ func (pMgr *PolicyManager) bootup(_ []string) error {
 	pMgr.reconcileManager.forceLock()
 	defer pMgr.reconcileManager.forceUnlock()
 
-	// 0.2. cleanup
-	if err := pMgr.cleanupOtherIptables(); err != nil {
-		return npmerrors.SimpleErrorWrapper("failed to cleanup other iptables chains", err)
-	}
+	pMgr.staleChains.empty()
+	for _, cmds := range pMgr.iptablesCommandsForEachFamily() {
+		if cmds.IsIPv6() {
+			klog.Infof("booting up ip6tables Azure chains")
+		}
 
-	if err := pMgr.bootupAfterDetectAndCleanup(); err != nil {
-		return err
+		// 0.2. cleanup
+		if err := pMgr.cleanupOtherIptables(cmds); err != nil {
+			return npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to cleanup other %s chains", cmds.Iptables), err)
+		}
+
+		if err := pMgr.bootupAfterDetectAndCleanup(cmds); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (pMgr *PolicyManager) bootupAfterDetectAndCleanup() error {
+// iptablesCommandsForEachFamily returns the commands of the detected iptables version for iptables and, if IPv6 is enabled, ip6tables.
+// The commands are passed to the iptables helpers instead of switching the global util.Iptables commands for ip6tables.
+func (pMgr *PolicyManager) iptablesCommandsForEachFamily() []util.IptablesCommands {
+	cmds := []util.IptablesCommands{util.CurrentIptablesCommands(false)}
+	if pMgr.EnableIPv6 {
+		cmds = append(cmds, util.CurrentIptablesCommands(true))
+	}
+	return cmds
+}
+
+func (pMgr *PolicyManager) bootupAfterDetectAndCleanup(cmds util.IptablesCommands) error {
 	// 1. delete the deprecated jump to AZURE-NPM
-	deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
+	deprecatedErrCode, deprecatedErr := pMgr.ignoreErrorsAndRunIPTablesCommand(cmds, removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
 	if deprecatedErrCode == 0 {
 		klog.Infof("deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 	} else if deprecatedErr != nil {
@@ -221,25 +255,30 @@ func (pMgr *PolicyManager) bootupAfterDetectAndCleanup() error {
 			deprecatedErrCode, deprecatedErr.Error())
 	}
 
-	currentChains, err := ioutil.AllCurrentAzureChains(pMgr.ioShim.Exec, util.IptablesDefaultWaitTime)
+	currentChains, err := ioutil.AllCurrentAzureChains(pMgr.ioShim.Exec, cmds.Iptables, util.IptablesDefaultWaitTime)
 	if err != nil {
 		return npmerrors.SimpleErrorWrapper("failed to get current chains for bootup", err)
 	}
 
-	klog.Infof("found %d current chains in the default iptables", len(currentChains))
+	klog.Infof("found %d current chains in %s", len(currentChains), cmds.Iptables)
 
 	// 2. cleanup old NPM chains, and configure base chains and their rules.
 	creator := pMgr.creatorForBootup(currentChains)
-	if err := restore(creator); err != nil {
+	if err := restore(cmds, creator); err != nil {
 		return npmerrors.SimpleErrorWrapper("failed to run iptables-restore for bootup", err)
 	}
 
 	// 3. add/reposition the jump to AZURE-NPM
-	if err := pMgr.positionAzureChainJumpRule(); err != nil {
+	if err := pMgr.positionAzureChainJumpRule(cmds); err != nil {
 		baseErrString := "failed to add/reposition jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error: %s", baseErrString, err.Error())
 		return npmerrors.SimpleErrorWrapper(baseErrString, err) // we used to ignore this error in v1
 	}
+
+	// 4. add the rule which snoops DNS responses
+	if err := pMgr.ensureDNSSnoopRule(cmds); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -267,20 +306,15 @@ func (pMgr *PolicyManager) detectIptablesVersion() error {
 
 func (pMgr *PolicyManager) hintOrCanaryChainExist(iptablesCmd string) bool {
 	// hint chain should exist since k8s 1.24 (see https://kubernetes.io/blog/2022/09/07/iptables-chains-not-api/#use-case-iptables-mode)
-	prevIptables := util.Iptables
-	util.Iptables = iptablesCmd
-	defer func() {
-		util.Iptables = prevIptables
-	}()
-
-	_, hintErr := pMgr.runIPTablesCommand(util.IptablesListFlag, listHintChainArgs...)
+	cmds := util.IptablesCommands{Iptables: iptablesCmd}
+	_, hintErr := pMgr.runIPTablesCommand(cmds, util.IptablesListFlag, listHintChainArgs...)
 	if hintErr == nil {
 		metrics.SendLog(util.IptmID, "found hint chain. will use iptables version: %s"+iptablesCmd, metrics.DonotPrint)
 		return true
 	}
 
 	// check for canary chain
-	_, canaryErr := pMgr.runIPTablesCommand(util.IptablesListFlag, listCanaryChainArgs...)
+	_, canaryErr := pMgr.runIPTablesCommand(cmds, util.IptablesListFlag, listCanaryChainArgs...)
 	if canaryErr != nil {
 		return false
 	}
@@ -289,36 +323,33 @@ func (pMgr *PolicyManager) hintOrCanaryChainExist(iptablesCmd string) bool {
 	return true
 }
 
-// clenaupOtherIptablesChains cleans up legacy tables if using nft and vice versa.
+// clenaupOtherIptablesChains cleans up legacy tables if cmds are the nft commands and vice versa, in the ip family of cmds.
 // It will only return an error if it fails to delete a jump rule and flush the AZURE-NPM chain (see comment about #3088 below).
 // Cleanup logic:
 // 1. delete jump rules to AZURE-NPM
 // 2. flush all chains
 // 3. delete all chains
-func (pMgr *PolicyManager) cleanupOtherIptables() error {
-	hadNFT := util.Iptables == util.IptablesNft
+func (pMgr *PolicyManager) cleanupOtherIptables(cmds util.IptablesCommands) error {
+	hadNFT := cmds.IsNft()
 	if hadNFT {
 		klog.Info("detected nft iptables. cleaning up legacy iptables")
-		util.SetIptablesToLegacy()
 	} else {
 		klog.Info("detected legacy iptables. cleaning up nft iptables")
-		util.SetIptablesToNft()
 	}
+	cmds = cmds.OtherVersion()
 
 	defer func() {
 		if hadNFT {
 			klog.Info("cleaned up legacy iptables")
-			util.SetIptablesToNft()
 		} else {
 			klog.Info("cleaned up nft tables")
-			util.SetIptablesToLegacy()
 		}
 	}()
 
 	deletedJumpRule := false
 
 	// 1.1. delete the deprecated jump to AZURE-NPM
-	errCode, err := pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
+	errCode, err := pMgr.ignoreErrorsAndRunIPTablesCommand(cmds, removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, deprecatedJumpFromForwardToAzureChainArgs...)
 	if errCode == 0 {
 		klog.Infof("[cleanup] deleted deprecated jump rule from FORWARD chain to AZURE-NPM chain")
 		deletedJumpRule = true
@@ -329,7 +360,7 @@ func (pMgr *PolicyManager) cleanupOtherIptables() error {
 	}
 
 	// 1.2. delete the jump to AZURE-NPM
-	errCode, err = pMgr.ignoreErrorsAndRunIPTablesCommand(removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...)
+	errCode, err = pMgr.ignoreErrorsAndRunIPTablesCommand(cmds, removeDeprecatedJumpIgnoredErrors, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...)
 	if errCode == 0 {
 		deletedJumpRule = true
 		klog.Infof("[cleanup] deleted jump rule from FORWARD chain to AZURE-NPM chain")
@@ -340,7 +371,7 @@ func (pMgr *PolicyManager) cleanupOtherIptables() error {
 	}
 
 	// 2. get current chains
-	currentChains, err := ioutil.AllCurrentAzureChains(pMgr.ioShim.Exec, util.IptablesDefaultWaitTime)
+	currentChains, err := ioutil.AllCurrentAzureChains(pMgr.ioShim.Exec, cmds.Iptables, util.IptablesDefaultWaitTime)
 	if err != nil {
 		return npmerrors.SimpleErrorWrapper("[cleanup] failed to get current chains for bootup", err)
 	}
@@ -368,7 +399,7 @@ func (pMgr *PolicyManager) cleanupOtherIptables() error {
 	}
 
 	creator := pMgr.creatorForCleanup(chains)
-	if err := restore(creator); err != nil {
+	if err := restore(cmds, creator); err != nil {
 		msg := "[cleanup] failed to flush all chains with error: %s"
 		klog.Infof(msg, err.Error())
 		metrics.SendErrorLogAndMetric(util.IptmID, msg, err.Error())
@@ -376,7 +407,7 @@ func (pMgr *PolicyManager) cleanupOtherIptables() error {
 		// 3.2. if we failed to flush all chains, then try to flush and delete them one by one
 		var aggregateError error
 		if _, ok := currentChains[util.IptablesAzureChain]; ok {
-			_, err := pMgr.runIPTablesCommand(util.IptablesFlushFlag, util.IptablesAzureChain)
+			_, err := pMgr.runIPTablesCommand(cmds, util.IptablesFlushFlag, util.IptablesAzureChain)
 			aggregateError = err
 			if err != nil && !deletedJumpRule {
 				// fixes #3088
@@ -394,7 +425,7 @@ func (pMgr *PolicyManager) cleanupOtherIptables() error {
 				continue
 			}
 
-			errCode, err := pMgr.runIPTablesCommand(util.IptablesFlushFlag, chain)
+			errCode, err := pMgr.runIPTablesCommand(cmds, util.IptablesFlushFlag, chain)
 			if err != nil && errCode != doesNotExistErrorCode {
 				// NOTE: if we fail to flush or delete the chain, then we will never clean it up in the future.
 				// This is zero-day behavior since NPM supported nft (we used to mark the chain stale, but this would not have worked as expected).
@@ -418,7 +449,7 @@ func (pMgr *PolicyManager) cleanupOtherIptables() error {
 	// 4. delete all chains
 	var aggregateError error
 	for _, chain := range chains {
-		errCode, err := pMgr.runIPTablesCommand(util.IptablesDestroyFlag, chain)
+		errCode, err := pMgr.runIPTablesCommand(cmds, util.IptablesDestroyFlag, chain)
 		if err != nil && errCode != doesNotExistErrorCode {
 			// NOTE: if we fail to flush or delete the chain, then we will never clean it up in the future.
 			// This is zero-day behavior since NPM supported nft (we used to mark the chain stale, but this would not have worked as expected).
@@ -455,14 +486,27 @@ func (pMgr *PolicyManager) creatorForCleanup(chains []string) *ioutil.FileCreato
 // - creates the jump rule from FORWARD chain to AZURE-NPM chain (if it does not exist) and makes sure it's after the jumps to KUBE-FORWARD & KUBE-SERVICES chains (if they exist).
 // - cleans up stale policy chains. It can be forced to stop this process if reconcileManager.forceLock() is called.
 func (pMgr *PolicyManager) reconcile() {
-	if err := pMgr.positionAzureChainJumpRule(); err != nil {
-		msg := fmt.Sprintf("failed to reconcile jump rule to Azure-NPM due to %s", err.Error())
-		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
-		klog.Error(msg)
+	if pMgr.UseNFTables {
+		// policy chains are deleted in the same nft transaction that removes the jumps to them, so there are no stale chains
+		return
 	}
 
 	pMgr.reconcileManager.Lock()
 	defer pMgr.reconcileManager.Unlock()
+
+	for _, cmds := range pMgr.iptablesCommandsForEachFamily() {
+		if err := pMgr.positionAzureChainJumpRule(cmds); err != nil {
+			msg := fmt.Sprintf("failed to reconcile jump rule to Azure-NPM in %s due to %s", cmds.Iptables, err.Error())
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
+			klog.Error(msg)
+		}
+		if err := pMgr.ensureDNSSnoopRule(cmds); err != nil {
+			msg := fmt.Sprintf("failed to reconcile DNS snoop rule in %s due to %s", cmds.Iptables, err.Error())
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s", msg)
+			klog.Error(msg)
+		}
+	}
+
 	staleChains := pMgr.staleChains.emptyAndGetAll()
 
 	if len(staleChains) == 0 {
@@ -492,7 +536,7 @@ deleteLoop:
 			}
 			break deleteLoop
 		default:
-			errCode, err := pMgr.runIPTablesCommand(util.IptablesDestroyFlag, chain)
+			errCode, err := pMgr.destroyChain(chain)
 			if err != nil && errCode != doesNotExistErrorCode {
 				// add to staleChains if it's not one of the iptablesAzureChains
 				pMgr.staleChains.add(chain)
@@ -511,18 +555,32 @@ deleteLoop:
 	return nil
 }
 
+// destroyChain deletes the chain in iptables and, if IPv6 is enabled, in ip6tables.
+func (pMgr *PolicyManager) destroyChain(chain string) (int, error) {
+	var errCode int
+	var err error
+	for _, cmds := range pMgr.iptablesCommandsForEachFamily() {
+		errCode, err = pMgr.runIPTablesCommand(cmds, util.IptablesDestroyFlag, chain)
+		if err != nil && errCode != doesNotExistErrorCode {
+			return errCode, err
+		}
+	}
+	return errCode, err
+}
+
+// runIPTablesCommand runs cmds.Iptables, which is the iptables or ip6tables command of the detected iptables version.
 // this function has a direct comparison in NPM v1 iptables manager (iptm.go)
-func (pMgr *PolicyManager) runIPTablesCommand(operationFlag string, args ...string) (int, error) {
-	return pMgr.ignoreErrorsAndRunIPTablesCommand(nil, operationFlag, args...)
+func (pMgr *PolicyManager) runIPTablesCommand(cmds util.IptablesCommands, operationFlag string, args ...string) (int, error) {
+	return pMgr.ignoreErrorsAndRunIPTablesCommand(cmds, nil, operationFlag, args...)
 }
 
-func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErrorInfo, operationFlag string, args ...string) (int, error) {
+func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(cmds util.IptablesCommands, ignored []*exitErrorInfo, operationFlag string, args ...string) (int, error) {
 	allArgs := []string{util.IptablesWaitFlag, util.IptablesDefaultWaitTime, operationFlag}
 	allArgs = append(allArgs, args...)
 
-	klog.Infof("executing iptables command [%s] with args %v", util.Iptables, allArgs)
+	klog.Infof("executing iptables command [%s] with args %v", cmds.Iptables, allArgs)
 
-	command := pMgr.ioShim.Exec.Command(util.Iptables, allArgs...)
+	command := pMgr.ioShim.Exec.Command(cmds.Iptables, allArgs...)
 	output, err := command.CombinedOutput()
 
 	var exitError utilexec.ExitError
@@ -532,14 +590,14 @@ func (pMgr *PolicyManager) ignoreErrorsAndRunIPTablesCommand(ignored []*exitErro
 		outputString := strings.TrimSuffix(string(output), "\n")
 		for _, info := range ignored {
 			if errCode == info.exitCode && strings.Contains(outputString, info.stdErr) {
-				klog.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, util.Iptables, allArgsString, errCode, outputString)
+				klog.Infof("%s. not able to run iptables command [%s %s]. exit code: %d, output: %s", info.messageToLog, cmds.Iptables, allArgsString, errCode, outputString)
 				return errCode, nil
 			}
 		}
 		if errCode > 0 {
-			metrics.SendErrorLogAndMetric(util.IptmID, "error: There was an error running command: [%s %s] Stderr: [%v, %s]", util.Iptables, allArgsString, exitError, outputString)
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: There was an error running command: [%s %s] Stderr: [%v, %s]", cmds.Iptables, allArgsString, exitError, outputString)
 		}
-		return errCode, fmt.Errorf("failed to run iptables command [%s %s] Stderr: [%s]. err: [%w]", util.Iptables, allArgsString, outputString, exitError)
+		return errCode, fmt.Errorf("failed to run iptables command [%s %s] Stderr: [%s]. err: [%w]", cmds.Iptables, allArgsString, outputString, exitError)
 	}
 	return 0, nil
 }
@@ -558,24 +616,32 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	// Step 2.1 in bootup() comment: cleanup old NPM chains, and configure base chains and their rules
 	// To leave NPM deactivated, don't specify any rules for AZURE-NPM chain.
 	creator := pMgr.newCreatorWithChains(chainsToCreate)
-	pMgr.staleChains.empty()
 	for chain := range currentChains {
 		creator.AddLine("", nil, fmt.Sprintf("-F %s", chain))
 		// Step 2.2 in bootup() comment: delete deprecated chains and old v2 policy chains in the background
 		pMgr.staleChains.add(chain) // won't add base chains
 	}
+	writeBaseChainRules(creator)
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+	return creator
+}
 
+// writeBaseChainRules writes the rules of AZURE-NPM-INGRESS, AZURE-NPM-INGRESS-ALLOW-MARK, AZURE-NPM-EGRESS, and AZURE-NPM-ACCEPT chains.
+func writeBaseChainRules(creator *ioutil.FileCreator) {
 	// add AZURE-NPM-INGRESS chain rules
 	ingressDropSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesDrop}
 	ingressDropSpecs = append(ingressDropSpecs, onMarkSpecs(util.IptablesAzureIngressDropMarkHex)...)
 	ingressDropSpecs = append(ingressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-INGRESS-DROP-MARK-%s", util.IptablesAzureIngressDropMarkHex))...)
 	creator.AddLine("", nil, ingressDropSpecs...)
+	// BaselineAdminNetworkPolicies only apply if no NetworkPolicy decided on the flow
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressChain, util.IptablesJumpFlag, util.IptablesAzureIngressBaselineChain)
 
 	// add AZURE-NPM-INGRESS-ALLOW-MARK chain
 	markIngressAllowSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain}
 	markIngressAllowSpecs = append(markIngressAllowSpecs, setMarkSpecs(util.IptablesAzureIngressAllowMarkHex)...)
 	markIngressAllowSpecs = append(markIngressAllowSpecs, commentSpecs(fmt.Sprintf("SET-INGRESS-ALLOW-MARK-%s", util.IptablesAzureIngressAllowMarkHex))...)
 	creator.AddLine("", nil, markIngressAllowSpecs...)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureEgressAdminChain)
 	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureIngressAllowMarkChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
 
 	// add AZURE-NPM-EGRESS chain rules
@@ -583,6 +649,7 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 	egressDropSpecs = append(egressDropSpecs, onMarkSpecs(util.IptablesAzureEgressDropMarkHex)...)
 	egressDropSpecs = append(egressDropSpecs, commentSpecs(fmt.Sprintf("DROP-ON-EGRESS-DROP-MARK-%s", util.IptablesAzureEgressDropMarkHex))...)
 	creator.AddLine("", nil, egressDropSpecs...)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureEgressBaselineChain)
 
 	jumpOnIngressMatchSpecs := []string{util.IptablesAppendFlag, util.IptablesAzureEgressChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
 	jumpOnIngressMatchSpecs = append(jumpOnIngressMatchSpecs, onMarkSpecs(util.IptablesAzureIngressAllowMarkHex)...)
@@ -591,16 +658,14 @@ func (pMgr *PolicyManager) creatorForBootup(currentChains map[string]struct{}) *
 
 	// add AZURE-NPM-ACCEPT chain rules
 	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureAcceptChain, util.IptablesJumpFlag, util.IptablesAccept)
-	creator.AddLine("", nil, util.IptablesRestoreCommit)
-	return creator
 }
 
 // add/reposition the jump from FORWARD chain to AZURE-NPM chain to be in the correct position based on config:
 // option 1) jump to AZURE-NPM chain should be the first rule
 // option 2) jump to AZURE-NPM chain should be after the jump to KUBE-SERVICES chain
-func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
+func (pMgr *PolicyManager) positionAzureChainJumpRule(cmds util.IptablesCommands) error {
 	// get the line number for the azure jump
-	azureChainLineNum, err := pMgr.chainLineNumber(util.IptablesAzureChain)
+	azureChainLineNum, err := pMgr.chainLineNumber(cmds, util.IptablesAzureChain)
 	if err != nil {
 		baseErrString := "failed to get index of jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s: %s", baseErrString, err.Error())
@@ -615,7 +680,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 	// place the azure jump in the first position, unless we want option 2 above and the kube jump exists
 	targetIndex := 1
 	if pMgr.PlaceAzureChainFirst == util.PlaceAzureChainAfterKubeServices {
-		kubeChainLineNum, err := pMgr.chainLineNumber(util.IptablesKubeServicesChain)
+		kubeChainLineNum, err := pMgr.chainLineNumber(cmds, util.IptablesKubeServicesChain)
 		if err != nil {
 			baseErrString := "failed to get index of jump from FORWARD chain to KUBE-SERVICES chain"
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s: %s", baseErrString, err.Error())
@@ -637,7 +702,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 	// delete the azure jump if it exists and update the target index
 	if azureChainLineNum != 0 {
 		metrics.SendErrorLogAndMetric(util.IptmID, "Info: Reconciler deleting and re-adding jump from FORWARD chain to AZURE-NPM chain table.")
-		if deleteErrCode, deleteErr := pMgr.runIPTablesCommand(util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...); deleteErr != nil {
+		if deleteErrCode, deleteErr := pMgr.runIPTablesCommand(cmds, util.IptablesDeletionFlag, jumpFromForwardToAzureChainArgs...); deleteErr != nil {
 			baseErrString := "failed to delete jump from FORWARD chain to AZURE-NPM chain"
 			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error code %d and error %s", baseErrString, deleteErrCode, deleteErr.Error())
 			return npmerrors.SimpleErrorWrapper(baseErrString, deleteErr)
@@ -661,7 +726,7 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 		args = []string{util.IptablesForwardChain, strconv.Itoa(targetIndex)}
 		args = append(args, jumpToAzureChainArgs...)
 	}
-	if insertErrCode, err := pMgr.runIPTablesCommand(util.IptablesInsertionFlag, args...); err != nil {
+	if insertErrCode, err := pMgr.runIPTablesCommand(cmds, util.IptablesInsertionFlag, args...); err != nil {
 		baseErrString := "failed to insert jump from FORWARD chain to AZURE-NPM chain"
 		metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error code %d and error %s", baseErrString, insertErrCode, err.Error())
 		return npmerrors.SimpleErrorWrapper(baseErrString, err)
@@ -669,10 +734,64 @@ func (pMgr *PolicyManager) positionAzureChainJumpRule() error {
 	return nil
 }
 
+// ensureDNSSnoopRule inserts the rules which send DNS responses to the NFLOG group of the FQDN snooper
+// at the top of the FORWARD chain if the rules are missing.
+// There is a rule for each DNS server of the IP family, which only matches the replies of established flows to the server,
+// so pods can't fill the FQDN ipsets by sending packets from port 53 to other pods.
+// The NFLOG target doesn't end the traversal of the FORWARD chain, so the rules only copy the responses
+// and don't change how they're filtered.
+func (pMgr *PolicyManager) ensureDNSSnoopRule(cmds util.IptablesCommands) error {
+	if !pMgr.SnoopDNS {
+		return nil
+	}
+
+	for _, specs := range pMgr.dnsSnoopRuleSpecs(cmds) {
+		if errCode, err := pMgr.ignoreErrorsAndRunIPTablesCommand(cmds, checkDNSSnoopRuleIgnoredErrors, util.IptablesCheckFlag, specs...); err == nil && errCode == 0 {
+			continue
+		}
+
+		klog.Infof("Inserting rule in FORWARD chain to snoop DNS responses")
+		if insertErrCode, err := pMgr.runIPTablesCommand(cmds, util.IptablesInsertionFlag, specs...); err != nil {
+			baseErrString := "failed to insert rule in FORWARD chain to snoop DNS responses"
+			metrics.SendErrorLogAndMetric(util.IptmID, "error: %s with error code %d and error %s", baseErrString, insertErrCode, err.Error())
+			return npmerrors.SimpleErrorWrapper(baseErrString, err)
+		}
+	}
+	return nil
+}
+
+// dnsSnoopRuleSpecs returns the specs of the rules for the DNS servers of the IP family of cmds
+func (pMgr *PolicyManager) dnsSnoopRuleSpecs(cmds util.IptablesCommands) [][]string {
+	allSpecs := make([][]string, 0, len(pMgr.DNSServers))
+	for _, server := range pMgr.DNSServers {
+		ip, err := netip.ParseAddr(server)
+		if err != nil {
+			klog.Warningf("ignoring invalid DNS server IP %s", server)
+			continue
+		}
+		if ip.Is6() != cmds.IsIPv6() {
+			continue
+		}
+		allSpecs = append(allSpecs, []string{
+			util.IptablesForwardChain,
+			util.IptablesProtFlag, string(UDP),
+			util.IptablesSrcPortFlag, util.DNSPort,
+			util.IptablesModuleFlag, util.IptablesCtstateModuleFlag,
+			util.IptablesCtstateFlag, util.IptablesEstablishedState,
+			util.IptablesCtdirFlag, util.IptablesReplyDirection,
+			util.IptablesCtorigdstFlag, ip.String(),
+			util.IptablesJumpFlag, util.IptablesNFLog,
+			util.IptablesNFLogGroupFlag, strconv.Itoa(pMgr.DNSNFLogGroup),
+			util.IptablesNFLogPrefixFlag, util.NFLogDNSPrefix,
+		})
+	}
+	return allSpecs
+}
+
 // returns 0 if the chain does not exist
 // this function has a direct comparison in NPM v1 iptables manager (iptm.go)
-func (pMgr *PolicyManager) chainLineNumber(chain string) (int, error) {
-	listForwardEntriesCommand := pMgr.ioShim.Exec.Command(util.Iptables, listForwardEntriesArgs...)
+func (pMgr *PolicyManager) chainLineNumber(cmds util.IptablesCommands, chain string) (int, error) {
+	listForwardEntriesCommand := pMgr.ioShim.Exec.Command(cmds.Iptables, listForwardEntriesArgs...)
 	grepCommand := pMgr.ioShim.Exec.Command(ioutil.Grep, chain)
 	searchResults, gotMatches, err := ioutil.PipeCommandToGrep(listForwardEntriesCommand, grepCommand)
 	if err != nil {
@@ -703,3 +822,13 @@ func onMarkSpecs(mark string) []string {
 		mark,
 	}
 }
+
+func notOnMarkSpecs(mark string) []string {
+	return []string{
+		util.IptablesModuleFlag,
+		util.IptablesMarkVerb,
+		util.IptablesNotFlag,
+		util.IptablesMarkFlag,
+		mark,
+	}
+}
@@ -170,6 +170,24 @@ func TestCleanupChainsFailure(t *testing.T) {
 	assertStaleChainsContain(t, pMgr.staleChains, testChain1, testChain3)
 }
 
+func TestCleanupChainsIPv6(t *testing.T) {
+	calls := []testutils.TestCmd{
+		getFakeDestroyCommand(testChain1),
+		{Cmd: []string{"ip6tables-nft", "-w", "60", "-X", testChain1}},
+		getFakeDestroyCommand(testChain2),
+		{Cmd: []string{"ip6tables-nft", "-w", "60", "-X", testChain2}, ExitCode: 2},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	cfg := *ipsetConfig
+	cfg.EnableIPv6 = true
+	pMgr := NewPolicyManager(ioshim, &cfg)
+
+	require.Error(t, pMgr.cleanupChains([]string{testChain1, testChain2}))
+	assertStaleChainsContain(t, pMgr.staleChains, testChain2)
+	require.Equal(t, util.IptablesNft, util.Iptables, "the global iptables command should be unchanged")
+}
+
 func TestCreatorForBootup(t *testing.T) {
 	v1Chains := []string{
 		"AZURE-NPM-INGRESS-DROPS",
@@ -196,10 +214,17 @@ func TestCreatorForBootup(t *testing.T) {
 				":AZURE-NPM-INGRESS-ALLOW-MARK - -",
 				":AZURE-NPM-EGRESS - -",
 				":AZURE-NPM-ACCEPT - -",
+				":AZURE-NPM-INGRESS-ADMIN - -",
+				":AZURE-NPM-EGRESS-ADMIN - -",
+				":AZURE-NPM-INGRESS-BASELINE - -",
+				":AZURE-NPM-EGRESS-BASELINE - -",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS-ADMIN",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -221,6 +246,10 @@ func TestCreatorForBootup(t *testing.T) {
 			// same expected lines as "no NPM prior", except for the old v2 policy chains in the header
 			expectedLines: []string{
 				"*filter",
+				":AZURE-NPM-INGRESS-ADMIN - -",
+				":AZURE-NPM-EGRESS-ADMIN - -",
+				":AZURE-NPM-INGRESS-BASELINE - -",
+				":AZURE-NPM-EGRESS-BASELINE - -",
 				"-F AZURE-NPM",
 				"-F AZURE-NPM-INGRESS",
 				"-F AZURE-NPM-INGRESS-ALLOW-MARK",
@@ -229,9 +258,12 @@ func TestCreatorForBootup(t *testing.T) {
 				"-F AZURE-NPM-INGRESS-123456",
 				"-F AZURE-NPM-EGRESS-123456",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS-ADMIN",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -254,13 +286,20 @@ func TestCreatorForBootup(t *testing.T) {
 				"*filter",
 				":AZURE-NPM - -",
 				":AZURE-NPM-EGRESS - -",
+				":AZURE-NPM-INGRESS-ADMIN - -",
+				":AZURE-NPM-EGRESS-ADMIN - -",
+				":AZURE-NPM-INGRESS-BASELINE - -",
+				":AZURE-NPM-EGRESS-BASELINE - -",
 				"-F AZURE-NPM-ACCEPT",
 				"-F AZURE-NPM-INGRESS",
 				"-F AZURE-NPM-INGRESS-ALLOW-MARK",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS-ADMIN",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -279,6 +318,10 @@ func TestCreatorForBootup(t *testing.T) {
 				":AZURE-NPM-INGRESS-ALLOW-MARK - -",
 				":AZURE-NPM-EGRESS - -",
 				":AZURE-NPM-ACCEPT - -",
+				":AZURE-NPM-INGRESS-ADMIN - -",
+				":AZURE-NPM-EGRESS-ADMIN - -",
+				":AZURE-NPM-INGRESS-BASELINE - -",
+				":AZURE-NPM-EGRESS-BASELINE - -",
 				"-F AZURE-NPM-INGRESS-DROPS",
 				"-F AZURE-NPM-INGRESS-TO",
 				"-F AZURE-NPM-INGRESS-PORTS",
@@ -286,9 +329,12 @@ func TestCreatorForBootup(t *testing.T) {
 				"-F AZURE-NPM-EGRESS-FROM",
 				"-F AZURE-NPM-EGRESS-PORTS",
 				"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+				"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS-ADMIN",
 				"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
 				"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+				"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
 				"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
 				"-A AZURE-NPM-ACCEPT -j ACCEPT",
 				"COMMIT",
@@ -478,7 +524,7 @@ func TestBootupLinux(t *testing.T) {
 			ioshim := common.NewMockIOShim(tt.calls)
 			defer ioshim.VerifyCalls(t, tt.calls)
 			pMgr := NewPolicyManager(ioshim, ipsetConfig)
-			err := pMgr.bootupAfterDetectAndCleanup()
+			err := pMgr.bootupAfterDetectAndCleanup(util.CurrentIptablesCommands(false))
 			if tt.wantErr {
 				require.Error(t, err)
 			} else {
@@ -767,7 +813,71 @@ func TestPositionAzureChainJumpRule(t *testing.T) {
 			}
 			pMgr := NewPolicyManager(ioshim, cfg)
 
-			err := pMgr.positionAzureChainJumpRule()
+			err := pMgr.positionAzureChainJumpRule(util.CurrentIptablesCommands(false))
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEnsureDNSSnoopRule(t *testing.T) {
+	// the IPv6 DNS server only has a rule in ip6tables
+	dnsSnoopRule := []string{
+		"FORWARD", "-p", "UDP", "--sport", "53", "-m", "conntrack", "--ctstate", "ESTABLISHED", "--ctdir", "REPLY", "--ctorigdst", "10.0.0.10",
+		"-j", "NFLOG", "--nflog-group", "101", "--nflog-prefix", "NPM-DNS",
+	}
+	tests := []struct {
+		name     string
+		calls    []testutils.TestCmd
+		snoopDNS bool
+		wantErr  bool
+	}{
+		{
+			name:     "disabled",
+			snoopDNS: false,
+		},
+		{
+			name: "rule exists",
+			calls: []testutils.TestCmd{
+				{Cmd: append([]string{"iptables-nft", "-w", "60", "-C"}, dnsSnoopRule...)},
+			},
+			snoopDNS: true,
+		},
+		{
+			name: "no rule yet",
+			calls: []testutils.TestCmd{
+				{Cmd: append([]string{"iptables-nft", "-w", "60", "-C"}, dnsSnoopRule...), ExitCode: 1, Stdout: "Bad rule (does a matching rule exist in that chain?)"},
+				{Cmd: append([]string{"iptables-nft", "-w", "60", "-I"}, dnsSnoopRule...)},
+			},
+			snoopDNS: true,
+		},
+		{
+			name: "insert fails",
+			calls: []testutils.TestCmd{
+				{Cmd: append([]string{"iptables-nft", "-w", "60", "-C"}, dnsSnoopRule...), ExitCode: 1, Stdout: "Bad rule (does a matching rule exist in that chain?)"},
+				{Cmd: append([]string{"iptables-nft", "-w", "60", "-I"}, dnsSnoopRule...), ExitCode: 2},
+			},
+			snoopDNS: true,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ioshim := common.NewMockIOShim(tt.calls)
+			defer ioshim.VerifyCalls(t, tt.calls)
+			cfg := &PolicyManagerCfg{
+				PolicyMode:    IPSetPolicyMode,
+				SnoopDNS:      tt.snoopDNS,
+				DNSNFLogGroup: util.DefaultDNSNFLogGroup,
+				DNSServers:    []string{"10.0.0.10", "fd00::10"},
+			}
+			pMgr := NewPolicyManager(ioshim, cfg)
+
+			err := pMgr.ensureDNSSnoopRule(util.CurrentIptablesCommands(false))
 			if tt.wantErr {
 				require.Error(t, err)
 			} else {
@@ -859,7 +969,7 @@ func TestChainLineNumber(t *testing.T) {
 			defer ioshim.VerifyCalls(t, tt.calls)
 			pMgr := NewPolicyManager(ioshim, ipsetConfig)
 
-			lineNum, err := pMgr.chainLineNumber(testChainName)
+			lineNum, err := pMgr.chainLineNumber(util.CurrentIptablesCommands(false), testChainName)
 			if tt.wantErr {
 				require.Error(t, err)
 			} else {
@@ -1357,7 +1467,7 @@ func TestCleanupOtherChains(t *testing.T) {
 				defer util.SetIptablesToNft()
 			}
 
-			err := pMgr.cleanupOtherIptables()
+			err := pMgr.cleanupOtherIptables(util.CurrentIptablesCommands(false))
 			if tt.expectedErr {
 				require.Error(t, err)
 			} else {
@@ -32,8 +32,40 @@ type NPMNetworkPolicy struct {
 	// podIP is key and endpoint ID as value
 	// Will be populated by dataplane and policy manager
 	PodEndpoints map[string]string
+	// Tier is the API the policy was translated from. See PolicyTier.
+	Tier PolicyTier
+	// Priority orders the policies of the AdminTier. Lower values are evaluated first.
+	Priority int32
+	// Audit policies log the traffic their Dropped ACLs match instead of dropping it.
+	// Only Linux can log. Windows ignores the Dropped ACLs of audited policies.
+	Audit bool
+	// FQDNs are the domain names which the selected pods may send egress traffic to.
+	// The DNS snooper adds the IPs which they resolve to into FQDNIPSet. Only Linux supports FQDNs.
+	FQDNs     []string
+	FQDNIPSet *ipsets.IPSetMetadata
 }
 
+// PolicyTier orders policies from different APIs.
+// The AdminTier is evaluated before the NetworkPolicyTier, and the BaselineTier is evaluated only
+// for the directions of a pod which no NetworkPolicy selects.
+type PolicyTier int8
+
+const (
+	// NetworkPolicyTier holds networking.k8s.io/v1 NetworkPolicies
+	NetworkPolicyTier PolicyTier = 0
+	// AdminTier holds AdminNetworkPolicies
+	AdminTier PolicyTier = 1
+	// BaselineTier holds BaselineAdminNetworkPolicies
+	BaselineTier PolicyTier = 2
+
+	// MaxWindowsAdminPriority is the highest AdminNetworkPolicy priority which the Windows dataplane
+	// can order before the ACLs of NetworkPolicies
+	MaxWindowsAdminPriority = 189
+
+	adminNetworkPolicyKind         = "AdminNetworkPolicy"
+	baselineAdminNetworkPolicyKind = "BaselineAdminNetworkPolicy"
+)
+
 func NewNPMNetworkPolicy(netPolName, netPolNamespace string) *NPMNetworkPolicy {
 	return &NPMNetworkPolicy{
 		Namespace:   netPolNamespace,
@@ -42,6 +74,40 @@ func NewNPMNetworkPolicy(netPolName, netPolNamespace string) *NPMNetworkPolicy {
 	}
 }
 
+// NewAdminNPMNetworkPolicy creates a policy in the AdminTier for the cluster-scoped AdminNetworkPolicy.
+func NewAdminNPMNetworkPolicy(anpName string, priority int32) *NPMNetworkPolicy {
+	return &NPMNetworkPolicy{
+		PolicyKey:   AdminPolicyKey(anpName),
+		ACLPolicyID: aclPolicyID(adminNetworkPolicyKind, anpName),
+		Tier:        AdminTier,
+		Priority:    priority,
+	}
+}
+
+// NewBaselineAdminNPMNetworkPolicy creates a policy in the BaselineTier for the cluster-scoped BaselineAdminNetworkPolicy.
+func NewBaselineAdminNPMNetworkPolicy(banpName string) *NPMNetworkPolicy {
+	return &NPMNetworkPolicy{
+		PolicyKey:   BaselineAdminPolicyKey(banpName),
+		ACLPolicyID: aclPolicyID(baselineAdminNetworkPolicyKind, banpName),
+		Tier:        BaselineTier,
+	}
+}
+
+// AdminPolicyKey returns the PolicyKey of an AdminNetworkPolicy.
+// Kinds are capitalized, so the key can't collide with the "namespace/name" key of a NetworkPolicy.
+func AdminPolicyKey(anpName string) string {
+	return fmt.Sprintf("%s/%s", adminNetworkPolicyKind, anpName)
+}
+
+// BaselineAdminPolicyKey returns the PolicyKey of a BaselineAdminNetworkPolicy.
+func BaselineAdminPolicyKey(banpName string) string {
+	return fmt.Sprintf("%s/%s", baselineAdminNetworkPolicyKind, banpName)
+}
+
+func (netPol *NPMNetworkPolicy) isTiered() bool {
+	return netPol.Tier != NetworkPolicyTier
+}
+
 func (netPol *NPMNetworkPolicy) HasCIDRRules() bool {
 	for _, set := range netPol.RuleIPSets {
 		if set.Metadata.Type == ipsets.CIDRBlocks {
@@ -125,6 +191,9 @@ type ACLPolicy struct {
 	DstPorts Ports
 	// Protocol is the value of traffic protocol
 	Protocol Protocol
+	// RuleIndex is the index of the AdminNetworkPolicy or BaselineAdminNetworkPolicy rule in its direction
+	// which the ACL is translated from. Windows uses it to order the ACLs of a tiered policy.
+	RuleIndex int
 }
 
 // NormalizePolicy helps fill in missed fields in aclPolicy
@@ -145,6 +214,9 @@ func ValidatePolicy(networkPolicy *NPMNetworkPolicy) error {
 		if !aclPolicy.hasKnownTarget() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown target [%s]", networkPolicy.PolicyKey, aclPolicy.Target))
 		}
+		if aclPolicy.Target == Passed && networkPolicy.Tier != AdminTier {
+			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has target [%s] outside of the admin tier", networkPolicy.PolicyKey, aclPolicy.Target))
+		}
 		if !aclPolicy.hasKnownDirection() {
 			return npmerrors.SimpleError(fmt.Sprintf("ACL policy for NetPol %s has unknown direction [%s]", networkPolicy.PolicyKey, aclPolicy.Direction))
 		}
@@ -233,7 +305,7 @@ func (aclPolicy *ACLPolicy) hasKnownProtocol() bool {
 }
 
 func (aclPolicy *ACLPolicy) hasKnownTarget() bool {
-	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped
+	return aclPolicy.Target == Allowed || aclPolicy.Target == Dropped || aclPolicy.Target == Passed
 }
 
 func (aclPolicy *ACLPolicy) satisifiesPortAndProtocolConstraints() bool {
@@ -339,6 +411,8 @@ const (
 	Allowed Verdict = "ALLOW"
 	// Dropped is denying a flow
 	Dropped Verdict = "DROP"
+	// Passed skips the remaining AdminNetworkPolicies for a flow. It's only valid in the AdminTier.
+	Passed Verdict = "PASS"
 )
 
 // Protocol can be TCP, UDP, SCTP, or unspecified since they are currently supported in networkpolicy.
@@ -72,6 +72,10 @@ func (networkPolicy *NPMNetworkPolicy) commentForJump(direction UniqueDirection)
 	if len(networkPolicy.PodSelectorList) > 0 {
 		podSelectorComment = commentForInfos(networkPolicy.PodSelectorList)
 	}
+	if networkPolicy.Namespace == "" {
+		// cluster-scoped policies like AdminNetworkPolicies have no namespace
+		return fmt.Sprintf("%s-POLICY-%s-%s-%s", prefix, networkPolicy.PolicyKey, toFrom, podSelectorComment)
+	}
 	return fmt.Sprintf("%s-POLICY-%s-%s-%s-IN-ns-%s", prefix, networkPolicy.PolicyKey, toFrom, podSelectorComment, networkPolicy.Namespace)
 }
 
@@ -128,11 +132,7 @@ func (aclPolicy *ACLPolicy) comment() string {
 	}
 
 	builder := strings.Builder{}
-	if aclPolicy.Target == Allowed {
-		builder.WriteString("ALLOW")
-	} else {
-		builder.WriteString("DROP")
-	}
+	builder.WriteString(string(aclPolicy.Target))
 
 	if len(cleanPeerList) == 0 {
 		builder.WriteString("-ALL")
@@ -214,6 +214,7 @@ func (portRange *Ports) toIPTablesString() string {
 					- ingress: "ALLOW-FROM"
 					- egress: "ALLOW-TO"
 			- denied: replace "ALLOW" with "DROP"
+			- passed (AdminNetworkPolicies only): replace "ALLOW" with "PASS"
 		- similar idea (think there are at most two non-namedPort ipsets e.g. ns selector and pod selector):
 			prefix
 			[-ipset1Name]
@@ -234,7 +235,7 @@ func (portRange *Ports) toIPTablesString() string {
 			-policyKey
 			-TO         (or "-FROM" if egress)
 			[-podSelectorComment]   (or "all" if there are no pod selectors)
-			-IN-ns
+			-IN-ns      (omitted for cluster-scoped policies)
 			-namespaceName
 
 	strings for protocol, ports, selectors:
@@ -8,10 +8,20 @@ import (
 	"github.com/Microsoft/hcsshim/hcn"
 )
 
+// HNS evaluates ACLs with lower priorities first. The priorities of the tiers are:
+//   - AdminNetworkPolicies: adminRulePriorityBase + priority*adminRulesPerPriority + rule index
+//   - NetworkPolicies: allowRulePriotity, then blockRulePriotity
+//   - BaselineAdminNetworkPolicies: baselineRulePriorityBase + rule index
 const (
-	blockRulePriotity = 3000
-	allowRulePriotity = 222
+	blockRulePriotity = 30000
+	allowRulePriotity = 20000
 	policyIDPrefix    = "azure-acl"
+
+	adminRulePriorityBase = 1000
+	// adminRulesPerPriority is the max number of rules per direction of an AdminNetworkPolicy.
+	// With MaxWindowsAdminPriority, the ACLs of AdminNetworkPolicies fit before allowRulePriotity.
+	adminRulesPerPriority    = 100
+	baselineRulePriorityBase = 40000
 )
 
 var (
@@ -26,6 +36,7 @@ var (
 	ErrNamedPortsNotSupported     = errors.New("Named Port translation is not supported in windows dataplane")
 	ErrNegativeMatchsNotSupported = errors.New("Negative match types is not supported in windows dataplane")
 	ErrProtocolNotSupported       = errors.New("Protocol mentioned is not supported")
+	ErrPassActionNotSupported     = errors.New("Pass action is not supported in windows dataplane")
 )
 
 // aclPolicyID returns azure-acl-<network policy namespace>-<network policy name> format
@@ -77,6 +88,10 @@ func (acl *ACLPolicy) convertToAclSettings(aclID string) (*NPMACLPolSettings, er
 		return policySettings, ErrNamedPortsNotSupported
 	}
 
+	if acl.Target == Passed {
+		return policySettings, ErrPassActionNotSupported
+	}
+
 	policySettings.RuleType = hcn.RuleTypeSwitch
 	policySettings.Id = aclID
 	policySettings.Direction = getHCNDirection(acl.Direction)
@@ -140,6 +155,15 @@ func (acl *ACLPolicy) convertToAclSettings(aclID string) (*NPMACLPolSettings, er
 	return policySettings, nil
 }
 
+// tieredRulePriority orders the ACLs of AdminNetworkPolicies before and the ACLs of BaselineAdminNetworkPolicies after
+// the ACLs of NetworkPolicies. ACLs translated from the same rule share a priority.
+func tieredRulePriority(policy *NPMNetworkPolicy, acl *ACLPolicy) uint16 {
+	if policy.Tier == BaselineTier {
+		return uint16(baselineRulePriorityBase + acl.RuleIndex)
+	}
+	return uint16(adminRulePriorityBase + int(policy.Priority)*adminRulesPerPriority + acl.RuleIndex)
+}
+
 func (acl *ACLPolicy) checkIPSets() bool {
 	for _, set := range acl.SrcList {
 		if set.IPSet.Type == ipsets.NamedPorts {
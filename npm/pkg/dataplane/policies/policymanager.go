@@ -25,7 +25,7 @@ const (
 	// this number is based on the implementation in chain-management_linux.go
 	// it represents the number of rules unrelated to policies
 	// it's technically 3 off when there are no policies since we flush the AZURE-NPM chain then
-	numLinuxBaseACLRules = 11
+	numLinuxBaseACLRules = 16
 )
 
 type PolicyManagerCfg struct {
@@ -39,6 +39,33 @@ type PolicyManagerCfg struct {
 	// The zero value is valid.
 	// A NetworkPolicy's ACLs are always in the same batch, and there will be at least one NetworkPolicy per batch.
 	MaxBatchedACLsPerPod int
+	// UseNFTables only affects Linux. It renders the policies into nftables chains instead of iptables chains.
+	UseNFTables bool
+	// LogDeniedFlows only affects Linux. It sends the packets dropped by policies to NFLogGroup.
+	LogDeniedFlows bool
+	// NFLogGroup is the NFLOG group for denied flows and audited policies. Only used in Linux.
+	NFLogGroup int
+	// SnoopDNS only affects Linux. It sends the DNS responses forwarded to pods to DNSNFLogGroup for FQDN egress rules.
+	SnoopDNS bool
+	// DNSNFLogGroup is the NFLOG group for DNS responses. Only used in Linux.
+	DNSNFLogGroup int
+	// DNSServers are the IPs of the DNS servers whose responses are snooped. Only used in Linux.
+	DNSServers []string
+	// EnableIPv6 only affects Linux. It mirrors the AZURE-NPM chains into ip6tables.
+	EnableIPv6 bool
+}
+
+// KernelDrift describes the rules in the kernel which drifted from the cache.
+type KernelDrift struct {
+	// BaseChains are the NPM chains which drifted, excluding the chains of policies. Only used in Linux.
+	BaseChains []string
+	// Policies are the keys of the policies whose rules drifted.
+	Policies []string
+}
+
+// IsEmpty returns true if nothing drifted.
+func (drift *KernelDrift) IsEmpty() bool {
+	return len(drift.BaseChains) == 0 && len(drift.Policies) == 0
 }
 
 type PolicyMap struct {
@@ -110,6 +137,14 @@ func (pMgr *PolicyManager) Reconcile() {
 	pMgr.reconcile()
 }
 
+// ReconcileWithKernel repairs the rules in the kernel which drifted from the cache and returns what drifted.
+// In Linux, it compares the AZURE-NPM chains in iptables. In Windows, it compares the ACLs of each endpoint in HNS.
+func (pMgr *PolicyManager) ReconcileWithKernel() (*KernelDrift, error) {
+	pMgr.policyMap.Lock()
+	defer pMgr.policyMap.Unlock()
+	return pMgr.reconcileWithKernel()
+}
+
 func (pMgr *PolicyManager) PolicyExists(policyKey string) bool {
 	pMgr.policyMap.RLock()
 	defer pMgr.policyMap.RUnlock()
@@ -126,6 +161,20 @@ func (pMgr *PolicyManager) GetPolicy(policyKey string) (*NPMNetworkPolicy, bool)
 	return policy, ok
 }
 
+// PolicyKeyForHash returns the PolicyKey whose util.Hash is hash.
+// The Linux dataplane identifies policies by this hash in chain names and NFLOG prefixes.
+func (pMgr *PolicyManager) PolicyKeyForHash(hash string) (string, bool) {
+	pMgr.policyMap.RLock()
+	defer pMgr.policyMap.RUnlock()
+
+	for policyKey := range pMgr.policyMap.cache {
+		if util.Hash(policyKey) == hash {
+			return policyKey, true
+		}
+	}
+	return "", false
+}
+
 func (pMgr *PolicyManager) AddPolicies(policies []*NPMNetworkPolicy, endpointList map[string]string) error {
 	nonEmptyPolicies := make([]*NPMNetworkPolicy, 0, len(policies))
 	for _, policy := range policies {
@@ -0,0 +1,269 @@
+package policies
+
+// This file contains code for repairing NPM chains which drifted from the cache in iptables.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	NPMIPtable "github.com/Azure/azure-container-networking/npm/pkg/dataplane/iptables"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	"k8s.io/klog"
+)
+
+const (
+	// setXMarkFlag is how iptables-save prints the --set-mark option of MARK targets
+	setXMarkFlag = "--set-xmark"
+	// fullMarkMask is the mask of marks without one
+	fullMarkMask = "0xffffffff"
+)
+
+// reconcileWithKernel compares the rules in each NPM chain with iptables-save and restores all NPM chains if any chain drifted.
+// It also adds back the jump from FORWARD chain to AZURE-NPM chain if it's missing.
+// Assumes the PolicyMap is locked.
+func (pMgr *PolicyManager) reconcileWithKernel() (*KernelDrift, error) {
+	if pMgr.UseNFTables {
+		// nftables chains aren't verified
+		return &KernelDrift{}, nil
+	}
+
+	creator, expectedRules := pMgr.creatorForKernelState()
+
+	// Stop reconciling so we don't contend for iptables, and so reconcile doesn't delete the policy chains.
+	pMgr.reconcileManager.forceLock()
+	defer pMgr.reconcileManager.forceUnlock()
+
+	driftedChains := make(map[string]struct{})
+	for _, cmds := range pMgr.iptablesCommandsForEachFamily() {
+		familyDriftedChains, err := pMgr.driftedChains(cmds, expectedRules)
+		if err != nil {
+			return &KernelDrift{}, err
+		}
+		for chain := range familyDriftedChains {
+			driftedChains[chain] = struct{}{}
+		}
+	}
+	_, forwardDrifted := driftedChains[util.IptablesForwardChain]
+	if forwardDrifted {
+		delete(driftedChains, util.IptablesForwardChain)
+	}
+
+	drift := pMgr.kernelDrift(driftedChains)
+	if forwardDrifted {
+		drift.BaseChains = append(drift.BaseChains, util.IptablesForwardChain)
+	}
+	if drift.IsEmpty() {
+		return drift, nil
+	}
+
+	klog.Infof("[PolicyManager] repairing chains which drifted from the cache: %+v", drift)
+	if len(driftedChains) > 0 {
+		if err := pMgr.restoreForEachFamily(creator); err != nil {
+			return drift, npmerrors.SimpleErrorWrapper("failed to restore drifted chains", err)
+		}
+	}
+	if forwardDrifted {
+		for _, cmds := range pMgr.iptablesCommandsForEachFamily() {
+			if err := pMgr.positionAzureChainJumpRule(cmds); err != nil {
+				return drift, err
+			}
+		}
+	}
+	return drift, nil
+}
+
+// creatorForKernelState writes the restore file which renders all NPM chains for the cached policies.
+// It also returns the rules the file writes to each chain, normalized by normalizedRule.
+// The chains of a tier without policies are skipped since their rules depend on whether the tier ever had policies.
+func (pMgr *PolicyManager) creatorForKernelState() (*ioutil.FileCreator, map[string][]string) {
+	keys := make([]string, 0, len(pMgr.policyMap.cache))
+	for key := range pMgr.policyMap.cache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	networkPolicies := make([]*NPMNetworkPolicy, 0, len(keys))
+	for _, key := range keys {
+		networkPolicies = append(networkPolicies, pMgr.policyMap.cache[key])
+	}
+
+	tiersWithPolicies := make(map[PolicyTier]struct{})
+	for _, networkPolicy := range networkPolicies {
+		if networkPolicy.isTiered() {
+			tiersWithPolicies[networkPolicy.Tier] = struct{}{}
+		}
+	}
+	skippedChains := make(map[string]struct{})
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		if _, ok := tiersWithPolicies[tier]; !ok {
+			ingressChain, egressChain := tierChainNames(tier)
+			skippedChains[ingressChain] = struct{}{}
+			skippedChains[egressChain] = struct{}{}
+		}
+	}
+
+	chains := make([]string, 0, len(iptablesAzureChains))
+	for _, chain := range iptablesAzureChains {
+		if _, ok := skippedChains[chain]; !ok {
+			chains = append(chains, chain)
+		}
+	}
+	chains = append(chains, chainNames(networkPolicies)...)
+
+	// declaring the chains flushes them
+	creator := pMgr.newCreatorWithChains(chains)
+	if len(networkPolicies) > 0 {
+		writeActivationRules(creator)
+	}
+	for _, networkPolicy := range networkPolicies {
+		pMgr.writeNetworkPolicyRules(creator, networkPolicy)
+		if networkPolicy.isTiered() {
+			continue
+		}
+
+		// jumps to the policy chains precede the base rules of AZURE-NPM-INGRESS and AZURE-NPM-EGRESS chains
+		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+		if hasIngress {
+			creator.AddLine("", nil, append([]string{util.IptablesAppendFlag, util.IptablesAzureIngressChain}, ingressJumpSpecs(networkPolicy)...)...)
+		}
+		if hasEgress {
+			creator.AddLine("", nil, append([]string{util.IptablesAppendFlag, util.IptablesAzureEgressChain}, egressJumpSpecs(networkPolicy)...)...)
+		}
+	}
+	writeBaseChainRules(creator)
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		if _, ok := tiersWithPolicies[tier]; ok {
+			writeTierRules(creator, tier, pMgr.tierPolicies(tier, nil, ""))
+		}
+	}
+	creator.AddLine("", nil, util.IptablesRestoreCommit)
+
+	expectedRules := make(map[string][]string, len(chains))
+	for _, chain := range chains {
+		expectedRules[chain] = []string{}
+	}
+	for _, line := range strings.Split(creator.ToString(), "\n") {
+		specs := strings.Split(line, " ")
+		if len(specs) > 2 && specs[0] == util.IptablesAppendFlag {
+			rule := parse.Rule([]byte(strings.Join(savedRuleSpecs(specs[2:]), " ")))
+			expectedRules[specs[1]] = append(expectedRules[specs[1]], normalizedRule(rule))
+		}
+	}
+	return creator, expectedRules
+}
+
+// savedRuleSpecs rewrites the specs of a rule the way iptables-save prints them:
+// protocols are lowercase, ports are matched by the module of their protocol, and MARK targets set an xmark with a mask.
+func savedRuleSpecs(specs []string) []string {
+	saved := make([]string, 0, len(specs)+2)
+	for i := 0; i < len(specs); i++ {
+		switch {
+		case specs[i] == util.IptablesProtFlag && i+1 < len(specs):
+			saved = append(saved, specs[i], strings.ToLower(specs[i+1]))
+			i++
+		case specs[i] == util.IptablesDstPortFlag && i >= 2 && specs[i-2] == util.IptablesProtFlag:
+			saved = append(saved, util.IptablesModuleFlag, strings.ToLower(specs[i-1]), specs[i])
+		case specs[i] == util.IptablesSetMarkFlag && i+1 < len(specs):
+			mark := specs[i+1]
+			if !strings.Contains(mark, "/") {
+				mark += "/" + fullMarkMask
+			}
+			saved = append(saved, setXMarkFlag, mark)
+			i++
+		default:
+			saved = append(saved, specs[i])
+		}
+	}
+	return saved
+}
+
+// normalizedRule prints the rule independently of the order of its matches and options.
+func normalizedRule(rule *NPMIPtable.Rule) string {
+	modules := make([]string, 0, len(rule.Modules))
+	for _, module := range rule.Modules {
+		modules = append(modules, module.Verb+" "+normalizedOptions(module.OptionValueMap))
+	}
+	sort.Strings(modules)
+	target := ""
+	if rule.Target != nil {
+		target = rule.Target.Name + " " + normalizedOptions(rule.Target.OptionValueMap)
+	}
+	return fmt.Sprintf("protocol: %s, modules: [%s], target: %s", rule.Protocol, strings.Join(modules, "; "), target)
+}
+
+func normalizedOptions(optionValueMap map[string][]string) string {
+	options := make([]string, 0, len(optionValueMap))
+	for option, values := range optionValueMap {
+		options = append(options, "--"+option+" "+strings.Join(values, " "))
+	}
+	sort.Strings(options)
+	return strings.Join(options, " ")
+}
+
+// driftedChains returns the chains which are missing or whose rules differ from the expected rules in the current iptables.
+// FORWARD chain drifted if it doesn't jump to AZURE-NPM chain.
+func (pMgr *PolicyManager) driftedChains(cmds util.IptablesCommands, expectedRules map[string][]string) (map[string]struct{}, error) {
+	parser := &parse.IPTablesParser{IOShim: pMgr.ioShim, IptablesSave: cmds.IptablesSave}
+	table, err := parser.Iptables(util.IptablesFilterTable)
+	if err != nil {
+		return nil, npmerrors.SimpleErrorWrapper(fmt.Sprintf("failed to list rules with %s while checking for drift", cmds.IptablesSave), err)
+	}
+
+	driftedChains := make(map[string]struct{})
+	for chain, rules := range expectedRules {
+		kernelChain, ok := table.Chains[chain]
+		if !ok || len(kernelChain.Rules) != len(rules) {
+			driftedChains[chain] = struct{}{}
+			continue
+		}
+		for i, rule := range kernelChain.Rules {
+			if normalizedRule(rule) != rules[i] {
+				klog.Infof("[PolicyManager] rule %d of chain %s drifted. expected: %s. actual: %s", i+1, chain, rules[i], normalizedRule(rule))
+				driftedChains[chain] = struct{}{}
+				break
+			}
+		}
+	}
+
+	hasAzureJump := false
+	if forwardChain, ok := table.Chains[util.IptablesForwardChain]; ok {
+		for _, rule := range forwardChain.Rules {
+			if rule.Target != nil && rule.Target.Name == util.IptablesAzureChain {
+				hasAzureJump = true
+				break
+			}
+		}
+	}
+	if !hasAzureJump {
+		driftedChains[util.IptablesForwardChain] = struct{}{}
+	}
+	return driftedChains, nil
+}
+
+// kernelDrift maps the drifted chains to the keys of their policies.
+func (pMgr *PolicyManager) kernelDrift(driftedChains map[string]struct{}) *KernelDrift {
+	drift := &KernelDrift{}
+	policyKeys := make(map[string]struct{})
+	for key, networkPolicy := range pMgr.policyMap.cache {
+		for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+			if _, ok := driftedChains[chain]; ok {
+				policyKeys[key] = struct{}{}
+			}
+		}
+	}
+	for key := range policyKeys {
+		drift.Policies = append(drift.Policies, key)
+	}
+	sort.Strings(drift.Policies)
+
+	for chain := range driftedChains {
+		if isBaseChain(chain) {
+			drift.BaseChains = append(drift.BaseChains, chain)
+		}
+	}
+	sort.Strings(drift.BaseChains)
+	return drift
+}
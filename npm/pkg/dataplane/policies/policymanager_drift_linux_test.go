@@ -0,0 +1,196 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/parse"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var baseChainRules = []string{
+	"-A AZURE-NPM-INGRESS -j DROP -m mark --mark 0x400/0x400 -m comment --comment DROP-ON-INGRESS-DROP-MARK-0x400/0x400",
+	"-A AZURE-NPM-INGRESS -j AZURE-NPM-INGRESS-BASELINE",
+	"-A AZURE-NPM-INGRESS-ALLOW-MARK -j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+	"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS-ADMIN",
+	"-A AZURE-NPM-INGRESS-ALLOW-MARK -j AZURE-NPM-EGRESS",
+	"-A AZURE-NPM-EGRESS -j DROP -m mark --mark 0x800/0x800 -m comment --comment DROP-ON-EGRESS-DROP-MARK-0x800/0x800",
+	"-A AZURE-NPM-EGRESS -j AZURE-NPM-EGRESS-BASELINE",
+	"-A AZURE-NPM-EGRESS -j AZURE-NPM-ACCEPT -m mark --mark 0x200/0x200 -m comment --comment ACCEPT-ON-INGRESS-ALLOW-MARK-0x200/0x200",
+	"-A AZURE-NPM-ACCEPT -j ACCEPT",
+}
+
+func TestCreatorForKernelState(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), ipsetConfig)
+
+	// without policies, NPM is deactivated
+	creator, expectedRules := pMgr.creatorForKernelState()
+	expectedLines := []string{
+		"*filter",
+		":AZURE-NPM - -",
+		":AZURE-NPM-INGRESS - -",
+		":AZURE-NPM-INGRESS-ALLOW-MARK - -",
+		":AZURE-NPM-EGRESS - -",
+		":AZURE-NPM-ACCEPT - -",
+	}
+	expectedLines = append(expectedLines, baseChainRules...)
+	expectedLines = append(expectedLines, "COMMIT", "")
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+	require.Equal(t, map[string]int{
+		"AZURE-NPM":                    0,
+		"AZURE-NPM-INGRESS":            2,
+		"AZURE-NPM-INGRESS-ALLOW-MARK": 3,
+		"AZURE-NPM-EGRESS":             3,
+		"AZURE-NPM-ACCEPT":             1,
+	}, ruleCounts(expectedRules))
+
+	pMgr.policyMap.cache[egressNetPol.PolicyKey] = egressNetPol
+	pMgr.policyMap.cache[bothDirectionsNetPol.PolicyKey] = bothDirectionsNetPol
+	creator, expectedRules = pMgr.creatorForKernelState()
+	expectedLines = []string{
+		"*filter",
+		":AZURE-NPM - -",
+		":AZURE-NPM-INGRESS - -",
+		":AZURE-NPM-INGRESS-ALLOW-MARK - -",
+		":AZURE-NPM-EGRESS - -",
+		":AZURE-NPM-ACCEPT - -",
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolIngressChain),
+		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
+		fmt.Sprintf(":%s - -", egressNetPolChain),
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS-ADMIN",
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS-ADMIN",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolIngressChain, ingressAllowRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressDropRule),
+		fmt.Sprintf("-A %s %s", bothDirectionsNetPolEgressChain, egressAllowRule),
+		fmt.Sprintf("-A AZURE-NPM-INGRESS %s", ingressEgressNetPolIngressJump),
+		fmt.Sprintf("-A AZURE-NPM-EGRESS %s", ingressEgressNetPolEgressJump),
+		fmt.Sprintf("-A %s %s", egressNetPolChain, egressAllowRule),
+		fmt.Sprintf("-A AZURE-NPM-EGRESS %s", egressNetPolJump),
+	}
+	expectedLines = append(expectedLines, baseChainRules...)
+	expectedLines = append(expectedLines, "COMMIT", "")
+	dptestutils.AssertEqualLines(t, expectedLines, strings.Split(creator.ToString(), "\n"))
+	require.Equal(t, map[string]int{
+		"AZURE-NPM":                      5,
+		"AZURE-NPM-INGRESS":              3,
+		"AZURE-NPM-INGRESS-ALLOW-MARK":   3,
+		"AZURE-NPM-EGRESS":               5,
+		"AZURE-NPM-ACCEPT":               1,
+		bothDirectionsNetPolIngressChain: 2,
+		bothDirectionsNetPolEgressChain:  2,
+		egressNetPolChain:                1,
+	}, ruleCounts(expectedRules))
+}
+
+func TestNormalizedRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		specs     string
+		savedLine string
+	}{
+		{
+			name:      "jump",
+			specs:     "-j AZURE-NPM-INGRESS",
+			savedLine: "-j AZURE-NPM-INGRESS",
+		},
+		{
+			name:      "mark with port",
+			specs:     "-j MARK --set-mark 0x4000 -p TCP --dport 222:333 -m set --match-set azure-npm-123 src -m set ! --match-set azure-npm-456 dst -m comment --comment DROP-FROM-x",
+			savedLine: "-p tcp -m tcp --dport 222:333 -m set --match-set azure-npm-123 src -m set ! --match-set azure-npm-456 dst -m comment --comment DROP-FROM-x -j MARK --set-xmark 0x4000/0xffffffff",
+		},
+		{
+			name:      "mark with mask",
+			specs:     "-j MARK --set-mark 0x200/0x200 -m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200",
+			savedLine: "-m comment --comment SET-INGRESS-ALLOW-MARK-0x200/0x200 -j MARK --set-xmark 0x200/0x200",
+		},
+		{
+			name:      "nflog",
+			specs:     "-j NFLOG --nflog-group 100 --nflog-prefix NPM-DROP-IN:123 -p UDP --dport 53 -m mark ! --mark 0x100/0x100",
+			savedLine: "-p udp -m udp --dport 53 -m mark ! --mark 0x100/0x100 -j NFLOG --nflog-prefix NPM-DROP-IN:123 --nflog-group 100",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			expected := normalizedRule(parse.Rule([]byte(strings.Join(savedRuleSpecs(strings.Split(tt.specs, " ")), " "))))
+			require.Equal(t, expected, normalizedRule(parse.Rule([]byte(tt.savedLine))))
+		})
+	}
+
+	// a different match set is a different rule
+	require.NotEqual(t,
+		normalizedRule(parse.Rule([]byte("-m set --match-set azure-npm-123 src -j ACCEPT"))),
+		normalizedRule(parse.Rule([]byte("-m set --match-set azure-npm-456 src -j ACCEPT"))),
+	)
+}
+
+func TestReconcileWithKernelLinux(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), ipsetConfig)
+	pMgr.policyMap.cache[egressNetPol.PolicyKey] = egressNetPol
+	creator, _ := pMgr.creatorForKernelState()
+
+	// iptables-save prints the chains and rules of the restore file
+	forwardChain := ":FORWARD ACCEPT [0:0]\n-A FORWARD -m conntrack --ctstate NEW -j AZURE-NPM\n"
+	restoreFile := strings.ReplaceAll(creator.ToString(), "--set-mark 0x200/0x200", "--set-xmark 0x200/0x200")
+	saveOutput := strings.Replace(restoreFile, "*filter\n", "*filter\n"+forwardChain, 1)
+	// another agent flushed the policy chain and deleted the jump from FORWARD chain
+	driftedSaveOutput := strings.Replace(restoreFile, fmt.Sprintf("-A %s %s\n", egressNetPolChain, egressAllowRule), "", 1)
+	// another agent replaced the rule of the policy chain
+	replacedRule := strings.Replace(egressAllowRule, "--match-set "+ipsets.TestNamedportSet.HashedName, "--match-set "+ipsets.TestCIDRSet.HashedName, 1)
+	replacedSaveOutput := strings.Replace(saveOutput, egressAllowRule, replacedRule, 1)
+
+	iptablesSaveCommand := []string{"iptables-nft-save", "-t", "filter"}
+	calls := []testutils.TestCmd{
+		{Cmd: iptablesSaveCommand, Stdout: saveOutput},
+		{Cmd: iptablesSaveCommand, Stdout: driftedSaveOutput},
+		fakeIPTablesRestoreCommand,
+		{Cmd: listLineNumbersCommandStrings, PipedToCommand: true},
+		{Cmd: []string{"grep", "AZURE-NPM"}, ExitCode: 1},
+		{Cmd: []string{"iptables-nft", "-w", "60", "-I", "FORWARD", "-j", "AZURE-NPM", "-m", "conntrack", "--ctstate", "NEW"}},
+		{Cmd: iptablesSaveCommand, Stdout: replacedSaveOutput},
+		fakeIPTablesRestoreCommand,
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr.ioShim = ioshim
+
+	drift, err := pMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.True(t, drift.IsEmpty())
+
+	drift, err = pMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.Equal(t, &KernelDrift{
+		BaseChains: []string{util.IptablesForwardChain},
+		Policies:   []string{egressNetPol.PolicyKey},
+	}, drift)
+
+	drift, err = pMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.Equal(t, &KernelDrift{Policies: []string{egressNetPol.PolicyKey}}, drift)
+}
+
+func ruleCounts(expectedRules map[string][]string) map[string]int {
+	counts := make(map[string]int, len(expectedRules))
+	for chain, rules := range expectedRules {
+		counts[chain] = len(rules)
+	}
+	return counts
+}
+
+func TestReconcileWithKernelNFTables(t *testing.T) {
+	pMgr := NewPolicyManager(common.NewMockIOShim(nil), &PolicyManagerCfg{UseNFTables: true})
+	drift, err := pMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.True(t, drift.IsEmpty())
+}
@@ -0,0 +1,104 @@
+package policies
+
+// This file contains code for repairing policies which drifted from the cache in HNS.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Microsoft/hcsshim/hcn"
+	"k8s.io/klog"
+)
+
+// reconcileWithKernel compares the number of ACLs of each policy on each of its endpoints with HNS,
+// and applies the policy to the endpoint again if the ACLs drifted.
+// Endpoints which no longer exist are skipped since the pod controller removes them.
+// Assumes the PolicyMap is locked.
+func (pMgr *PolicyManager) reconcileWithKernel() (*KernelDrift, error) {
+	drift := &KernelDrift{}
+
+	keys := make([]string, 0, len(pMgr.policyMap.cache))
+	for key := range pMgr.policyMap.cache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	endpoints := make(map[string]*hcn.HostComputeEndpoint)
+	var aggregateErr error
+	for _, key := range keys {
+		policy := pMgr.policyMap.cache[key]
+		if len(policy.PodEndpoints) == 0 {
+			continue
+		}
+
+		rules, err := pMgr.getSettingsFromACL(policy)
+		if err != nil {
+			return drift, err
+		}
+		epPolicyRequest, err := getEPPolicyReqFromACLSettings(rules)
+		if err != nil {
+			return drift, err
+		}
+
+		drifted := false
+		for _, epID := range policy.PodEndpoints {
+			epObj, ok := endpoints[epID]
+			if !ok {
+				timer := metrics.StartNewTimer()
+				epObj, err = pMgr.ioShim.Hns.GetEndpointByID(epID)
+				metrics.RecordGetEndpointLatency(timer)
+				if err != nil {
+					if isNotFoundErr(err) || strings.Contains(err.Error(), "endpoint was not found") {
+						continue
+					}
+					metrics.IncGetEndpointFailures()
+					return drift, fmt.Errorf("[PolicyManagerWindows] failed to get the endpoint while checking for drift. endpoint: %s, err: %w", epID, err)
+				}
+				endpoints[epID] = epObj
+			}
+
+			var epBuilder *endpointPolicyBuilder
+			epBuilder, err = splitEndpointPolicies(epObj.Policies)
+			if err != nil {
+				return drift, fmt.Errorf("couldn't split endpoint policies while checking for drift. endpoint: %s, err: %w", epID, err)
+			}
+			numACLs := 0
+			for _, acl := range epBuilder.aclPolicies {
+				if acl.Id == policy.ACLPolicyID {
+					numACLs++
+				}
+			}
+			if numACLs == len(rules) {
+				continue
+			}
+
+			drifted = true
+			klog.Infof("[PolicyManagerWindows] repairing policy %s on endpoint %s. found %d of %d ACLs", key, epID, numACLs, len(rules))
+			// the endpoint is fetched again after it's updated
+			delete(endpoints, epID)
+			if numACLs > 0 {
+				err = pMgr.removePolicyByEndpointID(policy.ACLPolicyID, epID, numACLs, removeOnlyGivenPolicy)
+			}
+			if err == nil {
+				err = pMgr.applyPoliciesToEndpointID(epID, epPolicyRequest)
+			}
+			if err != nil {
+				if aggregateErr == nil {
+					aggregateErr = fmt.Errorf("failed to repair policy %s on %s ID Endpoint with err: %w", key, epID, err)
+				} else {
+					aggregateErr = fmt.Errorf("failed to repair policy %s on %s ID Endpoint with err: %s. previous err: [%w]", key, epID, err.Error(), aggregateErr)
+				}
+			}
+		}
+		if drifted {
+			drift.Policies = append(drift.Policies, key)
+		}
+	}
+
+	if aggregateErr != nil {
+		return drift, fmt.Errorf("[PolicyManagerWindows] %w", aggregateErr)
+	}
+	return drift, nil
+}
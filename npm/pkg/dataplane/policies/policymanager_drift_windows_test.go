@@ -0,0 +1,33 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Microsoft/hcsshim/hcn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileWithKernelWindows(t *testing.T) {
+	metrics.InitializeWindowsMetrics()
+
+	pMgr, hns := getPMgr(t)
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{TestNetworkPolicies[0]}, endpointIDListCopy()))
+
+	drift, err := pMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.True(t, drift.IsEmpty())
+
+	// another agent removed the ACLs of an endpoint
+	require.NoError(t, hns.ApplyEndpointPolicy(&hcn.HostComputeEndpoint{Id: "test1"}, hcn.RequestTypeUpdate, hcn.PolicyEndpointRequest{}))
+
+	drift, err = pMgr.ReconcileWithKernel()
+	require.NoError(t, err)
+	require.Equal(t, &KernelDrift{Policies: []string{TestNetworkPolicies[0].PolicyKey}}, drift)
+
+	aclPolicies, err := hns.Cache.ACLPolicies(endPointIDList, TestNetworkPolicies[0].ACLPolicyID)
+	require.NoError(t, err)
+	for _, id := range endPointIDList {
+		verifyFakeHNSCacheACLs(t, expectedACLs, aclPolicies[id])
+	}
+}
@@ -4,6 +4,9 @@ package policies
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/util"
@@ -42,6 +45,10 @@ Known errors that we should retry on:
 */
 
 func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ map[string]string) error {
+	if pMgr.UseNFTables {
+		return pMgr.addNFTPolicies(networkPolicies)
+	}
+
 	// 1. Add rules for the network policies and activate NPM (if necessary).
 	chainsToCreate := chainNames(networkPolicies)
 	creator := pMgr.creatorForNewNetworkPolicies(chainsToCreate, networkPolicies)
@@ -51,7 +58,7 @@ func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ ma
 	defer pMgr.reconcileManager.forceUnlock()
 
 	timer := metrics.StartNewTimer()
-	err := restore(creator)
+	err := pMgr.restoreForEachFamily(creator)
 	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
 	if err != nil {
 		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
@@ -66,8 +73,12 @@ func (pMgr *PolicyManager) addPolicies(networkPolicies []*NPMNetworkPolicy, _ ma
 }
 
 func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[string]string) error {
+	if pMgr.UseNFTables {
+		return pMgr.removeNFTPolicy(networkPolicy)
+	}
+
 	chainsToDelete := chainNames([]*NPMNetworkPolicy{networkPolicy})
-	creator := pMgr.creatorForRemovingPolicies(chainsToDelete)
+	creator := pMgr.creatorForRemovingPolicies(networkPolicy, chainsToDelete)
 
 	// Stop reconciling so we don't contend for iptables, and so we don't update the staleChains at the same time as reconcile()
 	pMgr.reconcileManager.forceLock()
@@ -75,14 +86,18 @@ func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[s
 
 	// 1. Delete jump rules from ingress/egress chains to ingress/egress policy chains.
 	// We ought to delete these jump rules here in the foreground since if we add an NP back after deleting, iptables-restore --noflush can add duplicate jump rules.
-	deleteErr := pMgr.deleteOldJumpRulesOnRemove(networkPolicy)
-	if deleteErr != nil {
-		return fmt.Errorf("failed to delete jumps to policy chains. err: %w", deleteErr)
+	// The tier chains of tiered policies are rendered again in the restore file instead.
+	if !networkPolicy.isTiered() {
+		for _, cmds := range pMgr.iptablesCommandsForEachFamily() {
+			if deleteErr := pMgr.deleteOldJumpRulesOnRemove(cmds, networkPolicy); deleteErr != nil {
+				return fmt.Errorf("failed to delete jumps to policy chains. err: %w", deleteErr)
+			}
+		}
 	}
 
 	// 2. Flush the policy chains and deactivate NPM (if necessary).
 	timer := metrics.StartNewTimer()
-	restoreErr := restore(creator)
+	restoreErr := pMgr.restoreForEachFamily(creator)
 	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
 	if restoreErr != nil {
 		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
@@ -96,23 +111,63 @@ func (pMgr *PolicyManager) removePolicy(networkPolicy *NPMNetworkPolicy, _ map[s
 	return nil
 }
 
-func restore(creator *ioutil.FileCreator) error {
-	err := creator.RunCommandWithFile(util.IptablesRestore, util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
+// restore runs the file with cmds.IptablesRestore, which is the iptables-restore or ip6tables-restore command of the detected iptables version.
+func restore(cmds util.IptablesCommands, creator *ioutil.FileCreator) error {
+	err := creator.RunCommandWithFile(cmds.IptablesRestore, util.IptablesWaitFlag, util.IptablesDefaultWaitTime, util.IptablesRestoreTableFlag, util.IptablesFilterTable, util.IptablesRestoreNoFlushFlag)
 	if err != nil {
 		return fmt.Errorf("failed to restore iptables file. err: %w", err)
 	}
 	return nil
 }
 
+// restoreForEachFamily restores the file in iptables and, if IPv6 is enabled, in ip6tables with the IPv6 ipsets.
+func (pMgr *PolicyManager) restoreForEachFamily(creator *ioutil.FileCreator) error {
+	if !pMgr.EnableIPv6 {
+		return restore(util.CurrentIptablesCommands(false), creator)
+	}
+
+	// copy the file before restoring since failed lines are omitted from the original
+	ipv6Creator := creator.Transformed(ipv6RuleContent)
+	if err := restore(util.CurrentIptablesCommands(false), creator); err != nil {
+		return err
+	}
+	if err := restore(util.CurrentIptablesCommands(true), ipv6Creator); err != nil {
+		return fmt.Errorf("failed to restore ip6tables. err: %w", err)
+	}
+	return nil
+}
+
+// ipv6RuleContent matches the IPv6 counterparts of the ipsets in a line of an iptables-restore file
+func ipv6RuleContent(content string) string {
+	return strings.Join(ipv6RuleSpecs(strings.Split(content, " ")), " ")
+}
+
+// ipv6RuleSpecs matches the IPv6 counterparts of the ipsets in iptables rule specs
+func ipv6RuleSpecs(specs []string) []string {
+	result := make([]string, len(specs))
+	copy(result, specs)
+	for i := 1; i < len(result); i++ {
+		if result[i-1] == util.IptablesMatchSetFlag {
+			result[i] = util.GetIPv6HashedName(result[i])
+		}
+	}
+	return result
+}
+
 // NOTE: if removing multiple policies, would need to add a isLastPolicy argument instead
-func (pMgr *PolicyManager) creatorForRemovingPolicies(allChainNames []string) *ioutil.FileCreator {
+func (pMgr *PolicyManager) creatorForRemovingPolicies(networkPolicy *NPMNetworkPolicy, allChainNames []string) *ioutil.FileCreator {
 	creator := pMgr.newCreatorWithChains(nil)
 	// 1. Deactivate NPM (if necessary).
 	if pMgr.isLastPolicy() {
 		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain)
 	}
 
-	// 2. Flush the policy chains.
+	// 2. Stop jumping to the policy chains of a tiered policy.
+	if networkPolicy.isTiered() {
+		writeTierRules(creator, networkPolicy.Tier, pMgr.tierPolicies(networkPolicy.Tier, nil, networkPolicy.PolicyKey))
+	}
+
+	// 3. Flush the policy chains.
 	for _, chainName := range allChainNames {
 		creator.AddLine("", nil, util.IptablesFlushFlag, chainName)
 	}
@@ -151,22 +206,22 @@ func (pMgr *PolicyManager) newCreatorWithChains(chainNames []string) *ioutil.Fil
 }
 
 // will make a similar func for on update eventually
-func (pMgr *PolicyManager) deleteOldJumpRulesOnRemove(policy *NPMNetworkPolicy) error {
+func (pMgr *PolicyManager) deleteOldJumpRulesOnRemove(cmds util.IptablesCommands, policy *NPMNetworkPolicy) error {
 	shouldDeleteIngress, shouldDeleteEgress := policy.hasIngressAndEgress()
 	if shouldDeleteIngress {
-		if err := pMgr.deleteJumpRule(policy, true); err != nil {
+		if err := pMgr.deleteJumpRule(cmds, policy, true); err != nil {
 			return err
 		}
 	}
 	if shouldDeleteEgress {
-		if err := pMgr.deleteJumpRule(policy, false); err != nil {
+		if err := pMgr.deleteJumpRule(cmds, policy, false); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (pMgr *PolicyManager) deleteJumpRule(policy *NPMNetworkPolicy, direction UniqueDirection) error {
+func (pMgr *PolicyManager) deleteJumpRule(cmds util.IptablesCommands, policy *NPMNetworkPolicy, direction UniqueDirection) error {
 	var specs []string
 	var baseChainName string
 	var chainName string
@@ -180,9 +235,12 @@ func (pMgr *PolicyManager) deleteJumpRule(policy *NPMNetworkPolicy, direction Un
 		chainName = policy.egressChainName()
 	}
 
+	if cmds.IsIPv6() {
+		specs = ipv6RuleSpecs(specs)
+	}
 	specs = append([]string{baseChainName}, specs...)
 	timer := metrics.StartNewTimer()
-	errCode, err := pMgr.runIPTablesCommand(util.IptablesDeletionFlag, specs...)
+	errCode, err := pMgr.runIPTablesCommand(cmds, util.IptablesDeletionFlag, specs...)
 	metrics.RecordIPTablesDeleteLatency(timer)
 	// if this actually happens (don't think it should), could use ignoreErrorsAndRunIPTablesCommand instead with: "Bad rule (does a matching rule exist in that chain?)"
 	if err != nil && errCode != doesNotExistErrorCode && errCode != couldntLoadTargetErrorCode {
@@ -215,17 +273,22 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 	// 1. Activate NPM if necessary
 	if pMgr.isFirstPolicy() {
 		creator.AddLine("", nil, util.IptablesFlushFlag, util.IptablesAzureChain) // flush just in case there are old rules
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureIngressChain)
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
-		creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain)
+		writeActivationRules(creator)
 	}
 
 	// 2. Add all rules for the network policies
 	ingressJumpLineNumber := 1
 	egressJumpLineNumber := 1
+	changedTiers := make(map[PolicyTier]struct{})
 	for _, networkPolicy := range networkPolicies {
 		// 2.1 add all rules for the policy chain(s)
-		writeNetworkPolicyRules(creator, networkPolicy)
+		pMgr.writeNetworkPolicyRules(creator, networkPolicy)
+
+		if networkPolicy.isTiered() {
+			// jumps to tiered policies are ordered by priority, so the whole tier chain is rendered below
+			changedTiers[networkPolicy.Tier] = struct{}{}
+			continue
+		}
 
 		// 2.2 add jump rule(s) to the policy chain(s)
 		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
@@ -240,13 +303,106 @@ func (pMgr *PolicyManager) creatorForNewNetworkPolicies(policyChains []string, n
 			egressJumpLineNumber++
 		}
 	}
+
+	// 3. Render the tier chains with jumps to the cached and new policies of the tier
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		if _, ok := changedTiers[tier]; ok {
+			writeTierRules(creator, tier, pMgr.tierPolicies(tier, networkPolicies, ""))
+		}
+	}
 	creator.AddLine("", nil, util.IptablesRestoreCommit)
 	return creator
 }
 
+// writeActivationRules writes the rules of AZURE-NPM chain, which jump to the tier chains and base chains.
+func writeActivationRules(creator *ioutil.FileCreator) {
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureIngressAdminChain)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureIngressChain)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureEgressAdminChain)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureEgressChain)
+	creator.AddLine("", nil, util.IptablesAppendFlag, util.IptablesAzureChain, util.IptablesJumpFlag, util.IptablesAzureAcceptChain)
+}
+
+// tierPolicies returns the cached policies of the tier plus the policies to add and minus the policy to remove,
+// ordered by priority and then by key.
+func (pMgr *PolicyManager) tierPolicies(tier PolicyTier, toAdd []*NPMNetworkPolicy, keyToRemove string) []*NPMNetworkPolicy {
+	policies := make(map[string]*NPMNetworkPolicy)
+	for key, policy := range pMgr.policyMap.cache {
+		if policy.Tier == tier && key != keyToRemove {
+			policies[key] = policy
+		}
+	}
+	for _, policy := range toAdd {
+		if policy.Tier == tier {
+			policies[policy.PolicyKey] = policy
+		}
+	}
+
+	result := make([]*NPMNetworkPolicy, 0, len(policies))
+	for _, policy := range policies {
+		result = append(result, policy)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Priority != result[j].Priority {
+			return result[i].Priority < result[j].Priority
+		}
+		return result[i].PolicyKey < result[j].PolicyKey
+	})
+	return result
+}
+
+func tierChainNames(tier PolicyTier) (ingressChain, egressChain string) {
+	if tier == AdminTier {
+		return util.IptablesAzureIngressAdminChain, util.IptablesAzureEgressAdminChain
+	}
+	return util.IptablesAzureIngressBaselineChain, util.IptablesAzureEgressBaselineChain
+}
+
+// writeTierRules flushes the tier chains and jumps to the policy chains of the tier in order.
+// In the admin tier, a policy which passes a flow sets the pass mark, and the remaining policies are skipped.
+func writeTierRules(creator *ioutil.FileCreator, tier PolicyTier, tierPolicies []*NPMNetworkPolicy) {
+	ingressChain, egressChain := tierChainNames(tier)
+	creator.AddLine("", nil, util.IptablesFlushFlag, ingressChain)
+	creator.AddLine("", nil, util.IptablesFlushFlag, egressChain)
+
+	var skipOnPassSpecs []string
+	if tier == AdminTier {
+		for _, chain := range []string{ingressChain, egressChain} {
+			clearSpecs := []string{util.IptablesAppendFlag, chain}
+			clearSpecs = append(clearSpecs, setMarkSpecs(util.IptablesAzureClearPassMarkHex)...)
+			clearSpecs = append(clearSpecs, commentSpecs(fmt.Sprintf("CLEAR-PASS-MARK-%s", util.IptablesAzurePassMarkHex))...)
+			creator.AddLine("", nil, clearSpecs...)
+		}
+		skipOnPassSpecs = notOnMarkSpecs(util.IptablesAzurePassMarkHex)
+	}
+
+	for _, networkPolicy := range tierPolicies {
+		hasIngress, hasEgress := networkPolicy.hasIngressAndEgress()
+		if hasIngress {
+			specs := []string{util.IptablesAppendFlag, ingressChain, util.IptablesJumpFlag, networkPolicy.ingressChainName()}
+			specs = append(specs, matchSetSpecsForNetworkPolicy(networkPolicy, DstMatch)...)
+			specs = append(specs, skipOnPassSpecs...)
+			specs = append(specs, commentSpecs(networkPolicy.commentForJumpToIngress())...)
+			creator.AddLine("", nil, specs...)
+		}
+		if hasEgress {
+			specs := []string{util.IptablesAppendFlag, egressChain, util.IptablesJumpFlag, networkPolicy.egressChainName()}
+			specs = append(specs, matchSetSpecsForNetworkPolicy(networkPolicy, SrcMatch)...)
+			specs = append(specs, skipOnPassSpecs...)
+			specs = append(specs, commentSpecs(networkPolicy.commentForJumpToEgress())...)
+			creator.AddLine("", nil, specs...)
+		}
+	}
+}
+
 // write rules for the policy chain(s)
-func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+func (pMgr *PolicyManager) writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
 	for _, aclPolicy := range networkPolicy.ACLs {
+		if networkPolicy.isTiered() {
+			pMgr.writeTieredACLRules(creator, networkPolicy, aclPolicy)
+			continue
+		}
+
 		var chainName string
 		var actionSpecs []string
 		if aclPolicy.hasIngress() {
@@ -264,6 +420,12 @@ func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetw
 				actionSpecs = setMarkSpecs(util.IptablesAzureEgressDropMarkHex)
 			}
 		}
+		if aclPolicy.Target == Dropped {
+			pMgr.writeNFLogRule(creator, chainName, networkPolicy, aclPolicy)
+			if networkPolicy.Audit {
+				continue
+			}
+		}
 		line := []string{"-A", chainName}
 		line = append(line, actionSpecs...)
 		line = append(line, iptablesRuleSpecs(aclPolicy)...)
@@ -271,6 +433,72 @@ func writeNetworkPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetw
 	}
 }
 
+// ACLs of tiered policies decide on a flow right away instead of marking it for the base chains.
+// A passed flow returns to the tier chain with the pass mark set.
+func (pMgr *PolicyManager) writeTieredACLRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy, aclPolicy *ACLPolicy) {
+	chainName := networkPolicy.egressChainName()
+	allowSpecs := []string{util.IptablesJumpFlag, util.IptablesAzureAcceptChain}
+	if aclPolicy.hasIngress() {
+		chainName = networkPolicy.ingressChainName()
+		allowSpecs = []string{util.IptablesJumpFlag, util.IptablesAzureIngressAllowMarkChain}
+	}
+
+	var actionSpecs []string
+	switch aclPolicy.Target {
+	case Allowed:
+		actionSpecs = allowSpecs
+	case Passed:
+		actionSpecs = setMarkSpecs(util.IptablesAzurePassMarkHex)
+	default:
+		pMgr.writeNFLogRule(creator, chainName, networkPolicy, aclPolicy)
+		if networkPolicy.Audit {
+			return
+		}
+		actionSpecs = []string{util.IptablesJumpFlag, util.IptablesDrop}
+	}
+	line := []string{"-A", chainName}
+	line = append(line, actionSpecs...)
+	line = append(line, iptablesRuleSpecs(aclPolicy)...)
+	creator.AddLine("", nil, line...)
+
+	if aclPolicy.Target == Passed {
+		returnSpecs := []string{"-A", chainName, util.IptablesJumpFlag, util.IptablesReturn}
+		returnSpecs = append(returnSpecs, onMarkSpecs(util.IptablesAzurePassMarkHex)...)
+		creator.AddLine("", nil, returnSpecs...)
+	}
+}
+
+// writeNFLogRule sends the flows of a Dropped ACL to the NFLOG group of denied flows.
+// It writes nothing unless denied flows are logged or the policy is audited.
+// NFLOG is a non-terminating target, so the ACL's own rule still applies afterwards.
+func (pMgr *PolicyManager) writeNFLogRule(creator *ioutil.FileCreator, chainName string, networkPolicy *NPMNetworkPolicy, aclPolicy *ACLPolicy) {
+	if !pMgr.LogDeniedFlows && !networkPolicy.Audit {
+		return
+	}
+	line := []string{
+		"-A", chainName,
+		util.IptablesJumpFlag, util.IptablesNFLog,
+		util.IptablesNFLogGroupFlag, strconv.Itoa(pMgr.NFLogGroup),
+		util.IptablesNFLogPrefixFlag, networkPolicy.nflogPrefix(aclPolicy.hasIngress()),
+	}
+	line = append(line, iptablesRuleSpecs(aclPolicy)...)
+	creator.AddLine("", nil, line...)
+}
+
+// nflogPrefix identifies the policy and direction of a denied flow, e.g. "NPM-DROP-IN:1234567".
+// The policy is identified by the hash of its PolicyKey, which its chain names use too.
+func (networkPolicy *NPMNetworkPolicy) nflogPrefix(ingress bool) string {
+	prefix := util.NFLogDropPrefix
+	if networkPolicy.Audit {
+		prefix = util.NFLogAuditPrefix
+	}
+	direction := Egress
+	if ingress {
+		direction = Ingress
+	}
+	return fmt.Sprintf("%s-%s:%s", prefix, direction, util.Hash(networkPolicy.PolicyKey))
+}
+
 func iptablesRuleSpecs(aclPolicy *ACLPolicy) []string {
 	specs := make([]string, 0)
 	if aclPolicy.Protocol != UnspecifiedProtocol {
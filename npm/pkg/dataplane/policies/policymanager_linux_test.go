@@ -236,7 +236,9 @@ func TestCreatorForAddPolicies(t *testing.T) {
 		fmt.Sprintf(":%s - -", bothDirectionsNetPolEgressChain),
 		"-F AZURE-NPM",
 		// activation rules for AZURE-NPM chain
+		"-A AZURE-NPM -j AZURE-NPM-INGRESS-ADMIN",
 		"-A AZURE-NPM -j AZURE-NPM-INGRESS",
+		"-A AZURE-NPM -j AZURE-NPM-EGRESS-ADMIN",
 		"-A AZURE-NPM -j AZURE-NPM-EGRESS",
 		"-A AZURE-NPM -j AZURE-NPM-ACCEPT",
 		// policy 1
@@ -290,7 +292,7 @@ func TestCreatorForRemovePolicies(t *testing.T) {
 
 	// 1. test without deactivation (i.e. flushing azure chain when removing the last policy)
 	// hack: the cache is empty (and len(cache) != len(allTestNetworkPolicies)), so shouldDeactivate will be false
-	creator := pMgr.creatorForRemovingPolicies(chainNames(allTestNetworkPolicies))
+	creator := pMgr.creatorForRemovingPolicies(bothDirectionsNetPol, chainNames(allTestNetworkPolicies))
 	actualLines := strings.Split(creator.ToString(), "\n")
 	expectedLines := []string{
 		"*filter",
@@ -307,7 +309,7 @@ func TestCreatorForRemovePolicies(t *testing.T) {
 	// add to the cache so that we deactivate
 	policy := TestNetworkPolicies[0]
 	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{policy}, nil))
-	creator = pMgr.creatorForRemovingPolicies(chainNames([]*NPMNetworkPolicy{policy}))
+	creator = pMgr.creatorForRemovingPolicies(policy, chainNames([]*NPMNetworkPolicy{policy}))
 	actualLines = strings.Split(creator.ToString(), "\n")
 	expectedLines = []string{
 		"*filter",
@@ -435,3 +437,231 @@ func TestUpdatingStaleChains(t *testing.T) {
 	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
 	assertStaleChainsContain(t, pMgr.staleChains, egressNetPolChain)
 }
+
+// tiered policies
+var (
+	ingressPassedACL = &ACLPolicy{
+		SrcList: []SetInfo{
+			{
+				ipsets.TestCIDRSet.Metadata,
+				true,
+				SrcMatch,
+			},
+		},
+		Target:    Passed,
+		Direction: Ingress,
+		Protocol:  UnspecifiedProtocol,
+	}
+
+	adminNetPol = &NPMNetworkPolicy{
+		PolicyKey:   "AdminNetworkPolicy/test-anp",
+		ACLPolicyID: "azure-acl-AdminNetworkPolicy-test-anp",
+		PodSelectorIPSets: []*ipsets.TranslatedIPSet{
+			{Metadata: ipsets.TestNSSet.Metadata},
+		},
+		PodSelectorList: []SetInfo{
+			{
+				IPSet:     ipsets.TestNSSet.Metadata,
+				Included:  true,
+				MatchType: EitherMatch,
+			},
+		},
+		ACLs: []*ACLPolicy{
+			ingressPassedACL,
+			egressDeniedACL,
+		},
+		Tier:     AdminTier,
+		Priority: 10,
+	}
+	higherPriorityAdminNetPol = &NPMNetworkPolicy{
+		PolicyKey:   "AdminNetworkPolicy/test-anp-2",
+		ACLPolicyID: "azure-acl-AdminNetworkPolicy-test-anp-2",
+		ACLs: []*ACLPolicy{
+			ingressAllowedACL,
+		},
+		Tier:     AdminTier,
+		Priority: 5,
+	}
+	baselineNetPol = &NPMNetworkPolicy{
+		PolicyKey:   "BaselineAdminNetworkPolicy/default",
+		ACLPolicyID: "azure-acl-BaselineAdminNetworkPolicy-default",
+		ACLs: []*ACLPolicy{
+			egressAllowedACL,
+		},
+		Tier: BaselineTier,
+	}
+)
+
+const (
+	ingressPassComment = "PASS-FROM-cidr-test-cidr-set"
+)
+
+var (
+	adminNetPolIngressJump = fmt.Sprintf(
+		"-j %s -m set --match-set %s dst -m mark ! --mark 0x100/0x100 -m comment --comment INGRESS-POLICY-AdminNetworkPolicy/test-anp-TO-ns-test-ns-set",
+		adminNetPol.ingressChainName(),
+		ipsets.TestNSSet.HashedName,
+	)
+	adminNetPolEgressJump = fmt.Sprintf(
+		"-j %s -m set --match-set %s src -m mark ! --mark 0x100/0x100 -m comment --comment EGRESS-POLICY-AdminNetworkPolicy/test-anp-FROM-ns-test-ns-set",
+		adminNetPol.egressChainName(),
+		ipsets.TestNSSet.HashedName,
+	)
+	higherPriorityAdminNetPolIngressJump = fmt.Sprintf(
+		"-j %s -m mark ! --mark 0x100/0x100 -m comment --comment INGRESS-POLICY-AdminNetworkPolicy/test-anp-2-TO-all",
+		higherPriorityAdminNetPol.ingressChainName(),
+	)
+	adminTierFlushLines = []string{
+		"-F AZURE-NPM-INGRESS-ADMIN",
+		"-F AZURE-NPM-EGRESS-ADMIN",
+		"-A AZURE-NPM-INGRESS-ADMIN -j MARK --set-mark 0x0/0x100 -m comment --comment CLEAR-PASS-MARK-0x100/0x100",
+		"-A AZURE-NPM-EGRESS-ADMIN -j MARK --set-mark 0x0/0x100 -m comment --comment CLEAR-PASS-MARK-0x100/0x100",
+	}
+)
+
+func TestCreatorForTieredPolicies(t *testing.T) {
+	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{adminNetPol}, nil))
+
+	// 1. the admin tier chains jump to the cached and new policies by priority
+	policies := []*NPMNetworkPolicy{higherPriorityAdminNetPol, baselineNetPol}
+	creator := pMgr.creatorForNewNetworkPolicies(chainNames(policies), policies)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf(":%s - -", higherPriorityAdminNetPol.ingressChainName()),
+		fmt.Sprintf(":%s - -", baselineNetPol.egressChainName()),
+		fmt.Sprintf("-A %s %s", higherPriorityAdminNetPol.ingressChainName(), ingressAllowRule),
+		fmt.Sprintf("-A %s -j AZURE-NPM-ACCEPT -m set --match-set %s dst -m comment --comment %s",
+			baselineNetPol.egressChainName(), ipsets.TestNamedportSet.HashedName, egressAllowComment),
+	}
+	expectedLines = append(expectedLines, adminTierFlushLines...)
+	expectedLines = append(expectedLines,
+		fmt.Sprintf("-A AZURE-NPM-INGRESS-ADMIN %s", higherPriorityAdminNetPolIngressJump),
+		fmt.Sprintf("-A AZURE-NPM-INGRESS-ADMIN %s", adminNetPolIngressJump),
+		fmt.Sprintf("-A AZURE-NPM-EGRESS-ADMIN %s", adminNetPolEgressJump),
+		"-F AZURE-NPM-INGRESS-BASELINE",
+		"-F AZURE-NPM-EGRESS-BASELINE",
+		fmt.Sprintf("-A AZURE-NPM-EGRESS-BASELINE -j %s -m comment --comment EGRESS-POLICY-BaselineAdminNetworkPolicy/default-FROM-all",
+			baselineNetPol.egressChainName()),
+		"COMMIT",
+		"",
+	)
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 2. passing, dropping, and allowing rules of a tiered policy decide right away
+	creator = pMgr.newCreatorWithChains(nil)
+	pMgr.writeNetworkPolicyRules(creator, adminNetPol)
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{
+		"*filter",
+		fmt.Sprintf("-A %s -j MARK --set-mark 0x100/0x100 -m set --match-set %s src -m comment --comment %s",
+			adminNetPol.ingressChainName(), ipsets.TestCIDRSet.HashedName, ingressPassComment),
+		fmt.Sprintf("-A %s -j RETURN -m mark --mark 0x100/0x100", adminNetPol.ingressChainName()),
+		fmt.Sprintf("-A %s -j DROP -p UDP --dport 144 -m set --match-set %s dst -m comment --comment %s",
+			adminNetPol.egressChainName(), ipsets.TestCIDRSet.HashedName, egressDropComment),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 3. removing a tiered policy renders its tier chains without it instead of deleting jumps
+	creator = pMgr.creatorForRemovingPolicies(adminNetPol, chainNames([]*NPMNetworkPolicy{adminNetPol}))
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{"*filter", "-F AZURE-NPM"}
+	expectedLines = append(expectedLines, adminTierFlushLines...)
+	expectedLines = append(expectedLines,
+		fmt.Sprintf("-F %s", adminNetPol.ingressChainName()),
+		fmt.Sprintf("-F %s", adminNetPol.egressChainName()),
+		"COMMIT",
+		"",
+	)
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestValidatePassedTarget(t *testing.T) {
+	passingNetPol := &NPMNetworkPolicy{
+		PolicyKey: "x/test-pass",
+		ACLs:      []*ACLPolicy{ingressPassedACL},
+	}
+	require.Error(t, ValidatePolicy(passingNetPol))
+	passingNetPol.Tier = BaselineTier
+	require.Error(t, ValidatePolicy(passingNetPol))
+	passingNetPol.Tier = AdminTier
+	require.NoError(t, ValidatePolicy(passingNetPol))
+}
+
+func TestIPv6RuleSpecs(t *testing.T) {
+	specs := []string{"-j", "AZURE-NPM-INGRESS", "-m", "set", "--match-set", "azure-npm-123", "dst", "-m", "comment", "--comment", "x"}
+	ipv6Specs := ipv6RuleSpecs(specs)
+	require.Equal(t, "azure-npm-123", specs[5], "original specs should be unchanged")
+	require.Equal(t, util.GetIPv6HashedName("azure-npm-123"), ipv6Specs[5])
+	require.Equal(t, specs[:5], ipv6Specs[:5])
+	require.Equal(t, specs[6:], ipv6Specs[6:])
+	require.Equal(t, strings.Join(ipv6Specs, " "), ipv6RuleContent(strings.Join(specs, " ")))
+}
+
+func TestDeniedFlowLogRules(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, &PolicyManagerCfg{
+		PolicyMode:     IPSetPolicyMode,
+		LogDeniedFlows: true,
+		NFLogGroup:     100,
+	})
+	ingressDropMatch := fmt.Sprintf("-p TCP --dport 222:333 -m set --match-set %s src -m set ! --match-set %s dst -m comment --comment %s",
+		ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment)
+
+	// 1. denied flows are logged before they're marked for dropping
+	creator := pMgr.newCreatorWithChains(nil)
+	pMgr.writeNetworkPolicyRules(creator, ingressNetPol)
+	actualLines := strings.Split(creator.ToString(), "\n")
+	expectedLines := []string{
+		"*filter",
+		fmt.Sprintf("-A %s -j NFLOG --nflog-group 100 --nflog-prefix NPM-DROP-IN:%s %s", ingressNetPolChain, util.Hash(ingressNetPol.PolicyKey), ingressDropMatch),
+		fmt.Sprintf("-A %s %s", ingressNetPolChain, ingressDropRule),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 2. audited policies only log, even if denied flows aren't logged otherwise
+	pMgr.LogDeniedFlows = false
+	auditedNetPol := *ingressNetPol
+	auditedNetPol.Audit = true
+	creator = pMgr.newCreatorWithChains(nil)
+	pMgr.writeNetworkPolicyRules(creator, &auditedNetPol)
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{
+		"*filter",
+		fmt.Sprintf("-A %s -j NFLOG --nflog-group 100 --nflog-prefix NPM-AUDIT-IN:%s %s", ingressNetPolChain, util.Hash(ingressNetPol.PolicyKey), ingressDropMatch),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 3. nothing is logged otherwise
+	creator = pMgr.newCreatorWithChains(nil)
+	pMgr.writeNetworkPolicyRules(creator, ingressNetPol)
+	actualLines = strings.Split(creator.ToString(), "\n")
+	expectedLines = []string{
+		"*filter",
+		fmt.Sprintf("-A %s %s", ingressNetPolChain, ingressDropRule),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestPolicyKeyForHash(t *testing.T) {
+	calls := []testutils.TestCmd{fakeIPTablesRestoreCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, ipsetConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{ingressNetPol}, nil))
+	policyKey, ok := pMgr.PolicyKeyForHash(util.Hash(ingressNetPol.PolicyKey))
+	require.True(t, ok)
+	require.Equal(t, ingressNetPol.PolicyKey, policyKey)
+	_, ok = pMgr.PolicyKeyForHash(util.Hash(egressNetPol.PolicyKey))
+	require.False(t, ok)
+}
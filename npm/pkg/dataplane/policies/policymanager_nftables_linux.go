@@ -0,0 +1,421 @@
+package policies
+
+// This file contains code for the nftables implementation of booting up and adding/removing policies.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/npm/metrics"
+	"github.com/Azure/azure-container-networking/npm/util"
+	npmerrors "github.com/Azure/azure-container-networking/npm/util/errors"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	"k8s.io/klog"
+)
+
+const (
+	// nft applies the whole file in one transaction, so there is nothing to salvage from a failed try
+	nftMaxTryCount = 2
+
+	// the forward hook runs before (priority -10) or after (priority 10) the iptables filter FORWARD chain,
+	// which is where the iptables dataplane places the jump to AZURE-NPM
+	nftPriorityBeforeIptables = "-10"
+	nftPriorityAfterIptables  = "10"
+
+	// marks in nftables are the same bits as the iptables marks in NPM v2
+	nftIngressAllowMark = "0x200"
+	nftIngressDropMark  = "0x400"
+	nftEgressDropMark   = "0x800"
+	nftPassMark         = "0x100"
+	// nftClearPassMask keeps every bit of the mark except for the pass mark
+	nftClearPassMask = "0xfffffeff"
+)
+
+var (
+	nftTable = util.NftFamily + " " + util.NftTable
+
+	nftBaseChains = []string{
+		util.IptablesAzureChain,
+		util.IptablesAzureIngressChain,
+		util.IptablesAzureIngressAllowMarkChain,
+		util.IptablesAzureEgressChain,
+		util.IptablesAzureAcceptChain,
+		util.IptablesAzureIngressAdminChain,
+		util.IptablesAzureEgressAdminChain,
+		util.IptablesAzureIngressBaselineChain,
+		util.IptablesAzureEgressBaselineChain,
+	}
+)
+
+/*
+The nftables dataplane mirrors the iptables chain hierarchy in the inet azure-npm table:
+
+	AZURE-NPM-FORWARD (forward hook): ct state new -> AZURE-NPM
+	AZURE-NPM: -> AZURE-NPM-INGRESS-ADMIN, -> AZURE-NPM-INGRESS, -> AZURE-NPM-EGRESS-ADMIN, -> AZURE-NPM-EGRESS,
+		-> AZURE-NPM-ACCEPT (only while there are policies)
+	AZURE-NPM-INGRESS-ADMIN: clear the pass mark, then jumps to ingress AdminNetworkPolicy chains by priority
+	AZURE-NPM-INGRESS: jumps to ingress policy chains, drop on the ingress drop mark, -> AZURE-NPM-INGRESS-BASELINE
+	AZURE-NPM-INGRESS-ALLOW-MARK: set the ingress allow mark, -> AZURE-NPM-EGRESS-ADMIN, -> AZURE-NPM-EGRESS
+	AZURE-NPM-EGRESS-ADMIN: clear the pass mark, then jumps to egress AdminNetworkPolicy chains by priority
+	AZURE-NPM-EGRESS: jumps to egress policy chains, drop on the egress drop mark, -> AZURE-NPM-EGRESS-BASELINE,
+		then accept on the ingress allow mark
+	AZURE-NPM-ACCEPT: accept
+	AZURE-NPM-INGRESS-BASELINE and AZURE-NPM-EGRESS-BASELINE: jumps to BaselineAdminNetworkPolicy chains
+
+Unlike iptables, the jump rules aren't inserted or deleted one by one. AZURE-NPM and the ingress/egress chains of each tier
+are flushed and rendered again for all policies in the same transaction that adds or deletes policy chains. So each
+batch is applied atomically, and policy chains can be deleted right away instead of being cleaned up in the background.
+*/
+
+// bootupNFT cleans up the chains of the iptables dataplane and recreates the NPM table with no policies.
+func (pMgr *PolicyManager) bootupNFT() error {
+	klog.Infof("booting up nftables table %s", nftTable)
+
+	// cleanupOtherIptables cleans up the iptables version other than the one of the commands
+	if err := pMgr.cleanupOtherIptables(util.NewIptablesCommands(false, false)); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to cleanup iptables-nft chains", err)
+	}
+	util.SetIptablesToNft()
+	if err := pMgr.cleanupOtherIptables(util.NewIptablesCommands(true, false)); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to cleanup iptables-legacy chains", err)
+	}
+
+	creator := pMgr.nftCreatorForBootup()
+	if err := runNFT(creator); err != nil {
+		return npmerrors.SimpleErrorWrapper("failed to run nft for bootup", err)
+	}
+	return nil
+}
+
+func (pMgr *PolicyManager) nftCreatorForBootup() *ioutil.FileCreator {
+	creator := ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+
+	// adding the table first makes the delete succeed if the table doesn't exist
+	addNFTLine(creator, "add table", nftTable)
+	addNFTLine(creator, "delete table", nftTable)
+	addNFTLine(creator, "add table", nftTable)
+
+	priority := nftPriorityAfterIptables
+	if pMgr.PlaceAzureChainFirst == util.PlaceAzureChainFirst {
+		priority = nftPriorityBeforeIptables
+	}
+	addNFTLine(creator, "add chain", nftTable, util.NftForwardChain,
+		"{ type filter hook forward priority", priority, "; policy accept ; }")
+	for _, chain := range nftBaseChains {
+		addNFTLine(creator, "add chain", nftTable, chain)
+	}
+
+	addNFTLine(creator, "add rule", nftTable, util.NftForwardChain, "ct state new jump", util.IptablesAzureChain)
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureIngressAllowMarkChain,
+		nftSetMarkSpecs(nftIngressAllowMark), "jump", util.IptablesAzureEgressAdminChain,
+		nftCommentSpecs("SET-INGRESS-ALLOW-MARK-"+nftIngressAllowMark))
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureIngressAllowMarkChain, "jump", util.IptablesAzureEgressChain)
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureAcceptChain, "accept")
+
+	// leave NPM deactivated until the first policy is added
+	writeNFTBaseRules(creator, nil)
+	return creator
+}
+
+func (pMgr *PolicyManager) addNFTPolicies(networkPolicies []*NPMNetworkPolicy) error {
+	creator := ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+
+	// 1. create or replace the policy chains
+	for _, networkPolicy := range networkPolicies {
+		for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+			addNFTLine(creator, "add chain", nftTable, chain)
+			addNFTLine(creator, "flush chain", nftTable, chain)
+		}
+		pMgr.writeNFTPolicyRules(creator, networkPolicy)
+	}
+
+	// 2. jump to the policy chains of the cached and new policies
+	policies := make(map[string]*NPMNetworkPolicy, len(pMgr.policyMap.cache)+len(networkPolicies))
+	for key, policy := range pMgr.policyMap.cache {
+		policies[key] = policy
+	}
+	for _, policy := range networkPolicies {
+		policies[policy.PolicyKey] = policy
+	}
+	writeNFTBaseRules(creator, policies)
+
+	timer := metrics.StartNewTimer()
+	err := runNFT(creator)
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.CreateOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.CreateOp)
+		return fmt.Errorf("failed to run nft with updated policies. err: %w", err)
+	}
+	return nil
+}
+
+func (pMgr *PolicyManager) removeNFTPolicy(networkPolicy *NPMNetworkPolicy) error {
+	creator := ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+
+	// 1. stop jumping to the policy chains
+	policies := make(map[string]*NPMNetworkPolicy, len(pMgr.policyMap.cache))
+	for key, policy := range pMgr.policyMap.cache {
+		if key != networkPolicy.PolicyKey {
+			policies[key] = policy
+		}
+	}
+	writeNFTBaseRules(creator, policies)
+
+	// 2. delete the policy chains. Adding the chain first makes the delete succeed if the chain doesn't exist.
+	for _, chain := range chainNames([]*NPMNetworkPolicy{networkPolicy}) {
+		addNFTLine(creator, "add chain", nftTable, chain)
+		addNFTLine(creator, "flush chain", nftTable, chain)
+		addNFTLine(creator, "delete chain", nftTable, chain)
+	}
+
+	timer := metrics.StartNewTimer()
+	err := runNFT(creator)
+	metrics.RecordIPTablesRestoreLatency(timer, metrics.DeleteOp)
+	if err != nil {
+		metrics.IncIPTablesRestoreFailures(metrics.DeleteOp)
+		return fmt.Errorf("failed to run nft to remove policy. err: %w", err)
+	}
+	return nil
+}
+
+// addNFTLine adds the items to the file separated by spaces, skipping empty items.
+func addNFTLine(creator *ioutil.FileCreator, items ...string) {
+	nonEmptyItems := make([]string, 0, len(items))
+	for _, item := range items {
+		if item != "" {
+			nonEmptyItems = append(nonEmptyItems, item)
+		}
+	}
+	creator.AddLine("", nil, nonEmptyItems...)
+}
+
+func runNFT(creator *ioutil.FileCreator) error {
+	if err := creator.RunCommandWithFile(util.Nft, util.NftFileFlag, util.NftStdin); err != nil {
+		return fmt.Errorf("failed to run nft file. err: %w", err)
+	}
+	return nil
+}
+
+// writeNFTBaseRules renders the rules of AZURE-NPM and the ingress/egress chains of each tier for the policies.
+// NPM is activated if there are policies and deactivated otherwise.
+func writeNFTBaseRules(creator *ioutil.FileCreator, policies map[string]*NPMNetworkPolicy) {
+	addNFTLine(creator, "flush chain", nftTable, util.IptablesAzureChain)
+	addNFTLine(creator, "flush chain", nftTable, util.IptablesAzureIngressChain)
+	addNFTLine(creator, "flush chain", nftTable, util.IptablesAzureEgressChain)
+
+	if len(policies) > 0 {
+		addNFTLine(creator, "add rule", nftTable, util.IptablesAzureChain, "jump", util.IptablesAzureIngressAdminChain)
+		addNFTLine(creator, "add rule", nftTable, util.IptablesAzureChain, "jump", util.IptablesAzureIngressChain)
+		addNFTLine(creator, "add rule", nftTable, util.IptablesAzureChain, "jump", util.IptablesAzureEgressAdminChain)
+		addNFTLine(creator, "add rule", nftTable, util.IptablesAzureChain, "jump", util.IptablesAzureEgressChain)
+		addNFTLine(creator, "add rule", nftTable, util.IptablesAzureChain, "jump", util.IptablesAzureAcceptChain)
+	}
+
+	keys := make([]string, 0, len(policies))
+	tierPolicies := make(map[PolicyTier][]*NPMNetworkPolicy)
+	for key, policy := range policies {
+		if policy.isTiered() {
+			tierPolicies[policy.Tier] = append(tierPolicies[policy.Tier], policy)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		policy := policies[key]
+		hasIngress, hasEgress := policy.hasIngressAndEgress()
+		if hasIngress {
+			addNFTLine(creator, "add rule", nftTable, util.IptablesAzureIngressChain,
+				nftMatchSpecsForNetworkPolicy(policy, DstMatch), "jump", policy.ingressChainName(),
+				nftCommentSpecs(policy.commentForJumpToIngress()))
+		}
+		if hasEgress {
+			addNFTLine(creator, "add rule", nftTable, util.IptablesAzureEgressChain,
+				nftMatchSpecsForNetworkPolicy(policy, SrcMatch), "jump", policy.egressChainName(),
+				nftCommentSpecs(policy.commentForJumpToEgress()))
+		}
+	}
+
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureIngressChain,
+		nftOnMarkSpecs(nftIngressDropMark), "drop",
+		nftCommentSpecs("DROP-ON-INGRESS-DROP-MARK-"+nftIngressDropMark))
+	// BaselineAdminNetworkPolicies only apply if no NetworkPolicy decided on the flow
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureIngressChain, "jump", util.IptablesAzureIngressBaselineChain)
+	// the drop mark wins over the ingress allow mark
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureEgressChain,
+		nftOnMarkSpecs(nftEgressDropMark), "drop",
+		nftCommentSpecs("DROP-ON-EGRESS-DROP-MARK-"+nftEgressDropMark))
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureEgressChain, "jump", util.IptablesAzureEgressBaselineChain)
+	addNFTLine(creator, "add rule", nftTable, util.IptablesAzureEgressChain,
+		nftOnMarkSpecs(nftIngressAllowMark), "jump", util.IptablesAzureAcceptChain,
+		nftCommentSpecs("ACCEPT-ON-INGRESS-ALLOW-MARK-"+nftIngressAllowMark))
+
+	for _, tier := range []PolicyTier{AdminTier, BaselineTier} {
+		writeNFTTierRules(creator, tier, tierPolicies[tier])
+	}
+}
+
+// writeNFTTierRules renders the ingress/egress chains of the tier with jumps to the policy chains in order.
+// In the admin tier, a policy which passes a flow sets the pass mark, and the remaining policies are skipped.
+func writeNFTTierRules(creator *ioutil.FileCreator, tier PolicyTier, tierPolicies []*NPMNetworkPolicy) {
+	sort.Slice(tierPolicies, func(i, j int) bool {
+		if tierPolicies[i].Priority != tierPolicies[j].Priority {
+			return tierPolicies[i].Priority < tierPolicies[j].Priority
+		}
+		return tierPolicies[i].PolicyKey < tierPolicies[j].PolicyKey
+	})
+
+	ingressChain, egressChain := tierChainNames(tier)
+	addNFTLine(creator, "flush chain", nftTable, ingressChain)
+	addNFTLine(creator, "flush chain", nftTable, egressChain)
+
+	skipOnPassSpecs := ""
+	if tier == AdminTier {
+		for _, chain := range []string{ingressChain, egressChain} {
+			addNFTLine(creator, "add rule", nftTable, chain, "meta mark set meta mark &", nftClearPassMask,
+				nftCommentSpecs("CLEAR-PASS-MARK-"+nftPassMark))
+		}
+		skipOnPassSpecs = fmt.Sprintf("meta mark & %s == 0", nftPassMark)
+	}
+
+	for _, policy := range tierPolicies {
+		hasIngress, hasEgress := policy.hasIngressAndEgress()
+		if hasIngress {
+			addNFTLine(creator, "add rule", nftTable, ingressChain,
+				nftMatchSpecsForNetworkPolicy(policy, DstMatch), skipOnPassSpecs, "jump", policy.ingressChainName(),
+				nftCommentSpecs(policy.commentForJumpToIngress()))
+		}
+		if hasEgress {
+			addNFTLine(creator, "add rule", nftTable, egressChain,
+				nftMatchSpecsForNetworkPolicy(policy, SrcMatch), skipOnPassSpecs, "jump", policy.egressChainName(),
+				nftCommentSpecs(policy.commentForJumpToEgress()))
+		}
+	}
+}
+
+// writeNFTPolicyRules renders the ACLs of the policy into its policy chain(s).
+func (pMgr *PolicyManager) writeNFTPolicyRules(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy) {
+	for _, aclPolicy := range networkPolicy.ACLs {
+		if networkPolicy.isTiered() {
+			pMgr.writeNFTTieredACLRule(creator, networkPolicy, aclPolicy)
+			continue
+		}
+		if aclPolicy.hasIngress() {
+			verdict := pMgr.nftDeniedVerdict(networkPolicy, true, nftSetMarkSpecs(nftIngressDropMark))
+			if aclPolicy.Target == Allowed {
+				verdict = "jump " + util.IptablesAzureIngressAllowMarkChain
+			}
+			addNFTLine(creator, "add rule", nftTable, networkPolicy.ingressChainName(), nftRuleSpecs(aclPolicy), verdict,
+				nftCommentSpecs(aclPolicy.comment()))
+		}
+		if aclPolicy.hasEgress() {
+			verdict := pMgr.nftDeniedVerdict(networkPolicy, false, nftSetMarkSpecs(nftEgressDropMark))
+			if aclPolicy.Target == Allowed {
+				verdict = "jump " + util.IptablesAzureAcceptChain
+			}
+			addNFTLine(creator, "add rule", nftTable, networkPolicy.egressChainName(), nftRuleSpecs(aclPolicy), verdict,
+				nftCommentSpecs(aclPolicy.comment()))
+		}
+	}
+}
+
+// ACLs of tiered policies decide on a flow right away instead of marking it for the base chains.
+// A passed flow returns to the tier chain with the pass mark set.
+func (pMgr *PolicyManager) writeNFTTieredACLRule(creator *ioutil.FileCreator, networkPolicy *NPMNetworkPolicy, aclPolicy *ACLPolicy) {
+	chainName := networkPolicy.egressChainName()
+	allowVerdict := "jump " + util.IptablesAzureAcceptChain
+	if aclPolicy.hasIngress() {
+		chainName = networkPolicy.ingressChainName()
+		allowVerdict = "jump " + util.IptablesAzureIngressAllowMarkChain
+	}
+
+	var verdict string
+	switch aclPolicy.Target {
+	case Allowed:
+		verdict = allowVerdict
+	case Passed:
+		verdict = nftSetMarkSpecs(nftPassMark) + " return"
+	default:
+		verdict = pMgr.nftDeniedVerdict(networkPolicy, aclPolicy.hasIngress(), "drop")
+	}
+	addNFTLine(creator, "add rule", nftTable, chainName, nftRuleSpecs(aclPolicy), verdict, nftCommentSpecs(aclPolicy.comment()))
+}
+
+// nftDeniedVerdict logs a denied flow to the NFLOG group before the verdict if denied flows are logged.
+// Audited policies only log, so the flow continues through the chain.
+func (pMgr *PolicyManager) nftDeniedVerdict(networkPolicy *NPMNetworkPolicy, ingress bool, verdict string) string {
+	if !pMgr.LogDeniedFlows && !networkPolicy.Audit {
+		return verdict
+	}
+	logStatement := fmt.Sprintf("log group %d prefix \"%s\"", pMgr.NFLogGroup, networkPolicy.nflogPrefix(ingress))
+	if networkPolicy.Audit {
+		return logStatement
+	}
+	return logStatement + " " + verdict
+}
+
+func nftRuleSpecs(aclPolicy *ACLPolicy) string {
+	specs := make([]string, 0)
+	if aclPolicy.Protocol != UnspecifiedProtocol {
+		specs = append(specs, "meta l4proto", strings.ToLower(string(aclPolicy.Protocol)))
+	}
+	if !aclPolicy.DstPorts.isUnspecified() {
+		specs = append(specs, "th dport", aclPolicy.DstPorts.toNFTString())
+	}
+	for _, setInfo := range aclPolicy.SrcList {
+		specs = append(specs, setInfo.nftMatchSpecs(setInfo.MatchType))
+	}
+	for _, setInfo := range aclPolicy.DstList {
+		specs = append(specs, setInfo.nftMatchSpecs(setInfo.MatchType))
+	}
+	return strings.Join(specs, " ")
+}
+
+func nftMatchSpecsForNetworkPolicy(networkPolicy *NPMNetworkPolicy, matchType MatchType) string {
+	specs := make([]string, 0, len(networkPolicy.PodSelectorList))
+	for _, setInfo := range networkPolicy.PodSelectorList {
+		specs = append(specs, setInfo.nftMatchSpecs(matchType))
+	}
+	return strings.Join(specs, " ")
+}
+
+// nftMatchSpecs returns e.g. ip saddr != @azure-npm-123
+func (info SetInfo) nftMatchSpecs(matchType MatchType) string {
+	var selector string
+	switch matchType { //nolint:exhaustive // EitherMatch is resolved before rendering
+	case SrcMatch:
+		selector = "ip saddr"
+	case DstDstMatch:
+		selector = "ip daddr . meta l4proto . th dport"
+	default:
+		selector = "ip daddr"
+	}
+	operator := ""
+	if !info.Included {
+		operator = "!= "
+	}
+	return fmt.Sprintf("%s %s@%s", selector, operator, info.IPSet.GetHashedName())
+}
+
+func (portRange *Ports) toNFTString() string {
+	if portRange.Port == portRange.EndPort {
+		return fmt.Sprint(portRange.Port)
+	}
+	return fmt.Sprintf("%d-%d", portRange.Port, portRange.EndPort)
+}
+
+func nftSetMarkSpecs(mark string) string {
+	return "meta mark set meta mark | " + mark
+}
+
+func nftOnMarkSpecs(mark string) string {
+	return fmt.Sprintf("meta mark & %s == %s", mark, mark)
+}
+
+func nftCommentSpecs(comment string) string {
+	if len(comment) > util.NftMaxCommentLength {
+		comment = comment[:util.NftMaxCommentLength]
+	}
+	return fmt.Sprintf("comment %q", comment)
+}
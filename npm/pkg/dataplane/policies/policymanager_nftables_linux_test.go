@@ -0,0 +1,295 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
+	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Azure/azure-container-networking/npm/util"
+	"github.com/Azure/azure-container-networking/npm/util/ioutil"
+	testutils "github.com/Azure/azure-container-networking/test/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fakeNFTCommand = testutils.TestCmd{Cmd: []string{"nft", "-f", "-"}}
+
+	nftConfig = &PolicyManagerCfg{
+		NodeIP:               "6.7.8.9",
+		PolicyMode:           IPSetPolicyMode,
+		PlaceAzureChainFirst: util.PlaceAzureChainFirst,
+		UseNFTables:          true,
+	}
+
+	nftBaseFlushLines = []string{
+		"flush chain inet azure-npm AZURE-NPM",
+		"flush chain inet azure-npm AZURE-NPM-INGRESS",
+		"flush chain inet azure-npm AZURE-NPM-EGRESS",
+	}
+	nftActivationLines = []string{
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-INGRESS-ADMIN",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-INGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-EGRESS-ADMIN",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-EGRESS",
+		"add rule inet azure-npm AZURE-NPM jump AZURE-NPM-ACCEPT",
+	}
+	nftVerdictLines = []string{
+		`add rule inet azure-npm AZURE-NPM-INGRESS meta mark & 0x400 == 0x400 drop comment "DROP-ON-INGRESS-DROP-MARK-0x400"`,
+		"add rule inet azure-npm AZURE-NPM-INGRESS jump AZURE-NPM-INGRESS-BASELINE",
+		`add rule inet azure-npm AZURE-NPM-EGRESS meta mark & 0x800 == 0x800 drop comment "DROP-ON-EGRESS-DROP-MARK-0x800"`,
+		"add rule inet azure-npm AZURE-NPM-EGRESS jump AZURE-NPM-EGRESS-BASELINE",
+		`add rule inet azure-npm AZURE-NPM-EGRESS meta mark & 0x200 == 0x200 jump AZURE-NPM-ACCEPT comment "ACCEPT-ON-INGRESS-ALLOW-MARK-0x200"`,
+	}
+	nftAdminTierFlushLines = []string{
+		"flush chain inet azure-npm AZURE-NPM-INGRESS-ADMIN",
+		"flush chain inet azure-npm AZURE-NPM-EGRESS-ADMIN",
+		`add rule inet azure-npm AZURE-NPM-INGRESS-ADMIN meta mark set meta mark & 0xfffffeff comment "CLEAR-PASS-MARK-0x100"`,
+		`add rule inet azure-npm AZURE-NPM-EGRESS-ADMIN meta mark set meta mark & 0xfffffeff comment "CLEAR-PASS-MARK-0x100"`,
+	}
+	nftBaselineTierFlushLines = []string{
+		"flush chain inet azure-npm AZURE-NPM-INGRESS-BASELINE",
+		"flush chain inet azure-npm AZURE-NPM-EGRESS-BASELINE",
+	}
+
+	nftBothDirectionsNetPolLines = []string{
+		fmt.Sprintf(`add rule inet azure-npm %s meta l4proto tcp th dport 222-333 ip saddr @%s ip daddr != @%s meta mark set meta mark | 0x400 comment %q`,
+			bothDirectionsNetPolIngressChain, ipsets.TestCIDRSet.HashedName, ipsets.TestKeyPodSet.HashedName, ingressDropComment),
+		fmt.Sprintf(`add rule inet azure-npm %s ip saddr @%s jump AZURE-NPM-INGRESS-ALLOW-MARK comment %q`,
+			bothDirectionsNetPolIngressChain, ipsets.TestCIDRSet.HashedName, ingressAllowComment),
+		fmt.Sprintf(`add rule inet azure-npm %s meta l4proto udp th dport 144 ip daddr @%s meta mark set meta mark | 0x800 comment %q`,
+			bothDirectionsNetPolEgressChain, ipsets.TestCIDRSet.HashedName, egressDropComment),
+		fmt.Sprintf(`add rule inet azure-npm %s ip daddr @%s jump AZURE-NPM-ACCEPT comment %q`,
+			bothDirectionsNetPolEgressChain, ipsets.TestNamedportSet.HashedName, egressAllowComment),
+	}
+	nftBothDirectionsNetPolJumpLines = []string{
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-INGRESS ip daddr @%s jump %s comment %q`,
+			ipsets.TestKeyPodSet.HashedName, bothDirectionsNetPolIngressChain, bothDirectionsNetPolIngressJumpComment),
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-EGRESS ip saddr @%s jump %s comment %q`,
+			ipsets.TestKeyPodSet.HashedName, bothDirectionsNetPolEgressChain, bothDirectionsNetPolEgressJumpComment),
+	}
+)
+
+func TestNFTCreatorForBootup(t *testing.T) {
+	tests := []struct {
+		name                 string
+		placeAzureChainFirst bool
+		priority             string
+	}{
+		{
+			name:                 "place azure chain first",
+			placeAzureChainFirst: util.PlaceAzureChainFirst,
+			priority:             "-10",
+		},
+		{
+			name:                 "place azure chain after kube services",
+			placeAzureChainFirst: util.PlaceAzureChainAfterKubeServices,
+			priority:             "10",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ioshim := common.NewMockIOShim(nil)
+			defer ioshim.VerifyCalls(t, nil)
+			cfg := &PolicyManagerCfg{
+				PolicyMode:           IPSetPolicyMode,
+				PlaceAzureChainFirst: tt.placeAzureChainFirst,
+				UseNFTables:          true,
+			}
+			pMgr := NewPolicyManager(ioshim, cfg)
+
+			creator := pMgr.nftCreatorForBootup()
+			actualLines := strings.Split(creator.ToString(), "\n")
+			expectedLines := []string{
+				"add table inet azure-npm",
+				"delete table inet azure-npm",
+				"add table inet azure-npm",
+				fmt.Sprintf("add chain inet azure-npm AZURE-NPM-FORWARD { type filter hook forward priority %s ; policy accept ; }", tt.priority),
+				"add chain inet azure-npm AZURE-NPM",
+				"add chain inet azure-npm AZURE-NPM-INGRESS",
+				"add chain inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK",
+				"add chain inet azure-npm AZURE-NPM-EGRESS",
+				"add chain inet azure-npm AZURE-NPM-ACCEPT",
+				"add chain inet azure-npm AZURE-NPM-INGRESS-ADMIN",
+				"add chain inet azure-npm AZURE-NPM-EGRESS-ADMIN",
+				"add chain inet azure-npm AZURE-NPM-INGRESS-BASELINE",
+				"add chain inet azure-npm AZURE-NPM-EGRESS-BASELINE",
+				"add rule inet azure-npm AZURE-NPM-FORWARD ct state new jump AZURE-NPM",
+				`add rule inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK meta mark set meta mark | 0x200 jump AZURE-NPM-EGRESS-ADMIN comment "SET-INGRESS-ALLOW-MARK-0x200"`,
+				"add rule inet azure-npm AZURE-NPM-INGRESS-ALLOW-MARK jump AZURE-NPM-EGRESS",
+				"add rule inet azure-npm AZURE-NPM-ACCEPT accept",
+			}
+			expectedLines = append(expectedLines, nftBaseFlushLines...)
+			expectedLines = append(expectedLines, nftVerdictLines...)
+			expectedLines = append(expectedLines, nftAdminTierFlushLines...)
+			expectedLines = append(expectedLines, nftBaselineTierFlushLines...)
+			expectedLines = append(expectedLines, "")
+			dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+		})
+	}
+}
+
+func TestAddAndRemoveNFTPolicies(t *testing.T) {
+	calls := []testutils.TestCmd{fakeNFTCommand, fakeNFTCommand, fakeNFTCommand}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+	require.NoError(t, pMgr.AddPolicies([]*NPMNetworkPolicy{egressNetPol}, nil))
+	require.True(t, pMgr.PolicyExists(bothDirectionsNetPol.PolicyKey))
+	require.True(t, pMgr.PolicyExists(egressNetPol.PolicyKey))
+
+	require.NoError(t, pMgr.RemovePolicy(bothDirectionsNetPol.PolicyKey))
+	require.False(t, pMgr.PolicyExists(bothDirectionsNetPol.PolicyKey))
+	require.True(t, pMgr.PolicyExists(egressNetPol.PolicyKey))
+}
+
+func TestAddNFTPoliciesFailure(t *testing.T) {
+	calls := []testutils.TestCmd{
+		{Cmd: []string{"nft", "-f", "-"}, ExitCode: 1},
+		{Cmd: []string{"nft", "-f", "-"}, ExitCode: 1},
+	}
+	ioshim := common.NewMockIOShim(calls)
+	defer ioshim.VerifyCalls(t, calls)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	require.Error(t, pMgr.AddPolicies([]*NPMNetworkPolicy{bothDirectionsNetPol}, nil))
+	require.False(t, pMgr.PolicyExists(bothDirectionsNetPol.PolicyKey))
+}
+
+func TestNFTRulesForPolicies(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	// 1. policy chains and jumps for the policies
+	policies := map[string]*NPMNetworkPolicy{
+		bothDirectionsNetPol.PolicyKey: bothDirectionsNetPol,
+		egressNetPol.PolicyKey:         egressNetPol,
+	}
+	fileCreator := ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+	pMgr.writeNFTPolicyRules(fileCreator, bothDirectionsNetPol)
+	writeNFTBaseRules(fileCreator, policies)
+	actualLines := strings.Split(fileCreator.ToString(), "\n")
+	expectedLines := append([]string{}, nftBothDirectionsNetPolLines...)
+	expectedLines = append(expectedLines, nftBaseFlushLines...)
+	expectedLines = append(expectedLines, nftActivationLines...)
+	expectedLines = append(expectedLines, nftBothDirectionsNetPolJumpLines...)
+	expectedLines = append(expectedLines,
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-EGRESS jump %s comment %q`, egressNetPolChain, egressNetPolJumpComment),
+	)
+	expectedLines = append(expectedLines, nftVerdictLines...)
+	expectedLines = append(expectedLines, nftAdminTierFlushLines...)
+	expectedLines = append(expectedLines, nftBaselineTierFlushLines...)
+	expectedLines = append(expectedLines, "")
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 2. no jumps and no activation without policies
+	fileCreator = ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+	writeNFTBaseRules(fileCreator, nil)
+	actualLines = strings.Split(fileCreator.ToString(), "\n")
+	expectedLines = append([]string{}, nftBaseFlushLines...)
+	expectedLines = append(expectedLines, nftVerdictLines...)
+	expectedLines = append(expectedLines, nftAdminTierFlushLines...)
+	expectedLines = append(expectedLines, nftBaselineTierFlushLines...)
+	expectedLines = append(expectedLines, "")
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestNFTCommentSpecs(t *testing.T) {
+	require.Equal(t, `comment "ALLOW-ALL"`, nftCommentSpecs("ALLOW-ALL"))
+	longComment := strings.Repeat("a", util.NftMaxCommentLength+10)
+	require.Equal(t, fmt.Sprintf("comment %q", longComment[:util.NftMaxCommentLength]), nftCommentSpecs(longComment))
+}
+
+func TestNFTRulesForTieredPolicies(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	pMgr := NewPolicyManager(ioshim, nftConfig)
+
+	// 1. tiered ACLs decide right away, and a passed flow returns with the pass mark
+	fileCreator := ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+	pMgr.writeNFTPolicyRules(fileCreator, adminNetPol)
+	actualLines := strings.Split(fileCreator.ToString(), "\n")
+	expectedLines := []string{
+		fmt.Sprintf(`add rule inet azure-npm %s ip saddr @%s meta mark set meta mark | 0x100 return comment %q`,
+			adminNetPol.ingressChainName(), ipsets.TestCIDRSet.HashedName, ingressPassComment),
+		fmt.Sprintf(`add rule inet azure-npm %s meta l4proto udp th dport 144 ip daddr @%s drop comment %q`,
+			adminNetPol.egressChainName(), ipsets.TestCIDRSet.HashedName, egressDropComment),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 2. tier chains jump to the policies of the tier by priority
+	policies := map[string]*NPMNetworkPolicy{
+		adminNetPol.PolicyKey:               adminNetPol,
+		higherPriorityAdminNetPol.PolicyKey: higherPriorityAdminNetPol,
+		baselineNetPol.PolicyKey:            baselineNetPol,
+	}
+	fileCreator = ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+	writeNFTBaseRules(fileCreator, policies)
+	actualLines = strings.Split(fileCreator.ToString(), "\n")
+	expectedLines = append([]string{}, nftBaseFlushLines...)
+	expectedLines = append(expectedLines, nftActivationLines...)
+	expectedLines = append(expectedLines, nftVerdictLines...)
+	expectedLines = append(expectedLines, nftAdminTierFlushLines...)
+	expectedLines = append(expectedLines,
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-INGRESS-ADMIN meta mark & 0x100 == 0 jump %s comment %q`,
+			higherPriorityAdminNetPol.ingressChainName(), "INGRESS-POLICY-AdminNetworkPolicy/test-anp-2-TO-all"),
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-INGRESS-ADMIN ip daddr @%s meta mark & 0x100 == 0 jump %s comment %q`,
+			ipsets.TestNSSet.HashedName, adminNetPol.ingressChainName(), "INGRESS-POLICY-AdminNetworkPolicy/test-anp-TO-ns-test-ns-set"),
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-EGRESS-ADMIN ip saddr @%s meta mark & 0x100 == 0 jump %s comment %q`,
+			ipsets.TestNSSet.HashedName, adminNetPol.egressChainName(), "EGRESS-POLICY-AdminNetworkPolicy/test-anp-FROM-ns-test-ns-set"),
+	)
+	expectedLines = append(expectedLines, nftBaselineTierFlushLines...)
+	expectedLines = append(expectedLines,
+		fmt.Sprintf(`add rule inet azure-npm AZURE-NPM-EGRESS-BASELINE jump %s comment %q`,
+			baselineNetPol.egressChainName(), "EGRESS-POLICY-BaselineAdminNetworkPolicy/default-FROM-all"),
+		"",
+	)
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
+
+func TestNFTDeniedFlowLogRules(t *testing.T) {
+	ioshim := common.NewMockIOShim(nil)
+	defer ioshim.VerifyCalls(t, nil)
+	cfg := *nftConfig
+	cfg.LogDeniedFlows = true
+	cfg.NFLogGroup = 100
+	pMgr := NewPolicyManager(ioshim, &cfg)
+
+	// 1. denied flows are logged before they're marked or dropped
+	fileCreator := ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+	pMgr.writeNFTPolicyRules(fileCreator, egressNetPol)
+	pMgr.writeNFTPolicyRules(fileCreator, adminNetPol)
+	actualLines := strings.Split(fileCreator.ToString(), "\n")
+	expectedLines := []string{
+		fmt.Sprintf(`add rule inet azure-npm %s ip daddr @%s jump AZURE-NPM-ACCEPT comment %q`,
+			egressNetPolChain, ipsets.TestNamedportSet.HashedName, egressAllowComment),
+		fmt.Sprintf(`add rule inet azure-npm %s ip saddr @%s meta mark set meta mark | 0x100 return comment %q`,
+			adminNetPol.ingressChainName(), ipsets.TestCIDRSet.HashedName, ingressPassComment),
+		fmt.Sprintf(`add rule inet azure-npm %s meta l4proto udp th dport 144 ip daddr @%s log group 100 prefix "NPM-DROP-OUT:%s" drop comment %q`,
+			adminNetPol.egressChainName(), ipsets.TestCIDRSet.HashedName, util.Hash(adminNetPol.PolicyKey), egressDropComment),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+
+	// 2. audited policies only log
+	auditedNetPol := *adminNetPol
+	auditedNetPol.Audit = true
+	fileCreator = ioutil.NewFileCreator(pMgr.ioShim, nftMaxTryCount)
+	pMgr.writeNFTPolicyRules(fileCreator, &auditedNetPol)
+	actualLines = strings.Split(fileCreator.ToString(), "\n")
+	expectedLines = []string{
+		fmt.Sprintf(`add rule inet azure-npm %s ip saddr @%s meta mark set meta mark | 0x100 return comment %q`,
+			adminNetPol.ingressChainName(), ipsets.TestCIDRSet.HashedName, ingressPassComment),
+		fmt.Sprintf(`add rule inet azure-npm %s meta l4proto udp th dport 144 ip daddr @%s log group 100 prefix "NPM-AUDIT-OUT:%s" comment %q`,
+			adminNetPol.egressChainName(), ipsets.TestCIDRSet.HashedName, util.Hash(adminNetPol.PolicyKey), egressDropComment),
+		"",
+	}
+	dptestutils.AssertEqualLines(t, expectedLines, actualLines)
+}
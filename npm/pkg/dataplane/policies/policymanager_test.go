@@ -119,7 +119,7 @@ func TestBootup(t *testing.T) {
 		require.Equal(t, util.IptablesNft, util.Iptables)
 	}
 
-	expectedNumACLs := 11
+	expectedNumACLs := 16
 	if util.IsWindowsDP() {
 		expectedNumACLs = 0
 	}
@@ -488,19 +488,26 @@ func getEPPolicyReqFromACLSettings(settings []*NPMACLPolSettings) (hcn.PolicyEnd
 
 func (pMgr *PolicyManager) getSettingsFromACL(policy *NPMNetworkPolicy) ([]*NPMACLPolSettings, error) {
 	// +1 for readiness probe ACL
-	hnsRules := make([]*NPMACLPolSettings, len(policy.ACLs)+1)
-	for i, acl := range policy.ACLs {
+	hnsRules := make([]*NPMACLPolSettings, 0, len(policy.ACLs)+1)
+	for _, acl := range policy.ACLs {
+		if policy.Audit && acl.Target == Dropped {
+			// HNS can't log the flows of an ACL, so audited policies don't drop anything in Windows
+			continue
+		}
 		rule, err := acl.convertToAclSettings(policy.ACLPolicyID)
 		if err != nil {
 			// TODO need some retry mechanism to check why the translations failed
 			return hnsRules, err
 		}
-		hnsRules[i] = rule
+		if policy.isTiered() {
+			rule.Priority = tieredRulePriority(policy, acl)
+		}
+		hnsRules = append(hnsRules, rule)
 	}
 
 	// fixes #1881
 	// readiness probe ACL. allows ingress from host to pod
-	hnsRules[len(policy.ACLs)] = &NPMACLPolSettings{
+	hnsRules = append(hnsRules, &NPMACLPolSettings{
 		Id:              policy.ACLPolicyID,
 		Action:          hcn.ActionTypeAllow,
 		Direction:       hcn.DirectionTypeIn,
@@ -508,7 +515,7 @@ func (pMgr *PolicyManager) getSettingsFromACL(policy *NPMNetworkPolicy) ([]*NPMA
 		Protocols:       "", // any protocol
 		Priority:        priority201,
 		RuleType:        hcn.RuleTypeSwitch,
-	}
+	})
 	return hnsRules, nil
 }
 
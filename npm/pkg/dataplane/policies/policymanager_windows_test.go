@@ -10,6 +10,7 @@ import (
 	"github.com/Azure/azure-container-networking/npm/metrics"
 	"github.com/Azure/azure-container-networking/npm/pkg/dataplane/ipsets"
 	dptestutils "github.com/Azure/azure-container-networking/npm/pkg/dataplane/testutils"
+	"github.com/Microsoft/hcsshim/hcn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -270,6 +271,57 @@ func TestRemovePoliciesEndpointNotFound(t *testing.T) {
 	}.test(t)
 }
 
+func TestGetSettingsFromTieredACLs(t *testing.T) {
+	pMgr, _ := getPMgr(t)
+	newACL := func(target Verdict, ruleIndex int) *ACLPolicy {
+		return &ACLPolicy{
+			Target:    target,
+			Direction: Ingress,
+			Protocol:  TCP,
+			RuleIndex: ruleIndex,
+		}
+	}
+
+	anp := NewAdminNPMNetworkPolicy("test-anp", 3)
+	anp.ACLs = []*ACLPolicy{newACL(Dropped, 0), newACL(Allowed, 0), newACL(Allowed, 1)}
+	rules, err := pMgr.getSettingsFromACL(anp)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1300), rules[0].Priority)
+	require.Equal(t, uint16(1300), rules[1].Priority)
+	require.Equal(t, uint16(1301), rules[2].Priority)
+	require.Less(t, adminRulePriorityBase+MaxWindowsAdminPriority*adminRulesPerPriority+adminRulesPerPriority-1, allowRulePriotity)
+
+	banp := NewBaselineAdminNPMNetworkPolicy("default")
+	banp.ACLs = []*ACLPolicy{newACL(Dropped, 2)}
+	rules, err = pMgr.getSettingsFromACL(banp)
+	require.NoError(t, err)
+	require.Equal(t, uint16(40002), rules[0].Priority)
+
+	anp.ACLs = []*ACLPolicy{newACL(Passed, 0)}
+	_, err = pMgr.getSettingsFromACL(anp)
+	require.ErrorIs(t, err, ErrPassActionNotSupported)
+}
+
+func TestGetSettingsFromAuditedACLs(t *testing.T) {
+	pMgr, _ := getPMgr(t)
+	netPol := NewNPMNetworkPolicy("test", "x")
+	netPol.ACLs = []*ACLPolicy{
+		{Target: Dropped, Direction: Ingress, Protocol: TCP},
+		{Target: Allowed, Direction: Ingress, Protocol: TCP},
+	}
+	rules, err := pMgr.getSettingsFromACL(netPol)
+	require.NoError(t, err)
+	// +1 for the readiness probe ACL
+	require.Len(t, rules, 3)
+
+	// audited policies don't drop anything
+	netPol.Audit = true
+	rules, err = pMgr.getSettingsFromACL(netPol)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, hcn.ActionTypeAllow, rules[0].Action)
+}
+
 // Helper functions for UTS
 
 func getPMgr(t *testing.T) (*PolicyManager, *hnswrapper.Hnsv2wrapperFake) {
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.27.1
+// 	protoc-gen-go v1.36.10
 // 	protoc        v3.19.1
 // source: transport.proto
 
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -24,15 +25,20 @@ type DatapathPodMetadata_APIVersion int32
 
 const (
 	DatapathPodMetadata_V1 DatapathPodMetadata_APIVersion = 0
+	// V2 supports goal state generations, acknowledgements, resuming from the
+	// last applied generation, and IPSets filtered for the node.
+	DatapathPodMetadata_V2 DatapathPodMetadata_APIVersion = 1
 )
 
 // Enum value maps for DatapathPodMetadata_APIVersion.
 var (
 	DatapathPodMetadata_APIVersion_name = map[int32]string{
 		0: "V1",
+		1: "V2",
 	}
 	DatapathPodMetadata_APIVersion_value = map[string]int32{
 		"V1": 0,
+		"V2": 1,
 	}
 )
 
@@ -111,22 +117,22 @@ func (Events_EventType) EnumDescriptor() ([]byte, []int) {
 
 // DatapathPodMetadata is the metadata for a datapath pod
 type DatapathPodMetadata struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
+	state      protoimpl.MessageState         `protogen:"open.v1"`
 	PodName    string                         `protobuf:"bytes,1,opt,name=pod_name,json=podName,proto3" json:"pod_name,omitempty"`                                    // Daemonset Pod ID
 	NodeName   string                         `protobuf:"bytes,2,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`                                 // Node name
 	ApiVersion DatapathPodMetadata_APIVersion `protobuf:"varint,3,opt,name=apiVersion,proto3,enum=protos.DatapathPodMetadata_APIVersion" json:"apiVersion,omitempty"` // Controlplane API version to support backwards compatibility
+	// last_applied_generation is the generation the datapath pod applied before reconnecting.
+	// The controlplane replays the generations after it instead of hydrating when it still has them.
+	LastAppliedGeneration uint64 `protobuf:"varint,4,opt,name=last_applied_generation,json=lastAppliedGeneration,proto3" json:"last_applied_generation,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
 }
 
 func (x *DatapathPodMetadata) Reset() {
 	*x = DatapathPodMetadata{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_transport_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_transport_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *DatapathPodMetadata) String() string {
@@ -137,7 +143,7 @@ func (*DatapathPodMetadata) ProtoMessage() {}
 
 func (x *DatapathPodMetadata) ProtoReflect() protoreflect.Message {
 	mi := &file_transport_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -173,26 +179,33 @@ func (x *DatapathPodMetadata) GetApiVersion() DatapathPodMetadata_APIVersion {
 	return DatapathPodMetadata_V1
 }
 
+func (x *DatapathPodMetadata) GetLastAppliedGeneration() uint64 {
+	if x != nil {
+		return x.LastAppliedGeneration
+	}
+	return 0
+}
+
 // Events defines the operation (event type) and object type being
 // streamed to the datapath client. A events message may carry one or
 // more Event objects.
 type Events struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	EventType Events_EventType `protobuf:"varint,1,opt,name=eventType,proto3,enum=protos.Events_EventType" json:"eventType,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	EventType Events_EventType       `protobuf:"varint,1,opt,name=eventType,proto3,enum=protos.Events_EventType" json:"eventType,omitempty"`
 	// Payload can contain one or more Event objects.
-	Payload map[string]*GoalState `protobuf:"bytes,2,rep,name=payload,proto3" json:"payload,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Payload map[string]*GoalState `protobuf:"bytes,2,rep,name=payload,proto3" json:"payload,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// generation increases with each GoalState event. A Hydration event has the generation
+	// of the last GoalState event included in it.
+	Generation    uint64 `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Events) Reset() {
 	*x = Events{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_transport_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_transport_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Events) String() string {
@@ -203,7 +216,7 @@ func (*Events) ProtoMessage() {}
 
 func (x *Events) ProtoReflect() protoreflect.Message {
 	mi := &file_transport_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -232,25 +245,29 @@ func (x *Events) GetPayload() map[string]*GoalState {
 	return nil
 }
 
+func (x *Events) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
 // Event is a generic object that can be Created,
 // Updated, Deleted by the controlplane.
 type GoalState struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
+	state protoimpl.MessageState `protogen:"open.v1"`
 	// Data can contain one or more instances of IPSet or NetworkPolicy
 	// objects.
-	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Data          []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GoalState) Reset() {
 	*x = GoalState{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_transport_proto_msgTypes[2]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_transport_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *GoalState) String() string {
@@ -261,7 +278,7 @@ func (*GoalState) ProtoMessage() {}
 
 func (x *GoalState) ProtoReflect() protoreflect.Message {
 	mi := &file_transport_proto_msgTypes[2]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -283,82 +300,191 @@ func (x *GoalState) GetData() []byte {
 	return nil
 }
 
-var File_transport_proto protoreflect.FileDescriptor
+// GoalStateAck acknowledges a goal state generation.
+type GoalStateAck struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	PodName    string                 `protobuf:"bytes,1,opt,name=pod_name,json=podName,proto3" json:"pod_name,omitempty"`    // Daemonset Pod ID
+	NodeName   string                 `protobuf:"bytes,2,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"` // Node name
+	Generation uint64                 `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	// error is set when the datapath pod failed to apply the generation,
+	// in which case the controlplane hydrates the pod again.
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GoalStateAck) Reset() {
+	*x = GoalStateAck{}
+	mi := &file_transport_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-var file_transport_proto_rawDesc = []byte{
-	0x0a, 0x0f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x12, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x22, 0xab, 0x01, 0x0a, 0x13, 0x44, 0x61,
-	0x74, 0x61, 0x70, 0x61, 0x74, 0x68, 0x50, 0x6f, 0x64, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
-	0x61, 0x12, 0x19, 0x0a, 0x08, 0x70, 0x6f, 0x64, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x6f, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09,
-	0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x08, 0x6e, 0x6f, 0x64, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x46, 0x0a, 0x0a, 0x61, 0x70, 0x69,
-	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x26, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x70, 0x61, 0x74, 0x68, 0x50,
-	0x6f, 0x64, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x2e, 0x41, 0x50, 0x49, 0x56, 0x65,
-	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x61, 0x70, 0x69, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
-	0x6e, 0x22, 0x14, 0x0a, 0x0a, 0x41, 0x50, 0x49, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
-	0x06, 0x0a, 0x02, 0x56, 0x31, 0x10, 0x00, 0x22, 0xf1, 0x01, 0x0a, 0x06, 0x45, 0x76, 0x65, 0x6e,
-	0x74, 0x73, 0x12, 0x36, 0x0a, 0x09, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2e, 0x45,
-	0x76, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52,
-	0x09, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x35, 0x0a, 0x07, 0x70, 0x61,
-	0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x73, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x50, 0x61, 0x79, 0x6c,
-	0x6f, 0x61, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61,
-	0x64, 0x1a, 0x4d, 0x0a, 0x0c, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x45, 0x6e, 0x74, 0x72,
-	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
-	0x6b, 0x65, 0x79, 0x12, 0x27, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2e, 0x47, 0x6f, 0x61, 0x6c,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
-	0x22, 0x29, 0x0a, 0x09, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0d, 0x0a,
-	0x09, 0x47, 0x6f, 0x61, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x65, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09,
-	0x48, 0x79, 0x64, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x10, 0x01, 0x22, 0x1f, 0x0a, 0x09, 0x47,
-	0x6f, 0x61, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x32, 0x4b, 0x0a, 0x0f,
-	0x44, 0x61, 0x74, 0x61, 0x70, 0x6c, 0x61, 0x6e, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12,
-	0x38, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x1b, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x73, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x70, 0x61, 0x74, 0x68, 0x50, 0x6f, 0x64, 0x4d,
-	0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x1a, 0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73,
-	0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x30, 0x01, 0x42, 0x43, 0x5a, 0x41, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x7a, 0x75, 0x72, 0x65, 0x2f, 0x61, 0x7a,
-	0x75, 0x72, 0x65, 0x2d, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x2d, 0x6e, 0x65,
-	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2f, 0x6e, 0x70, 0x6d, 0x2f, 0x70, 0x6b, 0x67,
-	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x3b, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *GoalStateAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
+func (*GoalStateAck) ProtoMessage() {}
+
+func (x *GoalStateAck) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GoalStateAck.ProtoReflect.Descriptor instead.
+func (*GoalStateAck) Descriptor() ([]byte, []int) {
+	return file_transport_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GoalStateAck) GetPodName() string {
+	if x != nil {
+		return x.PodName
+	}
+	return ""
+}
+
+func (x *GoalStateAck) GetNodeName() string {
+	if x != nil {
+		return x.NodeName
+	}
+	return ""
+}
+
+func (x *GoalStateAck) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *GoalStateAck) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// GoalStateAckResponse is the response to a GoalStateAck.
+type GoalStateAckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GoalStateAckResponse) Reset() {
+	*x = GoalStateAckResponse{}
+	mi := &file_transport_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GoalStateAckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GoalStateAckResponse) ProtoMessage() {}
+
+func (x *GoalStateAckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GoalStateAckResponse.ProtoReflect.Descriptor instead.
+func (*GoalStateAckResponse) Descriptor() ([]byte, []int) {
+	return file_transport_proto_rawDescGZIP(), []int{4}
+}
+
+var File_transport_proto protoreflect.FileDescriptor
+
+const file_transport_proto_rawDesc = "" +
+	"\n" +
+	"\x0ftransport.proto\x12\x06protos\"\xeb\x01\n" +
+	"\x13DatapathPodMetadata\x12\x19\n" +
+	"\bpod_name\x18\x01 \x01(\tR\apodName\x12\x1b\n" +
+	"\tnode_name\x18\x02 \x01(\tR\bnodeName\x12F\n" +
+	"\n" +
+	"apiVersion\x18\x03 \x01(\x0e2&.protos.DatapathPodMetadata.APIVersionR\n" +
+	"apiVersion\x126\n" +
+	"\x17last_applied_generation\x18\x04 \x01(\x04R\x15lastAppliedGeneration\"\x1c\n" +
+	"\n" +
+	"APIVersion\x12\x06\n" +
+	"\x02V1\x10\x00\x12\x06\n" +
+	"\x02V2\x10\x01\"\x91\x02\n" +
+	"\x06Events\x126\n" +
+	"\teventType\x18\x01 \x01(\x0e2\x18.protos.Events.EventTypeR\teventType\x125\n" +
+	"\apayload\x18\x02 \x03(\v2\x1b.protos.Events.PayloadEntryR\apayload\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x04R\n" +
+	"generation\x1aM\n" +
+	"\fPayloadEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12'\n" +
+	"\x05value\x18\x02 \x01(\v2\x11.protos.GoalStateR\x05value:\x028\x01\")\n" +
+	"\tEventType\x12\r\n" +
+	"\tGoalState\x10\x00\x12\r\n" +
+	"\tHydration\x10\x01\"\x1f\n" +
+	"\tGoalState\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"|\n" +
+	"\fGoalStateAck\x12\x19\n" +
+	"\bpod_name\x18\x01 \x01(\tR\apodName\x12\x1b\n" +
+	"\tnode_name\x18\x02 \x01(\tR\bnodeName\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x04R\n" +
+	"generation\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x16\n" +
+	"\x14GoalStateAckResponse2\x8e\x01\n" +
+	"\x0fDataplaneEvents\x128\n" +
+	"\aConnect\x12\x1b.protos.DatapathPodMetadata\x1a\x0e.protos.Events0\x01\x12A\n" +
+	"\vAcknowledge\x12\x14.protos.GoalStateAck\x1a\x1c.protos.GoalStateAckResponseBCZAgithub.com/Azure/azure-container-networking/npm/pkg/protos;protosb\x06proto3"
+
 var (
 	file_transport_proto_rawDescOnce sync.Once
-	file_transport_proto_rawDescData = file_transport_proto_rawDesc
+	file_transport_proto_rawDescData []byte
 )
 
 func file_transport_proto_rawDescGZIP() []byte {
 	file_transport_proto_rawDescOnce.Do(func() {
-		file_transport_proto_rawDescData = protoimpl.X.CompressGZIP(file_transport_proto_rawDescData)
+		file_transport_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_transport_proto_rawDesc), len(file_transport_proto_rawDesc)))
 	})
 	return file_transport_proto_rawDescData
 }
 
 var file_transport_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_transport_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
-var file_transport_proto_goTypes = []interface{}{
+var file_transport_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_transport_proto_goTypes = []any{
 	(DatapathPodMetadata_APIVersion)(0), // 0: protos.DatapathPodMetadata.APIVersion
 	(Events_EventType)(0),               // 1: protos.Events.EventType
 	(*DatapathPodMetadata)(nil),         // 2: protos.DatapathPodMetadata
 	(*Events)(nil),                      // 3: protos.Events
 	(*GoalState)(nil),                   // 4: protos.GoalState
-	nil,                                 // 5: protos.Events.PayloadEntry
+	(*GoalStateAck)(nil),                // 5: protos.GoalStateAck
+	(*GoalStateAckResponse)(nil),        // 6: protos.GoalStateAckResponse
+	nil,                                 // 7: protos.Events.PayloadEntry
 }
 var file_transport_proto_depIdxs = []int32{
 	0, // 0: protos.DatapathPodMetadata.apiVersion:type_name -> protos.DatapathPodMetadata.APIVersion
 	1, // 1: protos.Events.eventType:type_name -> protos.Events.EventType
-	5, // 2: protos.Events.payload:type_name -> protos.Events.PayloadEntry
+	7, // 2: protos.Events.payload:type_name -> protos.Events.PayloadEntry
 	4, // 3: protos.Events.PayloadEntry.value:type_name -> protos.GoalState
 	2, // 4: protos.DataplaneEvents.Connect:input_type -> protos.DatapathPodMetadata
-	3, // 5: protos.DataplaneEvents.Connect:output_type -> protos.Events
-	5, // [5:6] is the sub-list for method output_type
-	4, // [4:5] is the sub-list for method input_type
+	5, // 5: protos.DataplaneEvents.Acknowledge:input_type -> protos.GoalStateAck
+	3, // 6: protos.DataplaneEvents.Connect:output_type -> protos.Events
+	6, // 7: protos.DataplaneEvents.Acknowledge:output_type -> protos.GoalStateAckResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
 	4, // [4:4] is the sub-list for extension type_name
 	4, // [4:4] is the sub-list for extension extendee
 	0, // [0:4] is the sub-list for field type_name
@@ -369,51 +495,13 @@ func file_transport_proto_init() {
 	if File_transport_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_transport_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DatapathPodMetadata); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_transport_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Events); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_transport_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GoalState); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_transport_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_transport_proto_rawDesc), len(file_transport_proto_rawDesc)),
 			NumEnums:      2,
-			NumMessages:   4,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -423,7 +511,6 @@ func file_transport_proto_init() {
 		MessageInfos:      file_transport_proto_msgTypes,
 	}.Build()
 	File_transport_proto = out.File
-	file_transport_proto_rawDesc = nil
 	file_transport_proto_goTypes = nil
 	file_transport_proto_depIdxs = nil
 }
@@ -1,4 +1,8 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.19.1
+// source: transport.proto
 
 package protos
 
@@ -19,6 +23,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DataplaneEventsClient interface {
 	Connect(ctx context.Context, in *DatapathPodMetadata, opts ...grpc.CallOption) (DataplaneEvents_ConnectClient, error)
+	// Acknowledge reports the last goal state generation a datapath pod applied.
+	Acknowledge(ctx context.Context, in *GoalStateAck, opts ...grpc.CallOption) (*GoalStateAckResponse, error)
 }
 
 type dataplaneEventsClient struct {
@@ -61,11 +67,22 @@ func (x *dataplaneEventsConnectClient) Recv() (*Events, error) {
 	return m, nil
 }
 
+func (c *dataplaneEventsClient) Acknowledge(ctx context.Context, in *GoalStateAck, opts ...grpc.CallOption) (*GoalStateAckResponse, error) {
+	out := new(GoalStateAckResponse)
+	err := c.cc.Invoke(ctx, "/protos.DataplaneEvents/Acknowledge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DataplaneEventsServer is the server API for DataplaneEvents service.
 // All implementations must embed UnimplementedDataplaneEventsServer
 // for forward compatibility
 type DataplaneEventsServer interface {
 	Connect(*DatapathPodMetadata, DataplaneEvents_ConnectServer) error
+	// Acknowledge reports the last goal state generation a datapath pod applied.
+	Acknowledge(context.Context, *GoalStateAck) (*GoalStateAckResponse, error)
 	mustEmbedUnimplementedDataplaneEventsServer()
 }
 
@@ -76,6 +93,9 @@ type UnimplementedDataplaneEventsServer struct {
 func (UnimplementedDataplaneEventsServer) Connect(*DatapathPodMetadata, DataplaneEvents_ConnectServer) error {
 	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
 }
+func (UnimplementedDataplaneEventsServer) Acknowledge(context.Context, *GoalStateAck) (*GoalStateAckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acknowledge not implemented")
+}
 func (UnimplementedDataplaneEventsServer) mustEmbedUnimplementedDataplaneEventsServer() {}
 
 // UnsafeDataplaneEventsServer may be embedded to opt out of forward compatibility for this service.
@@ -110,13 +130,36 @@ func (x *dataplaneEventsConnectServer) Send(m *Events) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _DataplaneEvents_Acknowledge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GoalStateAck)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataplaneEventsServer).Acknowledge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protos.DataplaneEvents/Acknowledge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataplaneEventsServer).Acknowledge(ctx, req.(*GoalStateAck))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DataplaneEvents_ServiceDesc is the grpc.ServiceDesc for DataplaneEvents service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var DataplaneEvents_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.DataplaneEvents",
 	HandlerType: (*DataplaneEventsServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acknowledge",
+			Handler:    _DataplaneEvents_Acknowledge_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Connect",
@@ -1,6 +1,11 @@
 package transport
 
+import "time"
+
 const (
 	// concurrentInputRegistrations = 10
 	grpcMaxConcurrentStreams = 100
+
+	// acknowledgeTimeout is how long a daemon waits for the controller to receive an acknowledgement
+	acknowledgeTimeout = 10 * time.Second
 )
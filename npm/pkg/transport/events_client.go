@@ -3,6 +3,7 @@ package transport
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/Azure/azure-container-networking/npm/pkg/protos"
 	"google.golang.org/grpc"
@@ -20,6 +21,9 @@ type EventsClient struct {
 	serverAddr string
 
 	outCh chan *protos.Events
+
+	// appliedGeneration is the last generation the daemon applied. The client resumes from it when it reconnects.
+	appliedGeneration atomic.Uint64
 }
 
 var (
@@ -75,10 +79,6 @@ func (c *EventsClient) Start(stopCh <-chan struct{}) error {
 func (c *EventsClient) run(ctx context.Context, stopCh <-chan struct{}) error {
 	var connectClient protos.DataplaneEvents_ConnectClient
 	var err error
-	clientMetadata := &protos.DatapathPodMetadata{
-		PodName:  c.pod,
-		NodeName: c.node,
-	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -89,7 +89,13 @@ func (c *EventsClient) run(ctx context.Context, stopCh <-chan struct{}) error {
 			return nil
 		default:
 			if connectClient == nil {
-				klog.Info("Reconnecting to gRPC server controller")
+				clientMetadata := &protos.DatapathPodMetadata{
+					PodName:               c.pod,
+					NodeName:              c.node,
+					ApiVersion:            protos.DatapathPodMetadata_V2,
+					LastAppliedGeneration: c.appliedGeneration.Load(),
+				}
+				klog.Infof("Reconnecting to gRPC server controller from generation %d", clientMetadata.LastAppliedGeneration)
 				opts := []grpc.CallOption{grpc.WaitForReady(false)}
 				connectClient, err = c.Connect(ctx, clientMetadata, opts...)
 				if err != nil {
@@ -108,3 +114,24 @@ func (c *EventsClient) run(ctx context.Context, stopCh <-chan struct{}) error {
 		}
 	}
 }
+
+// Acknowledge reports the generation which the daemon applied to the controller.
+// A failed generation makes the controller hydrate the daemon again.
+func (c *EventsClient) Acknowledge(generation uint64, err error) {
+	ack := &protos.GoalStateAck{
+		PodName:    c.pod,
+		NodeName:   c.node,
+		Generation: generation,
+	}
+	if err != nil {
+		ack.Error = err.Error()
+	} else {
+		c.appliedGeneration.Store(generation)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, acknowledgeTimeout)
+	defer cancel()
+	if _, ackErr := c.DataplaneEventsClient.Acknowledge(ctx, ack); ackErr != nil {
+		klog.Errorf("failed to acknowledge generation %d: %v", generation, ackErr)
+	}
+}
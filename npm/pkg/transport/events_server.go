@@ -27,6 +27,15 @@ type EventsServer struct {
 	// Registrations is a map of dataplane pod address to their associate connection stream
 	Registrations map[string]clientStreamConnection
 
+	// AppliedGenerations is a map of dataplane pod name to the last goal state generation it acknowledged
+	AppliedGenerations map[string]uint64
+
+	// generation is the generation of the last GoalState event broadcasted
+	generation uint64
+
+	// hydratedGenerations is a map of dataplane pod name to the generation of its last hydration
+	hydratedGenerations map[string]uint64
+
 	// port is the port the manager is listening on
 	port int
 
@@ -39,6 +48,9 @@ type EventsServer struct {
 	// deregCh is the deregistration channel
 	deregCh chan deregistrationEvent
 
+	// ackCh is the acknowledgement channel
+	ackCh chan *protos.GoalStateAck
+
 	// errCh is the error channel
 	errCh chan error
 
@@ -54,17 +66,24 @@ func NewEventsServer(ctx context.Context, port int, dp *dpshim.DPShim) *EventsSe
 	// Create a deregistration channel
 	deregCh := make(chan deregistrationEvent, grpcMaxConcurrentStreams)
 
+	// Create an acknowledgement channel
+	ackCh := make(chan *protos.GoalStateAck, grpcMaxConcurrentStreams)
+
 	return &EventsServer{
-		ctx:           ctx,
-		Server:        NewServer(ctx, regCh),
-		Watchdog:      NewWatchdog(deregCh),
-		Registrations: make(map[string]clientStreamConnection),
-		port:          port,
-		inCh:          dp.OutChannel,
-		errCh:         make(chan error),
-		deregCh:       deregCh,
-		regCh:         regCh,
-		dp:            dp,
+		ctx:                 ctx,
+		Server:              NewServer(ctx, regCh, ackCh),
+		Watchdog:            NewWatchdog(deregCh),
+		Registrations:       make(map[string]clientStreamConnection),
+		AppliedGenerations:  make(map[string]uint64),
+		hydratedGenerations: make(map[string]uint64),
+		generation:          dp.Generation(),
+		port:                port,
+		inCh:                dp.OutChannel,
+		errCh:               make(chan error),
+		deregCh:             deregCh,
+		ackCh:               ackCh,
+		regCh:               regCh,
+		dp:                  dp,
 	}
 }
 
@@ -100,20 +119,11 @@ func (m *EventsServer) start(stopCh <-chan struct{}) error {
 			// within the same castegory we will have to paginate.
 			klog.Infof("Registering remote client %s", client)
 			m.Registrations[client.String()] = client
-			event, err := m.dp.HydrateClients()
-			if err != nil {
-				klog.Errorf("Failed to hydrate client %s: %v", client, err)
-			}
 			// (TODO) Hydration event takes a lock of whole DPShim instance, essentially blocking the
 			// controllers from receiving any more new events or servicing existing daemons.
 			// So we will need to add a buffering mechanism to wait until either we have a N number of daemons
 			// or hit S milliseconds of wait time and send huydration event to all the buffered daemons.
-			go func() {
-				klog.Infof("Hydrating remote client %s", client)
-				if err := client.stream.SendMsg(event); err != nil {
-					klog.Errorf("Failed to hydrate client %s: %v", client, err)
-				}
-			}()
+			m.resumeOrHydrate(client)
 		case ev := <-m.deregCh:
 			// (TODO) A heart beat for each daemon should also be added alongside watchdog to monitor
 			// daemon restarts and then if that fails, we will need to delete the client.
@@ -126,17 +136,30 @@ func (m *EventsServer) start(stopCh <-chan struct{}) error {
 					klog.Info("Ignoring stale deregistration event")
 				}
 			}
-		case msg := <-m.inCh:
+		case <-m.inCh:
 			klog.Infof("######## Received event to broadcast ######")
-			for clientName, client := range m.Registrations {
-				// (TODO) Should we call this SendMsg per client in a separate go routine?
-				klog.Infof("######## Servicing the event to %s ######", clientName)
-				if err := client.stream.SendMsg(msg); err != nil {
+			// the events on the channel can be out of order, so broadcast all events after the last broadcasted generation
+			events, ok := m.dp.GoalStatesSince(m.generation)
+			if !ok {
+				klog.Infof("Missed goal state events after generation %d. Hydrating all remote clients", m.generation)
+				m.generation = m.dp.Generation()
+				for _, client := range m.Registrations {
+					m.hydrate(client)
+				}
+				continue
+			}
+			for _, event := range events {
+				for clientName, client := range m.Registrations {
+					// (TODO) Should we call this SendMsg per client in a separate go routine?
+					klog.Infof("######## Servicing generation %d to %s ######", event.GetGeneration(), clientName)
 					// (TODO) What happens if a portion of the clients fails?
 					// there should be a mechanism to retry the failed clients.
-					klog.Errorf("Failed to send message to client %s: %v", client, err)
+					m.send(client, event)
 				}
+				m.generation = event.GetGeneration()
 			}
+		case ack := <-m.ackCh:
+			m.acknowledge(ack)
 		case <-m.ctx.Done():
 			klog.Info("Context Done. Stopping transport manager")
 			return nil
@@ -150,6 +173,71 @@ func (m *EventsServer) start(stopCh <-chan struct{}) error {
 	}
 }
 
+// resumeOrHydrate sends the events after the last generation the client applied,
+// or hydrates the client if it didn't apply any generation or the events are no longer available.
+func (m *EventsServer) resumeOrHydrate(client clientStreamConnection) {
+	if client.GetApiVersion() >= protos.DatapathPodMetadata_V2 && client.GetLastAppliedGeneration() != 0 {
+		if events, ok := m.dp.GoalStatesSince(client.GetLastAppliedGeneration()); ok {
+			klog.Infof("Resuming remote client %s from generation %d with %d events", client, client.GetLastAppliedGeneration(), len(events))
+			for _, event := range events {
+				m.send(client, event)
+			}
+			return
+		}
+		klog.Infof("Unable to resume remote client %s from generation %d", client, client.GetLastAppliedGeneration())
+	}
+	m.hydrate(client)
+}
+
+func (m *EventsServer) hydrate(client clientStreamConnection) {
+	event, err := m.dp.HydrateClients()
+	if err != nil {
+		klog.Errorf("Failed to hydrate client %s: %v", client, err)
+		return
+	}
+	if event == nil {
+		return
+	}
+	klog.Infof("Hydrating remote client %s with generation %d", client, event.GetGeneration())
+	m.hydratedGenerations[client.GetPodName()] = event.GetGeneration()
+	m.send(client, event)
+}
+
+// send filters the event for the node of the client if the client supports it
+func (m *EventsServer) send(client clientStreamConnection, event *protos.Events) {
+	if client.GetApiVersion() >= protos.DatapathPodMetadata_V2 {
+		filteredEvent, err := dpshim.FilterGoalStateForNode(event, client.GetNodeName())
+		if err != nil {
+			klog.Errorf("Failed to filter generation %d for client %s: %v", event.GetGeneration(), client, err)
+			return
+		}
+		event = filteredEvent
+	}
+	if err := client.stream.SendMsg(event); err != nil {
+		klog.Errorf("Failed to send message to client %s: %v", client, err)
+	}
+}
+
+// acknowledge records the generation the client applied, and hydrates the client if it failed to apply the generation
+func (m *EventsServer) acknowledge(ack *protos.GoalStateAck) {
+	if ack.GetError() == "" {
+		klog.Infof("Remote client %s applied generation %d", ack.GetPodName(), ack.GetGeneration())
+		m.AppliedGenerations[ack.GetPodName()] = ack.GetGeneration()
+		return
+	}
+
+	klog.Errorf("Remote client %s failed to apply generation %d: %s", ack.GetPodName(), ack.GetGeneration(), ack.GetError())
+	if ack.GetGeneration() <= m.hydratedGenerations[ack.GetPodName()] {
+		// hydrating again wouldn't fix a failed hydration
+		return
+	}
+	for _, client := range m.Registrations {
+		if client.GetPodName() == ack.GetPodName() {
+			m.hydrate(client)
+		}
+	}
+}
+
 func (m *EventsServer) handle() error {
 	klog.Infof("Starting transport manager listener on port %v", m.port)
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", m.port))
@@ -26,13 +26,15 @@ type DataplaneEventsServer struct {
 	protos.UnimplementedDataplaneEventsServer
 	ctx   context.Context
 	regCh chan<- clientStreamConnection
+	ackCh chan<- *protos.GoalStateAck
 }
 
 // NewServer creates a new DataplaneEventsServer instance
-func NewServer(ctx context.Context, ch chan clientStreamConnection) *DataplaneEventsServer {
+func NewServer(ctx context.Context, ch chan clientStreamConnection, ackCh chan *protos.GoalStateAck) *DataplaneEventsServer {
 	return &DataplaneEventsServer{
 		ctx:   ctx,
 		regCh: ch,
+		ackCh: ackCh,
 	}
 }
 
@@ -58,3 +60,15 @@ func (d *DataplaneEventsServer) Connect(m *protos.DatapathPodMetadata, stream pr
 
 	return nil
 }
+
+// Acknowledge is called when a client applied a goal state generation
+func (d *DataplaneEventsServer) Acknowledge(ctx context.Context, ack *protos.GoalStateAck) (*protos.GoalStateAckResponse, error) {
+	select {
+	case d.ackCh <- ack:
+		return &protos.GoalStateAckResponse{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err() //nolint:wrapcheck // the gRPC status of the context error is returned to the client
+	case <-d.ctx.Done():
+		return nil, d.ctx.Err() //nolint:wrapcheck // the gRPC status of the context error is returned to the client
+	}
+}
@@ -16,6 +16,12 @@ const (
 	KubePodStatusSucceededFlag string = "Succeeded"
 	KubePodStatusUnknownFlag   string = "Unknown"
 
+	// NetworkPolicyAuditAnnotation set to "true" on a NetworkPolicy logs the traffic the policy would deny instead of dropping it
+	NetworkPolicyAuditAnnotation string = "npm.azure.com/audit"
+	// NetworkPolicyFQDNEgressAnnotation on a NetworkPolicy lists the comma-separated domain names which the selected pods may send egress traffic to,
+	// e.g. "*.blob.core.windows.net,login.microsoftonline.com". A "*." prefix matches any subdomain.
+	NetworkPolicyFQDNEgressAnnotation string = "npm.azure.com/fqdn-egress"
+
 	// The version of k8s that accept "AND" between namespaceSelector and podSelector is "1.11"
 	k8sMajorVerForNewPolicyDef string = "1"
 	k8sMinorVerForNewPolicyDef string = "11"
@@ -40,6 +46,12 @@ const (
 	IptablesLegacy             string = "iptables-legacy"
 	IptablesSaveLegacy         string = "iptables-legacy-save"
 	IptablesRestoreLegacy      string = "iptables-legacy-restore"
+	Ip6tablesNft               string = "ip6tables-nft"            //nolint (avoid warning to capitalize this p)
+	Ip6tablesSaveNft           string = "ip6tables-nft-save"       //nolint (avoid warning to capitalize this p)
+	Ip6tablesRestoreNft        string = "ip6tables-nft-restore"    //nolint (avoid warning to capitalize this p)
+	Ip6tablesLegacyCommand     string = "ip6tables-legacy"         //nolint (avoid warning to capitalize this p)
+	Ip6tablesSaveLegacy        string = "ip6tables-legacy-save"    //nolint (avoid warning to capitalize this p)
+	Ip6tablesRestoreLegacy     string = "ip6tables-legacy-restore" //nolint (avoid warning to capitalize this p)
 	IptablesRestoreNoFlushFlag string = "--noflush"
 	IptablesRestoreTableFlag   string = "-T"
 	IptablesRestoreCommit      string = "COMMIT"
@@ -61,6 +73,9 @@ const (
 	IptablesDrop               string = "DROP"
 	IptablesReturn             string = "RETURN"
 	IptablesMark               string = "MARK"
+	IptablesNFLog              string = "NFLOG"
+	IptablesNFLogGroupFlag     string = "--nflog-group"
+	IptablesNFLogPrefixFlag    string = "--nflog-prefix"
 	IptablesSrcFlag            string = "src"
 	IptablesDstFlag            string = "dst"
 	IptablesNamedPortFlag      string = "dst,dst"
@@ -80,6 +95,9 @@ const (
 	IptablesStateFlag          string = "--state"
 	IptablesCtstateModuleFlag  string = "conntrack" // state module is obsolete: https://unix.stackexchange.com/questions/108169/what-is-the-difference-between-m-conntrack-ctstate-and-m-state-state
 	IptablesCtstateFlag        string = "--ctstate"
+	IptablesCtdirFlag          string = "--ctdir"
+	IptablesCtorigdstFlag      string = "--ctorigdst"
+	IptablesReplyDirection     string = "REPLY"
 	IptablesMultiportFlag      string = "multiport"
 	IptablesRelatedState       string = "RELATED"
 	IptablesEstablishedState   string = "ESTABLISHED"
@@ -104,6 +122,11 @@ const (
 	IptablesAzureIngressChain          string = "AZURE-NPM-INGRESS"
 	IptablesAzureIngressAllowMarkChain string = "AZURE-NPM-INGRESS-ALLOW-MARK"
 	IptablesAzureEgressChain           string = "AZURE-NPM-EGRESS"
+	// AdminNetworkPolicies are evaluated before NetworkPolicies and BaselineAdminNetworkPolicies after them
+	IptablesAzureIngressAdminChain    string = "AZURE-NPM-INGRESS-ADMIN"
+	IptablesAzureEgressAdminChain     string = "AZURE-NPM-EGRESS-ADMIN"
+	IptablesAzureIngressBaselineChain string = "AZURE-NPM-INGRESS-BASELINE"
+	IptablesAzureEgressBaselineChain  string = "AZURE-NPM-EGRESS-BASELINE"
 
 	// Chains used in NPM v1
 	IptablesAzureIngressPortChain  string = "AZURE-NPM-INGRESS-PORT"
@@ -141,6 +164,9 @@ const (
 	IptablesAzureIngressAllowMarkHex string = "0x200/0x200"
 	IptablesAzureIngressDropMarkHex  string = "0x400/0x400"
 	IptablesAzureEgressDropMarkHex   string = "0x800/0x800"
+	// IptablesAzurePassMarkHex skips the lower priority AdminNetworkPolicies after an AdminNetworkPolicy passes a flow
+	IptablesAzurePassMarkHex      string = "0x100/0x100"
+	IptablesAzureClearPassMarkHex string = "0x0/0x100"
 
 	// marks in NPM v1
 	IptablesAzureIngressMarkHex string = "0x2000"
@@ -151,6 +177,18 @@ const (
 	// IptablesAzureEgressMarkHex is for checking the absolute value of the mark
 	IptablesAzureEgressMarkHex string = "0x1000"
 	IptablesAzureAcceptMarkHex string = "0x3000"
+
+	// NFLOG prefixes of denied flows are "<prefix>-<direction>:<hash of the policy key>",
+	// e.g. "NPM-DROP-IN:1234567". Audited policies log instead of dropping.
+	NFLogDropPrefix  string = "NPM-DROP"
+	NFLogAuditPrefix string = "NPM-AUDIT"
+	// DefaultNFLogGroup is the NFLOG group of denied flows unless configured otherwise
+	DefaultNFLogGroup int = 100
+	// NFLogDNSPrefix is the NFLOG prefix of the DNS responses which the FQDN snooper reads
+	NFLogDNSPrefix string = "NPM-DNS"
+	// DefaultDNSNFLogGroup is the NFLOG group of DNS responses unless configured otherwise
+	DefaultDNSNFLogGroup int    = 101
+	DNSPort              string = "53"
 )
 
 // ipset related constants.
@@ -184,6 +222,8 @@ const (
 
 	AzureNpmFlag   string = "azure-npm"
 	AzureNpmPrefix string = "azure-npm-"
+	// IPv6SetNameSuffix is appended to a hashed ipset name before hashing it again to name the ipset's family inet6 counterpart
+	IPv6SetNameSuffix string = "-ipv6"
 
 	IpsetMaxelemName string = "maxelem" // todo, what's using this?
 	IpsetMaxelemNum  string = "4294967295"
@@ -204,6 +244,21 @@ const (
 	SetPolicyDelimiter string = ","
 )
 
+// nftables related constants.
+const (
+	Nft         string = "nft"
+	NftFileFlag string = "-f"
+	// NftStdin makes nft read the file from stdin
+	NftStdin  string = "-"
+	NftFamily string = "inet"
+	// NftTable holds all NPM sets and chains when the nftables dataplane is enabled
+	NftTable string = "azure-npm"
+	// NftForwardChain is the base chain of NftTable attached to the forward hook
+	NftForwardChain string = "AZURE-NPM-FORWARD"
+	// NftMaxCommentLength is the maximum length of a rule comment in nftables
+	NftMaxCommentLength int = 128
+)
+
 const (
 	BashCommand     string = "bash"
 	BashCommandFlag string = "-c"
@@ -278,3 +333,44 @@ func SetIptablesToLegacy() {
 	IptablesSave = IptablesSaveLegacy
 	IptablesRestore = IptablesRestoreLegacy
 }
+
+// IptablesCommands are the iptables, iptables-save, and iptables-restore commands of one iptables version (nft or legacy) and ip family.
+type IptablesCommands struct {
+	Iptables        string
+	IptablesSave    string
+	IptablesRestore string
+}
+
+// NewIptablesCommands returns the commands of the iptables version and ip family.
+func NewIptablesCommands(nft, ipv6 bool) IptablesCommands {
+	switch {
+	case nft && ipv6:
+		return IptablesCommands{Ip6tablesNft, Ip6tablesSaveNft, Ip6tablesRestoreNft}
+	case nft:
+		return IptablesCommands{IptablesNft, IptablesSaveNft, IptablesRestoreNft}
+	case ipv6:
+		return IptablesCommands{Ip6tablesLegacyCommand, Ip6tablesSaveLegacy, Ip6tablesRestoreLegacy}
+	default:
+		return IptablesCommands{IptablesLegacy, IptablesSaveLegacy, IptablesRestoreLegacy}
+	}
+}
+
+// CurrentIptablesCommands returns the commands of the ip family for the iptables version which Iptables is set to.
+func CurrentIptablesCommands(ipv6 bool) IptablesCommands {
+	return NewIptablesCommands(Iptables != IptablesLegacy, ipv6)
+}
+
+// IsNft returns true for the iptables-nft commands.
+func (c IptablesCommands) IsNft() bool {
+	return c.Iptables == IptablesNft || c.Iptables == Ip6tablesNft
+}
+
+// IsIPv6 returns true for the ip6tables commands.
+func (c IptablesCommands) IsIPv6() bool {
+	return c.Iptables == Ip6tablesNft || c.Iptables == Ip6tablesLegacyCommand
+}
+
+// OtherVersion returns the commands of the same ip family for the other iptables version.
+func (c IptablesCommands) OtherVersion() IptablesCommands {
+	return NewIptablesCommands(!c.IsNft(), c.IsIPv6())
+}
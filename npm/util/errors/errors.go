@@ -14,6 +14,12 @@ var (
 
 	// ErrDstNotSpecified thrown during NPM debug cli mode when the source packet is not specified
 	ErrDstNotSpecified = errors.New("destination not specified")
+
+	// ErrManifestsNotSpecified thrown during NPM debug cli mode when no manifest files are specified for a simulation
+	ErrManifestsNotSpecified = errors.New("manifest files not specified")
+
+	// ErrUnexpectedVerdict thrown during NPM debug cli mode when a simulated flow's verdict isn't the expected one
+	ErrUnexpectedVerdict = errors.New("unexpected verdict")
 )
 
 /*
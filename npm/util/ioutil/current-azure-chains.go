@@ -22,8 +22,9 @@ var (
 	errInvalidGrepResult    = errors.New("unexpectedly got no lines while grepping for current Azure chains")
 )
 
-func AllCurrentAzureChains(exec utilexec.Interface, lockWaitTimeSeconds string) (map[string]struct{}, error) {
-	iptablesListCommand := exec.Command(util.Iptables,
+// AllCurrentAzureChains lists the Azure chains in the filter table with the iptables command, e.g. ip6tables-nft for ip6tables.
+func AllCurrentAzureChains(exec utilexec.Interface, iptablesCmd, lockWaitTimeSeconds string) (map[string]struct{}, error) {
+	iptablesListCommand := exec.Command(iptablesCmd,
 		util.IptablesWaitFlag, lockWaitTimeSeconds, util.IptablesTableFlag, util.IptablesFilterTable,
 		util.IptablesNumericFlag, util.IptablesListFlag,
 	)
@@ -122,6 +122,31 @@ func (creator *FileCreator) AddLine(sectionID string, errorHandlers []*LineError
 	section.lineNums = append(section.lineNums, len(creator.lines)-1)
 }
 
+// Transformed returns a FileCreator with the same settings and a transformed copy of each line, keeping the line's section and error handlers.
+// Lines omitted after a failed run are still copied, so call this before running the command with the original.
+func (creator *FileCreator) Transformed(transform func(content string) string) *FileCreator {
+	transformed := &FileCreator{
+		lines:                  make([]*Line, 0, len(creator.lines)),
+		sections:               make(map[string]*Section, len(creator.sections)),
+		lineNumbersToOmit:      make(map[int]struct{}),
+		errorsToRetryOn:        creator.errorsToRetryOn,
+		lineFailureDefinitions: creator.lineFailureDefinitions,
+		tryCount:               0,
+		maxTryCount:            creator.maxTryCount,
+		ioShim:                 creator.ioShim,
+		verbose:                creator.verbose,
+	}
+	for _, line := range creator.lines {
+		transformed.lines = append(transformed.lines, &Line{transform(line.content), line.sectionID, line.errorHandlers})
+	}
+	for id, section := range creator.sections {
+		lineNums := make([]int, len(section.lineNums))
+		copy(lineNums, section.lineNums)
+		transformed.sections[id] = &Section{id, lineNums}
+	}
+	return transformed
+}
+
 // ToString combines the lines in the FileCreator and ends with a new line.
 func (creator *FileCreator) ToString() string {
 	result := strings.Builder{}
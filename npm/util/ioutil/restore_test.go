@@ -4,6 +4,7 @@
 package ioutil
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Azure/azure-container-networking/common"
@@ -55,6 +56,35 @@ line3-item1 line3-item2 line3-item3
 	)
 }
 
+func TestTransformed(t *testing.T) {
+	creator := NewFileCreator(common.NewMockIOShim(nil), 1)
+	creator.AddLine(section1ID, nil, "line1-item1", "line1-item2")
+	creator.AddLine(section2ID, nil, "line2-item1", "line2-item2")
+
+	transformed := creator.Transformed(func(content string) string {
+		return strings.ReplaceAll(content, "item", "transformed")
+	})
+	require.Equal(t, []int{0}, transformed.sections[section1ID].lineNums)
+	require.Equal(t, []int{1}, transformed.sections[section2ID].lineNums)
+	require.Equal(t, section2ID, transformed.lines[1].sectionID)
+	assert.Equal(
+		t,
+		`line1-transformed1 line1-transformed2
+line2-transformed1 line2-transformed2
+`,
+		transformed.ToString(),
+	)
+
+	// the original is unchanged
+	assert.Equal(
+		t,
+		`line1-item1 line1-item2
+line2-item1 line2-item2
+`,
+		creator.ToString(),
+	)
+}
+
 func TestRunCommandWithFile(t *testing.T) {
 	calls := []testutils.TestCmd{fakeSuccessCommand}
 	creator := NewFileCreator(common.NewMockIOShim(calls), 1)
@@ -164,6 +164,12 @@ func GetHashedName(name string) string {
 	return AzureNpmPrefix + Hash(name)
 }
 
+// GetIPv6HashedName returns the name of the family inet6 counterpart of the ipset with the hashed name.
+// It's derived from the hashed name so that the counterparts of list members are known without their prefixed names.
+func GetIPv6HashedName(hashedName string) string {
+	return GetHashedName(hashedName + IPv6SetNameSuffix)
+}
+
 // CompareK8sVer compares two k8s versions.
 // returns -1, 0, 1 if firstVer smaller, equals, bigger than secondVer respectively.
 // returns -2 for error.
@@ -342,6 +348,20 @@ func IsIPV4(ip string) bool {
 	return address.Is4()
 }
 
+// IsIPV6 returns true for an IPv6 address or CIDR.
+func IsIPV6(ip string) bool {
+	ipOnly, _, isIPBlock := strings.Cut(ip, "/")
+	address, err := netip.ParseAddr(ipOnly)
+	if err != nil || !address.Is6() || address.Is4In6() {
+		return false
+	}
+	if isIPBlock {
+		_, err := netip.ParsePrefix(ip)
+		return err == nil
+	}
+	return true
+}
+
 // Get preferred outbound ip of this machine
 // source: https://stackoverflow.com/questions/23558425/how-do-i-get-the-local-ip-address-in-go
 func NodeIP() (string, error) {
@@ -451,3 +451,39 @@ func TestNodeIP(t *testing.T) {
 	_, err := NodeIP()
 	require.Nil(t, err, "NodeIP() returned error")
 }
+
+func TestIsIPV6(t *testing.T) {
+	tests := map[string]bool{
+		"2001:db8::1":         true,
+		"2001:db8::/32":       true,
+		"::/0":                true,
+		"2001:db8::/129":      false,
+		"::ffff:10.0.0.1":     false,
+		"10.0.0.1":            false,
+		"10.0.0.0/8":          false,
+		"2001:db8::1,tcp:80":  false,
+		"":                    false,
+		"2001:db8::1 nomatch": false,
+	}
+	for ip, want := range tests {
+		require.Equal(t, want, IsIPV6(ip), "IsIPV6(%s)", ip)
+	}
+}
+
+func TestIptablesCommands(t *testing.T) {
+	legacy6 := NewIptablesCommands(false, true)
+	require.Equal(t, IptablesCommands{Ip6tablesLegacyCommand, Ip6tablesSaveLegacy, Ip6tablesRestoreLegacy}, legacy6)
+	require.True(t, legacy6.IsIPv6())
+	require.False(t, legacy6.IsNft())
+	require.Equal(t, IptablesCommands{Ip6tablesNft, Ip6tablesSaveNft, Ip6tablesRestoreNft}, legacy6.OtherVersion())
+
+	nft4 := NewIptablesCommands(true, false)
+	require.Equal(t, IptablesCommands{IptablesNft, IptablesSaveNft, IptablesRestoreNft}, nft4)
+	require.False(t, nft4.IsIPv6())
+	require.Equal(t, IptablesCommands{IptablesLegacy, IptablesSaveLegacy, IptablesRestoreLegacy}, nft4.OtherVersion())
+
+	SetIptablesToLegacy()
+	defer SetIptablesToNft()
+	require.Equal(t, legacy6, CurrentIptablesCommands(true))
+	require.Equal(t, IptablesLegacy, Iptables, "the global commands should be unchanged")
+}
@@ -7,8 +7,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -236,6 +238,36 @@ func HasMellanoxAdapter() bool {
 // Not needed for Linux
 func MonitorAndSetMellanoxRegKeyPriorityVLANTag(_ context.Context, _ int) {}
 
+// sysClassNetPath is where the kernel exposes the network interfaces, a variable to be overridden in tests.
+var sysClassNetPath = "/sys/class/net"
+
+// FetchMacAddressPnpIDMapping returns the pci address of the SR-IOV capable physical functions by their mac address.
 func FetchMacAddressPnpIDMapping(_ context.Context, _ ExecClient) (map[string]string, error) {
-	return nil, nil
+	entries, err := os.ReadDir(sysClassNetPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list network interfaces")
+	}
+
+	result := make(map[string]string)
+	for _, entry := range entries {
+		ifPath := filepath.Join(sysClassNetPath, entry.Name())
+		// only physical functions expose sriov_totalvfs
+		if _, err := os.Stat(filepath.Join(ifPath, "device", "sriov_totalvfs")); err != nil {
+			continue
+		}
+		device, err := os.Readlink(filepath.Join(ifPath, "device"))
+		if err != nil {
+			continue
+		}
+		address, err := os.ReadFile(filepath.Join(ifPath, "address"))
+		if err != nil {
+			continue
+		}
+		macAddress, err := net.ParseMAC(strings.TrimSpace(string(address)))
+		if err != nil {
+			continue
+		}
+		result[macAddress.String()] = filepath.Base(device)
+	}
+	return result, nil
 }
@@ -3,7 +3,9 @@ package platform
 import (
 	"context"
 	"errors"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -60,3 +62,39 @@ func TestExecuteCommandTimeout(t *testing.T) {
 	}
 	t.Logf("%s", err.Error())
 }
+
+func TestFetchMacAddressPnpIDMapping(t *testing.T) {
+	root := t.TempDir()
+	sysClassNetPath = filepath.Join(root, "class", "net")
+	defer func() { sysClassNetPath = "/sys/class/net" }()
+
+	// eth1 is an SR-IOV physical function, eth0 is not
+	files := map[string]string{
+		"devices/0000:00:08.0/vendor":         "0x1414\n",
+		"devices/0000:3b:00.0/sriov_totalvfs": "8\n",
+		"class/net/eth0/address":              "00:0d:3a:00:00:01\n",
+		"class/net/eth1/address":              "00:0D:3A:00:00:02\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for ifName, pciAddress := range map[string]string{"eth0": "0000:00:08.0", "eth1": "0000:3b:00.0"} {
+		if err := os.Symlink(filepath.Join(root, "devices", pciAddress), filepath.Join(sysClassNetPath, ifName, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mapping, err := FetchMacAddressPnpIDMapping(context.Background(), NewMockExecClient(false))
+	if err != nil {
+		t.Fatalf("FetchMacAddressPnpIDMapping failed with error %v", err)
+	}
+	if len(mapping) != 1 || mapping["00:0d:3a:00:00:02"] != "0000:3b:00.0" {
+		t.Errorf("unexpected mapping %v", mapping)
+	}
+}
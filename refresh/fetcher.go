@@ -2,31 +2,54 @@ package refresh
 
 import (
 	"context"
+	stderrors "errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
 	DefaultMinInterval = 4 * time.Second
 	DefaultMaxInterval = 1024 * time.Second
+	// errorBackoffJitter is the fraction of the interval added at random to it after failed fetches, so that
+	// fetchers failing together don't retry together.
+	errorBackoffJitter = 0.2
 )
 
-// Fetcher fetches data at regular intervals. The interval will vary within the range of minInterval and
-// maxInterval. When no diff is observed after a fetch, the interval doubles (subject to the maximum interval).
-// When a diff is observed, the interval resets to the minimum. The interval can be made unchanging by setting
-// minInterval and maxInterval to the same desired value.
+// ErrStale is returned by the health check of a Fetcher whose data hasn't been fetched successfully recently.
+var ErrStale = errors.New("fetched data is stale")
 
+// Fetcher fetches data at regular intervals and publishes it to its subscribers. The interval will vary within
+// the range of minInterval and maxInterval. When no diff is observed after a fetch, the interval doubles (subject
+// to the maximum interval). When a diff is observed, the interval resets to the minimum. When fetches fail, the
+// interval backs off exponentially from the minimum with jitter until a fetch succeeds. The interval can be made
+// unchanging by setting minInterval and maxInterval to the same desired value.
 type Fetcher[T equaler[T]] struct {
-	fetchFunc       func(context.Context) (T, error)
+	fetchFunc   func(context.Context) (T, error)
+	minInterval time.Duration
+	maxInterval time.Duration
+	ticker      TickProvider
+	logger      Logger
+
+	// fetchMu serializes the fetches and the notifications of the subscribers.
+	fetchMu         sync.Mutex
 	cache           T
-	minInterval     time.Duration
-	maxInterval     time.Duration
+	cached          bool
 	currentInterval time.Duration
-	ticker          TickProvider
-	consumeFunc     func(T) error
-	logger          Logger
+	failures        int
+	subscribers     []func(T) error
+
+	// mu guards the status of the fetches, which is read concurrently with them.
+	mu          sync.RWMutex
+	lastSuccess time.Time
+	lastErr     error
 }
 
-// NewFetcher creates a new Fetcher. If minInterval is 0, it will default to 4 seconds.
+// NewFetcher creates a new Fetcher. If minInterval is 0, it will default to 4 seconds. The consumeFunc, if not
+// nil, is the first subscriber of the Fetcher.
 func NewFetcher[T equaler[T]](
 	fetchFunc func(context.Context) (T, error),
 	minInterval time.Duration,
@@ -44,33 +67,40 @@ func NewFetcher[T equaler[T]](
 
 	maxInterval = max(minInterval, maxInterval)
 
-	return &Fetcher[T]{
+	f := &Fetcher[T]{
 		fetchFunc:       fetchFunc,
 		minInterval:     minInterval,
 		maxInterval:     maxInterval,
 		currentInterval: minInterval,
-		consumeFunc:     consumeFunc,
 		logger:          logger,
 	}
+	if consumeFunc != nil {
+		f.subscribers = append(f.subscribers, consumeFunc)
+	}
+	return f
+}
+
+// Subscribe adds a consumer of the fetched data. It is invoked with the latest data right away if any was
+// fetched already, and then after every fetch which observes a diff.
+func (f *Fetcher[T]) Subscribe(consumeFunc func(T) error) {
+	f.fetchMu.Lock()
+	defer f.fetchMu.Unlock()
+
+	f.subscribers = append(f.subscribers, consumeFunc)
+	if f.cached {
+		if err := consumeFunc(f.cache); err != nil {
+			f.logger.Errorf("Error consuming data: %v", err)
+		}
+	}
 }
 
 func (f *Fetcher[T]) Start(ctx context.Context) {
 	go func() {
 		// do an initial fetch
-		res, err := f.fetchFunc(ctx)
-		if err != nil {
-			f.logger.Printf("Error invoking fetch: %v", err)
-		}
-
-		f.cache = res
-		if f.consumeFunc != nil {
-			if err := f.consumeFunc(res); err != nil {
-				f.logger.Errorf("Error consuming data: %v", err)
-			}
-		}
+		_ = f.refresh(ctx)
 
 		if f.ticker == nil {
-			f.ticker = NewTimedTickProvider(f.currentInterval)
+			f.ticker = NewTimedTickProvider(f.interval())
 		}
 
 		defer f.ticker.Stop()
@@ -81,30 +111,92 @@ func (f *Fetcher[T]) Start(ctx context.Context) {
 				f.logger.Printf("Fetcher stopped")
 				return
 			case <-f.ticker.C():
-				result, err := f.fetchFunc(ctx)
-				if err != nil {
-					f.logger.Errorf("Error fetching data: %v", err)
-				} else {
-					if result.Equal(f.cache) {
-						f.updateFetchIntervalForNoObservedDiff()
-						f.logger.Printf("No diff observed in fetch, not invoking the consumer")
-					} else {
-						f.cache = result
-						f.updateFetchIntervalForObservedDiff()
-						if f.consumeFunc != nil {
-							if err := f.consumeFunc(result); err != nil {
-								f.logger.Errorf("Error consuming data: %v", err)
-							}
-						}
-					}
-				}
-
-				f.ticker.Reset(f.currentInterval)
+				_ = f.refresh(ctx)
+				f.ticker.Reset(f.interval())
 			}
 		}
 	}()
 }
 
+// Trigger fetches the data right away, and returns once the subscribers have consumed it if it changed. It
+// returns the error of the fetch, or the errors of the subscribers.
+func (f *Fetcher[T]) Trigger(ctx context.Context) error {
+	return f.refresh(ctx)
+}
+
+// LastSuccess returns when the data was last fetched successfully, or the zero time if it never was.
+func (f *Fetcher[T]) LastSuccess() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.lastSuccess
+}
+
+// HealthCheck returns a check which fails with ErrStale when the data wasn't fetched successfully within
+// maxAge, which should be longer than the maximum interval. It can be used as a healthz.Checker.
+func (f *Fetcher[T]) HealthCheck(maxAge time.Duration) func(*http.Request) error {
+	return func(*http.Request) error {
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		if f.lastSuccess.IsZero() {
+			return errors.Wrapf(ErrStale, "no successful fetch yet, last error: %v", f.lastErr)
+		}
+		if age := time.Since(f.lastSuccess); age > maxAge {
+			return errors.Wrapf(ErrStale, "last successful fetch %v ago, last error: %v", age.Round(time.Second), f.lastErr)
+		}
+		return nil
+	}
+}
+
+// refresh fetches the data and publishes it to the subscribers if it changed, and updates the interval until the
+// next fetch.
+func (f *Fetcher[T]) refresh(ctx context.Context) error {
+	f.fetchMu.Lock()
+	defer f.fetchMu.Unlock()
+
+	result, err := f.fetchFunc(ctx)
+	f.mu.Lock()
+	f.lastErr = err
+	if err == nil {
+		f.lastSuccess = time.Now()
+	}
+	f.mu.Unlock()
+
+	if err != nil {
+		f.failures++
+		f.currentInterval = f.backoffInterval()
+		f.logger.Errorf("Error fetching data: %v", err)
+		return errors.Wrap(err, "failed to fetch data")
+	}
+
+	if f.failures > 0 {
+		f.failures = 0
+		f.currentInterval = f.minInterval
+	}
+
+	if f.cached && result.Equal(f.cache) {
+		f.updateFetchIntervalForNoObservedDiff()
+		f.logger.Printf("No diff observed in fetch, not invoking the consumer")
+		return nil
+	}
+
+	f.cache, f.cached = result, true
+	f.updateFetchIntervalForObservedDiff()
+	var errs []error
+	for _, consume := range f.subscribers {
+		if err := consume(result); err != nil {
+			f.logger.Errorf("Error consuming data: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+func (f *Fetcher[T]) interval() time.Duration {
+	f.fetchMu.Lock()
+	defer f.fetchMu.Unlock()
+	return f.currentInterval
+}
+
 func (f *Fetcher[T]) updateFetchIntervalForNoObservedDiff() {
 	f.currentInterval = min(f.currentInterval*2, f.maxInterval) // nolint:gomnd // doubling logic
 }
@@ -112,3 +204,15 @@ func (f *Fetcher[T]) updateFetchIntervalForNoObservedDiff() {
 func (f *Fetcher[T]) updateFetchIntervalForObservedDiff() {
 	f.currentInterval = f.minInterval
 }
+
+// backoffInterval is the interval after consecutive failed fetches. It starts at the minimum interval and doubles
+// with every failure up to the maximum interval, with jitter.
+func (f *Fetcher[T]) backoffInterval() time.Duration {
+	backoff := f.minInterval
+	for i := 1; i < f.failures && backoff < f.maxInterval; i++ {
+		backoff *= 2 // nolint:gomnd // doubling logic
+	}
+	backoff = min(backoff, f.maxInterval)
+	jitter := time.Duration(rand.Int64N(int64(float64(backoff)*errorBackoffJitter) + 1)) //nolint:gosec // jitter doesn't need a secure random number
+	return backoff + jitter
+}
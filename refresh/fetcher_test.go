@@ -6,11 +6,15 @@ import (
 	"net/netip"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/nodesubnet"
 	"github.com/Azure/azure-container-networking/nmagent"
 	"github.com/Azure/azure-container-networking/refresh"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock client that simply tracks if refresh has been called
@@ -147,6 +151,103 @@ func TestRefresh(t *testing.T) {
 	}
 }
 
+// value is fetched by the fetchers under test.
+type value int
+
+func (v value) Equal(o value) bool {
+	return v == o
+}
+
+// source returns its values in turn, or errors where a value is negative.
+type source struct {
+	values []value
+	calls  int
+}
+
+var errFetch = errors.New("fetch failed")
+
+func (s *source) fetch(context.Context) (value, error) {
+	v := s.values[min(s.calls, len(s.values)-1)]
+	s.calls++
+	if v < 0 {
+		return 0, errFetch
+	}
+	return v, nil
+}
+
+func TestFetcherSubscribers(t *testing.T) {
+	src := &source{values: []value{1, 1, 2}}
+	var first, second []value
+	fetcher := refresh.NewFetcher[value](src.fetch, time.Second, time.Minute, func(v value) error {
+		first = append(first, v)
+		return nil
+	}, logger.Log)
+
+	require.NoError(t, fetcher.Trigger(context.Background()))
+	// late subscribers get the latest value right away
+	fetcher.Subscribe(func(v value) error {
+		second = append(second, v)
+		return nil
+	})
+	require.NoError(t, fetcher.Trigger(context.Background()))
+	require.NoError(t, fetcher.Trigger(context.Background()))
+
+	assert.Equal(t, []value{1, 2}, first)
+	assert.Equal(t, []value{1, 2}, second)
+}
+
+func TestFetcherSubscriberErrors(t *testing.T) {
+	src := &source{values: []value{1}}
+	errConsume := errors.New("consume failed")
+	fetcher := refresh.NewFetcher[value](src.fetch, time.Second, time.Minute, func(value) error {
+		return errConsume
+	}, logger.Log)
+
+	require.ErrorIs(t, fetcher.Trigger(context.Background()), errConsume)
+}
+
+func TestFetcherBackoffOnErrors(t *testing.T) {
+	src := &source{values: []value{1, -1, -1, -1, -1, 1, 2}}
+	var consumed []value
+	fetcher := refresh.NewFetcher[value](src.fetch, time.Second, 5*time.Second, func(v value) error {
+		consumed = append(consumed, v)
+		return nil
+	}, logger.Log)
+
+	require.NoError(t, fetcher.Trigger(context.Background()))
+	assert.Equal(t, time.Second, fetcher.CurrentInterval())
+
+	// the interval doubles with every failure up to the maximum, with up to 20% jitter
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		require.ErrorIs(t, fetcher.Trigger(context.Background()), errFetch)
+		assert.GreaterOrEqual(t, fetcher.CurrentInterval(), want)
+		assert.LessOrEqual(t, fetcher.CurrentInterval(), want+want/5)
+	}
+
+	// the interval resumes doubling from the minimum after a successful fetch, which doesn't notify without a diff
+	require.NoError(t, fetcher.Trigger(context.Background()))
+	assert.Equal(t, 2*time.Second, fetcher.CurrentInterval())
+	require.NoError(t, fetcher.Trigger(context.Background()))
+	assert.Equal(t, time.Second, fetcher.CurrentInterval())
+	assert.Equal(t, []value{1, 2}, consumed)
+}
+
+func TestFetcherHealthCheck(t *testing.T) {
+	src := &source{values: []value{-1, 1}}
+	fetcher := refresh.NewFetcher[value](src.fetch, time.Second, time.Minute, nil, logger.Log)
+	check := fetcher.HealthCheck(time.Minute)
+
+	require.Error(t, fetcher.Trigger(context.Background()))
+	assert.True(t, fetcher.LastSuccess().IsZero())
+	require.ErrorIs(t, check(nil), refresh.ErrStale)
+
+	require.NoError(t, fetcher.Trigger(context.Background()))
+	assert.False(t, fetcher.LastSuccess().IsZero())
+	require.NoError(t, check(nil))
+
+	require.ErrorIs(t, fetcher.HealthCheck(0)(nil), refresh.ErrStale)
+}
+
 // testContext creates a context from the provided testing.T that will be
 // canceled if the test suite is terminated.
 func testContext(t *testing.T) (context.Context, context.CancelFunc) {
@@ -1,5 +1,11 @@
 package refresh
 
+import "time"
+
 func (f *Fetcher[T]) SetTicker(t TickProvider) {
 	f.ticker = t
 }
+
+func (f *Fetcher[T]) CurrentInterval() time.Duration {
+	return f.interval()
+}
@@ -4,6 +4,15 @@ package tls
 
 import "time"
 
+// Sources of the TLS certificate.
+const (
+	CertificateSourceFile             = "file"
+	CertificateSourceKeyVault         = "keyvault"
+	CertificateSourceKubernetesSecret = "kubernetes-secret"
+	CertificateSourceSecretStoreCSI   = "secret-store-csi"
+	CertificateSourceSelfSigned       = "self-signed"
+)
+
 // TlsSettings - Details related to the TLS certificate.
 type TlsSettings struct {
 	TLSSubjectName                     string
@@ -16,6 +25,26 @@ type TlsSettings struct {
 	UseMTLS                            bool
 	MinTLSVersion                      string
 	MtlsClientCertSubjectName          string
+	// MtlsClientCAPath is an optional PEM bundle of the CAs trusted to sign client certificates.
+	// The CAs of the server certificate are trusted if it's unset.
+	MtlsClientCAPath string
+	// CertificateReloadInterval is how often the certificate files are checked for changes, and
+	// reloaded. Zero disables reloading.
+	CertificateReloadInterval time.Duration
+	// CertificateSource selects where the certificate is read from. It defaults to the file when
+	// TLSCertificatePath is set, and to KeyVault otherwise.
+	CertificateSource string
+	// KubernetesSecretNamespace and KubernetesSecretName locate the TLS Secret of the certificate.
+	KubernetesSecretNamespace string
+	KubernetesSecretName      string
+	// SecretStoreDirectory is where the Secrets Store CSI driver mounts the certificate, as the
+	// SecretStoreObjectName file.
+	SecretStoreDirectory  string
+	SecretStoreObjectName string
+	// SelfSignedDNSNames are the DNS names of self-signed certificates, which are valid for
+	// SelfSignedValidity.
+	SelfSignedDNSNames []string
+	SelfSignedValidity time.Duration
 }
 
 func GetTlsCertificateRetriever(settings TlsSettings) (TlsCertificateRetriever, error) {
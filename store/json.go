@@ -124,19 +124,20 @@ func (kvs *jsonFileStore) Write(key string, value interface{}) error {
 
 	kvs.data[key] = &raw
 
-	return kvs.flush()
+	return kvs.flush(false)
 }
 
-// Flush commits in-memory state to persistent store.
+// Flush commits in-memory state to persistent store, and syncs it to disk so that it survives a
+// reboot of the host.
 func (kvs *jsonFileStore) Flush() error {
 	kvs.Mutex.Lock()
 	defer kvs.Mutex.Unlock()
 
-	return kvs.flush()
+	return kvs.flush(true)
 }
 
 // Lock-free flush for internal callers.
-func (kvs *jsonFileStore) flush() error {
+func (kvs *jsonFileStore) flush(sync bool) error {
 	buf, err := json.MarshalIndent(&kvs.data, "", "\t")
 	if err != nil {
 		return err
@@ -167,6 +168,12 @@ func (kvs *jsonFileStore) flush() error {
 		return fmt.Errorf("Temp file write failed with: %v", err)
 	}
 
+	if sync {
+		if err = f.Sync(); err != nil {
+			return fmt.Errorf("temp file sync failed with: %v", err)
+		}
+	}
+
 	if err = f.Close(); err != nil {
 		return fmt.Errorf("temp file close failed with: %v", err)
 	}
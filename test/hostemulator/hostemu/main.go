@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-container-networking/test/hostemulator"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "address to serve the NMAgent, Wireserver, and IMDS APIs on")
+	scenarioPath := flag.String("scenario", "", "path to a JSON scenario file, defaults to a VM with a single primary interface")
+	flag.Parse()
+
+	scenario := hostemulator.DefaultScenario()
+	if *scenarioPath != "" {
+		var err error
+		if scenario, err = hostemulator.LoadScenario(*scenarioPath); err != nil {
+			log.Fatalf("failed to load scenario: %v", err)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           hostemulator.New(scenario),
+		ReadHeaderTimeout: 5 * time.Second, //nolint:gomnd // reasonable timeout
+	}
+	log.Printf("serving the host emulator on %s, scenario at %s", *addr, hostemulator.ScenarioPath)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("host emulator failed: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Package hostemulator emulates the NMAgent, Wireserver, and IMDS APIs of an
+// Azure VM, so that CNS can be run and tested without one.
+package hostemulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/imds"
+	acntime "github.com/Azure/azure-container-networking/internal/time"
+)
+
+const (
+	// wirePluginPath is the Wireserver path which proxies requests to NMAgent.
+	wirePluginPath = "/machine/plugins"
+	// imdsPathPrefix is the prefix of the IMDS paths.
+	imdsPathPrefix = "/metadata/"
+	// ScenarioPath is the path to get and replace the Scenario of a running
+	// Emulator.
+	ScenarioPath = "/emulator/scenario"
+)
+
+// Emulator is an http.Handler serving the NMAgent, Wireserver, and IMDS APIs
+// from a programmable Scenario. The Wireserver and IMDS APIs don't share any
+// paths, so the Emulator serves both from a single address.
+type Emulator struct {
+	mu       sync.Mutex
+	scenario Scenario
+	calls    map[API]int
+	// incarnation is the DocumentIncarnation of the scheduled events, which
+	// changes with them.
+	incarnation int
+}
+
+// New creates an Emulator with the state of the Scenario.
+func New(s Scenario) *Emulator {
+	e := &Emulator{calls: map[API]int{}}
+	e.SetScenario(s)
+	return e
+}
+
+// Scenario returns a copy of the current state.
+func (e *Emulator) Scenario() Scenario {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.scenario.clone()
+}
+
+// SetScenario replaces the current state.
+func (e *Emulator) SetScenario(s Scenario) {
+	s = s.clone()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario = s
+	e.incarnation++
+}
+
+// SetSupportedAPIs sets the APIs which NMAgent reports as supported.
+func (e *Emulator) SetSupportedAPIs(apis ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario.SupportedAPIs = apis
+}
+
+// SetHomeAz sets the home AZ returned by GetHomeAz.
+func (e *Emulator) SetHomeAz(az uint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario.HomeAz = az
+}
+
+// SetInterfaces sets the interfaces of the VM.
+func (e *Emulator) SetInterfaces(interfaces ...Interface) {
+	s := Scenario{Interfaces: interfaces}
+	s = s.clone()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario.Interfaces = s.Interfaces
+}
+
+// SetNCVersion sets the version of a published network container, like
+// NMAgent does once it has programmed the network container.
+func (e *Emulator) SetNCVersion(ncID, version string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	nc := e.scenario.NetworkContainers[ncID]
+	nc.Version = version
+	e.scenario.NetworkContainers[ncID] = nc
+}
+
+// SetScheduledEvents sets the maintenance events returned by IMDS.
+func (e *Emulator) SetScheduledEvents(events ...imds.ScheduledEvent) {
+	s := Scenario{ScheduledEvents: events}
+	s = s.clone()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario.ScheduledEvents = s.ScheduledEvents
+	e.incarnation++
+}
+
+// InjectFault makes the API fail.
+func (e *Emulator) InjectFault(api API, f Fault) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.scenario.Faults == nil {
+		e.scenario.Faults = map[API]Fault{}
+	}
+	e.scenario.Faults[api] = f
+}
+
+// ClearFaults removes the faults from all APIs.
+func (e *Emulator) ClearFaults() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario.Faults = map[API]Fault{}
+}
+
+// SetLatency delays the responses of the API, or of all APIs without their own
+// latency for AllAPIs.
+func (e *Emulator) SetLatency(api API, d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.scenario.Latency == nil {
+		e.scenario.Latency = map[API]acntime.Duration{}
+	}
+	e.scenario.Latency[api] = acntime.Duration{Duration: d}
+}
+
+// Calls returns the number of requests to the API, including the failed ones.
+func (e *Emulator) Calls(api API) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls[api]
+}
+
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == wirePluginPath || r.URL.Path == wirePluginPath+"/":
+		e.serveWireserver(w, r)
+	case strings.HasPrefix(r.URL.Path, imdsPathPrefix):
+		e.serveIMDS(w, r)
+	case r.URL.Path == ScenarioPath:
+		e.serveScenario(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveScenario gets or replaces the Scenario, so that a standalone Emulator
+// can be driven through scenarios.
+func (e *Emulator) serveScenario(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, e.Scenario())
+	case http.MethodPut:
+		var s Scenario
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e.SetScenario(s)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// begin records a call to the API and returns the fault to fail it with, if
+// any, and the latency to delay it by.
+func (e *Emulator) begin(api API) (*Fault, time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls[api]++
+
+	latency, ok := e.scenario.Latency[api]
+	if !ok {
+		latency = e.scenario.Latency[AllAPIs]
+	}
+
+	f, ok := e.scenario.Faults[api]
+	if !ok {
+		return nil, latency.Duration
+	}
+	if f.Count > 0 {
+		remaining := f
+		remaining.Count--
+		if remaining.Count == 0 {
+			delete(e.scenario.Faults, api)
+		} else {
+			e.scenario.Faults[api] = remaining
+		}
+	}
+	return &f, latency.Duration
+}
+
+// delay waits for the latency, or until the request is canceled.
+func delay(r *http.Request, latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+	t := time.NewTimer(latency)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-r.Context().Done():
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
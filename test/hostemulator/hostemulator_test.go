@@ -0,0 +1,309 @@
+package hostemulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/imds"
+	"github.com/Azure/azure-container-networking/cns/wireserver"
+	"github.com/Azure/azure-container-networking/nmagent"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...any) {}
+
+func newNMAgentClient(t *testing.T, srv *httptest.Server) *nmagent.Client {
+	t.Helper()
+	config, err := nmagent.NewConfig(srv.URL)
+	require.NoError(t, err)
+	client, err := nmagent.NewClient(config)
+	require.NoError(t, err)
+	return client
+}
+
+func TestNMAgentClient(t *testing.T) {
+	e := New(DefaultScenario())
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	client := newNMAgentClient(t, srv)
+	ctx := context.Background()
+
+	apis, err := client.SupportedAPIs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"GetHomeAz"}, apis)
+
+	az, err := client.GetHomeAz(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), az.HomeAz)
+	require.True(t, az.ContainsFixes(nmagent.HomeAZFixIPv6))
+
+	interfaces, err := client.GetInterfaceIPInfo(ctx)
+	require.NoError(t, err)
+	require.Len(t, interfaces.Entries, 1)
+	require.True(t, interfaces.Entries[0].IsPrimary)
+	require.Equal(t, "00:22:48:26:3d:bd", net.HardwareAddr(interfaces.Entries[0].MacAddress).String())
+	require.Equal(t, "10.240.0.0/16", interfaces.Entries[0].InterfaceSubnets[0].Prefix)
+
+	require.NoError(t, client.JoinNetwork(ctx, nmagent.JoinNetworkRequest{NetworkID: "vnet"}))
+	_, err = client.GetNetworkConfiguration(ctx, nmagent.GetNetworkConfigRequest{VNetID: "vnet"})
+	require.NoError(t, err)
+
+	require.NoError(t, client.PutNetworkContainer(ctx, &nmagent.PutNetworkContainerRequest{
+		ID:                  "nc1",
+		VNetID:              "vnet",
+		Version:             2,
+		SubnetName:          "subnet",
+		IPv4Addrs:           []string{"10.0.0.4"},
+		AuthenticationToken: "token",
+		PrimaryAddress:      "10.240.0.4",
+	}))
+	list, err := client.GetNCVersionList(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []nmagent.NCVersion{{NetworkContainerID: "nc1", Version: "2"}}, list.Containers)
+
+	// NMAgent lags behind the published version until it has programmed it
+	e.SetNCVersion("nc1", "1")
+	version, err := client.GetNCVersion(ctx, nmagent.NCVersionRequest{
+		AuthToken:          "token",
+		NetworkContainerID: "nc1",
+		PrimaryAddress:     "10.240.0.4",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1", version.Version)
+
+	require.NoError(t, client.DeleteNetworkContainer(ctx, nmagent.DeleteContainerRequest{
+		NCID:                "nc1",
+		PrimaryAddress:      "10.240.0.4",
+		AuthenticationToken: "token",
+	}))
+	require.Empty(t, e.Scenario().NetworkContainers)
+
+	require.NoError(t, client.DeleteNetwork(ctx, nmagent.DeleteNetworkRequest{NetworkID: "vnet"}))
+	require.Empty(t, e.Scenario().JoinedNetworks)
+}
+
+func TestNMAgentFaults(t *testing.T) {
+	e := New(DefaultScenario())
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	client := newNMAgentClient(t, srv)
+	ctx := context.Background()
+
+	e.InjectFault(APIGetHomeAz, Fault{StatusCode: http.StatusInternalServerError, Count: 1})
+	_, err := client.GetHomeAz(ctx)
+	var nmaErr nmagent.Error
+	require.ErrorAs(t, err, &nmaErr)
+	require.Equal(t, http.StatusInternalServerError, nmaErr.StatusCode())
+	// the fault is gone after a single request
+	_, err = client.GetHomeAz(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, e.Calls(APIGetHomeAz))
+
+	e.InjectFault(APIGetInterfaceInfo, Fault{StatusCode: http.StatusServiceUnavailable, Wireserver: true})
+	_, err = client.GetInterfaceIPInfo(ctx)
+	require.ErrorAs(t, err, &nmaErr)
+	require.Equal(t, http.StatusServiceUnavailable, nmaErr.StatusCode())
+	require.Contains(t, nmaErr.Error(), "wireserver")
+	e.ClearFaults()
+
+	require.NoError(t, client.PutNetworkContainer(ctx, &nmagent.PutNetworkContainerRequest{
+		ID:                  "nc1",
+		VNetID:              "vnet",
+		SubnetName:          "subnet",
+		IPv4Addrs:           []string{"10.0.0.4"},
+		AuthenticationToken: "token",
+		PrimaryAddress:      "10.240.0.4",
+	}))
+	_, err = client.GetNCVersion(ctx, nmagent.NCVersionRequest{
+		AuthToken:          "other",
+		NetworkContainerID: "nc1",
+		PrimaryAddress:     "10.240.0.4",
+	})
+	require.ErrorAs(t, err, &nmaErr)
+	require.True(t, nmaErr.Unauthorized())
+}
+
+func TestLatency(t *testing.T) {
+	e := New(DefaultScenario())
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	client := newNMAgentClient(t, srv)
+
+	e.SetLatency(AllAPIs, time.Minute)
+	e.SetLatency(APISupportedAPIs, 0)
+	_, err := client.SupportedAPIs(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GetHomeAz(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWireserverClientAndProxy(t *testing.T) {
+	e := New(DefaultScenario())
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	hostport := srv.Listener.Addr().String()
+	ctx := context.Background()
+
+	client := &wireserver.Client{HostPort: hostport, HTTPClient: &http.Client{}, Logger: nopLogger{}}
+	res, err := client.GetInterfaces(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []wireserver.Interface{
+		{
+			MacAddress: "002248263DBD",
+			IsPrimary:  true,
+			IPSubnet: []wireserver.Subnet{
+				{
+					Prefix:    "10.240.0.0/16",
+					IPAddress: []wireserver.Address{{Address: "10.240.0.4", IsPrimary: true}},
+				},
+			},
+		},
+	}, res.Interface)
+
+	proxy := &wireserver.Proxy{Host: hostport, HTTPClient: &http.Client{}}
+	resp, err := proxy.JoinNetwork(ctx, "vnet")
+	require.NoError(t, err)
+	requireNMAgentStatus(t, resp, http.StatusOK)
+	require.Contains(t, e.Scenario().JoinedNetworks, "vnet")
+
+	params := cns.NetworkContainerParameters{NCID: "nc1", AuthToken: "token", AssociatedInterfaceID: "10.240.0.4"}
+	resp, err = proxy.PublishNC(ctx, params, []byte(`{"version":"3","virtualNetworkId":"vnet","subnetName":"subnet"}`))
+	require.NoError(t, err)
+	requireNMAgentStatus(t, resp, http.StatusOK)
+	require.Equal(t, "3", e.Scenario().NetworkContainers["nc1"].Version)
+
+	resp, err = proxy.PublishNC(ctx, params, []byte(`not json`))
+	require.NoError(t, err)
+	requireNMAgentStatus(t, resp, http.StatusBadRequest)
+
+	resp, err = proxy.UnpublishNC(ctx, params, nil)
+	require.NoError(t, err)
+	requireNMAgentStatus(t, resp, http.StatusOK)
+	require.Empty(t, e.Scenario().NetworkContainers)
+}
+
+func requireNMAgentStatus(t *testing.T, resp *http.Response, code int) {
+	t.Helper()
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		HTTPStatusCode string `json:"httpStatusCode"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, strconv.Itoa(code), body.HTTPStatusCode)
+}
+
+func TestIMDSClient(t *testing.T) {
+	s := DefaultScenario()
+	s.Interfaces[0].InterfaceCompartmentID = "nc1"
+	e := New(s)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	client := imds.NewClient(imds.Endpoint(srv.URL), imds.RetryAttempts(1))
+	ctx := context.Background()
+
+	vmID, err := client.GetVMUniqueID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, s.VMID, vmID)
+
+	interfaces, err := client.GetNetworkInterfaces(ctx)
+	require.NoError(t, err)
+	require.Len(t, interfaces, 1)
+	require.Equal(t, "00:22:48:26:3d:bd", interfaces[0].MacAddress.String())
+	require.Equal(t, "nc1", interfaces[0].InterfaceCompartmentID)
+
+	versions, err := client.GetIMDSVersions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, s.IMDSVersions, versions.APIVersions)
+
+	e.InjectFault(APIIMDSCompute, Fault{StatusCode: http.StatusTooManyRequests})
+	_, err = client.GetVMUniqueID(ctx)
+	require.ErrorIs(t, err, imds.ErrUnexpectedStatusCode)
+
+	// IMDS rejects requests without the Metadata header
+	resp, err := http.Get(srv.URL + imdsVersionsPath) //nolint:noctx // test
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestIMDSScheduledEvents(t *testing.T) {
+	e := New(DefaultScenario())
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	client := imds.NewClient(imds.Endpoint(srv.URL), imds.RetryAttempts(1))
+	ctx := context.Background()
+
+	vmName, err := client.GetVMName(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "local-vm", vmName)
+
+	events, err := client.GetScheduledEvents(ctx)
+	require.NoError(t, err)
+	require.Empty(t, events.Events)
+
+	e.SetScheduledEvents(imds.ScheduledEvent{
+		EventID:      "reboot",
+		EventType:    imds.EventTypeReboot,
+		ResourceType: "VirtualMachine",
+		Resources:    []string{vmName},
+		EventStatus:  imds.EventStatusScheduled,
+	})
+	events, err = client.GetScheduledEvents(ctx)
+	require.NoError(t, err)
+	require.Len(t, events.Events, 1)
+	require.Equal(t, imds.EventStatusScheduled, events.Events[0].EventStatus)
+	incarnation := events.DocumentIncarnation
+
+	// acknowledged events start
+	require.NoError(t, client.AckScheduledEvents(ctx, "reboot"))
+	events, err = client.GetScheduledEvents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, imds.EventStatusStarted, events.Events[0].EventStatus)
+	require.Greater(t, events.DocumentIncarnation, incarnation)
+	require.Equal(t, 4, e.Calls(APIIMDSScheduledEvents))
+}
+
+func TestScenarioEndpoint(t *testing.T) {
+	e := New(DefaultScenario())
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	s := DefaultScenario()
+	s.HomeAz = 3
+	s.Faults = map[API]Fault{APIGetNCVersionList: {StatusCode: http.StatusInternalServerError}}
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPut, srv.URL+ScenarioPath, bytes.NewReader(b))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	client := newNMAgentClient(t, srv)
+	az, err := client.GetHomeAz(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint(3), az.HomeAz)
+
+	resp, err = http.Get(srv.URL + ScenarioPath) //nolint:noctx // test
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var got Scenario
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	require.Equal(t, uint(3), got.HomeAz)
+	require.Equal(t, http.StatusInternalServerError, got.Faults[APIGetNCVersionList].StatusCode)
+}
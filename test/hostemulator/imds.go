@@ -0,0 +1,147 @@
+package hostemulator
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns/imds"
+)
+
+const (
+	imdsComputePath         = "/metadata/instance/compute"
+	imdsNetworkPath         = "/metadata/instance/network"
+	imdsVersionsPath        = "/metadata/versions"
+	imdsScheduledEventsPath = "/metadata/scheduledevents"
+)
+
+// serveIMDS serves the IMDS APIs. Like IMDS, it requires the Metadata header
+// on every request.
+func (e *Emulator) serveIMDS(w http.ResponseWriter, r *http.Request) {
+	var api API
+	switch r.URL.Path {
+	case imdsComputePath:
+		api = APIIMDSCompute
+	case imdsNetworkPath:
+		api = APIIMDSNetwork
+	case imdsVersionsPath:
+		api = APIIMDSVersions
+	case imdsScheduledEventsPath:
+		api = APIIMDSScheduledEvents
+	default:
+		writeJSON(w, http.StatusNotFound, imdsError("Not found"))
+		return
+	}
+	// scheduled events are acknowledged with a POST
+	if r.Method != http.MethodGet && (api != APIIMDSScheduledEvents || r.Method != http.MethodPost) {
+		writeJSON(w, http.StatusMethodNotAllowed, imdsError("Method not allowed"))
+		return
+	}
+	if r.Header.Get("Metadata") != "true" {
+		writeJSON(w, http.StatusBadRequest, imdsError("Required metadata header not specified"))
+		return
+	}
+
+	fault, latency := e.begin(api)
+	delay(r, latency)
+	if fault != nil {
+		writeJSON(w, fault.StatusCode, imdsError(http.StatusText(fault.StatusCode)))
+		return
+	}
+
+	switch api {
+	case APIIMDSCompute:
+		e.imdsCompute(w)
+	case APIIMDSNetwork:
+		e.imdsNetwork(w)
+	case APIIMDSVersions:
+		e.imdsVersions(w)
+	case APIIMDSScheduledEvents:
+		if r.Method == http.MethodPost {
+			e.imdsAckScheduledEvents(w, r)
+			return
+		}
+		e.imdsScheduledEvents(w)
+	}
+}
+
+func imdsError(msg string) map[string]string {
+	return map[string]string{"error": msg}
+}
+
+func (e *Emulator) imdsCompute(w http.ResponseWriter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{
+		"vmId":     e.scenario.VMID,
+		"name":     e.scenario.VMName,
+		"location": e.scenario.Location,
+		"osType":   "Linux",
+	})
+}
+
+type imdsInterface struct {
+	MacAddress             string `json:"macAddress"`
+	InterfaceCompartmentID string `json:"interfaceCompartmentID,omitempty"`
+}
+
+func (e *Emulator) imdsNetwork(w http.ResponseWriter) {
+	e.mu.Lock()
+	resp := struct {
+		Interface []imdsInterface `json:"interface"`
+	}{Interface: []imdsInterface{}}
+	for _, iface := range e.scenario.Interfaces {
+		mac, err := parseMAC(iface.MacAddress)
+		if err != nil {
+			e.mu.Unlock()
+			writeJSON(w, http.StatusInternalServerError, imdsError(err.Error()))
+			return
+		}
+		resp.Interface = append(resp.Interface, imdsInterface{
+			MacAddress:             mac.String(),
+			InterfaceCompartmentID: iface.InterfaceCompartmentID,
+		})
+	}
+	e.mu.Unlock()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) imdsVersions(w http.ResponseWriter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string][]string{"apiVersions": e.scenario.IMDSVersions})
+}
+
+func (e *Emulator) imdsScheduledEvents(w http.ResponseWriter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	writeJSON(w, http.StatusOK, imds.ScheduledEvents{
+		DocumentIncarnation: e.incarnation,
+		Events:              append([]imds.ScheduledEvent{}, e.scenario.ScheduledEvents...),
+	})
+}
+
+// imdsAckScheduledEvents starts the acknowledged events, like IMDS does once
+// every VM affected by an event has acknowledged it.
+func (e *Emulator) imdsAckScheduledEvents(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		StartRequests []struct {
+			EventID string `json:"EventId"`
+		} `json:"StartRequests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, imdsError(err.Error()))
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, start := range req.StartRequests {
+		for i := range e.scenario.ScheduledEvents {
+			if e.scenario.ScheduledEvents[i].EventID == start.EventID {
+				e.scenario.ScheduledEvents[i].EventStatus = imds.EventStatusStarted
+				e.incarnation++
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
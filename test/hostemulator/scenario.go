@@ -0,0 +1,190 @@
+package hostemulator
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/Azure/azure-container-networking/cns/imds"
+	acntime "github.com/Azure/azure-container-networking/internal/time"
+	"github.com/Azure/azure-container-networking/nmagent"
+	"github.com/pkg/errors"
+)
+
+// API identifies one of the host APIs served by the Emulator.
+type API string
+
+const (
+	// AllAPIs applies latency to every API.
+	AllAPIs API = "*"
+
+	APIJoinNetwork             API = "JoinNetwork"
+	APIDeleteNetwork           API = "DeleteNetwork"
+	APIGetNetworkConfiguration API = "GetNetworkConfiguration"
+	APIPutNetworkContainer     API = "PutNetworkContainer"
+	APIDeleteNetworkContainer  API = "DeleteNetworkContainer"
+	APIGetNCVersion            API = "GetNCVersion"
+	APIGetNCVersionList        API = "GetNCVersionList"
+	APISupportedAPIs           API = "SupportedAPIs"
+	APIGetHomeAz               API = "GetHomeAz"
+	APIGetInterfaceInfo        API = "GetInterfaceInfo"
+	APIIMDSCompute             API = "IMDSCompute"
+	APIIMDSNetwork             API = "IMDSNetwork"
+	APIIMDSVersions            API = "IMDSVersions"
+	APIIMDSScheduledEvents     API = "IMDSScheduledEvents"
+)
+
+// Scenario is the programmable state of the Emulator.
+type Scenario struct {
+	// SupportedAPIs is the list of APIs which NMAgent reports as supported.
+	SupportedAPIs []string `json:"supportedApis"`
+	// HomeAz is the home AZ returned by GetHomeAz.
+	HomeAz uint `json:"homeAz"`
+	// HomeAzAPIVersion is the API version returned by GetHomeAz. Version 2
+	// reports the IPv6 fix.
+	HomeAzAPIVersion uint `json:"homeAzApiVersion"`
+	// Interfaces are the interfaces of the VM, returned by Wireserver and IMDS.
+	Interfaces []Interface `json:"interfaces"`
+	// NetworkContainers are the published network containers, by ID.
+	NetworkContainers map[string]NetworkContainer `json:"networkContainers"`
+	// JoinedNetworks are the joined virtual networks, by ID.
+	JoinedNetworks map[string]nmagent.VirtualNetwork `json:"joinedNetworks"`
+	// VMID is the vmId returned by the IMDS compute metadata.
+	VMID string `json:"vmId"`
+	// VMName is the name returned by the IMDS compute metadata, which
+	// scheduled events use to identify the VMs they affect.
+	VMName string `json:"vmName"`
+	// Location is the location returned by the IMDS compute metadata.
+	Location string `json:"location"`
+	// IMDSVersions are the API versions returned by IMDS.
+	IMDSVersions []string `json:"imdsVersions"`
+	// ScheduledEvents are the maintenance events returned by IMDS. Acknowledged
+	// events are Started.
+	ScheduledEvents []imds.ScheduledEvent `json:"scheduledEvents"`
+	// Faults are the failures injected into the APIs.
+	Faults map[API]Fault `json:"faults"`
+	// Latency delays the responses of the APIs. The AllAPIs latency applies to
+	// APIs without their own latency.
+	Latency map[API]acntime.Duration `json:"latency"`
+}
+
+// Interface is a network interface of the VM.
+type Interface struct {
+	// MacAddress is the MAC address, with or without separators.
+	MacAddress string   `json:"macAddress"`
+	IsPrimary  bool     `json:"isPrimary"`
+	Subnets    []Subnet `json:"subnets"`
+	// InterfaceCompartmentID is the compartment reported by IMDS.
+	InterfaceCompartmentID string `json:"interfaceCompartmentID,omitempty"`
+}
+
+// Subnet is a subnet of an Interface.
+type Subnet struct {
+	Prefix      string      `json:"prefix"`
+	IPAddresses []IPAddress `json:"ipAddresses"`
+}
+
+// IPAddress is an address of an Interface in a Subnet.
+type IPAddress struct {
+	Address   string `json:"address"`
+	IsPrimary bool   `json:"isPrimary"`
+}
+
+// NetworkContainer is a network container published to NMAgent.
+type NetworkContainer struct {
+	// InterfaceAddress is the primary address of the interface which the
+	// network container was published on.
+	InterfaceAddress    string   `json:"interfaceAddress"`
+	AuthenticationToken string   `json:"authenticationToken"`
+	Version             string   `json:"version"`
+	VNetID              string   `json:"vnetId"`
+	SubnetName          string   `json:"subnetName"`
+	IPv4Addresses       []string `json:"ipv4Addresses"`
+}
+
+// Fault makes an API fail.
+type Fault struct {
+	// StatusCode is the HTTP status code of the failure.
+	StatusCode int `json:"statusCode"`
+	// Wireserver makes Wireserver itself fail the request, rather than
+	// NMAgent. It's ignored for the IMDS APIs.
+	Wireserver bool `json:"wireserver,omitempty"`
+	// Count is the number of requests which fail before the API recovers. Zero
+	// fails every request.
+	Count int `json:"count,omitempty"`
+}
+
+// DefaultScenario returns the state of a VM with a single primary interface,
+// which supports GetHomeAz and hasn't any network containers.
+func DefaultScenario() Scenario {
+	return Scenario{
+		SupportedAPIs:    []string{"GetHomeAz"},
+		HomeAz:           1,
+		HomeAzAPIVersion: 2, //nolint:gomnd // the version with the IPv6 fix
+		Interfaces: []Interface{
+			{
+				MacAddress: "002248263DBD",
+				IsPrimary:  true,
+				Subnets: []Subnet{
+					{
+						Prefix:      "10.240.0.0/16",
+						IPAddresses: []IPAddress{{Address: "10.240.0.4", IsPrimary: true}},
+					},
+				},
+			},
+		},
+		VMID:         "00000000-0000-0000-0000-000000000001",
+		VMName:       "local-vm",
+		Location:     "local",
+		IMDSVersions: []string{"2021-01-01", "2025-07-24"},
+	}
+}
+
+// LoadScenario reads a Scenario from a JSON file.
+func LoadScenario(path string) (Scenario, error) {
+	var s Scenario
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return s, errors.Wrap(err, "reading scenario file")
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, errors.Wrap(err, "decoding scenario file")
+	}
+	return s, nil
+}
+
+// clone returns a deep copy of the Scenario.
+func (s *Scenario) clone() Scenario {
+	out := *s
+	out.SupportedAPIs = slices.Clone(s.SupportedAPIs)
+	out.IMDSVersions = slices.Clone(s.IMDSVersions)
+	out.ScheduledEvents = make([]imds.ScheduledEvent, len(s.ScheduledEvents))
+	for i := range s.ScheduledEvents {
+		out.ScheduledEvents[i] = s.ScheduledEvents[i]
+		out.ScheduledEvents[i].Resources = slices.Clone(s.ScheduledEvents[i].Resources)
+	}
+	out.Interfaces = make([]Interface, len(s.Interfaces))
+	for i := range s.Interfaces {
+		out.Interfaces[i] = s.Interfaces[i]
+		out.Interfaces[i].Subnets = make([]Subnet, len(s.Interfaces[i].Subnets))
+		for j := range s.Interfaces[i].Subnets {
+			out.Interfaces[i].Subnets[j] = s.Interfaces[i].Subnets[j]
+			out.Interfaces[i].Subnets[j].IPAddresses = slices.Clone(s.Interfaces[i].Subnets[j].IPAddresses)
+		}
+	}
+	out.NetworkContainers = make(map[string]NetworkContainer, len(s.NetworkContainers))
+	for id, nc := range s.NetworkContainers {
+		nc.IPv4Addresses = slices.Clone(nc.IPv4Addresses)
+		out.NetworkContainers[id] = nc
+	}
+	out.JoinedNetworks = make(map[string]nmagent.VirtualNetwork, len(s.JoinedNetworks))
+	for id, vnet := range s.JoinedNetworks {
+		vnet.DNSServers = slices.Clone(vnet.DNSServers)
+		vnet.Subnets = slices.Clone(vnet.Subnets)
+		out.JoinedNetworks[id] = vnet
+	}
+	out.Faults = maps.Clone(s.Faults)
+	out.Latency = maps.Clone(s.Latency)
+	return out
+}
@@ -0,0 +1,342 @@
+package hostemulator
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-container-networking/nmagent"
+)
+
+// nmagentRequest is a request to NMAgent, proxied by Wireserver.
+type nmagentRequest struct {
+	api        API
+	vnetID     string
+	ifAddress  string
+	ncID       string
+	authToken  string
+	deleteCall bool
+}
+
+// parseNMAgentType identifies the NMAgent API from the method and the type of
+// a Wireserver plugin query, like
+// NetworkManagement/interfaces/{address}/networkContainers/{id}/authenticationToken/{token}/api-version/1.
+func parseNMAgentType(method, typ string) (nmagentRequest, bool) {
+	typ = strings.TrimPrefix(typ, "/")
+	switch typ {
+	case "GetSupportedApis":
+		return nmagentRequest{api: APISupportedAPIs}, method == http.MethodGet
+	case "GetHomeAz/api-version/1":
+		return nmagentRequest{api: APIGetHomeAz}, method == http.MethodGet
+	case "getinterfaceinfov1":
+		return nmagentRequest{api: APIGetInterfaceInfo}, method == http.MethodGet
+	case "NetworkManagement/interfaces/api-version/2":
+		return nmagentRequest{api: APIGetNCVersionList}, method == http.MethodGet
+	}
+
+	var req nmagentRequest
+	typ, req.deleteCall = strings.CutSuffix(typ, "/method/DELETE")
+	typ, ok := strings.CutSuffix(typ, "/api-version/1")
+	if !ok {
+		return req, false
+	}
+
+	if vnetID, ok := strings.CutPrefix(typ, "NetworkManagement/joinedVirtualNetworks/"); ok {
+		req.vnetID = vnetID
+		switch {
+		case req.deleteCall && method == http.MethodPost:
+			req.api = APIDeleteNetwork
+		case method == http.MethodPost:
+			req.api = APIJoinNetwork
+		case method == http.MethodGet:
+			req.api = APIGetNetworkConfiguration
+		default:
+			return req, false
+		}
+		return req, vnetID != ""
+	}
+
+	// the authentication token is last, since it may contain slashes
+	nc, token, ok := strings.Cut(typ, "/authenticationToken/")
+	if !ok {
+		return req, false
+	}
+	req.authToken = token
+	nc, ok = strings.CutPrefix(nc, "NetworkManagement/interfaces/")
+	if !ok {
+		return req, false
+	}
+	nc, version := strings.CutSuffix(nc, "/version")
+	req.ifAddress, req.ncID, ok = strings.Cut(nc, "/networkContainers/")
+	if !ok || req.ifAddress == "" || req.ncID == "" {
+		return req, false
+	}
+	switch {
+	case version && !req.deleteCall && method == http.MethodGet:
+		req.api = APIGetNCVersion
+	case !version && req.deleteCall && method == http.MethodPost:
+		req.api = APIDeleteNetworkContainer
+	case !version && !req.deleteCall && method == http.MethodPost:
+		req.api = APIPutNetworkContainer
+	default:
+		return req, false
+	}
+	return req, true
+}
+
+// serveWireserver serves the NMAgent APIs proxied by Wireserver. Wireserver
+// always responds with 200 OK unless it fails itself, and reports the status
+// code of NMAgent in the httpStatusCode of a JSON response. Successful XML
+// responses are returned as they are.
+func (e *Emulator) serveWireserver(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("comp") != "nmagent" {
+		http.Error(w, "unknown plugin", http.StatusBadRequest)
+		return
+	}
+	req, ok := parseNMAgentType(r.Method, q.Get("type"))
+	if !ok {
+		writeNMAgent(w, http.StatusNotFound, nil)
+		return
+	}
+
+	fault, latency := e.begin(req.api)
+	delay(r, latency)
+	if fault != nil {
+		if fault.Wireserver {
+			w.WriteHeader(fault.StatusCode)
+			return
+		}
+		writeNMAgent(w, fault.StatusCode, nil)
+		return
+	}
+
+	switch req.api {
+	case APISupportedAPIs:
+		e.supportedAPIs(w)
+	case APIGetHomeAz:
+		e.getHomeAz(w)
+	case APIGetInterfaceInfo:
+		e.getInterfaceInfo(w)
+	case APIGetNCVersionList:
+		e.getNCVersionList(w)
+	case APIGetNCVersion:
+		e.getNCVersion(w, req)
+	case APIPutNetworkContainer:
+		e.putNetworkContainer(w, r, req)
+	case APIDeleteNetworkContainer:
+		e.deleteNetworkContainer(w, req)
+	case APIJoinNetwork:
+		e.joinNetwork(w, req)
+	case APIDeleteNetwork:
+		e.deleteNetwork(w, req)
+	case APIGetNetworkConfiguration:
+		e.getNetworkConfiguration(w, req)
+	}
+}
+
+func (e *Emulator) supportedAPIs(w http.ResponseWriter) {
+	e.mu.Lock()
+	resp := nmagent.SupportedAPIsResponseXML{SupportedApis: e.scenario.SupportedAPIs}
+	e.mu.Unlock()
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"SupportedApis"`
+		nmagent.SupportedAPIsResponseXML
+	}{SupportedAPIsResponseXML: resp})
+}
+
+func (e *Emulator) getHomeAz(w http.ResponseWriter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	writeNMAgent(w, http.StatusOK, map[string]uint{
+		"homeAz":     e.scenario.HomeAz,
+		"apiVersion": e.scenario.HomeAzAPIVersion,
+	})
+}
+
+type xmlIPAddress struct {
+	Address   string `xml:"Address,attr"`
+	IsPrimary bool   `xml:"IsPrimary,attr"`
+}
+
+type xmlSubnet struct {
+	Prefix    string         `xml:"Prefix,attr"`
+	IPAddress []xmlIPAddress `xml:"IPAddress"`
+}
+
+type xmlInterface struct {
+	MacAddress string      `xml:"MacAddress,attr"`
+	IsPrimary  bool        `xml:"IsPrimary,attr"`
+	IPSubnet   []xmlSubnet `xml:"IPSubnet"`
+}
+
+type xmlInterfaces struct {
+	XMLName   xml.Name       `xml:"Interfaces"`
+	Interface []xmlInterface `xml:"Interface"`
+}
+
+func (e *Emulator) getInterfaceInfo(w http.ResponseWriter) {
+	e.mu.Lock()
+	var resp xmlInterfaces
+	for _, iface := range e.scenario.Interfaces {
+		mac, err := parseMAC(iface.MacAddress)
+		if err != nil {
+			e.mu.Unlock()
+			writeNMAgent(w, http.StatusInternalServerError, nil)
+			return
+		}
+		x := xmlInterface{
+			MacAddress: strings.ToUpper(hex.EncodeToString(mac)),
+			IsPrimary:  iface.IsPrimary,
+		}
+		for _, subnet := range iface.Subnets {
+			s := xmlSubnet{Prefix: subnet.Prefix}
+			for _, ip := range subnet.IPAddresses {
+				s.IPAddress = append(s.IPAddress, xmlIPAddress(ip))
+			}
+			x.IPSubnet = append(x.IPSubnet, s)
+		}
+		resp.Interface = append(resp.Interface, x)
+	}
+	e.mu.Unlock()
+	writeXML(w, resp)
+}
+
+func (e *Emulator) getNCVersionList(w http.ResponseWriter) {
+	e.mu.Lock()
+	resp := nmagent.NCVersionList{Containers: []nmagent.NCVersion{}}
+	for id, nc := range e.scenario.NetworkContainers {
+		resp.Containers = append(resp.Containers, nmagent.NCVersion{NetworkContainerID: id, Version: nc.Version})
+	}
+	e.mu.Unlock()
+	sort.Slice(resp.Containers, func(i, j int) bool {
+		return resp.Containers[i].NetworkContainerID < resp.Containers[j].NetworkContainerID
+	})
+	writeNMAgent(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) getNCVersion(w http.ResponseWriter, req nmagentRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	nc, ok := e.scenario.NetworkContainers[req.ncID]
+	if !ok {
+		writeNMAgent(w, http.StatusNotFound, nil)
+		return
+	}
+	if nc.AuthenticationToken != req.authToken {
+		writeNMAgent(w, http.StatusUnauthorized, nil)
+		return
+	}
+	writeNMAgent(w, http.StatusOK, nmagent.NCVersion{NetworkContainerID: req.ncID, Version: nc.Version})
+}
+
+func (e *Emulator) putNetworkContainer(w http.ResponseWriter, r *http.Request, req nmagentRequest) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeNMAgent(w, http.StatusBadRequest, nil)
+		return
+	}
+	var body nmagent.PutNetworkContainerRequest
+	if err := json.Unmarshal(b, &body); err != nil {
+		writeNMAgent(w, http.StatusBadRequest, nil)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if nc, ok := e.scenario.NetworkContainers[req.ncID]; ok && nc.AuthenticationToken != req.authToken {
+		writeNMAgent(w, http.StatusUnauthorized, nil)
+		return
+	}
+	e.scenario.NetworkContainers[req.ncID] = NetworkContainer{
+		InterfaceAddress:    req.ifAddress,
+		AuthenticationToken: req.authToken,
+		Version:             strconv.FormatUint(body.Version, 10),
+		VNetID:              body.VNetID,
+		SubnetName:          body.SubnetName,
+		IPv4Addresses:       body.IPv4Addrs,
+	}
+	writeNMAgent(w, http.StatusOK, nil)
+}
+
+func (e *Emulator) deleteNetworkContainer(w http.ResponseWriter, req nmagentRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if nc, ok := e.scenario.NetworkContainers[req.ncID]; ok && nc.AuthenticationToken != req.authToken {
+		writeNMAgent(w, http.StatusUnauthorized, nil)
+		return
+	}
+	delete(e.scenario.NetworkContainers, req.ncID)
+	writeNMAgent(w, http.StatusOK, nil)
+}
+
+func (e *Emulator) joinNetwork(w http.ResponseWriter, req nmagentRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.scenario.JoinedNetworks[req.vnetID]; !ok {
+		e.scenario.JoinedNetworks[req.vnetID] = nmagent.VirtualNetwork{}
+	}
+	writeNMAgent(w, http.StatusOK, nil)
+}
+
+func (e *Emulator) deleteNetwork(w http.ResponseWriter, req nmagentRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.scenario.JoinedNetworks, req.vnetID)
+	writeNMAgent(w, http.StatusOK, nil)
+}
+
+func (e *Emulator) getNetworkConfiguration(w http.ResponseWriter, req nmagentRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	vnet, ok := e.scenario.JoinedNetworks[req.vnetID]
+	if !ok {
+		writeNMAgent(w, http.StatusNotFound, nil)
+		return
+	}
+	writeNMAgent(w, http.StatusOK, vnet)
+}
+
+// writeNMAgent writes a JSON response of NMAgent through Wireserver, which adds
+// the status code of NMAgent to the response object.
+func writeNMAgent(w http.ResponseWriter, code int, v any) {
+	resp := map[string]json.RawMessage{}
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := json.Unmarshal(b, &resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	resp["httpStatusCode"], _ = json.Marshal(strconv.Itoa(code))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write(b)
+}
+
+// parseMAC parses a MAC address with or without separators.
+func parseMAC(s string) (net.HardwareAddr, error) {
+	if b, err := hex.DecodeString(s); err == nil {
+		return net.HardwareAddr(b), nil
+	}
+	mac, err := net.ParseMAC(s)
+	return mac, err //nolint:wrapcheck // the error names the address
+}
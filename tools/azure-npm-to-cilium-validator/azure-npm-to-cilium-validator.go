@@ -24,6 +24,12 @@ const scriptMetricOperationID = 10000
 
 // Use this tool to validate if your cluster is ready to migrate from Azure Network Policy Manager (NPM) to Cilium.
 func main() {
+	// Use the convert mode to generate Cilium policies for the flagged network policies and services
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+		runConvert(os.Args[2:])
+		return
+	}
+
 	// Parse the kubeconfig flag
 	kubeconfig := flag.String("kubeconfig", "~/.kube/config", "absolute path to the kubeconfig file")
 	detailedMigrationSummary := flag.Bool("detailed-migration-summary", false, "display flagged network polices/services and total cluster resource count")
@@ -41,6 +47,28 @@ func main() {
 		log.Fatalf("Error creating Kubernetes client: %v", err)
 	}
 
+	// Get namespaces, network policies, services and pods
+	namespaces, policiesByNamespace, servicesByNamespace, podsByNamespace := getClusterResources(clientset)
+
+	// Create telemetry handle
+	// Note: npmVersionNum and imageVersion telemetry is not needed for this tool so they are set to abitrary values
+	err = metrics.CreateTelemetryHandle(0, "NPM-script-v0.0.1", "014c22bd-4107-459e-8475-67909e96edcb")
+
+	if err != nil {
+		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
+	}
+
+	// Print the migration summary
+	printMigrationSummary(detailedMigrationSummary, namespaces, policiesByNamespace, servicesByNamespace, podsByNamespace)
+}
+
+// getClusterResources gets the namespaces of the cluster and stores the network policies, services, and pods of each namespace in maps
+func getClusterResources(clientset kubernetes.Interface) (
+	*corev1.NamespaceList,
+	map[string][]*networkingv1.NetworkPolicy,
+	map[string][]*corev1.Service,
+	map[string][]*corev1.Pod,
+) {
 	// Get namespaces
 	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
@@ -94,16 +122,7 @@ func main() {
 		}
 	}
 
-	// Create telemetry handle
-	// Note: npmVersionNum and imageVersion telemetry is not needed for this tool so they are set to abitrary values
-	err = metrics.CreateTelemetryHandle(0, "NPM-script-v0.0.1", "014c22bd-4107-459e-8475-67909e96edcb")
-
-	if err != nil {
-		klog.Infof("CreateTelemetryHandle failed with error %v. AITelemetry is not initialized.", err)
-	}
-
-	// Print the migration summary
-	printMigrationSummary(detailedMigrationSummary, namespaces, policiesByNamespace, servicesByNamespace, podsByNamespace)
+	return namespaces, policiesByNamespace, servicesByNamespace, podsByNamespace
 }
 
 func printMigrationSummary(
@@ -439,7 +458,7 @@ func getNamedPortPolicies(policiesByNamespace map[string][]*networkingv1.Network
 func checkNamedPortInPolicyRules(ports []networkingv1.NetworkPolicyPort) bool {
 	for _, port := range ports {
 		// If port is a string it is a named port
-		if port.Port.Type == intstr.String {
+		if port.Port != nil && port.Port.Type == intstr.String {
 			return true
 		}
 	}
@@ -0,0 +1,763 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ciliumAPIVersion              = "cilium.io/v2"
+	ciliumNetworkPolicyKind       = "CiliumNetworkPolicy"
+	ciliumNamespaceLabel          = "k8s:io.kubernetes.pod.namespace"
+	ciliumNamespaceLabelKeyPrefix = "k8s:io.cilium.k8s.namespace.labels."
+
+	// Cilium rejects port rules with more than 40 ports
+	maxPortsPerCiliumPortRule = 40
+	// endPort ranges with more ports than this are not expanded into single ports
+	maxExpandedEndPortRange = 1000
+)
+
+// Cilium entities which replace the peers that Cilium doesn't match the same way as Azure NPM
+const (
+	entityAll        = "all"
+	entityCluster    = "cluster"
+	entityWorld      = "world"
+	entityRemoteNode = "remote-node"
+)
+
+var errUnknownPodCIDRs = errors.New("the pod CIDRs are unknown, set them with --pod-cidrs")
+
+// ciliumNetworkPolicy is the manifest of a cilium.io/v2 CiliumNetworkPolicy with the fields used by the conversion
+type ciliumNetworkPolicy struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Metadata   ciliumPolicyMetadata `json:"metadata"`
+	Spec       ciliumRule           `json:"spec"`
+}
+
+type ciliumPolicyMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type ciliumRule struct {
+	Description      string               `json:"description,omitempty"`
+	EndpointSelector metav1.LabelSelector `json:"endpointSelector"`
+	Ingress          []ciliumIngressRule  `json:"ingress,omitempty"`
+	Egress           []ciliumEgressRule   `json:"egress,omitempty"`
+}
+
+// Cilium doesn't allow more than one kind of peer in a rule, so only one of the peer fields is set in each rule
+type ciliumIngressRule struct {
+	FromEndpoints []metav1.LabelSelector `json:"fromEndpoints,omitempty"`
+	FromCIDRSet   []ciliumCIDRRule       `json:"fromCIDRSet,omitempty"`
+	FromEntities  []string               `json:"fromEntities,omitempty"`
+	ToPorts       []ciliumPortRule       `json:"toPorts,omitempty"`
+}
+
+type ciliumEgressRule struct {
+	ToEndpoints []metav1.LabelSelector `json:"toEndpoints,omitempty"`
+	ToCIDRSet   []ciliumCIDRRule       `json:"toCIDRSet,omitempty"`
+	ToEntities  []string               `json:"toEntities,omitempty"`
+	ToPorts     []ciliumPortRule       `json:"toPorts,omitempty"`
+}
+
+type ciliumCIDRRule struct {
+	CIDR   string   `json:"cidr"`
+	Except []string `json:"except,omitempty"`
+}
+
+type ciliumPortRule struct {
+	Ports []ciliumPortProtocol `json:"ports"`
+}
+
+type ciliumPortProtocol struct {
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// ciliumPeer is one kind of peer of a Cilium rule
+type ciliumPeer struct {
+	endpoints []metav1.LabelSelector
+	cidrSet   []ciliumCIDRRule
+	entities  []string
+}
+
+// clusterResources are the resources to convert, read from a cluster or from manifest files
+type clusterResources struct {
+	namespaces          *corev1.NamespaceList
+	policiesByNamespace map[string][]*networkingv1.NetworkPolicy
+	servicesByNamespace map[string][]*corev1.Service
+	podsByNamespace     map[string][]*corev1.Pod
+	podCIDRs            []netip.Prefix
+}
+
+// conversionResult is the Cilium policy converted from a flagged network policy or service,
+// or the reasons why the resource can't be converted
+type conversionResult struct {
+	resource string
+	policy   *ciliumNetworkPolicy
+	reasons  []string
+}
+
+func runConvert(args []string) {
+	convertFlags := flag.NewFlagSet("convert", flag.ExitOnError)
+	kubeconfig := convertFlags.String("kubeconfig", "~/.kube/config", "absolute path to the kubeconfig file")
+	manifests := convertFlags.String("manifests", "", "comma-separated manifest files or directories to read the resources from instead of the cluster")
+	podCIDRs := convertFlags.String("pod-cidrs", "", "comma-separated pod CIDRs of the cluster (defaults to the pod CIDRs of the nodes)")
+	output := convertFlags.String("output", "cilium-policies.yaml", "file to write the converted Cilium policies to")
+	_ = convertFlags.Parse(args)
+
+	var resources *clusterResources
+	var err error
+	if *manifests != "" {
+		resources, err = readManifests(strings.Split(*manifests, ","))
+	} else {
+		resources, err = readCluster(*kubeconfig)
+	}
+	if err != nil {
+		log.Fatalf("Error reading resources: %v", err)
+	}
+
+	if *podCIDRs != "" {
+		resources.podCIDRs, err = parsePodCIDRs(strings.Split(*podCIDRs, ","))
+		if err != nil {
+			log.Fatalf("Error parsing pod CIDRs: %v", err)
+		}
+	}
+
+	results := convertFlaggedResources(resources)
+
+	convertedPolicies := 0
+	var manifest bytes.Buffer
+	for _, result := range results {
+		if result.policy == nil {
+			continue
+		}
+		policyYAML, err := yaml.Marshal(result.policy)
+		if err != nil {
+			log.Fatalf("Error marshaling Cilium policy %s/%s: %v", result.policy.Metadata.Namespace, result.policy.Metadata.Name, err)
+		}
+		if convertedPolicies > 0 {
+			manifest.WriteString("---\n")
+		}
+		manifest.Write(policyYAML)
+		convertedPolicies++
+	}
+	if convertedPolicies > 0 {
+		if err := os.WriteFile(*output, manifest.Bytes(), 0o600); err != nil {
+			log.Fatalf("Error writing Cilium policies: %v", err)
+		}
+	}
+
+	renderConversionReportTable(results)
+
+	if len(results) == 0 {
+		fmt.Println("\n\033[32m✔ No flagged network policies or services to convert.\033[0m")
+		return
+	}
+	if convertedPolicies > 0 {
+		fmt.Printf("\nWrote %d Cilium policies to \033[32m%s\033[0m. Review them before applying them to the cluster.\n", convertedPolicies, *output)
+	}
+	if convertedPolicies < len(results) {
+		fmt.Println("\033[31m✘ Resources marked by ❌ can't be converted and need to be migrated manually.\033[0m")
+	}
+}
+
+func renderConversionReportTable(results []conversionResult) {
+	reportTable := tablewriter.NewWriter(os.Stdout)
+	reportTable.SetHeader([]string{"Flagged Resource", "Converted", "Cilium Policy / Reason"})
+	reportTable.SetRowLine(true)
+	for _, result := range results {
+		if result.policy != nil {
+			reportTable.Append([]string{result.resource, "✅", fmt.Sprintf("%s %s/%s", result.policy.Kind, result.policy.Metadata.Namespace, result.policy.Metadata.Name)})
+		} else {
+			reportTable.Append([]string{result.resource, "❌", strings.Join(result.reasons, "\n")})
+		}
+	}
+
+	fmt.Println("\nConversion Report:")
+	reportTable.Render()
+}
+
+func readCluster(kubeconfig string) (*clusterResources, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubeconfig: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	resources := &clusterResources{}
+	resources.namespaces, resources.policiesByNamespace, resources.servicesByNamespace, resources.podsByNamespace = getClusterResources(clientset)
+
+	// The pod CIDRs are needed to convert ipBlocks. They can be set with --pod-cidrs if the nodes don't have them.
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Printf("Error getting nodes: %v\n", err)
+		return resources, nil
+	}
+	for i := range nodes.Items {
+		if err := resources.addNodePodCIDRs(&nodes.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// readManifests reads the namespaces, network policies, services, pods, and nodes in the YAML or JSON manifests of the files and directories
+func readManifests(paths []string) (*clusterResources, error) {
+	resources := &clusterResources{
+		namespaces:          &corev1.NamespaceList{},
+		policiesByNamespace: make(map[string][]*networkingv1.NetworkPolicy),
+		servicesByNamespace: make(map[string][]*corev1.Service),
+		podsByNamespace:     make(map[string][]*corev1.Pod),
+	}
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			// only skip files without manifests in directories
+			if file != path {
+				switch filepath.Ext(file) {
+				case ".yaml", ".yml", ".json":
+				default:
+					return nil
+				}
+			}
+			return resources.readManifestFile(file)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifests in %s: %w", path, err)
+		}
+	}
+
+	// The namespaces of the resources are added in case the manifests don't have them
+	namespaces := make(map[string]struct{})
+	for i := range resources.namespaces.Items {
+		namespaces[resources.namespaces.Items[i].Name] = struct{}{}
+	}
+	resourceNamespaces := append(append(sortedKeys(resources.policiesByNamespace), sortedKeys(resources.servicesByNamespace)...), sortedKeys(resources.podsByNamespace)...)
+	for _, namespace := range resourceNamespaces {
+		if _, ok := namespaces[namespace]; !ok {
+			namespaces[namespace] = struct{}{}
+			resources.namespaces.Items = append(resources.namespaces.Items, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		}
+	}
+	return resources, nil
+}
+
+func (r *clusterResources) readManifestFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read manifest %s: %w", file, err)
+		}
+		if err := r.addManifest(document); err != nil {
+			return fmt.Errorf("failed to decode manifest %s: %w", file, err)
+		}
+	}
+}
+
+func (r *clusterResources) addManifest(document []byte) error {
+	if len(bytes.TrimSpace(document)) == 0 {
+		return nil
+	}
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(document, nil, nil)
+	if err != nil {
+		// skip documents without resources and resources which aren't used for the conversion, like custom resources
+		if runtime.IsMissingKind(err) || runtime.IsNotRegisteredError(err) {
+			return nil
+		}
+		return err
+	}
+
+	switch obj := obj.(type) {
+	case *corev1.List:
+		for _, item := range obj.Items {
+			if err := r.addManifest(item.Raw); err != nil {
+				return err
+			}
+		}
+	case *corev1.Namespace:
+		r.namespaces.Items = append(r.namespaces.Items, *obj)
+	case *networkingv1.NetworkPolicy:
+		namespace := defaultNamespace(obj.Namespace)
+		obj.Namespace = namespace
+		r.policiesByNamespace[namespace] = append(r.policiesByNamespace[namespace], obj)
+	case *corev1.Service:
+		namespace := defaultNamespace(obj.Namespace)
+		obj.Namespace = namespace
+		r.servicesByNamespace[namespace] = append(r.servicesByNamespace[namespace], obj)
+	case *corev1.Pod:
+		namespace := defaultNamespace(obj.Namespace)
+		obj.Namespace = namespace
+		r.podsByNamespace[namespace] = append(r.podsByNamespace[namespace], obj)
+	case *corev1.Node:
+		return r.addNodePodCIDRs(obj)
+	}
+	return nil
+}
+
+func (r *clusterResources) addNodePodCIDRs(node *corev1.Node) error {
+	nodePodCIDRs := node.Spec.PodCIDRs
+	if len(nodePodCIDRs) == 0 && node.Spec.PodCIDR != "" {
+		nodePodCIDRs = []string{node.Spec.PodCIDR}
+	}
+	podCIDRs, err := parsePodCIDRs(nodePodCIDRs)
+	if err != nil {
+		return fmt.Errorf("failed to parse pod CIDRs of node %s: %w", node.Name, err)
+	}
+	r.podCIDRs = append(r.podCIDRs, podCIDRs...)
+	return nil
+}
+
+func parsePodCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	podCIDRs := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		podCIDR, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod CIDR %s: %w", cidr, err)
+		}
+		podCIDRs = append(podCIDRs, podCIDR.Masked())
+	}
+	return podCIDRs, nil
+}
+
+// convertFlaggedResources converts the network policies and services which are flagged by the migration summary, sorted by namespace and name
+func convertFlaggedResources(resources *clusterResources) []conversionResult {
+	ingressEndportNetworkPolicy, egressEndportNetworkPolicy := getEndportNetworkPolicies(resources.policiesByNamespace)
+	ingressPoliciesWithCIDR, egressPoliciesWithCIDR := getCIDRNetworkPolicies(resources.policiesByNamespace)
+	ingressPoliciesWithNamedPort, egressPoliciesWithNamedPort := getNamedPortPolicies(resources.policiesByNamespace)
+	egressPolicies := getEgressPolicies(resources.policiesByNamespace)
+	unsafeServices := getUnsafeExternalTrafficPolicyClusterServices(resources.namespaces, resources.servicesByNamespace, resources.policiesByNamespace)
+
+	flaggedPolicies := make(map[string]struct{})
+	for _, policies := range [][]string{
+		ingressEndportNetworkPolicy, egressEndportNetworkPolicy,
+		ingressPoliciesWithCIDR, egressPoliciesWithCIDR,
+		ingressPoliciesWithNamedPort, egressPoliciesWithNamedPort,
+		egressPolicies,
+	} {
+		for _, policy := range policies {
+			flaggedPolicies[policy] = struct{}{}
+		}
+	}
+	flaggedServices := make(map[string]struct{}, len(unsafeServices))
+	for _, service := range unsafeServices {
+		flaggedServices[service] = struct{}{}
+	}
+
+	var results []conversionResult
+	for _, namespace := range sortedKeys(resources.policiesByNamespace) {
+		policies := resources.policiesByNamespace[namespace]
+		sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+		for _, policy := range policies {
+			if _, ok := flaggedPolicies[fmt.Sprintf("%s/%s", namespace, policy.Name)]; ok {
+				results = append(results, convertNetworkPolicy(policy, resources))
+			}
+		}
+	}
+	for _, namespace := range sortedKeys(resources.servicesByNamespace) {
+		services := resources.servicesByNamespace[namespace]
+		sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+		for _, service := range services {
+			if _, ok := flaggedServices[fmt.Sprintf("%s/%s", namespace, service.Name)]; ok {
+				results = append(results, convertService(service, resources))
+			}
+		}
+	}
+	return results
+}
+
+// convertNetworkPolicy converts the network policy to an equivalent CiliumNetworkPolicy with the same name.
+// Peers are converted the way Azure NPM matches them:
+// - ipBlocks which contain the pod CIDRs also select pods with the cluster entity, since Cilium only matches CIDRs outside the cluster
+// - named ports are resolved to the port number of the pods
+// - endPort ranges are expanded into single ports
+func convertNetworkPolicy(policy *networkingv1.NetworkPolicy, resources *clusterResources) conversionResult {
+	result := conversionResult{resource: fmt.Sprintf("NetworkPolicy %s/%s", policy.Namespace, policy.Name)}
+	cnp := newCiliumNetworkPolicy(policy.Namespace, policy.Name, fmt.Sprintf("Converted from NetworkPolicy %s/%s", policy.Namespace, policy.Name))
+	cnp.Spec.EndpointSelector = *policy.Spec.PodSelector.DeepCopy()
+
+	podSelector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	if err != nil {
+		result.reasons = append(result.reasons, fmt.Sprintf("invalid podSelector: %v", err))
+		return result
+	}
+
+	hasIngress, hasEgress := policyTypes(policy)
+	if hasIngress {
+		for _, rule := range policy.Spec.Ingress {
+			// named ports of ingress rules are the ports of the pods which the policy selects
+			ports, portReasons := convertPorts(rule.Ports, func(name string, protocol corev1.Protocol) (int32, error) {
+				return resolveNamedPort(resources.podsByNamespace, name, protocol, policy.Namespace, podSelector)
+			})
+			peers, peerReasons := convertPeers(policy.Namespace, rule.From, resources.podCIDRs)
+			result.reasons = append(result.reasons, portReasons...)
+			result.reasons = append(result.reasons, peerReasons...)
+			for _, peer := range peers {
+				cnp.Spec.Ingress = append(cnp.Spec.Ingress, ciliumIngressRule{
+					FromEndpoints: peer.endpoints,
+					FromCIDRSet:   peer.cidrSet,
+					FromEntities:  peer.entities,
+					ToPorts:       ports,
+				})
+			}
+		}
+		// an empty rule denies all ingress
+		if len(cnp.Spec.Ingress) == 0 {
+			cnp.Spec.Ingress = []ciliumIngressRule{{}}
+		}
+	}
+	if hasEgress {
+		for _, rule := range policy.Spec.Egress {
+			// named ports of egress rules are the ports of the peers, which can be pods in any namespace
+			ports, portReasons := convertPorts(rule.Ports, func(name string, protocol corev1.Protocol) (int32, error) {
+				return resolveNamedPort(resources.podsByNamespace, name, protocol, metav1.NamespaceAll, labels.Everything())
+			})
+			peers, peerReasons := convertPeers(policy.Namespace, rule.To, resources.podCIDRs)
+			result.reasons = append(result.reasons, portReasons...)
+			result.reasons = append(result.reasons, peerReasons...)
+			for _, peer := range peers {
+				cnp.Spec.Egress = append(cnp.Spec.Egress, ciliumEgressRule{
+					ToEndpoints: peer.endpoints,
+					ToCIDRSet:   peer.cidrSet,
+					ToEntities:  peer.entities,
+					ToPorts:     ports,
+				})
+			}
+		}
+		// an empty rule denies all egress
+		if len(cnp.Spec.Egress) == 0 {
+			cnp.Spec.Egress = []ciliumEgressRule{{}}
+		}
+	}
+
+	if len(result.reasons) == 0 {
+		result.policy = cnp
+	}
+	return result
+}
+
+// convertService converts a service with externalTrafficPolicy=Cluster to a CiliumNetworkPolicy which allows
+// traffic from outside the cluster to the target ports of the service's pods. With externalTrafficPolicy=Cluster
+// the traffic can also be forwarded to the pods by other nodes, so the remote-node entity is allowed too.
+func convertService(service *corev1.Service, resources *clusterResources) conversionResult {
+	result := conversionResult{resource: fmt.Sprintf("Service %s/%s", service.Namespace, service.Name)}
+	if len(service.Spec.Selector) == 0 {
+		result.reasons = append(result.reasons, "service without a selector can't be converted")
+		return result
+	}
+	podSelector := labels.SelectorFromSet(service.Spec.Selector)
+
+	servicePorts := make([]networkingv1.NetworkPolicyPort, 0, len(service.Spec.Ports))
+	for _, servicePort := range service.Spec.Ports {
+		protocol := servicePort.Protocol
+		targetPort := servicePort.TargetPort
+		// the target port defaults to the port of the service
+		if targetPort.Type == intstr.Int && targetPort.IntVal == 0 {
+			targetPort = intstr.FromInt32(servicePort.Port)
+		}
+		servicePorts = append(servicePorts, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &targetPort})
+	}
+	ports, portReasons := convertPorts(servicePorts, func(name string, protocol corev1.Protocol) (int32, error) {
+		return resolveNamedPort(resources.podsByNamespace, name, protocol, service.Namespace, podSelector)
+	})
+	if len(portReasons) > 0 {
+		result.reasons = portReasons
+		return result
+	}
+
+	cnp := newCiliumNetworkPolicy(service.Namespace, service.Name+"-external-traffic",
+		fmt.Sprintf("Allows external traffic to Service %s/%s with externalTrafficPolicy=Cluster", service.Namespace, service.Name))
+	cnp.Spec.EndpointSelector = metav1.LabelSelector{MatchLabels: service.Spec.Selector}
+	cnp.Spec.Ingress = []ciliumIngressRule{
+		{
+			FromEntities: []string{entityWorld, entityRemoteNode},
+			ToPorts:      ports,
+		},
+	}
+	result.policy = cnp
+	return result
+}
+
+func newCiliumNetworkPolicy(namespace, name, description string) *ciliumNetworkPolicy {
+	return &ciliumNetworkPolicy{
+		APIVersion: ciliumAPIVersion,
+		Kind:       ciliumNetworkPolicyKind,
+		Metadata: ciliumPolicyMetadata{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: ciliumRule{
+			Description: description,
+		},
+	}
+}
+
+// policyTypes returns whether the policy applies to ingress and egress.
+// A policy without policyTypes applies to ingress, and to egress if it has egress rules.
+func policyTypes(policy *networkingv1.NetworkPolicy) (hasIngress, hasEgress bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+	for _, policyType := range policy.Spec.PolicyTypes {
+		switch policyType {
+		case networkingv1.PolicyTypeIngress:
+			hasIngress = true
+		case networkingv1.PolicyTypeEgress:
+			hasEgress = true
+		}
+	}
+	return hasIngress, hasEgress
+}
+
+// convertPeers converts the peers of a rule to Cilium peers, with one Cilium peer for each kind of peer.
+// A rule without peers allows all peers.
+func convertPeers(namespace string, peers []networkingv1.NetworkPolicyPeer, podCIDRs []netip.Prefix) ([]ciliumPeer, []string) {
+	if len(peers) == 0 {
+		return []ciliumPeer{{entities: []string{entityAll}}}, nil
+	}
+
+	var endpoints []metav1.LabelSelector
+	var cidrSet []ciliumCIDRRule
+	selectsPods := false
+	var reasons []string
+	for _, peer := range peers {
+		if peer.IPBlock == nil {
+			endpoints = append(endpoints, convertPeerSelector(namespace, peer))
+			continue
+		}
+		cidrRule, coversPodCIDRs, err := convertIPBlock(peer.IPBlock, podCIDRs)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		cidrSet = append(cidrSet, cidrRule)
+		selectsPods = selectsPods || coversPodCIDRs
+	}
+
+	var ciliumPeers []ciliumPeer
+	if len(endpoints) > 0 {
+		ciliumPeers = append(ciliumPeers, ciliumPeer{endpoints: endpoints})
+	}
+	if len(cidrSet) > 0 {
+		ciliumPeers = append(ciliumPeers, ciliumPeer{cidrSet: cidrSet})
+	}
+	if selectsPods {
+		ciliumPeers = append(ciliumPeers, ciliumPeer{entities: []string{entityCluster}})
+	}
+	return ciliumPeers, reasons
+}
+
+// convertPeerSelector converts the pod and namespace selectors of a peer to a Cilium endpoint selector.
+// A peer without a namespace selector selects pods in the namespace of the policy.
+func convertPeerSelector(namespace string, peer networkingv1.NetworkPolicyPeer) metav1.LabelSelector {
+	selector := metav1.LabelSelector{}
+	if peer.PodSelector != nil {
+		selector = *peer.PodSelector.DeepCopy()
+	}
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = make(map[string]string)
+	}
+
+	if peer.NamespaceSelector == nil {
+		selector.MatchLabels[ciliumNamespaceLabel] = namespace
+		return selector
+	}
+
+	for key, value := range peer.NamespaceSelector.MatchLabels {
+		selector.MatchLabels[ciliumNamespaceLabelKeyPrefix+key] = value
+	}
+	for _, requirement := range peer.NamespaceSelector.MatchExpressions {
+		namespaceRequirement := *requirement.DeepCopy()
+		namespaceRequirement.Key = ciliumNamespaceLabelKeyPrefix + namespaceRequirement.Key
+		selector.MatchExpressions = append(selector.MatchExpressions, namespaceRequirement)
+	}
+	// otherwise Cilium only selects pods in the namespace of the policy
+	selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      ciliumNamespaceLabel,
+		Operator: metav1.LabelSelectorOpExists,
+	})
+	if len(selector.MatchLabels) == 0 {
+		selector.MatchLabels = nil
+	}
+	return selector
+}
+
+// convertIPBlock converts the ipBlock to a Cilium CIDR rule, and returns whether the ipBlock contains any pod CIDR.
+// An ipBlock which contains part of a pod CIDR, or with an except which contains part of a pod CIDR, can't be converted
+// since Cilium selects pods by identity instead of IP.
+func convertIPBlock(ipBlock *networkingv1.IPBlock, podCIDRs []netip.Prefix) (ciliumCIDRRule, bool, error) {
+	cidr, err := netip.ParsePrefix(ipBlock.CIDR)
+	if err != nil {
+		return ciliumCIDRRule{}, false, fmt.Errorf("invalid ipBlock %s: %w", ipBlock.CIDR, err)
+	}
+	excepts := make([]netip.Prefix, 0, len(ipBlock.Except))
+	for _, except := range ipBlock.Except {
+		exceptCIDR, err := netip.ParsePrefix(except)
+		if err != nil {
+			return ciliumCIDRRule{}, false, fmt.Errorf("invalid except %s of ipBlock %s: %w", except, ipBlock.CIDR, err)
+		}
+		excepts = append(excepts, exceptCIDR)
+	}
+	if len(podCIDRs) == 0 {
+		return ciliumCIDRRule{}, false, fmt.Errorf("ipBlock %s can't be converted: %w", ipBlock.CIDR, errUnknownPodCIDRs)
+	}
+
+	coversPodCIDRs := false
+	for _, podCIDR := range podCIDRs {
+		if !cidr.Overlaps(podCIDR) {
+			continue
+		}
+		if cidr.Bits() > podCIDR.Bits() {
+			return ciliumCIDRRule{}, false, fmt.Errorf("ipBlock %s selects part of the pod CIDR %s", ipBlock.CIDR, podCIDR)
+		}
+		for _, except := range excepts {
+			if except.Overlaps(podCIDR) {
+				return ciliumCIDRRule{}, false, fmt.Errorf("except %s of ipBlock %s selects part of the pod CIDR %s", except, ipBlock.CIDR, podCIDR)
+			}
+		}
+		coversPodCIDRs = true
+	}
+	return ciliumCIDRRule{CIDR: ipBlock.CIDR, Except: ipBlock.Except}, coversPodCIDRs, nil
+}
+
+// convertPorts converts the ports of a rule to Cilium port rules. Named ports are resolved with resolveNamedPort.
+func convertPorts(ports []networkingv1.NetworkPolicyPort, resolveNamedPort func(string, corev1.Protocol) (int32, error)) ([]ciliumPortRule, []string) {
+	var portProtocols []ciliumPortProtocol
+	var reasons []string
+	for _, port := range ports {
+		// Note: an empty protocol defaults to "TCP"
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil && *port.Protocol != "" {
+			protocol = *port.Protocol
+		}
+
+		switch {
+		case port.Port == nil:
+			// Cilium matches all ports with port 0
+			portProtocols = append(portProtocols, ciliumPortProtocol{Port: "0", Protocol: string(protocol)})
+		case port.Port.Type == intstr.String:
+			portNumber, err := resolveNamedPort(port.Port.StrVal, protocol)
+			if err != nil {
+				reasons = append(reasons, err.Error())
+				continue
+			}
+			portProtocols = append(portProtocols, ciliumPortProtocol{Port: strconv.Itoa(int(portNumber)), Protocol: string(protocol)})
+		case port.EndPort != nil:
+			if *port.EndPort-port.Port.IntVal >= maxExpandedEndPortRange {
+				reasons = append(reasons, fmt.Sprintf("endPort range %d-%d has more than %d ports", port.Port.IntVal, *port.EndPort, maxExpandedEndPortRange))
+				continue
+			}
+			for portNumber := port.Port.IntVal; portNumber <= *port.EndPort; portNumber++ {
+				portProtocols = append(portProtocols, ciliumPortProtocol{Port: strconv.Itoa(int(portNumber)), Protocol: string(protocol)})
+			}
+		default:
+			portProtocols = append(portProtocols, ciliumPortProtocol{Port: strconv.Itoa(int(port.Port.IntVal)), Protocol: string(protocol)})
+		}
+	}
+
+	var portRules []ciliumPortRule
+	for len(portProtocols) > 0 {
+		n := min(len(portProtocols), maxPortsPerCiliumPortRule)
+		portRules = append(portRules, ciliumPortRule{Ports: portProtocols[:n]})
+		portProtocols = portProtocols[n:]
+	}
+	return portRules, reasons
+}
+
+// resolveNamedPort returns the number of the container port with the name and protocol in the pods which the selector selects in the namespace.
+// The named port can't be resolved if none of the pods have it, or if the pods have different numbers for it.
+func resolveNamedPort(podsByNamespace map[string][]*corev1.Pod, name string, protocol corev1.Protocol, namespace string, selector labels.Selector) (int32, error) {
+	portNumbers := make(map[int32]struct{})
+	for podNamespace, pods := range podsByNamespace {
+		if namespace != metav1.NamespaceAll && podNamespace != namespace {
+			continue
+		}
+		for _, pod := range pods {
+			if !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			for i := range pod.Spec.Containers {
+				for _, containerPort := range pod.Spec.Containers[i].Ports {
+					containerProtocol := containerPort.Protocol
+					if containerProtocol == "" {
+						containerProtocol = corev1.ProtocolTCP
+					}
+					if containerPort.Name == name && containerProtocol == protocol {
+						portNumbers[containerPort.ContainerPort] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+
+	switch len(portNumbers) {
+	case 0:
+		return 0, fmt.Errorf("named port %s/%s isn't a container port of any selected pod", name, protocol)
+	case 1:
+		for portNumber := range portNumbers {
+			return portNumber, nil
+		}
+	}
+	return 0, fmt.Errorf("named port %s/%s has %d different numbers in the selected pods", name, protocol, len(portNumbers))
+}
+
+func defaultNamespace(namespace string) string {
+	if namespace == "" {
+		return metav1.NamespaceDefault
+	}
+	return namespace
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	sorted := make([]string, 0, len(m))
+	for key := range m {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+func testResources() *clusterResources {
+	return &clusterResources{
+		namespaces: &corev1.NamespaceList{Items: []corev1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "namespace1"}},
+		}},
+		policiesByNamespace: map[string][]*networkingv1.NetworkPolicy{},
+		servicesByNamespace: map[string][]*corev1.Service{},
+		podsByNamespace: map[string][]*corev1.Pod{
+			"namespace1": {
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "namespace1", Labels: map[string]string{"app": "web"}},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{
+						{Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+					}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "namespace1", Labels: map[string]string{"app": "db"}},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{
+						{Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 9090}}},
+					}},
+				},
+			},
+		},
+		podCIDRs: []netip.Prefix{netip.MustParsePrefix("10.244.0.0/16")},
+	}
+}
+
+// Test function for convertNetworkPolicy
+func TestConvertNetworkPolicy(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	tests := []struct {
+		name            string
+		policy          *networkingv1.NetworkPolicy
+		expectedYAML    string
+		expectedReasons []string
+	}{
+		{
+			name: "ipBlock containing the pod CIDR also selects the cluster entity",
+			policy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "cidr-policy", Namespace: "namespace1"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{
+						{
+							From: []networkingv1.NetworkPolicyPeer{
+								{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}},
+								{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+							},
+							Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: intstrPtr(intstr.FromInt(80))}},
+						},
+					},
+				},
+			},
+			expectedYAML: `apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: cidr-policy
+  namespace: namespace1
+spec:
+  description: Converted from NetworkPolicy namespace1/cidr-policy
+  endpointSelector:
+    matchLabels:
+      app: web
+  ingress:
+  - fromEndpoints:
+    - matchLabels:
+        app: db
+        k8s:io.kubernetes.pod.namespace: namespace1
+    toPorts:
+    - ports:
+      - port: "80"
+        protocol: TCP
+  - fromCIDRSet:
+    - cidr: 10.0.0.0/8
+      except:
+      - 10.1.0.0/16
+    toPorts:
+    - ports:
+      - port: "80"
+        protocol: TCP
+  - fromEntities:
+    - cluster
+    toPorts:
+    - ports:
+      - port: "80"
+        protocol: TCP
+`,
+		},
+		{
+			name: "egress named port with different numbers in the pods can't be converted",
+			policy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "egress-named-port-policy", Namespace: "namespace1"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+					Egress: []networkingv1.NetworkPolicyEgressRule{
+						{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: intstrPtr(intstr.FromString("http"))}}},
+					},
+				},
+			},
+			expectedReasons: []string{"named port http/TCP has 2 different numbers in the selected pods"},
+		},
+		{
+			name: "egress with endPort and namespace selector",
+			policy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "egress-policy", Namespace: "namespace1"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+					Egress: []networkingv1.NetworkPolicyEgressRule{
+						{
+							To: []networkingv1.NetworkPolicyPeer{
+								{
+									NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+									PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+								},
+							},
+							Ports: []networkingv1.NetworkPolicyPort{
+								{Port: intstrPtr(intstr.FromInt(8000)), EndPort: int32Ptr(8002)},
+							},
+						},
+					},
+				},
+			},
+			expectedYAML: `apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: egress-policy
+  namespace: namespace1
+spec:
+  description: Converted from NetworkPolicy namespace1/egress-policy
+  egress:
+  - toEndpoints:
+    - matchExpressions:
+      - key: k8s:io.kubernetes.pod.namespace
+        operator: Exists
+      matchLabels:
+        app: db
+        k8s:io.cilium.k8s.namespace.labels.team: a
+    toPorts:
+    - ports:
+      - port: "8000"
+        protocol: TCP
+      - port: "8001"
+        protocol: TCP
+      - port: "8002"
+        protocol: TCP
+  endpointSelector: {}
+`,
+		},
+		{
+			name: "ingress named port is resolved with the selected pods",
+			policy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "named-port-policy", Namespace: "namespace1"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{
+						{Ports: []networkingv1.NetworkPolicyPort{{Port: intstrPtr(intstr.FromString("http"))}}},
+					},
+				},
+			},
+			expectedYAML: `apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: named-port-policy
+  namespace: namespace1
+spec:
+  description: Converted from NetworkPolicy namespace1/named-port-policy
+  endpointSelector:
+    matchLabels:
+      app: web
+  ingress:
+  - fromEntities:
+    - all
+    toPorts:
+    - ports:
+      - port: "8080"
+        protocol: TCP
+`,
+		},
+		{
+			name: "deny all egress",
+			policy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "deny-all-egress", Namespace: "namespace1"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+					Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+				},
+			},
+			expectedYAML: `apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: deny-all-egress
+  namespace: namespace1
+spec:
+  description: Converted from NetworkPolicy namespace1/deny-all-egress
+  egress:
+  - {}
+  endpointSelector: {}
+  ingress:
+  - fromEntities:
+    - all
+`,
+		},
+		{
+			name: "ipBlock selecting part of the pod CIDR can't be converted",
+			policy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "partial-cidr-policy", Namespace: "namespace1"},
+				Spec: networkingv1.NetworkPolicySpec{
+					Ingress: []networkingv1.NetworkPolicyIngressRule{
+						{
+							From: []networkingv1.NetworkPolicyPeer{
+								{IPBlock: &networkingv1.IPBlock{CIDR: "10.244.1.0/24"}},
+								{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.244.0.0/24"}}},
+							},
+						},
+					},
+				},
+			},
+			expectedReasons: []string{
+				"ipBlock 10.244.1.0/24 selects part of the pod CIDR 10.244.0.0/16",
+				"except 10.244.0.0/24 of ipBlock 10.0.0.0/8 selects part of the pod CIDR 10.244.0.0/16",
+			},
+		},
+		{
+			name: "endPort range which is too large can't be converted",
+			policy: &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "large-endport-policy", Namespace: "namespace1"},
+				Spec: networkingv1.NetworkPolicySpec{
+					Ingress: []networkingv1.NetworkPolicyIngressRule{
+						{Ports: []networkingv1.NetworkPolicyPort{{Port: intstrPtr(intstr.FromInt(1)), EndPort: int32Ptr(65535)}}},
+					},
+				},
+			},
+			expectedReasons: []string{"endPort range 1-65535 has more than 1000 ports"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := convertNetworkPolicy(tt.policy, testResources())
+			if !reflect.DeepEqual(result.reasons, tt.expectedReasons) {
+				t.Fatalf("expected reasons %v, got %v", tt.expectedReasons, result.reasons)
+			}
+			if tt.expectedYAML == "" {
+				if result.policy != nil {
+					t.Errorf("expected no Cilium policy, got %+v", result.policy)
+				}
+				return
+			}
+			policyYAML, err := yaml.Marshal(result.policy)
+			if err != nil {
+				t.Fatalf("failed to marshal Cilium policy: %v", err)
+			}
+			if string(policyYAML) != tt.expectedYAML {
+				t.Errorf("expected Cilium policy:\n%s\ngot:\n%s", tt.expectedYAML, policyYAML)
+			}
+		})
+	}
+}
+
+func TestConvertIPBlockWithUnknownPodCIDRs(t *testing.T) {
+	_, _, err := convertIPBlock(&networkingv1.IPBlock{CIDR: "0.0.0.0/0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), errUnknownPodCIDRs.Error()) {
+		t.Errorf("expected error %v, got %v", errUnknownPodCIDRs, err)
+	}
+}
+
+// Test function for convertService
+func TestConvertService(t *testing.T) {
+	tests := []struct {
+		name            string
+		service         *corev1.Service
+		expectedYAML    string
+		expectedReasons []string
+	}{
+		{
+			name: "service with named and default target ports",
+			service: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "namespace1"},
+				Spec: corev1.ServiceSpec{
+					Type:     corev1.ServiceTypeLoadBalancer,
+					Selector: map[string]string{"app": "web"},
+					Ports: []corev1.ServicePort{
+						{Port: 80, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromString("http")},
+						{Port: 53, Protocol: corev1.ProtocolUDP},
+					},
+				},
+			},
+			expectedYAML: `apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: web-external-traffic
+  namespace: namespace1
+spec:
+  description: Allows external traffic to Service namespace1/web with externalTrafficPolicy=Cluster
+  endpointSelector:
+    matchLabels:
+      app: web
+  ingress:
+  - fromEntities:
+    - world
+    - remote-node
+    toPorts:
+    - ports:
+      - port: "8080"
+        protocol: TCP
+      - port: "53"
+        protocol: UDP
+`,
+		},
+		{
+			name: "service without a selector",
+			service: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "namespace1"},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeNodePort,
+					Ports: []corev1.ServicePort{{Port: 80}},
+				},
+			},
+			expectedReasons: []string{"service without a selector can't be converted"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := convertService(tt.service, testResources())
+			if !reflect.DeepEqual(result.reasons, tt.expectedReasons) {
+				t.Fatalf("expected reasons %v, got %v", tt.expectedReasons, result.reasons)
+			}
+			if tt.expectedYAML == "" {
+				return
+			}
+			policyYAML, err := yaml.Marshal(result.policy)
+			if err != nil {
+				t.Fatalf("failed to marshal Cilium policy: %v", err)
+			}
+			if string(policyYAML) != tt.expectedYAML {
+				t.Errorf("expected Cilium policy:\n%s\ngot:\n%s", tt.expectedYAML, policyYAML)
+			}
+		})
+	}
+}
+
+func TestConvertFlaggedResourcesFromManifests(t *testing.T) {
+	manifests := `apiVersion: v1
+kind: Node
+metadata:
+  name: node1
+spec:
+  podCIDR: 10.244.0.0/24
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: networking.k8s.io/v1
+  kind: NetworkPolicy
+  metadata:
+    name: allow-frontend-ingress
+    namespace: namespace1
+  spec:
+    podSelector: {}
+    policyTypes:
+    - Ingress
+    ingress:
+    - from:
+      - podSelector:
+          matchLabels:
+            app: frontend
+- apiVersion: networking.k8s.io/v1
+  kind: NetworkPolicy
+  metadata:
+    name: egress-cidr
+    namespace: namespace1
+  spec:
+    podSelector: {}
+    policyTypes:
+    - Egress
+    egress:
+    - to:
+      - ipBlock:
+          cidr: 0.0.0.0/0
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: namespace1
+spec:
+  type: LoadBalancer
+  selector:
+    app: web
+  ports:
+  - port: 80
+---
+# custom resources are skipped
+apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: existing
+  namespace: namespace1
+spec:
+  endpointSelector: {}
+`
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "manifests.yaml"), []byte(manifests), 0o600); err != nil {
+		t.Fatalf("failed to write manifests: %v", err)
+	}
+	// files which aren't manifests are skipped in directories
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# manifests"), 0o600); err != nil {
+		t.Fatalf("failed to write README: %v", err)
+	}
+
+	resources, err := readManifests([]string{dir})
+	if err != nil {
+		t.Fatalf("failed to read manifests: %v", err)
+	}
+	if !reflect.DeepEqual(resources.podCIDRs, []netip.Prefix{netip.MustParsePrefix("10.244.0.0/24")}) {
+		t.Errorf("expected pod CIDRs from the node, got %v", resources.podCIDRs)
+	}
+
+	results := convertFlaggedResources(resources)
+	// only the egress policy and the service with externalTrafficPolicy=Cluster are flagged
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if results[0].resource != "NetworkPolicy namespace1/egress-cidr" || results[0].policy == nil {
+		t.Errorf("expected converted NetworkPolicy namespace1/egress-cidr, got %+v", results[0])
+	} else if !reflect.DeepEqual(results[0].policy.Spec.Egress[1].ToEntities, []string{entityCluster}) {
+		t.Errorf("expected egress to the cluster entity, got %+v", results[0].policy.Spec.Egress)
+	}
+	if results[1].resource != "Service namespace1/web" || results[1].policy == nil {
+		t.Errorf("expected converted Service namespace1/web, got %+v", results[1])
+	}
+}